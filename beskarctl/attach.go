@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+type attachment struct {
+	AttachmentId string `json:"attachmentId"`
+	URL          string `json:"url"`
+	FileName     string `json:"fileName"`
+	FileSize     int64  `json:"fileSize"`
+	MimeType     string `json:"mimeType"`
+}
+
+func attachCommand(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("attach", flag.ContinueOnError)
+	pageId := flags.Int64("page", 0, "page to attach the files to")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *pageId < 1 {
+		return usageError("-page is required")
+	}
+	if flags.NArg() == 0 {
+		return usageError("attach takes one or more files")
+	}
+
+	uploaded := make([]attachment, 0, flags.NArg())
+	for _, file := range flags.Args() {
+		var res attachment
+		fields := map[string]string{"pageId": strconv.FormatInt(*pageId, 10)}
+		if err := a.client.Upload(ctx, "/attachments/upload", fields, file, &res); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		uploaded = append(uploaded, res)
+	}
+
+	if a.json {
+		return a.printJSON(uploaded)
+	}
+	w := a.table()
+	fmt.Fprintln(w, "ID\tNAME\tSIZE\tURL")
+	for _, u := range uploaded {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", u.AttachmentId, u.FileName, u.FileSize, u.URL)
+	}
+	return w.Flush()
+}
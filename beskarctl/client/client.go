@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const API_PREFIX = "/api/v1"
+
+// Client calls the beskar REST API with a personal access token.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// APIError is a failed response from the server.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status %d", e.Status)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Message, e.Status)
+}
+
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error struct {
+		Message string `json:"message"`
+		Detail  string `json:"detail"`
+	} `json:"error"`
+}
+
+func New(baseURL string, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Do sends body as JSON to the API path and decodes the response data into
+// out. out may be a *json.RawMessage to keep the data as sent.
+func (c *Client) Do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+API_PREFIX+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// Upload posts file as multipart form data along with fields.
+func (c *Client) Upload(ctx context.Context, path string, fields map[string]string, file string, out interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+API_PREFIX+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return c.send(req, out)
+}
+
+func (c *Client) send(req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var env envelope
+	decodeErr := json.Unmarshal(data, &env)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := &APIError{Status: res.StatusCode}
+		if decodeErr == nil {
+			apiErr.Message = env.Error.Detail
+			if apiErr.Message == "" {
+				apiErr.Message = env.Error.Message
+			}
+		}
+		return apiErr
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("unexpected response from server: %w", decodeErr)
+	}
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*json.RawMessage); ok {
+		*raw = env.Data
+		return nil
+	}
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoSendsTokenAndBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/space/create" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q, want Bearer tok", got)
+		}
+		if got := r.Header.Get("Accept"); got != "application/json" {
+			t.Errorf("Accept = %q, want application/json", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["name"] != "Docs" {
+			t.Errorf("unexpected body %v (%v)", body, err)
+		}
+		w.Write([]byte(`{"data":{"id":"abc"}}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Id string `json:"id"`
+	}
+	c := New(srv.URL+"/", "tok")
+	if err := c.Do(context.Background(), http.MethodPost, "/space/create", map[string]string{"name": "Docs"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Id != "abc" {
+		t.Fatalf("expected id abc, got %q", out.Id)
+	}
+}
+
+func TestDoKeepsQueryString(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("path"); got != "/space/docs" {
+			t.Errorf("path query = %q, want /space/docs", got)
+		}
+		if r.Header.Get("Content-Type") != "" {
+			t.Errorf("GET without a body sent Content-Type %q", r.Header.Get("Content-Type"))
+		}
+		w.Write([]byte(`{"data":{"spaceId":"s1"}}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		SpaceId string `json:"spaceId"`
+	}
+	if err := New(srv.URL, "tok").Do(context.Background(), http.MethodGet, "/slug/resolve?path=%2Fspace%2Fdocs", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SpaceId != "s1" {
+		t.Fatalf("expected s1, got %q", out.SpaceId)
+	}
+}
+
+func TestDoDecodesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"detail", http.StatusForbidden, `{"error":{"message":"ERROR_CODE_UNAUTHORIZED","detail":"you cannot edit this page"}}`, "you cannot edit this page (status 403)"},
+		{"message", http.StatusBadRequest, `{"error":{"message":"ERROR_CODE_INVALID_INPUT"}}`, "ERROR_CODE_INVALID_INPUT (status 400)"},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "request failed with status 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			err := New(srv.URL, "tok").Do(context.Background(), http.MethodGet, "/space/list", nil, nil)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an APIError, got %v", err)
+			}
+			if apiErr.Status != tt.status {
+				t.Errorf("status = %d, want %d", apiErr.Status, tt.status)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRejectsMalformedSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not json`))
+	}))
+	defer srv.Close()
+
+	var out []string
+	err := New(srv.URL, "tok").Do(context.Background(), http.MethodGet, "/space/list", nil, &out)
+	if err == nil {
+		t.Fatal("expected an error for a non JSON response")
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		t.Fatalf("a 200 response should not be an APIError, got %v", err)
+	}
+}
+
+func TestDoRawAndNullData(t *testing.T) {
+	body := `{"data":[{"id":"a","extra":1}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	c := New(srv.URL, "tok")
+
+	var raw json.RawMessage
+	if err := c.Do(context.Background(), http.MethodGet, "/space/list", nil, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != `[{"id":"a","extra":1}]` {
+		t.Fatalf("raw data changed: %s", raw)
+	}
+
+	body = `{"data":null}`
+	out := []string{"kept"}
+	if err := c.Do(context.Background(), http.MethodGet, "/space/list", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0] != "kept" {
+		t.Fatalf("null data should leave out alone, got %v", out)
+	}
+}
+
+func TestUploadSendsMultipart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "diagram.png")
+	if err := os.WriteFile(file, []byte("png bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/attachments/upload" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q, want Bearer tok", got)
+		}
+		if got := r.FormValue("pageId"); got != "42" {
+			t.Errorf("pageId = %q, want 42", got)
+		}
+		part, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("no file part: %v", err)
+		}
+		defer part.Close()
+		data, _ := io.ReadAll(part)
+		if header.Filename != "diagram.png" || string(data) != "png bytes" {
+			t.Errorf("unexpected file %s: %q", header.Filename, data)
+		}
+		w.Write([]byte(`{"data":{"id":"att-1"}}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Id string `json:"id"`
+	}
+	if err := New(srv.URL, "tok").Upload(context.Background(), "/attachments/upload", map[string]string{"pageId": "42"}, file, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Id != "att-1" {
+		t.Fatalf("expected att-1, got %q", out.Id)
+	}
+}
@@ -0,0 +1,9 @@
+module beskarctl
+
+go 1.22.2
+
+require jbi v0.0.0
+
+require github.com/google/uuid v1.6.0
+
+replace jbi => ../jbi
@@ -0,0 +1,104 @@
+// beskarctl works with a beskar server from the command line. It signs in
+// with a personal access token and covers the docs-as-code basics: listing
+// spaces and pages, pulling pages as Markdown, pushing Markdown back as
+// drafts or published pages, uploading attachments and managing members.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"beskarctl/client"
+)
+
+const usage = `usage: beskarctl [-url URL] [-token TOKEN] [-json] <command> [arguments]
+
+commands:
+  spaces                                   list spaces
+  pages    -space SPACE                    list pages of a space
+  pull     -space SPACE [-r] [-out DIR] PAGE
+                                           save a page, or with -r its subtree, as Markdown
+  push     -space SPACE [-parent PAGE] [-draft] FILE|DIR...
+                                           publish Markdown files, or save them as drafts
+  attach   -page PAGE FILE...              upload attachments to a page
+  members  list|add|role|remove -space SPACE ...
+                                           manage space members
+
+SPACE is a space id or slug. The server URL and token default to the
+BESKAR_URL and BESKAR_TOKEN environment variables.
+`
+
+type app struct {
+	client *client.Client
+	json   bool
+	out    io.Writer
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"spaces":  spacesCommand,
+	"pages":   pagesCommand,
+	"pull":    pullCommand,
+	"push":    pushCommand,
+	"attach":  attachCommand,
+	"members": membersCommand,
+}
+
+func main() {
+	flags := flag.NewFlagSet("beskarctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	baseURL := flags.String("url", os.Getenv("BESKAR_URL"), "server URL")
+	token := flags.String("token", os.Getenv("BESKAR_TOKEN"), "personal access token")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beskarctl: unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *baseURL == "" || *token == "" {
+		fmt.Fprintln(os.Stderr, "beskarctl: set -url and -token, or BESKAR_URL and BESKAR_TOKEN")
+		os.Exit(2)
+	}
+
+	a := &app{client: client.New(*baseURL, *token), json: *asJSON, out: os.Stdout}
+	if err := run(context.Background(), a, flags.Args()[1:]); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "beskarctl %s: %s\n", flags.Arg(0), usageErr)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "beskarctl %s: %s\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+// usageError reports a command invoked with missing or bad arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func (a *app) printJSON(v interface{}) error {
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (a *app) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"beskarctl/client"
+)
+
+const testSpace = "5b0f2b7e-3c41-4d8e-9a57-2f6d0c1e8a90"
+
+// request is a call the fake server received, with its body decoded.
+type request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]interface{}
+}
+
+// fakeServer answers "METHOD /path" routes under the API prefix with the
+// handler's value wrapped in the response envelope.
+type fakeServer struct {
+	mu       sync.Mutex
+	requests []request
+}
+
+func (f *fakeServer) calls() []request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]request(nil), f.requests...)
+}
+
+func newTestApp(t *testing.T, asJSON bool, routes map[string]func(req request) interface{}) (*app, *bytes.Buffer, *fakeServer) {
+	t.Helper()
+	fake := &fakeServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q, want Bearer tok", got)
+		}
+		req := request{Method: r.Method, Path: strings.TrimPrefix(r.URL.Path, client.API_PREFIX), Query: r.URL.RawQuery}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &req.Body); err != nil {
+				t.Errorf("%s %s: body is not JSON: %s", r.Method, r.URL.Path, data)
+			}
+		}
+		fake.mu.Lock()
+		fake.requests = append(fake.requests, req)
+		fake.mu.Unlock()
+
+		handler, ok := routes[req.Method+" "+req.Path]
+		if !ok {
+			t.Errorf("unexpected request %s %s", req.Method, req.Path)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"ERROR_CODE_NOT_FOUND"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": handler(req)})
+	}))
+	t.Cleanup(srv.Close)
+
+	var out bytes.Buffer
+	return &app{client: client.New(srv.URL, "tok"), json: asJSON, out: &out}, &out, fake
+}
+
+func TestSpacesJSONPrintsServerData(t *testing.T) {
+	a, out, _ := newTestApp(t, true, map[string]func(request) interface{}{
+		"GET /space/list": func(request) interface{} {
+			return []map[string]interface{}{{"id": testSpace, "name": "Docs", "slug": "docs", "archived": false}}
+		},
+	})
+	if err := spacesCommand(context.Background(), a, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "[\n  {\n    \"archived\": false,\n    \"id\": \"" + testSpace + "\",\n    \"name\": \"Docs\",\n    \"slug\": \"docs\"\n  }\n]\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output\nwant:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestSpacesTable(t *testing.T) {
+	a, out, _ := newTestApp(t, false, map[string]func(request) interface{}{
+		"GET /space/list": func(request) interface{} {
+			return []space{{Id: testSpace, Name: "Docs", Slug: "docs", UserRole: "admin", DocCount: 3}}
+		},
+	})
+	if err := spacesCommand(context.Background(), a, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "docs") || !strings.HasSuffix(lines[1], "3") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+}
+
+func TestResolveSpaceBySlug(t *testing.T) {
+	a, _, fake := newTestApp(t, false, map[string]func(request) interface{}{
+		"GET /slug/resolve": func(request) interface{} { return resolution{SpaceId: testSpace} },
+	})
+	got, err := resolveSpace(context.Background(), a, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != testSpace {
+		t.Fatalf("expected %s, got %s", testSpace, got)
+	}
+	if calls := fake.calls(); len(calls) != 1 || calls[0].Query != "path=%2Fspace%2Fdocs" {
+		t.Fatalf("unexpected resolve calls %+v", calls)
+	}
+
+	if got, err := resolveSpace(context.Background(), a, strings.ToUpper(testSpace)); err != nil || got != testSpace {
+		t.Fatalf("a space id should pass through, got %q (%v)", got, err)
+	}
+	if len(fake.calls()) != 1 {
+		t.Fatal("a space id should not be resolved on the server")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type member struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	IsOwner bool   `json:"isOwner"`
+	Direct  bool   `json:"direct"`
+}
+
+type memberChange struct {
+	UserId string `json:"userId"`
+	Role   string `json:"role,omitempty"`
+}
+
+type candidate struct {
+	UserId string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+const membersUsage = `members list -space SPACE
+members add -space SPACE -role ROLE USER...
+members role -space SPACE -role ROLE USER
+members remove -space SPACE USER
+
+USER is a user id or email, ROLE is admin, editor, commenter or viewer`
+
+func membersCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return usageError(membersUsage)
+	}
+	action := args[0]
+	flags := flag.NewFlagSet("members "+action, flag.ContinueOnError)
+	spaceRef := flags.String("space", "", "space id or slug")
+	role := flags.String("role", "", "member role")
+	if err := flags.Parse(args[1:]); err != nil {
+		return usageError(err.Error())
+	}
+
+	switch action {
+	case "list":
+	case "add":
+		if *role == "" || flags.NArg() == 0 {
+			return usageError(membersUsage)
+		}
+	case "role":
+		if *role == "" || flags.NArg() != 1 {
+			return usageError(membersUsage)
+		}
+	case "remove":
+		if flags.NArg() != 1 {
+			return usageError(membersUsage)
+		}
+	default:
+		return usageError(membersUsage)
+	}
+
+	spaceId, err := resolveSpace(ctx, a, *spaceRef)
+	if err != nil {
+		return err
+	}
+	if action == "list" {
+		return listMembers(ctx, a, spaceId)
+	}
+	userIds, err := resolveUsers(ctx, a, spaceId, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	base := "/space/" + spaceId + "/members/"
+	switch action {
+	case "add":
+		changes := make([]memberChange, 0, len(userIds))
+		for _, userId := range userIds {
+			changes = append(changes, memberChange{UserId: userId, Role: *role})
+		}
+		err = a.client.Do(ctx, http.MethodPost, base+"add", map[string]interface{}{"members": changes}, &raw)
+	case "role":
+		err = a.client.Do(ctx, http.MethodPut, base+"role", memberChange{UserId: userIds[0], Role: *role}, &raw)
+	case "remove":
+		err = a.client.Do(ctx, http.MethodDelete, base+"remove", memberChange{UserId: userIds[0]}, &raw)
+	}
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(raw)
+	}
+	fmt.Fprintf(a.out, "%s: done\n", action)
+	return nil
+}
+
+func listMembers(ctx context.Context, a *app, spaceId string) error {
+	var raw json.RawMessage
+	if err := a.client.Do(ctx, http.MethodGet, "/space/"+spaceId+"/users", nil, &raw); err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(raw)
+	}
+	var members []member
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return err
+	}
+	w := a.table()
+	fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE\tACCESS")
+	for _, m := range members {
+		access := "direct"
+		switch {
+		case m.IsOwner:
+			access = "owner"
+		case !m.Direct:
+			access = "group"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Id, m.Name, m.Email, m.Role, access)
+	}
+	return w.Flush()
+}
+
+// resolveUsers maps emails to user ids through the space's member search.
+// Anything without an @ is taken as a user id.
+func resolveUsers(ctx context.Context, a *app, spaceId string, refs []string) ([]string, error) {
+	emails := make([]string, 0)
+	for _, ref := range refs {
+		if strings.Contains(ref, "@") {
+			emails = append(emails, strings.ToLower(ref))
+		}
+	}
+	byEmail := make(map[string]string)
+	if len(emails) > 0 {
+		var res struct {
+			Matches         []candidate `json:"matches"`
+			ExistingMembers []candidate `json:"existingMembers"`
+		}
+		req := map[string]interface{}{"emails": emails}
+		if err := a.client.Do(ctx, http.MethodPost, "/space/"+spaceId+"/members/candidates/search", req, &res); err != nil {
+			return nil, err
+		}
+		for _, c := range append(res.Matches, res.ExistingMembers...) {
+			if c.UserId != "" {
+				byEmail[strings.ToLower(c.Email)] = c.UserId
+			}
+		}
+	}
+
+	userIds := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !strings.Contains(ref, "@") {
+			userIds = append(userIds, ref)
+			continue
+		}
+		userId, ok := byEmail[strings.ToLower(ref)]
+		if !ok {
+			return nil, fmt.Errorf("no user with email %s", ref)
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, nil
+}
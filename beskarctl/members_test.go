@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMembersListJSON(t *testing.T) {
+	a, out, _ := newTestApp(t, true, map[string]func(request) interface{}{
+		"GET /space/" + testSpace + "/users": func(request) interface{} {
+			return []member{{Id: "u1", Name: "Ada", Email: "ada@example.com", Role: "admin", IsOwner: true, Direct: true}}
+		},
+	})
+	if err := membersCommand(context.Background(), a, []string{"list", "-space", testSpace}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "id": "u1",
+    "name": "Ada",
+    "email": "ada@example.com",
+    "role": "admin",
+    "isOwner": true,
+    "direct": true
+  }
+]
+`
+	if out.String() != want {
+		t.Fatalf("unexpected output\nwant:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestMembersAddResolvesEmails(t *testing.T) {
+	a, out, fake := newTestApp(t, false, map[string]func(request) interface{}{
+		"POST /space/" + testSpace + "/members/candidates/search": func(request) interface{} {
+			return map[string]interface{}{
+				"matches":         []candidate{{UserId: "u2", Email: "bob@example.com"}},
+				"existingMembers": []candidate{},
+			}
+		},
+		"POST /space/" + testSpace + "/members/add": func(request) interface{} { return nil },
+	})
+	args := []string{"add", "-space", testSpace, "-role", "editor", "Bob@Example.com", "u3"}
+	if err := membersCommand(context.Background(), a, args); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected search and add, got %+v", calls)
+	}
+	emails, _ := calls[0].Body["emails"].([]interface{})
+	if len(emails) != 1 || emails[0] != "bob@example.com" {
+		t.Errorf("unexpected search body %v", calls[0].Body)
+	}
+	members, _ := calls[1].Body["members"].([]interface{})
+	if len(members) != 2 {
+		t.Fatalf("unexpected add body %v", calls[1].Body)
+	}
+	for i, want := range []string{"u2", "u3"} {
+		m := members[i].(map[string]interface{})
+		if m["userId"] != want || m["role"] != "editor" {
+			t.Errorf("member %d = %v, want %s as editor", i, m, want)
+		}
+	}
+	if out.String() != "add: done\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestMembersUnknownEmail(t *testing.T) {
+	a, _, fake := newTestApp(t, false, map[string]func(request) interface{}{
+		"POST /space/" + testSpace + "/members/candidates/search": func(request) interface{} {
+			return map[string]interface{}{"matches": []candidate{}, "existingMembers": []candidate{}}
+		},
+	})
+	err := membersCommand(context.Background(), a, []string{"remove", "-space", testSpace, "nobody@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "nobody@example.com") {
+		t.Fatalf("expected an unknown email error, got %v", err)
+	}
+	if len(fake.calls()) != 1 {
+		t.Fatal("nothing should be removed for an unknown email")
+	}
+}
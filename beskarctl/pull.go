@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"jbi/core"
+	"jbi/markdown"
+)
+
+type documentView struct {
+	PageId   int64  `json:"pageId"`
+	SpaceId  string `json:"spaceId"`
+	PageType string `json:"pageType"`
+	Title    string `json:"title"`
+	Document *struct {
+		ParentId int64         `json:"parentId"`
+		Nodes    core.NodeData `json:"nodeData"`
+	} `json:"document"`
+}
+
+type descendant struct {
+	PageId   int64        `json:"pageId"`
+	Title    string       `json:"title"`
+	Type     string       `json:"type"`
+	Children []descendant `json:"children"`
+}
+
+type pulledPage struct {
+	PageId int64  `json:"pageId"`
+	Title  string `json:"title"`
+	File   string `json:"file"`
+}
+
+func pullCommand(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("pull", flag.ContinueOnError)
+	spaceRef := flags.String("space", "", "space id or slug")
+	recursive := flags.Bool("r", false, "pull the page's subtree as well")
+	out := flags.String("out", ".", "directory to write to")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() != 1 {
+		return usageError("pull takes one page id")
+	}
+	pageId, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil || pageId < 1 {
+		return usageError("invalid page id " + flags.Arg(0))
+	}
+	spaceId, err := resolveSpace(ctx, a, *spaceRef)
+	if err != nil {
+		return err
+	}
+
+	pulled := make([]pulledPage, 0)
+	root, err := pullPage(ctx, a, spaceId, pageId, *out)
+	if err != nil {
+		return err
+	}
+	pulled = append(pulled, root)
+
+	if *recursive {
+		var children []descendant
+		path := fmt.Sprintf("/space/%s/page/%d/descendants", spaceId, pageId)
+		if err := a.client.Do(ctx, http.MethodGet, path, nil, &children); err != nil {
+			return err
+		}
+		var walk func(dir string, pages []descendant) error
+		walk = func(dir string, pages []descendant) error {
+			for _, child := range pages {
+				if child.Type != "" && child.Type != "document" {
+					continue
+				}
+				p, err := pullPage(ctx, a, spaceId, child.PageId, dir)
+				if err != nil {
+					return err
+				}
+				pulled = append(pulled, p)
+				if err := walk(strings.TrimSuffix(p.File, ".md"), child.Children); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := walk(strings.TrimSuffix(root.File, ".md"), children); err != nil {
+			return err
+		}
+	}
+
+	if a.json {
+		return a.printJSON(pulled)
+	}
+	for _, p := range pulled {
+		fmt.Fprintf(a.out, "%d\t%s\n", p.PageId, p.File)
+	}
+	return nil
+}
+
+// pullPage writes the published page to dir as Markdown with front matter.
+func pullPage(ctx context.Context, a *app, spaceId string, pageId int64, dir string) (pulledPage, error) {
+	var view documentView
+	path := fmt.Sprintf("/editor/space/%s/page/%d", spaceId, pageId)
+	if err := a.client.Do(ctx, http.MethodGet, path, nil, &view); err != nil {
+		return pulledPage{}, fmt.Errorf("page %d: %w", pageId, err)
+	}
+	if view.PageType != "" && view.PageType != "document" {
+		return pulledPage{}, fmt.Errorf("page %d is a %s, only documents can be pulled", pageId, view.PageType)
+	}
+
+	meta := markdown.Meta{Title: view.Title, Space: spaceId, Page: pageId}
+	body := ""
+	if view.Document != nil {
+		meta.Parent = view.Document.ParentId
+		body = markdown.NodeDataToMarkdown(view.Document.Nodes)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return pulledPage{}, err
+	}
+	file := filepath.Join(dir, fileName(view.Title, pageId))
+	if err := os.WriteFile(file, []byte(markdown.WithFrontMatter(meta, body)), 0o644); err != nil {
+		return pulledPage{}, err
+	}
+	return pulledPage{PageId: pageId, Title: view.Title, File: file}, nil
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fileName names a pulled page after its title; the id keeps names unique.
+func fileName(title string, pageId int64) string {
+	name := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "-")
+	}
+	if name == "" {
+		name = "page"
+	}
+	return fmt.Sprintf("%s-%d.md", name, pageId)
+}
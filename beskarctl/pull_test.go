@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jbi/markdown"
+)
+
+// pageRoute serves a published document the way the editor API does.
+func pageRoute(pageId int64, title string, parentId int64, body string) func(request) interface{} {
+	return func(request) interface{} {
+		return map[string]interface{}{
+			"pageId":   pageId,
+			"spaceId":  testSpace,
+			"pageType": "document",
+			"title":    title,
+			"document": map[string]interface{}{
+				"parentId": parentId,
+				"nodeData": markdown.FromMarkdown(body).ConvertToContentObjects(pageId),
+			},
+		}
+	}
+}
+
+func editorPath(pageId int64) string {
+	return fmt.Sprintf("GET /editor/space/%s/page/%d", testSpace, pageId)
+}
+
+func TestPullWritesMarkdownWithFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	a, out, _ := newTestApp(t, false, map[string]func(request) interface{}{
+		editorPath(10): pageRoute(10, "Getting Started", 4, "Hello **world**.\n"),
+	})
+	if err := pullCommand(context.Background(), a, []string{"-space", testSpace, "-out", dir, "10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "getting-started-10.md")
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, body := markdown.SplitFrontMatter(string(src))
+	if meta.Title != "Getting Started" || meta.Space != testSpace || meta.Page != 10 || meta.Parent != 4 {
+		t.Errorf("unexpected front matter %+v", meta)
+	}
+	if body != "Hello **world**.\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if want := "10\t" + file + "\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPullRecursiveJSON(t *testing.T) {
+	dir := t.TempDir()
+	a, out, fake := newTestApp(t, true, map[string]func(request) interface{}{
+		editorPath(10): pageRoute(10, "Guide", 0, "Top.\n"),
+		editorPath(11): pageRoute(11, "Install", 10, "Install it.\n"),
+		editorPath(12): pageRoute(12, "Linux", 11, "On Linux.\n"),
+		editorPath(14): pageRoute(14, "Usage", 10, "Use it.\n"),
+		"GET /space/" + testSpace + "/page/10/descendants": func(request) interface{} {
+			return []descendant{
+				{PageId: 11, Title: "Install", Type: "document", Children: []descendant{
+					{PageId: 12, Title: "Linux", Type: "document"},
+				}},
+				{PageId: 13, Title: "Board", Type: "whiteboard"},
+				{PageId: 14, Title: "Usage", Type: "document"},
+			}
+		},
+	})
+	if err := pullCommand(context.Background(), a, []string{"-space", testSpace, "-r", "-out", dir, "10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var pulled []pulledPage
+	if err := json.Unmarshal(out.Bytes(), &pulled); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	want := []pulledPage{
+		{PageId: 10, Title: "Guide", File: filepath.Join(dir, "guide-10.md")},
+		{PageId: 11, Title: "Install", File: filepath.Join(dir, "guide-10", "install-11.md")},
+		{PageId: 12, Title: "Linux", File: filepath.Join(dir, "guide-10", "install-11", "linux-12.md")},
+		{PageId: 14, Title: "Usage", File: filepath.Join(dir, "guide-10", "usage-14.md")},
+	}
+	if len(pulled) != len(want) {
+		t.Fatalf("expected %d pages, got %+v", len(want), pulled)
+	}
+	for i := range want {
+		if pulled[i] != want[i] {
+			t.Errorf("page %d = %+v, want %+v", i, pulled[i], want[i])
+		}
+		if _, err := os.Stat(want[i].File); err != nil {
+			t.Errorf("page %d was not written: %v", want[i].PageId, err)
+		}
+	}
+	for _, call := range fake.calls() {
+		if strings.HasSuffix(call.Path, "/page/13") {
+			t.Error("the whiteboard should not be fetched")
+		}
+	}
+}
+
+func TestPullRejectsNonDocuments(t *testing.T) {
+	a, _, _ := newTestApp(t, false, map[string]func(request) interface{}{
+		editorPath(7): func(request) interface{} {
+			return map[string]interface{}{"pageId": 7, "pageType": "whiteboard", "title": "Board"}
+		},
+	})
+	err := pullCommand(context.Background(), a, []string{"-space", testSpace, "-out", t.TempDir(), "7"})
+	if err == nil || !strings.Contains(err.Error(), "only documents can be pulled") {
+		t.Fatalf("expected a whiteboard to be refused, got %v", err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Getting Started", "getting-started-3.md"},
+		{"  C++ & Go: notes!  ", "c-go-notes-3.md"},
+		{"", "page-3.md"},
+		{"日本語", "page-3.md"},
+		{strings.Repeat("word ", 20), strings.TrimRight(strings.Repeat("word-", 12), "-") + "-3.md"},
+	}
+	for _, tt := range tests {
+		if got := fileName(tt.title, 3); got != tt.want {
+			t.Errorf("fileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jbi/core"
+	"jbi/markdown"
+)
+
+type newPage struct {
+	Title    string `json:"title"`
+	ParentId int64  `json:"parentId"`
+	SpaceId  string `json:"spaceId"`
+}
+
+type pageContent struct {
+	Id       int64         `json:"id"`
+	Title    string        `json:"title"`
+	ParentId int64         `json:"parentId"`
+	SpaceId  string        `json:"spaceId"`
+	Nodes    core.NodeData `json:"nodeData"`
+}
+
+type pushedPage struct {
+	PageId  int64  `json:"pageId"`
+	Title   string `json:"title"`
+	File    string `json:"file"`
+	Created bool   `json:"created"`
+	Draft   bool   `json:"draft"`
+}
+
+type pusher struct {
+	app     *app
+	spaceId string
+	draft   bool
+	pushed  []pushedPage
+}
+
+func pushCommand(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("push", flag.ContinueOnError)
+	spaceRef := flags.String("space", "", "space id or slug, when files do not name one")
+	parent := flags.Int64("parent", 0, "parent page for new pages")
+	draft := flags.Bool("draft", false, "save as drafts instead of publishing")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() == 0 {
+		return usageError("push takes Markdown files or directories")
+	}
+	p := &pusher{app: a, draft: *draft, pushed: make([]pushedPage, 0)}
+	if *spaceRef != "" {
+		spaceId, err := resolveSpace(ctx, a, *spaceRef)
+		if err != nil {
+			return err
+		}
+		p.spaceId = spaceId
+	}
+
+	for _, arg := range flags.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = p.pushDir(ctx, arg, *parent)
+		} else {
+			_, err = p.pushFile(ctx, arg, *parent)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.json {
+		return a.printJSON(p.pushed)
+	}
+	for _, page := range p.pushed {
+		state := "published"
+		if page.Draft {
+			state = "draft saved"
+		}
+		if page.Created {
+			state = "created, " + state
+		}
+		fmt.Fprintf(a.out, "%d\t%s\t%s\n", page.PageId, page.File, state)
+	}
+	return nil
+}
+
+// pushDir pushes the Markdown files in dir under parent. A directory named
+// like a file holds that page's children, the layout pull -r writes.
+func (p *pusher) pushDir(ctx context.Context, dir string, parent int64) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	pushed := make(map[string]int64)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		pageId, err := p.pushFile(ctx, filepath.Join(dir, entry.Name()), parent)
+		if err != nil {
+			return err
+		}
+		pushed[strings.TrimSuffix(entry.Name(), ".md")] = pageId
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		childParent := parent
+		if pageId, ok := pushed[entry.Name()]; ok {
+			childParent = pageId
+		}
+		if err := p.pushDir(ctx, filepath.Join(dir, entry.Name()), childParent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushFile publishes or drafts one file. Pages created here have their id
+// written into the file's front matter so the next push updates them.
+func (p *pusher) pushFile(ctx context.Context, file string, parent int64) (int64, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	meta, body := markdown.SplitFrontMatter(string(src))
+	if meta.Title == "" {
+		if title, rest := markdown.TakeTitle(body); title != "" {
+			meta.Title, body = title, rest
+		} else {
+			meta.Title = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+	}
+	if meta.Space == "" {
+		meta.Space = p.spaceId
+	}
+	if meta.Space == "" {
+		return 0, usageError(file + ": no space, pass -space or set space in the front matter")
+	}
+	if meta.Space, err = resolveSpace(ctx, p.app, meta.Space); err != nil {
+		return 0, err
+	}
+	if meta.Parent == 0 {
+		meta.Parent = parent
+	}
+
+	created := false
+	if meta.Page == 0 {
+		var res struct {
+			Page int64 `json:"page"`
+		}
+		req := newPage{Title: meta.Title, ParentId: meta.Parent, SpaceId: meta.Space}
+		if err := p.app.client.Do(ctx, http.MethodPost, "/editor/space/"+meta.Space+"/page/create", req, &res); err != nil {
+			return 0, fmt.Errorf("%s: %w", file, err)
+		}
+		meta.Page = res.Page
+		created = true
+	}
+
+	content := pageContent{
+		Id:       meta.Page,
+		Title:    meta.Title,
+		ParentId: meta.Parent,
+		SpaceId:  meta.Space,
+		Nodes:    markdown.FromMarkdown(body).ConvertToContentObjects(0),
+	}
+	path := "/editor/publish"
+	if p.draft {
+		path = "/editor/update"
+	}
+	if err := p.app.client.Do(ctx, http.MethodPut, path, content, nil); err != nil {
+		return meta.Page, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if created {
+		if err := os.WriteFile(file, []byte(markdown.WithFrontMatter(meta, strings.TrimLeft(body, "\n"))), 0o644); err != nil {
+			return meta.Page, err
+		}
+	}
+	p.pushed = append(p.pushed, pushedPage{PageId: meta.Page, Title: meta.Title, File: file, Created: created, Draft: p.draft})
+	return meta.Page, nil
+}
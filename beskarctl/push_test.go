@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jbi/markdown"
+)
+
+func TestPushCreatesAndPublishes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "install.md")
+	if err := os.WriteFile(file, []byte("# Install guide\n\nRun **it**.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, out, fake := newTestApp(t, true, map[string]func(request) interface{}{
+		"POST /editor/space/" + testSpace + "/page/create": func(request) interface{} {
+			return map[string]interface{}{"page": 21}
+		},
+		"PUT /editor/publish": func(request) interface{} { return nil },
+	})
+	if err := pushCommand(context.Background(), a, []string{"-space", testSpace, "-parent", "5", file}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected create and publish, got %+v", calls)
+	}
+	create := calls[0].Body
+	if create["title"] != "Install guide" || create["parentId"] != float64(5) || create["spaceId"] != testSpace {
+		t.Errorf("unexpected create body %v", create)
+	}
+	publish := calls[1].Body
+	if publish["id"] != float64(21) || publish["title"] != "Install guide" || publish["parentId"] != float64(5) {
+		t.Errorf("unexpected publish body %v", publish)
+	}
+	if _, ok := publish["nodeData"].(map[string]interface{}); !ok {
+		t.Errorf("publish body has no nodeData: %v", publish)
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, body := markdown.SplitFrontMatter(string(src))
+	if meta.Page != 21 || meta.Title != "Install guide" || meta.Space != testSpace || meta.Parent != 5 {
+		t.Errorf("page id was not written back: %+v", meta)
+	}
+	if body != "Run **it**.\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	var pushed []pushedPage
+	if err := json.Unmarshal(out.Bytes(), &pushed); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	want := pushedPage{PageId: 21, Title: "Install guide", File: file, Created: true}
+	if len(pushed) != 1 || pushed[0] != want {
+		t.Fatalf("pushed = %+v, want %+v", pushed, want)
+	}
+}
+
+func TestPushDraftUpdatesExistingPage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.md")
+	src := markdown.WithFrontMatter(markdown.Meta{Title: "Notes", Space: testSpace, Page: 30, Parent: 2}, "Draft text.\n")
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, out, fake := newTestApp(t, false, map[string]func(request) interface{}{
+		"PUT /editor/update": func(request) interface{} { return nil },
+	})
+	if err := pushCommand(context.Background(), a, []string{"-draft", file}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.calls()
+	if len(calls) != 1 || calls[0].Body["id"] != float64(30) || calls[0].Body["parentId"] != float64(2) {
+		t.Fatalf("expected one draft save of page 30, got %+v", calls)
+	}
+	if got, err := os.ReadFile(file); err != nil || string(got) != src {
+		t.Errorf("an existing page's file should be left alone, got %q (%v)", got, err)
+	}
+	if want := "30\t" + file + "\tdraft saved\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPushDirNestsChildren(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide.md":         "# Guide\n",
+		"guide/install.md": "# Install\n",
+		"notes.txt":        "not markdown",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next := int64(40)
+	a, out, fake := newTestApp(t, true, map[string]func(request) interface{}{
+		"POST /editor/space/" + testSpace + "/page/create": func(request) interface{} {
+			next++
+			return map[string]interface{}{"page": next}
+		},
+		"PUT /editor/publish": func(request) interface{} { return nil },
+	})
+	if err := pushCommand(context.Background(), a, []string{"-space", testSpace, dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	var creates []request
+	for _, call := range fake.calls() {
+		if call.Method == "POST" {
+			creates = append(creates, call)
+		}
+	}
+	if len(creates) != 2 {
+		t.Fatalf("expected two pages created, got %+v", creates)
+	}
+	if creates[0].Body["title"] != "Guide" || creates[0].Body["parentId"] != float64(0) {
+		t.Errorf("unexpected first page %v", creates[0].Body)
+	}
+	if creates[1].Body["title"] != "Install" || creates[1].Body["parentId"] != float64(41) {
+		t.Errorf("the child should be created under page 41, got %v", creates[1].Body)
+	}
+
+	var pushed []pushedPage
+	if err := json.Unmarshal(out.Bytes(), &pushed); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if len(pushed) != 2 || pushed[0].PageId != 41 || pushed[1].PageId != 42 {
+		t.Fatalf("unexpected pushed pages %+v", pushed)
+	}
+}
+
+func TestPushNeedsASpace(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "loose.md")
+	if err := os.WriteFile(file, []byte("text\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, _, fake := newTestApp(t, false, nil)
+	err := pushCommand(context.Background(), a, []string{file})
+	if _, ok := err.(usageError); !ok {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+	if len(fake.calls()) != 0 {
+		t.Fatal("nothing should be sent without a space")
+	}
+}
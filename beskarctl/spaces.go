@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type space struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	UserRole string `json:"userRole"`
+	DocCount int    `json:"docCount"`
+}
+
+type page struct {
+	PageId   int64  `json:"pageId"`
+	Title    string `json:"title"`
+	ParentId int64  `json:"parentId"`
+	Type     string `json:"type"`
+}
+
+type resolution struct {
+	SpaceId string `json:"spaceId"`
+}
+
+func spacesCommand(ctx context.Context, a *app, args []string) error {
+	var raw json.RawMessage
+	if err := a.client.Do(ctx, http.MethodGet, "/space/list", nil, &raw); err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(raw)
+	}
+	var spaces []space
+	if err := json.Unmarshal(raw, &spaces); err != nil {
+		return err
+	}
+	w := a.table()
+	fmt.Fprintln(w, "ID\tSLUG\tNAME\tROLE\tPAGES")
+	for _, s := range spaces {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", s.Id, s.Slug, s.Name, s.UserRole, s.DocCount)
+	}
+	return w.Flush()
+}
+
+func pagesCommand(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("pages", flag.ContinueOnError)
+	spaceRef := flags.String("space", "", "space id or slug")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	spaceId, err := resolveSpace(ctx, a, *spaceRef)
+	if err != nil {
+		return err
+	}
+	var raw json.RawMessage
+	if err := a.client.Do(ctx, http.MethodGet, "/space/"+spaceId+"/page/list", nil, &raw); err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(raw)
+	}
+	var pages []page
+	if err := json.Unmarshal(raw, &pages); err != nil {
+		return err
+	}
+
+	// print the pages as a tree under their parents
+	children := make(map[int64][]page)
+	known := make(map[int64]bool)
+	for _, p := range pages {
+		known[p.PageId] = true
+	}
+	for _, p := range pages {
+		parent := p.ParentId
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], p)
+	}
+	w := a.table()
+	fmt.Fprintln(w, "ID\tTYPE\tTITLE")
+	var walk func(parent int64, depth int)
+	walk = func(parent int64, depth int) {
+		for _, p := range children[parent] {
+			fmt.Fprintf(w, "%d\t%s\t%s%s\n", p.PageId, p.Type, strings.Repeat("  ", depth), p.Title)
+			walk(p.PageId, depth+1)
+		}
+	}
+	walk(0, 0)
+	return w.Flush()
+}
+
+// resolveSpace turns a space id or slug into the space id.
+func resolveSpace(ctx context.Context, a *app, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", usageError("-space is required")
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		return id.String(), nil
+	}
+	var res resolution
+	path := "/slug/resolve?path=" + url.QueryEscape("/space/"+ref)
+	if err := a.client.Do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return "", fmt.Errorf("space %q: %w", ref, err)
+	}
+	return res.SpaceId, nil
+}
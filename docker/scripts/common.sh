@@ -94,6 +94,13 @@ load_env_file() {
     : "${REDIS_HOST:=redis}"
     : "${REDIS_PORT:=6379}"
     : "${PERMIFY_ENDPOINT:=guard:3478}"
+    : "${AUTHORIZER:=permify}"
+    : "${PERMIFY_CACHE_TTL_SECONDS:=5}"
+    : "${PERMISSION_RELAY_ENABLED:=true}"
+    : "${PERMISSION_RELAY_INTERVAL_SECONDS:=5}"
+    : "${PERMISSION_OUTBOX_RETENTION_DAYS:=7}"
+    : "${PERMISSION_RECONCILE_ENABLED:=true}"
+    : "${PERMISSION_RECONCILE_INTERVAL_MINUTES:=60}"
     : "${NEXTAUTH_URL_INTERNAL:=http://ui:3000}"
 
     require_var APP_DOMAIN
@@ -158,6 +165,34 @@ load_env_file() {
     : "${EMAIL_MAX_ATTEMPTS:=10}"
     : "${EMAIL_RETRY_INITIAL_SECONDS:=30}"
     : "${EMAIL_RETRY_MAX_SECONDS:=21600}"
+    : "${SPACE_PURGE_ENABLED:=false}"
+    : "${SPACE_PURGE_GRACE_DAYS:=30}"
+    : "${SPACE_PURGE_INTERVAL_MINUTES:=60}"
+    : "${SPACE_PURGE_BATCH_SIZE:=10}"
+    : "${ACCESS_REVIEW_ENABLED:=false}"
+    : "${ACCESS_REVIEW_INTERVAL_DAYS:=90}"
+    : "${ACCESS_REVIEW_CHECK_MINUTES:=60}"
+    : "${ACCESS_REVIEW_BATCH_SIZE:=20}"
+    : "${ACCESS_REVIEW_INACTIVE_DAYS:=90}"
+    : "${ACCESS_REVIEW_INTERNAL_DOMAINS:=}"
+    : "${MEMBERSHIP_EXPIRY_ENABLED:=true}"
+    : "${MEMBERSHIP_EXPIRY_INTERVAL_MINUTES:=15}"
+    : "${MEMBERSHIP_EXPIRY_BATCH_SIZE:=50}"
+    : "${MEMBERSHIP_EXPIRY_NOTICE_DAYS:=3}"
+    : "${INVITE_SWEEP_ENABLED:=true}"
+    : "${INVITE_SWEEP_INTERVAL_MINUTES:=15}"
+    : "${INVITE_SWEEP_BATCH_SIZE:=50}"
+    : "${INVITE_EXPIRY_DAYS:=14}"
+    : "${INVITE_REMINDER_DAYS:=3}"
+    : "${INVITE_NOTIFY_ADMINS:=false}"
+    : "${SPACE_IMPORT_MAX_MB:=512}"
+    : "${GIT_SYNC_ENABLED:=false}"
+    : "${GIT_SYNC_INTERVAL_MINUTES:=5}"
+    : "${GIT_SYNC_TIMEOUT_SECONDS:=120}"
+    : "${GIT_SYNC_DIR:=git-sync}"
+    : "${GIT_SYNC_LOCAL_ROOT:=}"
+    : "${GIT_SYNC_AUTHOR_NAME:=Beskar}"
+    : "${GIT_SYNC_AUTHOR_EMAIL:=beskar@localhost}"
 
     PROXY_DOMAIN_ALIASES_ENABLED="$(normalize_bool "$PROXY_DOMAIN_ALIASES_ENABLED")"
     ORIGIN_CERT_TRUST_INJECTION_ENABLED="$(normalize_bool "$ORIGIN_CERT_TRUST_INJECTION_ENABLED")"
@@ -254,6 +289,34 @@ EOF
     export EMAIL_MAX_ATTEMPTS
     export EMAIL_RETRY_INITIAL_SECONDS
     export EMAIL_RETRY_MAX_SECONDS
+    export SPACE_PURGE_ENABLED
+    export SPACE_PURGE_GRACE_DAYS
+    export SPACE_PURGE_INTERVAL_MINUTES
+    export SPACE_PURGE_BATCH_SIZE
+    export ACCESS_REVIEW_ENABLED
+    export ACCESS_REVIEW_INTERVAL_DAYS
+    export ACCESS_REVIEW_CHECK_MINUTES
+    export ACCESS_REVIEW_BATCH_SIZE
+    export ACCESS_REVIEW_INACTIVE_DAYS
+    export ACCESS_REVIEW_INTERNAL_DOMAINS
+    export MEMBERSHIP_EXPIRY_ENABLED
+    export MEMBERSHIP_EXPIRY_INTERVAL_MINUTES
+    export MEMBERSHIP_EXPIRY_BATCH_SIZE
+    export MEMBERSHIP_EXPIRY_NOTICE_DAYS
+    export INVITE_SWEEP_ENABLED
+    export INVITE_SWEEP_INTERVAL_MINUTES
+    export INVITE_SWEEP_BATCH_SIZE
+    export INVITE_EXPIRY_DAYS
+    export INVITE_REMINDER_DAYS
+    export INVITE_NOTIFY_ADMINS
+    export SPACE_IMPORT_MAX_MB
+    export GIT_SYNC_ENABLED
+    export GIT_SYNC_INTERVAL_MINUTES
+    export GIT_SYNC_TIMEOUT_SECONDS
+    export GIT_SYNC_DIR
+    export GIT_SYNC_LOCAL_ROOT
+    export GIT_SYNC_AUTHOR_NAME
+    export GIT_SYNC_AUTHOR_EMAIL
     export DB_HOST
     export DB_PORT
     export POSTGRES_DATA_MOUNT
@@ -266,6 +329,13 @@ EOF
     export REDIS_HOST
     export REDIS_PORT
     export PERMIFY_ENDPOINT
+    export AUTHORIZER
+    export PERMIFY_CACHE_TTL_SECONDS
+    export PERMISSION_RELAY_ENABLED
+    export PERMISSION_RELAY_INTERVAL_SECONDS
+    export PERMISSION_OUTBOX_RETENTION_DAYS
+    export PERMISSION_RECONCILE_ENABLED
+    export PERMISSION_RECONCILE_INTERVAL_MINUTES
     export ZITADEL_ISSUER_URL
     export ZITADEL_EXTERNALDOMAIN
     export ZITADEL_EXTERNALSECURE
@@ -2,7 +2,6 @@ module jbi
 
 go 1.22.2
 
-require (
-	github.com/gofrs/uuid v4.4.0+incompatible // indirect
-	github.com/google/uuid v1.6.0 // indirect
-)
+require github.com/google/uuid v1.6.0
+
+require github.com/gofrs/uuid v4.4.0+incompatible // indirect
@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Meta is the front matter kept at the top of pulled, pushed and git synced
+// files so a file can be matched back to the page it came from.
+type Meta struct {
+	Title  string
+	Space  string
+	Page   int64
+	Parent int64
+}
+
+// SplitFrontMatter separates the front matter from the Markdown body. Files
+// without front matter return an empty Meta and the source unchanged.
+func SplitFrontMatter(src string) (Meta, string) {
+	var meta Meta
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	if !strings.HasPrefix(src, "---\n") {
+		return meta, src
+	}
+	end := strings.Index(src[4:], "\n---")
+	if end < 0 {
+		return meta, src
+	}
+	header := src[4 : 4+end]
+	body := strings.TrimLeft(src[4+end+4:], "\n")
+
+	for _, line := range strings.Split(header, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		switch strings.TrimSpace(key) {
+		case "title":
+			meta.Title = value
+		case "space":
+			meta.Space = value
+		case "page":
+			meta.Page, _ = strconv.ParseInt(value, 10, 64)
+		case "parent":
+			meta.Parent, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return meta, body
+}
+
+// WithFrontMatter prefixes body with meta.
+func WithFrontMatter(meta Meta, body string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	if meta.Title != "" {
+		fmt.Fprintf(&b, "title: %s\n", strconv.Quote(meta.Title))
+	}
+	if meta.Space != "" {
+		fmt.Fprintf(&b, "space: %s\n", meta.Space)
+	}
+	if meta.Page > 0 {
+		fmt.Fprintf(&b, "page: %d\n", meta.Page)
+	}
+	if meta.Parent > 0 {
+		fmt.Fprintf(&b, "parent: %d\n", meta.Parent)
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(body)
+	return b.String()
+}
+
+// TakeTitle removes a leading level one heading from body and returns its
+// text, for files that carry their title in the document.
+func TakeTitle(body string) (string, string) {
+	trimmed := strings.TrimLeft(body, "\n")
+	line, rest, _ := strings.Cut(trimmed, "\n")
+	if !strings.HasPrefix(line, "# ") {
+		return "", body
+	}
+	return strings.TrimSpace(strings.TrimRight(line[2:], "# ")), rest
+}
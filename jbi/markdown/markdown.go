@@ -0,0 +1,257 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jbi/core"
+)
+
+// ToMarkdown renders an editor document as Markdown. Nodes without a
+// Markdown form keep their text and drop the rest.
+func ToMarkdown(doc core.Document) string {
+	out := strings.Join(renderBlocks(doc.Content), "\n\n")
+	if out == "" {
+		return ""
+	}
+	return out + "\n"
+}
+
+// NodeDataToMarkdown renders the content nodes a page is stored as.
+func NodeDataToMarkdown(nodes core.NodeData) string {
+	if len(nodes.Content) == 0 {
+		return ""
+	}
+	text := make([]core.TextNode, len(nodes.Text))
+	copy(text, nodes.Text)
+	sort.SliceStable(text, func(i, j int) bool { return text[i].OrderId < text[j].OrderId })
+	return ToMarkdown(core.Doc{NodeData: core.NodeData{Content: nodes.Content, Text: text}}.CreateEditorDocument())
+}
+
+func renderBlocks(nodes []core.Document) []string {
+	blocks := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if block := renderBlock(node); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+func renderBlock(node core.Document) string {
+	switch node.Type {
+	case "paragraph":
+		return escapeLeading(renderInline(node.Content))
+	case "heading":
+		level := intAttr(node.Attributes, "level", 1)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + renderInline(node.Content)
+	case "blockquote":
+		return prefixLines(strings.Join(renderBlocks(node.Content), "\n\n"), "> ", ">")
+	case "codeBlock":
+		language, _ := node.Attributes["language"].(string)
+		return "```" + language + "\n" + plainText(node.Content) + "\n```"
+	case "horizontalRule":
+		return "---"
+	case "bulletList":
+		return renderList(node.Content, func(int, core.Document) string { return "- " })
+	case "orderedList":
+		start := intAttr(node.Attributes, "start", 1)
+		return renderList(node.Content, func(i int, _ core.Document) string { return strconv.Itoa(start+i) + ". " })
+	case "taskList":
+		return renderList(node.Content, func(_ int, item core.Document) string {
+			if checked, _ := item.Attributes["checked"].(bool); checked {
+				return "- [x] "
+			}
+			return "- [ ] "
+		})
+	case "imageBlock", "image":
+		src, _ := node.Attributes["src"].(string)
+		if src == "" {
+			return ""
+		}
+		alt, _ := node.Attributes["alt"].(string)
+		return fmt.Sprintf("![%s](%s)", escape(alt), src)
+	case "hardBreak":
+		return ""
+	}
+	if hasInline(node.Content) {
+		return renderInline(node.Content)
+	}
+	return strings.Join(renderBlocks(node.Content), "\n\n")
+}
+
+func renderList(items []core.Document, marker func(int, core.Document) string) string {
+	lines := make([]string, 0, len(items))
+	for i, item := range items {
+		m := marker(i, item)
+		body := strings.Join(renderBlocks(item.Content), "\n")
+		lines = append(lines, m+indentLines(body, len(m)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderInline(nodes []core.Document) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			b.WriteString(renderText(node.Text, node.Marks))
+		case "hardBreak":
+			b.WriteString("\\\n")
+		case "imageInline", "image":
+			src, _ := node.Attributes["src"].(string)
+			alt, _ := node.Attributes["alt"].(string)
+			if src != "" {
+				b.WriteString(fmt.Sprintf("![%s](%s)", escape(alt), src))
+			}
+		default:
+			b.WriteString(renderInline(node.Content))
+		}
+	}
+	return b.String()
+}
+
+func renderText(text string, marks []map[string]interface{}) string {
+	if text == "" {
+		return ""
+	}
+	// emphasis cannot open or close on whitespace, keep it outside
+	inner := strings.TrimSpace(text)
+	if inner == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, inner)]
+	trail := text[len(lead)+len(inner):]
+
+	if hasMark(marks, "code") {
+		inner = "`" + inner + "`"
+	} else {
+		inner = escape(inner)
+	}
+	for _, mark := range marks {
+		switch mark["type"] {
+		case "bold":
+			inner = "**" + inner + "**"
+		case "italic":
+			inner = "*" + inner + "*"
+		case "strike":
+			inner = "~~" + inner + "~~"
+		}
+	}
+	for _, mark := range marks {
+		if mark["type"] != "link" {
+			continue
+		}
+		attrs, _ := mark["attrs"].(map[string]interface{})
+		if href, _ := attrs["href"].(string); href != "" {
+			inner = "[" + inner + "](" + href + ")"
+		}
+	}
+	return lead + inner + trail
+}
+
+func plainText(nodes []core.Document) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		if node.Type == "hardBreak" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(node.Text)
+		b.WriteString(plainText(node.Content))
+	}
+	return b.String()
+}
+
+func hasInline(nodes []core.Document) bool {
+	for _, node := range nodes {
+		if node.Type == "text" || node.Type == "hardBreak" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMark(marks []map[string]interface{}, kind string) bool {
+	for _, mark := range marks {
+		if mark["type"] == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func intAttr(attrs map[string]interface{}, name string, fallback int) int {
+	switch v := attrs[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"~", `\~`,
+)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// escapeLeading keeps a paragraph from reading back as another block.
+func escapeLeading(text string) string {
+	if text == "" {
+		return text
+	}
+	switch text[0] {
+	case '#', '>', '-', '+':
+		return `\` + text
+	}
+	if m := orderedMarker.FindStringIndex(text); m != nil {
+		dot := m[1] - 2
+		return text[:dot] + `\` + text[dot:]
+	}
+	return text
+}
+
+func prefixLines(text string, prefix string, blankPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = blankPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines indents every line but the first, which follows a list marker.
+func indentLines(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	pad := strings.Repeat(" ", width)
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,86 @@
+package markdown
+
+import "testing"
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"paragraphs", "First paragraph.\n\nSecond one.\n"},
+		{"headings", "## Install\n\nRun it.\n\n### Notes\n"},
+		{"marks", "Some **bold**, *italic*, ~~gone~~ and `code` with a [link](https://example.com).\n"},
+		{"nested marks", "A ***strong emphasis*** here.\n"},
+		{"escapes", "Literal \\*stars\\* and snake\\_case.\n"},
+		{"hard break", "line one\\\nline two\n"},
+		{"code block", "```go\nfunc main() {\n\tprintln(\"*\")\n}\n```\n"},
+		{"quote", "> quoted\n>\n> - item\n"},
+		{"rule", "above\n\n---\n\nbelow\n"},
+		{"bullet list", "- one\n- two\n  - nested\n- three\n"},
+		{"ordered list", "3. three\n4. four\n"},
+		{"task list", "- [x] done\n- [ ] todo\n"},
+		{"image", "![diagram](https://example.com/d.png)\n"},
+		{"leading marker", "\\# not a heading\n\n1\\. not a list\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToMarkdown(FromMarkdown(tt.src))
+			if got != tt.src {
+				t.Errorf("round trip changed the document\nwant:\n%s\ngot:\n%s", tt.src, got)
+			}
+		})
+	}
+}
+
+func TestFromMarkdownNodes(t *testing.T) {
+	doc := FromMarkdown("# Title\n\n- [x] **done**\n")
+	if len(doc.Content) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(doc.Content))
+	}
+	heading := doc.Content[0]
+	if heading.Type != "heading" || heading.Attributes["level"] != 1 {
+		t.Errorf("expected a level 1 heading, got %s %v", heading.Type, heading.Attributes)
+	}
+	list := doc.Content[1]
+	if list.Type != "taskList" || list.Content[0].Type != "taskItem" || list.Content[0].Attributes["checked"] != true {
+		t.Fatalf("expected a checked task item, got %+v", list)
+	}
+	text := list.Content[0].Content[0].Content[0]
+	if text.Text != "done" || len(text.Marks) != 1 || text.Marks[0]["type"] != "bold" {
+		t.Errorf("expected bold text, got %+v", text)
+	}
+}
+
+func TestStoredNodesRoundTrip(t *testing.T) {
+	src := "Intro with **bold**.\n\n- one\n- two\n"
+	nodes := FromMarkdown(src).ConvertToContentObjects(7)
+	for _, text := range nodes.Text {
+		if text.DocId != 7 {
+			t.Fatalf("expected doc id 7 on text nodes, got %d", text.DocId)
+		}
+	}
+	got := NodeDataToMarkdown(nodes)
+	if got != src {
+		t.Errorf("stored nodes changed the document\nwant:\n%s\ngot:\n%s", src, got)
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	meta := Meta{Title: `Release "notes"`, Space: "8f0c2a4e-8f7a-4a53-9a53-3a8f55b7e0a1", Page: 42, Parent: 7}
+	got, body := SplitFrontMatter(WithFrontMatter(meta, "Body\n"))
+	if got != meta {
+		t.Errorf("expected %+v, got %+v", meta, got)
+	}
+	if body != "Body\n" {
+		t.Errorf("expected the body back, got %q", body)
+	}
+
+	plain := "# Heading\n\ntext\n"
+	if got, body := SplitFrontMatter(plain); got != (Meta{}) || body != plain {
+		t.Errorf("expected files without front matter unchanged, got %+v %q", got, body)
+	}
+	title, rest := TakeTitle(plain)
+	if title != "Heading" || rest != "\ntext\n" {
+		t.Errorf("expected the heading as title, got %q %q", title, rest)
+	}
+}
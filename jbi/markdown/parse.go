@@ -0,0 +1,458 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"jbi/core"
+)
+
+var (
+	headingLine   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceLine     = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	ruleLine      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	listLine      = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	orderedMarker = regexp.MustCompile(`^\d{1,9}[.)]\s`)
+	taskMarker    = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	imageLine     = regexp.MustCompile(`^ {0,3}!\[([^\]]*)\]\(([^)\s]+)(?:[ \t]+"[^"]*")?\)[ \t]*$`)
+)
+
+// FromMarkdown parses Markdown into an editor document.
+func FromMarkdown(src string) core.Document {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	return core.Document{Type: "doc", Content: parseBlocks(strings.Split(src, "\n"))}
+}
+
+func parseBlocks(lines []string) []core.Document {
+	blocks := make([]core.Document, 0)
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fenceLine.MatchString(line):
+			var block core.Document
+			block, i = parseFence(lines, i)
+			blocks = append(blocks, block)
+		case headingLine.MatchString(line):
+			m := headingLine.FindStringSubmatch(line)
+			blocks = append(blocks, core.Document{
+				Type:       "heading",
+				Attributes: map[string]interface{}{"level": len(m[1])},
+				Content:    parseInline(m[2], nil),
+			})
+			i++
+		case ruleLine.MatchString(line):
+			blocks = append(blocks, core.Document{Type: "horizontalRule"})
+			i++
+		case isQuote(line):
+			quoted := make([]string, 0)
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				text := strings.TrimLeft(lines[i], " ")[1:]
+				quoted = append(quoted, strings.TrimPrefix(text, " "))
+			}
+			blocks = append(blocks, core.Document{Type: "blockquote", Content: parseBlocks(quoted)})
+		case listLine.MatchString(line):
+			var block core.Document
+			block, i = parseList(lines, i)
+			blocks = append(blocks, block)
+		case imageLine.MatchString(line):
+			m := imageLine.FindStringSubmatch(line)
+			blocks = append(blocks, core.Document{
+				Type:       "imageBlock",
+				Attributes: map[string]interface{}{"src": m[2], "alt": unescape(m[1])},
+			})
+			i++
+		default:
+			var block core.Document
+			block, i = parseParagraph(lines, i)
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+func isQuote(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) < 4 && strings.HasPrefix(trimmed, ">")
+}
+
+func startsBlock(line string) bool {
+	return fenceLine.MatchString(line) || headingLine.MatchString(line) || ruleLine.MatchString(line) ||
+		isQuote(line) || listLine.MatchString(line)
+}
+
+func parseFence(lines []string, i int) (core.Document, int) {
+	m := fenceLine.FindStringSubmatch(lines[i])
+	fence := m[1]
+	code := make([]string, 0)
+	for i++; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	block := core.Document{Type: "codeBlock", Attributes: map[string]interface{}{"language": m[2]}}
+	if text := strings.Join(code, "\n"); text != "" {
+		block.Content = []core.Document{{Type: "text", Text: text}}
+	}
+	return block, i
+}
+
+func parseParagraph(lines []string, i int) (core.Document, int) {
+	var b strings.Builder
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || (b.Len() > 0 && startsBlock(line)) {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasSuffix(trimmed, "  "):
+			b.WriteString(strings.TrimRight(trimmed, " "))
+			b.WriteString("\n")
+		case strings.HasSuffix(trimmed, `\`) && !strings.HasSuffix(trimmed, `\\`):
+			b.WriteString(strings.TrimSuffix(trimmed, `\`))
+			b.WriteString("\n")
+		default:
+			b.WriteString(strings.TrimRight(trimmed, " \t"))
+		}
+	}
+	text := strings.TrimRight(b.String(), "\n")
+	text = strings.ReplaceAll(text, "\n ", "\n")
+	return core.Document{Type: "paragraph", Content: parseInline(text, nil)}, i
+}
+
+type listItem struct {
+	lines   []string
+	task    bool
+	checked bool
+}
+
+func parseList(lines []string, i int) (core.Document, int) {
+	first := listLine.FindStringSubmatch(lines[i])
+	ordered := isOrdered(first[2])
+	start := 1
+	if ordered {
+		start, _ = strconv.Atoi(first[2][:len(first[2])-1])
+	}
+
+	items := make([]listItem, 0)
+	for i < len(lines) {
+		m := listLine.FindStringSubmatch(lines[i])
+		if m == nil || isOrdered(m[2]) != ordered {
+			break
+		}
+		width := len(m[0])
+		if strings.TrimSpace(lines[i][width:]) == "" {
+			width = len(m[1]) + len(m[2]) + 1
+		}
+		item := listItem{lines: []string{lines[i][len(m[0]):]}}
+		if !ordered {
+			if t := taskMarker.FindStringSubmatch(item.lines[0]); t != nil {
+				item.task = true
+				item.checked = t[1] != " "
+				item.lines[0] = item.lines[0][len(t[0]):]
+			}
+		}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// a blank line continues the item only if indented content follows
+				next := i + 1
+				for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+					next++
+				}
+				if next >= len(lines) || indentOf(lines[next]) < width {
+					break
+				}
+				item.lines = append(item.lines, "")
+				i++
+				continue
+			}
+			if indentOf(line) >= width {
+				item.lines = append(item.lines, stripIndent(line, width))
+				i++
+				continue
+			}
+			// lazy continuation of the item's paragraph
+			if !startsBlock(line) && strings.TrimSpace(item.lines[len(item.lines)-1]) != "" {
+				item.lines = append(item.lines, strings.TrimLeft(line, " \t"))
+				i++
+				continue
+			}
+			break
+		}
+		items = append(items, item)
+		// blank lines between items keep the list going
+		next := i
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		if next < len(lines) && listLine.MatchString(lines[next]) {
+			i = next
+		}
+	}
+
+	list := core.Document{Type: "bulletList"}
+	itemType := "listItem"
+	switch {
+	case ordered:
+		list.Type = "orderedList"
+		if start != 1 {
+			list.Attributes = map[string]interface{}{"start": start}
+		}
+	case len(items) > 0 && items[0].task:
+		list.Type = "taskList"
+		itemType = "taskItem"
+	}
+	for _, item := range items {
+		node := core.Document{Type: itemType, Content: parseBlocks(item.lines)}
+		if len(node.Content) == 0 {
+			node.Content = []core.Document{{Type: "paragraph"}}
+		}
+		if itemType == "taskItem" {
+			node.Attributes = map[string]interface{}{"checked": item.checked}
+		}
+		list.Content = append(list.Content, node)
+	}
+	return list, i
+}
+
+func isOrdered(marker string) bool {
+	return !strings.ContainsAny(marker[:1], "-*+")
+}
+
+func indentOf(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+func stripIndent(line string, width int) string {
+	seen := 0
+	for i, r := range line {
+		if seen >= width || (r != ' ' && r != '\t') {
+			return strings.Repeat(" ", seen-width) + line[i:]
+		}
+		if r == '\t' {
+			seen += 4 - seen%4
+		} else {
+			seen++
+		}
+	}
+	return ""
+}
+
+// parseInline turns inline Markdown into text nodes carrying marks. Newlines
+// become hard breaks.
+func parseInline(src string, marks []map[string]interface{}) []core.Document {
+	nodes := make([]core.Document, 0)
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, core.Document{Type: "text", Text: buf.String(), Marks: marks})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && isPunct(src[i+1]):
+			buf.WriteByte(src[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			flush()
+			nodes = append(nodes, core.Document{Type: "hardBreak"})
+			i++
+			continue
+		case c == '`':
+			run := runLength(src, i, '`')
+			fence := strings.Repeat("`", run)
+			if end := strings.Index(src[i+run:], fence); end >= 0 {
+				flush()
+				code := src[i+run : i+run+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				nodes = append(nodes, core.Document{Type: "text", Text: code, Marks: withMark(marks, "code", nil)})
+				i += run + end + run
+				continue
+			}
+			buf.WriteString(fence)
+			i += run
+			continue
+		case c == '!' && strings.HasPrefix(src[i:], "!["):
+			if label, href, n := linkAt(src, i+1); n > 0 {
+				flush()
+				nodes = append(nodes, core.Document{Type: "text", Text: unescape(label), Marks: withMark(marks, "link", map[string]interface{}{"href": href})})
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if label, href, n := linkAt(src, i); n > 0 {
+				flush()
+				nodes = append(nodes, parseInline(label, withMark(marks, "link", map[string]interface{}{"href": href}))...)
+				i += n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(src[i:], '>'); end > 0 {
+				target := src[i+1 : i+end]
+				if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "mailto:") {
+					flush()
+					nodes = append(nodes, core.Document{Type: "text", Text: target, Marks: withMark(marks, "link", map[string]interface{}{"href": target})})
+					i += end + 1
+					continue
+				}
+			}
+		case c == '*' || c == '_' || c == '~':
+			if n, kind, inner := emphasisAt(src, i); n > 0 {
+				flush()
+				innerMarks := marks
+				for _, k := range strings.Fields(kind) {
+					innerMarks = withMark(innerMarks, k, nil)
+				}
+				nodes = append(nodes, parseInline(inner, innerMarks)...)
+				i += n
+				continue
+			}
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// emphasisAt matches a delimited run at i and returns its length, mark and
+// inner text.
+func emphasisAt(src string, i int) (int, string, string) {
+	c := src[i]
+	run := runLength(src, i, c)
+	var delim, kind string
+	switch {
+	case c == '~' && run >= 2:
+		delim, kind = "~~", "strike"
+	case c == '~':
+		return 0, "", ""
+	case run >= 3:
+		delim, kind = string([]byte{c, c, c}), "bold italic"
+	case run == 2:
+		delim, kind = string([]byte{c, c}), "bold"
+	default:
+		delim, kind = string(c), "italic"
+	}
+	// underscores inside words are literal
+	if c == '_' && i > 0 && isWordByte(src[i-1]) {
+		return 0, "", ""
+	}
+	open := i + len(delim)
+	if open >= len(src) || src[open] == ' ' {
+		return 0, "", ""
+	}
+	for j := open + 1; j+len(delim) <= len(src); j++ {
+		if src[j-1] == '\\' {
+			continue
+		}
+		if src[j:j+len(delim)] != delim || src[j-1] == ' ' {
+			continue
+		}
+		// a single delimiter must not be part of a longer run
+		if len(delim) == 1 && j+1 < len(src) && src[j+1] == c {
+			j++
+			continue
+		}
+		if c == '_' && j+1 < len(src) && isWordByte(src[j+1]) {
+			continue
+		}
+		return j + len(delim) - i, kind, src[open:j]
+	}
+	return 0, "", ""
+}
+
+// linkAt matches [label](href) at i and returns its parts and length.
+func linkAt(src string, i int) (string, string, int) {
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if j+1 >= len(src) || src[j+1] != '(' {
+				return "", "", 0
+			}
+			end := strings.IndexByte(src[j+2:], ')')
+			if end < 0 {
+				return "", "", 0
+			}
+			target := strings.TrimSpace(src[j+2 : j+2+end])
+			if k := strings.IndexAny(target, " \t"); k >= 0 {
+				target = target[:k]
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			return src[i+1 : j], target, j + 2 + end + 1 - i
+		}
+	}
+	return "", "", 0
+}
+
+func withMark(marks []map[string]interface{}, kind string, attrs map[string]interface{}) []map[string]interface{} {
+	next := make([]map[string]interface{}, 0, len(marks)+1)
+	next = append(next, marks...)
+	mark := map[string]interface{}{"type": kind}
+	if attrs != nil {
+		mark["attrs"] = attrs
+	}
+	return append(next, mark)
+}
+
+func runLength(src string, i int, c byte) int {
+	n := 0
+	for i+n < len(src) && src[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func unescape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isPunct(text[i+1]) {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
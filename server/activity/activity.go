@@ -0,0 +1,58 @@
+package activity
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func getUserFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+
+	query := r.URL.Query()
+	var actorID *uuid.UUID
+	if raw := strings.TrimSpace(query.Get("actor")); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+		actorID = &parsed
+	}
+
+	feed, err := listUserFeed(r.Context(), userID, actorID, query.Get("cursor"), query.Get("limit"))
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT] {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, feed)
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/feed", getUserFeed)
+	return r
+}
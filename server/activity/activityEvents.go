@@ -0,0 +1,136 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+type Client struct {
+	spaceID uuid.UUID
+	userID  uuid.UUID
+	channel chan Activity
+}
+
+type EventHub struct {
+	sync.RWMutex
+	clients    map[*Client]bool
+	broadcast  chan Activity
+	register   chan *Client
+	unregister chan *Client
+}
+
+// broadcastBuffer is how many entries can wait for the hub before Publish
+// starts dropping them.
+const broadcastBuffer = 1024
+
+var Hub *EventHub
+
+func init() {
+	Hub = newEventHub()
+	go Hub.run()
+}
+
+func newEventHub() *EventHub {
+	return &EventHub{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan Activity, broadcastBuffer),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+	}
+}
+
+func (h *EventHub) run() {
+	for {
+		select {
+		case client := <-h.register:
+			h.Lock()
+			h.clients[client] = true
+			h.Unlock()
+		case client := <-h.unregister:
+			h.Lock()
+			if _, ok := h.clients[client]; ok {
+				delete(h.clients, client)
+				close(client.channel)
+			}
+			h.Unlock()
+		case entry := <-h.broadcast:
+			h.Lock()
+			for client := range h.clients {
+				if client.spaceID == entry.SpaceId {
+					select {
+					case client.channel <- entry:
+					default:
+						// a client that can't keep up is dropped here rather
+						// than through unregister, which only this loop reads
+						delete(h.clients, client)
+						close(client.channel)
+					}
+				}
+			}
+			h.Unlock()
+		}
+	}
+}
+
+// Publish hands an entry to the hub without waiting. Entries are already
+// stored, so when the hub is backed up the live copy is dropped rather than
+// holding up the caller.
+func (h *EventHub) Publish(entry Activity) {
+	select {
+	case h.broadcast <- entry:
+	default:
+		logger().Warn("activity hub is backed up, dropping live entry")
+	}
+}
+
+// SSEHandler streams new activity of a space. Page level entries are checked
+// against the subscriber's page permissions before they are written out.
+func (h *EventHub) SSEHandler(w http.ResponseWriter, r *http.Request, spaceId uuid.UUID, userId uuid.UUID) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	client := &Client{
+		spaceID: spaceId,
+		userID:  userId,
+		channel: make(chan Activity, 256),
+	}
+
+	h.register <- client
+
+	// Listen for client disconnect
+	notify := r.Context().Done()
+	go func() {
+		<-notify
+		h.unregister <- client
+	}()
+
+	// Send initial ping to establish connection
+	fmt.Fprintf(w, ": ping\n\n")
+	flusher.Flush()
+
+	ctx := core.WithoutPermissionMemo(r.Context())
+	for entry := range client.channel {
+		if entry.PageId != nil && !core.ValidateUserPagePermission(ctx, strconv.FormatInt(*entry.PageId, 10), client.userID, core.PAGE_VIEW) {
+			continue
+		}
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "data: %s\n\n", payload)
+		flusher.Flush()
+	}
+}
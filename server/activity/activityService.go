@@ -0,0 +1,152 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFeedLimit = 25
+	maxFeedLimit     = 100
+)
+
+// Record stores an activity entry and fans it out to live subscribers of the
+// space. Failures are logged only; activity must never fail the action that
+// produced it.
+func Record(event Event) {
+	var spaceId interface{}
+	if event.SpaceId != uuid.Nil {
+		spaceId = event.SpaceId
+	}
+	var actorName *string
+	if event.ActorName != "" {
+		actorName = &event.ActorName
+	}
+	data := event.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	entry := Activity{
+		PageId:    event.PageId,
+		ActorId:   event.ActorId,
+		ActorName: actorName,
+		Type:      event.Type,
+		Data:      data,
+	}
+	err := core.GetPool().QueryRow(context.Background(), insertActivity, spaceId, event.PageId, event.ActorId, actorName, string(event.Type), data).
+		Scan(&entry.Id, &entry.SpaceId, &entry.CreatedAt)
+	if err != nil {
+		logger().Error("unable to record activity", zap.String("type", string(event.Type)), zap.Error(err))
+		return
+	}
+	Hub.Publish(entry)
+}
+
+// RecordThrottled records the event unless the same actor already produced
+// the same kind of entry for the page within the window. It is meant for
+// autosaving editors that would otherwise flood the feed.
+func RecordThrottled(event Event, window time.Duration) {
+	if event.PageId != nil {
+		var recent bool
+		err := core.GetPool().QueryRow(context.Background(), hasRecentActivity, *event.PageId, event.ActorId, string(event.Type), window).Scan(&recent)
+		if err != nil {
+			logger().Error("unable to check recent activity", zap.Error(err))
+		}
+		if recent {
+			return
+		}
+	}
+	Record(event)
+}
+
+// PageRef is a small helper for producers that only have a page id.
+func PageRef(pageId int64) *int64 {
+	return &pageId
+}
+
+func visiblePageIds(ctx context.Context, userId uuid.UUID) ([]int64, error) {
+	ids, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		return nil, err
+	}
+	pageIds := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		parsed, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		pageIds = append(pageIds, parsed)
+	}
+	return pageIds, nil
+}
+
+func listFeed(ctx context.Context, userId uuid.UUID, filter feedFilter) (Feed, error) {
+	feed := Feed{Items: []Activity{}}
+	if len(filter.spaceIds) == 0 {
+		return feed, nil
+	}
+	pageIds, err := visiblePageIds(ctx, userId)
+	if err != nil {
+		logger().Error(err.Error())
+		return feed, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+
+	rows, err := core.GetPool().Query(ctx, listActivity, filter.spaceIds, filter.actorId, filter.cursor, pageIds, filter.limit)
+	if err != nil {
+		logger().Error(err.Error())
+		return feed, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Activity])
+	if err != nil {
+		logger().Error(err.Error())
+		return feed, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	feed.Items = items
+	if len(items) == filter.limit {
+		next := strconv.FormatInt(items[len(items)-1].Id, 10)
+		feed.NextCursor = &next
+	}
+	return feed, nil
+}
+
+// ListSpaceActivity returns the activity of one space as seen by userId.
+// The caller is expected to have checked space view permission.
+func ListSpaceActivity(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, cursor string, limit string) (Feed, error) {
+	filter, err := parseFeedFilter(cursor, limit)
+	if err != nil {
+		return Feed{Items: []Activity{}}, err
+	}
+	filter.spaceIds = []uuid.UUID{spaceId}
+	return listFeed(ctx, userId, filter)
+}
+
+// listUserFeed returns activity across every space the user can view,
+// optionally narrowed down to a single actor.
+func listUserFeed(ctx context.Context, userId uuid.UUID, actorId *uuid.UUID, cursor string, limit string) (Feed, error) {
+	filter, err := parseFeedFilter(cursor, limit)
+	if err != nil {
+		return Feed{Items: []Activity{}}, err
+	}
+	spaceIds, err := core.GetEntitiesWithPermission(ctx, "space", "user", userId.String(), core.SPACE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return Feed{Items: []Activity{}}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	for _, id := range spaceIds {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		filter.spaceIds = append(filter.spaceIds, parsed)
+	}
+	filter.actorId = actorId
+	return listFeed(ctx, userId, filter)
+}
@@ -0,0 +1,31 @@
+package activity
+
+const (
+	insertActivity = `INSERT INTO core.activity (space_id, page_id, actor_id, actor_name, type, data)
+VALUES (COALESCE($1::uuid, (SELECT p.space_id FROM core.page p WHERE p.id = $2)), $2, $3, $4, $5, $6)
+RETURNING id, space_id, created_at`
+
+	hasRecentActivity = `SELECT EXISTS (
+	SELECT 1 FROM core.activity
+	WHERE page_id = $1 AND actor_id = $2 AND type = $3 AND created_at > now() - $4::interval
+)`
+
+	// Entries about a page are only visible to users who can view that page.
+	// Once the page is gone its permissions are gone too, so the entry stays
+	// visible to everyone in the space but without its data: the title of a
+	// restricted page must not outlive the page.
+	listActivity = `SELECT a.id, a.space_id, a.page_id, a.actor_id, a.actor_name, a.type,
+	CASE WHEN a.page_id IS NULL OR a.page_id = ANY($4::bigint[]) THEN a.data ELSE '{}'::jsonb END AS data,
+	a.created_at
+FROM core.activity a
+WHERE a.space_id = ANY($1)
+	AND ($2::uuid IS NULL OR a.actor_id = $2)
+	AND ($3::bigint = 0 OR a.id < $3)
+	AND (
+		a.page_id IS NULL
+		OR a.page_id = ANY($4::bigint[])
+		OR NOT EXISTS (SELECT 1 FROM core.page p WHERE p.id = a.page_id)
+	)
+ORDER BY a.id DESC
+LIMIT $5`
+)
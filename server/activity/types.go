@@ -0,0 +1,61 @@
+package activity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	PageCreated       Type = "page:created"
+	PagePublished     Type = "page:published"
+	PageDeleted       Type = "page:deleted"
+	WhiteboardCreated Type = "whiteboard:created"
+	WhiteboardUpdated Type = "whiteboard:updated"
+	WhiteboardDeleted Type = "whiteboard:deleted"
+	CommentCreated    Type = "comment:created"
+	CommentReplied    Type = "comment:replied"
+	CommentResolved   Type = "comment:resolved"
+	MemberAdded       Type = "member:added"
+	MemberRemoved     Type = "member:removed"
+	MemberRoleChanged Type = "member:role_changed"
+	InviteSent        Type = "invite:sent"
+	InviteAccepted    Type = "invite:accepted"
+	InviteDeclined    Type = "invite:declined"
+)
+
+// Event is what producers hand to Record. SpaceId may be left empty when
+// PageId is set; the space is then resolved from the page.
+type Event struct {
+	SpaceId   uuid.UUID
+	PageId    *int64
+	ActorId   uuid.UUID
+	ActorName string
+	Type      Type
+	Data      map[string]interface{}
+}
+
+type Activity struct {
+	Id        int64                  `json:"id" db:"id"`
+	SpaceId   uuid.UUID              `json:"spaceId" db:"space_id"`
+	PageId    *int64                 `json:"pageId,omitempty" db:"page_id"`
+	ActorId   uuid.UUID              `json:"actorId" db:"actor_id"`
+	ActorName *string                `json:"actorName,omitempty" db:"actor_name"`
+	Type      Type                   `json:"type" db:"type"`
+	Data      map[string]interface{} `json:"data" db:"data"`
+	CreatedAt time.Time              `json:"createdAt" db:"created_at"`
+}
+
+type Feed struct {
+	Items      []Activity `json:"items"`
+	NextCursor *string    `json:"nextCursor"`
+}
+
+type feedFilter struct {
+	spaceIds []uuid.UUID
+	actorId  *uuid.UUID
+	cursor   int64
+	limit    int
+}
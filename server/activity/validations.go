@@ -0,0 +1,33 @@
+package activity
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+func parseFeedFilter(cursor string, limit string) (feedFilter, error) {
+	filter := feedFilter{limit: defaultFeedLimit}
+
+	if cursor = strings.TrimSpace(cursor); cursor != "" {
+		parsed, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || parsed <= 0 {
+			return filter, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		filter.cursor = parsed
+	}
+
+	if limit = strings.TrimSpace(limit); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return filter, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if parsed > maxFeedLimit {
+			parsed = maxFeedLimit
+		}
+		filter.limit = parsed
+	}
+	return filter, nil
+}
@@ -0,0 +1,38 @@
+package activity
+
+import "testing"
+
+func TestParseFeedFilterDefaults(t *testing.T) {
+	filter, err := parseFeedFilter("", "")
+	if err != nil {
+		t.Fatalf("expected empty filter to parse: %v", err)
+	}
+	if filter.cursor != 0 {
+		t.Fatalf("expected no cursor, got %d", filter.cursor)
+	}
+	if filter.limit != defaultFeedLimit {
+		t.Fatalf("expected default limit %d, got %d", defaultFeedLimit, filter.limit)
+	}
+}
+
+func TestParseFeedFilterCapsLimit(t *testing.T) {
+	filter, err := parseFeedFilter("42", "1000")
+	if err != nil {
+		t.Fatalf("expected filter to parse: %v", err)
+	}
+	if filter.cursor != 42 {
+		t.Fatalf("expected cursor 42, got %d", filter.cursor)
+	}
+	if filter.limit != maxFeedLimit {
+		t.Fatalf("expected limit %d, got %d", maxFeedLimit, filter.limit)
+	}
+}
+
+func TestParseFeedFilterRejectsInvalidCursor(t *testing.T) {
+	if _, err := parseFeedFilter("abc", ""); err == nil {
+		t.Fatalf("expected invalid cursor to be rejected")
+	}
+	if _, err := parseFeedFilter("-5", ""); err == nil {
+		t.Fatalf("expected negative cursor to be rejected")
+	}
+}
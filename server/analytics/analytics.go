@@ -0,0 +1,119 @@
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func getPageViews(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	pageIdStr := chi.URLParam(r, "pageId")
+	pageID, err := strconv.ParseInt(pageIdStr, 10, 64)
+	if err != nil || pageID <= 0 {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserPagePermission(r.Context(), pageIdStr, userID, core.PAGE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	window, err := parseTimeRange(r.URL.Query(), time.Now())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	stats, err := getPageViewStats(pageID, window)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, stats)
+}
+
+func getSpaceTopPages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	window, err := parseTimeRange(r.URL.Query(), time.Now())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	pages, err := getTopPages(r.Context(), spaceID, userID, window, limit)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, pages)
+}
+
+func getRecentPages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	pages, err := getRecentlyViewed(r.Context(), userID, limit)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, pages)
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/page/{pageId}/views", getPageViews)
+	r.Get("/space/{spaceId}/top", getSpaceTopPages)
+	r.Get("/recent", getRecentPages)
+	return r
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CountPageViews returns the all-time number of recorded views for a page.
+func CountPageViews(ctx context.Context, pageId int64) (int64, error) {
+	var count int64
+	err := core.GetPool().QueryRow(ctx, countPageViews, pageId).Scan(&count)
+	return count, err
+}
+
+func getPageViewStats(pageId int64, window TimeRange) (PageViewStats, error) {
+	stats := PageViewStats{PageId: pageId, From: window.From, To: window.To, Daily: []DailyViews{}}
+	ctx := context.Background()
+	conn, err := core.GetPool().Acquire(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return stats, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, getPageViewTotals, pageId, window.From, window.To).Scan(&stats.Views, &stats.UniqueViewers, &stats.LastViewedAt)
+	if err != nil {
+		logger().Error(err.Error())
+		return stats, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+
+	rows, err := conn.Query(ctx, getPageViewsDaily, pageId, window.From, window.To)
+	if err != nil {
+		logger().Error(err.Error())
+		return stats, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	daily, err := pgx.CollectRows(rows, pgx.RowToStructByName[DailyViews])
+	if err != nil {
+		logger().Error(err.Error())
+		return stats, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	stats.Daily = daily
+	return stats, nil
+}
+
+func getTopPages(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, window TimeRange, limit int) ([]TopPage, error) {
+	pages := []TopPage{}
+	pageIds, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(pageIds) == 0 {
+		return pages, nil
+	}
+	rows, err := core.GetPool().Query(ctx, getTopPagesForSpace, spaceId, window.From, window.To, pageIds, limit)
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	pages, err = pgx.CollectRows(rows, pgx.RowToStructByName[TopPage])
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return pages, nil
+}
+
+// getRecentlyViewed lists the pages a user opened most recently. Pages the
+// user can no longer view are left out.
+func getRecentlyViewed(ctx context.Context, userId uuid.UUID, limit int) ([]RecentPage, error) {
+	pages := []RecentPage{}
+	pageIds, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(pageIds) == 0 {
+		return pages, nil
+	}
+	rows, err := core.GetPool().Query(ctx, getRecentlyViewedPages, userId, pageIds, limit)
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	pages, err = pgx.CollectRows(rows, pgx.RowToStructByName[RecentPage])
+	if err != nil {
+		logger().Error(err.Error())
+		return pages, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return pages, nil
+}
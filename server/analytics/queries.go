@@ -0,0 +1,54 @@
+package analytics
+
+const (
+	// insertPageViews drops views whose page isn't in the space from the URL,
+	// so a view is never credited to another space.
+	insertPageViews = `INSERT INTO core.page_views (page_id, space_id, user_id, viewed_at)
+SELECT v.page_id, v.space_id, v.user_id, v.viewed_at
+FROM unnest($1::bigint[], $2::uuid[], $3::uuid[], $4::timestamptz[]) AS v(page_id, space_id, user_id, viewed_at)
+JOIN core.page p ON p.id = v.page_id AND p.space_id = v.space_id`
+
+	countPageViews = `SELECT COUNT(*) FROM core.page_views WHERE page_id = $1`
+
+	getPageViewTotals = `SELECT COUNT(*) AS views, COUNT(DISTINCT user_id) AS unique_viewers, MAX(viewed_at) AS last_viewed_at
+FROM core.page_views
+WHERE page_id = $1 AND viewed_at >= $2 AND viewed_at < $3`
+
+	getPageViewsDaily = `SELECT date_trunc('day', viewed_at) AS day, COUNT(*) AS views, COUNT(DISTINCT user_id) AS unique_viewers
+FROM core.page_views
+WHERE page_id = $1 AND viewed_at >= $2 AND viewed_at < $3
+GROUP BY day
+ORDER BY day`
+
+	getTopPagesForSpace = `SELECT
+	v.page_id,
+	t.title,
+	COALESCE(p.type, 'document') AS type,
+	COUNT(*) AS views,
+	COUNT(DISTINCT v.user_id) AS unique_viewers,
+	MAX(v.viewed_at) AS last_viewed_at
+FROM core.page_views v
+JOIN core.page p ON p.id = v.page_id
+LEFT JOIN LATERAL (
+	SELECT d.title FROM core.page_doc_map d WHERE d.page_id = p.id ORDER BY d.version DESC LIMIT 1
+) t ON true
+WHERE v.space_id = $1 AND v.viewed_at >= $2 AND v.viewed_at < $3 AND v.page_id = ANY($4)
+GROUP BY v.page_id, t.title, p.type
+ORDER BY views DESC, last_viewed_at DESC
+LIMIT $5`
+
+	getRecentlyViewedPages = `SELECT r.page_id, r.space_id, s.name AS space_name, t.title, COALESCE(p.type, 'document') AS type, r.viewed_at
+FROM (
+	SELECT page_id, space_id, MAX(viewed_at) AS viewed_at
+	FROM core.page_views
+	WHERE user_id = $1 AND page_id = ANY($2)
+	GROUP BY page_id, space_id
+) r
+JOIN core.page p ON p.id = r.page_id
+JOIN core.space s ON s.id = r.space_id AND s.deleted_at IS NULL
+LEFT JOIN LATERAL (
+	SELECT d.title FROM core.page_doc_map d WHERE d.page_id = p.id ORDER BY d.version DESC LIMIT 1
+) t ON true
+ORDER BY r.viewed_at DESC
+LIMIT $3`
+)
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBufferSize    = 4096
+	defaultBatchSize     = 200
+	defaultFlushInterval = 5 * time.Second
+)
+
+// ViewRecorder buffers page view events in memory and writes them to the
+// database in batches, so recording a view never blocks a read request.
+type ViewRecorder struct {
+	events        chan PageView
+	batchSize     int
+	flushInterval time.Duration
+}
+
+var Views *ViewRecorder
+
+func init() {
+	Views = NewViewRecorder(defaultBufferSize, defaultBatchSize, defaultFlushInterval)
+}
+
+func NewViewRecorder(bufferSize int, batchSize int, flushInterval time.Duration) *ViewRecorder {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	return &ViewRecorder{
+		events:        make(chan PageView, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Record queues a view. When the buffer is full the event is dropped rather
+// than slowing down the caller.
+func (v *ViewRecorder) Record(pageId int64, spaceId uuid.UUID, userId uuid.UUID) {
+	event := PageView{
+		PageId:   pageId,
+		SpaceId:  spaceId,
+		UserId:   userId,
+		ViewedAt: time.Now().UTC(),
+	}
+	select {
+	case v.events <- event:
+	default:
+		logger().Warn("page view buffer full, dropping event", zap.Int64("pageId", pageId))
+	}
+}
+
+func (v *ViewRecorder) Start(ctx context.Context) {
+	ticker := time.NewTicker(v.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]PageView, 0, v.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			v.drain(&batch)
+			v.flush(context.Background(), batch)
+			return
+		case event := <-v.events:
+			batch = append(batch, event)
+			if len(batch) >= v.batchSize {
+				v.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				v.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+func (v *ViewRecorder) drain(batch *[]PageView) {
+	for {
+		select {
+		case event := <-v.events:
+			*batch = append(*batch, event)
+		default:
+			return
+		}
+	}
+}
+
+func (v *ViewRecorder) flush(ctx context.Context, batch []PageView) {
+	if len(batch) == 0 {
+		return
+	}
+	pageIds := make([]int64, 0, len(batch))
+	spaceIds := make([]uuid.UUID, 0, len(batch))
+	userIds := make([]uuid.UUID, 0, len(batch))
+	viewedAt := make([]time.Time, 0, len(batch))
+	for _, event := range batch {
+		pageIds = append(pageIds, event.PageId)
+		spaceIds = append(spaceIds, event.SpaceId)
+		userIds = append(userIds, event.UserId)
+		viewedAt = append(viewedAt, event.ViewedAt)
+	}
+	_, err := core.GetPool().Exec(ctx, insertPageViews, pageIds, spaceIds, userIds, viewedAt)
+	if err != nil {
+		logger().Error("page view flush failed", zap.Int("events", len(batch)), zap.Error(err))
+	}
+}
@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PageView struct {
+	PageId   int64
+	SpaceId  uuid.UUID
+	UserId   uuid.UUID
+	ViewedAt time.Time
+}
+
+type TimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type DailyViews struct {
+	Day           time.Time `json:"day" db:"day"`
+	Views         int64     `json:"views" db:"views"`
+	UniqueViewers int64     `json:"uniqueViewers" db:"unique_viewers"`
+}
+
+type PageViewStats struct {
+	PageId        int64        `json:"pageId"`
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	Views         int64        `json:"views"`
+	UniqueViewers int64        `json:"uniqueViewers"`
+	LastViewedAt  *time.Time   `json:"lastViewedAt"`
+	Daily         []DailyViews `json:"daily"`
+}
+
+type TopPage struct {
+	PageId        int64     `json:"pageId" db:"page_id"`
+	Title         *string   `json:"title" db:"title"`
+	Type          string    `json:"type" db:"type"`
+	Views         int64     `json:"views" db:"views"`
+	UniqueViewers int64     `json:"uniqueViewers" db:"unique_viewers"`
+	LastViewedAt  time.Time `json:"lastViewedAt" db:"last_viewed_at"`
+}
+
+type RecentPage struct {
+	PageId    int64     `json:"pageId" db:"page_id"`
+	SpaceId   uuid.UUID `json:"spaceId" db:"space_id"`
+	SpaceName string    `json:"spaceName" db:"space_name"`
+	Title     *string   `json:"title" db:"title"`
+	Type      string    `json:"type" db:"type"`
+	ViewedAt  time.Time `json:"viewedAt" db:"viewed_at"`
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+const (
+	defaultRangeDays = 30
+	maxRangeDays     = 366
+	defaultLimit     = 10
+	maxLimit         = 100
+)
+
+// parseTimeRange reads the optional `from`/`to` (RFC3339) or `days` query
+// parameters. Without any of them the last 30 days are used.
+func parseTimeRange(query url.Values, now time.Time) (TimeRange, error) {
+	window := TimeRange{To: now.UTC()}
+
+	if raw := strings.TrimSpace(query.Get("to")); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return window, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		window.To = to.UTC()
+	}
+
+	if raw := strings.TrimSpace(query.Get("from")); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return window, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		window.From = from.UTC()
+	} else {
+		days := defaultRangeDays
+		if raw := strings.TrimSpace(query.Get("days")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 || parsed > maxRangeDays {
+				return window, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+			days = parsed
+		}
+		window.From = window.To.AddDate(0, 0, -days)
+	}
+
+	if !window.From.Before(window.To) {
+		return window, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return window, nil
+}
+
+func parseLimit(query url.Values) (int, error) {
+	raw := strings.TrimSpace(query.Get("limit"))
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, nil
+}
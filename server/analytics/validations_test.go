@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseTimeRangeDefaultsToLastThirtyDays(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	window, err := parseTimeRange(url.Values{}, now)
+	if err != nil {
+		t.Fatalf("expected default range to parse: %v", err)
+	}
+	if !window.To.Equal(now) {
+		t.Fatalf("expected range to end now, got %v", window.To)
+	}
+	if want := now.AddDate(0, 0, -30); !window.From.Equal(want) {
+		t.Fatalf("expected range to start at %v, got %v", want, window.From)
+	}
+}
+
+func TestParseTimeRangeUsesDays(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	window, err := parseTimeRange(url.Values{"days": {"7"}}, now)
+	if err != nil {
+		t.Fatalf("expected days range to parse: %v", err)
+	}
+	if want := now.AddDate(0, 0, -7); !window.From.Equal(want) {
+		t.Fatalf("expected range to start at %v, got %v", want, window.From)
+	}
+}
+
+func TestParseTimeRangeRejectsInvertedRange(t *testing.T) {
+	query := url.Values{
+		"from": {"2024-05-10T00:00:00Z"},
+		"to":   {"2024-05-01T00:00:00Z"},
+	}
+	if _, err := parseTimeRange(query, time.Now()); err == nil {
+		t.Fatalf("expected inverted range to be rejected")
+	}
+}
+
+func TestParseLimitCapsLargeValues(t *testing.T) {
+	limit, err := parseLimit(url.Values{"limit": {"5000"}})
+	if err != nil {
+		t.Fatalf("expected limit to parse: %v", err)
+	}
+	if limit != maxLimit {
+		t.Fatalf("expected limit %d, got %d", maxLimit, limit)
+	}
+	if _, err := parseLimit(url.Values{"limit": {"-1"}}); err == nil {
+		t.Fatalf("expected negative limit to be rejected")
+	}
+}
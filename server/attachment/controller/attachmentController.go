@@ -51,7 +51,7 @@ func uploadAttachment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Same as editor getDocumentToEdit: must be able to edit the page (editor role or higher).
-	if !core.ValidateUserPagePermission(pageIDStr, ownerID, "edit") {
+	if !core.ValidateUserPagePermission(r.Context(), pageIDStr, ownerID, "edit") {
 		render.Status(r, http.StatusForbidden)
 		render.Render(w, r, core.NewFailedResponse(http.StatusForbidden, core.FAILURE, "no permission to upload for this page", ""))
 		return
@@ -182,7 +182,7 @@ func downloadAttachment(w http.ResponseWriter, r *http.Request) {
 
 	// Same as editor getDocumentToView: viewer (or higher) on the page may download.
 	pageIDStr := strconv.FormatInt(rec.PageID, 10)
-	if !core.ValidateUserPagePermission(pageIDStr, ownerID, "view") {
+	if !core.ValidateUserPagePermission(r.Context(), pageIDStr, ownerID, "view") {
 		render.Status(r, http.StatusForbidden)
 		render.Render(w, r, core.NewFailedResponse(http.StatusForbidden, core.FAILURE, "no permission to download this attachment", ""))
 		return
@@ -91,32 +91,10 @@ func SaveAttachment(ctx context.Context, pageID int64, createdBy, originalFilena
 	}
 
 	display := SanitizeDisplayName(originalFilename)
-	onDisk := diskFileName(originalFilename)
-	relPath := core.AttachmentStoragePath(onDisk)
-
-	if err := ensureAttachmentsDir(); err != nil {
-		return nil, err
-	}
-
-	fullPath, err := core.ResolveUploadPath(relPath)
+	relPath, fullPath, err := WriteAttachmentFile(originalFilename, data)
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Create(fullPath)
-	if err != nil {
-		core.Logger.Error("attachment: create file: " + err.Error())
-		return nil, fmt.Errorf("failed to store file")
-	}
-	_, werr := f.Write(data)
-	cerr := f.Close()
-	if werr != nil {
-		_ = os.Remove(fullPath)
-		return nil, werr
-	}
-	if cerr != nil {
-		_ = os.Remove(fullPath)
-		return nil, cerr
-	}
 
 	size := int64(len(data))
 	pool := core.GetPool()
@@ -189,6 +167,36 @@ ORDER BY created_at DESC, id DESC`
 	return records, nil
 }
 
+// WriteAttachmentFile stores bytes under a fresh disk name and returns the
+// storage_path to record in core.attachment along with the absolute path.
+func WriteAttachmentFile(originalFilename string, data []byte) (string, string, error) {
+	relPath := core.AttachmentStoragePath(diskFileName(originalFilename))
+	if err := ensureAttachmentsDir(); err != nil {
+		return "", "", err
+	}
+
+	fullPath, err := core.ResolveUploadPath(relPath)
+	if err != nil {
+		return "", "", err
+	}
+	f, err := os.Create(fullPath)
+	if err != nil {
+		core.Logger.Error("attachment: create file: " + err.Error())
+		return "", "", fmt.Errorf("failed to store file")
+	}
+	_, werr := f.Write(data)
+	cerr := f.Close()
+	if werr != nil {
+		_ = os.Remove(fullPath)
+		return "", "", werr
+	}
+	if cerr != nil {
+		_ = os.Remove(fullPath)
+		return "", "", cerr
+	}
+	return relPath, fullPath, nil
+}
+
 // ReadAttachmentBytes loads file bytes from disk using storage_path relative to process cwd.
 func ReadAttachmentBytes(storagePath string) ([]byte, error) {
 	relPath, err := core.NormalizeAttachmentStoragePath(storagePath)
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+// spaceFilter parses the request filters and pins them to the space in the
+// URL after checking the caller may read that space's audit trail.
+func spaceFilter(w http.ResponseWriter, r *http.Request) (Filter, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return Filter{}, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return Filter{}, false
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return Filter{}, false
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW_AUDIT) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return Filter{}, false
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return Filter{}, false
+	}
+	filter.SpaceId = &spaceID
+	return filter, true
+}
+
+func listSpaceAudit(w http.ResponseWriter, r *http.Request) {
+	filter, ok := spaceFilter(w, r)
+	if !ok {
+		return
+	}
+	page, err := listRecords(filter)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, page)
+}
+
+func exportSpaceAudit(w http.ResponseWriter, r *http.Request) {
+	filter, ok := spaceFilter(w, r)
+	if !ok {
+		return
+	}
+	sendCSV(w, r, filter, fmt.Sprintf("audit-%s.csv", filter.SpaceId.String()))
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/space/{spaceId}", listSpaceAudit)
+	r.Get("/space/{spaceId}/export", exportSpaceAudit)
+	return r
+}
+
+type AdminController struct {
+	config Config
+}
+
+func NewAdminController(config Config) *AdminController {
+	return &AdminController{config: config}
+}
+
+func (a *AdminController) Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/logs", a.listLogs)
+	r.Get("/logs/export", a.exportLogs)
+	r.Get("/verify", a.verify)
+	return r
+}
+
+func (a *AdminController) ensureEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if !a.config.AdminEnabled || a.config.AdminToken == "" {
+		core.SendFailedReponse(w, r, http.StatusNotFound, "audit admin routes are disabled")
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Audit-Admin-Token")), []byte(a.config.AdminToken)) != 1 {
+		core.SendFailedReponse(w, r, http.StatusForbidden, "audit admin access denied")
+		return false
+	}
+	return true
+}
+
+func (a *AdminController) listLogs(w http.ResponseWriter, r *http.Request) {
+	if !a.ensureEnabled(w, r) {
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := listRecords(filter)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, page)
+}
+
+func (a *AdminController) exportLogs(w http.ResponseWriter, r *http.Request) {
+	if !a.ensureEnabled(w, r) {
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	sendCSV(w, r, filter, "audit.csv")
+}
+
+func (a *AdminController) verify(w http.ResponseWriter, r *http.Request) {
+	if !a.ensureEnabled(w, r) {
+		return
+	}
+	result, err := verifyLog(r.Context())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, result)
+}
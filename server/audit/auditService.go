@@ -0,0 +1,296 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+	maxExportRows    = 50000
+)
+
+func init() {
+	core.RelationAuditor = recordRelationChange
+}
+
+// Record appends an entry on behalf of the user making the request. Audit
+// failures are logged and never fail the audited action.
+func Record(r *http.Request, entry Entry) {
+	write(context.Background(), metaFromRequest(r), entry)
+}
+
+// RecordSystem appends an entry that has no request behind it, such as a
+// background job.
+func RecordSystem(entry Entry) {
+	write(context.Background(), Meta{}, entry)
+}
+
+func recordRelationChange(actorId *uuid.UUID, action string, entity string, entityId string, subjectId string, subject string, relation string) {
+	auditAction := ActionRelationWritten
+	if action == "delete" {
+		auditAction = ActionRelationDeleted
+	}
+	entry := Entry{
+		Action:     auditAction,
+		TargetType: entity,
+		TargetId:   entityId,
+	}
+	if entity == "space" {
+		if spaceId, err := uuid.Parse(entityId); err == nil {
+			entry.SpaceId = &spaceId
+		}
+	}
+	tuple := map[string]string{"subject": subject, "subjectId": subjectId, "relation": relation}
+	if auditAction == ActionRelationDeleted {
+		entry.Before = tuple
+	} else {
+		entry.After = tuple
+	}
+	// only the id is known here: the relay may apply the change after the
+	// actor's request is over
+	write(context.Background(), Meta{ActorId: actorId}, entry)
+}
+
+func metaFromRequest(r *http.Request) Meta {
+	meta := Meta{
+		IP:        clientIP(r, trustedProxies()),
+		UserAgent: r.UserAgent(),
+	}
+	user, err := core.GetUserInfo(r.Context())
+	if err == nil && user.AId != "" {
+		if actorId, err := uuid.Parse(user.AId); err == nil {
+			meta.ActorId = &actorId
+		}
+		meta.ActorName = user.Name
+	}
+	return meta
+}
+
+// clientIP is the address the request came from. Forwarding headers are only
+// believed when the request came through one of the trusted proxies, and then
+// X-Forwarded-For is read from the right, skipping the proxies themselves, so
+// a client can't put an address of its choosing in front.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+	if !isTrustedProxy(remote, trusted) {
+		return remote
+	}
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if i == 0 || !isTrustedProxy(hop, trusted) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return remote
+}
+
+func isTrustedProxy(address string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func marshalState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func write(ctx context.Context, meta Meta, entry Entry) {
+	if err := insertRecord(ctx, meta, entry); err != nil {
+		logger().Error("unable to write audit log",
+			zap.String("action", entry.Action),
+			zap.String("target_type", entry.TargetType),
+			zap.String("target_id", entry.TargetId),
+			zap.Error(err),
+		)
+	}
+}
+
+func insertRecord(ctx context.Context, meta Meta, entry Entry) error {
+	before, err := marshalState(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalState(entry.After)
+	if err != nil {
+		return err
+	}
+	record := LogRecord{
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		ActorId:    meta.ActorId,
+		ActorName:  optionalString(meta.ActorName),
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetId:   entry.TargetId,
+		SpaceId:    entry.SpaceId,
+		Before:     before,
+		After:      after,
+		IP:         optionalString(meta.IP),
+		UserAgent:  optionalString(meta.UserAgent),
+	}
+
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialise writers so every record links to the one before it.
+	if _, err := tx.Exec(ctx, lockAuditChain); err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx, getLastHash).Scan(&record.PrevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	record.Hash = computeHash(record.PrevHash, record)
+
+	_, err = tx.Exec(ctx, insertAuditLog,
+		record.OccurredAt, record.ActorId, record.ActorName, record.Action, record.TargetType, record.TargetId,
+		record.SpaceId, nullableJSON(record.Before), nullableJSON(record.After), record.IP, record.UserAgent,
+		record.PrevHash, record.Hash,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func nullableJSON(value json.RawMessage) *string {
+	if value == nil {
+		return nil
+	}
+	text := string(value)
+	return &text
+}
+
+func scanRecord(row pgx.Row) (LogRecord, error) {
+	var record LogRecord
+	var before, after *string
+	err := row.Scan(
+		&record.Id, &record.OccurredAt, &record.ActorId, &record.ActorName, &record.Action, &record.TargetType,
+		&record.TargetId, &record.SpaceId, &before, &after, &record.IP, &record.UserAgent, &record.PrevHash, &record.Hash,
+	)
+	if before != nil {
+		record.Before = json.RawMessage(*before)
+	}
+	if after != nil {
+		record.After = json.RawMessage(*after)
+	}
+	return record, err
+}
+
+func queryRecords(ctx context.Context, filter Filter, each func(LogRecord) error) error {
+	rows, err := core.GetPool().Query(ctx, listAuditLog,
+		filter.SpaceId, filter.ActorId, filter.Action, filter.TargetType, filter.TargetId,
+		filter.From, filter.To, filter.Cursor, filter.Limit,
+	)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		if err := each(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return nil
+}
+
+func listRecords(filter Filter) (Page, error) {
+	page := Page{Items: []LogRecord{}}
+	err := queryRecords(context.Background(), filter, func(record LogRecord) error {
+		page.Items = append(page.Items, record)
+		return nil
+	})
+	if err != nil {
+		return page, err
+	}
+	if len(page.Items) == filter.Limit {
+		next := strconv.FormatInt(page.Items[len(page.Items)-1].Id, 10)
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+func verifyLog(ctx context.Context) (VerifyResult, error) {
+	verifier := chainVerifier{}
+	rows, err := core.GetPool().Query(ctx, listAuditLogForVerify)
+	if err != nil {
+		logger().Error(err.Error())
+		return VerifyResult{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			logger().Error(err.Error())
+			return VerifyResult{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		if !verifier.add(record) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return VerifyResult{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return VerifyResult{
+		Checked:    verifier.checked,
+		Valid:      verifier.brokenAt == nil,
+		BrokenAtId: verifier.brokenAt,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPIgnoresHeadersFromUntrustedClients(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "203.0.113.7:5123"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Real-IP", "198.51.100.2")
+	if got := clientIP(r, nil); got != "203.0.113.7" {
+		t.Fatalf("expected the connecting address, got %q", got)
+	}
+}
+
+func TestClientIPReadsForwardedForBehindTrustedProxy(t *testing.T) {
+	trusted := parseTrustedProxies("10.0.0.0/8, 192.0.2.10, not-an-ip")
+	if len(trusted) != 2 {
+		t.Fatalf("expected two trusted proxies, got %v", trusted)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.2.3:443"
+	// the client forged the first hop; the proxies appended the rest
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 192.0.2.10")
+	if got := clientIP(r, trusted); got != "203.0.113.7" {
+		t.Fatalf("expected the address the trusted proxy saw, got %q", got)
+	}
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("X-Real-IP", "203.0.113.8")
+	if got := clientIP(r, trusted); got != "203.0.113.8" {
+		t.Fatalf("expected X-Real-IP from a trusted proxy, got %q", got)
+	}
+}
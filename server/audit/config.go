@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Config struct {
+	AdminEnabled bool
+	AdminToken   string
+}
+
+func LoadConfig() Config {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("AUDIT_ADMIN_ENABLED")))
+	if err != nil {
+		enabled = false
+	}
+	return Config{
+		AdminEnabled: enabled,
+		AdminToken:   strings.TrimSpace(os.Getenv("AUDIT_ADMIN_TOKEN")),
+	}
+}
+
+// trustedProxies are the proxies whose forwarding headers are believed when
+// recording where a request came from, read once from AUDIT_TRUSTED_PROXIES.
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	return parseTrustedProxies(os.Getenv("AUDIT_TRUSTED_PROXIES"))
+})
+
+// parseTrustedProxies reads a comma separated list of addresses and CIDR
+// ranges, skipping entries it can't parse.
+func parseTrustedProxies(value string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+var csvHeader = []string{
+	"id", "occurred_at", "actor_id", "actor_name", "action", "target_type", "target_id", "space_id",
+	"before", "after", "ip_address", "user_agent", "prev_hash", "hash",
+}
+
+// csvRow escapes every cell: actor names, user agents, target ids and the
+// before and after documents all come from users.
+func csvRow(record LogRecord) []string {
+	return core.CSVRow([]string{
+		fmt.Sprintf("%d", record.Id),
+		record.OccurredAt.UTC().Format(time.RFC3339Nano),
+		uuidString(record.ActorId),
+		stringValue(record.ActorName),
+		record.Action,
+		record.TargetType,
+		record.TargetId,
+		uuidString(record.SpaceId),
+		string(record.Before),
+		string(record.After),
+		stringValue(record.IP),
+		stringValue(record.UserAgent),
+		record.PrevHash,
+		record.Hash,
+	})
+}
+
+func writeCSV(ctx context.Context, out io.Writer, filter Filter) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	filter.Cursor = 0
+	filter.Limit = maxExportRows
+	err := queryRecords(ctx, filter, func(record LogRecord) error {
+		return writer.Write(csvRow(record))
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func sendCSV(w http.ResponseWriter, r *http.Request, filter Filter, name string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	if err := writeCSV(r.Context(), w, filter); err != nil {
+		logger().Error("audit export failed: " + err.Error())
+	}
+}
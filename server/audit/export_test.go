@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCSVRowEscapesFormulas(t *testing.T) {
+	actorName := `=HYPERLINK("http://evil.example","click")`
+	userAgent := "@curl"
+	row := csvRow(LogRecord{
+		Id:         7,
+		OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ActorName:  &actorName,
+		Action:     ActionMemberAdded,
+		TargetType: "space",
+		TargetId:   "+1",
+		Before:     json.RawMessage(`{"name": "Docs"}`),
+		After:      json.RawMessage(`-1`),
+		UserAgent:  &userAgent,
+		Hash:       "abc",
+	})
+	if row[3] != "'"+actorName {
+		t.Fatalf("expected the actor name to be escaped, got %q", row[3])
+	}
+	if row[6] != "'+1" || row[9] != "'-1" || row[11] != "'@curl" {
+		t.Fatalf("expected user input to be escaped, got %v", row)
+	}
+	if row[0] != "7" || row[8] != `{"name": "Docs"}` || row[13] != "abc" {
+		t.Fatalf("expected other cells to stay as they are, got %v", row)
+	}
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func buildChain(actions ...string) []LogRecord {
+	records := make([]LogRecord, 0, len(actions))
+	prev := ""
+	occurred := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, action := range actions {
+		record := LogRecord{
+			Id:         int64(i + 1),
+			OccurredAt: occurred.Add(time.Duration(i) * time.Minute),
+			Action:     action,
+			TargetType: "space_member",
+			TargetId:   "member",
+			After:      json.RawMessage(`{"role":"editor"}`),
+			PrevHash:   prev,
+		}
+		record.Hash = computeHash(prev, record)
+		prev = record.Hash
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestVerifyChainIntact(t *testing.T) {
+	records := buildChain(ActionMemberAdded, ActionMemberRoleChanged, ActionMemberRemoved)
+	if brokenAt := verifyChain(records); brokenAt != nil {
+		t.Fatalf("expected intact chain, broken at %d", *brokenAt)
+	}
+}
+
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	records := buildChain(ActionMemberAdded, ActionMemberRoleChanged, ActionMemberRemoved)
+	records[1].After = json.RawMessage(`{"role":"admin"}`)
+
+	brokenAt := verifyChain(records)
+	if brokenAt == nil {
+		t.Fatalf("expected tampered record to break the chain")
+	}
+	if *brokenAt != 2 {
+		t.Fatalf("expected chain to break at 2, got %d", *brokenAt)
+	}
+}
+
+func TestVerifyChainDetectsRemovedRecord(t *testing.T) {
+	records := buildChain(ActionMemberAdded, ActionMemberRoleChanged, ActionMemberRemoved)
+	records = append(records[:1], records[2:]...)
+
+	brokenAt := verifyChain(records)
+	if brokenAt == nil || *brokenAt != 3 {
+		t.Fatalf("expected chain to break at 3 after removing a record")
+	}
+}
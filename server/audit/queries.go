@@ -0,0 +1,24 @@
+package audit
+
+const (
+	lockAuditChain = `SELECT pg_advisory_xact_lock(hashtext('core.audit_log'))`
+	getLastHash    = `SELECT hash FROM core.audit_log ORDER BY id DESC LIMIT 1`
+	insertAuditLog = `INSERT INTO core.audit_log (occurred_at, actor_id, actor_name, action, target_type, target_id, space_id, before_state, after_state, ip_address, user_agent, prev_hash, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	auditLogColumns = `id, occurred_at, actor_id, actor_name, action, target_type, target_id, space_id, before_state, after_state, ip_address, user_agent, prev_hash, hash`
+
+	listAuditLog = `SELECT ` + auditLogColumns + `
+FROM core.audit_log
+WHERE ($1::uuid IS NULL OR space_id = $1)
+	AND ($2::uuid IS NULL OR actor_id = $2)
+	AND ($3::text = '' OR action = $3)
+	AND ($4::text = '' OR target_type = $4)
+	AND ($5::text = '' OR target_id = $5)
+	AND ($6::timestamptz IS NULL OR occurred_at >= $6)
+	AND ($7::timestamptz IS NULL OR occurred_at < $7)
+	AND ($8::bigint = 0 OR id < $8)
+ORDER BY id DESC
+LIMIT $9`
+
+	listAuditLogForVerify = `SELECT ` + auditLogColumns + ` FROM core.audit_log ORDER BY id ASC`
+)
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActionMemberAdded          = "space.member_added"
+	ActionMemberRoleChanged    = "space.member_role_changed"
+	ActionMemberRemoved        = "space.member_removed"
+	ActionMemberExpiryChanged  = "space.member_expiry_changed"
+	ActionOwnershipTransferred = "space.ownership_transferred"
+	ActionSpaceArchived        = "space.archived"
+	ActionSpaceUnarchived      = "space.unarchived"
+	ActionSpaceDeleted         = "space.deleted"
+	ActionSpaceRestored        = "space.restored"
+	ActionSpacePurged          = "space.purged"
+	ActionSpaceExported        = "space.exported"
+	ActionSpaceImported        = "space.imported"
+	ActionSpaceSlugChanged     = "space.slug_changed"
+	ActionTokenCreated         = "token.created"
+	ActionTokenRevoked         = "token.revoked"
+	ActionPageRestricted       = "page.restrictions_updated"
+	ActionGroupCreated         = "group.created"
+	ActionGroupUpdated         = "group.updated"
+	ActionGroupMemberAdded     = "group.member_added"
+	ActionGroupMemberRemoved   = "group.member_removed"
+	ActionOrgCreated           = "org.created"
+	ActionOrgUpdated           = "org.updated"
+	ActionOrgMemberAdded       = "org.member_added"
+	ActionOrgMemberRoleChanged = "org.member_role_changed"
+	ActionOrgMemberRemoved     = "org.member_removed"
+	ActionInviteCreated        = "invite.created"
+	ActionInviteAccepted       = "invite.accepted"
+	ActionInviteRejected       = "invite.rejected"
+	ActionInviteRemoved        = "invite.removed"
+	ActionInviteRevoked        = "invite.revoked"
+	ActionInviteResent         = "invite.resent"
+	ActionInviteExpired        = "invite.expired"
+	ActionRelationWritten      = "permission.relation_written"
+	ActionRelationDeleted      = "permission.relation_deleted"
+	ActionEmailRequeued        = "email.requeued"
+
+	ActionSpaceJoinPolicyChanged = "space.join_policy_changed"
+
+	ActionAccessReviewCreated   = "space.access_review_created"
+	ActionAccessReviewDecided   = "space.access_review_decided"
+	ActionAccessRequested       = "space.access_requested"
+	ActionAccessRequestApproved = "space.access_request_approved"
+	ActionAccessRequestDeclined = "space.access_request_declined"
+
+	ActionSpaceGitLinked           = "space.git_linked"
+	ActionSpaceGitUnlinked         = "space.git_unlinked"
+	ActionSpaceGitConflictResolved = "space.git_conflict_resolved"
+)
+
+// Entry is what callers pass to Record. Before and After are marshalled to
+// JSON as-is.
+type Entry struct {
+	Action     string
+	TargetType string
+	TargetId   string
+	SpaceId    *uuid.UUID
+	Before     interface{}
+	After      interface{}
+}
+
+type Meta struct {
+	ActorId   *uuid.UUID
+	ActorName string
+	IP        string
+	UserAgent string
+}
+
+type LogRecord struct {
+	Id         int64           `json:"id" db:"id"`
+	OccurredAt time.Time       `json:"occurredAt" db:"occurred_at"`
+	ActorId    *uuid.UUID      `json:"actorId" db:"actor_id"`
+	ActorName  *string         `json:"actorName" db:"actor_name"`
+	Action     string          `json:"action" db:"action"`
+	TargetType string          `json:"targetType" db:"target_type"`
+	TargetId   string          `json:"targetId" db:"target_id"`
+	SpaceId    *uuid.UUID      `json:"spaceId" db:"space_id"`
+	Before     json.RawMessage `json:"before" db:"before_state"`
+	After      json.RawMessage `json:"after" db:"after_state"`
+	IP         *string         `json:"ipAddress" db:"ip_address"`
+	UserAgent  *string         `json:"userAgent" db:"user_agent"`
+	PrevHash   string          `json:"prevHash" db:"prev_hash"`
+	Hash       string          `json:"hash" db:"hash"`
+}
+
+type Filter struct {
+	SpaceId    *uuid.UUID
+	ActorId    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetId   string
+	From       *time.Time
+	To         *time.Time
+	Cursor     int64
+	Limit      int
+}
+
+type Page struct {
+	Items      []LogRecord `json:"items"`
+	NextCursor *string     `json:"nextCursor"`
+}
+
+type VerifyResult struct {
+	Checked    int64  `json:"checked"`
+	Valid      bool   `json:"valid"`
+	BrokenAtId *int64 `json:"brokenAtId,omitempty"`
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+func invalidInput() error {
+	return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+}
+
+// parseFilter reads the query string filters shared by the list and export
+// endpoints.
+func parseFilter(query url.Values) (Filter, error) {
+	filter := Filter{
+		Action:     strings.TrimSpace(query.Get("action")),
+		TargetType: strings.TrimSpace(query.Get("targetType")),
+		TargetId:   strings.TrimSpace(query.Get("targetId")),
+		Limit:      defaultPageLimit,
+	}
+
+	if raw := strings.TrimSpace(query.Get("spaceId")); raw != "" {
+		spaceId, err := uuid.Parse(raw)
+		if err != nil {
+			return filter, invalidInput()
+		}
+		filter.SpaceId = &spaceId
+	}
+	if raw := strings.TrimSpace(query.Get("actorId")); raw != "" {
+		actorId, err := uuid.Parse(raw)
+		if err != nil {
+			return filter, invalidInput()
+		}
+		filter.ActorId = &actorId
+	}
+	if raw := strings.TrimSpace(query.Get("from")); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, invalidInput()
+		}
+		filter.From = &from
+	}
+	if raw := strings.TrimSpace(query.Get("to")); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, invalidInput()
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, invalidInput()
+	}
+	if raw := strings.TrimSpace(query.Get("cursor")); raw != "" {
+		cursor, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || cursor <= 0 {
+			return filter, invalidInput()
+		}
+		filter.Cursor = cursor
+	}
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return filter, invalidInput()
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -3,9 +3,12 @@ package comment
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
+	"github.com/durgakiran/beskar/activity"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 var svc *CommentService
@@ -14,6 +17,24 @@ func init() {
 	svc = NewCommentService()
 }
 
+func recordCommentActivity(user core.UserInfo, docId string, activityType activity.Type, data map[string]interface{}) {
+	actorId, err := uuid.Parse(user.AId)
+	if err != nil {
+		return
+	}
+	pageId, err := strconv.ParseInt(docId, 10, 64)
+	if err != nil {
+		return
+	}
+	activity.Record(activity.Event{
+		PageId:    activity.PageRef(pageId),
+		ActorId:   actorId,
+		ActorName: user.Name,
+		Type:      activityType,
+		Data:      data,
+	})
+}
+
 func listThreads(w http.ResponseWriter, r *http.Request) {
 	docId := chi.URLParam(r, "docId")
 	includeResolved := r.URL.Query().Get("includeResolved") == "true"
@@ -26,7 +47,7 @@ func listThreads(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Permission: any user with page VIEW can view comments.
-	allowed, err := core.CheckPermission("page", docId, "user", user.AId, core.PAGE_VIEW)
+	allowed, err := core.CheckPermission(ctx, "page", docId, "user", user.AId, core.PAGE_VIEW)
 	if err != nil || !allowed {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Forbidden")
 		return
@@ -58,7 +79,7 @@ func createThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allowed, _ := core.CheckPermission("page", docId, "user", user.AId, core.PAGE_ADD_COMMENT)
+	allowed, _ := core.CheckPermission(ctx, "page", docId, "user", user.AId, core.PAGE_ADD_COMMENT)
 	if !allowed {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Forbidden")
 		return
@@ -83,6 +104,14 @@ func createThread(w http.ResponseWriter, r *http.Request) {
 	}
 
 	Hub.Publish(EventThreadCreated, docId, thread)
+	recordCommentActivity(user, docId, activity.CommentCreated, map[string]interface{}{
+		"threadId":   thread.ID,
+		"quotedText": thread.Anchor.QuotedText,
+	})
+	if len(thread.Replies) > 0 {
+		first := thread.Replies[0]
+		notifyMentions(ctx, user, docId, thread.ID, first, parseMentions(first.Body))
+	}
 	core.SendSuccessResponse(w, r, http.StatusCreated, thread)
 }
 
@@ -107,6 +136,10 @@ func resolveThread(w http.ResponseWriter, r *http.Request) {
 	}
 
 	Hub.Publish(EventThreadResolved, thread.DocumentID, thread)
+	recordCommentActivity(user, thread.DocumentID, activity.CommentResolved, map[string]interface{}{
+		"threadId":   thread.ID,
+		"quotedText": thread.Anchor.QuotedText,
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, thread)
 }
 
@@ -224,6 +257,14 @@ func createReply(w http.ResponseWriter, r *http.Request) {
 	// Fetch documentId from thread
 	// In a real scenario we'd query it or return it from the service.
 	// For now we'll just not send it via SSE if we don't have it, but wait! The client filters by DocumentID.
+	if docId, err := svc.ThreadDocumentID(ctx, threadId); err == nil {
+		recordCommentActivity(user, docId, activity.CommentReplied, map[string]interface{}{
+			"threadId": threadId,
+			"replyId":  reply.ID,
+		})
+		mentioned := notifyMentions(ctx, user, docId, threadId, reply, parseMentions(reply.Body))
+		notifyReply(ctx, user, docId, threadId, reply, mentioned)
+	}
 
 	core.SendSuccessResponse(w, r, http.StatusCreated, reply)
 }
@@ -250,7 +291,7 @@ func editReply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reply, err := svc.EditReply(ctx, replyId, req.Body, req.AttachmentIDs, user.AId)
+	reply, previous, err := svc.EditReply(ctx, replyId, req.Body, req.AttachmentIDs, user.AId)
 	if err != nil {
 		if err.Error() == "forbidden" {
 			core.SendFailedReponse(w, r, http.StatusForbidden, "Forbidden")
@@ -260,6 +301,9 @@ func editReply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if docId, err := svc.ThreadDocumentID(ctx, reply.ThreadID); err == nil {
+		notifyMentions(ctx, user, docId, reply.ThreadID, reply, newMentions(previous, reply.Body))
+	}
 	core.SendSuccessResponse(w, r, http.StatusOK, reply)
 }
 
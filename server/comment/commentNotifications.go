@@ -0,0 +1,274 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	commentReplyCategory   = notification.CategoryCommentReply
+	commentMentionCategory = notification.CategoryMention
+	replyExcerptLength     = 280
+)
+
+// mentionPattern matches a mention as the comment box writes it,
+// @[Display Name](user:<user id>).
+var mentionPattern = regexp.MustCompile(`@\[([^\]]*)\]\(user:([0-9a-fA-F-]{36})\)`)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+// excerpt shortens a comment for notifications, on a rune boundary, with
+// mentions shown as @name.
+func excerpt(body string) string {
+	body = strings.TrimSpace(mentionPattern.ReplaceAllString(body, "@$1"))
+	runes := []rune(body)
+	if len(runes) <= replyExcerptLength {
+		return body
+	}
+	return strings.TrimSpace(string(runes[:replyExcerptLength])) + "…"
+}
+
+// parseMentions returns the users mentioned in body, each once, in the order
+// they are first mentioned.
+func parseMentions(body string) []uuid.UUID {
+	mentioned := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		userId, err := uuid.Parse(match[2])
+		if err != nil || seen[userId] {
+			continue
+		}
+		seen[userId] = true
+		mentioned = append(mentioned, userId)
+	}
+	return mentioned
+}
+
+// newMentions returns the users mentioned in body but not in previous, so
+// editing a comment only notifies the people it adds.
+func newMentions(previous string, body string) []uuid.UUID {
+	before := make(map[uuid.UUID]bool)
+	for _, userId := range parseMentions(previous) {
+		before[userId] = true
+	}
+	added := make([]uuid.UUID, 0)
+	for _, userId := range parseMentions(body) {
+		if !before[userId] {
+			added = append(added, userId)
+		}
+	}
+	return added
+}
+
+// commentPage is the page a comment is on, as notifications show it.
+type commentPage struct {
+	spaceId uuid.UUID
+	pageId  int64
+	title   string
+	path    string
+}
+
+func loadCommentPage(ctx context.Context, docId string) (commentPage, error) {
+	var page commentPage
+	if err := core.GetPool().QueryRow(ctx, FETCH_PAGE_CONTEXT, docId).Scan(&page.spaceId, &page.title); err != nil {
+		return page, err
+	}
+	pageId, err := strconv.ParseInt(docId, 10, 64)
+	if err != nil {
+		return page, err
+	}
+	page.pageId = pageId
+	if page.title == "" {
+		page.title = "Untitled"
+	}
+	page.path = fmt.Sprintf("/space/%s/view/%d", page.spaceId, pageId)
+	return page, nil
+}
+
+func authorName(author core.UserInfo) string {
+	if name := strings.TrimSpace(author.Name); name != "" {
+		return name
+	}
+	return "Someone"
+}
+
+// notifyMentions tells the users mentioned in a comment, other than its
+// author, that they were mentioned. Users who can't view the page are left
+// out. It returns who was told, so the reply notification can skip them.
+func notifyMentions(ctx context.Context, author core.UserInfo, docId string, threadId string, reply CommentReply, mentioned []uuid.UUID) map[uuid.UUID]bool {
+	notified := make(map[uuid.UUID]bool)
+	if len(mentioned) == 0 {
+		return notified
+	}
+	if err := sendMentionNotifications(ctx, author, docId, threadId, reply, mentioned, notified); err != nil {
+		logger().Error("failed to notify comment mentions", zap.String("thread_id", threadId), zap.Error(err))
+	}
+	return notified
+}
+
+func sendMentionNotifications(ctx context.Context, author core.UserInfo, docId string, threadId string, reply CommentReply, mentioned []uuid.UUID, notified map[uuid.UUID]bool) error {
+	authorId, err := uuid.Parse(author.AId)
+	if err != nil {
+		return err
+	}
+	recipients := make([]uuid.UUID, 0, len(mentioned))
+	ids := make([]string, 0, len(mentioned))
+	for _, userId := range mentioned {
+		if userId == authorId || !core.ValidateUserPagePermission(ctx, docId, userId, core.PAGE_VIEW) {
+			continue
+		}
+		recipients = append(recipients, userId)
+		ids = append(ids, userId.String())
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+	page, err := loadCommentPage(ctx, docId)
+	if err != nil {
+		return err
+	}
+	profiles, err := core.GetUserProfiles(ids)
+	if err != nil {
+		return err
+	}
+
+	config := notification.LoadConfig()
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	name := authorName(author)
+	body := excerpt(reply.Body)
+	for _, userId := range recipients {
+		n := inbox.Notification{
+			UserId:    userId,
+			Category:  commentMentionCategory,
+			Type:      inbox.CommentMentioned,
+			Title:     fmt.Sprintf("%s mentioned you on %s", name, page.title),
+			Body:      body,
+			Url:       page.path,
+			SpaceId:   &page.spaceId,
+			PageId:    &page.pageId,
+			ActorId:   &authorId,
+			ActorName: name,
+			Data:      map[string]interface{}{"threadId": threadId, "replyId": reply.ID},
+		}
+		if profile := profiles[userId.String()]; strings.TrimSpace(profile.Email) != "" {
+			n.Email = &notification.EnqueueEmailRequest{
+				MessageKey:  fmt.Sprintf("%s:%s:%s", notification.TemplateCommentMention, reply.ID, userId),
+				Category:    commentMentionCategory,
+				TemplateKey: notification.TemplateCommentMention,
+				Recipient: notification.EmailRecipient{
+					UserID: &userId,
+					Email:  profile.Email,
+					Name:   profile.Name,
+				},
+				TemplateData: map[string]any{
+					"author_name": name,
+					"page_title":  page.title,
+					"comment":     body,
+					"page_url":    appURL + page.path,
+					"app_url":     appURL + "/",
+				},
+				Priority: notification.PriorityNormal,
+			}
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+		notified[userId] = true
+	}
+	return nil
+}
+
+// notifyReply tells everyone taking part in the thread, other than the
+// author and those the reply mentions, that it got a new reply. Participants
+// who can no longer view the page are left out.
+func notifyReply(ctx context.Context, author core.UserInfo, docId string, threadId string, reply CommentReply, mentioned map[uuid.UUID]bool) {
+	if err := sendReplyNotifications(ctx, author, docId, threadId, reply, mentioned); err != nil {
+		logger().Error("failed to notify comment reply", zap.String("thread_id", threadId), zap.Error(err))
+	}
+}
+
+func sendReplyNotifications(ctx context.Context, author core.UserInfo, docId string, threadId string, reply CommentReply, mentioned map[uuid.UUID]bool) error {
+	rows, err := core.GetPool().Query(ctx, LIST_THREAD_PARTICIPANTS, threadId, author.AId)
+	if err != nil {
+		return err
+	}
+	participants, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(participants) == 0 {
+		return err
+	}
+	page, err := loadCommentPage(ctx, docId)
+	if err != nil {
+		return err
+	}
+	profiles, err := core.GetUserProfiles(participants)
+	if err != nil {
+		return err
+	}
+	authorId, err := uuid.Parse(author.AId)
+	if err != nil {
+		return err
+	}
+
+	config := notification.LoadConfig()
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	name := authorName(author)
+	body := excerpt(reply.Body)
+	for _, participant := range participants {
+		userId, err := uuid.Parse(participant)
+		if err != nil || mentioned[userId] {
+			continue
+		}
+		if !core.ValidateUserPagePermission(ctx, docId, userId, core.PAGE_VIEW) {
+			continue
+		}
+		n := inbox.Notification{
+			UserId:    userId,
+			Category:  commentReplyCategory,
+			Type:      inbox.CommentReplied,
+			Title:     fmt.Sprintf("%s replied on %s", name, page.title),
+			Body:      body,
+			Url:       page.path,
+			SpaceId:   &page.spaceId,
+			PageId:    &page.pageId,
+			ActorId:   &authorId,
+			ActorName: name,
+			Data:      map[string]interface{}{"threadId": threadId, "replyId": reply.ID},
+		}
+		if profile := profiles[participant]; strings.TrimSpace(profile.Email) != "" && body != "" {
+			n.Email = &notification.EnqueueEmailRequest{
+				MessageKey:  fmt.Sprintf("%s:%s:%s", notification.TemplateCommentReply, reply.ID, userId),
+				Category:    commentReplyCategory,
+				TemplateKey: notification.TemplateCommentReply,
+				Recipient: notification.EmailRecipient{
+					UserID: &userId,
+					Email:  profile.Email,
+					Name:   profile.Name,
+				},
+				TemplateData: map[string]any{
+					"author_name": name,
+					"page_title":  page.title,
+					"reply":       body,
+					"page_url":    appURL + page.path,
+					"app_url":     appURL + "/",
+				},
+				Priority: notification.PriorityNormal,
+			}
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package comment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseMentions(t *testing.T) {
+	ada, kiran := uuid.New(), uuid.New()
+	body := "@[Ada](user:" + ada.String() + ") and @[Kiran](user:" + kiran.String() + ") please look, @[Ada](user:" + ada.String() + ")"
+	if got := parseMentions(body); !reflect.DeepEqual(got, []uuid.UUID{ada, kiran}) {
+		t.Fatalf("expected each user once in order, got %v", got)
+	}
+	if got := parseMentions("mail ada@example.com or @[Ada](user:not-a-user-id)"); len(got) != 0 {
+		t.Fatalf("expected plain text not to mention anyone, got %v", got)
+	}
+}
+
+func TestNewMentionsOnlyReturnsAddedUsers(t *testing.T) {
+	ada, kiran := uuid.New(), uuid.New()
+	previous := "@[Ada](user:" + ada.String() + ") thoughts?"
+	body := previous + " cc @[Kiran](user:" + kiran.String() + ")"
+	if got := newMentions(previous, body); !reflect.DeepEqual(got, []uuid.UUID{kiran}) {
+		t.Fatalf("expected only the added mention, got %v", got)
+	}
+	if got := newMentions(body, previous); len(got) != 0 {
+		t.Fatalf("expected removing a mention to notify no one, got %v", got)
+	}
+}
+
+func TestExcerptShowsMentionsByName(t *testing.T) {
+	body := "  @[Ada](user:" + uuid.NewString() + ") can you check?  "
+	if got := excerpt(body); got != "@Ada can you check?" {
+		t.Fatalf("unexpected excerpt %q", got)
+	}
+	long := excerpt(strings.Repeat("a", replyExcerptLength+10))
+	if !strings.HasSuffix(long, "…") || len([]rune(long)) != replyExcerptLength+1 {
+		t.Fatalf("expected a shortened excerpt, got %d runes", len([]rune(long)))
+	}
+}
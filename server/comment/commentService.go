@@ -400,7 +400,7 @@ func (s *CommentService) ResolveThread(ctx context.Context, threadId, userId str
 	if createdBy != nil && *createdBy == userId {
 		canResolve = true
 	} else {
-		allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_EDIT)
+		allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_EDIT)
 		if allowed {
 			canResolve = true
 		}
@@ -457,7 +457,7 @@ func (s *CommentService) UnresolveThread(ctx context.Context, threadId, userId s
 	if createdBy != nil && *createdBy == userId {
 		canUnresolve = true
 	} else {
-		allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_EDIT)
+		allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_EDIT)
 		if allowed {
 			canUnresolve = true
 		}
@@ -510,7 +510,7 @@ func (s *CommentService) DeleteThread(ctx context.Context, threadId, userId stri
 	}
 
 	// Wait, we need the space_id to check space delete permisson per rules!
-	// core.CheckPermission("space", spaceId, "user", userId, SPACE_EDIT)
+	// core.CheckPermission(ctx, "space", spaceId, "user", userId, SPACE_EDIT)
 	// Actually, the requirements say admin+, which implies SPACE_EDIT. Let's do a strict check on space
 	// Assuming docId is the page id, we have to fetch spaceId from page_doc_map
 	// For simplicity, let's just do PAGE_DELETE which inherits from space
@@ -518,7 +518,7 @@ func (s *CommentService) DeleteThread(ctx context.Context, threadId, userId stri
 	if createdBy != nil && *createdBy == userId {
 		canDelete = true
 	} else {
-		allowed, err := core.CheckPermission("page", docId, "user", userId, core.PAGE_DELETE)
+		allowed, err := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_DELETE)
 		if err == nil && allowed {
 			canDelete = true
 		}
@@ -550,7 +550,7 @@ func (s *CommentService) OrphanThread(ctx context.Context, threadId, userId stri
 		return CommentThread{}, err
 	}
 
-	allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_ADD_COMMENT)
+	allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_ADD_COMMENT)
 	if !allowed {
 		return CommentThread{}, fmt.Errorf("forbidden")
 	}
@@ -590,7 +590,7 @@ func (s *CommentService) CreateReply(ctx context.Context, threadId, body string,
 	}
 	_ = createdBy
 
-	allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_ADD_COMMENT)
+	allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_ADD_COMMENT)
 	if !allowed {
 		return CommentReply{}, fmt.Errorf("forbidden")
 	}
@@ -634,11 +634,22 @@ func (s *CommentService) CreateReply(ctx context.Context, threadId, body string,
 	return reply, nil
 }
 
-func (s *CommentService) EditReply(ctx context.Context, replyId, body string, attachmentIDs []string, userId string) (CommentReply, error) {
+// ThreadDocumentID returns the page a thread belongs to.
+func (s *CommentService) ThreadDocumentID(ctx context.Context, threadId string) (string, error) {
+	var createdBy *string
+	var docId string
+	err := core.GetPool().QueryRow(ctx, FETCH_THREAD_BASIC, threadId).Scan(&createdBy, &docId)
+	return docId, err
+}
+
+// EditReply changes the body and attachments of the user's own reply. It
+// also returns the body the reply had, so callers can tell what the edit
+// added.
+func (s *CommentService) EditReply(ctx context.Context, replyId, body string, attachmentIDs []string, userId string) (CommentReply, string, error) {
 	connPool := core.GetPool()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	defer conn.Release()
 
@@ -646,30 +657,35 @@ func (s *CommentService) EditReply(ctx context.Context, replyId, body string, at
 	var docId string
 	err = conn.QueryRow(ctx, FETCH_REPLY_BASIC, replyId).Scan(&authorId, &docId)
 	if err != nil {
-		return CommentReply{}, fmt.Errorf("not found")
+		return CommentReply{}, "", fmt.Errorf("not found")
 	}
 
-	allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_ADD_COMMENT)
+	allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_ADD_COMMENT)
 	if !allowed {
-		return CommentReply{}, fmt.Errorf("forbidden")
+		return CommentReply{}, "", fmt.Errorf("forbidden")
 	}
 
 	if authorId == nil || *authorId != userId {
-		return CommentReply{}, fmt.Errorf("forbidden")
+		return CommentReply{}, "", fmt.Errorf("forbidden")
 	}
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	defer tx.Rollback(ctx)
 
+	var previous string
+	if err := tx.QueryRow(ctx, LOCK_REPLY_BODY, replyId).Scan(&previous); err != nil {
+		return CommentReply{}, "", err
+	}
+
 	var reply CommentReply
 	var tID string
 	var aID *string
 	err = tx.QueryRow(ctx, UPDATE_REPLY, body, replyId, userId).Scan(&reply.ID, &tID, &aID, &reply.Body, &reply.EditedAt, &reply.CreatedAt)
 	if err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	reply.ThreadID = tID
 	reply.Attachments = []CommentAttachment{}
@@ -677,18 +693,18 @@ func (s *CommentService) EditReply(ctx context.Context, replyId, body string, at
 		reply.Author = &AuthorInfo{ID: *aID}
 	}
 	if _, err := tx.Exec(ctx, DELETE_REPLY_ATTACHMENTS, replyId); err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	if err := attachReplyAttachments(ctx, tx, replyId, attachmentIDs); err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	if err := tx.Commit(ctx); err != nil {
-		return CommentReply{}, err
+		return CommentReply{}, "", err
 	}
 	if len(attachmentIDs) > 0 {
 		attachmentsByReply, err := loadReplyAttachments(ctx, conn, []string{reply.ID})
 		if err != nil {
-			return CommentReply{}, err
+			return CommentReply{}, "", err
 		}
 		reply.Attachments = attachmentsByReply[reply.ID]
 	}
@@ -696,10 +712,10 @@ func (s *CommentService) EditReply(ctx context.Context, replyId, body string, at
 	t := CommentThread{Replies: []CommentReply{reply}}
 	hydrated, _ := hydrateUsers([]CommentThread{t})
 	if len(hydrated) > 0 && len(hydrated[0].Replies) > 0 {
-		return hydrated[0].Replies[0], nil
+		return hydrated[0].Replies[0], previous, nil
 	}
 
-	return reply, nil
+	return reply, previous, nil
 }
 
 func (s *CommentService) DeleteReply(ctx context.Context, replyId, userId string) error {
@@ -720,7 +736,7 @@ func (s *CommentService) DeleteReply(ctx context.Context, replyId, userId string
 		return err
 	}
 
-	allowed, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_ADD_COMMENT)
+	allowed, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_ADD_COMMENT)
 	if !allowed {
 		return fmt.Errorf("forbidden")
 	}
@@ -729,7 +745,7 @@ func (s *CommentService) DeleteReply(ctx context.Context, replyId, userId string
 	if authorId != nil && *authorId == userId {
 		canDelete = true
 	} else {
-		allowedDelete, _ := core.CheckPermission("page", docId, "user", userId, core.PAGE_DELETE)
+		allowedDelete, _ := core.CheckPermission(ctx, "page", docId, "user", userId, core.PAGE_DELETE)
 		if allowedDelete {
 			canDelete = true
 		}
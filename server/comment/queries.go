@@ -77,6 +77,10 @@ const (
 	FETCH_THREAD_BASIC = `
 		SELECT created_by, document_id FROM core.comment_threads WHERE id = $1`
 
+	// LOCK_REPLY_BODY fetches the body a reply has before it is edited
+	LOCK_REPLY_BODY = `
+		SELECT body FROM core.comment_replies WHERE id = $1 FOR UPDATE`
+
 	// UPDATE_REPLY edits a reply if the requester is the original author
 	UPDATE_REPLY = `
 		UPDATE core.comment_replies 
@@ -88,6 +92,19 @@ const (
 	DELETE_REPLY = `
 		DELETE FROM core.comment_replies WHERE id = $1`
 
+	// LIST_THREAD_PARTICIPANTS fetches the thread's creator and everyone who replied, except the given user
+	LIST_THREAD_PARTICIPANTS = `
+		SELECT created_by FROM core.comment_threads
+		WHERE id = $1 AND created_by IS NOT NULL AND created_by <> $2
+		UNION
+		SELECT author_id FROM core.comment_replies
+		WHERE thread_id = $1 AND author_id IS NOT NULL AND author_id <> $2`
+
+	// FETCH_PAGE_CONTEXT fetches the space and latest title of a document for notifications
+	FETCH_PAGE_CONTEXT = `
+		SELECT p.space_id, COALESCE((SELECT m.title FROM core.page_doc_map m WHERE m.page_id = p.id ORDER BY m.version DESC LIMIT 1), '')
+		FROM core.page p WHERE p.id = $1`
+
 	// FETCH_REPLY_BASIC fetches the basic metadata of a reply and its thread joining document for auth
 	FETCH_REPLY_BASIC = `
 		SELECT r.author_id, t.document_id 
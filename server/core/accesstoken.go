@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// personal access token scopes, from least to most privileged
+const (
+	TOKEN_SCOPE_READ  = "read"
+	TOKEN_SCOPE_EDIT  = "edit"
+	TOKEN_SCOPE_ADMIN = "admin"
+)
+
+// AccessToken is the personal access token a request was authenticated with.
+// An empty SpaceIds means the token is not limited to particular spaces.
+type AccessToken struct {
+	Id       uuid.UUID
+	UserId   uuid.UUID
+	OrgId    uuid.UUID
+	Scope    string
+	SpaceIds []uuid.UUID
+}
+
+type accessTokenKey struct{}
+
+type accessTokenPrincipal struct {
+	user  UserInfo
+	token AccessToken
+}
+
+// tokenEditPermissions lists what an edit scoped token may do per entity;
+// everything else needs the admin scope.
+var tokenEditPermissions = map[string]map[string]bool{
+	"space": {SPACE_VIEW: true, SPACE_EDIT_PAGE: true, "add_comment": true},
+	"page":  {PAGE_VIEW: true, PAGE_EDIT: true, PAGE_DELETE: true, PAGE_ADD_COMMENT: true},
+}
+
+const (
+	getTokenPageSpace   = `SELECT space_id FROM core.page WHERE id = $1`
+	filterTokenPageList = `SELECT id FROM core.page WHERE id = ANY($1) AND space_id = ANY($2)`
+)
+
+// WithAccessToken authenticates the request context as user through token.
+// GetUserInfo returns user for it without consulting the session.
+func WithAccessToken(ctx context.Context, user UserInfo, token AccessToken) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, accessTokenPrincipal{user: user, token: token})
+}
+
+// AccessTokenFromContext returns the token the request was authenticated
+// with, if any.
+func AccessTokenFromContext(ctx context.Context) (AccessToken, bool) {
+	if ctx == nil {
+		return AccessToken{}, false
+	}
+	principal, ok := ctx.Value(accessTokenKey{}).(accessTokenPrincipal)
+	return principal.token, ok
+}
+
+func accessTokenUser(ctx context.Context) (UserInfo, bool) {
+	if ctx == nil {
+		return UserInfo{}, false
+	}
+	principal, ok := ctx.Value(accessTokenKey{}).(accessTokenPrincipal)
+	return principal.user, ok
+}
+
+func TokenScopeRank(scope string) int {
+	switch scope {
+	case TOKEN_SCOPE_READ:
+		return 1
+	case TOKEN_SCOPE_EDIT:
+		return 2
+	case TOKEN_SCOPE_ADMIN:
+		return 3
+	}
+	return 0
+}
+
+// IsSafeMethod reports whether the method only reads.
+func IsSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// RequireTokenScope rejects changes made through tokens below scope or
+// limited to particular spaces. Session requests pass untouched.
+func RequireTokenScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AccessTokenFromContext(r.Context())
+			if ok && !IsSafeMethod(r.Method) && (TokenScopeRank(token.Scope) < TokenScopeRank(scope) || len(token.SpaceIds) > 0) {
+				SendFailedReponse(w, r, http.StatusForbidden, ErrorCode_name[ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenFor returns the request token when the permission check is about the
+// token's own user. Checks made on behalf of other users are not narrowed.
+func tokenFor(ctx context.Context, subject string, subjectId string) (AccessToken, bool) {
+	token, ok := AccessTokenFromContext(ctx)
+	if !ok || subject != "user" || subjectId != token.UserId.String() {
+		return AccessToken{}, false
+	}
+	return token, true
+}
+
+func tokenScopeAllows(token AccessToken, entity string, permission string) bool {
+	switch token.Scope {
+	case TOKEN_SCOPE_ADMIN:
+		return true
+	case TOKEN_SCOPE_EDIT:
+		if tokenEditPermissions[entity][permission] {
+			return true
+		}
+	}
+	return permission == "view"
+}
+
+func (t AccessToken) allowsSpace(spaceId uuid.UUID) bool {
+	if len(t.SpaceIds) == 0 {
+		return true
+	}
+	for _, id := range t.SpaceIds {
+		if id == spaceId {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenEntityAllowed checks the entity against the token's space limit.
+// Entities outside spaces are only readable by space limited tokens.
+func tokenEntityAllowed(ctx context.Context, token AccessToken, entity string, entityId string, permission string) bool {
+	if len(token.SpaceIds) == 0 {
+		return true
+	}
+	switch entity {
+	case "space":
+		spaceId, err := uuid.Parse(entityId)
+		return err == nil && token.allowsSpace(spaceId)
+	case "page":
+		pageId, err := strconv.ParseInt(entityId, 10, 64)
+		if err != nil {
+			return false
+		}
+		var spaceId uuid.UUID
+		if err := GetPool().QueryRow(ctx, getTokenPageSpace, pageId).Scan(&spaceId); err != nil {
+			return false
+		}
+		return token.allowsSpace(spaceId)
+	}
+	return permission == "view"
+}
+
+// tokenFilterEntities narrows a permission lookup to what the token covers.
+func tokenFilterEntities(ctx context.Context, entity string, subject string, subjectId string, permission string, entityIds []string) ([]string, error) {
+	token, ok := tokenFor(ctx, subject, subjectId)
+	if !ok {
+		return entityIds, nil
+	}
+	if !tokenScopeAllows(token, entity, permission) {
+		return make([]string, 0), nil
+	}
+	if len(token.SpaceIds) == 0 {
+		return entityIds, nil
+	}
+	filtered := make([]string, 0, len(entityIds))
+	switch entity {
+	case "space":
+		for _, id := range entityIds {
+			if spaceId, err := uuid.Parse(id); err == nil && token.allowsSpace(spaceId) {
+				filtered = append(filtered, id)
+			}
+		}
+	case "page":
+		pageIds := make([]int64, 0, len(entityIds))
+		for _, id := range entityIds {
+			if pageId, err := strconv.ParseInt(id, 10, 64); err == nil {
+				pageIds = append(pageIds, pageId)
+			}
+		}
+		rows, err := GetPool().Query(ctx, filterTokenPageList, pageIds, token.SpaceIds)
+		if err != nil {
+			return filtered, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var pageId int64
+			if err := rows.Scan(&pageId); err != nil {
+				return filtered, err
+			}
+			filtered = append(filtered, strconv.FormatInt(pageId, 10))
+		}
+		return filtered, rows.Err()
+	default:
+		if permission == "view" {
+			return entityIds, nil
+		}
+	}
+	return filtered, nil
+}
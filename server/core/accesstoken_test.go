@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTokenScopeAllows(t *testing.T) {
+	read := AccessToken{Scope: TOKEN_SCOPE_READ}
+	edit := AccessToken{Scope: TOKEN_SCOPE_EDIT}
+	admin := AccessToken{Scope: TOKEN_SCOPE_ADMIN}
+	if !tokenScopeAllows(read, "page", PAGE_VIEW) || tokenScopeAllows(read, "page", PAGE_EDIT) {
+		t.Fatalf("read tokens should only view")
+	}
+	if !tokenScopeAllows(edit, "page", PAGE_EDIT) || !tokenScopeAllows(edit, "space", SPACE_EDIT_PAGE) {
+		t.Fatalf("edit tokens should edit content")
+	}
+	if tokenScopeAllows(edit, "space", SPACE_MANAGE_MEMBERS) || tokenScopeAllows(edit, "space", SPACE_DELETE) {
+		t.Fatalf("edit tokens should not administer spaces")
+	}
+	if !tokenScopeAllows(admin, "space", SPACE_DELETE) {
+		t.Fatalf("admin tokens should be unrestricted")
+	}
+}
+
+func TestGetUserInfoPrefersAccessToken(t *testing.T) {
+	user := UserInfo{Id: "zita", AId: uuid.NewString(), Name: "CI"}
+	ctx := WithAccessToken(context.Background(), user, AccessToken{Scope: TOKEN_SCOPE_READ})
+	got, err := GetUserInfo(ctx)
+	if err != nil || got != user {
+		t.Fatalf("expected token user, got %+v %v", got, err)
+	}
+}
+
+func TestTokenChecksOnlyNarrowTheTokenUser(t *testing.T) {
+	userId := uuid.New()
+	ctx := WithAccessToken(context.Background(), UserInfo{AId: userId.String()}, AccessToken{UserId: userId, Scope: TOKEN_SCOPE_READ})
+	if _, ok := tokenFor(ctx, "user", uuid.NewString()); ok {
+		t.Fatalf("checks about other users should not be narrowed")
+	}
+	if _, ok := tokenFor(ctx, "user", userId.String()); !ok {
+		t.Fatalf("checks about the token user should be narrowed")
+	}
+}
+
+func TestTokenFilterEntitiesLimitsSpaces(t *testing.T) {
+	userId := uuid.New()
+	allowed := uuid.New()
+	other := uuid.New()
+	ctx := WithAccessToken(context.Background(), UserInfo{}, AccessToken{UserId: userId, Scope: TOKEN_SCOPE_EDIT, SpaceIds: []uuid.UUID{allowed}})
+	ids, err := tokenFilterEntities(ctx, "space", "user", userId.String(), SPACE_VIEW, []string{allowed.String(), other.String()})
+	if err != nil || len(ids) != 1 || ids[0] != allowed.String() {
+		t.Fatalf("expected only the allowed space, got %v %v", ids, err)
+	}
+	ids, _ = tokenFilterEntities(ctx, "space", "user", userId.String(), SPACE_DELETE, []string{allowed.String()})
+	if len(ids) != 0 {
+		t.Fatalf("expected permissions beyond the scope to find nothing, got %v", ids)
+	}
+}
+
+func TestRequireTokenScope(t *testing.T) {
+	handler := RequireTokenScope(TOKEN_SCOPE_ADMIN)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	cases := []struct {
+		method string
+		token  *AccessToken
+		want   int
+	}{
+		{http.MethodPost, nil, http.StatusNoContent},
+		{http.MethodGet, &AccessToken{Scope: TOKEN_SCOPE_READ}, http.StatusNoContent},
+		{http.MethodPost, &AccessToken{Scope: TOKEN_SCOPE_EDIT}, http.StatusForbidden},
+		{http.MethodPost, &AccessToken{Scope: TOKEN_SCOPE_ADMIN, SpaceIds: []uuid.UUID{uuid.New()}}, http.StatusForbidden},
+		{http.MethodPost, &AccessToken{Scope: TOKEN_SCOPE_ADMIN}, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		if tc.token != nil {
+			req = req.WithContext(WithAccessToken(req.Context(), UserInfo{}, *tc.token))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s with %+v: got %d, want %d", tc.method, tc.token, rec.Code, tc.want)
+		}
+	}
+}
@@ -215,8 +215,8 @@ func ZitadelMiddleware() *authentication.Interceptor[*openid.UserInfoContext[*zo
 
 func Authenticated(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if authentication.IsAuthenticated(r.Context()) {
-			next.ServeHTTP(w, r)
+		if _, ok := AccessTokenFromContext(r.Context()); ok || authentication.IsAuthenticated(r.Context()) {
+			next.ServeHTTP(w, r.WithContext(WithPermissionMemo(r.Context())))
 		} else {
 			render.Status(r, http.StatusUnauthorized)
 			render.Render(w, r, NewFailedResponse(401, FAILURE, "Not authenticated", ""))
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
+)
+
+const (
+	AUTHORIZER_PERMIFY  = "permify"
+	AUTHORIZER_EMBEDDED = "embedded"
+)
+
+// Authorizer is the backend behind the permission functions of this package,
+// such as CheckPermission, WriteRelations and GetEntitiesWithPermission. It
+// stores relationships and evaluates permify/schema.perm; caching, auditing and
+// access token limits are added on top by those functions. Writes and deletes
+// return a snap token that later reads of the same tenant can pass on, which
+// is empty when the backend has none.
+type Authorizer interface {
+	WriteRelationships(ctx context.Context, tenant string, tuples []*permify_payload.Tuple) (string, error)
+	DeleteRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter) (string, error)
+	// ReadRelationships returns a page of tuples and the token of the next
+	// page, empty after the last one.
+	ReadRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter, pageSize uint32, continuousToken string) ([]*permify_payload.Tuple, string, error)
+	Check(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, permission string, subject *permify_payload.Subject) (bool, error)
+	// SubjectPermission answers every permission of the entity, and every
+	// relation too unless onlyPermission is set.
+	SubjectPermission(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, subject *permify_payload.Subject, onlyPermission bool) (map[string]permify_payload.CheckResult, error)
+	LookupEntity(ctx context.Context, tenant string, snapToken string, entityType string, permission string, subject *permify_payload.Subject) ([]string, error)
+	// Expand returns the tree of relations behind a permission or relation.
+	// Subject sets such as group#member are left as leaves.
+	Expand(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, permission string) (*permify_payload.Expand, error)
+	CreateTenant(ctx context.Context, tenantId string, name string, schema string) error
+}
+
+var (
+	authorizerOnce   sync.Once
+	activeAuthorizer Authorizer
+	authorizerErr    error
+)
+
+// InitAuthorizer sets up the backend named by AUTHORIZER: "permify", the
+// default, or "embedded", which evaluates the schema at PERMIFY_SCHEMA_PATH
+// against relationships kept in core.authz_relations.
+func InitAuthorizer() error {
+	authorizerOnce.Do(func() {
+		backend := strings.ToLower(strings.TrimSpace(os.Getenv("AUTHORIZER")))
+		switch backend {
+		case "", AUTHORIZER_PERMIFY:
+			activeAuthorizer, authorizerErr = newPermifyAuthorizer()
+		case AUTHORIZER_EMBEDDED:
+			activeAuthorizer, authorizerErr = newEmbeddedAuthorizer(schemaPath())
+		default:
+			authorizerErr = fmt.Errorf("unknown AUTHORIZER %q", backend)
+		}
+		if authorizerErr != nil {
+			activeAuthorizer = unavailableAuthorizer{err: authorizerErr}
+		}
+	})
+	return authorizerErr
+}
+
+func authorizer() Authorizer {
+	InitAuthorizer()
+	return activeAuthorizer
+}
+
+// schemaPath falls back to the schema in the repository so the embedded
+// engine works from a checkout without further setup.
+func schemaPath() string {
+	if path := strings.TrimSpace(os.Getenv("PERMIFY_SCHEMA_PATH")); path != "" {
+		return path
+	}
+	return "../permify/schema.perm"
+}
+
+// unavailableAuthorizer stands in for a backend that could not be set up, so
+// every call fails with the reason instead of the server panicking.
+type unavailableAuthorizer struct {
+	err error
+}
+
+func (u unavailableAuthorizer) WriteRelationships(context.Context, string, []*permify_payload.Tuple) (string, error) {
+	return "", u.err
+}
+
+func (u unavailableAuthorizer) DeleteRelationships(context.Context, string, *permify_payload.TupleFilter) (string, error) {
+	return "", u.err
+}
+
+func (u unavailableAuthorizer) ReadRelationships(context.Context, string, *permify_payload.TupleFilter, uint32, string) ([]*permify_payload.Tuple, string, error) {
+	return nil, "", u.err
+}
+
+func (u unavailableAuthorizer) Check(context.Context, string, string, *permify_payload.Entity, string, *permify_payload.Subject) (bool, error) {
+	return false, u.err
+}
+
+func (u unavailableAuthorizer) SubjectPermission(context.Context, string, string, *permify_payload.Entity, *permify_payload.Subject, bool) (map[string]permify_payload.CheckResult, error) {
+	return nil, u.err
+}
+
+func (u unavailableAuthorizer) LookupEntity(context.Context, string, string, string, string, *permify_payload.Subject) ([]string, error) {
+	return nil, u.err
+}
+
+func (u unavailableAuthorizer) Expand(context.Context, string, string, *permify_payload.Entity, string) (*permify_payload.Expand, error) {
+	return nil, u.err
+}
+
+func (u unavailableAuthorizer) CreateTenant(context.Context, string, string, string) error {
+	return u.err
+}
@@ -0,0 +1,21 @@
+package core
+
+import "strings"
+
+// CSVRow escapes every cell of a row bound for a CSV export, so names,
+// emails and other user input are not read as formulas when the file is
+// opened in a spreadsheet.
+func CSVRow(row []string) []string {
+	for i, cell := range row {
+		row[i] = CSVCell(cell)
+	}
+	return row
+}
+
+// CSVCell quotes a cell a spreadsheet would otherwise evaluate.
+func CSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
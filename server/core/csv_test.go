@@ -0,0 +1,16 @@
+package core
+
+import "testing"
+
+func TestCSVCellQuotesFormulas(t *testing.T) {
+	for _, cell := range []string{"=1+1", "+1", "-1", "@SUM(A1)", "\tname", "\rname"} {
+		if got := CSVCell(cell); got != "'"+cell {
+			t.Errorf("expected %q to be quoted, got %q", cell, got)
+		}
+	}
+	for _, cell := range []string{"", "kiran@acme.com", `{"role": "viewer"}`} {
+		if got := CSVCell(cell); got != cell {
+			t.Errorf("expected %q to stay as it is, got %q", cell, got)
+		}
+	}
+}
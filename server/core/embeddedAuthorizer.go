@@ -0,0 +1,519 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
+)
+
+// embeddedAuthorizer evaluates the schema in-process against relationships
+// kept in core.authz_relations, for installations that do not run Permify.
+// Every tenant shares the schema it was started with. Answers always reflect
+// the latest writes, so snap tokens are not needed and none are handed out.
+//
+// A lookup checks every entity of the type that holds any relationship, which
+// is fine for the number of spaces and pages a small team has.
+type embeddedAuthorizer struct {
+	schema *authzSchema
+}
+
+// embeddedMaxDepth bounds how many relations one answer may follow, like the
+// depth sent to Permify.
+const embeddedMaxDepth = 64
+
+const (
+	insertAuthzRelations = `INSERT INTO core.authz_relations
+		(tenant_id, entity_type, entity_id, relation, subject_type, subject_id, subject_relation)
+		SELECT $1, * FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+		ON CONFLICT DO NOTHING`
+
+	getEntityAuthzRelations = `SELECT relation, subject_type, subject_id, subject_relation
+		FROM core.authz_relations
+		WHERE tenant_id = $1 AND entity_type = $2 AND entity_id = $3`
+
+	getAuthzEntityIds = `SELECT DISTINCT entity_id FROM core.authz_relations WHERE tenant_id = $1 AND entity_type = $2`
+)
+
+var errDepthExceeded = errors.New("authorization depth exceeded")
+
+func newEmbeddedAuthorizer(path string) (*embeddedAuthorizer, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	schema, err := parseSchema(string(source))
+	if err != nil {
+		return nil, err
+	}
+	return &embeddedAuthorizer{schema: schema}, nil
+}
+
+type relationTuple struct {
+	entity          string
+	entityId        string
+	relation        string
+	subject         string
+	subjectId       string
+	subjectRelation string
+}
+
+func (e *embeddedAuthorizer) WriteRelationships(ctx context.Context, tenant string, tuples []*permify_payload.Tuple) (string, error) {
+	if len(tuples) == 0 {
+		return "", nil
+	}
+	columns := make([][]string, 6)
+	for _, t := range tuples {
+		entity, subject := t.GetEntity(), t.GetSubject()
+		if entity.GetId() == "" || subject.GetId() == "" || !e.schema.allows(entity.GetType(), t.GetRelation(), subject.GetType(), subject.GetRelation()) {
+			return "", fmt.Errorf("schema does not allow %s:%s#%s@%s:%s#%s", entity.GetType(), entity.GetId(), t.GetRelation(), subject.GetType(), subject.GetId(), subject.GetRelation())
+		}
+		for i, value := range []string{entity.GetType(), entity.GetId(), t.GetRelation(), subject.GetType(), subject.GetId(), subject.GetRelation()} {
+			columns[i] = append(columns[i], value)
+		}
+	}
+	_, err := GetPool().Exec(ctx, insertAuthzRelations, tenant, columns[0], columns[1], columns[2], columns[3], columns[4], columns[5])
+	return "", err
+}
+
+// tupleFilterClause turns a filter into a WHERE clause. Like Permify, empty
+// fields match anything but the entity type is required.
+func tupleFilterClause(tenant string, filter *permify_payload.TupleFilter) (string, []any, error) {
+	if filter.GetEntity().GetType() == "" {
+		return "", nil, errors.New("a relationship filter needs an entity type")
+	}
+	conditions := []string{"tenant_id = $1", "entity_type = $2"}
+	args := []any{tenant, filter.GetEntity().GetType()}
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if ids := filter.GetEntity().GetIds(); len(ids) > 0 {
+		add("entity_id = ANY($%d)", ids)
+	}
+	if filter.GetRelation() != "" {
+		add("relation = $%d", filter.GetRelation())
+	}
+	if filter.GetSubject().GetType() != "" {
+		add("subject_type = $%d", filter.GetSubject().GetType())
+	}
+	if ids := filter.GetSubject().GetIds(); len(ids) > 0 {
+		add("subject_id = ANY($%d)", ids)
+	}
+	if filter.GetSubject().GetRelation() != "" {
+		add("subject_relation = $%d", filter.GetSubject().GetRelation())
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+func (e *embeddedAuthorizer) DeleteRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter) (string, error) {
+	where, args, err := tupleFilterClause(tenant, filter)
+	if err != nil {
+		return "", err
+	}
+	_, err = GetPool().Exec(ctx, "DELETE FROM core.authz_relations WHERE "+where, args...)
+	return "", err
+}
+
+// ReadRelationships pages by offset; the continuous token is the offset of
+// the next page.
+func (e *embeddedAuthorizer) ReadRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter, pageSize uint32, continuousToken string) ([]*permify_payload.Tuple, string, error) {
+	where, args, err := tupleFilterClause(tenant, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	offset := 0
+	if continuousToken != "" {
+		if offset, err = strconv.Atoi(continuousToken); err != nil || offset < 0 {
+			return nil, "", errors.New("invalid continuous token")
+		}
+	}
+	query := `SELECT entity_type, entity_id, relation, subject_type, subject_id, subject_relation
+		FROM core.authz_relations WHERE ` + where + `
+		ORDER BY entity_type, entity_id, relation, subject_type, subject_id, subject_relation`
+	if pageSize > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+	}
+	rows, err := GetPool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	tuples := make([]*permify_payload.Tuple, 0)
+	for rows.Next() {
+		var t relationTuple
+		if err := rows.Scan(&t.entity, &t.entityId, &t.relation, &t.subject, &t.subjectId, &t.subjectRelation); err != nil {
+			return nil, "", err
+		}
+		tuples = append(tuples, &permify_payload.Tuple{
+			Entity:   &permify_payload.Entity{Type: t.entity, Id: t.entityId},
+			Relation: t.relation,
+			Subject:  &permify_payload.Subject{Type: t.subject, Id: t.subjectId, Relation: t.subjectRelation},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if pageSize > 0 && len(tuples) == int(pageSize) {
+		next = strconv.Itoa(offset + len(tuples))
+	}
+	return tuples, next, nil
+}
+
+func (e *embeddedAuthorizer) Check(ctx context.Context, tenant string, _ string, entity *permify_payload.Entity, permission string, subject *permify_payload.Subject) (bool, error) {
+	return e.evaluation(ctx, tenant, subject).check(entity.GetType(), entity.GetId(), permission, 0)
+}
+
+func (e *embeddedAuthorizer) SubjectPermission(ctx context.Context, tenant string, _ string, entity *permify_payload.Entity, subject *permify_payload.Subject, onlyPermission bool) (map[string]permify_payload.CheckResult, error) {
+	def, ok := e.schema.entities[entity.GetType()]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %s", entity.GetType())
+	}
+	eval := e.evaluation(ctx, tenant, subject)
+	results := make(map[string]permify_payload.CheckResult)
+	for _, name := range def.names {
+		if _, isPermission := def.permissions[name]; onlyPermission && !isPermission {
+			continue
+		}
+		can, err := eval.check(entity.GetType(), entity.GetId(), name, 0)
+		if err != nil {
+			return nil, err
+		}
+		results[name] = checkResult(can)
+	}
+	return results, nil
+}
+
+func (e *embeddedAuthorizer) LookupEntity(ctx context.Context, tenant string, _ string, entityType string, permission string, subject *permify_payload.Subject) ([]string, error) {
+	if _, ok := e.schema.entities[entityType]; !ok {
+		return nil, fmt.Errorf("unknown entity %s", entityType)
+	}
+	rows, err := GetPool().Query(ctx, getAuthzEntityIds, tenant, entityType)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(candidates)
+	eval := e.evaluation(ctx, tenant, subject)
+	entityIds := make([]string, 0)
+	for _, id := range candidates {
+		can, err := eval.check(entityType, id, permission, 0)
+		if err != nil {
+			return nil, err
+		}
+		if can {
+			entityIds = append(entityIds, id)
+		}
+	}
+	return entityIds, nil
+}
+
+func (e *embeddedAuthorizer) Expand(ctx context.Context, tenant string, _ string, entity *permify_payload.Entity, permission string) (*permify_payload.Expand, error) {
+	return e.evaluation(ctx, tenant, nil).expand(entity.GetType(), entity.GetId(), permission, 0)
+}
+
+// CreateTenant only checks the schema: relationships carry their tenant and
+// every tenant is evaluated against the schema the server started with.
+func (e *embeddedAuthorizer) CreateTenant(_ context.Context, _ string, _ string, schema string) error {
+	_, err := parseSchema(schema)
+	return err
+}
+
+func (e *embeddedAuthorizer) evaluation(ctx context.Context, tenant string, subject *permify_payload.Subject) *evaluation {
+	return newEvaluation(e.schema, relationSubject{entity: subject.GetType(), id: subject.GetId(), relation: subject.GetRelation()},
+		func(entity string, entityId string) ([]relationTuple, error) {
+			return loadEntityRelations(ctx, tenant, entity, entityId)
+		})
+}
+
+func loadEntityRelations(ctx context.Context, tenant string, entity string, entityId string) ([]relationTuple, error) {
+	rows, err := GetPool().Query(ctx, getEntityAuthzRelations, tenant, entity, entityId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tuples := make([]relationTuple, 0)
+	for rows.Next() {
+		t := relationTuple{entity: entity, entityId: entityId}
+		if err := rows.Scan(&t.relation, &t.subject, &t.subjectId, &t.subjectRelation); err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, t)
+	}
+	return tuples, rows.Err()
+}
+
+func checkResult(can bool) permify_payload.CheckResult {
+	if can {
+		return permify_payload.CheckResult_CHECK_RESULT_ALLOWED
+	}
+	return permify_payload.CheckResult_CHECK_RESULT_DENIED
+}
+
+type relationSubject struct {
+	entity   string
+	id       string
+	relation string
+}
+
+// evaluation answers checks for one subject. The relationships of each entity
+// are loaded once and every answer is kept, so the many checks behind a
+// lookup share their work.
+type evaluation struct {
+	schema   *authzSchema
+	subject  relationSubject
+	load     func(entity string, entityId string) ([]relationTuple, error)
+	tuples   map[string][]relationTuple
+	results  map[string]bool
+	visiting map[string]bool
+	// cuts counts the cycles broken so far; an answer that depended on one
+	// is not kept, since it may differ when asked on its own
+	cuts int
+}
+
+func newEvaluation(schema *authzSchema, subject relationSubject, load func(entity string, entityId string) ([]relationTuple, error)) *evaluation {
+	return &evaluation{
+		schema:   schema,
+		subject:  subject,
+		load:     load,
+		tuples:   make(map[string][]relationTuple),
+		results:  make(map[string]bool),
+		visiting: make(map[string]bool),
+	}
+}
+
+func (e *evaluation) relations(entity string, entityId string) ([]relationTuple, error) {
+	key := entity + ":" + entityId
+	if tuples, ok := e.tuples[key]; ok {
+		return tuples, nil
+	}
+	tuples, err := e.load(entity, entityId)
+	if err != nil {
+		return nil, err
+	}
+	e.tuples[key] = tuples
+	return tuples, nil
+}
+
+// check answers whether the subject holds name, a relation or a permission,
+// on entity:entityId. A name reached again while it is being answered, as in
+// groups nested in each other, does not grant anything.
+func (e *evaluation) check(entity string, entityId string, name string, depth int) (bool, error) {
+	if depth > embeddedMaxDepth {
+		return false, errDepthExceeded
+	}
+	key := entity + ":" + entityId + "#" + name
+	if result, ok := e.results[key]; ok {
+		return result, nil
+	}
+	if e.visiting[key] {
+		e.cuts++
+		return false, nil
+	}
+	def, ok := e.schema.entities[entity]
+	if !ok {
+		return false, fmt.Errorf("unknown entity %s", entity)
+	}
+	e.visiting[key] = true
+	cuts := e.cuts
+	var result bool
+	var err error
+	if expr, isPermission := def.permissions[name]; isPermission {
+		result, err = e.evaluate(entity, entityId, expr, depth+1)
+	} else if _, isRelation := def.relations[name]; isRelation {
+		result, err = e.holds(entity, entityId, name, depth+1)
+	} else {
+		err = fmt.Errorf("unknown permission %s.%s", entity, name)
+	}
+	delete(e.visiting, key)
+	if err != nil {
+		return false, err
+	}
+	if e.cuts == cuts {
+		e.results[key] = result
+	}
+	return result, nil
+}
+
+// holds answers a relation: directly, or through a subject set such as
+// group#member.
+func (e *evaluation) holds(entity string, entityId string, relation string, depth int) (bool, error) {
+	tuples, err := e.relations(entity, entityId)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range tuples {
+		if t.relation != relation {
+			continue
+		}
+		if t.subject == e.subject.entity && t.subjectId == e.subject.id && t.subjectRelation == e.subject.relation {
+			return true, nil
+		}
+		if t.subjectRelation == "" {
+			continue
+		}
+		can, err := e.check(t.subject, t.subjectId, t.subjectRelation, depth)
+		if err != nil || can {
+			return can, err
+		}
+	}
+	return false, nil
+}
+
+func (e *evaluation) evaluate(entity string, entityId string, expr *permissionExpr, depth int) (bool, error) {
+	switch expr.op {
+	case exprLeaf:
+		if expr.via == "" {
+			return e.check(entity, entityId, expr.name, depth)
+		}
+		tuples, err := e.relations(entity, entityId)
+		if err != nil {
+			return false, err
+		}
+		for _, t := range tuples {
+			if t.relation != expr.via {
+				continue
+			}
+			if def, ok := e.schema.entities[t.subject]; !ok || !def.declares(expr.name) {
+				continue
+			}
+			can, err := e.check(t.subject, t.subjectId, expr.name, depth)
+			if err != nil || can {
+				return can, err
+			}
+		}
+		return false, nil
+	case exprOr:
+		left, err := e.evaluate(entity, entityId, expr.left, depth)
+		if err != nil || left {
+			return left, err
+		}
+		return e.evaluate(entity, entityId, expr.right, depth)
+	case exprAnd, exprNot:
+		left, err := e.evaluate(entity, entityId, expr.left, depth)
+		if err != nil || !left {
+			return false, err
+		}
+		right, err := e.evaluate(entity, entityId, expr.right, depth)
+		if err != nil {
+			return false, err
+		}
+		if expr.op == exprNot {
+			return !right, nil
+		}
+		return right, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", expr.op)
+}
+
+// expand builds the tree Permify's expand API returns for name on
+// entity:entityId: relations become leaves listing their subjects, and
+// permissions become union, intersection and exclusion nodes. A walk such as
+// parent.view is the union of name expanded on every related entity.
+func (e *evaluation) expand(entity string, entityId string, name string, depth int) (*permify_payload.Expand, error) {
+	if depth > embeddedMaxDepth {
+		return nil, errDepthExceeded
+	}
+	def, ok := e.schema.entities[entity]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %s", entity)
+	}
+	result := &permify_payload.Expand{
+		Entity:     &permify_payload.Entity{Type: entity, Id: entityId},
+		Permission: name,
+	}
+	if expr, isPermission := def.permissions[name]; isPermission {
+		node, err := e.expandExpr(entity, entityId, expr, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		result.Node = node.Node
+		return result, nil
+	}
+	if _, isRelation := def.relations[name]; !isRelation {
+		return nil, fmt.Errorf("unknown permission %s.%s", entity, name)
+	}
+	tuples, err := e.relations(entity, entityId)
+	if err != nil {
+		return nil, err
+	}
+	subjects := make([]*permify_payload.Subject, 0)
+	for _, t := range tuples {
+		if t.relation == name {
+			subjects = append(subjects, &permify_payload.Subject{Type: t.subject, Id: t.subjectId, Relation: t.subjectRelation})
+		}
+	}
+	result.Node = &permify_payload.Expand_Leaf{Leaf: &permify_payload.ExpandLeaf{
+		Type: &permify_payload.ExpandLeaf_Subjects{Subjects: &permify_payload.Subjects{Subjects: subjects}},
+	}}
+	return result, nil
+}
+
+var expandOperations = map[string]permify_payload.ExpandTreeNode_Operation{
+	exprOr:  permify_payload.ExpandTreeNode_OPERATION_UNION,
+	exprAnd: permify_payload.ExpandTreeNode_OPERATION_INTERSECTION,
+	exprNot: permify_payload.ExpandTreeNode_OPERATION_EXCLUSION,
+}
+
+func (e *evaluation) expandExpr(entity string, entityId string, expr *permissionExpr, depth int) (*permify_payload.Expand, error) {
+	children := make([]*permify_payload.Expand, 0)
+	operation := expandOperations[expr.op]
+	switch {
+	case expr.op == exprLeaf && expr.via == "":
+		return e.expand(entity, entityId, expr.name, depth)
+	case expr.op == exprLeaf:
+		operation = permify_payload.ExpandTreeNode_OPERATION_UNION
+		tuples, err := e.relations(entity, entityId)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tuples {
+			if t.relation != expr.via {
+				continue
+			}
+			if def, ok := e.schema.entities[t.subject]; !ok || !def.declares(expr.name) {
+				continue
+			}
+			child, err := e.expand(t.subject, t.subjectId, expr.name, depth)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+	default:
+		if _, ok := expandOperations[expr.op]; !ok {
+			return nil, fmt.Errorf("unknown operator %s", expr.op)
+		}
+		for _, side := range []*permissionExpr{expr.left, expr.right} {
+			child, err := e.expandExpr(entity, entityId, side, depth)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+	}
+	return &permify_payload.Expand{
+		Node: &permify_payload.Expand_Expand{Expand: &permify_payload.ExpandTreeNode{
+			Operation: operation,
+			Children:  children,
+		}},
+	}, nil
+}
@@ -19,8 +19,9 @@ const (
 	ErrorCode_ERROR_CODE_INVALID_INPUT           ErrorCode = 2004
 
 	// not found
-	ErrorCode_ERROR_CODE_NO_DATA        ErrorCode = 4001
-	ErrorCode_ERROR_CODE_ALREADY_EXISTS ErrorCode = 4002
+	ErrorCode_ERROR_CODE_NO_DATA            ErrorCode = 4001
+	ErrorCode_ERROR_CODE_ALREADY_EXISTS     ErrorCode = 4002
+	ErrorCode_ERROR_CODE_ALREADY_HAS_ACCESS ErrorCode = 4003
 )
 
 var ErrorCode_name = map[ErrorCode]string{
@@ -36,6 +37,7 @@ var ErrorCode_name = map[ErrorCode]string{
 	2004: "ERROR_CODE_INVALID_INPUT",
 	4001: "ERROR_CODE_NO_DATA",
 	4002: "ERROR_CODE_ALREADY_EXISTS",
+	4003: "ERROR_CODE_ALREADY_HAS_ACCESS",
 }
 
 var ErrorName_Code = map[string]ErrorCode{
@@ -51,6 +53,7 @@ var ErrorName_Code = map[string]ErrorCode{
 	"ERROR_CODE_INVALID_INPUT":           2004,
 	"ERROR_CODE_NO_DATA":                 4001,
 	"ERROR_CODE_ALREADY_EXISTS":          4002,
+	"ERROR_CODE_ALREADY_HAS_ACCESS":      4003,
 }
 
 type Code uint32
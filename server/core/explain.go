@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
+)
+
+// traceMaxDepth bounds how many subject sets a trace follows, so groups
+// nested in each other end.
+const traceMaxDepth = 32
+
+// AccessPath is one chain from a permission down to a subject, e.g.
+// page:12#view, space:s#view, space:s#editor, group:g#member, user:u.
+type AccessPath []string
+
+// PermissionTrace explains a permission for one subject. Grants lists the
+// paths that give it; Blocks lists the paths that took it away through a
+// "not" in the schema, such as a page restriction the subject is missing
+// from. Blocks is only filled when nothing grants the permission.
+type PermissionTrace struct {
+	Granted bool         `json:"granted"`
+	Grants  []AccessPath `json:"grants"`
+	Blocks  []AccessPath `json:"blocks"`
+}
+
+// ExpandPermission returns the authorizer's expand tree for a permission or
+// relation on an entity.
+func ExpandPermission(ctx context.Context, entity string, entityId string, permission string) (*permify_payload.Expand, error) {
+	tenant := TenantFromContext(ctx)
+	tree, err := authorizer().Expand(ctx, tenant, snapToken(ctx, tenant), &permify_payload.Entity{
+		Type: entity,
+		Id:   entityId,
+	}, permission)
+	if err != nil {
+		Logger.Error(err.Error())
+		return nil, err
+	}
+	return tree, nil
+}
+
+// TracePermission expands the permission and follows the tree down to the
+// subject, expanding subject sets such as group#member on the way. It
+// explains the relationships only; access token limits are not part of it.
+func TracePermission(ctx context.Context, entity string, entityId string, permission string, subject string, subjectId string) (PermissionTrace, error) {
+	t := newTracer(subject, subjectId, func(entity string, entityId string, permission string) (*permify_payload.Expand, error) {
+		return ExpandPermission(ctx, entity, entityId, permission)
+	})
+	return t.trace(entity, entityId, permission)
+}
+
+type tracer struct {
+	subject   string
+	subjectId string
+	expand    func(entity string, entityId string, permission string) (*permify_payload.Expand, error)
+	expanded  map[string]*permify_payload.Expand
+	visiting  map[string]bool
+}
+
+func newTracer(subject string, subjectId string, expand func(entity string, entityId string, permission string) (*permify_payload.Expand, error)) *tracer {
+	return &tracer{
+		subject:   subject,
+		subjectId: subjectId,
+		expand:    expand,
+		expanded:  make(map[string]*permify_payload.Expand),
+		visiting:  make(map[string]bool),
+	}
+}
+
+func (t *tracer) trace(entity string, entityId string, permission string) (PermissionTrace, error) {
+	result, err := t.follow(entity, entityId, permission, nil, 0)
+	if err != nil {
+		return PermissionTrace{}, err
+	}
+	trace := PermissionTrace{Granted: result.granted, Grants: result.grants, Blocks: result.blocks}
+	if trace.Grants == nil {
+		trace.Grants = make([]AccessPath, 0)
+	}
+	if trace.Blocks == nil || trace.Granted {
+		trace.Blocks = make([]AccessPath, 0)
+	}
+	return trace, nil
+}
+
+type traceResult struct {
+	granted bool
+	grants  []AccessPath
+	blocks  []AccessPath
+}
+
+// follow expands entity:entityId#name and walks it. A subject set reached
+// again while it is being walked grants nothing.
+func (t *tracer) follow(entity string, entityId string, name string, trail AccessPath, depth int) (traceResult, error) {
+	if depth > traceMaxDepth {
+		return traceResult{}, errDepthExceeded
+	}
+	key := entity + ":" + entityId + "#" + name
+	if t.visiting[key] {
+		return traceResult{}, nil
+	}
+	tree, ok := t.expanded[key]
+	if !ok {
+		var err error
+		if tree, err = t.expand(entity, entityId, name); err != nil {
+			return traceResult{}, err
+		}
+		t.expanded[key] = tree
+	}
+	t.visiting[key] = true
+	defer delete(t.visiting, key)
+	return t.walk(tree, extendPath(trail, key), depth)
+}
+
+func (t *tracer) walk(node *permify_payload.Expand, trail AccessPath, depth int) (traceResult, error) {
+	if node.GetEntity() != nil && node.GetPermission() != "" {
+		trail = extendPath(trail, node.GetEntity().GetType()+":"+node.GetEntity().GetId()+"#"+node.GetPermission())
+	}
+	switch n := node.GetNode().(type) {
+	case *permify_payload.Expand_Leaf:
+		return t.walkLeaf(n.Leaf, trail, depth)
+	case *permify_payload.Expand_Expand:
+		children := make([]traceResult, 0, len(n.Expand.GetChildren()))
+		for _, child := range n.Expand.GetChildren() {
+			result, err := t.walk(child, trail, depth)
+			if err != nil {
+				return traceResult{}, err
+			}
+			children = append(children, result)
+		}
+		return combineTrace(n.Expand.GetOperation(), children)
+	}
+	return traceResult{}, nil
+}
+
+func (t *tracer) walkLeaf(leaf *permify_payload.ExpandLeaf, trail AccessPath, depth int) (traceResult, error) {
+	var result traceResult
+	for _, s := range leaf.GetSubjects().GetSubjects() {
+		if s.GetRelation() == "" {
+			if s.GetType() == t.subject && s.GetId() == t.subjectId {
+				result.granted = true
+				result.grants = append(result.grants, extendPath(trail, s.GetType()+":"+s.GetId()))
+			}
+			continue
+		}
+		child, err := t.follow(s.GetType(), s.GetId(), s.GetRelation(), trail, depth+1)
+		if err != nil {
+			return traceResult{}, err
+		}
+		result.granted = result.granted || child.granted
+		result.grants = append(result.grants, child.grants...)
+	}
+	return result, nil
+}
+
+// combineTrace applies a tree node's operation. The first child of an
+// exclusion is the base; what grants any later child blocks the base.
+func combineTrace(operation permify_payload.ExpandTreeNode_Operation, children []traceResult) (traceResult, error) {
+	var result traceResult
+	switch operation {
+	case permify_payload.ExpandTreeNode_OPERATION_UNION:
+		for _, child := range children {
+			result.granted = result.granted || child.granted
+			result.grants = append(result.grants, child.grants...)
+			result.blocks = append(result.blocks, child.blocks...)
+		}
+	case permify_payload.ExpandTreeNode_OPERATION_INTERSECTION:
+		result.granted = len(children) > 0
+		for _, child := range children {
+			result.granted = result.granted && child.granted
+			result.blocks = append(result.blocks, child.blocks...)
+		}
+		if result.granted {
+			for _, child := range children {
+				result.grants = append(result.grants, child.grants...)
+			}
+		}
+	case permify_payload.ExpandTreeNode_OPERATION_EXCLUSION:
+		if len(children) == 0 {
+			return result, nil
+		}
+		base := children[0]
+		result.blocks = append(result.blocks, base.blocks...)
+		if !base.granted {
+			return result, nil
+		}
+		result.granted = true
+		for _, excluded := range children[1:] {
+			if excluded.granted {
+				result.granted = false
+				result.blocks = append(result.blocks, excluded.grants...)
+			}
+		}
+		if result.granted {
+			result.grants = base.grants
+		}
+	default:
+		return result, errors.New("unknown expand operation " + operation.String())
+	}
+	if result.granted {
+		result.blocks = nil
+	}
+	return result, nil
+}
+
+func extendPath(trail AccessPath, step string) AccessPath {
+	if len(trail) > 0 && trail[len(trail)-1] == step {
+		return trail
+	}
+	path := make(AccessPath, len(trail), len(trail)+1)
+	copy(path, trail)
+	return append(path, step)
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
+)
+
+func testTrace(t *testing.T, tuples map[string][]relationTuple, user string, entity string, entityId string, permission string) PermissionTrace {
+	t.Helper()
+	schema := loadTestSchema(t)
+	eval := newEvaluation(schema, relationSubject{}, func(entity string, entityId string) ([]relationTuple, error) {
+		return tuples[entity+":"+entityId], nil
+	})
+	tr := newTracer("user", user, func(entity string, entityId string, permission string) (*permify_payload.Expand, error) {
+		return eval.expand(entity, entityId, permission, 0)
+	})
+	trace, err := tr.trace(entity, entityId, permission)
+	if err != nil {
+		t.Fatalf("trace %s:%s#%s for %s: %v", entity, entityId, permission, user, err)
+	}
+	if want := testCheck(t, schema, tuples, user, entity, entityId, permission); trace.Granted != want {
+		t.Fatalf("trace %s:%s#%s for %s: granted %v, check says %v", entity, entityId, permission, user, trace.Granted, want)
+	}
+	return trace
+}
+
+func TestTraceFollowsGroupMembership(t *testing.T) {
+	tuples := parseTuples(
+		"space:s#editor@group:writers#member",
+		"group:writers#member@group:nested#member",
+		"group:nested#member@user:writer",
+		"page:1#space@space:s",
+	)
+	trace := testTrace(t, tuples, "writer", "page", "1", "edit")
+	want := []AccessPath{{"page:1#edit", "space:s#editor", "group:writers#member", "group:nested#member", "user:writer"}}
+	if !reflect.DeepEqual(trace.Grants, want) {
+		t.Fatalf("expected %v, got %v", want, trace.Grants)
+	}
+}
+
+func TestTraceReportsRestrictionBlocks(t *testing.T) {
+	tuples := parseTuples(
+		"space:s#viewer@user:reader",
+		"space:s#viewer@user:granted",
+		"page:1#space@space:s",
+		"page:1#view_restricted@space:s",
+		"page:1#restricted_viewer@user:granted",
+		"page:2#space@space:s",
+		"page:2#parent@page:1",
+	)
+	trace := testTrace(t, tuples, "reader", "page", "2", "view")
+	if len(trace.Grants) != 0 || len(trace.Blocks) != 1 {
+		t.Fatalf("expected one block and no grants, got %+v", trace)
+	}
+	want := AccessPath{"page:2#view", "page:2#view_denied", "page:1#view_denied", "space:s#view", "space:s#viewer", "user:reader"}
+	if !reflect.DeepEqual(trace.Blocks[0], want) {
+		t.Fatalf("expected %v, got %v", want, trace.Blocks[0])
+	}
+
+	trace = testTrace(t, tuples, "granted", "page", "2", "view")
+	if len(trace.Grants) != 1 || len(trace.Blocks) != 0 {
+		t.Fatalf("expected one grant and no blocks, got %+v", trace)
+	}
+}
+
+func TestTraceSurvivesGroupCycles(t *testing.T) {
+	tuples := parseTuples(
+		"space:s#viewer@group:a#member",
+		"group:a#member@group:b#member",
+		"group:b#member@group:a#member",
+	)
+	trace := testTrace(t, tuples, "x", "space", "s", "view")
+	if len(trace.Grants) != 0 {
+		t.Fatalf("expected a cycle not to grant anything, got %v", trace.Grants)
+	}
+}
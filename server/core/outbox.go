@@ -0,0 +1,432 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Relationship changes go through core.permission_outbox: they are queued in
+// the transaction that writes the rows they belong to and applied to Permify
+// once it commits, right away by the request and otherwise by the relay. A
+// change that keeps failing is retried with a growing delay and finally marked
+// failed; the reconciliation job repairs what it left behind. Changes to one
+// entity are applied in order, so a change that waits for its retry only holds
+// up later changes to the same entity.
+
+const (
+	RELATION_WRITE  = "write"
+	RELATION_DELETE = "delete"
+)
+
+const (
+	outboxBatchSize       = 200
+	outboxMaxAttempts     = 12
+	outboxRetryInitial    = 5 * time.Second
+	outboxRetryMax        = 30 * time.Minute
+	outboxStructuralBatch = 100
+	// how long claimed changes are left to their applier before another one
+	// may pick them up, in case it died halfway
+	outboxClaimSeconds = 300
+)
+
+const (
+	insertOutboxChange = `INSERT INTO core.permission_outbox
+		(tenant_id, operation, entity_type, entity_id, relation, subject_type, subject_id, subject_relation, structural, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	// only held while claiming, so two appliers never claim the same changes
+	lockOutboxTenant = `SELECT pg_advisory_xact_lock(hashtext('permission_outbox'), hashtext($1))`
+
+	// due changes with no earlier change to the same entity still waiting
+	getDueOutboxChanges = `SELECT o.id, o.operation, o.entity_type, o.entity_id, o.relation, o.subject_type, o.subject_id,
+			o.subject_relation, o.structural, o.actor_id, o.attempt_count
+		FROM core.permission_outbox o
+		WHERE o.tenant_id = $1 AND o.status = 'pending' AND o.next_attempt_at <= now()
+			AND NOT EXISTS (
+				SELECT 1 FROM core.permission_outbox w
+				WHERE w.tenant_id = o.tenant_id AND w.status = 'pending' AND w.entity_type = o.entity_type
+					AND w.entity_id = o.entity_id AND w.id < o.id AND w.next_attempt_at > now()
+			)
+		ORDER BY o.id
+		LIMIT $2`
+
+	// a claim pushes the changes out of reach of other appliers until they
+	// are marked or the claim runs out
+	claimOutboxChanges = `UPDATE core.permission_outbox
+		SET next_attempt_at = now() + $2 * interval '1 second'
+		WHERE id = ANY($1)`
+
+	releaseOutboxChanges = `UPDATE core.permission_outbox SET next_attempt_at = now() WHERE id = ANY($1)`
+
+	markOutboxApplied = `UPDATE core.permission_outbox
+		SET status = 'applied', applied_at = now(), attempt_count = attempt_count + 1, last_error = NULL
+		WHERE id = ANY($1)`
+
+	markOutboxRetrying = `UPDATE core.permission_outbox
+		SET attempt_count = $2, next_attempt_at = $3, last_error = $4
+		WHERE id = $1`
+
+	markOutboxFailed = `UPDATE core.permission_outbox
+		SET status = 'failed', attempt_count = $2, last_error = $3
+		WHERE id = $1`
+)
+
+// RelationChange is one relationship write or delete. Empty fields widen a
+// delete: without a relation every relation the subject holds on the entity
+// goes, without a subject every relation on the entity.
+type RelationChange struct {
+	Operation       string
+	Entity          string
+	EntityId        string
+	Relation        string
+	Subject         string
+	SubjectId       string
+	SubjectRelation string
+	// Structural changes, such as page#parent, describe how entities hang
+	// together rather than who can access them and are not audited.
+	Structural bool
+}
+
+// RelationWrite grants relation on entity:entityId to subject:subjectId.
+func RelationWrite(entity string, entityId string, relation string, subject string, subjectId string) RelationChange {
+	return RelationChange{Operation: RELATION_WRITE, Entity: entity, EntityId: entityId, Relation: relation, Subject: subject, SubjectId: subjectId}
+}
+
+// RelationDelete removes what RelationWrite granted; see RelationChange for
+// what empty fields match.
+func RelationDelete(entity string, entityId string, relation string, subject string, subjectId string) RelationChange {
+	return RelationChange{Operation: RELATION_DELETE, Entity: entity, EntityId: entityId, Relation: relation, Subject: subject, SubjectId: subjectId}
+}
+
+// OfSubjectSet points the change at subject#subjectRelation, e.g.
+// space#editor@group#member.
+func (c RelationChange) OfSubjectSet(subjectRelation string) RelationChange {
+	c.SubjectRelation = subjectRelation
+	return c
+}
+
+// AsStructural marks the change as structural.
+func (c RelationChange) AsStructural() RelationChange {
+	c.Structural = true
+	return c
+}
+
+type outboxChange struct {
+	id       int64
+	change   RelationChange
+	actor    *uuid.UUID
+	attempts int
+}
+
+// entityKey identifies the entity whose changes have to stay in order.
+func (o outboxChange) entityKey() string {
+	return o.change.Entity + ":" + o.change.EntityId
+}
+
+// outboxAttempt is a change that did not go through and what became of it.
+type outboxAttempt struct {
+	id       int64
+	attempts int
+	next     time.Time
+	err      error
+}
+
+// outboxOutcome is what applying the claimed changes did to each of them.
+type outboxOutcome struct {
+	applied  []int64
+	retrying []outboxAttempt
+	failed   []outboxAttempt
+	// changes held back behind a retrying change to the same entity
+	released []int64
+	err      error
+}
+
+// QueueRelations adds changes to the outbox of the context's tenant as part of
+// tx, on behalf of the context's RelationActor. Call ApplyQueuedRelations once
+// tx has committed.
+func QueueRelations(ctx context.Context, tx pgx.Tx, changes ...RelationChange) error {
+	tenant := TenantFromContext(ctx)
+	actor := RelationActor(ctx)
+	for _, c := range changes {
+		if c.Operation != RELATION_WRITE && c.Operation != RELATION_DELETE {
+			return errors.New(ErrorCode_name[ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if _, err := tx.Exec(ctx, insertOutboxChange, tenant, c.Operation, c.Entity, c.EntityId, c.Relation, c.Subject, c.SubjectId, c.SubjectRelation, c.Structural, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyQueuedRelations applies the pending changes of the context's tenant.
+// A failure is only logged: the changes stay queued for the relay.
+func ApplyQueuedRelations(ctx context.Context) {
+	if err := ApplyTenantRelations(ctx, TenantFromContext(ctx)); err != nil {
+		Logger.Warn("relation changes are queued for retry: " + err.Error())
+	}
+}
+
+// ApplyRelations is for changes that have no rows of their own, such as
+// space roles. They are queued in one transaction before being applied, so a
+// failure halfway leaves the rest to the relay instead of half a change.
+func ApplyRelations(ctx context.Context, changes ...RelationChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := QueueRelations(ctx, tx, changes...); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	ApplyQueuedRelations(ctx)
+	return nil
+}
+
+// ApplyTenantRelations applies a tenant's due changes in order. A change that
+// fails only holds up later changes to its entity; the first failure that will
+// be retried is returned once the rest are through.
+func ApplyTenantRelations(ctx context.Context, tenant string) error {
+	ctx = WithTenant(ctx, tenant)
+	var applyErr error
+	for {
+		claimed, err := applyOutboxBatch(ctx, tenant)
+		if applyErr == nil {
+			applyErr = err
+		}
+		if claimed < outboxBatchSize {
+			return applyErr
+		}
+	}
+}
+
+// applyOutboxBatch claims a batch of due changes, applies them without
+// holding a transaction open and marks what happened to each. It returns how
+// many it claimed, so the caller knows whether more are waiting.
+func applyOutboxBatch(ctx context.Context, tenant string) (int, error) {
+	changes, err := claimOutboxBatch(ctx, tenant)
+	if err != nil || len(changes) == 0 {
+		return 0, err
+	}
+	outcome := runOutboxChanges(changes, func(batch []outboxChange) error {
+		return applyRelationChanges(ctx, batch)
+	})
+	if err := markOutboxOutcome(ctx, outcome); err != nil {
+		// the claim runs out and the changes are applied again, which
+		// Permify takes as a no-op
+		return len(changes), err
+	}
+	return len(changes), outcome.err
+}
+
+func claimOutboxBatch(ctx context.Context, tenant string) ([]outboxChange, error) {
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, lockOutboxTenant, tenant); err != nil {
+		return nil, err
+	}
+	changes, err := dueOutboxChanges(ctx, tx, tenant)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, c.id)
+	}
+	if _, err := tx.Exec(ctx, claimOutboxChanges, ids, outboxClaimSeconds); err != nil {
+		return nil, err
+	}
+	return changes, tx.Commit(ctx)
+}
+
+// runOutboxChanges applies changes in order. A change that failed for good
+// is marked failed right away; one that will be retried holds back the rest
+// of its entity's changes, which are released to wait behind it.
+func runOutboxChanges(changes []outboxChange, apply func([]outboxChange) error) outboxOutcome {
+	var outcome outboxOutcome
+	waiting := make(map[string]bool)
+	for start := 0; start < len(changes); {
+		if waiting[changes[start].entityKey()] {
+			outcome.released = append(outcome.released, changes[start].id)
+			start++
+			continue
+		}
+		batch := structuralRun(changes[start:])
+		for i, c := range batch {
+			if waiting[c.entityKey()] {
+				batch = batch[:i]
+				break
+			}
+		}
+		start += len(batch)
+		err := apply(batch)
+		if err == nil {
+			for _, c := range batch {
+				outcome.applied = append(outcome.applied, c.id)
+			}
+			continue
+		}
+		// a batch carries the attempt on its first change; the others are
+		// released to go again on their own
+		failed := batch[0]
+		for _, c := range batch[1:] {
+			outcome.released = append(outcome.released, c.id)
+		}
+		attempt := outboxAttempt{id: failed.id, attempts: failed.attempts + 1, err: err}
+		if attempt.attempts >= outboxMaxAttempts || permanentRelationError(err) {
+			Logger.Error("permission outbox change failed for good: " + err.Error())
+			outcome.failed = append(outcome.failed, attempt)
+			continue
+		}
+		attempt.next = time.Now().Add(outboxRetryDelay(attempt.attempts))
+		outcome.retrying = append(outcome.retrying, attempt)
+		waiting[failed.entityKey()] = true
+		if outcome.err == nil {
+			outcome.err = err
+		}
+	}
+	return outcome
+}
+
+// permanentRelationError reports whether Permify rejected a change in a way
+// no retry will fix, such as a tuple the schema does not allow.
+func permanentRelationError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func markOutboxOutcome(ctx context.Context, outcome outboxOutcome) error {
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if len(outcome.applied) > 0 {
+		if _, err := tx.Exec(ctx, markOutboxApplied, outcome.applied); err != nil {
+			return err
+		}
+	}
+	for _, f := range outcome.failed {
+		if _, err := tx.Exec(ctx, markOutboxFailed, f.id, f.attempts, f.err.Error()); err != nil {
+			return err
+		}
+	}
+	for _, r := range outcome.retrying {
+		if _, err := tx.Exec(ctx, markOutboxRetrying, r.id, r.attempts, r.next, r.err.Error()); err != nil {
+			return err
+		}
+	}
+	if len(outcome.released) > 0 {
+		if _, err := tx.Exec(ctx, releaseOutboxChanges, outcome.released); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func dueOutboxChanges(ctx context.Context, tx pgx.Tx, tenant string) ([]outboxChange, error) {
+	rows, err := tx.Query(ctx, getDueOutboxChanges, tenant, outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := make([]outboxChange, 0)
+	for rows.Next() {
+		var o outboxChange
+		c := &o.change
+		if err := rows.Scan(&o.id, &c.Operation, &c.Entity, &c.EntityId, &c.Relation, &c.Subject, &c.SubjectId, &c.SubjectRelation, &c.Structural, &o.actor, &o.attempts); err != nil {
+			return nil, err
+		}
+		changes = append(changes, o)
+	}
+	return changes, rows.Err()
+}
+
+// structuralRun returns the leading changes that can go to Permify as one
+// write: structural writes of the same relation, such as the page#parent
+// tuples of an imported space. Anything else goes on its own.
+func structuralRun(changes []outboxChange) []outboxChange {
+	first := changes[0].change
+	if !first.Structural || first.Operation != RELATION_WRITE || first.SubjectRelation != "" {
+		return changes[:1]
+	}
+	end := 1
+	seen := map[string]bool{first.EntityId: true}
+	for end < len(changes) && end < outboxStructuralBatch {
+		c := changes[end]
+		if !c.change.Structural || c.change.Operation != RELATION_WRITE || c.change.SubjectRelation != "" ||
+			c.change.Entity != first.Entity || c.change.Relation != first.Relation || c.change.Subject != first.Subject ||
+			seen[c.change.EntityId] {
+			break
+		}
+		seen[c.change.EntityId] = true
+		end++
+	}
+	return changes[:end]
+}
+
+// applyRelationChanges applies a batch on behalf of whoever queued it, not
+// whoever happens to be applying the tenant's outbox.
+func applyRelationChanges(ctx context.Context, batch []outboxChange) error {
+	ctx = WithRelationActor(ctx, batch[0].actor)
+	if len(batch) > 1 {
+		first := batch[0].change
+		subjectByEntity := make(map[string]string, len(batch))
+		for _, c := range batch {
+			subjectByEntity[c.change.EntityId] = c.change.SubjectId
+		}
+		return WriteStructuralRelations(ctx, first.Entity, first.Relation, first.Subject, subjectByEntity)
+	}
+	return applyRelationChange(ctx, batch[0].change)
+}
+
+func applyRelationChange(ctx context.Context, c RelationChange) error {
+	if c.Operation == RELATION_WRITE {
+		switch {
+		case c.Structural && c.SubjectRelation == "":
+			return WriteStructuralRelations(ctx, c.Entity, c.Relation, c.Subject, map[string]string{c.EntityId: c.SubjectId})
+		case c.SubjectRelation != "":
+			return WriteSubjectSetRelation(ctx, c.EntityId, c.Entity, c.SubjectId, c.Subject, c.SubjectRelation, c.Relation)
+		default:
+			return WriteRelations(ctx, c.EntityId, c.Entity, c.SubjectId, c.Subject, c.Relation)
+		}
+	}
+	switch {
+	case c.Subject == "":
+		return DeleteEntityRelations(ctx, c.Entity, []string{c.EntityId})
+	case c.SubjectRelation != "":
+		return DeleteSubjectSetRelation(ctx, c.EntityId, c.Entity, c.SubjectId, c.Subject, c.SubjectRelation, c.Relation)
+	case c.Relation == "":
+		return DeleteSubjectRelations(ctx, c.EntityId, c.Entity, c.SubjectId, c.Subject)
+	default:
+		return DeleteRelation(ctx, c.EntityId, c.Entity, c.SubjectId, c.Subject, c.Relation)
+	}
+}
+
+func outboxRetryDelay(attempt int) time.Duration {
+	delay := outboxRetryInitial
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= outboxRetryMax {
+			return outboxRetryMax
+		}
+	}
+	return delay
+}
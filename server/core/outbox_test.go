@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func outboxWrite(id int64, entity string, entityId string) outboxChange {
+	return outboxChange{id: id, change: RelationWrite(entity, entityId, "viewer", "user", "u1")}
+}
+
+func TestRunOutboxChangesOnlyHoldsUpTheFailingEntity(t *testing.T) {
+	changes := []outboxChange{
+		outboxWrite(1, "page", "1"),
+		outboxWrite(2, "page", "2"),
+		outboxWrite(3, "page", "1"),
+		outboxWrite(4, "space", "1"),
+	}
+	unavailable := status.Error(codes.Unavailable, "permify is down")
+	outcome := runOutboxChanges(changes, func(batch []outboxChange) error {
+		if batch[0].id == 1 {
+			return unavailable
+		}
+		return nil
+	})
+
+	if !reflect.DeepEqual(outcome.applied, []int64{2, 4}) {
+		t.Fatalf("expected the other entities to go through, got %v", outcome.applied)
+	}
+	if !reflect.DeepEqual(outcome.released, []int64{3}) {
+		t.Fatalf("expected the later change to page 1 to wait, got %v", outcome.released)
+	}
+	if len(outcome.retrying) != 1 || outcome.retrying[0].id != 1 || outcome.retrying[0].attempts != 1 {
+		t.Fatalf("expected the failing change to be retried, got %+v", outcome.retrying)
+	}
+	if len(outcome.failed) != 0 || !errors.Is(outcome.err, unavailable) {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+}
+
+func TestRunOutboxChangesFailsRejectedChangesRightAway(t *testing.T) {
+	changes := []outboxChange{outboxWrite(1, "page", "1"), outboxWrite(2, "page", "1")}
+	outcome := runOutboxChanges(changes, func(batch []outboxChange) error {
+		if batch[0].id == 1 {
+			return status.Error(codes.InvalidArgument, "relation not defined")
+		}
+		return nil
+	})
+
+	if len(outcome.failed) != 1 || outcome.failed[0].id != 1 {
+		t.Fatalf("expected the rejected change to fail, got %+v", outcome.failed)
+	}
+	if !reflect.DeepEqual(outcome.applied, []int64{2}) || len(outcome.retrying) != 0 || outcome.err != nil {
+		t.Fatalf("expected the next change to go through, got %+v", outcome)
+	}
+}
+
+func TestRunOutboxChangesGivesUpAfterMaxAttempts(t *testing.T) {
+	change := outboxWrite(1, "page", "1")
+	change.attempts = outboxMaxAttempts - 1
+	outcome := runOutboxChanges([]outboxChange{change}, func([]outboxChange) error {
+		return errors.New("connection refused")
+	})
+	if len(outcome.failed) != 1 || outcome.failed[0].attempts != outboxMaxAttempts || outcome.err != nil {
+		t.Fatalf("expected the change to fail for good, got %+v", outcome)
+	}
+}
+
+func TestRunOutboxChangesReleasesTheRestOfAFailedRun(t *testing.T) {
+	var changes []outboxChange
+	for i := int64(1); i <= 3; i++ {
+		c := outboxWrite(i, "page", strconv.FormatInt(i, 10))
+		c.change = c.change.AsStructural()
+		changes = append(changes, c)
+	}
+	outcome := runOutboxChanges(changes, func(batch []outboxChange) error {
+		if len(batch) != 3 {
+			t.Fatalf("expected one structural write, got %d changes", len(batch))
+		}
+		return errors.New("timeout")
+	})
+	if len(outcome.retrying) != 1 || outcome.retrying[0].id != 1 || !reflect.DeepEqual(outcome.released, []int64{2, 3}) {
+		t.Fatalf("expected the first change to carry the attempt, got %+v", outcome)
+	}
+}
+
+func TestPermanentRelationError(t *testing.T) {
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition} {
+		if !permanentRelationError(status.Error(code, "rejected")) {
+			t.Errorf("expected %v to be permanent", code)
+		}
+	}
+	for _, err := range []error{status.Error(codes.Unavailable, "down"), status.Error(codes.DeadlineExceeded, "slow"), errors.New("connection reset")} {
+		if permanentRelationError(err) {
+			t.Errorf("expected %v to be retried", err)
+		}
+	}
+}
@@ -2,294 +2,431 @@ package core
 
 import (
 	"context"
-	"fmt"
 	"os"
-	"sync"
+
+	"github.com/google/uuid"
 
 	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
-	permify_grpc "github.com/Permify/permify-go/grpc"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-var lock = &sync.Mutex{}
+// RelationAuditor, when set, is called after every successful relationship
+// write or delete so the change ends up in the audit log. actorId is who made
+// the change, nil for the system.
+var RelationAuditor func(actorId *uuid.UUID, action string, entity string, entityId string, subjectId string, subject string, relation string)
 
-var singleInstance *permify_grpc.Client
+// relationActorKey marks a context whose relationship changes belong to a
+// given actor rather than to whoever is signed in.
+type relationActorKey struct{}
 
-// nonSecureTokenCredentials represents a map used for storing non-secure tokens.
-// These tokens do not require transport security.
-type nonSecureTokenCredentials map[string]string
+type relationActor struct {
+	id *uuid.UUID
+}
 
-// RequireTransportSecurity indicates that transport security is not required for these credentials.
-func (c nonSecureTokenCredentials) RequireTransportSecurity() bool {
-	return false // Transport security is not required for non-secure tokens.
+// WithRelationActor attributes the relationship changes made with ctx to
+// actorId, nil being the system.
+func WithRelationActor(ctx context.Context, actorId *uuid.UUID) context.Context {
+	return context.WithValue(ctx, relationActorKey{}, relationActor{id: actorId})
 }
 
-// GetRequestMetadata retrieves the current metadata (non-secure tokens) for a request.
-func (c nonSecureTokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
-	return c, nil // Returns the non-secure tokens as metadata with no error.
+// RelationActor is who the relationship changes made with ctx are attributed
+// to: the actor set with WithRelationActor, otherwise the signed-in user.
+func RelationActor(ctx context.Context) *uuid.UUID {
+	if actor, ok := ctx.Value(relationActorKey{}).(relationActor); ok {
+		return actor.id
+	}
+	user, err := GetUserInfo(ctx)
+	if err != nil || user.AId == "" {
+		return nil
+	}
+	actorId, err := uuid.Parse(user.AId)
+	if err != nil {
+		return nil
+	}
+	return &actorId
 }
 
-func GetPermifyInstance() *permify_grpc.Client {
-	if singleInstance == nil {
-		lock.Lock()
-		defer lock.Unlock()
-		if singleInstance == nil {
-			fmt.Println("creating single instance now")
-			client, err := permify_grpc.NewClient(
-				permify_grpc.Config{
-					Endpoint: os.Getenv("PERMIFY_ENDPOINT"),
-				},
-				grpc.WithTransportCredentials(insecure.NewCredentials()),
-				grpc.WithPerRPCCredentials(nonSecureTokenCredentials{"authorization": fmt.Sprintf("Bearer %s", os.Getenv("PERMIFY_SECRET"))}),
-			)
-			if err != nil {
-				panic("unable to create permify client")
-			}
-			singleInstance = client
-		} else {
-			fmt.Println("Single instance already created.")
-		}
-	} else {
-		fmt.Println("Single instance already created.")
+func auditRelation(ctx context.Context, action string, entity string, entityId string, subjectId string, subject string, relation string) {
+	if RelationAuditor != nil {
+		RelationAuditor(RelationActor(ctx), action, entity, entityId, subjectId, subject, relation)
 	}
-	return singleInstance
 }
 
-func WriteRelations(entityId string, entity string, subjectId string, subject string, relation string) error {
-	ctx := context.Background()
-	_, err := GetPermifyInstance().Data.WriteRelationships(
-		ctx,
-		&permify_payload.RelationshipWriteRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.RelationshipWriteRequestMetadata{
-				SchemaVersion: "",
+func WriteRelations(ctx context.Context, entityId string, entity string, subjectId string, subject string, relation string) error {
+	token, err := authorizer().WriteRelationships(ctx, TenantFromContext(ctx), []*permify_payload.Tuple{
+		{
+			Entity: &permify_payload.Entity{
+				Type: entity,
+				Id:   entityId,
 			},
-			Tuples: []*permify_payload.Tuple{
-				{
-					Entity: &permify_payload.Entity{
-						Type: entity,
-						Id:   entityId,
-					},
-					Relation: relation,
-					Subject: &permify_payload.Subject{
-						Type: subject,
-						Id:   subjectId,
-					},
-				},
+			Relation: relation,
+			Subject: &permify_payload.Subject{
+				Type: subject,
+				Id:   subjectId,
 			},
 		},
-	)
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		auditRelation(ctx, "write", entity, entityId, subjectId, subject, relation)
+	}
 	return err
 }
 
-func DeleteRelation(entityId string, entity string, subjectId string, subject string, relation string) error {
-	_, err := GetPermifyInstance().Data.DeleteRelationships(
-		context.Background(),
-		&permify_payload.RelationshipDeleteRequest{
-			TenantId: "t1",
-			Filter: &permify_payload.TupleFilter{
-				Entity: &permify_payload.EntityFilter{
-					Type: entity,
-					Ids:  []string{entityId},
-				},
-				Relation: relation,
-				Subject: &permify_payload.SubjectFilter{
-					Type: subject,
-					Ids:  []string{subjectId},
-				},
+// WriteSubjectSetRelation grants relation on the entity to everyone reachable
+// through subject#subjectRelation, e.g. space#editor@group#member.
+func WriteSubjectSetRelation(ctx context.Context, entityId string, entity string, subjectId string, subject string, subjectRelation string, relation string) error {
+	token, err := authorizer().WriteRelationships(ctx, TenantFromContext(ctx), []*permify_payload.Tuple{
+		{
+			Entity: &permify_payload.Entity{
+				Type: entity,
+				Id:   entityId,
+			},
+			Relation: relation,
+			Subject: &permify_payload.Subject{
+				Type:     subject,
+				Id:       subjectId,
+				Relation: subjectRelation,
 			},
 		},
-	)
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		auditRelation(ctx, "write", entity, entityId, subjectId, subject+"#"+subjectRelation, relation)
+	}
 	return err
 }
 
-func DeleteSubjectRelations(entityId string, entity string, subjectId string, subject string) error {
-	_, err := GetPermifyInstance().Data.DeleteRelationships(
-		context.Background(),
-		&permify_payload.RelationshipDeleteRequest{
-			TenantId: "t1",
-			Filter: &permify_payload.TupleFilter{
-				Entity: &permify_payload.EntityFilter{
-					Type: entity,
-					Ids:  []string{entityId},
-				},
-				Subject: &permify_payload.SubjectFilter{
-					Type: subject,
-					Ids:  []string{subjectId},
-				},
-			},
+// DeleteSubjectSetRelation removes a tuple written by WriteSubjectSetRelation.
+// An empty relation removes every relation the subject set holds on the entity.
+func DeleteSubjectSetRelation(ctx context.Context, entityId string, entity string, subjectId string, subject string, subjectRelation string, relation string) error {
+	token, err := authorizer().DeleteRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+		Entity: &permify_payload.EntityFilter{
+			Type: entity,
+			Ids:  []string{entityId},
+		},
+		Relation: relation,
+		Subject: &permify_payload.SubjectFilter{
+			Type:     subject,
+			Ids:      []string{subjectId},
+			Relation: subjectRelation,
 		},
-	)
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		auditRelation(ctx, "delete", entity, entityId, subjectId, subject+"#"+subjectRelation, relation)
+	}
 	return err
 }
 
-func GetEntitiesWithPermission(entity string, subject string, subjectId string, permission string) ([]string, error) {
-	rr, err := GetPermifyInstance().Permission.LookupEntity(
-		context.Background(),
-		&permify_payload.PermissionLookupEntityRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.PermissionLookupEntityRequestMetadata{
-				SchemaVersion: "",
-				SnapToken:     "",
-				Depth:         20,
+// WriteStructuralRelations writes one relation per entity/subject pair in a
+// single request. It is meant for tuples describing how entities hang together
+// (such as page#parent) rather than who can access them, so the writes are not
+// reported to RelationAuditor.
+func WriteStructuralRelations(ctx context.Context, entity string, relation string, subject string, subjectByEntity map[string]string) error {
+	if len(subjectByEntity) == 0 {
+		return nil
+	}
+	tuples := make([]*permify_payload.Tuple, 0, len(subjectByEntity))
+	for entityId, subjectId := range subjectByEntity {
+		tuples = append(tuples, &permify_payload.Tuple{
+			Entity: &permify_payload.Entity{
+				Type: entity,
+				Id:   entityId,
 			},
-			EntityType: entity,
-			Permission: permission,
+			Relation: relation,
 			Subject: &permify_payload.Subject{
-				Type:     subject,
-				Id:       subjectId,
-				Relation: "",
+				Type: subject,
+				Id:   subjectId,
 			},
+		})
+	}
+	token, err := authorizer().WriteRelationships(ctx, TenantFromContext(ctx), tuples)
+	invalidatePermissions(ctx, token)
+	return err
+}
+
+func DeleteRelation(ctx context.Context, entityId string, entity string, subjectId string, subject string, relation string) error {
+	token, err := authorizer().DeleteRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+		Entity: &permify_payload.EntityFilter{
+			Type: entity,
+			Ids:  []string{entityId},
 		},
-	)
+		Relation: relation,
+		Subject: &permify_payload.SubjectFilter{
+			Type: subject,
+			Ids:  []string{subjectId},
+		},
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		auditRelation(ctx, "delete", entity, entityId, subjectId, subject, relation)
+	}
+	return err
+}
+
+func DeleteSubjectRelations(ctx context.Context, entityId string, entity string, subjectId string, subject string) error {
+	token, err := authorizer().DeleteRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+		Entity: &permify_payload.EntityFilter{
+			Type: entity,
+			Ids:  []string{entityId},
+		},
+		Subject: &permify_payload.SubjectFilter{
+			Type: subject,
+			Ids:  []string{subjectId},
+		},
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		auditRelation(ctx, "delete", entity, entityId, subjectId, subject, "")
+	}
+	return err
+}
+
+// DeleteEntityRelations removes every relation held by the given entities,
+// whatever the subject. Used when the entities themselves are going away.
+func DeleteEntityRelations(ctx context.Context, entity string, entityIds []string) error {
+	if len(entityIds) == 0 {
+		return nil
+	}
+	token, err := authorizer().DeleteRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+		Entity: &permify_payload.EntityFilter{
+			Type: entity,
+			Ids:  entityIds,
+		},
+	})
+	invalidatePermissions(ctx, token)
+	if err == nil {
+		for _, entityId := range entityIds {
+			auditRelation(ctx, "delete", entity, entityId, "", "", "")
+		}
+	}
+	return err
+}
+
+func GetEntitiesWithPermission(ctx context.Context, entity string, subject string, subjectId string, permission string) ([]string, error) {
+	entityIds, err := lookupEntities(ctx, entity, subject, subjectId, permission)
 	if err != nil {
 		return make([]string, 0), err
 	}
-	return rr.GetEntityIds(), err
+	return tokenFilterEntities(ctx, entity, subject, subjectId, permission, entityIds)
 }
 
-func CreateSubjectPermissions(entity string, entityId string, subject string, subjectId string, permission string) (string, error) {
-	rr, err := GetPermifyInstance().Data.Write(
-		context.Background(),
-		&permify_payload.DataWriteRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.DataWriteRequestMetadata{
-				SchemaVersion: "",
-			},
-			Tuples: []*permify_payload.Tuple{
-				{
-					Entity: &permify_payload.Entity{
-						Type: entity,
-						Id:   entityId,
-					},
-					Relation: permission,
-					Subject: &permify_payload.Subject{
-						Type:     subject,
-						Id:       subjectId,
-						Relation: "",
-					},
-				},
-			},
-		},
-	)
+// lookupEntities asks the authorizer which entities the subject holds permission on.
+// Callers get their own copy of a cached answer.
+func lookupEntities(ctx context.Context, entity string, subject string, subjectId string, permission string) ([]string, error) {
+	tenant := TenantFromContext(ctx)
+	key := lookupKey(tenant, entity, subject, subjectId, permission)
+	entry, ok := cachedPermission(ctx, tenant, key)
+	if ok {
+		return append([]string(nil), entry.entityIds...), nil
+	}
+	entityIds, err := authorizer().LookupEntity(ctx, tenant, snapToken(ctx, tenant), entity, permission, &permify_payload.Subject{
+		Type:     subject,
+		Id:       subjectId,
+		Relation: "",
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return rr.SnapToken, err
+	entry.entityIds = entityIds
+	storePermission(ctx, tenant, key, entry)
+	return append([]string(nil), entry.entityIds...), nil
 }
 
-func GetSubjectPermissionList(entity string, entityId string, subject string, subjectId string) map[string]permify_payload.CheckResult {
-	client := GetPermifyInstance()
-	var output map[string]permify_payload.CheckResult
-	cr, err := client.Permission.SubjectPermission(
-		context.Background(),
-		&permify_payload.PermissionSubjectPermissionRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.PermissionSubjectPermissionRequestMetadata{
-				SnapToken:      "",
-				SchemaVersion:  "",
-				OnlyPermission: false,
-				Depth:          20,
-			},
+func CreateSubjectPermissions(ctx context.Context, entity string, entityId string, subject string, subjectId string, permission string) (string, error) {
+	token, err := authorizer().WriteRelationships(ctx, TenantFromContext(ctx), []*permify_payload.Tuple{
+		{
 			Entity: &permify_payload.Entity{
 				Type: entity,
 				Id:   entityId,
 			},
+			Relation: permission,
 			Subject: &permify_payload.Subject{
 				Type:     subject,
 				Id:       subjectId,
 				Relation: "",
 			},
 		},
-	)
+	})
+	invalidatePermissions(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	auditRelation(ctx, "write", entity, entityId, subjectId, subject, permission)
+	return token, err
+}
+
+func GetSubjectPermissionList(ctx context.Context, entity string, entityId string, subject string, subjectId string) map[string]permify_payload.CheckResult {
+	var output map[string]permify_payload.CheckResult
+	results, err := authorizer().SubjectPermission(ctx, TenantFromContext(ctx), "", &permify_payload.Entity{
+		Type: entity,
+		Id:   entityId,
+	}, &permify_payload.Subject{
+		Type:     subject,
+		Id:       subjectId,
+		Relation: "",
+	}, false)
 	if err != nil {
 		Logger.Error(err.Error())
 		return output
 	}
-	output = cr.GetResults()
+	output = results
+	if token, ok := tokenFor(ctx, subject, subjectId); ok {
+		// outside spaces a space limited token only reads
+		entityAllowed := tokenEntityAllowed(ctx, token, entity, entityId, "view")
+		readOnly := len(token.SpaceIds) > 0 && entity != "space" && entity != "page"
+		for permission := range output {
+			if !entityAllowed || !tokenScopeAllows(token, entity, permission) || (readOnly && permission != "view") {
+				output[permission] = permify_payload.CheckResult_CHECK_RESULT_DENIED
+			}
+		}
+	}
 	return output
 }
 
-func GetListOfEntitiesWithPermission(subject string, subjectId string, permission string, entity string) ([]string, error) {
-	client := GetPermifyInstance()
-	var entityIds []string
-	str, err := client.Permission.LookupEntity(
-		context.Background(),
-		&permify_payload.PermissionLookupEntityRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.PermissionLookupEntityRequestMetadata{
-				SchemaVersion: "",
-				Depth:         20,
-				SnapToken:     "",
-			},
-			EntityType: entity,
-			Permission: permission,
-			Subject: &permify_payload.Subject{
-				Type: subject,
-				Id:   subjectId,
-			},
-		},
-	)
+func GetListOfEntitiesWithPermission(ctx context.Context, subject string, subjectId string, permission string, entity string) ([]string, error) {
+	entityIds, err := lookupEntities(ctx, entity, subject, subjectId, permission)
 	if err != nil {
 		Logger.Error(err.Error())
 		return entityIds, err
 	}
-	entityIds = str.GetEntityIds()
-	return entityIds, nil
+	entityIds, err = tokenFilterEntities(ctx, entity, subject, subjectId, permission, entityIds)
+	if err != nil {
+		Logger.Error(err.Error())
+	}
+	return entityIds, err
 }
 
-func CheckPermission(entity string, entityId string, subject string, subjectId string, permission string) (bool, error) {
-	cr, err := GetPermifyInstance().Permission.Check(
-		context.Background(),
-		&permify_payload.PermissionCheckRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.PermissionCheckRequestMetadata{
-				SchemaVersion: "",
-				SnapToken:     "",
-				Depth:         20,
-			},
-			Entity: &permify_payload.Entity{
-				Type: entity,
-				Id:   entityId,
-			},
-			Permission: permission,
-			Subject: &permify_payload.Subject{
-				Type:     subject,
-				Id:       subjectId,
-				Relation: "",
-			},
-		},
-	)
+func CheckPermission(ctx context.Context, entity string, entityId string, subject string, subjectId string, permission string) (bool, error) {
+	if token, ok := tokenFor(ctx, subject, subjectId); ok {
+		if !tokenScopeAllows(token, entity, permission) || !tokenEntityAllowed(ctx, token, entity, entityId, permission) {
+			return false, nil
+		}
+	}
+	tenant := TenantFromContext(ctx)
+	key := checkKey(tenant, entity, entityId, subject, subjectId, permission)
+	entry, ok := cachedPermission(ctx, tenant, key)
+	if ok {
+		return entry.allowed, nil
+	}
+	can, err := authorizer().Check(ctx, tenant, snapToken(ctx, tenant), &permify_payload.Entity{
+		Type: entity,
+		Id:   entityId,
+	}, permission, &permify_payload.Subject{
+		Type:     subject,
+		Id:       subjectId,
+		Relation: "",
+	})
 	if err != nil {
 		Logger.Error(err.Error())
 		return false, err
 	}
-	return cr.Can == permify_payload.CheckResult_CHECK_RESULT_ALLOWED, nil
+	entry.allowed = can
+	storePermission(ctx, tenant, key, entry)
+	return entry.allowed, nil
 }
 
-func GetSubjectsAssociatedWithEntity(entity string, entityId string) ([]*permify_payload.Tuple, error) {
+// CheckPermissions answers several permissions on one entity. Whatever is not
+// cached yet comes from a single authorizer call listing every permission the
+// subject has on the entity; names that call does not cover, such as plain
+// relations, fall back to CheckPermission.
+func CheckPermissions(ctx context.Context, entity string, entityId string, subject string, subjectId string, permissions ...string) (map[string]bool, error) {
+	tenant := TenantFromContext(ctx)
+	allowed := make(map[string]bool, len(permissions))
+	missing := make([]string, 0, len(permissions))
+	var generation uint64
+	for _, permission := range permissions {
+		entry, ok := cachedPermission(ctx, tenant, checkKey(tenant, entity, entityId, subject, subjectId, permission))
+		if ok {
+			allowed[permission] = entry.allowed
+			continue
+		}
+		if len(missing) == 0 {
+			generation = entry.generation
+		}
+		missing = append(missing, permission)
+	}
+	if len(missing) > 0 {
+		results, err := authorizer().SubjectPermission(ctx, tenant, snapToken(ctx, tenant), &permify_payload.Entity{
+			Type: entity,
+			Id:   entityId,
+		}, &permify_payload.Subject{
+			Type:     subject,
+			Id:       subjectId,
+			Relation: "",
+		}, true)
+		if err != nil {
+			Logger.Error(err.Error())
+			return allowed, err
+		}
+		for permission, result := range results {
+			entry := permissionEntry{allowed: result == permify_payload.CheckResult_CHECK_RESULT_ALLOWED, generation: generation}
+			storePermission(ctx, tenant, checkKey(tenant, entity, entityId, subject, subjectId, permission), entry)
+		}
+		for _, permission := range missing {
+			if result, ok := results[permission]; ok {
+				allowed[permission] = result == permify_payload.CheckResult_CHECK_RESULT_ALLOWED
+				continue
+			}
+			can, err := CheckPermission(ctx, entity, entityId, subject, subjectId, permission)
+			if err != nil {
+				return allowed, err
+			}
+			allowed[permission] = can
+		}
+	}
+	if token, ok := tokenFor(ctx, subject, subjectId); ok {
+		for permission, can := range allowed {
+			if can && (!tokenScopeAllows(token, entity, permission) || !tokenEntityAllowed(ctx, token, entity, entityId, permission)) {
+				allowed[permission] = false
+			}
+		}
+	}
+	return allowed, nil
+}
 
-	client := GetPermifyInstance()
-	data, err := client.Data.ReadRelationships(
-		context.Background(),
-		&permify_payload.RelationshipReadRequest{
-			TenantId: "t1",
-			Metadata: &permify_payload.RelationshipReadRequestMetadata{
-				SnapToken: "",
-			},
-			Filter: &permify_payload.TupleFilter{
-				Entity: &permify_payload.EntityFilter{
-					Type: entity,
-					Ids:  []string{entityId},
-				},
-			},
+func GetSubjectsAssociatedWithEntity(ctx context.Context, entity string, entityId string) ([]*permify_payload.Tuple, error) {
+	tuples, _, err := authorizer().ReadRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+		Entity: &permify_payload.EntityFilter{
+			Type: entity,
+			Ids:  []string{entityId},
 		},
-	)
-
+	}, 0, "")
 	if err != nil {
 		Logger.Error(err.Error())
 		return nil, err
 	}
-	return data.Tuples, nil
+	return tuples, nil
+}
+
+// ReadEntityRelationships pages through every tuple on entities of one type
+// in the context's tenant.
+func ReadEntityRelationships(ctx context.Context, entity string) ([]*permify_payload.Tuple, error) {
+	tuples := make([]*permify_payload.Tuple, 0)
+	token := ""
+	for {
+		page, next, err := authorizer().ReadRelationships(ctx, TenantFromContext(ctx), &permify_payload.TupleFilter{
+			Entity: &permify_payload.EntityFilter{
+				Type: entity,
+			},
+		}, 100, token)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, page...)
+		token = next
+		if token == "" || len(page) == 0 {
+			return tuples, nil
+		}
+	}
+}
+
+// CreateTenant provisions a tenant and writes the schema found at
+// PERMIFY_SCHEMA_PATH to it.
+func CreateTenant(ctx context.Context, tenantId string, name string) error {
+	schema, err := os.ReadFile(schemaPath())
+	if err != nil {
+		return err
+	}
+	return authorizer().CreateTenant(ctx, tenantId, name, string(schema))
 }
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	permify_payload "buf.build/gen/go/permifyco/permify/protocolbuffers/go/base/v1"
+	permify_grpc "github.com/Permify/permify-go/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// permifyDepth bounds how far Permify follows relations for one answer.
+const permifyDepth = 20
+
+// nonSecureTokenCredentials represents a map used for storing non-secure tokens.
+// These tokens do not require transport security.
+type nonSecureTokenCredentials map[string]string
+
+// RequireTransportSecurity indicates that transport security is not required for these credentials.
+func (c nonSecureTokenCredentials) RequireTransportSecurity() bool {
+	return false // Transport security is not required for non-secure tokens.
+}
+
+// GetRequestMetadata retrieves the current metadata (non-secure tokens) for a request.
+func (c nonSecureTokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return c, nil // Returns the non-secure tokens as metadata with no error.
+}
+
+// permifyAuthorizer talks to a Permify deployment over gRPC. The connection is
+// made lazily, so an unreachable Permify fails calls instead of startup.
+type permifyAuthorizer struct {
+	client *permify_grpc.Client
+}
+
+func newPermifyAuthorizer() (*permifyAuthorizer, error) {
+	client, err := permify_grpc.NewClient(
+		permify_grpc.Config{
+			Endpoint: os.Getenv("PERMIFY_ENDPOINT"),
+		},
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(nonSecureTokenCredentials{"authorization": fmt.Sprintf("Bearer %s", os.Getenv("PERMIFY_SECRET"))}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create permify client: %w", err)
+	}
+	return &permifyAuthorizer{client: client}, nil
+}
+
+func (p *permifyAuthorizer) WriteRelationships(ctx context.Context, tenant string, tuples []*permify_payload.Tuple) (string, error) {
+	rr, err := p.client.Data.WriteRelationships(
+		ctx,
+		&permify_payload.RelationshipWriteRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.RelationshipWriteRequestMetadata{
+				SchemaVersion: "",
+			},
+			Tuples: tuples,
+		},
+	)
+	return rr.GetSnapToken(), err
+}
+
+func (p *permifyAuthorizer) DeleteRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter) (string, error) {
+	rr, err := p.client.Data.DeleteRelationships(
+		ctx,
+		&permify_payload.RelationshipDeleteRequest{
+			TenantId: tenant,
+			Filter:   filter,
+		},
+	)
+	return rr.GetSnapToken(), err
+}
+
+func (p *permifyAuthorizer) ReadRelationships(ctx context.Context, tenant string, filter *permify_payload.TupleFilter, pageSize uint32, continuousToken string) ([]*permify_payload.Tuple, string, error) {
+	data, err := p.client.Data.ReadRelationships(
+		ctx,
+		&permify_payload.RelationshipReadRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.RelationshipReadRequestMetadata{
+				SnapToken: "",
+			},
+			Filter:          filter,
+			PageSize:        pageSize,
+			ContinuousToken: continuousToken,
+		},
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	return data.GetTuples(), data.GetContinuousToken(), nil
+}
+
+func (p *permifyAuthorizer) Check(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, permission string, subject *permify_payload.Subject) (bool, error) {
+	cr, err := p.client.Permission.Check(
+		ctx,
+		&permify_payload.PermissionCheckRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.PermissionCheckRequestMetadata{
+				SchemaVersion: "",
+				SnapToken:     snapToken,
+				Depth:         permifyDepth,
+			},
+			Entity:     entity,
+			Permission: permission,
+			Subject:    subject,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return cr.Can == permify_payload.CheckResult_CHECK_RESULT_ALLOWED, nil
+}
+
+func (p *permifyAuthorizer) SubjectPermission(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, subject *permify_payload.Subject, onlyPermission bool) (map[string]permify_payload.CheckResult, error) {
+	cr, err := p.client.Permission.SubjectPermission(
+		ctx,
+		&permify_payload.PermissionSubjectPermissionRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.PermissionSubjectPermissionRequestMetadata{
+				SnapToken:      snapToken,
+				SchemaVersion:  "",
+				OnlyPermission: onlyPermission,
+				Depth:          permifyDepth,
+			},
+			Entity:  entity,
+			Subject: subject,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cr.GetResults(), nil
+}
+
+func (p *permifyAuthorizer) LookupEntity(ctx context.Context, tenant string, snapToken string, entityType string, permission string, subject *permify_payload.Subject) ([]string, error) {
+	rr, err := p.client.Permission.LookupEntity(
+		ctx,
+		&permify_payload.PermissionLookupEntityRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.PermissionLookupEntityRequestMetadata{
+				SchemaVersion: "",
+				SnapToken:     snapToken,
+				Depth:         permifyDepth,
+			},
+			EntityType: entityType,
+			Permission: permission,
+			Subject:    subject,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rr.GetEntityIds(), nil
+}
+
+func (p *permifyAuthorizer) Expand(ctx context.Context, tenant string, snapToken string, entity *permify_payload.Entity, permission string) (*permify_payload.Expand, error) {
+	er, err := p.client.Permission.Expand(
+		ctx,
+		&permify_payload.PermissionExpandRequest{
+			TenantId: tenant,
+			Metadata: &permify_payload.PermissionExpandRequestMetadata{
+				SchemaVersion: "",
+				SnapToken:     snapToken,
+			},
+			Entity:     entity,
+			Permission: permission,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return er.GetTree(), nil
+}
+
+func (p *permifyAuthorizer) CreateTenant(ctx context.Context, tenantId string, name string, schema string) error {
+	_, err := p.client.Tenancy.Create(ctx, &permify_payload.TenantCreateRequest{
+		Id:   tenantId,
+		Name: name,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = p.client.Schema.Write(ctx, &permify_payload.SchemaWriteRequest{
+		TenantId: tenantId,
+		Schema:   schema,
+	})
+	return err
+}
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRelationActorPrefersExplicitActor(t *testing.T) {
+	if actor := RelationActor(context.Background()); actor != nil {
+		t.Fatalf("expected no actor without a signed-in user, got %v", actor)
+	}
+	actorId := uuid.New()
+	ctx := WithRelationActor(context.Background(), &actorId)
+	if actor := RelationActor(ctx); actor == nil || *actor != actorId {
+		t.Fatalf("expected the explicit actor, got %v", actor)
+	}
+	// the relay applies system changes with an explicit nil actor
+	if actor := RelationActor(WithRelationActor(ctx, nil)); actor != nil {
+		t.Fatalf("expected the system, got %v", actor)
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Permission answers are reused at two levels. Within a request each check
+// goes to Permify at most once, through the memo Authenticated puts on the
+// context. Across requests raw answers are kept for PERMIFY_CACHE_TTL_SECONDS.
+// Every relationship write or delete moves its tenant to a new generation, so
+// changes made through this server are seen by the very next check; changes
+// made by another server instance show up once the TTL runs out.
+//
+// Only what Permify said is kept. Access token limits are applied on top of
+// the cached answer on every call.
+
+const (
+	defaultPermissionCacheTTL = 5 * time.Second
+	maxPermissionCacheEntries = 50000
+)
+
+type permissionEntry struct {
+	allowed    bool
+	entityIds  []string
+	generation uint64
+	expires    time.Time
+}
+
+type permissionCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	now         func() time.Time
+	generations map[string]uint64
+	entries     map[string]permissionEntry
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{
+		ttl:         ttl,
+		now:         time.Now,
+		generations: make(map[string]uint64),
+		entries:     make(map[string]permissionEntry),
+	}
+}
+
+var (
+	permissionCacheOnce   sync.Once
+	sharedPermissionCache *permissionCache
+)
+
+// permissionResults returns the process wide cache. It is built on first use
+// so the TTL is read after the environment has been loaded.
+func permissionResults() *permissionCache {
+	permissionCacheOnce.Do(func() {
+		sharedPermissionCache = newPermissionCache(permissionCacheTTL())
+	})
+	return sharedPermissionCache
+}
+
+func permissionCacheTTL() time.Duration {
+	value := strings.TrimSpace(os.Getenv("PERMIFY_CACHE_TTL_SECONDS"))
+	if value == "" {
+		return defaultPermissionCacheTTL
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return defaultPermissionCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (c *permissionCache) generation(tenant string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[tenant]
+}
+
+// invalidate makes every answer cached for the tenant stale, including the
+// ones held by request memos.
+func (c *permissionCache) invalidate(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[tenant]++
+}
+
+func (c *permissionCache) get(tenant string, key string) (permissionEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return entry, false
+	}
+	if entry.generation != c.generations[tenant] || !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return entry, false
+	}
+	return entry, true
+}
+
+// put keeps the answer unless the tenant changed since it was asked for.
+func (c *permissionCache) put(tenant string, key string, entry permissionEntry) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.generation != c.generations[tenant] {
+		return
+	}
+	if len(c.entries) >= maxPermissionCacheEntries {
+		now := c.now()
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxPermissionCacheEntries {
+			c.entries = make(map[string]permissionEntry)
+		}
+	}
+	entry.expires = c.now().Add(c.ttl)
+	c.entries[key] = entry
+}
+
+type permissionMemoKey struct{}
+
+type permissionMemo struct {
+	mu      sync.Mutex
+	entries map[string]permissionEntry
+	// snap token of the request's last relationship change per tenant, so
+	// the checks that follow it see the change
+	snapTokens map[string]string
+}
+
+// WithPermissionMemo makes permission checks made with the returned context
+// share their answers. It keeps an existing memo.
+func WithPermissionMemo(ctx context.Context) context.Context {
+	if memo, ok := ctx.Value(permissionMemoKey{}).(*permissionMemo); ok && memo != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, permissionMemoKey{}, &permissionMemo{entries: make(map[string]permissionEntry), snapTokens: make(map[string]string)})
+}
+
+// WithoutPermissionMemo is for requests that stay open, such as event
+// streams, where answers memoized at the start would go on being used long
+// after the shared cache has let them go.
+func WithoutPermissionMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, permissionMemoKey{}, (*permissionMemo)(nil))
+}
+
+// invalidatePermissions follows a relationship change made with ctx.
+func invalidatePermissions(ctx context.Context, token string) {
+	tenant := TenantFromContext(ctx)
+	permissionResults().invalidate(tenant)
+	if memo, ok := ctx.Value(permissionMemoKey{}).(*permissionMemo); ok && memo != nil && token != "" {
+		memo.mu.Lock()
+		memo.snapTokens[tenant] = token
+		memo.mu.Unlock()
+	}
+}
+
+func snapToken(ctx context.Context, tenant string) string {
+	memo, ok := ctx.Value(permissionMemoKey{}).(*permissionMemo)
+	if !ok || memo == nil {
+		return ""
+	}
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	return memo.snapTokens[tenant]
+}
+
+func checkKey(tenant string, entity string, entityId string, subject string, subjectId string, permission string) string {
+	return tenant + "|check|" + entity + ":" + entityId + "#" + permission + "@" + subject + ":" + subjectId
+}
+
+func lookupKey(tenant string, entity string, subject string, subjectId string, permission string) string {
+	return tenant + "|lookup|" + entity + "#" + permission + "@" + subject + ":" + subjectId
+}
+
+// cachedPermission answers from the request memo or the shared cache. On a
+// miss the returned entry carries the generation to store the answer under.
+func cachedPermission(ctx context.Context, tenant string, key string) (permissionEntry, bool) {
+	cache := permissionResults()
+	generation := cache.generation(tenant)
+	memo, _ := ctx.Value(permissionMemoKey{}).(*permissionMemo)
+	if memo != nil {
+		memo.mu.Lock()
+		entry, ok := memo.entries[key]
+		memo.mu.Unlock()
+		if ok && entry.generation == generation {
+			return entry, true
+		}
+	}
+	entry, ok := cache.get(tenant, key)
+	if !ok {
+		return permissionEntry{generation: generation}, false
+	}
+	if memo != nil {
+		memo.mu.Lock()
+		memo.entries[key] = entry
+		memo.mu.Unlock()
+	}
+	return entry, true
+}
+
+func storePermission(ctx context.Context, tenant string, key string, entry permissionEntry) {
+	if memo, ok := ctx.Value(permissionMemoKey{}).(*permissionMemo); ok && memo != nil {
+		memo.mu.Lock()
+		memo.entries[key] = entry
+		memo.mu.Unlock()
+	}
+	permissionResults().put(tenant, key, entry)
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPermissionCacheExpires(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newPermissionCache(5 * time.Second)
+	cache.now = func() time.Time { return now }
+	cache.put("t1", "k", permissionEntry{allowed: true})
+	if entry, ok := cache.get("t1", "k"); !ok || !entry.allowed {
+		t.Fatalf("expected a cached answer, got %+v %v", entry, ok)
+	}
+	now = now.Add(5 * time.Second)
+	if _, ok := cache.get("t1", "k"); ok {
+		t.Fatalf("expected the answer to expire")
+	}
+}
+
+func TestPermissionCacheInvalidatesPerTenant(t *testing.T) {
+	cache := newPermissionCache(time.Minute)
+	cache.put("t1", "a", permissionEntry{allowed: true})
+	cache.put("t2", "b", permissionEntry{allowed: true})
+	cache.invalidate("t1")
+	if _, ok := cache.get("t1", "a"); ok {
+		t.Fatalf("expected a write to make the tenant's answers stale")
+	}
+	if _, ok := cache.get("t2", "b"); !ok {
+		t.Fatalf("other tenants should keep their answers")
+	}
+	// an answer asked for before the write is not kept
+	cache.put("t1", "a", permissionEntry{allowed: true, generation: 0})
+	if _, ok := cache.get("t1", "a"); ok {
+		t.Fatalf("expected an answer from an older generation to be dropped")
+	}
+}
+
+func TestPermissionCacheDisabled(t *testing.T) {
+	cache := newPermissionCache(0)
+	cache.put("t1", "k", permissionEntry{allowed: true})
+	if _, ok := cache.get("t1", "k"); ok {
+		t.Fatalf("a zero TTL should not cache")
+	}
+}
+
+func TestPermissionMemoFollowsWrites(t *testing.T) {
+	ctx := WithTenant(WithPermissionMemo(context.Background()), "memo-tenant")
+	key := checkKey("memo-tenant", "page", "1", "user", "u", PAGE_VIEW)
+	entry, ok := cachedPermission(ctx, "memo-tenant", key)
+	if ok {
+		t.Fatalf("expected nothing cached yet")
+	}
+	entry.allowed = true
+	storePermission(ctx, "memo-tenant", key, entry)
+	if got, ok := cachedPermission(ctx, "memo-tenant", key); !ok || !got.allowed {
+		t.Fatalf("expected the memoized answer, got %+v %v", got, ok)
+	}
+	invalidatePermissions(ctx, "snap")
+	if token := snapToken(ctx, "memo-tenant"); token != "snap" {
+		t.Fatalf("expected the write's snap token, got %q", token)
+	}
+	if _, ok := cachedPermission(ctx, "memo-tenant", key); ok {
+		t.Fatalf("expected a write to make the memo stale")
+	}
+	if _, ok := cachedPermission(WithoutPermissionMemo(ctx), "memo-tenant", checkKey("memo-tenant", "page", "2", "user", "u", PAGE_VIEW)); ok {
+		t.Fatalf("expected nothing cached for another page")
+	}
+}
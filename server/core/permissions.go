@@ -12,6 +12,7 @@ const (
 	SPACE_MANAGE_MEMBERS = "manage_members"
 	SPACE_TRANSFER_OWNER = "transfer_owner"
 	SPACE_ARCHIVE        = "archive"
+	SPACE_VIEW_AUDIT     = "view_audit"
 
 	// page permissions
 	PAGE_EDIT        = "edit"
@@ -0,0 +1,346 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The embedded authorizer reads the same schema file as Permify. Only the
+// parts permify/schema.perm uses are understood: entities with relations and
+// permissions built from relations, other permissions, relation.permission
+// walks, "and", "or", "not" and parentheses. As in Permify, "not" excludes:
+// "a not b" holds when a holds and b does not. "or" binds loosest.
+
+type authzSchema struct {
+	entities map[string]*schemaEntity
+}
+
+type schemaEntity struct {
+	name        string
+	relations   map[string][]schemaSubject
+	permissions map[string]*permissionExpr
+	// declaration order, so answers listing every name are stable
+	names []string
+}
+
+type schemaSubject struct {
+	entity   string
+	relation string
+}
+
+type permissionExpr struct {
+	op          string
+	left, right *permissionExpr
+	// leaves name a relation or permission, reached through the relation in
+	// via when it is set, e.g. parent.view
+	name string
+	via  string
+}
+
+const (
+	exprLeaf = "leaf"
+	exprOr   = "or"
+	exprAnd  = "and"
+	exprNot  = "not"
+)
+
+type schemaToken struct {
+	text string
+	line int
+}
+
+func tokenizeSchema(source string) []schemaToken {
+	tokens := make([]schemaToken, 0)
+	line := 1
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, schemaToken{text: source[start:i], line: line})
+		default:
+			tokens = append(tokens, schemaToken{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens
+}
+
+type schemaParser struct {
+	tokens []schemaToken
+	pos    int
+}
+
+func (p *schemaParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *schemaParser) next() string {
+	text := p.peek()
+	p.pos++
+	return text
+}
+
+func (p *schemaParser) errorf(format string, args ...any) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("schema line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *schemaParser) expect(text string) error {
+	if p.peek() != text {
+		return p.errorf("expected %q, found %q", text, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *schemaParser) identifier() (string, error) {
+	text := p.peek()
+	if text == "" || !(text[0] == '_' || unicode.IsLetter(rune(text[0]))) {
+		return "", p.errorf("expected a name, found %q", text)
+	}
+	p.pos++
+	return text, nil
+}
+
+// parseSchema parses and checks a schema: every name a permission uses has
+// to be declared on its entity, and every subject type has to exist.
+func parseSchema(source string) (*authzSchema, error) {
+	p := &schemaParser{tokens: tokenizeSchema(source)}
+	schema := &authzSchema{entities: make(map[string]*schemaEntity)}
+	for p.peek() != "" {
+		if err := p.expect("entity"); err != nil {
+			return nil, err
+		}
+		entity, err := p.parseEntity()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := schema.entities[entity.name]; ok {
+			return nil, p.errorf("entity %s is declared twice", entity.name)
+		}
+		schema.entities[entity.name] = entity
+	}
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (p *schemaParser) parseEntity() (*schemaEntity, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	entity := &schemaEntity{
+		name:        name,
+		relations:   make(map[string][]schemaSubject),
+		permissions: make(map[string]*permissionExpr),
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for p.peek() != "}" {
+		keyword := p.next()
+		member, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if entity.declares(member) {
+			return nil, p.errorf("%s.%s is declared twice", name, member)
+		}
+		switch keyword {
+		case "relation":
+			subjects, err := p.parseSubjects()
+			if err != nil {
+				return nil, err
+			}
+			entity.relations[member] = subjects
+		case "permission":
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			entity.permissions[member] = expr
+		default:
+			return nil, p.errorf("unsupported %q in entity %s", keyword, name)
+		}
+		entity.names = append(entity.names, member)
+	}
+	p.pos++
+	return entity, nil
+}
+
+func (p *schemaParser) parseSubjects() ([]schemaSubject, error) {
+	subjects := make([]schemaSubject, 0)
+	for p.peek() == "@" {
+		p.pos++
+		entity, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		subject := schemaSubject{entity: entity}
+		if p.peek() == "#" {
+			p.pos++
+			if subject.relation, err = p.identifier(); err != nil {
+				return nil, err
+			}
+		}
+		subjects = append(subjects, subject)
+	}
+	if len(subjects) == 0 {
+		return nil, p.errorf("a relation needs at least one subject type")
+	}
+	return subjects, nil
+}
+
+func (p *schemaParser) parseOr() (*permissionExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == exprOr {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &permissionExpr{op: exprOr, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *schemaParser) parseAnd() (*permissionExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == exprAnd || p.peek() == exprNot {
+		op := p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = &permissionExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *schemaParser) parseOperand() (*permissionExpr, error) {
+	if p.peek() == "(" {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if name == exprOr || name == exprAnd || name == exprNot {
+		p.pos--
+		return nil, p.errorf("expected a name, found %q", name)
+	}
+	leaf := &permissionExpr{op: exprLeaf, name: name}
+	if p.peek() == "." {
+		p.pos++
+		leaf.via = name
+		if leaf.name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+	}
+	return leaf, nil
+}
+
+func (e *schemaEntity) declares(name string) bool {
+	_, relation := e.relations[name]
+	_, permission := e.permissions[name]
+	return relation || permission
+}
+
+func (s *authzSchema) validate() error {
+	for _, entity := range s.entities {
+		for relation, subjects := range entity.relations {
+			for _, subject := range subjects {
+				target, ok := s.entities[subject.entity]
+				if !ok {
+					return fmt.Errorf("schema: %s.%s refers to unknown entity %s", entity.name, relation, subject.entity)
+				}
+				if subject.relation != "" && !target.declares(subject.relation) {
+					return fmt.Errorf("schema: %s.%s refers to unknown %s#%s", entity.name, relation, subject.entity, subject.relation)
+				}
+			}
+		}
+		for permission, expr := range entity.permissions {
+			if err := s.validateExpr(entity, permission, expr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *authzSchema) validateExpr(entity *schemaEntity, permission string, expr *permissionExpr) error {
+	if expr.op != exprLeaf {
+		if err := s.validateExpr(entity, permission, expr.left); err != nil {
+			return err
+		}
+		return s.validateExpr(entity, permission, expr.right)
+	}
+	if expr.via == "" {
+		if !entity.declares(expr.name) {
+			return fmt.Errorf("schema: %s.%s uses unknown %s", entity.name, permission, expr.name)
+		}
+		return nil
+	}
+	subjects, ok := entity.relations[expr.via]
+	if !ok {
+		return fmt.Errorf("schema: %s.%s walks unknown relation %s", entity.name, permission, expr.via)
+	}
+	for _, subject := range subjects {
+		if s.entities[subject.entity].declares(expr.name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("schema: %s.%s uses %s.%s, which no subject of %s declares", entity.name, permission, expr.via, expr.name, expr.via)
+}
+
+// allows reports whether the schema lets subject hold relation on entity.
+func (s *authzSchema) allows(entity string, relation string, subject string, subjectRelation string) bool {
+	def, ok := s.entities[entity]
+	if !ok {
+		return false
+	}
+	for _, allowed := range def.relations[relation] {
+		if allowed.entity == subject && allowed.relation == subjectRelation {
+			return true
+		}
+	}
+	return false
+}
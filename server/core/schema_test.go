@@ -0,0 +1,140 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestSchema(t *testing.T) *authzSchema {
+	t.Helper()
+	source, err := os.ReadFile("../../permify/schema.perm")
+	if err != nil {
+		t.Fatalf("reading schema: %v", err)
+	}
+	schema, err := parseSchema(string(source))
+	if err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+	return schema
+}
+
+func parseTuples(lines ...string) map[string][]relationTuple {
+	tuples := make(map[string][]relationTuple)
+	for _, line := range lines {
+		// page:1#space@space:s or space:s#viewer@group:g#member
+		entityPart, subjectPart, _ := strings.Cut(line, "@")
+		entityRef, relation, _ := strings.Cut(entityPart, "#")
+		entity, entityId, _ := strings.Cut(entityRef, ":")
+		subjectRef, subjectRelation, _ := strings.Cut(subjectPart, "#")
+		subject, subjectId, _ := strings.Cut(subjectRef, ":")
+		key := entity + ":" + entityId
+		tuples[key] = append(tuples[key], relationTuple{
+			entity: entity, entityId: entityId, relation: relation,
+			subject: subject, subjectId: subjectId, subjectRelation: subjectRelation,
+		})
+	}
+	return tuples
+}
+
+func testCheck(t *testing.T, schema *authzSchema, tuples map[string][]relationTuple, user string, entity string, entityId string, permission string) bool {
+	t.Helper()
+	eval := newEvaluation(schema, relationSubject{entity: "user", id: user}, func(entity string, entityId string) ([]relationTuple, error) {
+		return tuples[entity+":"+entityId], nil
+	})
+	can, err := eval.check(entity, entityId, permission, 0)
+	if err != nil {
+		t.Fatalf("check %s:%s#%s for %s: %v", entity, entityId, permission, user, err)
+	}
+	return can
+}
+
+func TestEmbeddedEvaluatesSpaceRoles(t *testing.T) {
+	schema := loadTestSchema(t)
+	tuples := parseTuples(
+		"space:s#owner@user:owner",
+		"space:s#viewer@user:reader",
+		"space:s#editor@group:writers#member",
+		"group:writers#member@group:nested#member",
+		"group:nested#member@user:writer",
+		"page:1#space@space:s",
+	)
+	cases := []struct {
+		user, entity, id, permission string
+		want                         bool
+	}{
+		{"owner", "space", "s", "transfer_owner", true},
+		{"reader", "space", "s", "view", true},
+		{"reader", "space", "s", "edit_page", false},
+		{"writer", "space", "s", "edit_page", true},
+		{"writer", "page", "1", "edit", true},
+		{"reader", "page", "1", "view", true},
+		{"reader", "page", "1", "edit", false},
+		{"stranger", "page", "1", "view", false},
+	}
+	for _, c := range cases {
+		if got := testCheck(t, schema, tuples, c.user, c.entity, c.id, c.permission); got != c.want {
+			t.Errorf("%s %s:%s#%s: expected %v, got %v", c.user, c.entity, c.id, c.permission, c.want, got)
+		}
+	}
+}
+
+func TestEmbeddedEvaluatesInheritedRestrictions(t *testing.T) {
+	schema := loadTestSchema(t)
+	tuples := parseTuples(
+		"space:s#admin@user:admin",
+		"space:s#viewer@user:reader",
+		"space:s#viewer@user:granted",
+		"space:s#viewer@group:team#member",
+		"group:team#member@user:teammate",
+		"page:1#space@space:s",
+		"page:1#view_restricted@space:s",
+		"page:1#restricted_viewer@user:granted",
+		"page:1#restricted_viewer@group:team#member",
+		"page:2#space@space:s",
+		"page:2#parent@page:1",
+	)
+	cases := []struct {
+		user, id string
+		want     bool
+	}{
+		{"reader", "1", false},
+		{"reader", "2", false},
+		{"granted", "2", true},
+		{"teammate", "2", true},
+		{"admin", "2", true},
+	}
+	for _, c := range cases {
+		if got := testCheck(t, schema, tuples, c.user, "page", c.id, "view"); got != c.want {
+			t.Errorf("%s page:%s#view: expected %v, got %v", c.user, c.id, c.want, got)
+		}
+	}
+}
+
+func TestEmbeddedSurvivesGroupCycles(t *testing.T) {
+	schema := loadTestSchema(t)
+	tuples := parseTuples(
+		"group:a#member@group:b#member",
+		"group:b#member@group:a#member",
+		"group:b#member@user:u",
+	)
+	if !testCheck(t, schema, tuples, "u", "group", "a", "view") {
+		t.Fatalf("expected membership through the other group")
+	}
+	if testCheck(t, schema, tuples, "x", "group", "a", "view") {
+		t.Fatalf("expected a cycle not to grant anything")
+	}
+}
+
+func TestParseSchemaRejectsUnknownNames(t *testing.T) {
+	for _, source := range []string{
+		"entity user {} entity doc { relation owner @user permission view = owner or editor }",
+		"entity doc { relation owner @person }",
+		"entity user {} entity doc { relation parent @doc permission view = parent.missing }",
+		"entity user {} entity doc { relation owner @user permission view = owner or }",
+	} {
+		if _, err := parseSchema(source); err == nil {
+			t.Errorf("expected %q to be rejected", source)
+		}
+	}
+}
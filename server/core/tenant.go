@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTenant is the Permify tenant of the default organization, which holds
+// every relation written before organizations existed.
+const DefaultTenant = "t1"
+
+// DefaultOrganization is the organization backfilled with all existing spaces
+// and users.
+var DefaultOrganization = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+type tenantKey struct{}
+
+type organizationKey struct{}
+
+// WithTenant scopes Permify calls made with the returned context to tenantId.
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantId)
+}
+
+// TenantFromContext returns the Permify tenant set by WithTenant, falling back
+// to DefaultTenant.
+func TenantFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if tenantId, ok := ctx.Value(tenantKey{}).(string); ok && tenantId != "" {
+			return tenantId
+		}
+	}
+	return DefaultTenant
+}
+
+// WithOrganization scopes the request to an organization and its tenant.
+func WithOrganization(ctx context.Context, orgId uuid.UUID, tenantId string) context.Context {
+	return WithTenant(context.WithValue(ctx, organizationKey{}, orgId), tenantId)
+}
+
+// OrganizationFromContext returns the organization set by WithOrganization,
+// falling back to DefaultOrganization.
+func OrganizationFromContext(ctx context.Context) uuid.UUID {
+	if ctx != nil {
+		if orgId, ok := ctx.Value(organizationKey{}).(uuid.UUID); ok && orgId != uuid.Nil {
+			return orgId
+		}
+	}
+	return DefaultOrganization
+}
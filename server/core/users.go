@@ -180,10 +180,15 @@ func SearchUsersByIds(userIds []string) (UserSearchResponse, error) {
 	}
 	filters := make([]Filter, 0)
 	filters = append(filters, filter)
+	// the search is paged, so ask for every id in one page
+	limit := uint32(len(userIds))
+	if limit < 10 {
+		limit = 10
+	}
 	var query = UserSearchQuery{
 		Query: Query{
 			Offset: 0,
-			Limit:  10,
+			Limit:  limit,
 			ASC:    true,
 		},
 		SortingColumn: "USER_FIELD_NAME_UNSPECIFIED",
@@ -377,6 +382,9 @@ func GetBeskarUser(zitadelId string) (string, error) {
 
 func GetUserInfo(ctx context.Context) (UserInfo, error) {
 	var user UserInfo
+	if user, ok := accessTokenUser(ctx); ok {
+		return user, nil
+	}
 	if !authentication.IsAuthenticated(ctx) {
 		return user, errors.New("not authenticated")
 	}
@@ -424,3 +432,44 @@ func GetZitaIds(userIds []string) ([]ZitaUser, error) {
 	}
 	return zitaIds, nil
 }
+
+// UserProfile is the display subset of a Zitadel user.
+type UserProfile struct {
+	Name  string
+	Email string
+}
+
+// GetUserProfiles resolves beskar user ids to display names and emails. Ids
+// without a Zitadel mapping are left out of the result.
+func GetUserProfiles(userIds []string) (map[string]UserProfile, error) {
+	profiles := make(map[string]UserProfile, len(userIds))
+	if len(userIds) == 0 {
+		return profiles, nil
+	}
+	zitaMapRows, err := GetZitaIds(userIds)
+	if err != nil {
+		return profiles, err
+	}
+	beskarIDByZitaID := make(map[string]string, len(zitaMapRows))
+	zitaIDs := make([]string, 0, len(zitaMapRows))
+	for _, row := range zitaMapRows {
+		beskarIDByZitaID[row.Id] = row.UserId
+		zitaIDs = append(zitaIDs, row.Id)
+	}
+	if len(zitaIDs) == 0 {
+		return profiles, nil
+	}
+	usersDetails, err := SearchUsersByIds(zitaIDs)
+	if err != nil {
+		return profiles, err
+	}
+	for _, result := range usersDetails.Result {
+		if beskarID, ok := beskarIDByZitaID[result.UserId]; ok {
+			profiles[beskarID] = UserProfile{
+				Name:  result.Human.Profile.DisplayName,
+				Email: result.Human.Email.Email,
+			}
+		}
+	}
+	return profiles, nil
+}
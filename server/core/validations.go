@@ -7,8 +7,8 @@ import (
 	"github.com/google/uuid"
 )
 
-func ValidateUserSpacePermissions(spaceId uuid.UUID, userId uuid.UUID, permission string) bool {
-	cr, err := CheckPermission("space", spaceId.String(), "user", userId.String(), permission)
+func ValidateUserSpacePermissions(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, permission string) bool {
+	cr, err := CheckPermission(ctx, "space", spaceId.String(), "user", userId.String(), permission)
 	if err != nil {
 		Logger.Error(err.Error())
 		return false
@@ -16,8 +16,8 @@ func ValidateUserSpacePermissions(spaceId uuid.UUID, userId uuid.UUID, permissio
 	return cr
 }
 
-func ValidateUserPagePermission(pageId string, userId uuid.UUID, permission string) bool {
-	cr, err := CheckPermission("page", pageId, "user", userId.String(), permission)
+func ValidateUserPagePermission(ctx context.Context, pageId string, userId uuid.UUID, permission string) bool {
+	cr, err := CheckPermission(ctx, "page", pageId, "user", userId.String(), permission)
 	if err != nil {
 		Logger.Error(err.Error())
 		return false
@@ -25,8 +25,8 @@ func ValidateUserPagePermission(pageId string, userId uuid.UUID, permission stri
 	return cr
 }
 
-func ValidateUserEntityPermission(entity string, entityId string, userId uuid.UUID, permission string) bool {
-	cr, err := CheckPermission(entity, entityId, "user", userId.String(), permission)
+func ValidateUserEntityPermission(ctx context.Context, entity string, entityId string, userId uuid.UUID, permission string) bool {
+	cr, err := CheckPermission(ctx, entity, entityId, "user", userId.String(), permission)
 	if err != nil {
 		Logger.Error(err.Error())
 		return false
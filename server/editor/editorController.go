@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/analytics"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -51,7 +53,7 @@ func getDocumentToView(w http.ResponseWriter, r *http.Request) {
 	ownerId := uuid.MustParse(userId)
 	spaceId := uuid.MustParse(chi.URLParam(r, "spaceId"))
 	pageId := chi.URLParam(r, "pageId")
-	validSpaceUserPermissions := core.ValidateUserPagePermission(pageId, ownerId, "view")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), pageId, ownerId, "view")
 	if !validSpaceUserPermissions {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
@@ -61,7 +63,7 @@ func getDocumentToView(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to get document")
 		return
 	}
-	outputDocument, err := GetDocumentView(page, spaceId, ownerId)
+	outputDocument, err := GetDocumentView(r.Context(), page, spaceId, ownerId)
 	if errors.Is(err, pgx.ErrNoRows) {
 		core.SendSuccessResponse(w, r, http.StatusOK, nil)
 		return
@@ -70,6 +72,7 @@ func getDocumentToView(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to get document")
 		return
 	}
+	analytics.Views.Record(page, spaceId, ownerId)
 	core.SendSuccessResponse(w, r, http.StatusOK, outputDocument)
 }
 
@@ -88,7 +91,7 @@ func getDocumentToEdit(w http.ResponseWriter, r *http.Request) {
 	ownerId := uuid.MustParse(userId)
 	spaceId := uuid.MustParse(chi.URLParam(r, "spaceId"))
 	pageId := chi.URLParam(r, "pageId")
-	validSpaceUserPermissions := core.ValidateUserPagePermission(pageId, ownerId, "edit")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), pageId, ownerId, "edit")
 	if !validSpaceUserPermissions {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
@@ -137,7 +140,7 @@ func saveDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	inputDoc.OwnerId = uuid.MustParse(userId)
-	validSpaceUserPermissions := core.ValidateUserSpacePermissions(inputDoc.SpaceId, inputDoc.OwnerId, "edit_page")
+	validSpaceUserPermissions := core.ValidateUserSpacePermissions(r.Context(), inputDoc.SpaceId, inputDoc.OwnerId, "edit_page")
 	if !validSpaceUserPermissions {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
@@ -145,12 +148,20 @@ func saveDoc(w http.ResponseWriter, r *http.Request) {
 	if !ensureMutableSpace(w, r, inputDoc.SpaceId) {
 		return
 	}
-	pageId, err := inputDoc.Create()
+	pageId, err := inputDoc.Create(r.Context())
 	if err != nil {
 		logger().Error(err.Error())
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to create new page")
 		return
 	}
+	activity.Record(activity.Event{
+		SpaceId:   inputDoc.SpaceId,
+		PageId:    activity.PageRef(pageId),
+		ActorId:   inputDoc.OwnerId,
+		ActorName: user.Name,
+		Type:      activity.PageCreated,
+		Data:      map[string]interface{}{"title": inputDoc.Title},
+	})
 	type PageId struct {
 		Page int64 `json:"page"`
 	}
@@ -184,7 +195,7 @@ func publishDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	inputDoc.OwnerId = uuid.MustParse(userId)
-	validSpaceUserPermissions := core.ValidateUserPagePermission(fmt.Sprintf("%v", inputDoc.Id), inputDoc.OwnerId, "edit")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), fmt.Sprintf("%v", inputDoc.Id), inputDoc.OwnerId, "edit")
 	if !validSpaceUserPermissions {
 		render.Status(r, http.StatusForbidden)
 		render.Render(w, r, core.NewFailedResponse(http.StatusForbidden, core.FAILURE, core.FAILURE, "Invalid space permissions"))
@@ -204,6 +215,15 @@ func publishDoc(w http.ResponseWriter, r *http.Request) {
 		render.Render(w, r, core.NewFailedResponse(http.StatusInternalServerError, core.FAILURE, core.FAILURE, "Unable to update document"))
 		return
 	}
+	activity.Record(activity.Event{
+		SpaceId:   inputDoc.SpaceId,
+		PageId:    activity.PageRef(inputDoc.Id),
+		ActorId:   inputDoc.OwnerId,
+		ActorName: user.Name,
+		Type:      activity.PagePublished,
+		Data:      map[string]interface{}{"title": inputDoc.Title},
+	})
+	notifyPageWatchers(ctx, user, inputDoc.SpaceId, inputDoc.Id, inputDoc.Title)
 
 	type PageId struct {
 		Page int64 `json:"page"`
@@ -238,7 +258,7 @@ func updateDraftDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	inputDoc.OwnerId = uuid.MustParse(userId)
-	validSpaceUserPermissions := core.ValidateUserPagePermission(fmt.Sprintf("%v", inputDoc.Id), inputDoc.OwnerId, "edit")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), fmt.Sprintf("%v", inputDoc.Id), inputDoc.OwnerId, "edit")
 	if !validSpaceUserPermissions {
 		render.Status(r, http.StatusForbidden)
 		render.Render(w, r, core.NewFailedResponse(http.StatusForbidden, core.FAILURE, core.FAILURE, "Invalid space permissions"))
@@ -275,7 +295,7 @@ func deleteDocument(w http.ResponseWriter, r *http.Request) {
 	userId := user.AId
 	ownerId := uuid.MustParse(userId)
 	pageId := chi.URLParam(r, "pageId")
-	validSpaceUserPermissions := core.ValidateUserPagePermission(pageId, ownerId, "delete")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), pageId, ownerId, "delete")
 	if !validSpaceUserPermissions {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
@@ -293,11 +313,20 @@ func deleteDocument(w http.ResponseWriter, r *http.Request) {
 	if !ensureMutableSpace(w, r, spaceId) {
 		return
 	}
-	rowsAffected, err := DeleteDocument(page, spaceId, ownerId)
+	title := latestPageTitle(ctx, page)
+	rowsAffected, err := DeleteDocument(ctx, page, spaceId, ownerId)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to delete document")
 		return
 	}
+	activity.Record(activity.Event{
+		SpaceId:   spaceId,
+		PageId:    activity.PageRef(page),
+		ActorId:   ownerId,
+		ActorName: user.Name,
+		Type:      activity.PageDeleted,
+		Data:      map[string]interface{}{"title": title},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, rowsAffected)
 }
 
@@ -317,7 +346,7 @@ func getPageMetadataHandler(w http.ResponseWriter, r *http.Request) {
 	spaceId := uuid.MustParse(chi.URLParam(r, "spaceId"))
 	pageIdStr := chi.URLParam(r, "pageId")
 
-	validSpaceUserPermissions := core.ValidateUserPagePermission(pageIdStr, ownerId, "view")
+	validSpaceUserPermissions := core.ValidateUserPagePermission(r.Context(), pageIdStr, ownerId, "view")
 	if !validSpaceUserPermissions {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
@@ -356,7 +385,7 @@ func getPageInlineLinkMetadataHandler(w http.ResponseWriter, r *http.Request) {
 	spaceId := uuid.MustParse(chi.URLParam(r, "spaceId"))
 	pageIdStr := chi.URLParam(r, "pageId")
 
-	if !core.ValidateUserPagePermission(pageIdStr, ownerId, "view") {
+	if !core.ValidateUserPagePermission(r.Context(), pageIdStr, ownerId, "view") {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Invalid space permissions")
 		return
 	}
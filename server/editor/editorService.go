@@ -8,10 +8,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/durgakiran/beskar/analytics"
 	attachmentservices "github.com/durgakiran/beskar/attachment/services"
 	"github.com/durgakiran/beskar/comment"
 	"github.com/durgakiran/beskar/core"
 	"github.com/durgakiran/beskar/page"
+	"github.com/durgakiran/beskar/slug"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -163,6 +165,11 @@ func fetchContent(conn pgx.Tx, ctx context.Context, docId int64) (NodeData, erro
 	return NodeData{Content: nodes, Text: textNodes}, err
 }
 
+// FetchContent returns the content nodes stored for a published doc version.
+func FetchContent(conn pgx.Tx, ctx context.Context, docId int64) (NodeData, error) {
+	return fetchContent(conn, ctx, docId)
+}
+
 func fetchContentToEdit(conn pgx.Tx, ctx context.Context, docId int64) (ContentDraft, error) {
 	var nodes ContentDraft
 	rows, err := conn.Query(ctx, getBinaryDocument, docId)
@@ -182,6 +189,28 @@ func fetchContentToEdit(conn pgx.Tx, ctx context.Context, docId int64) (ContentD
 	return nodes, err
 }
 
+// replaceDraftContent swaps the content nodes stored on a draft doc. Text
+// nodes go with their parents.
+func replaceDraftContent(conn pgx.Tx, ctx context.Context, docId int64, nodes NodeData) error {
+	if _, err := conn.Exec(ctx, deleteDocContent, docId); err != nil {
+		logger().Error(err.Error())
+		return err
+	}
+	for _, child := range nodes.Content {
+		child.DocId = docId
+		if _, err := child.Create(conn, ctx); err != nil {
+			return err
+		}
+	}
+	for _, child := range nodes.Text {
+		child.DocId = docId
+		if _, err := child.Create(conn, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (d Doc) Publish() int64 {
 	return int64(0)
 }
@@ -267,9 +296,8 @@ func GetSpace(id uuid.UUID) Space {
 	return space
 }
 
-func (document InputDocument) Create() (int64, error) {
+func (document InputDocument) Create(ctx context.Context) (int64, error) {
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to acquire a connection: " + err.Error())
@@ -293,13 +321,25 @@ func (document InputDocument) Create() (int64, error) {
 	if err != nil {
 		return pageId, err
 	}
-	_, err = core.CreateSubjectPermissions("page", fmt.Sprintf("%v", pageId), "space", document.SpaceId.String(), "space")
-	// TODO: use snap token
-	if err != nil {
+	if err = slug.SyncPageSlug(ctx, tx, pageId, document.Title); err != nil {
+		return pageId, err
+	}
+	pageID := fmt.Sprintf("%v", pageId)
+	relations := []core.RelationChange{core.RelationWrite("page", pageID, "space", "space", document.SpaceId.String())}
+	// link to the parent so page restrictions are inherited
+	if document.ParentId > 0 {
+		relations = append(relations, core.RelationWrite("page", pageID, "parent", "page", fmt.Sprintf("%v", document.ParentId)).AsStructural())
+	}
+	if err = core.QueueRelations(ctx, tx, relations...); err != nil {
 		logger().Error(err.Error())
 		return pageId, err
 	}
-	tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return pageId, err
+	}
+	// checks made with ctx from here on read at the write's snap token
+	core.ApplyQueuedRelations(ctx)
 	// return created page id
 	return pageId, nil
 }
@@ -338,6 +378,10 @@ func (document InputDocument) Publish() (int64, error) {
 		if err != nil {
 			return document.Id, err
 		}
+		// the published nodes replace any a draft was pushed with
+		if _, err = tx.Exec(ctx, deleteDocContent, docId); err != nil {
+			return document.Id, err
+		}
 	}
 	// create content
 	for _, child := range document.Nodes.Content {
@@ -360,6 +404,10 @@ func (document InputDocument) Publish() (int64, error) {
 	if err := comment.PromoteComments(ctx, tx, document.Id); err != nil {
 		return document.Id, err
 	}
+	// the published title decides the slug, drafts do not
+	if err := slug.SyncPageSlug(ctx, tx, document.Id, document.Title); err != nil {
+		return document.Id, err
+	}
 	// delete drafts for given docId
 	// ===== put delete on hold for now =====
 	// draftContent := ContentDraft{DocId: docId}
@@ -426,6 +474,13 @@ func (document InputDraftDocument) Update() (int64, error) {
 		}
 		docId = existingDocument.DocId
 	}
+	// drafts pushed as content nodes replace whatever the draft held and
+	// leave the binary state empty so editors load from the nodes
+	if len(document.Data) == 0 && len(document.Nodes.Content) > 0 {
+		if err = replaceDraftContent(tx, ctx, docId, document.Nodes); err != nil {
+			return document.Id, err
+		}
+	}
 	ContentDraft := ContentDraft{DocId: docId, Data: document.Data}
 	if existingDocument.DocId != 0 {
 		_, err = ContentDraft.Update(tx, ctx)
@@ -440,13 +495,9 @@ func (document InputDraftDocument) Update() (int64, error) {
 	return document.Id, nil
 }
 
-func GetDocument(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (OutputDocument, error) {
+func GetDocument(ctx context.Context, pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (OutputDocument, error) {
 	var outputDocument OutputDocument
-	permissions := core.GetSubjectPermissionList("page", fmt.Sprintf("%v", pageId), "user", ownerId.String())
-	fmt.Println(permissions)
 	connPool := core.GetPool()
-	// TODO: Attach permissions to response
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to start transaction" + err.Error())
@@ -475,6 +526,15 @@ func GetDocument(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (OutputDocu
 	return outputDocument, nil
 }
 
+// latestPageTitle is used to label activity for pages that are about to go away.
+func latestPageTitle(ctx context.Context, pageId int64) string {
+	var title string
+	if err := core.GetPool().QueryRow(ctx, getLatestPageTitle, pageId).Scan(&title); err != nil {
+		return ""
+	}
+	return title
+}
+
 func fetchViewSpaceSummary(conn pgx.Tx, ctx context.Context, spaceId uuid.UUID) (ViewSpaceSummary, error) {
 	var summary ViewSpaceSummary
 	err := conn.QueryRow(ctx, getViewSpaceSummary, spaceId).Scan(&summary.Name, &summary.ArchivedAt)
@@ -511,25 +571,61 @@ func attachmentTypeLabel(mimeType string, fileName string) string {
 	return ext
 }
 
-func buildCapabilities(pageId int64, ownerId uuid.UUID, archived bool) ViewCapabilities {
-	pageID := fmt.Sprintf("%v", pageId)
-	canEdit, _ := core.CheckPermission("page", pageID, "user", ownerId.String(), core.PAGE_EDIT)
-	canDelete, _ := core.CheckPermission("page", pageID, "user", ownerId.String(), core.PAGE_DELETE)
-	canComment, _ := core.CheckPermission("page", pageID, "user", ownerId.String(), core.PAGE_ADD_COMMENT)
+func buildCapabilities(ctx context.Context, pageId int64, ownerId uuid.UUID, archived bool) ViewCapabilities {
+	allowed, _ := core.CheckPermissions(ctx, "page", fmt.Sprintf("%v", pageId), "user", ownerId.String(), core.PAGE_EDIT, core.PAGE_DELETE, core.PAGE_ADD_COMMENT)
+	canEdit, canDelete, canComment := allowed[core.PAGE_EDIT], allowed[core.PAGE_DELETE], allowed[core.PAGE_ADD_COMMENT]
 
 	return ViewCapabilities{
-		CanEdit:    canEdit && !archived,
-		CanDelete:  canDelete && !archived,
-		CanComment: canComment,
-		CanShare:   true,
+		CanEdit:     canEdit && !archived,
+		CanDelete:   canDelete && !archived,
+		CanComment:  canComment,
+		CanShare:    true,
+		CanRestrict: canEdit && !archived,
 	}
 }
 
-func GetDocumentView(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (OutputDocumentView, error) {
+// crumbHrefs links every crumb through its slug path. A crumb falls back to
+// the id link when its chain is incomplete, has no slugs yet or passes a
+// restricted ancestor, since the path would give that ancestor away.
+func crumbHrefs(ctx context.Context, spaceId uuid.UUID, crumbs []page.Crumb) map[int64]string {
+	hrefs := make(map[int64]string, len(crumbs))
+	byId := make(map[int64]page.Crumb, len(crumbs))
+	ids := make([]int64, 0, len(crumbs))
+	for _, crumb := range crumbs {
+		hrefs[crumb.Id] = fmt.Sprintf("/space/%s/view/%d", spaceId.String(), crumb.Id)
+		byId[crumb.Id] = crumb
+		ids = append(ids, crumb.Id)
+	}
+	spaceSlug, err := slug.SpaceSlug(ctx, spaceId)
+	if err != nil || spaceSlug == "" {
+		return hrefs
+	}
+	pageSlugs, err := slug.PageSlugs(ctx, ids)
+	if err != nil {
+		return hrefs
+	}
+	for _, crumb := range crumbs {
+		chain := make([]string, 0, len(crumbs))
+		current, ok := crumb, true
+		for ok && len(chain) <= len(crumbs) {
+			if current.Restricted || pageSlugs[current.Id] == "" {
+				break
+			}
+			chain = append([]string{pageSlugs[current.Id]}, chain...)
+			if current.ParentId <= 0 {
+				hrefs[crumb.Id] = slug.Path(spaceSlug, chain...)
+				break
+			}
+			current, ok = byId[current.ParentId]
+		}
+	}
+	return hrefs
+}
+
+func GetDocumentView(ctx context.Context, pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (OutputDocumentView, error) {
 	var output OutputDocumentView
 
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to start transaction" + err.Error())
@@ -553,9 +649,19 @@ func GetDocumentView(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (Output
 	if err != nil {
 		return output, err
 	}
+	views, err := analytics.CountPageViews(ctx, pageId)
+	if err != nil {
+		logger().Error(err.Error())
+	}
+	meta.Views = views
+	restrictions, err := page.GetRestrictionSummary(ctx, pageId)
+	if err != nil {
+		return output, err
+	}
+	meta.Restrictions = restrictions
 
 	var document *OutputDocument
-	doc, err := GetDocument(pageId, spaceId, ownerId)
+	doc, err := GetDocument(ctx, pageId, spaceId, ownerId)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return output, err
 	}
@@ -563,19 +669,23 @@ func GetDocumentView(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (Output
 		document = &doc
 	}
 
-	crumbs, err := page.GetPageBreadCrumbs(pageId)
+	crumbs, err := page.GetVisibleBreadCrumbs(ctx, pageId, ownerId)
 	if err != nil {
 		return output, err
 	}
 
+	hrefs := crumbHrefs(ctx, spaceId, crumbs)
 	viewCrumbs := make([]ViewBreadcrumb, 0, len(crumbs))
 	for _, crumb := range crumbs {
-		href := fmt.Sprintf("/space/%s/view/%d", spaceId.String(), crumb.Id)
-		viewCrumbs = append(viewCrumbs, ViewBreadcrumb{
+		viewCrumb := ViewBreadcrumb{
 			Id:    crumb.Id,
 			Title: crumb.Name,
-			Href:  &href,
-		})
+		}
+		if !crumb.Restricted {
+			href := hrefs[crumb.Id]
+			viewCrumb.Href = &href
+		}
+		viewCrumbs = append(viewCrumbs, viewCrumb)
 	}
 
 	attachmentRecords, err := attachmentservices.ListAttachmentsForPage(ctx, pageId)
@@ -593,7 +703,7 @@ func GetDocumentView(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (Output
 		})
 	}
 
-	capabilities := buildCapabilities(pageId, ownerId, summary.ArchivedAt != nil)
+	capabilities := buildCapabilities(ctx, pageId, ownerId, summary.ArchivedAt != nil)
 
 	output = OutputDocumentView{
 		PageID:       pageId,
@@ -649,6 +759,13 @@ func GetDocumentToEdit(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (Outp
 			return outputDocument, err
 		}
 		outputDocument.Data = nodes
+		if len(nodes.Data) == 0 {
+			// drafts pushed as content nodes have no binary state yet
+			outputDocument.Nodes, err = fetchContent(tx, ctx, doc.DocId)
+			if err != nil {
+				return outputDocument, err
+			}
+		}
 	} else {
 		nodes, err := fetchContent(tx, ctx, doc.DocId)
 		if err != nil {
@@ -667,9 +784,8 @@ func GetDocumentToEdit(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (Outp
 	return outputDocument, nil
 }
 
-func DeleteDocument(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (int64, error) {
+func DeleteDocument(ctx context.Context, pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (int64, error) {
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to start transaction" + err.Error())
@@ -686,6 +802,16 @@ func DeleteDocument(pageId int64, spaceId uuid.UUID, ownerId uuid.UUID) (int64,
 	if err != nil {
 		return rowsAffected, err
 	}
-	tx.Commit(ctx)
+	if rowsAffected > 0 {
+		if err = core.QueueRelations(ctx, tx, core.RelationDelete("page", fmt.Sprintf("%v", pageId), "", "", "")); err != nil {
+			logger().Error(err.Error())
+			return 0, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return 0, err
+	}
+	core.ApplyQueuedRelations(ctx)
 	return rowsAffected, nil
 }
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const pageUpdateCategory = notification.CategoryPageUpdate
+
+// notifyPageWatchers tells everyone who starred the page, other than the
+// editor, that a new version of it was published. Users who can no longer
+// view the page are left out.
+func notifyPageWatchers(ctx context.Context, editor core.UserInfo, spaceId uuid.UUID, pageId int64, title string) {
+	if err := sendPageUpdateNotifications(ctx, editor, spaceId, pageId, title); err != nil {
+		logger().Error("failed to notify page watchers", zap.Int64("page_id", pageId), zap.Error(err))
+	}
+}
+
+func sendPageUpdateNotifications(ctx context.Context, editor core.UserInfo, spaceId uuid.UUID, pageId int64, title string) error {
+	editorId, err := uuid.Parse(editor.AId)
+	if err != nil {
+		return err
+	}
+	rows, err := core.GetPool().Query(ctx, getPageWatchers, pageId, editorId)
+	if err != nil {
+		return err
+	}
+	watchers, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil || len(watchers) == 0 {
+		return err
+	}
+	docId := strconv.FormatInt(pageId, 10)
+	recipients := make([]uuid.UUID, 0, len(watchers))
+	ids := make([]string, 0, len(watchers))
+	for _, userId := range watchers {
+		if !core.ValidateUserPagePermission(ctx, docId, userId, core.PAGE_VIEW) {
+			continue
+		}
+		recipients = append(recipients, userId)
+		ids = append(ids, userId.String())
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+	profiles, err := core.GetUserProfiles(ids)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(title) == "" {
+		title = "Untitled"
+	}
+	editorName := strings.TrimSpace(editor.Name)
+	if editorName == "" {
+		editorName = "Someone"
+	}
+	config := notification.LoadConfig()
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	pagePath := fmt.Sprintf("/space/%s/view/%d", spaceId, pageId)
+	// every publish is news, so emails are keyed by the publish, not the page
+	publishId := uuid.New()
+	for _, userId := range recipients {
+		n := inbox.Notification{
+			UserId:    userId,
+			Category:  pageUpdateCategory,
+			Type:      inbox.PageUpdated,
+			Title:     fmt.Sprintf("%s updated %s", editorName, title),
+			Url:       pagePath,
+			SpaceId:   &spaceId,
+			PageId:    &pageId,
+			ActorId:   &editorId,
+			ActorName: editorName,
+			Data:      map[string]interface{}{"title": title},
+		}
+		if profile := profiles[userId.String()]; strings.TrimSpace(profile.Email) != "" {
+			n.Email = &notification.EnqueueEmailRequest{
+				MessageKey:  fmt.Sprintf("%s:%d:%s:%s", notification.TemplatePageUpdated, pageId, publishId, userId),
+				Category:    pageUpdateCategory,
+				TemplateKey: notification.TemplatePageUpdated,
+				Recipient: notification.EmailRecipient{
+					UserID: &userId,
+					Email:  profile.Email,
+					Name:   profile.Name,
+				},
+				TemplateData: map[string]any{
+					"editor_name": editorName,
+					"page_title":  title,
+					"page_url":    appURL + pagePath,
+					"app_url":     appURL + "/",
+				},
+				Priority: notification.PriorityNormal,
+			}
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
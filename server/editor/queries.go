@@ -53,6 +53,7 @@ const (
 	updateDraftDocument = `UPDATE core.content_draft SET data_binary = $2 WHERE doc_id = $1 RETURNING id`
 	getBinaryDocument   = `SELECT id, doc_id, data_binary as data FROM core.content_draft cd WHERE cd.doc_id = $1`
 	deleteDraftDocument = `DELETE FROM core.content_draft WHERE doc_id = $1`
+	deleteDocContent    = `DELETE FROM core.content WHERE doc_id = $1`
 	deleteDocumentQuery = `DELETE FROM core.page WHERE id = $1 AND space_id = $2`
 
 	// Whiteboard page creation (type-aware)
@@ -80,6 +81,8 @@ const (
 								ORDER BY d.version DESC
 								LIMIT 1`
 
+	getLatestPageTitle = `SELECT COALESCE(d.title, '') FROM core.page_doc_map d WHERE d.page_id = $1 ORDER BY d.version DESC LIMIT 1`
+
 	getViewSpaceSummary = `SELECT s.name, s.archived_at
 FROM core.space s
 WHERE s.id = $1`
@@ -90,3 +93,6 @@ WHERE d.page_id = $1 AND d.draft = 0
 ORDER BY d.version DESC
 LIMIT 1`
 )
+
+// getPageWatchers lists who starred a page, except the given user
+const getPageWatchers = "SELECT user_id FROM core.starred_pages WHERE page_id = $1 AND user_id <> $2"
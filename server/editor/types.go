@@ -3,6 +3,7 @@ package editor
 import (
 	"time"
 
+	"github.com/durgakiran/beskar/page"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -89,9 +90,12 @@ type ContentDraft struct {
 	Data  []byte `json:"data" data:"data"`
 }
 
+// InputDraftDocument carries a draft either as the editor's binary state or,
+// for clients without one, as content nodes.
 type InputDraftDocument struct {
 	Document
-	Data []byte `json:"data"`
+	Data  []byte   `json:"data"`
+	Nodes NodeData `json:"nodeData"`
 }
 
 type OutputDocument struct {
@@ -111,17 +115,20 @@ type ViewSpaceSummary struct {
 }
 
 type ViewCapabilities struct {
-	CanEdit    bool `json:"canEdit"`
-	CanDelete  bool `json:"canDelete"`
-	CanComment bool `json:"canComment"`
-	CanShare   bool `json:"canShare"`
+	CanEdit     bool `json:"canEdit"`
+	CanDelete   bool `json:"canDelete"`
+	CanComment  bool `json:"canComment"`
+	CanShare    bool `json:"canShare"`
+	CanRestrict bool `json:"canRestrict"`
 }
 
 type ViewMeta struct {
-	CreatedByName *string    `json:"createdByName,omitempty"`
-	UpdatedByName *string    `json:"updatedByName,omitempty"`
-	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
-	PublishedAt   *time.Time `json:"publishedAt,omitempty"`
+	CreatedByName *string                 `json:"createdByName,omitempty"`
+	UpdatedByName *string                 `json:"updatedByName,omitempty"`
+	UpdatedAt     *time.Time              `json:"updatedAt,omitempty"`
+	PublishedAt   *time.Time              `json:"publishedAt,omitempty"`
+	Views         int64                   `json:"views"`
+	Restrictions  page.RestrictionSummary `json:"restrictions"`
 }
 
 type ViewAttachment struct {
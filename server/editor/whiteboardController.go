@@ -5,12 +5,19 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/analytics"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// Whiteboards autosave continuously, so updates by the same user are folded
+// into one activity entry per window.
+const whiteboardActivityWindow = 15 * time.Minute
+
 func createWhiteboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user, err := core.GetUserInfo(ctx)
@@ -48,7 +55,7 @@ func createWhiteboard(w http.ResponseWriter, r *http.Request) {
 	inputDoc.OwnerId = userId
 
 	// Authorization Check: Does the user have permission to create pages in this space?
-	hasPermission := core.ValidateUserSpacePermissions(inputDoc.SpaceId, inputDoc.OwnerId, "edit_page")
+	hasPermission := core.ValidateUserSpacePermissions(r.Context(), inputDoc.SpaceId, inputDoc.OwnerId, "edit_page")
 	if !hasPermission {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Not enough permissions to add whiteboard to space")
 		return
@@ -57,12 +64,20 @@ func createWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pageId, err := CreateWhiteboard(inputDoc)
+	pageId, err := CreateWhiteboard(r.Context(), inputDoc)
 	if err != nil {
 		logger().Error(fmt.Sprintf("createWhiteboard: %s", err.Error()))
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Could not create Whiteboard")
 		return
 	}
+	activity.Record(activity.Event{
+		SpaceId:   inputDoc.SpaceId,
+		PageId:    activity.PageRef(pageId),
+		ActorId:   userId,
+		ActorName: user.Name,
+		Type:      activity.WhiteboardCreated,
+		Data:      map[string]interface{}{"title": inputDoc.Title},
+	})
 
 	type PageId struct {
 		Page int64 `json:"page"`
@@ -96,7 +111,7 @@ func getWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasPermission := core.ValidateUserPagePermission(pageIdStr, userId, "view")
+	hasPermission := core.ValidateUserPagePermission(r.Context(), pageIdStr, userId, "view")
 	if !hasPermission {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Permission Denied: User cannot view whiteboard")
 		return
@@ -114,6 +129,7 @@ func getWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	analytics.Views.Record(pageId, spaceId, userId)
 	core.SendSuccessResponse(w, r, http.StatusOK, outputDoc)
 }
 
@@ -144,7 +160,7 @@ func updateWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasPermission := core.ValidateUserPagePermission(pageIdStr, userId, "edit")
+	hasPermission := core.ValidateUserPagePermission(r.Context(), pageIdStr, userId, "edit")
 	if !hasPermission {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Permission Denied: User cannot edit whiteboard")
 		return
@@ -176,6 +192,14 @@ func updateWhiteboard(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Could not update Whiteboard")
 		return
 	}
+	activity.RecordThrottled(activity.Event{
+		SpaceId:   spaceId,
+		PageId:    activity.PageRef(pageId),
+		ActorId:   userId,
+		ActorName: user.Name,
+		Type:      activity.WhiteboardUpdated,
+		Data:      map[string]interface{}{"title": latestPageTitle(ctx, pageId)},
+	}, whiteboardActivityWindow)
 
 	core.SendSuccessResponse(w, r, http.StatusOK, "Whiteboard updated")
 }
@@ -207,7 +231,7 @@ func deleteWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasPermission := core.ValidateUserPagePermission(pageIdStr, userId, "delete")
+	hasPermission := core.ValidateUserPagePermission(r.Context(), pageIdStr, userId, "delete")
 	if !hasPermission {
 		core.SendFailedReponse(w, r, http.StatusForbidden, "Permission Denied: User cannot delete whiteboard")
 		return
@@ -222,12 +246,21 @@ func deleteWhiteboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = DeleteWhiteboard(inputDoc)
+	title := latestPageTitle(ctx, pageId)
+	err = DeleteWhiteboard(ctx, inputDoc)
 	if err != nil {
 		logger().Error(fmt.Sprintf("deleteWhiteboard: %s", err.Error()))
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Could not delete Whiteboard")
 		return
 	}
+	activity.Record(activity.Event{
+		SpaceId:   spaceId,
+		PageId:    activity.PageRef(pageId),
+		ActorId:   userId,
+		ActorName: user.Name,
+		Type:      activity.WhiteboardDeleted,
+		Data:      map[string]interface{}{"title": title},
+	})
 
 	core.SendSuccessResponse(w, r, http.StatusOK, "Whiteboard is successfully deleted")
 }
@@ -7,10 +7,10 @@ import (
 	"time"
 
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/slug"
 )
 
-func CreateWhiteboard(d WhiteboardInput) (int64, error) {
-	ctx := context.Background()
+func CreateWhiteboard(ctx context.Context, d WhiteboardInput) (int64, error) {
 	tx, err := core.GetPool().Begin(ctx)
 	if err != nil {
 		logger().Error(fmt.Sprintf("CreateWhiteboard tx: %s", err.Error()))
@@ -33,15 +33,22 @@ func CreateWhiteboard(d WhiteboardInput) (int64, error) {
 		logger().Error(fmt.Sprintf("CreateWhiteboard newDoc err: %s", err.Error()))
 		return 0, err
 	}
+	if err = slug.SyncPageSlug(ctx, tx, pgId, d.Title); err != nil {
+		return 0, err
+	}
 
-	// Create Permify subject permission logic exactly mimicking document creation
-	core.CreateSubjectPermissions("page", strconv.FormatInt(pgId, 10), "space", d.SpaceId.String(), "space")
+	// same relation as a document page
+	if err = core.QueueRelations(ctx, tx, core.RelationWrite("page", strconv.FormatInt(pgId, 10), "space", "space", d.SpaceId.String())); err != nil {
+		logger().Error(fmt.Sprintf("CreateWhiteboard relations err: %s", err.Error()))
+		return 0, err
+	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
 		logger().Error(err.Error())
 		return 0, err
 	}
+	core.ApplyQueuedRelations(ctx)
 	return pgId, nil
 }
 
@@ -104,13 +111,29 @@ func UpdateWhiteboard(d WhiteboardInput) error {
 	return nil
 }
 
-func DeleteWhiteboard(d WhiteboardInput) error {
-	ctx := context.Background()
-	_, err := core.GetPool().Exec(ctx, deleteDocumentQuery, d.Id, d.SpaceId)
+func DeleteWhiteboard(ctx context.Context, d WhiteboardInput) error {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(fmt.Sprintf("DeleteWhiteboard tx: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, deleteDocumentQuery, d.Id, d.SpaceId)
 	// Database cascade rules cover core.page_doc_map and core.whiteboard_data
 	if err != nil {
 		logger().Error(fmt.Sprintf("DeleteWhiteboard err: %s", err.Error()))
 		return err
 	}
+	if tag.RowsAffected() > 0 {
+		if err = core.QueueRelations(ctx, tx, core.RelationDelete("page", strconv.FormatInt(d.Id, 10), "", "", "")); err != nil {
+			logger().Error(fmt.Sprintf("DeleteWhiteboard relations err: %s", err.Error()))
+			return err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return err
+	}
+	core.ApplyQueuedRelations(ctx)
 	return nil
 }
@@ -0,0 +1,57 @@
+package gitsync
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Enabled     bool
+	Interval    time.Duration
+	Timeout     time.Duration
+	WorkDir     string
+	LocalRoot   string
+	AuthorName  string
+	AuthorEmail string
+}
+
+// LoadConfig reads the git sync settings. Linking and manual syncs work even
+// when the background worker is disabled. Local repository paths are only
+// accepted below GIT_SYNC_LOCAL_ROOT.
+func LoadConfig() Config {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("GIT_SYNC_ENABLED")))
+	if err != nil {
+		enabled = false
+	}
+	localRoot := strings.TrimSpace(os.Getenv("GIT_SYNC_LOCAL_ROOT"))
+	if localRoot != "" {
+		localRoot = filepath.Clean(localRoot)
+	}
+	return Config{
+		Enabled:     enabled,
+		Interval:    time.Duration(envInt("GIT_SYNC_INTERVAL_MINUTES", 5)) * time.Minute,
+		Timeout:     time.Duration(envInt("GIT_SYNC_TIMEOUT_SECONDS", 120)) * time.Second,
+		WorkDir:     envString("GIT_SYNC_DIR", "git-sync"),
+		LocalRoot:   localRoot,
+		AuthorName:  envString("GIT_SYNC_AUTHOR_NAME", "Beskar"),
+		AuthorEmail: envString("GIT_SYNC_AUTHOR_EMAIL", "beskar@localhost"),
+	}
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envString(key string, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
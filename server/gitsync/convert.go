@@ -0,0 +1,34 @@
+package gitsync
+
+import (
+	"encoding/json"
+
+	"github.com/durgakiran/beskar/editor"
+	"jbi/core"
+	"jbi/markdown"
+)
+
+// The server's editor nodes and jbi's share their JSON shape, which is how
+// the editor hands them to each other anyway.
+
+func toMarkdown(nodes editor.NodeData) (string, error) {
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		return "", err
+	}
+	var converted core.NodeData
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return "", err
+	}
+	return markdown.NodeDataToMarkdown(converted), nil
+}
+
+func fromMarkdown(body string) (editor.NodeData, error) {
+	var nodes editor.NodeData
+	data, err := json.Marshal(markdown.FromMarkdown(body).ConvertToContentObjects(0))
+	if err != nil {
+		return nodes, err
+	}
+	err = json.Unmarshal(data, &nodes)
+	return nodes, err
+}
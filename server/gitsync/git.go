@@ -0,0 +1,207 @@
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// repository runs git in a space's working copy.
+type repository struct {
+	dir    string
+	config Config
+}
+
+func (r repository) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	protocols := "http:https:ssh"
+	if r.config.LocalRoot != "" {
+		protocols += ":file"
+	}
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_ALLOW_PROTOCOL="+protocols,
+		"GIT_AUTHOR_NAME="+r.config.AuthorName,
+		"GIT_AUTHOR_EMAIL="+r.config.AuthorEmail,
+		"GIT_COMMITTER_NAME="+r.config.AuthorName,
+		"GIT_COMMITTER_EMAIL="+r.config.AuthorEmail,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
+
+// checkout brings the working copy to the remote branch, discarding anything
+// a failed earlier run left behind. It returns the remote head, or "" when
+// the branch does not exist yet.
+func (r repository) checkout(ctx context.Context, remote string, branch string) (string, error) {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(r.dir, 0o700); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, "init", "-q"); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, "remote", "add", "origin", remote); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	} else if _, err := r.git(ctx, "remote", "set-url", "origin", remote); err != nil {
+		return "", err
+	}
+
+	heads, err := r.git(ctx, "ls-remote", "--heads", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(heads) == "" {
+		// nothing pushed yet: start the branch over with no history
+		if _, err := r.git(ctx, "checkout", "-q", "--orphan", "beskar-sync-"+branch); err == nil {
+			r.git(ctx, "branch", "-q", "-D", branch)
+			r.git(ctx, "branch", "-q", "-m", branch)
+		}
+		if _, err := r.git(ctx, "rm", "-r", "-q", "--cached", "--ignore-unmatch", "."); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, "clean", "-q", "-f", "-d"); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)
+	if _, err := r.git(ctx, "fetch", "-q", "origin", refspec); err != nil {
+		return "", err
+	}
+	if _, err := r.git(ctx, "checkout", "-q", "-f", "-B", branch, "origin/"+branch); err != nil {
+		return "", err
+	}
+	if _, err := r.git(ctx, "clean", "-q", "-f", "-d"); err != nil {
+		return "", err
+	}
+	return r.head(ctx)
+}
+
+func (r repository) head(ctx context.Context) (string, error) {
+	out, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// isAncestor reports whether commit is part of head's history. Force pushes
+// can drop the last synced commit.
+func (r repository) isAncestor(ctx context.Context, commit string, head string) bool {
+	_, err := r.git(ctx, "merge-base", "--is-ancestor", commit, head)
+	return err == nil
+}
+
+type fileChange struct {
+	status string
+	path   string
+}
+
+// changes lists Markdown files added, modified or deleted between two
+// commits. Without a usable base every file at head counts as added.
+func (r repository) changes(ctx context.Context, base string, head string) ([]fileChange, error) {
+	changes := make([]fileChange, 0)
+	if base == "" || !r.isAncestor(ctx, base, head) {
+		out, err := r.git(ctx, "ls-tree", "-r", "-z", "--name-only", head)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range strings.Split(out, "\x00") {
+			if isMarkdown(path) {
+				changes = append(changes, fileChange{status: "A", path: path})
+			}
+		}
+		return changes, nil
+	}
+	out, err := r.git(ctx, "diff", "--name-status", "--no-renames", "-z", base, head)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		if isMarkdown(fields[i+1]) {
+			changes = append(changes, fileChange{status: fields[i][:1], path: fields[i+1]})
+		}
+	}
+	return changes, nil
+}
+
+func (r repository) show(ctx context.Context, commit string, path string) (string, error) {
+	return r.git(ctx, "show", commit+":"+path)
+}
+
+func (r repository) blob(ctx context.Context, commit string, path string) (string, error) {
+	out, err := r.git(ctx, "rev-parse", commit+":"+path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// write stores content at path in the working copy and returns its blob id.
+func (r repository) write(ctx context.Context, path string, content string) (string, error) {
+	file := filepath.Join(r.dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		return "", err
+	}
+	out, err := r.git(ctx, "hash-object", "--", path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r repository) remove(ctx context.Context, path string) error {
+	_, err := r.git(ctx, "rm", "-q", "--ignore-unmatch", "--", path)
+	return err
+}
+
+// commitAndPush commits whatever changed and pushes it. It returns the new
+// head, or "" when there was nothing to commit.
+func (r repository) commitAndPush(ctx context.Context, branch string, message string) (string, error) {
+	if _, err := r.git(ctx, "add", "-A"); err != nil {
+		return "", err
+	}
+	status, err := r.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(status) == "" {
+		return "", nil
+	}
+	if _, err := r.git(ctx, "commit", "-q", "-m", message); err != nil {
+		return "", err
+	}
+	if _, err := r.git(ctx, "push", "-q", "origin", "HEAD:refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return r.head(ctx)
+}
+
+func isMarkdown(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".md")
+}
@@ -0,0 +1,113 @@
+package gitsync
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func testRepository(t *testing.T) (repository, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v %s", err, out)
+	}
+	config := Config{WorkDir: filepath.Join(root, "work"), LocalRoot: root, AuthorName: "Beskar", AuthorEmail: "beskar@localhost"}
+	return repository{dir: filepath.Join(config.WorkDir, "space"), config: config}, remote
+}
+
+func TestRepositoryExportsToAnEmptyRemote(t *testing.T) {
+	ctx := context.Background()
+	repo, remote := testRepository(t)
+	head, err := repo.checkout(ctx, remote, "docs")
+	if err != nil || head != "" {
+		t.Fatalf("expected an empty branch, got %q %v", head, err)
+	}
+	if _, err := repo.write(ctx, "guide.md", "# Guide\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := repo.write(ctx, "guide/setup.md", "Setup\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	commit, err := repo.commitAndPush(ctx, "docs", "Sync")
+	if err != nil || commit == "" {
+		t.Fatalf("expected a commit, got %q %v", commit, err)
+	}
+	if again, err := repo.commitAndPush(ctx, "docs", "Sync"); err != nil || again != "" {
+		t.Fatalf("expected nothing to commit, got %q %v", again, err)
+	}
+
+	// a fresh working copy sees the pushed branch
+	other := repository{dir: filepath.Join(repo.config.WorkDir, "other"), config: repo.config}
+	head, err = other.checkout(ctx, remote, "docs")
+	if err != nil || head != commit {
+		t.Fatalf("expected head %q, got %q %v", commit, head, err)
+	}
+	changes, err := other.changes(ctx, "", head)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected every file on first sync, got %+v %v", changes, err)
+	}
+}
+
+func TestRepositoryListsChangesSinceLastSync(t *testing.T) {
+	ctx := context.Background()
+	repo, remote := testRepository(t)
+	if _, err := repo.checkout(ctx, remote, "main"); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	repo.write(ctx, "a.md", "a\n")
+	repo.write(ctx, "b.md", "b\n")
+	repo.write(ctx, "notes.txt", "ignored\n")
+	base, err := repo.commitAndPush(ctx, "main", "Sync")
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	// someone else edits a, deletes b and adds c
+	other := repository{dir: filepath.Join(repo.config.WorkDir, "other"), config: repo.config}
+	if _, err := other.checkout(ctx, remote, "main"); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	other.write(ctx, "a.md", "a changed\n")
+	other.write(ctx, "c.md", "c\n")
+	if err := other.remove(ctx, "b.md"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := other.commitAndPush(ctx, "main", "Edit"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	// leftovers from a failed run are discarded
+	os.WriteFile(filepath.Join(repo.dir, "a.md"), []byte("local\n"), 0o600)
+	head, err := repo.checkout(ctx, remote, "main")
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	changes, err := repo.changes(ctx, base, head)
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	got := map[string]string{}
+	for _, change := range changes {
+		got[change.path] = change.status
+	}
+	if len(got) != 3 || got["a.md"] != "M" || got["b.md"] != "D" || got["c.md"] != "A" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	content, err := repo.show(ctx, head, "a.md")
+	if err != nil || content != "a changed\n" {
+		t.Fatalf("unexpected content %q %v", content, err)
+	}
+
+	// a base that is no longer in the history lists everything again
+	changes, err = repo.changes(ctx, "0123456789012345678901234567890123456789", head)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected a full listing, got %+v %v", changes, err)
+	}
+}
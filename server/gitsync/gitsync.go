@@ -0,0 +1,238 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+type controller struct {
+	config Config
+}
+
+// authorize lets space owners and admins manage the link; the repository
+// receives every page they can see.
+func authorize(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return uuid.Nil, uuid.Nil, false
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_EDIT) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, spaceID, true
+}
+
+func ensureMutableSpace(w http.ResponseWriter, r *http.Request, spaceId uuid.UUID) bool {
+	err := core.ValidateSpaceMutable(spaceId)
+	if err == nil {
+		return true
+	}
+	switch err.Error() {
+	case "space has been deleted":
+		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+	case "space is archived":
+		core.SendFailedReponse(w, r, http.StatusForbidden, "This space is archived and read-only")
+	default:
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to validate space state")
+	}
+	return false
+}
+
+func (c controller) statusController(w http.ResponseWriter, r *http.Request) {
+	_, spaceID, ok := authorize(w, r)
+	if !ok {
+		return
+	}
+	status, err := getStatus(r.Context(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, status)
+}
+
+func (c controller) linkController(w http.ResponseWriter, r *http.Request) {
+	userID, spaceID, ok := authorize(w, r)
+	if !ok {
+		return
+	}
+	if !ensureMutableSpace(w, r, spaceID) {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateLinkRequest(data, c.config.LocalRoot)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	link, before, err := linkSpace(r.Context(), spaceID, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entry := audit.Entry{
+		Action:     audit.ActionSpaceGitLinked,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]string{"remote": redactRemote(link.Remote), "branch": link.Branch},
+	}
+	if before != nil {
+		entry.Before = map[string]string{"remote": redactRemote(before.Remote), "branch": before.Branch}
+	}
+	audit.Record(r, entry)
+	link.Remote = redactRemote(link.Remote)
+	core.SendSuccessResponse(w, r, http.StatusOK, link)
+}
+
+func (c controller) unlinkController(w http.ResponseWriter, r *http.Request) {
+	_, spaceID, ok := authorize(w, r)
+	if !ok {
+		return
+	}
+	link, err := unlinkSpace(r.Context(), c.config, spaceID)
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceGitUnlinked,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"remote": redactRemote(link.Remote), "branch": link.Branch},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]bool{"unlinked": true})
+}
+
+// syncController runs a sync right away and waits for it. It carries on if
+// the client goes away; the outcome is stored with the link either way.
+func (c controller) syncController(w http.ResponseWriter, r *http.Request) {
+	_, spaceID, ok := authorize(w, r)
+	if !ok {
+		return
+	}
+	if !ensureMutableSpace(w, r, spaceID) {
+		return
+	}
+	result, err := syncSpace(context.WithoutCancel(r.Context()), c.config, spaceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSyncRunning):
+			core.SendFailedReponse(w, r, http.StatusConflict, err.Error())
+		case err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			core.SendFailedReponse(w, r, http.StatusBadGateway, err.Error())
+		}
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, result)
+}
+
+// resolveController records the resolution and syncs so it takes effect
+// now. When a sync is already running that one or the next applies it.
+func (c controller) resolveController(w http.ResponseWriter, r *http.Request) {
+	userID, spaceID, ok := authorize(w, r)
+	if !ok {
+		return
+	}
+	if !ensureMutableSpace(w, r, spaceID) {
+		return
+	}
+	conflictID, err := strconv.ParseInt(chi.URLParam(r, "conflictId"), 10, 64)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateResolveRequest(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	conflict, err := resolveConflict(r.Context(), spaceID, conflictID, userID, req.Keep)
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceGitConflictResolved,
+		TargetType: "page",
+		TargetId:   strconv.FormatInt(conflict.PageId, 10),
+		SpaceId:    &spaceID,
+		After:      map[string]string{"keep": req.Keep, "path": conflict.Path, "commit": conflict.CommitSha},
+	})
+	if _, err := syncSpace(context.WithoutCancel(r.Context()), c.config, spaceID); err != nil && !errors.Is(err, errSyncRunning) {
+		logger().Warn("git sync after conflict resolution failed", zap.String("space_id", spaceID.String()), zap.Error(err))
+	}
+	status, err := getStatus(r.Context(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, status)
+}
+
+func Router(config Config) *chi.Mux {
+	c := controller{config: config}
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/space/{spaceId}", c.statusController)
+	r.Put("/space/{spaceId}", c.linkController)
+	r.Delete("/space/{spaceId}", c.unlinkController)
+	r.Post("/space/{spaceId}/sync", c.syncController)
+	r.Post("/space/{spaceId}/conflicts/{conflictId}/resolve", c.resolveController)
+	return r
+}
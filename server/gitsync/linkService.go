@@ -0,0 +1,116 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// getStatus reports the link with its remote redacted, how many pages are
+// in the repository and the conflicts waiting to be resolved or applied.
+func getStatus(ctx context.Context, spaceId uuid.UUID) (Status, error) {
+	status := Status{Conflicts: make([]Conflict, 0)}
+	link, err := fetchLink(ctx, spaceId)
+	if err != nil && err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	// errors are redacted when they are stored
+	link.Remote = redactRemote(link.Remote)
+	status.Linked, status.Link = true, &link
+	if err := core.GetPool().QueryRow(ctx, countPageFiles, spaceId).Scan(&status.Pages, &status.DetachedPages); err != nil {
+		return status, err
+	}
+	if status.Conflicts, err = fetchOpenConflicts(ctx, spaceId); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// linkSpace links the space or changes its link. Pointing it at another
+// repository or branch starts over: the page files and open conflicts
+// belonged to the old one.
+func linkSpace(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, req LinkRequest) (Link, *Link, error) {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return Link{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var before *Link
+	rows, err := tx.Query(ctx, getLink, spaceId)
+	if err != nil {
+		return Link{}, nil, err
+	}
+	previous, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Link])
+	if err == nil {
+		before = &previous
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return Link{}, nil, err
+	}
+
+	rows, err = tx.Query(ctx, upsertLink, spaceId, req.Remote, req.Branch, userId)
+	if err != nil {
+		return Link{}, before, err
+	}
+	link, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Link])
+	if err != nil {
+		return link, before, err
+	}
+	if before != nil && (before.Remote != req.Remote || before.Branch != req.Branch) {
+		if _, err := tx.Exec(ctx, deletePageFiles, spaceId); err != nil {
+			return link, before, err
+		}
+		if _, err := tx.Exec(ctx, deleteSpaceConflicts, spaceId); err != nil {
+			return link, before, err
+		}
+		if _, err := tx.Exec(ctx, resetLinkCommit, spaceId); err != nil {
+			return link, before, err
+		}
+		link.LastCommit = nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return link, before, err
+	}
+	return link, before, nil
+}
+
+// unlinkSpace removes the link and the server's working copy. The
+// repository itself is left as it is.
+func unlinkSpace(ctx context.Context, config Config, spaceId uuid.UUID) (Link, error) {
+	rows, err := core.GetPool().Query(ctx, deleteLink, spaceId)
+	if err != nil {
+		return Link{}, err
+	}
+	link, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Link])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return link, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		return link, err
+	}
+	if err := os.RemoveAll(filepath.Join(config.WorkDir, spaceId.String())); err != nil {
+		logger().Warn("git sync: removing working copy failed: " + err.Error())
+	}
+	return link, nil
+}
+
+// resolveConflict records which side wins. The next sync applies it.
+func resolveConflict(ctx context.Context, spaceId uuid.UUID, conflictId int64, userId uuid.UUID, keep string) (Conflict, error) {
+	rows, err := core.GetPool().Query(ctx, setConflictResolution, conflictId, spaceId, keep, userId)
+	if err != nil {
+		return Conflict{}, err
+	}
+	conflict, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Conflict])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return conflict, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return conflict, err
+}
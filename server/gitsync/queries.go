@@ -0,0 +1,73 @@
+package gitsync
+
+const (
+	getLink = `SELECT space_id, remote, branch, linked_by, linked_at, last_commit, status, last_synced_at, last_error, last_error_at
+FROM core.space_git_links WHERE space_id = $1`
+	upsertLink = `INSERT INTO core.space_git_links (space_id, remote, branch, linked_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (space_id) DO UPDATE SET remote = EXCLUDED.remote, branch = EXCLUDED.branch, linked_by = EXCLUDED.linked_by,
+	linked_at = now(), status = 'pending', last_error = NULL, last_error_at = NULL
+RETURNING space_id, remote, branch, linked_by, linked_at, last_commit, status, last_synced_at, last_error, last_error_at`
+	resetLinkCommit = `UPDATE core.space_git_links SET last_commit = NULL WHERE space_id = $1`
+	deleteLink      = `DELETE FROM core.space_git_links WHERE space_id = $1
+RETURNING space_id, remote, branch, linked_by, linked_at, last_commit, status, last_synced_at, last_error, last_error_at`
+	listSyncedSpaces = `SELECT l.space_id FROM core.space_git_links l
+JOIN core.space s ON s.id = l.space_id
+WHERE s.deleted_at IS NULL AND s.archived_at IS NULL
+ORDER BY l.last_synced_at NULLS FIRST`
+	setLastCommit = `UPDATE core.space_git_links SET last_commit = $2 WHERE space_id = $1`
+	setLinkStatus = `UPDATE core.space_git_links SET status = $2, last_synced_at = now(), last_error = $3,
+	last_error_at = CASE WHEN $3::text IS NULL THEN last_error_at ELSE now() END
+WHERE space_id = $1`
+
+	// session locks, the sync runs git outside of any transaction
+	lockSpaceSync   = `SELECT pg_try_advisory_lock(hashtext('core.space_git_links'), hashtext($1))`
+	unlockSpaceSync = `SELECT pg_advisory_unlock(hashtext('core.space_git_links'), hashtext($1))`
+
+	listPageFiles  = `SELECT page_id, path, doc_version, blob, detached FROM core.space_git_pages WHERE space_id = $1`
+	countPageFiles = `SELECT COUNT(*) FILTER (WHERE NOT detached), COUNT(*) FILTER (WHERE detached)
+FROM core.space_git_pages WHERE space_id = $1`
+	freePagePath   = `DELETE FROM core.space_git_pages WHERE space_id = $1 AND path = $2 AND page_id <> $3`
+	upsertPageFile = `INSERT INTO core.space_git_pages (page_id, space_id, path, doc_version, blob, detached)
+VALUES ($1, $2, $3, $4, $5, false)
+ON CONFLICT (page_id) DO UPDATE SET space_id = EXCLUDED.space_id, path = EXCLUDED.path,
+	doc_version = EXCLUDED.doc_version, blob = EXCLUDED.blob, detached = false`
+	detachPageFile  = `UPDATE core.space_git_pages SET detached = true, blob = NULL WHERE page_id = $1`
+	deletePageFile  = `DELETE FROM core.space_git_pages WHERE page_id = $1`
+	deletePageFiles = `DELETE FROM core.space_git_pages WHERE space_id = $1`
+	listSpacePages  = `SELECT p.id AS page_id, COALESCE(p.parent_id, 0) AS parent_id, d.doc_id, d.title, d.version
+FROM core.page p
+JOIN LATERAL (
+	SELECT doc_id, title, version FROM core.page_doc_map
+	WHERE page_id = p.id AND draft = 0
+	ORDER BY version DESC LIMIT 1
+) d ON true
+WHERE p.space_id = $1 AND p.type = 'document'
+ORDER BY p.id`
+	// drafts count: a page saved in the editor since the last sync has
+	// changed on the web side even before it is published
+	getPageVersion = `SELECT MAX(d.version), COALESCE((ARRAY_AGG(d.title ORDER BY d.version DESC))[1], '')
+FROM core.page p
+JOIN core.page_doc_map d ON d.page_id = p.id
+WHERE p.id = $1 AND p.space_id = $2 AND p.type = 'document'
+GROUP BY p.id`
+
+	upsertConflict = `INSERT INTO core.space_git_conflicts (space_id, page_id, path, commit_sha, doc_version)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (page_id) WHERE applied_at IS NULL DO UPDATE SET path = EXCLUDED.path, commit_sha = EXCLUDED.commit_sha,
+	doc_version = EXCLUDED.doc_version, detected_at = now(), resolution = NULL, resolved_by = NULL, resolved_at = NULL`
+	listOpenConflicts = `SELECT c.id, c.page_id, COALESCE(d.title, '') AS title, c.path, c.commit_sha, c.doc_version, c.detected_at,
+	c.resolution, c.resolved_by, c.resolved_at
+FROM core.space_git_conflicts c
+LEFT JOIN LATERAL (
+	SELECT title FROM core.page_doc_map WHERE page_id = c.page_id ORDER BY version DESC LIMIT 1
+) d ON true
+WHERE c.space_id = $1 AND c.applied_at IS NULL
+ORDER BY c.detected_at`
+	setConflictResolution = `UPDATE core.space_git_conflicts SET resolution = $3, resolved_by = $4, resolved_at = now()
+WHERE id = $1 AND space_id = $2 AND applied_at IS NULL
+RETURNING id, page_id, '' AS title, path, commit_sha, doc_version, detected_at, resolution, resolved_by, resolved_at`
+	applyConflict        = `UPDATE core.space_git_conflicts SET applied_at = now() WHERE id = $1`
+	deletePageConflicts  = `DELETE FROM core.space_git_conflicts WHERE page_id = $1 AND applied_at IS NULL`
+	deleteSpaceConflicts = `DELETE FROM core.space_git_conflicts WHERE space_id = $1 AND applied_at IS NULL`
+)
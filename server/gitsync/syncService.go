@@ -0,0 +1,730 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/editor"
+	"github.com/durgakiran/beskar/org"
+	"github.com/durgakiran/beskar/page"
+	"github.com/durgakiran/beskar/slug"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"jbi/markdown"
+)
+
+var errSyncRunning = errors.New("a sync of this space is already running")
+
+// Worker syncs every linked space on an interval.
+type Worker struct {
+	config Config
+}
+
+func NewWorker(config Config) *Worker {
+	return &Worker{config: config}
+}
+
+func (w *Worker) Start(ctx context.Context) {
+	if !w.config.Enabled {
+		return
+	}
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			w.SyncDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncDue syncs the linked spaces that are neither archived nor deleted.
+// Failures are stored on the link and retried on the next run.
+func (w *Worker) SyncDue(ctx context.Context) {
+	rows, err := core.GetPool().Query(ctx, listSyncedSpaces)
+	if err != nil {
+		logger().Error("git sync: listing linked spaces failed", zap.Error(err))
+		return
+	}
+	spaceIds, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		logger().Error("git sync: reading linked spaces failed", zap.Error(err))
+		return
+	}
+	for _, spaceId := range spaceIds {
+		result, err := syncSpace(ctx, w.config, spaceId)
+		if errors.Is(err, errSyncRunning) {
+			continue
+		}
+		if err != nil {
+			logger().Warn("git sync failed", zap.String("space_id", spaceId.String()), zap.Error(err))
+			continue
+		}
+		if result.Imported+result.Created+result.Exported+result.Removed+result.Conflicts > 0 {
+			logger().Info("git sync",
+				zap.String("space_id", spaceId.String()),
+				zap.Int("imported", result.Imported),
+				zap.Int("created", result.Created),
+				zap.Int("exported", result.Exported),
+				zap.Int("removed", result.Removed),
+				zap.Int("conflicts", result.Conflicts),
+				zap.Int("errors", len(result.Errors)),
+			)
+		}
+	}
+}
+
+// syncSpace runs one two-way sync. Repository changes are imported first so
+// a page edited on both sides is caught as a conflict before the export
+// would overwrite the repository's version. The outcome is stored on the
+// link, with credentials stripped from any error.
+func syncSpace(ctx context.Context, config Config, spaceId uuid.UUID) (SyncResult, error) {
+	result := SyncResult{Errors: make([]string, 0)}
+	conn, err := core.GetPool().Acquire(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Release()
+	var locked bool
+	if err := conn.QueryRow(ctx, lockSpaceSync, spaceId.String()).Scan(&locked); err != nil {
+		return result, err
+	}
+	if !locked {
+		return result, errSyncRunning
+	}
+	defer conn.Exec(context.Background(), unlockSpaceSync, spaceId.String())
+
+	link, err := fetchLink(ctx, spaceId)
+	if err != nil {
+		return result, err
+	}
+	if err := core.ValidateSpaceMutable(spaceId); err != nil {
+		return result, err
+	}
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return result, err
+	}
+	runCtx, cancel := context.WithTimeout(tenantCtx, config.Timeout)
+	defer cancel()
+
+	s := &spaceSync{
+		config: config,
+		link:   link,
+		repo:   repository{dir: filepath.Join(config.WorkDir, spaceId.String()), config: config},
+		result: &result,
+	}
+	err = s.run(runCtx)
+
+	status := STATUS_OK
+	var lastError *string
+	if err != nil {
+		message := redactError(err.Error(), link.Remote)
+		err = errors.New(message)
+		status, lastError = STATUS_ERROR, &message
+	} else if len(result.Errors) > 0 {
+		message := redactError(strings.Join(result.Errors, "; "), link.Remote)
+		status, lastError = STATUS_ERROR, &message
+	} else if open, countErr := countOpenConflicts(ctx, spaceId); countErr == nil && open > 0 {
+		status = STATUS_CONFLICT
+	}
+	if _, updateErr := core.GetPool().Exec(ctx, setLinkStatus, spaceId, status, lastError); updateErr != nil {
+		logger().Error("git sync: saving status failed", zap.String("space_id", spaceId.String()), zap.Error(updateErr))
+	}
+	return result, err
+}
+
+type spaceSync struct {
+	config Config
+	link   Link
+	repo   repository
+	result *SyncResult
+	files  map[int64]*pageFile
+	// pages the repository may see; view restricted subtrees stay out
+	visible map[int64]bool
+	// page files and applied "keep web" conflicts, saved once the export
+	// is pushed so a failed push is retried in full
+	exported []pageFile
+	removed  []int64
+	keptWeb  []int64
+}
+
+func (s *spaceSync) run(ctx context.Context) error {
+	spaceId := s.link.SpaceId
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, s.link.LinkedBy, core.SPACE_EDIT_PAGE) {
+		return errors.New("the user who linked the repository can no longer edit pages in this space")
+	}
+	head, err := s.repo.checkout(ctx, s.link.Remote, s.link.Branch)
+	if err != nil {
+		return err
+	}
+	if err := s.loadFiles(ctx); err != nil {
+		return err
+	}
+	pages, err := fetchSpacePages(ctx, spaceId)
+	if err != nil {
+		return err
+	}
+	if s.visible, err = visiblePages(ctx, pages); err != nil {
+		return err
+	}
+
+	if head != "" && (s.link.LastCommit == nil || *s.link.LastCommit != head) {
+		base := ""
+		if s.link.LastCommit != nil {
+			base = *s.link.LastCommit
+		}
+		if err := s.importChanges(ctx, base, head); err != nil {
+			return err
+		}
+		if _, err := core.GetPool().Exec(ctx, setLastCommit, spaceId, head); err != nil {
+			return err
+		}
+	}
+	if err := s.applyResolutions(ctx, head); err != nil {
+		return err
+	}
+
+	// imports and resolutions changed pages and files, start from the stored state
+	if err := s.loadFiles(ctx); err != nil {
+		return err
+	}
+	if pages, err = fetchSpacePages(ctx, spaceId); err != nil {
+		return err
+	}
+	if s.visible, err = visiblePages(ctx, pages); err != nil {
+		return err
+	}
+	if err := s.export(ctx, pages); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Sync %d page(s) from Beskar", s.result.Exported+s.result.Removed)
+	commit, err := s.repo.commitAndPush(ctx, s.link.Branch, message)
+	if err != nil {
+		return err
+	}
+	if err := s.saveExport(ctx); err != nil {
+		return err
+	}
+	if commit != "" {
+		s.result.Commit = commit
+		if _, err := core.GetPool().Exec(ctx, setLastCommit, spaceId, commit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *spaceSync) loadFiles(ctx context.Context) error {
+	rows, err := core.GetPool().Query(ctx, listPageFiles, s.link.SpaceId)
+	if err != nil {
+		return err
+	}
+	files, err := pgx.CollectRows(rows, pgx.RowToStructByName[pageFile])
+	if err != nil {
+		return err
+	}
+	s.files = make(map[int64]*pageFile, len(files))
+	for i := range files {
+		s.files[files[i].PageId] = &files[i]
+	}
+	return nil
+}
+
+func (s *spaceSync) fileAt(filePath string) *pageFile {
+	for _, file := range s.files {
+		if file.Path == filePath {
+			return file
+		}
+	}
+	return nil
+}
+
+// importChanges applies the commits since base. Deletions go first so a
+// file moved in the repository, which git reports as a delete and an add,
+// ends up attached to its page at the new path. Parents are handled before
+// the files in their folder.
+func (s *spaceSync) importChanges(ctx context.Context, base string, head string) error {
+	changes, err := s.repo.changes(ctx, base, head)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if (changes[i].status == "D") != (changes[j].status == "D") {
+			return changes[i].status == "D"
+		}
+		di, dj := strings.Count(changes[i].path, "/"), strings.Count(changes[j].path, "/")
+		if di != dj {
+			return di < dj
+		}
+		return changes[i].path < changes[j].path
+	})
+	for _, change := range changes {
+		if change.status == "D" {
+			file := s.fileAt(change.path)
+			if file == nil || file.Detached {
+				continue
+			}
+			if _, err := core.GetPool().Exec(ctx, detachPageFile, file.PageId); err != nil {
+				return err
+			}
+			file.Detached = true
+			s.result.Detached++
+			continue
+		}
+		if err := s.importFile(ctx, head, change.path, 0, false); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.result.Errors = append(s.result.Errors, fmt.Sprintf("%s: %v", change.path, err))
+		}
+	}
+	return nil
+}
+
+// importFile publishes the file at commit onto its page, creating the page
+// when the file belongs to none. A page that also changed on the web since
+// it was last synced is recorded as a conflict instead, unless force is set
+// because the conflict was resolved in the repository's favour.
+func (s *spaceSync) importFile(ctx context.Context, commit string, filePath string, pageId int64, force bool) error {
+	spaceId := s.link.SpaceId
+	blob, err := s.repo.blob(ctx, commit, filePath)
+	if err != nil {
+		return err
+	}
+	src, err := s.repo.show(ctx, commit, filePath)
+	if err != nil {
+		return err
+	}
+	meta, body := markdown.SplitFrontMatter(src)
+	title := meta.Title
+	if title == "" {
+		if heading, rest := markdown.TakeTitle(body); heading != "" {
+			title, body = heading, rest
+		}
+	}
+
+	file := s.fileAt(filePath)
+	if pageId == 0 {
+		// the front matter wins over the path so moved files keep their page
+		if meta.Page > 0 && meta.Page != fileId(file) {
+			if _, _, err := fetchPageVersion(ctx, meta.Page, spaceId); err == nil {
+				pageId = meta.Page
+				file = s.files[pageId]
+			}
+		}
+		if pageId == 0 && file != nil {
+			pageId = file.PageId
+		}
+	}
+	if pageId == 0 {
+		return s.createPage(ctx, commit, filePath, blob, title, meta, body)
+	}
+	if !s.visible[pageId] {
+		return fmt.Errorf("page %d is restricted and not synced", pageId)
+	}
+	if !force && file != nil && !file.Detached && file.Path == filePath && file.Blob != nil && *file.Blob == blob {
+		return nil
+	}
+	version, currentTitle, err := fetchPageVersion(ctx, pageId, spaceId)
+	if err != nil {
+		return err
+	}
+	if !force && (file == nil || file.DocVersion == nil || version.After(*file.DocVersion)) {
+		if _, err := core.GetPool().Exec(ctx, upsertConflict, spaceId, pageId, filePath, commit, version); err != nil {
+			return err
+		}
+		s.result.Conflicts++
+		return nil
+	}
+	if !core.ValidateUserPagePermission(ctx, fmt.Sprintf("%v", pageId), s.link.LinkedBy, "edit") {
+		return fmt.Errorf("no permission to edit page %d", pageId)
+	}
+	if title == "" {
+		title = currentTitle
+	}
+	nodes, err := fromMarkdown(body)
+	if err != nil {
+		return err
+	}
+	doc := editor.InputDocument{
+		Document: editor.Document{Title: title, OwnerId: s.link.LinkedBy, Id: pageId, SpaceId: spaceId},
+		Nodes:    nodes,
+	}
+	if _, err := doc.Publish(); err != nil {
+		return err
+	}
+	activity.Record(activity.Event{
+		SpaceId: spaceId,
+		PageId:  activity.PageRef(pageId),
+		ActorId: s.link.LinkedBy,
+		Type:    activity.PagePublished,
+		Data:    map[string]interface{}{"title": title, "source": "git", "commit": commit},
+	})
+	s.result.Imported++
+	return s.saveImported(ctx, pageId, filePath, blob)
+}
+
+// createPage adds a page for a new file. Its parent is the page whose file
+// sits next to the file's folder, then the parent in the front matter.
+func (s *spaceSync) createPage(ctx context.Context, commit string, filePath string, blob string, title string, meta markdown.Meta, body string) error {
+	spaceId := s.link.SpaceId
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	}
+	var parentId int64
+	if dir := path.Dir(filePath); dir != "." {
+		if parent := s.fileAt(dir + ".md"); parent != nil && !parent.Detached {
+			parentId = parent.PageId
+		}
+	}
+	if parentId == 0 && meta.Parent > 0 && s.visible[meta.Parent] {
+		parentId = meta.Parent
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, s.link.LinkedBy, core.SPACE_EDIT_PAGE) {
+		return errors.New("no permission to create pages in this space")
+	}
+	nodes, err := fromMarkdown(body)
+	if err != nil {
+		return err
+	}
+	doc := editor.InputDocument{
+		Document: editor.Document{Title: title, OwnerId: s.link.LinkedBy, ParentId: parentId, SpaceId: spaceId},
+		Nodes:    nodes,
+	}
+	pageId, err := doc.Create(ctx)
+	if err != nil {
+		return err
+	}
+	activity.Record(activity.Event{
+		SpaceId: spaceId,
+		PageId:  activity.PageRef(pageId),
+		ActorId: s.link.LinkedBy,
+		Type:    activity.PageCreated,
+		Data:    map[string]interface{}{"title": title, "source": "git", "commit": commit},
+	})
+	doc.Id = pageId
+	if _, err := doc.Publish(); err != nil {
+		return err
+	}
+	s.visible[pageId] = true
+	s.result.Created++
+	return s.saveImported(ctx, pageId, filePath, blob)
+}
+
+// saveImported records that the file and the page's newest version match.
+func (s *spaceSync) saveImported(ctx context.Context, pageId int64, filePath string, blob string) error {
+	version, _, err := fetchPageVersion(ctx, pageId, s.link.SpaceId)
+	if err != nil {
+		return err
+	}
+	if err := savePageFile(ctx, s.link.SpaceId, pageFile{PageId: pageId, Path: filePath, DocVersion: &version, Blob: &blob}); err != nil {
+		return err
+	}
+	file := s.files[pageId]
+	if file == nil {
+		file = &pageFile{PageId: pageId}
+		s.files[pageId] = file
+	}
+	file.Path, file.DocVersion, file.Blob, file.Detached = filePath, &version, &blob, false
+	return nil
+}
+
+// applyResolutions carries out resolved conflicts. Keeping the repository's
+// version publishes the file now; keeping the web version clears the synced
+// version so the export below rewrites the file.
+func (s *spaceSync) applyResolutions(ctx context.Context, head string) error {
+	conflicts, err := fetchOpenConflicts(ctx, s.link.SpaceId)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		if conflict.Resolution == nil {
+			continue
+		}
+		switch *conflict.Resolution {
+		case KEEP_REPO:
+			if head == "" {
+				s.result.Errors = append(s.result.Errors, fmt.Sprintf("%s: the branch no longer exists", conflict.Path))
+				continue
+			}
+			if err := s.importFile(ctx, conflict.CommitSha, conflict.Path, conflict.PageId, true); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.result.Errors = append(s.result.Errors, fmt.Sprintf("%s: %v", conflict.Path, err))
+				continue
+			}
+			if _, err := core.GetPool().Exec(ctx, applyConflict, conflict.Id); err != nil {
+				return err
+			}
+		case KEEP_WEB:
+			if err := savePageFile(ctx, s.link.SpaceId, pageFile{PageId: conflict.PageId, Path: conflict.Path}); err != nil {
+				return err
+			}
+			s.keptWeb = append(s.keptWeb, conflict.Id)
+		}
+	}
+	return nil
+}
+
+// export writes every page published since its last sync into the working
+// copy, parents first so children land in their parent's folder, and removes
+// the files of pages that were deleted or restricted. Pages with an
+// unresolved conflict are left alone.
+func (s *spaceSync) export(ctx context.Context, pages []syncPage) error {
+	conflicts, err := fetchOpenConflicts(ctx, s.link.SpaceId)
+	if err != nil {
+		return err
+	}
+	blocked := make(map[int64]bool)
+	for _, conflict := range conflicts {
+		if conflict.Resolution == nil {
+			blocked[conflict.PageId] = true
+		}
+	}
+	known := make(map[int64]bool, len(pages))
+	for _, p := range pages {
+		known[p.PageId] = true
+	}
+	children := make(map[int64][]syncPage)
+	for _, p := range pages {
+		parent := p.ParentId
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], p)
+	}
+	taken := make(map[string]bool)
+	for _, file := range s.files {
+		if !file.Detached {
+			taken[file.Path] = true
+		}
+	}
+
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	kept := make(map[int64]bool)
+	var walk func(parentId int64, dir string) error
+	walk = func(parentId int64, dir string) error {
+		for _, p := range children[parentId] {
+			if !s.visible[p.PageId] {
+				continue
+			}
+			kept[p.PageId] = true
+			file := s.files[p.PageId]
+			filePath := ""
+			if file != nil {
+				filePath = file.Path
+			} else {
+				filePath = s.freePath(dir, p, taken)
+				taken[filePath] = true
+			}
+			stale := file == nil || file.DocVersion == nil || p.Version.After(*file.DocVersion)
+			if stale && !blocked[p.PageId] && (file == nil || !file.Detached) {
+				nodes, err := editor.FetchContent(tx, ctx, p.DocId)
+				if err != nil {
+					return err
+				}
+				body, err := toMarkdown(nodes)
+				if err != nil {
+					return err
+				}
+				meta := markdown.Meta{Title: p.Title, Space: s.link.SpaceId.String(), Page: p.PageId, Parent: p.ParentId}
+				blob, err := s.repo.write(ctx, filePath, markdown.WithFrontMatter(meta, body))
+				if err != nil {
+					return err
+				}
+				version := p.Version
+				s.exported = append(s.exported, pageFile{PageId: p.PageId, Path: filePath, DocVersion: &version, Blob: &blob})
+				s.result.Exported++
+			}
+			if err := walk(p.PageId, strings.TrimSuffix(filePath, ".md")+"/"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(0, ""); err != nil {
+		return err
+	}
+
+	for pageId, file := range s.files {
+		if kept[pageId] {
+			continue
+		}
+		if !file.Detached {
+			if err := s.repo.remove(ctx, file.Path); err != nil {
+				return err
+			}
+			s.result.Removed++
+		}
+		s.removed = append(s.removed, pageId)
+	}
+	return nil
+}
+
+// freePath names a new file after the page's title, falling back to the
+// title and id when a file of that name already exists.
+func (s *spaceSync) freePath(dir string, p syncPage, taken map[string]bool) string {
+	name := slug.Slugify(p.Title)
+	if name == "" {
+		name = "page"
+	}
+	candidate := dir + name + ".md"
+	if _, err := os.Stat(filepath.Join(s.repo.dir, filepath.FromSlash(candidate))); taken[candidate] || err == nil {
+		candidate = fmt.Sprintf("%s%s-%d.md", dir, name, p.PageId)
+	}
+	return candidate
+}
+
+func (s *spaceSync) saveExport(ctx context.Context) error {
+	for _, file := range s.exported {
+		if err := savePageFile(ctx, s.link.SpaceId, file); err != nil {
+			return err
+		}
+	}
+	for _, pageId := range s.removed {
+		if _, err := core.GetPool().Exec(ctx, deletePageFile, pageId); err != nil {
+			return err
+		}
+		if _, err := core.GetPool().Exec(ctx, deletePageConflicts, pageId); err != nil {
+			return err
+		}
+	}
+	for _, conflictId := range s.keptWeb {
+		if _, err := core.GetPool().Exec(ctx, applyConflict, conflictId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visiblePages walks the page tree and leaves out view restricted pages with
+// everything below them, since restrictions are inherited.
+func visiblePages(ctx context.Context, pages []syncPage) (map[int64]bool, error) {
+	byId := make(map[int64]syncPage, len(pages))
+	for _, p := range pages {
+		byId[p.PageId] = p
+	}
+	visible := make(map[int64]bool, len(pages))
+	decided := make(map[int64]bool, len(pages))
+	var decide func(pageId int64, depth int) (bool, error)
+	decide = func(pageId int64, depth int) (bool, error) {
+		if done := decided[pageId]; done {
+			return visible[pageId], nil
+		}
+		p, ok := byId[pageId]
+		if !ok || depth > len(pages) {
+			return false, nil
+		}
+		summary, err := page.GetRestrictionSummary(ctx, pageId)
+		if err != nil {
+			return false, err
+		}
+		ok = !summary.ViewRestricted
+		if ok && byId[p.ParentId].PageId != 0 {
+			if ok, err = decide(p.ParentId, depth+1); err != nil {
+				return false, err
+			}
+		}
+		visible[pageId], decided[pageId] = ok, true
+		return ok, nil
+	}
+	for _, p := range pages {
+		if _, err := decide(p.PageId, 0); err != nil {
+			return nil, err
+		}
+	}
+	return visible, nil
+}
+
+func fileId(file *pageFile) int64 {
+	if file == nil {
+		return 0
+	}
+	return file.PageId
+}
+
+func savePageFile(ctx context.Context, spaceId uuid.UUID, file pageFile) error {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, freePagePath, spaceId, file.Path, file.PageId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, upsertPageFile, file.PageId, spaceId, file.Path, file.DocVersion, file.Blob); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func fetchLink(ctx context.Context, spaceId uuid.UUID) (Link, error) {
+	rows, err := core.GetPool().Query(ctx, getLink, spaceId)
+	if err != nil {
+		return Link{}, err
+	}
+	link, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Link])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return link, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return link, err
+}
+
+func fetchSpacePages(ctx context.Context, spaceId uuid.UUID) ([]syncPage, error) {
+	rows, err := core.GetPool().Query(ctx, listSpacePages, spaceId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[syncPage])
+}
+
+// fetchPageVersion returns the newest version of a document page in the
+// space, drafts included, with its title.
+func fetchPageVersion(ctx context.Context, pageId int64, spaceId uuid.UUID) (time.Time, string, error) {
+	var version time.Time
+	var title string
+	err := core.GetPool().QueryRow(ctx, getPageVersion, pageId, spaceId).Scan(&version, &title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return version, title, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return version, title, err
+}
+
+func fetchOpenConflicts(ctx context.Context, spaceId uuid.UUID) ([]Conflict, error) {
+	rows, err := core.GetPool().Query(ctx, listOpenConflicts, spaceId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Conflict])
+}
+
+func countOpenConflicts(ctx context.Context, spaceId uuid.UUID) (int, error) {
+	conflicts, err := fetchOpenConflicts(ctx, spaceId)
+	return len(conflicts), err
+}
@@ -0,0 +1,92 @@
+package gitsync
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	STATUS_PENDING  = "pending"
+	STATUS_OK       = "ok"
+	STATUS_CONFLICT = "conflict"
+	STATUS_ERROR    = "error"
+
+	KEEP_WEB  = "web"
+	KEEP_REPO = "repo"
+)
+
+type Link struct {
+	SpaceId      uuid.UUID  `json:"spaceId" db:"space_id"`
+	Remote       string     `json:"remote" db:"remote"`
+	Branch       string     `json:"branch" db:"branch"`
+	LinkedBy     uuid.UUID  `json:"linkedBy" db:"linked_by"`
+	LinkedAt     time.Time  `json:"linkedAt" db:"linked_at"`
+	LastCommit   *string    `json:"lastCommit" db:"last_commit"`
+	Status       string     `json:"status" db:"status"`
+	LastSyncedAt *time.Time `json:"lastSyncedAt" db:"last_synced_at"`
+	LastError    *string    `json:"lastError" db:"last_error"`
+	LastErrorAt  *time.Time `json:"lastErrorAt" db:"last_error_at"`
+}
+
+// pageFile is where a page lives in the repository. DocVersion is the page
+// version the file was last synced with; a newer version on either side is a
+// change, newer versions on both sides are a conflict.
+type pageFile struct {
+	PageId     int64      `db:"page_id"`
+	Path       string     `db:"path"`
+	DocVersion *time.Time `db:"doc_version"`
+	Blob       *string    `db:"blob"`
+	Detached   bool       `db:"detached"`
+}
+
+type Conflict struct {
+	Id         int64      `json:"id" db:"id"`
+	PageId     int64      `json:"pageId" db:"page_id"`
+	Title      string     `json:"title" db:"title"`
+	Path       string     `json:"path" db:"path"`
+	CommitSha  string     `json:"commit" db:"commit_sha"`
+	DocVersion time.Time  `json:"docVersion" db:"doc_version"`
+	DetectedAt time.Time  `json:"detectedAt" db:"detected_at"`
+	Resolution *string    `json:"resolution" db:"resolution"`
+	ResolvedBy *uuid.UUID `json:"resolvedBy" db:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolvedAt" db:"resolved_at"`
+}
+
+// syncPage is a document page of the space with its latest published version.
+type syncPage struct {
+	PageId   int64     `db:"page_id"`
+	ParentId int64     `db:"parent_id"`
+	DocId    int64     `db:"doc_id"`
+	Title    string    `db:"title"`
+	Version  time.Time `db:"version"`
+}
+
+type Status struct {
+	Linked        bool       `json:"linked"`
+	Link          *Link      `json:"link"`
+	Pages         int        `json:"pages"`
+	DetachedPages int        `json:"detachedPages"`
+	Conflicts     []Conflict `json:"conflicts"`
+}
+
+// SyncResult counts what one sync moved in each direction.
+type SyncResult struct {
+	Imported  int      `json:"imported"`
+	Created   int      `json:"created"`
+	Exported  int      `json:"exported"`
+	Removed   int      `json:"removed"`
+	Detached  int      `json:"detached"`
+	Conflicts int      `json:"conflicts"`
+	Commit    string   `json:"commit,omitempty"`
+	Errors    []string `json:"errors"`
+}
+
+type LinkRequest struct {
+	Remote string `json:"remote"`
+	Branch string `json:"branch"`
+}
+
+type ResolveRequest struct {
+	Keep string `json:"keep"`
+}
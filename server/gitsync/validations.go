@@ -0,0 +1,158 @@
+package gitsync
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+const (
+	defaultBranch   = "main"
+	maxRemoteLength = 2048
+	maxBranchLength = 200
+)
+
+var (
+	// user@host:path, the scp-like form git accepts for ssh
+	scpRemote     = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9.-]+:[^/\\][^\\]*$`)
+	branchPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+)
+
+func invalidInput() error {
+	return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+}
+
+// validateRemote accepts http(s) and ssh URLs, scp-like ssh remotes and, when
+// the server allows local repositories, absolute paths below localRoot. Other
+// transports such as file:// or ext:: could reach into the server itself.
+func validateRemote(remote string, localRoot string) (string, error) {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return remote, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(remote) > maxRemoteLength || strings.HasPrefix(remote, "-") || strings.Contains(remote, "::") {
+		return remote, invalidInput()
+	}
+	for _, r := range remote {
+		if r <= ' ' || r == 0x7f {
+			return remote, invalidInput()
+		}
+	}
+
+	if strings.HasPrefix(remote, "/") {
+		if localRoot == "" {
+			return remote, invalidInput()
+		}
+		clean := filepath.Clean(remote)
+		rel, err := filepath.Rel(localRoot, clean)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			return remote, invalidInput()
+		}
+		return clean, nil
+	}
+	if strings.Contains(remote, "://") {
+		parsed, err := url.Parse(remote)
+		if err != nil || parsed.Hostname() == "" {
+			return remote, invalidInput()
+		}
+		switch parsed.Scheme {
+		case "https", "http", "ssh":
+			return remote, nil
+		}
+		return remote, invalidInput()
+	}
+	if scpRemote.MatchString(remote) {
+		return remote, nil
+	}
+	return remote, invalidInput()
+}
+
+// validateBranch applies the parts of git check-ref-format a branch name
+// typed into a form can get wrong.
+func validateBranch(branch string) (string, error) {
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return defaultBranch, nil
+	}
+	if len(branch) > maxBranchLength || !branchPattern.MatchString(branch) {
+		return branch, invalidInput()
+	}
+	if strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/") ||
+		strings.HasSuffix(branch, ".") || strings.HasSuffix(branch, ".lock") ||
+		strings.Contains(branch, "..") || strings.Contains(branch, "//") || strings.Contains(branch, "/.") ||
+		strings.HasPrefix(branch, ".") {
+		return branch, invalidInput()
+	}
+	return branch, nil
+}
+
+func validateLinkRequest(data []byte, localRoot string) (LinkRequest, error) {
+	var req LinkRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, invalidInput()
+	}
+	remote, err := validateRemote(req.Remote, localRoot)
+	if err != nil {
+		return req, err
+	}
+	branch, err := validateBranch(req.Branch)
+	if err != nil {
+		return req, err
+	}
+	req.Remote, req.Branch = remote, branch
+	return req, nil
+}
+
+func validateResolveRequest(data []byte) (ResolveRequest, error) {
+	var req ResolveRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, invalidInput()
+	}
+	req.Keep = strings.ToLower(strings.TrimSpace(req.Keep))
+	switch req.Keep {
+	case "":
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	case KEEP_WEB, KEEP_REPO:
+		return req, nil
+	}
+	return req, invalidInput()
+}
+
+// redactRemote hides credentials embedded in a remote URL. For http(s) the
+// user name is often the token itself, so the whole user info goes.
+func redactRemote(remote string) string {
+	if !strings.Contains(remote, "://") {
+		return remote
+	}
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.User == nil {
+		return remote
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword && parsed.Scheme == "ssh" {
+		return remote
+	}
+	parsed.User = nil
+	return strings.Replace(parsed.String(), "://", "://***@", 1)
+}
+
+// redactError keeps a remote's credentials out of messages stored with the
+// link; git repeats the remote in many of its errors.
+func redactError(message string, remote string) string {
+	if redacted := redactRemote(remote); redacted != remote {
+		message = strings.ReplaceAll(message, remote, redacted)
+	}
+	if parsed, err := url.Parse(remote); err == nil && parsed.User != nil {
+		if password, ok := parsed.User.Password(); ok && password != "" {
+			message = strings.ReplaceAll(message, password, "***")
+		}
+		if parsed.Scheme != "ssh" && parsed.User.Username() != "" {
+			message = strings.ReplaceAll(message, parsed.User.Username(), "***")
+		}
+	}
+	return message
+}
@@ -0,0 +1,253 @@
+package group
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// groupFromRequest parses the group id and checks the caller holds permission
+// on it, writing the failure response itself.
+func groupFromRequest(w http.ResponseWriter, r *http.Request, userID uuid.UUID, permission string) (uuid.UUID, bool) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "groupId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return uuid.Nil, false
+	}
+	if !core.ValidateUserEntityPermission(r.Context(), "group", groupID.String(), userID, permission) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return uuid.Nil, false
+	}
+	return groupID, true
+}
+
+func sendGroupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err.Error() {
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS]:
+		core.SendFailedReponse(w, r, http.StatusConflict, err.Error())
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+	default:
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func listGroupsController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groups, err := listVisibleGroups(r.Context(), userID, r.URL.Query().Get("q"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, groups)
+}
+
+func createGroupController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateCreateGroup(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	group, err := createGroup(r.Context(), userID, req)
+	if err != nil {
+		sendGroupError(w, r, err)
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionGroupCreated,
+		TargetType: "group",
+		TargetId:   group.Id.String(),
+		After:      map[string]string{"name": group.Name},
+	})
+	core.SendSuccessResponse(w, r, http.StatusCreated, group)
+}
+
+func getGroupController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groupID, ok := groupFromRequest(w, r, userID, "view")
+	if !ok {
+		return
+	}
+	details, err := getGroupDetails(r.Context(), groupID, userID)
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, details)
+}
+
+func updateGroupController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groupID, ok := groupFromRequest(w, r, userID, "manage")
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateUpdateGroup(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	before, after, err := updateGroup(r.Context(), groupID, req)
+	if err != nil {
+		sendGroupError(w, r, err)
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionGroupUpdated,
+		TargetType: "group",
+		TargetId:   groupID.String(),
+		Before:     map[string]string{"name": before.Name, "description": before.Description},
+		After:      map[string]string{"name": after.Name, "description": after.Description},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, after)
+}
+
+func addMembersController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groupID, ok := groupFromRequest(w, r, userID, "manage")
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateAddMembers(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	added, err := addGroupMembers(r.Context(), groupID, userID, req.Members)
+	for _, member := range added {
+		audit.Record(r, audit.Entry{
+			Action:     audit.ActionGroupMemberAdded,
+			TargetType: "group",
+			TargetId:   groupID.String(),
+			After:      member,
+		})
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT]:
+			status = http.StatusBadRequest
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+			status = http.StatusForbidden
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+			status = http.StatusNotFound
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]any{
+		"addedCount": len(added),
+		"added":      added,
+	})
+}
+
+func removeMemberController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groupID, ok := groupFromRequest(w, r, userID, "manage")
+	if !ok {
+		return
+	}
+	ref, err := validateMemberRef(MemberRef{
+		Type: chi.URLParam(r, "memberType"),
+		Id:   chi.URLParam(r, "memberId"),
+	})
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := removeGroupMember(r.Context(), groupID, ref); err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionGroupMemberRemoved,
+		TargetType: "group",
+		TargetId:   groupID.String(),
+		Before:     ref,
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]bool{"removed": true})
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+	r.Get("/list", listGroupsController)
+	r.Post("/create", createGroupController)
+	r.Get("/{groupId}", getGroupController)
+	r.Put("/{groupId}", updateGroupController)
+	r.Post("/{groupId}/members/add", addMembersController)
+	r.Delete("/{groupId}/members/{memberType}/{memberId}", removeMemberController)
+	return r
+}
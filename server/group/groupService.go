@@ -0,0 +1,328 @@
+package group
+
+import (
+	"context"
+	"errors"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func createGroup(ctx context.Context, actorId uuid.UUID, req CreateGroupRequest) (Group, error) {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, insertGroup, req.Name, req.Description, actorId, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	group, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Group])
+	if isUniqueViolation(err) {
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if err := core.QueueRelations(ctx, tx, core.RelationWrite("group", group.Id.String(), "owner", "user", actorId.String())); err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	core.ApplyQueuedRelations(ctx)
+	return group, nil
+}
+
+// updateGroup returns the group before and after the change.
+func updateGroup(ctx context.Context, groupId uuid.UUID, req UpdateGroupRequest) (Group, Group, error) {
+	before, err := GetGroup(ctx, groupId)
+	if err != nil {
+		return Group{}, Group{}, err
+	}
+	rows, err := core.GetPool().Query(ctx, updateGroupDetails, groupId, req.Name, req.Description, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return before, Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	after, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Group])
+	if isUniqueViolation(err) {
+		return before, Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return before, Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	return before, after, nil
+}
+
+// GetGroup returns a single group of the context's organization with its
+// direct member count.
+func GetGroup(ctx context.Context, groupId uuid.UUID) (Group, error) {
+	rows, err := core.GetPool().Query(ctx, getGroup, groupId, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	group, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Group])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return Group{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return group, nil
+}
+
+func listVisibleGroups(ctx context.Context, userId uuid.UUID, query string) ([]Group, error) {
+	groups := make([]Group, 0)
+	groupIds, err := core.GetEntitiesWithPermission(ctx, "group", "user", userId.String(), "view")
+	if err != nil {
+		logger().Error(err.Error())
+		return groups, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(groupIds) == 0 {
+		return groups, nil
+	}
+	rows, err := core.GetPool().Query(ctx, listGroups, groupIds, query, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return groups, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	groups, err = pgx.CollectRows(rows, pgx.RowToStructByName[Group])
+	if err != nil {
+		logger().Error(err.Error())
+		return groups, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return groups, nil
+}
+
+func getGroupDetails(ctx context.Context, groupId uuid.UUID, userId uuid.UUID) (GroupDetails, error) {
+	var details GroupDetails
+	group, err := GetGroup(ctx, groupId)
+	if err != nil {
+		return details, err
+	}
+	rows, err := core.GetPool().Query(ctx, getGroupMembers, groupId)
+	if err != nil {
+		logger().Error(err.Error())
+		return details, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[Member])
+	if err != nil {
+		logger().Error(err.Error())
+		return details, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if err := nameMembers(ctx, members); err != nil {
+		return details, err
+	}
+	details.Group = group
+	details.Members = members
+	details.CanManage = core.ValidateUserEntityPermission(ctx, "group", groupId.String(), userId, "manage")
+	return details, nil
+}
+
+func nameMembers(ctx context.Context, members []Member) error {
+	userIds := make([]string, 0, len(members))
+	groupIds := make([]uuid.UUID, 0)
+	for _, member := range members {
+		if member.Type == MEMBER_TYPE_GROUP {
+			groupIds = append(groupIds, member.Id)
+		} else {
+			userIds = append(userIds, member.Id.String())
+		}
+	}
+	profiles, err := core.GetUserProfiles(userIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	names, err := GroupNames(ctx, groupIds)
+	if err != nil {
+		return err
+	}
+	for i := range members {
+		if members[i].Type == MEMBER_TYPE_GROUP {
+			members[i].Name = names[members[i].Id]
+			continue
+		}
+		profile := profiles[members[i].Id.String()]
+		members[i].Name = profile.Name
+		members[i].Email = profile.Email
+		if members[i].Name == "" {
+			members[i].Name = profile.Email
+		}
+	}
+	return nil
+}
+
+// addGroupMembers adds users and nested groups and returns the ones that were
+// not already members. Nesting a group that already contains this one is
+// rejected so membership can never loop. Members are added all or none.
+func addGroupMembers(ctx context.Context, groupId uuid.UUID, actorId uuid.UUID, refs []MemberRef) ([]MemberRef, error) {
+	if _, err := GetGroup(ctx, groupId); err != nil {
+		return nil, err
+	}
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	added := make([]MemberRef, 0, len(refs))
+	for _, ref := range refs {
+		memberId := uuid.MustParse(ref.Id)
+		if ref.Type == MEMBER_TYPE_GROUP {
+			if memberId == groupId {
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+			if !core.ValidateUserEntityPermission(ctx, "group", ref.Id, actorId, "view") {
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+			}
+			// only groups of the same organization can be nested
+			if _, err := GetGroup(ctx, memberId); err != nil {
+				return nil, err
+			}
+			var loops bool
+			if err := tx.QueryRow(ctx, groupContainsGroup, memberId, groupId).Scan(&loops); err != nil {
+				logger().Error(err.Error())
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+			}
+			if loops {
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+		}
+		tag, err := tx.Exec(ctx, insertGroupMember, groupId, ref.Type, memberId, actorId)
+		if err != nil {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		if err := core.QueueRelations(ctx, tx, memberRelation(core.RELATION_WRITE, groupId, ref)); err != nil {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		added = append(added, ref)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	core.ApplyQueuedRelations(ctx)
+	return added, nil
+}
+
+func removeGroupMember(ctx context.Context, groupId uuid.UUID, ref MemberRef) error {
+	if _, err := GetGroup(ctx, groupId); err != nil {
+		return err
+	}
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, deleteGroupMember, groupId, ref.Type, uuid.MustParse(ref.Id))
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err := core.QueueRelations(ctx, tx, memberRelation(core.RELATION_DELETE, groupId, ref)); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
+	return nil
+}
+
+// memberRelation is the group#member tuple behind a member row; a nested
+// group's members are members through group#member.
+func memberRelation(operation string, groupId uuid.UUID, ref MemberRef) core.RelationChange {
+	change := core.RelationChange{Operation: operation, Entity: "group", EntityId: groupId.String(), Relation: "member", Subject: "user", SubjectId: ref.Id}
+	if ref.Type == MEMBER_TYPE_GROUP {
+		change.Subject = "group"
+		return change.OfSubjectSet("member")
+	}
+	return change
+}
+
+// ExpandUsers returns, for each group of the context's organization, every
+// user who is a member directly or through nested groups.
+func ExpandUsers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	expanded := make(map[uuid.UUID][]uuid.UUID, len(groupIds))
+	if len(groupIds) == 0 {
+		return expanded, nil
+	}
+	rows, err := core.GetPool().Query(ctx, expandGroupUsers, groupIds, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return expanded, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupId, userId uuid.UUID
+		if err := rows.Scan(&groupId, &userId); err != nil {
+			logger().Error(err.Error())
+			return expanded, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		expanded[groupId] = append(expanded[groupId], userId)
+	}
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return expanded, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return expanded, nil
+}
+
+// GroupNames maps group ids to their names. Ids unknown in the context's
+// organization are left out.
+func GroupNames(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID]string, error) {
+	names := make(map[uuid.UUID]string, len(groupIds))
+	if len(groupIds) == 0 {
+		return names, nil
+	}
+	rows, err := core.GetPool().Query(ctx, getGroupNames, groupIds, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return names, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			logger().Error(err.Error())
+			return names, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return names, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return names, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	// 23505 is unique_violation: another group of the organization has the name
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,19 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	duplicate := fmt.Errorf("insert group: %w", &pgconn.PgError{Code: "23505"})
+	if !isUniqueViolation(duplicate) {
+		t.Fatalf("expected a wrapped unique violation to be recognised")
+	}
+	if isUniqueViolation(&pgconn.PgError{Code: "23503"}) || isUniqueViolation(errors.New("23505")) || isUniqueViolation(nil) {
+		t.Fatalf("expected other errors not to count as duplicate names")
+	}
+}
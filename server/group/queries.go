@@ -0,0 +1,59 @@
+package group
+
+const (
+	// Groups belong to one organization; every query is given its id.
+
+	insertGroup = `INSERT INTO core.groups (name, description, created_by, org_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, description, created_by, created_at, updated_at, 0 AS member_count`
+
+	updateGroupDetails = `UPDATE core.groups
+		SET name = COALESCE($2, name), description = COALESCE($3, description), updated_at = now()
+		WHERE id = $1 AND org_id = $4
+		RETURNING id, name, description, created_by, created_at, updated_at,
+			(SELECT count(*) FROM core.group_members m WHERE m.group_id = $1)::int AS member_count`
+
+	getGroup = `SELECT g.id, g.name, g.description, g.created_by, g.created_at, g.updated_at,
+			(SELECT count(*) FROM core.group_members m WHERE m.group_id = g.id)::int AS member_count
+		FROM core.groups g
+		WHERE g.id = $1 AND g.org_id = $2`
+
+	listGroups = `SELECT g.id, g.name, g.description, g.created_by, g.created_at, g.updated_at,
+			(SELECT count(*) FROM core.group_members m WHERE m.group_id = g.id)::int AS member_count
+		FROM core.groups g
+		WHERE g.id = ANY($1) AND g.org_id = $3 AND ($2 = '' OR g.name ILIKE '%' || $2 || '%')
+		ORDER BY lower(g.name), g.id`
+
+	getGroupNames = `SELECT id, name FROM core.groups WHERE id = ANY($1) AND org_id = $2`
+
+	getGroupMembers = `SELECT member_type, member_id, added_at
+		FROM core.group_members
+		WHERE group_id = $1
+		ORDER BY member_type, added_at`
+
+	insertGroupMember = `INSERT INTO core.group_members (group_id, member_type, member_id, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`
+
+	deleteGroupMember = `DELETE FROM core.group_members WHERE group_id = $1 AND member_type = $2 AND member_id = $3`
+
+	// $1 contains $2 directly or through nested groups
+	groupContainsGroup = `WITH RECURSIVE nested AS (
+			SELECT member_id FROM core.group_members WHERE group_id = $1 AND member_type = 'group'
+			UNION
+			SELECT m.member_id
+			FROM core.group_members m INNER JOIN nested n ON (m.group_id = n.member_id)
+			WHERE m.member_type = 'group'
+		)
+		SELECT EXISTS (SELECT 1 FROM nested WHERE member_id = $2)`
+
+	expandGroupUsers = `WITH RECURSIVE expanded (root_id, member_type, member_id) AS (
+			SELECT g.id, m.member_type, m.member_id
+			FROM core.groups g INNER JOIN core.group_members m ON (m.group_id = g.id)
+			WHERE g.id = ANY($1) AND g.org_id = $2
+			UNION
+			SELECT e.root_id, m.member_type, m.member_id
+			FROM expanded e INNER JOIN core.group_members m ON (e.member_type = 'group' AND m.group_id = e.member_id)
+		)
+		SELECT DISTINCT root_id, member_id FROM expanded WHERE member_type = 'user'`
+)
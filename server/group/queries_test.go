@@ -0,0 +1,37 @@
+package group
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// There is no database in unit tests, so this checks that expandGroupUsers
+// only reads columns its recursive CTE declares.
+func TestExpandGroupUsersUsesDeclaredColumns(t *testing.T) {
+	declaration := regexp.MustCompile(`WITH RECURSIVE expanded \(([^)]*)\) AS \(\s*SELECT ([^\n]*)\n`).FindStringSubmatch(expandGroupUsers)
+	if declaration == nil {
+		t.Fatalf("expected expandGroupUsers to declare the columns of expanded")
+	}
+	declared := make(map[string]bool)
+	for _, column := range strings.Split(declaration[1], ",") {
+		declared[strings.TrimSpace(column)] = true
+	}
+	if anchor := strings.Split(declaration[2], ","); len(anchor) != len(declared) {
+		t.Fatalf("expected the anchor to select %d columns, got %q", len(declared), declaration[2])
+	}
+	for _, match := range regexp.MustCompile(`\be\.(\w+)`).FindAllStringSubmatch(expandGroupUsers, -1) {
+		if !declared[match[1]] {
+			t.Errorf("the recursive term reads e.%s, which expanded does not have", match[1])
+		}
+	}
+	final := regexp.MustCompile(`SELECT DISTINCT ([^\n]*) FROM expanded`).FindStringSubmatch(expandGroupUsers)
+	if final == nil {
+		t.Fatalf("expected expandGroupUsers to select from expanded")
+	}
+	for _, column := range strings.Split(final[1], ",") {
+		if column = strings.TrimSpace(column); !declared[column] {
+			t.Errorf("the final select reads %s, which expanded does not have", column)
+		}
+	}
+}
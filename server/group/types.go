@@ -0,0 +1,55 @@
+package group
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MEMBER_TYPE_USER  = "user"
+	MEMBER_TYPE_GROUP = "group"
+)
+
+type Group struct {
+	Id          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	CreatedBy   uuid.UUID `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+	MemberCount int       `json:"memberCount" db:"member_count"`
+}
+
+type Member struct {
+	Type    string    `json:"type" db:"member_type"`
+	Id      uuid.UUID `json:"id" db:"member_id"`
+	Name    string    `json:"name" db:"-"`
+	Email   string    `json:"email,omitempty" db:"-"`
+	AddedAt time.Time `json:"addedAt" db:"added_at"`
+}
+
+type GroupDetails struct {
+	Group
+	Members   []Member `json:"members"`
+	CanManage bool     `json:"canManage"`
+}
+
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UpdateGroupRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type MemberRef struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type AddMembersRequest struct {
+	Members []MemberRef `json:"members"`
+}
@@ -0,0 +1,100 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+const (
+	maxGroupNameLength = 120
+	maxMembersPerAdd   = 200
+)
+
+func validateGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(name) > maxGroupNameLength {
+		return name, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return name, nil
+}
+
+func validateCreateGroup(data []byte) (CreateGroupRequest, error) {
+	var req CreateGroupRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	name, err := validateGroupName(req.Name)
+	if err != nil {
+		return req, err
+	}
+	req.Name = name
+	req.Description = strings.TrimSpace(req.Description)
+	return req, nil
+}
+
+func validateUpdateGroup(data []byte) (UpdateGroupRequest, error) {
+	var req UpdateGroupRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	if req.Name == nil && req.Description == nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if req.Name != nil {
+		name, err := validateGroupName(*req.Name)
+		if err != nil {
+			return req, err
+		}
+		req.Name = &name
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		req.Description = &description
+	}
+	return req, nil
+}
+
+func validateMemberRef(ref MemberRef) (MemberRef, error) {
+	ref.Type = strings.ToLower(strings.TrimSpace(ref.Type))
+	ref.Id = strings.TrimSpace(ref.Id)
+	if ref.Type == "" {
+		ref.Type = MEMBER_TYPE_USER
+	}
+	if ref.Type != MEMBER_TYPE_USER && ref.Type != MEMBER_TYPE_GROUP {
+		return ref, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	id, err := uuid.Parse(ref.Id)
+	if err != nil {
+		return ref, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	ref.Id = id.String()
+	return ref, nil
+}
+
+func validateAddMembers(data []byte) (AddMembersRequest, error) {
+	var req AddMembersRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	if len(req.Members) == 0 {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(req.Members) > maxMembersPerAdd {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	for i := range req.Members {
+		ref, err := validateMemberRef(req.Members[i])
+		if err != nil {
+			return req, err
+		}
+		req.Members[i] = ref
+	}
+	return req, nil
+}
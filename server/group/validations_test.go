@@ -0,0 +1,49 @@
+package group
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateCreateGroupTrimsName(t *testing.T) {
+	req, err := validateCreateGroup([]byte(`{"name":"  Platform  ","description":" infra "}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Name != "Platform" || req.Description != "infra" {
+		t.Fatalf("expected trimmed values, got %+v", req)
+	}
+}
+
+func TestValidateCreateGroupRejectsLongName(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", maxGroupNameLength+1) + `"}`
+	if _, err := validateCreateGroup([]byte(body)); err == nil {
+		t.Fatalf("expected overly long name to be rejected")
+	}
+}
+
+func TestValidateUpdateGroupRequiresAField(t *testing.T) {
+	if _, err := validateUpdateGroup([]byte(`{}`)); err == nil {
+		t.Fatalf("expected empty update to be rejected")
+	}
+}
+
+func TestValidateAddMembersDefaultsToUser(t *testing.T) {
+	id := uuid.New()
+	req, err := validateAddMembers([]byte(`{"members":[{"id":"` + id.String() + `"},{"type":"Group","id":"` + id.String() + `"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Members[0].Type != MEMBER_TYPE_USER || req.Members[1].Type != MEMBER_TYPE_GROUP {
+		t.Fatalf("unexpected member types: %+v", req.Members)
+	}
+}
+
+func TestValidateAddMembersRejectsUnknownType(t *testing.T) {
+	body := `{"members":[{"type":"team","id":"` + uuid.NewString() + `"}]}`
+	if _, err := validateAddMembers([]byte(body)); err == nil {
+		t.Fatalf("expected unknown member type to be rejected")
+	}
+}
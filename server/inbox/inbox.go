@@ -0,0 +1,262 @@
+package inbox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUserId(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return uuid.Nil, false
+	}
+	userId, err := uuid.Parse(user.AId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return uuid.Nil, false
+	}
+	return userId, true
+}
+
+func sendMarkError(w http.ResponseWriter, r *http.Request, err error) {
+	if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT] {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+}
+
+func getInbox(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filter, err := parseInboxFilter(query.Get("cursor"), query.Get("limit"), query.Get("unread"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := listInbox(r.Context(), userId, filter)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, page)
+}
+
+func getUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	count, err := unreadCount(r.Context(), userId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]int{"unreadCount": count})
+}
+
+func markController(read bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := currentUserId(w, r)
+		if !ok {
+			return
+		}
+		var req MarkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+		if err := validateMarkRequest(req); err != nil {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		update := markUnread
+		if read {
+			update = markRead
+		}
+		result, err := update(r.Context(), userId, req.Ids)
+		if err != nil {
+			sendMarkError(w, r, err)
+			return
+		}
+		core.SendSuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+func markAllReadController(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	var req MarkAllRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UpTo < 0 {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+	}
+	result, err := markEverythingRead(r.Context(), userId, req.UpTo)
+	if err != nil {
+		sendMarkError(w, r, err)
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, result)
+}
+
+func sseEvents(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	Hub.SSEHandler(w, r, userId)
+}
+
+func getPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	preferences, err := notification.NewService().Preferences(r.Context(), userId)
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, preferences)
+}
+
+func updateCategoryPreference(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	category := chi.URLParam(r, "category")
+	var update notification.CategoryUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if err := validateCategoryUpdate(category, update); err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated, err := notification.NewService().UpdateCategory(r.Context(), userId, category, update)
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, updated)
+}
+
+func updateSpacePreference(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	spaceId, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	var req SpacePreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err = parseSpacePreference(req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	preference := notification.SpacePreference{SpaceId: spaceId, Category: req.Category, Enabled: req.Enabled, Channel: req.Channel}
+	if err := notification.NewService().SetSpacePreference(r.Context(), userId, preference); err != nil {
+		if errors.Is(err, notification.ErrUnknownSpace) {
+			core.SendFailedReponse(w, r, http.StatusNotFound, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+			return
+		}
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, preference)
+}
+
+func clearSpacePreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	spaceId, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	category := strings.TrimSpace(r.URL.Query().Get("category"))
+	if category != "" && category != notification.AllCategories && !notification.IsCategory(category) {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if err := notification.NewService().ClearSpacePreferences(r.Context(), userId, spaceId, category); err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, "success")
+}
+
+func updateSchedule(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUserId(w, r)
+	if !ok {
+		return
+	}
+	var schedule notification.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if err := notification.NewService().SetSchedule(r.Context(), userId, schedule); err != nil {
+		if errors.Is(err, notification.ErrInvalidSchedule) {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, schedule)
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/", getInbox)
+	r.Get("/unread-count", getUnreadCount)
+	r.Get("/events", sseEvents)
+	r.Post("/read", markController(true))
+	r.Post("/unread", markController(false))
+	r.Post("/read-all", markAllReadController)
+	r.Get("/preferences", getPreferences)
+	r.Put("/preferences/schedule", updateSchedule)
+	r.Put("/preferences/spaces/{spaceId}", updateSpacePreference)
+	r.Delete("/preferences/spaces/{spaceId}", clearSpacePreferences)
+	r.Put("/preferences/{category}", updateCategoryPreference)
+	return r
+}
@@ -0,0 +1,144 @@
+package inbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventCreated EventType = "notification:created"
+	EventRead    EventType = "notification:read"
+	EventUnread  EventType = "notification:unread"
+)
+
+type InboxEvent struct {
+	Type    EventType   `json:"type"`
+	Payload interface{} `json:"payload"`
+	userID  uuid.UUID
+}
+
+type Client struct {
+	userID  uuid.UUID
+	channel chan []byte
+}
+
+type EventHub struct {
+	sync.RWMutex
+	clients    map[*Client]bool
+	broadcast  chan InboxEvent
+	register   chan *Client
+	unregister chan *Client
+}
+
+// broadcastBuffer is how many events can wait for the hub before Publish
+// starts dropping them.
+const broadcastBuffer = 1024
+
+var Hub *EventHub
+
+func init() {
+	Hub = newEventHub()
+	go Hub.run()
+}
+
+func newEventHub() *EventHub {
+	return &EventHub{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan InboxEvent, broadcastBuffer),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+	}
+}
+
+func (h *EventHub) run() {
+	for {
+		select {
+		case client := <-h.register:
+			h.Lock()
+			h.clients[client] = true
+			h.Unlock()
+		case client := <-h.unregister:
+			h.Lock()
+			if _, ok := h.clients[client]; ok {
+				delete(h.clients, client)
+				close(client.channel)
+			}
+			h.Unlock()
+		case event := <-h.broadcast:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			msg := []byte(fmt.Sprintf("data: %s\n\n", payload))
+
+			h.Lock()
+			for client := range h.clients {
+				if client.userID == event.userID {
+					select {
+					case client.channel <- msg:
+					default:
+						// a client that can't keep up is dropped here rather
+						// than through unregister, which only this loop reads
+						delete(h.clients, client)
+						close(client.channel)
+					}
+				}
+			}
+			h.Unlock()
+		}
+	}
+}
+
+// Publish sends an event to every open stream of the user, so all their tabs
+// stay in step. It doesn't wait: notifications are already stored, so when
+// the hub is backed up the live event is dropped rather than holding up the
+// caller.
+func (h *EventHub) Publish(eventType EventType, userID uuid.UUID, payload interface{}) {
+	select {
+	case h.broadcast <- InboxEvent{Type: eventType, Payload: payload, userID: userID}:
+	default:
+		logger().Warn("inbox hub is backed up, dropping live event")
+	}
+}
+
+// SSEHandler streams the user's new notifications and read state changes.
+func (h *EventHub) SSEHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	client := &Client{
+		userID:  userId,
+		channel: make(chan []byte, 256),
+	}
+
+	h.register <- client
+
+	// Listen for client disconnect
+	notify := r.Context().Done()
+	go func() {
+		<-notify
+		h.unregister <- client
+	}()
+
+	// Send initial ping to establish connection
+	fmt.Fprintf(w, ": ping\n\n")
+	flusher.Flush()
+
+	for msg := range client.channel {
+		w.Write(msg)
+		flusher.Flush()
+	}
+}
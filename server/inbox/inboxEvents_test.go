@@ -0,0 +1,54 @@
+package inbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHubDropsClientThatCannotKeepUp(t *testing.T) {
+	hub := newEventHub()
+	go hub.run()
+
+	userId := uuid.New()
+	slow := &Client{userID: userId, channel: make(chan []byte, 1)}
+	hub.register <- slow
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			hub.Publish(EventCreated, userId, map[string]int{"n": i})
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Publish not to wait on a full client")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		hub.RLock()
+		_, registered := hub.clients[slow]
+		hub.RUnlock()
+		if !registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the full client to be dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	<-slow.channel
+	if _, open := <-slow.channel; open {
+		t.Fatalf("expected the dropped client's channel to be closed")
+	}
+	// a late unregister from the disconnect watcher is harmless
+	select {
+	case hub.unregister <- slow:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the hub to keep running after dropping a client")
+	}
+}
@@ -0,0 +1,146 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultInboxLimit = 25
+	maxInboxLimit     = 100
+	maxMarkIds        = 500
+)
+
+// Notify delivers a notification where the recipient wants its category:
+// into their inbox, streamed to any open tab, and by email when email
+// notifications are on.
+func Notify(ctx context.Context, n Notification) error {
+	service := notification.NewService()
+	delivery := notification.Delivery{Email: true}
+	if n.UserId != uuid.Nil {
+		var err error
+		delivery, err = service.DeliveryFor(ctx, n.UserId, n.Category, n.SpaceId)
+		if err != nil {
+			return err
+		}
+	}
+	if delivery.InApp && n.UserId != uuid.Nil {
+		item, err := store(ctx, n)
+		if err != nil {
+			return err
+		}
+		Hub.Publish(EventCreated, n.UserId, item)
+	}
+	if delivery.Email && n.Email != nil && notification.LoadConfig().NotificationsEnabled {
+		req := *n.Email
+		req.Category = n.Category
+		if req.SpaceID == nil {
+			req.SpaceID = n.SpaceId
+		}
+		if _, err := service.EnqueueEmail(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func store(ctx context.Context, n Notification) (Item, error) {
+	data := n.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	item := Item{
+		Category:  n.Category,
+		Type:      n.Type,
+		Title:     n.Title,
+		Body:      optional(n.Body),
+		Url:       optional(n.Url),
+		SpaceId:   n.SpaceId,
+		PageId:    n.PageId,
+		ActorId:   n.ActorId,
+		ActorName: optional(n.ActorName),
+		Data:      data,
+	}
+	err := core.GetPool().QueryRow(ctx, insertItem, n.UserId, item.Category, string(item.Type), item.Title, item.Body, item.Url,
+		item.SpaceId, item.PageId, item.ActorId, item.ActorName, data).Scan(&item.Id, &item.CreatedAt)
+	return item, err
+}
+
+func unreadCount(ctx context.Context, userId uuid.UUID) (int, error) {
+	var count int
+	if err := core.GetPool().QueryRow(ctx, countUnread, userId).Scan(&count); err != nil {
+		logger().Error(err.Error())
+		return 0, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	return count, nil
+}
+
+func listInbox(ctx context.Context, userId uuid.UUID, filter inboxFilter) (Page, error) {
+	page := Page{Items: []Item{}}
+	rows, err := core.GetPool().Query(ctx, listItems, userId, filter.cursor, filter.unread, filter.limit)
+	if err != nil {
+		logger().Error(err.Error())
+		return page, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Item])
+	if err != nil {
+		logger().Error(err.Error())
+		return page, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	page.Items = items
+	if len(items) == filter.limit {
+		next := strconv.FormatInt(items[len(items)-1].Id, 10)
+		page.NextCursor = &next
+	}
+	page.UnreadCount, err = unreadCount(ctx, userId)
+	return page, err
+}
+
+// mark runs one of the read state updates and tells the user's other tabs
+// which items changed.
+func mark(ctx context.Context, userId uuid.UUID, eventType EventType, query string, args ...any) (MarkResult, error) {
+	result := MarkResult{Ids: []int64{}}
+	rows, err := core.GetPool().Query(ctx, query, append([]any{userId}, args...)...)
+	if err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	result.Ids = ids
+	if result.UnreadCount, err = unreadCount(ctx, userId); err != nil {
+		return result, err
+	}
+	if len(ids) > 0 {
+		Hub.Publish(eventType, userId, result)
+	}
+	return result, nil
+}
+
+func markRead(ctx context.Context, userId uuid.UUID, ids []int64) (MarkResult, error) {
+	return mark(ctx, userId, EventRead, markItemsRead, ids)
+}
+
+func markUnread(ctx context.Context, userId uuid.UUID, ids []int64) (MarkResult, error) {
+	return mark(ctx, userId, EventUnread, markItemsUnread, ids)
+}
+
+func markEverythingRead(ctx context.Context, userId uuid.UUID, upTo int64) (MarkResult, error) {
+	return mark(ctx, userId, EventRead, markAllRead, upTo)
+}
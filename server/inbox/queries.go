@@ -0,0 +1,29 @@
+package inbox
+
+const (
+	insertItem = `INSERT INTO notifications.inbox (user_id, category, type, title, body, url, space_id, page_id, actor_id, actor_name, data)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, created_at`
+
+	listItems = `SELECT id, category, type, title, body, url, space_id, page_id, actor_id, actor_name, data, read_at, created_at
+FROM notifications.inbox
+WHERE user_id = $1
+	AND ($2::bigint = 0 OR id < $2)
+	AND (NOT $3 OR read_at IS NULL)
+ORDER BY id DESC
+LIMIT $4`
+
+	countUnread = `SELECT count(*) FROM notifications.inbox WHERE user_id = $1 AND read_at IS NULL`
+
+	markItemsRead = `UPDATE notifications.inbox SET read_at = now()
+WHERE user_id = $1 AND id = ANY($2) AND read_at IS NULL
+RETURNING id`
+
+	markItemsUnread = `UPDATE notifications.inbox SET read_at = NULL
+WHERE user_id = $1 AND id = ANY($2) AND read_at IS NOT NULL
+RETURNING id`
+
+	markAllRead = `UPDATE notifications.inbox SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL AND ($2::bigint = 0 OR id <= $2)
+RETURNING id`
+)
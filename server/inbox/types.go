@@ -0,0 +1,92 @@
+package inbox
+
+import (
+	"time"
+
+	"github.com/durgakiran/beskar/notification"
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	CommentReplied       Type = "comment:replied"
+	CommentMentioned     Type = "comment:mentioned"
+	PageUpdated          Type = "page:updated"
+	InviteReceived       Type = "invite:received"
+	InviteAccepted       Type = "invite:accepted"
+	InviteDeclined       Type = "invite:declined"
+	AccessRequested      Type = "access:requested"
+	AccessRequestDecided Type = "access:decided"
+	AccessReviewStarted  Type = "access:review_started"
+	MembershipExpiring   Type = "membership:expiring"
+)
+
+// Notification is what producers hand to Notify. UserId may be left empty
+// for someone without an account yet; only the email is sent then. Email is
+// optional and its category is taken from the notification.
+type Notification struct {
+	UserId    uuid.UUID
+	Category  string
+	Type      Type
+	Title     string
+	Body      string
+	Url       string
+	SpaceId   *uuid.UUID
+	PageId    *int64
+	ActorId   *uuid.UUID
+	ActorName string
+	Data      map[string]interface{}
+	Email     *notification.EnqueueEmailRequest
+}
+
+type Item struct {
+	Id        int64                  `json:"id" db:"id"`
+	Category  string                 `json:"category" db:"category"`
+	Type      Type                   `json:"type" db:"type"`
+	Title     string                 `json:"title" db:"title"`
+	Body      *string                `json:"body,omitempty" db:"body"`
+	Url       *string                `json:"url,omitempty" db:"url"`
+	SpaceId   *uuid.UUID             `json:"spaceId,omitempty" db:"space_id"`
+	PageId    *int64                 `json:"pageId,omitempty" db:"page_id"`
+	ActorId   *uuid.UUID             `json:"actorId,omitempty" db:"actor_id"`
+	ActorName *string                `json:"actorName,omitempty" db:"actor_name"`
+	Data      map[string]interface{} `json:"data" db:"data"`
+	ReadAt    *time.Time             `json:"readAt,omitempty" db:"read_at"`
+	CreatedAt time.Time              `json:"createdAt" db:"created_at"`
+}
+
+type Page struct {
+	Items       []Item  `json:"items"`
+	NextCursor  *string `json:"nextCursor"`
+	UnreadCount int     `json:"unreadCount"`
+}
+
+type MarkRequest struct {
+	Ids []int64 `json:"ids"`
+}
+
+// MarkAllRequest marks everything up to and including UpTo, so items that
+// arrived after the client last looked stay unread. Zero marks everything.
+type MarkAllRequest struct {
+	UpTo int64 `json:"upTo"`
+}
+
+type MarkResult struct {
+	Ids         []int64 `json:"ids"`
+	UnreadCount int     `json:"unreadCount"`
+}
+
+// SpacePreferenceRequest overrides one category in a space, or every
+// category when Category is empty. A nil Channel keeps the category's.
+type SpacePreferenceRequest struct {
+	Category string  `json:"category"`
+	Enabled  bool    `json:"enabled"`
+	Channel  *string `json:"channel"`
+}
+
+type inboxFilter struct {
+	cursor int64
+	limit  int
+	unread bool
+}
@@ -0,0 +1,86 @@
+package inbox
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/notification"
+)
+
+func parseInboxFilter(cursor string, limit string, unread string) (inboxFilter, error) {
+	filter := inboxFilter{limit: defaultInboxLimit}
+
+	if cursor = strings.TrimSpace(cursor); cursor != "" {
+		parsed, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || parsed <= 0 {
+			return filter, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		filter.cursor = parsed
+	}
+
+	if limit = strings.TrimSpace(limit); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return filter, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if parsed > maxInboxLimit {
+			parsed = maxInboxLimit
+		}
+		filter.limit = parsed
+	}
+
+	if unread = strings.TrimSpace(unread); unread != "" {
+		parsed, err := strconv.ParseBool(unread)
+		if err != nil {
+			return filter, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		filter.unread = parsed
+	}
+	return filter, nil
+}
+
+func validateMarkRequest(req MarkRequest) error {
+	if len(req.Ids) == 0 || len(req.Ids) > maxMarkIds {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	for _, id := range req.Ids {
+		if id <= 0 {
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+	}
+	return nil
+}
+
+func validateCategoryUpdate(category string, update notification.CategoryUpdate) error {
+	if !notification.IsCategory(category) {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if update.Enabled == nil && update.Channel == nil && update.Frequency == nil {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if update.Channel != nil && !notification.IsChannel(*update.Channel) {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if update.Frequency != nil && !notification.IsFrequency(*update.Frequency) {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return nil
+}
+
+// parseSpacePreference checks an override and fills in its category, which
+// is every category when left out.
+func parseSpacePreference(req SpacePreferenceRequest) (SpacePreferenceRequest, error) {
+	req.Category = strings.TrimSpace(req.Category)
+	if req.Category == "" {
+		req.Category = notification.AllCategories
+	}
+	if req.Category != notification.AllCategories && !notification.IsCategory(req.Category) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if req.Channel != nil && !notification.IsChannel(*req.Channel) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
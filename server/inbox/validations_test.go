@@ -0,0 +1,91 @@
+package inbox
+
+import (
+	"testing"
+
+	"github.com/durgakiran/beskar/notification"
+)
+
+func TestParseInboxFilterDefaults(t *testing.T) {
+	filter, err := parseInboxFilter("", "", "")
+	if err != nil {
+		t.Fatalf("expected empty filter to parse: %v", err)
+	}
+	if filter.cursor != 0 || filter.unread {
+		t.Fatalf("expected no cursor and every item, got %+v", filter)
+	}
+	if filter.limit != defaultInboxLimit {
+		t.Fatalf("expected default limit %d, got %d", defaultInboxLimit, filter.limit)
+	}
+}
+
+func TestParseInboxFilterUnreadAndCap(t *testing.T) {
+	filter, err := parseInboxFilter("42", "1000", "true")
+	if err != nil {
+		t.Fatalf("expected filter to parse: %v", err)
+	}
+	if filter.cursor != 42 || !filter.unread || filter.limit != maxInboxLimit {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+	if _, err := parseInboxFilter("", "", "maybe"); err == nil {
+		t.Fatalf("expected invalid unread flag to be rejected")
+	}
+	if _, err := parseInboxFilter("-1", "", ""); err == nil {
+		t.Fatalf("expected negative cursor to be rejected")
+	}
+}
+
+func TestValidateMarkRequest(t *testing.T) {
+	if err := validateMarkRequest(MarkRequest{Ids: []int64{1, 2}}); err != nil {
+		t.Fatalf("expected ids to be accepted: %v", err)
+	}
+	if err := validateMarkRequest(MarkRequest{}); err == nil {
+		t.Fatalf("expected an empty request to be rejected")
+	}
+	if err := validateMarkRequest(MarkRequest{Ids: []int64{0}}); err == nil {
+		t.Fatalf("expected a zero id to be rejected")
+	}
+	if err := validateMarkRequest(MarkRequest{Ids: make([]int64, maxMarkIds+1)}); err == nil {
+		t.Fatalf("expected too many ids to be rejected")
+	}
+}
+
+func TestValidateCategoryUpdate(t *testing.T) {
+	inApp, both, sms := notification.ChannelInApp, notification.ChannelBoth, "sms"
+	daily, weekly := notification.FrequencyDaily, "weekly"
+	if err := validateCategoryUpdate(notification.CategoryCommentReply, notification.CategoryUpdate{Channel: &inApp}); err != nil {
+		t.Fatalf("expected a known category and channel to be accepted: %v", err)
+	}
+	if err := validateCategoryUpdate(notification.CategoryCommentReply, notification.CategoryUpdate{Frequency: &daily}); err != nil {
+		t.Fatalf("expected a known frequency to be accepted: %v", err)
+	}
+	if err := validateCategoryUpdate("newsletter", notification.CategoryUpdate{Channel: &both}); err == nil {
+		t.Fatalf("expected an unknown category to be rejected")
+	}
+	if err := validateCategoryUpdate(notification.CategorySpaceInvite, notification.CategoryUpdate{Channel: &sms}); err == nil {
+		t.Fatalf("expected an unknown channel to be rejected")
+	}
+	if err := validateCategoryUpdate(notification.CategorySpaceInvite, notification.CategoryUpdate{Frequency: &weekly}); err == nil {
+		t.Fatalf("expected an unknown frequency to be rejected")
+	}
+	if err := validateCategoryUpdate(notification.CategorySpaceInvite, notification.CategoryUpdate{}); err == nil {
+		t.Fatalf("expected an empty update to be rejected")
+	}
+}
+
+func TestParseSpacePreference(t *testing.T) {
+	req, err := parseSpacePreference(SpacePreferenceRequest{Enabled: false})
+	if err != nil || req.Category != notification.AllCategories {
+		t.Fatalf("expected a missing category to cover every category, got %q, %v", req.Category, err)
+	}
+	if _, err := parseSpacePreference(SpacePreferenceRequest{Category: notification.CategoryCommentReply, Enabled: true}); err != nil {
+		t.Fatalf("expected a known category to be accepted: %v", err)
+	}
+	if _, err := parseSpacePreference(SpacePreferenceRequest{Category: "newsletter"}); err == nil {
+		t.Fatalf("expected an unknown category to be rejected")
+	}
+	sms := "sms"
+	if _, err := parseSpacePreference(SpacePreferenceRequest{Enabled: true, Channel: &sms}); err == nil {
+		t.Fatalf("expected an unknown channel to be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package invite
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+	// ExpiresIn is how long a new invite stays valid when the sender doesn't
+	// pick an expiry. Zero keeps invites valid until they are answered.
+	ExpiresIn time.Duration
+	// RemindAfter is how long an invite waits unanswered before the invitee
+	// is reminded, once. Zero sends no reminders.
+	RemindAfter time.Duration
+	// NotifyAdmins copies accepted and declined invites to the space's owner
+	// and admins as well as the sender.
+	NotifyAdmins bool
+}
+
+// LoadConfig reads the invite expiry, reminder and notification settings. The
+// sweeper is on unless turned off, since invites given an expiry are expected
+// to lapse.
+func LoadConfig() Config {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("INVITE_SWEEP_ENABLED")))
+	if err != nil {
+		enabled = true
+	}
+	notifyAdmins, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("INVITE_NOTIFY_ADMINS")))
+	if err != nil {
+		notifyAdmins = false
+	}
+	return Config{
+		Enabled:      enabled,
+		Interval:     time.Duration(envInt("INVITE_SWEEP_INTERVAL_MINUTES", 15)) * time.Minute,
+		BatchSize:    envInt("INVITE_SWEEP_BATCH_SIZE", 50),
+		ExpiresIn:    time.Duration(envDays("INVITE_EXPIRY_DAYS", 14)) * 24 * time.Hour,
+		RemindAfter:  time.Duration(envDays("INVITE_REMINDER_DAYS", 3)) * 24 * time.Hour,
+		NotifyAdmins: notifyAdmins,
+	}
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// envDays is like envInt but lets 0 through, to turn the setting off.
+func envDays(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
@@ -4,4 +4,5 @@ const (
 	STATUS_ACCEPTED = "ACCEPTED"
 	STATUS_REMOVED  = "REMOVED"
 	STATUS_REJECTED = "REJECTED"
+	STATUS_EXPIRED  = "EXPIRED"
 )
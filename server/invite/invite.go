@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/audit"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -39,6 +41,8 @@ func sendInviteActionError(w http.ResponseWriter, r *http.Request, err error) {
 		sendFailedReponse(w, r, http.StatusForbidden, "This invitation was sent to a different email address")
 	case errors.Is(err, errInviteInvalidDecision):
 		sendFailedReponse(w, r, http.StatusBadRequest, "Decision must be accept or reject")
+	case errors.Is(err, errInviteExpired):
+		sendFailedReponse(w, r, http.StatusGone, "This invitation has expired")
 	case err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE]:
 		sendFailedReponse(w, r, http.StatusBadGateway, err.Error())
 	default:
@@ -46,6 +50,31 @@ func sendInviteActionError(w http.ResponseWriter, r *http.Request, err error) {
 	}
 }
 
+func recordInviteAudit(r *http.Request, action string, email string, entity string, entityId string, after interface{}) {
+	entry := audit.Entry{
+		Action:     action,
+		TargetType: "invite",
+		TargetId:   email,
+		After:      after,
+	}
+	if entity == "space" {
+		if spaceId, err := uuid.Parse(entityId); err == nil {
+			entry.SpaceId = &spaceId
+		}
+	}
+	audit.Record(r, entry)
+}
+
+// inviteTarget is what an answered invite was for, so its audit entry lands
+// in the right space. It is empty when the invite can't be found.
+func inviteTarget(email string, token string) (string, string) {
+	details, err := getInviteDetailsForUser(email, token)
+	if err != nil {
+		return "", ""
+	}
+	return details.Entity, details.EntityId
+}
+
 func acceptInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user, err := core.GetUserInfo(ctx)
@@ -65,11 +94,13 @@ func acceptInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// process token
-	err = processInvitation(userId, emailId, token, STATUS_ACCEPTED)
+	err = processInvitation(r.Context(), userId, emailId, token, STATUS_ACCEPTED)
 	if err != nil {
 		sendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
+	entity, entityId := inviteTarget(emailId, token)
+	recordInviteAudit(r, audit.ActionInviteAccepted, emailId, entity, entityId, map[string]string{"status": STATUS_ACCEPTED})
 	sendSuccessResponse(w, r, http.StatusOK, "")
 }
 
@@ -111,25 +142,182 @@ func createInvitation(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	// validate sender permissions
-	isAllowed := core.ValidateUserEntityPermission(invite.Entity, invite.EntityId, invite.SenderId, core.SPACE_INVITE_MEMBER)
+	isAllowed := core.ValidateUserEntityPermission(r.Context(), invite.Entity, invite.EntityId, invite.SenderId, core.SPACE_INVITE_MEMBER)
 	if !isAllowed {
 		sendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	token, err := invite.invite()
+	token, err := invite.invite(r.Context())
 	if err != nil {
 		core.SendFailedReponse(w, r, 0, err.Error())
 		return
 	}
-	if err := invite.enqueueSpaceInviteCreatedEmail(ctx, token, user); err != nil {
-		logger().Error("failed to enqueue space invite email",
+	inviteCreated(r, invite, token, user)
+	sendSuccessResponse(w, r, http.StatusOK, token)
+}
+
+// inviteCreated records a new invite and emails it to the invitee.
+func inviteCreated(r *http.Request, invite Invite, token string, user core.UserInfo) {
+	recordInviteAudit(r, audit.ActionInviteCreated, invite.Email, invite.Entity, invite.EntityId, map[string]string{"role": invite.Role})
+	if invite.Entity == "space" {
+		activity.Record(activity.Event{
+			SpaceId:   uuid.MustParse(invite.EntityId),
+			ActorId:   invite.SenderId,
+			ActorName: user.Name,
+			Type:      activity.InviteSent,
+			Data:      map[string]interface{}{"email": invite.Email, "role": invite.Role},
+		})
+	}
+	if err := invite.notifySpaceInviteCreated(r.Context(), token, user); err != nil {
+		logger().Error("failed to notify space invite",
 			zap.String("entity", invite.Entity),
 			zap.String("entity_id", invite.EntityId),
 			zap.String("email", invite.Email),
 			zap.Error(err),
 		)
 	}
-	sendSuccessResponse(w, r, http.StatusOK, token)
+}
+
+// spaceInviteAccess checks that the caller may manage invites of the space
+// in the URL, writing the failure response when they can't.
+func spaceInviteAccess(w http.ResponseWriter, r *http.Request) (core.UserInfo, uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return user, uuid.Nil, false
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return user, uuid.Nil, false
+	}
+	if err := core.ValidateSpaceMutable(spaceID); err != nil {
+		if err.Error() == "space is archived" {
+			core.SendFailedReponse(w, r, http.StatusForbidden, "This space is archived and read-only")
+		} else {
+			core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		}
+		return user, uuid.Nil, false
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, uuid.MustParse(user.AId), core.SPACE_INVITE_MEMBER) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return user, uuid.Nil, false
+	}
+	return user, spaceID, true
+}
+
+func resendInvitation(w http.ResponseWriter, r *http.Request) {
+	_, spaceID, ok := spaceInviteAccess(w, r)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateSpaceInviteRequest(data)
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	pending, err := resendSpaceInvite(r.Context(), spaceID, req.Email)
+	if err != nil {
+		sendInviteActionError(w, r, err)
+		return
+	}
+	if err := enqueuePendingInviteEmail(r.Context(), pending, false); err != nil {
+		logger().Error("failed to enqueue resent space invite email",
+			zap.String("entity_id", pending.EntityId),
+			zap.String("email", pending.Email),
+			zap.Error(err),
+		)
+	}
+	recordInviteAudit(r, audit.ActionInviteResent, pending.Email, pending.Entity, pending.EntityId, map[string]interface{}{"inviteExpiresAt": pending.ExpiresAt})
+	sendSuccessResponse(w, r, http.StatusOK, map[string]interface{}{"email": pending.Email, "inviteExpiresAt": pending.ExpiresAt})
+}
+
+// revokeInvitation withdraws the pending invite of an address to the space,
+// whichever admin sent it.
+func revokeInvitation(w http.ResponseWriter, r *http.Request) {
+	_, spaceID, ok := spaceInviteAccess(w, r)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateSpaceInviteRequest(data)
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	revoked, err := RevokeSpaceInvites(r.Context(), spaceID, req.Email)
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if revoked == 0 {
+		sendInviteActionError(w, r, errInviteNotFound)
+		return
+	}
+	recordInviteAudit(r, audit.ActionInviteRevoked, req.Email, "space", spaceID.String(), nil)
+	sendSuccessResponse(w, r, http.StatusOK, "")
+}
+
+// bulkInvitation invites every row of a CSV to the space and reports each
+// row's outcome; one bad row doesn't stop the rest.
+func bulkInvitation(w http.ResponseWriter, r *http.Request) {
+	user, spaceID, ok := spaceInviteAccess(w, r)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bulkInviteMaxBytes))
+	defer r.Body.Close()
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusRequestEntityTooLarge, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	rows, err := parseBulkInvites(data)
+	if err != nil {
+		sendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	report := BulkInviteReport{Results: make([]BulkInviteResult, 0, len(rows))}
+	for _, row := range rows {
+		result := BulkInviteResult{Row: row.Row, Email: row.Email, Role: row.Role, Status: "failed", Error: row.Error}
+		if row.Error == "" {
+			invite := Invite{
+				Entity:    "space",
+				EntityId:  spaceID.String(),
+				Email:     row.Email,
+				Role:      row.Role,
+				SenderId:  uuid.MustParse(user.AId),
+				ExpiresAt: row.ExpiresAt,
+			}
+			token, err := invite.invite(r.Context())
+			switch {
+			case err == nil:
+				result.Status = "invited"
+				inviteCreated(r, invite, token, user)
+			case err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT]:
+				result.Error = "already a member"
+			default:
+				result.Error = err.Error()
+			}
+		}
+		if result.Status == "invited" {
+			report.Invited++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	sendSuccessResponse(w, r, http.StatusOK, report)
 }
 
 func rejectInvitation(w http.ResponseWriter, r *http.Request) {
@@ -150,11 +338,13 @@ func rejectInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// process token
-	err = processInvitation(userId, user.Email, token, STATUS_REJECTED)
+	err = processInvitation(r.Context(), userId, user.Email, token, STATUS_REJECTED)
 	if err != nil {
 		sendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	entity, entityId := inviteTarget(user.Email, token)
+	recordInviteAudit(r, audit.ActionInviteRejected, user.Email, entity, entityId, map[string]string{"status": STATUS_REJECTED})
 	sendSuccessResponse(w, r, http.StatusOK, "")
 }
 
@@ -198,11 +388,16 @@ func decideInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := processInviteDecision(user.AId, user.Email, req.Token, req.Decision)
+	result, err := processInviteDecision(r.Context(), user.AId, user.Email, req.Token, req.Decision)
 	if err != nil {
 		sendInviteActionError(w, r, err)
 		return
 	}
+	action := audit.ActionInviteRejected
+	if result.Status == STATUS_ACCEPTED {
+		action = audit.ActionInviteAccepted
+	}
+	recordInviteAudit(r, action, user.Email, result.Entity, result.EntityId, map[string]string{"status": result.Status})
 	sendSuccessResponse(w, r, http.StatusOK, result)
 }
 
@@ -243,7 +438,7 @@ func removeInvitation(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	isAllowed := core.ValidateUserSpacePermissions(uuid.MustParse(invite.EntityId), userIDUUID, core.SPACE_INVITE_MEMBER)
+	isAllowed := core.ValidateUserSpacePermissions(r.Context(), uuid.MustParse(invite.EntityId), userIDUUID, core.SPACE_INVITE_MEMBER)
 	if !isAllowed {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
@@ -253,6 +448,7 @@ func removeInvitation(w http.ResponseWriter, r *http.Request) {
 		sendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	recordInviteAudit(r, audit.ActionInviteRemoved, invite.Email, invite.Entity, invite.EntityId, map[string]string{"status": STATUS_REMOVED})
 	sendSuccessResponse(w, r, http.StatusOK, "")
 }
 
@@ -271,12 +467,12 @@ func listSpaceInvites(w http.ResponseWriter, r *http.Request) {
 	userIDUUID := uuid.MustParse(userId)
 	spaceId := uuid.MustParse(chi.URLParam(r, "spaceId"))
 	// check permission allowed only for space admin or owner
-	isAllowed := core.ValidateUserSpacePermissions(spaceId, userIDUUID, core.SPACE_INVITE_MEMBER)
+	isAllowed := core.ValidateUserSpacePermissions(r.Context(), spaceId, userIDUUID, core.SPACE_INVITE_MEMBER)
 	if !isAllowed {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	data, err := getSpaceInvites(spaceId)
+	data, err := GetSpaceInvites(spaceId)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -314,6 +510,9 @@ func Router() *chi.Mux {
 	r.Post("/user/decision", decideInvitation)
 	r.Delete("/user/remove", removeInvitation)
 	r.Get("/space/{spaceId}/list", listSpaceInvites)
+	r.Post("/space/{spaceId}/resend", resendInvitation)
+	r.Post("/space/{spaceId}/revoke", revokeInvitation)
+	r.Post("/space/{spaceId}/bulk", bulkInvitation)
 	r.Get("/user/invites", listUserInvites)
 	return r
 }
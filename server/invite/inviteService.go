@@ -3,13 +3,16 @@ package invite
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/org"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,6 +22,7 @@ var (
 	errInviteNotFound        = errors.New("invite not found")
 	errInviteWrongAccount    = errors.New("invite belongs to another account")
 	errInviteInvalidDecision = errors.New("invalid invite decision")
+	errInviteExpired         = errors.New("invite expired")
 )
 
 func normalizeInviteStatus(status sql.NullString) *string {
@@ -68,7 +72,26 @@ func lookupSenderName(senderID uuid.UUID) string {
 	return "Someone"
 }
 
+// inviteLapsed reports whether an unanswered invite is past its expiry, which
+// the sweeper may not have marked yet.
+func inviteLapsed(expiresAt *time.Time) bool {
+	return expiresAt != nil && !expiresAt.After(time.Now())
+}
+
+// inviteRelation is the relation an invite's role is written as.
+func inviteRelation(role string) string {
+	if strings.EqualFold(strings.TrimSpace(role), "commenter") {
+		return "commentor"
+	}
+	return role
+}
+
 func inviteDetailsResponse(invite InviteDetailsDBO) InviteDetailsResponse {
+	status := normalizeInviteStatus(invite.Status)
+	if status == nil && inviteLapsed(invite.ExpiresAt) {
+		expired := strings.ToLower(STATUS_EXPIRED)
+		status = &expired
+	}
 	return InviteDetailsResponse{
 		Entity:     invite.Entity,
 		EntityId:   invite.EntityId,
@@ -77,9 +100,11 @@ func inviteDetailsResponse(invite InviteDetailsDBO) InviteDetailsResponse {
 		Name:       invite.Name,
 		Role:       invite.Role,
 		Token:      invite.Token,
-		Status:     normalizeInviteStatus(invite.Status),
+		Status:     status,
 		CreatedAt:  invite.CreatedAt,
 		UpdatedAt:  invite.UpdatedAt,
+
+		InviteExpiresAt: invite.ExpiresAt,
 	}
 }
 
@@ -124,25 +149,60 @@ func (invite InviteDBO) _rejectInvitation(userId string, emailId string, role st
 	return nil
 }
 
-func (invite InviteDBO) _acceptInvitation(userId string, emailId string, role string, token string, conn *pgxpool.Conn) error {
-	_, err := core.CreateSubjectPermissions(invite.Entity, invite.EntityId, "user", userId, role)
+func (invite InviteDBO) _acceptInvitation(ctx context.Context, userId string, emailId string, role string, token string, conn *pgxpool.Conn) error {
+	if invite.MembershipExpiresAt != nil && !invite.MembershipExpiresAt.After(time.Now()) {
+		// the membership would end before it starts
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if invite.Entity == "space" {
+		// the invitee may not belong to the space's organization yet
+		spaceCtx, orgId, err := org.WithSpaceTenant(ctx, uuid.MustParse(invite.EntityId))
+		if err != nil {
+			return err
+		}
+		if err := org.Join(ctx, orgId, uuid.MustParse(userId)); err != nil {
+			return err
+		}
+		ctx = spaceCtx
+	}
+	tx, err := conn.Begin(ctx)
 	if err != nil {
 		logger().Error(err.Error())
-		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	if err := core.QueueRelations(ctx, tx, core.RelationWrite(invite.Entity, invite.EntityId, inviteRelation(role), "user", userId)); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
 	}
-	tag, err := conn.Exec(context.Background(), UPDATE_INVITE, STATUS_ACCEPTED, token, emailId)
+	if invite.Entity == "space" {
+		if invite.MembershipExpiresAt != nil {
+			_, err = tx.Exec(ctx, UPSERT_SPACE_MEMBER_EXPIRY, invite.EntityId, userId, invite.MembershipExpiresAt, invite.SenderId)
+		} else {
+			_, err = tx.Exec(ctx, DELETE_SPACE_MEMBER_EXPIRY, invite.EntityId, userId)
+		}
+		if err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+	}
+	tag, err := tx.Exec(ctx, UPDATE_INVITE, STATUS_ACCEPTED, token, emailId)
 	if err != nil {
 		logger().Error(err.Error())
 		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
 	rowsAffected := tag.RowsAffected()
 	logger().Info(fmt.Sprintf("Updated rows %v", rowsAffected))
 	return nil
 }
 
-func processInvitation(userId string, emailId string, token string, decision string) error {
+func processInvitation(ctx context.Context, userId string, emailId string, token string, decision string) error {
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error(err.Error())
@@ -166,12 +226,22 @@ func processInvitation(userId string, emailId string, token string, decision str
 	}
 	if invite.Status.Valid {
 		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	} else if inviteLapsed(invite.ExpiresAt) && decision != STATUS_REMOVED {
+		return errInviteExpired
 	} else {
 		switch decision {
 		case STATUS_ACCEPTED:
-			return invite._acceptInvitation(userId, emailId, invite.Role, token, conn)
+			if err := invite._acceptInvitation(ctx, userId, emailId, invite.Role, token, conn); err != nil {
+				return err
+			}
+			invite.inviteDecided(ctx, token, userId, emailId, decision)
+			return nil
 		case STATUS_REJECTED:
-			return invite._rejectInvitation(userId, emailId, invite.Role, token, conn)
+			if err := invite._rejectInvitation(userId, emailId, invite.Role, token, conn); err != nil {
+				return err
+			}
+			invite.inviteDecided(ctx, token, userId, emailId, decision)
+			return nil
 		case STATUS_REMOVED:
 			return invite._removeInvitation(userId, token, conn)
 		}
@@ -219,7 +289,7 @@ func getInviteDetailsForUser(email string, token string) (InviteDetailsResponse,
 	return inviteDetailsResponse(invite), nil
 }
 
-func processInviteDecision(userId string, emailId string, token string, decision string) (InviteDecisionResponse, error) {
+func processInviteDecision(ctx context.Context, userId string, emailId string, token string, decision string) (InviteDecisionResponse, error) {
 	status, err := inviteDecisionToStatus(decision)
 	if err != nil {
 		return InviteDecisionResponse{}, err
@@ -229,6 +299,9 @@ func processInviteDecision(userId string, emailId string, token string, decision
 	if err != nil {
 		return InviteDecisionResponse{}, err
 	}
+	if details.Status != nil && *details.Status == strings.ToLower(STATUS_EXPIRED) {
+		return InviteDecisionResponse{}, errInviteExpired
+	}
 	if details.Status != nil {
 		return InviteDecisionResponse{
 			Status:   *details.Status,
@@ -237,7 +310,7 @@ func processInviteDecision(userId string, emailId string, token string, decision
 		}, nil
 	}
 
-	if err := processInvitation(userId, emailId, token, status); err != nil {
+	if err := processInvitation(ctx, userId, emailId, token, status); err != nil {
 		return InviteDecisionResponse{}, err
 	}
 
@@ -268,7 +341,13 @@ func (i Invite) removeInvitation() error {
 	return nil
 }
 
-func (i *Invite) invite() (string, error) {
+func (i *Invite) invite(ctx context.Context) (string, error) {
+	if i.InviteExpiresAt == nil {
+		if expiresIn := LoadConfig().ExpiresIn; expiresIn > 0 {
+			expiresAt := time.Now().Add(expiresIn)
+			i.InviteExpiresAt = &expiresAt
+		}
+	}
 	token := i.token()
 	if token == "" {
 		logger().Error("unable to create token")
@@ -289,7 +368,7 @@ func (i *Invite) invite() (string, error) {
 		}
 	}
 	if i.UserId != uuid.Nil {
-		permission, _ := core.CheckPermission(i.Entity, i.EntityId, "user", i.UserId.String(), core.PAGE_VIEW)
+		permission, _ := core.CheckPermission(ctx, i.Entity, i.EntityId, "user", i.UserId.String(), core.PAGE_VIEW)
 		if permission {
 			logger().Error("user is already a member of the space")
 			// user is already a member of the space
@@ -297,7 +376,6 @@ func (i *Invite) invite() (string, error) {
 		}
 	}
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to acquire a connection: " + err.Error())
@@ -311,6 +389,10 @@ func (i *Invite) invite() (string, error) {
 	}
 	defer tx.Rollback(ctx)
 	defer conn.Release()
+	if _, err := tx.Exec(ctx, EXPIRE_LAPSED_INVITE, i.Entity, i.EntityId, i.Email); err != nil {
+		logger().Error(err.Error())
+		return token, err
+	}
 	var exists int
 	if i.UserId != uuid.Nil {
 		err = conn.QueryRow(ctx, CHECK_PENDING_INVITE_EXISTS_BY_USER_QUERY, i.Entity, i.EntityId, i.UserId).Scan(&exists)
@@ -331,7 +413,7 @@ func (i *Invite) invite() (string, error) {
 		return token, err
 	}
 	// create entry in the database
-	tag, err := tx.Exec(ctx, CREATE_INVITE, i.SenderId, token, i.UserId, i.Entity, i.EntityId, i.Email, i.Role)
+	tag, err := tx.Exec(ctx, CREATE_INVITE, i.SenderId, token, i.UserId, i.Entity, i.EntityId, i.Email, i.Role, i.ExpiresAt, i.InviteExpiresAt)
 	if err != nil {
 		logger().Error(err.Error())
 		return token, err
@@ -347,9 +429,14 @@ func (i *Invite) invite() (string, error) {
 }
 
 func (i Invite) token() string {
+	// the nonce gives an address invited again after an expiry a new link
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return ""
+	}
 	str := i.Entity + i.EntityId + i.Email + i.SenderId.String() + i.Role
 	h := md5.New()
-	_, err := h.Write([]byte(str))
+	_, err := h.Write(append([]byte(str), nonce...))
 	if err != nil {
 		return ""
 	}
@@ -357,7 +444,8 @@ func (i Invite) token() string {
 	return hex.EncodeToString(hashValue)
 }
 
-func getSpaceInvites(spaceId uuid.UUID) ([]InviteDBOV3, error) {
+// GetSpaceInvites lists the pending invites of a space, newest first.
+func GetSpaceInvites(spaceId uuid.UUID) ([]InviteDBOV3, error) {
 	connPool := core.GetPool()
 	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
@@ -380,6 +468,41 @@ func getSpaceInvites(spaceId uuid.UUID) ([]InviteDBOV3, error) {
 	return invites, nil
 }
 
+// resendSpaceInvite restarts the pending invite of email to the space: it is
+// sent again, its reminder rearmed and its expiry pushed out.
+func resendSpaceInvite(ctx context.Context, spaceId uuid.UUID, email string) (PendingInvite, error) {
+	var expiresAt *time.Time
+	if expiresIn := LoadConfig().ExpiresIn; expiresIn > 0 {
+		until := time.Now().Add(expiresIn)
+		expiresAt = &until
+	}
+	rows, err := core.GetPool().Query(ctx, RESEND_SPACE_INVITE, spaceId.String(), email, expiresAt)
+	if err != nil {
+		logger().Error(err.Error())
+		return PendingInvite{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	pending, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[PendingInvite])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return PendingInvite{}, errInviteNotFound
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return PendingInvite{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return pending, nil
+}
+
+// RevokeSpaceInvites removes every pending invite of email to the space,
+// whoever sent it, and returns how many there were.
+func RevokeSpaceInvites(ctx context.Context, spaceId uuid.UUID, email string) (int64, error) {
+	tag, err := core.GetPool().Exec(ctx, REMOVE_PENDING_SPACE_INVITES, spaceId.String(), email)
+	if err != nil {
+		logger().Error(err.Error())
+		return 0, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	return tag.RowsAffected(), nil
+}
+
 func getUserInvites(userId string, email string) (UserInvites, error) {
 	var userInvites UserInvites
 	if email == "" {
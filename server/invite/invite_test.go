@@ -17,3 +17,55 @@ func TestHashFunction(t *testing.T) {
 		t.Error("couldn't generate token")
 	}
 }
+
+func TestTokensAreUnique(t *testing.T) {
+	i := Invite{Entity: "space", EntityId: "1", Email: "a@example.com", SenderId: uuid.New(), Role: "viewer"}
+	if i.token() == i.token() {
+		t.Error("expected a new token for every invite")
+	}
+}
+
+func TestParseBulkInvites(t *testing.T) {
+	csv := "email,role,expiresAt\n" +
+		"ada@example.com,Editor,\n" +
+		"grace@example.com\n" +
+		"\n" +
+		"not an email,viewer\n" +
+		"ADA@example.com,viewer\n" +
+		"alan@example.com,owner\n" +
+		"linus@example.com,commentor,2001-01-01\n"
+	rows, err := parseBulkInvites([]byte(csv))
+	if err != nil {
+		t.Fatalf("expected the file to parse, got %v", err)
+	}
+	want := []struct {
+		email, role, err string
+	}{
+		{"ada@example.com", "editor", ""},
+		{"grace@example.com", "viewer", ""},
+		{"not an email", "viewer", "invalid email"},
+		{"ADA@example.com", "viewer", "duplicate email"},
+		{"alan@example.com", "owner", "invalid role"},
+		{"linus@example.com", "commenter", "invalid expiry"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), rows)
+	}
+	for n, row := range rows {
+		if row.Email != want[n].email || row.Role != want[n].role || row.Error != want[n].err {
+			t.Errorf("row %d: got %+v, want %+v", n, row, want[n])
+		}
+	}
+	if rows[2].Row != 5 {
+		t.Errorf("expected rows numbered as in the file, got %d", rows[2].Row)
+	}
+	if _, err := parseBulkInvites([]byte("email,role\n")); err == nil {
+		t.Error("expected a file without rows to be rejected")
+	}
+}
+
+func TestInviteRelation(t *testing.T) {
+	if inviteRelation("commenter") != "commentor" || inviteRelation("editor") != "editor" {
+		t.Error("expected commenter invites to be written as commentor")
+	}
+}
@@ -2,42 +2,60 @@ package invite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 
+	"github.com/durgakiran/beskar/activity"
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
 	"github.com/durgakiran/beskar/notification"
+	"github.com/durgakiran/beskar/org"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
 )
 
 const (
-	spaceInviteEmailCategory = "space_invite"
+	spaceInviteEmailCategory = notification.CategorySpaceInvite
 	spaceInviteActionPath    = "/invite/action"
 )
 
-func (i Invite) enqueueSpaceInviteCreatedEmail(ctx context.Context, token string, sender core.UserInfo) error {
+// notifySpaceInviteCreated tells the invitee about a new space invite, in
+// their inbox when they already have an account and by email.
+func (i Invite) notifySpaceInviteCreated(ctx context.Context, token string, sender core.UserInfo) error {
 	if i.Entity != "space" {
 		return nil
 	}
 
-	config := notification.LoadConfig()
-	if !config.NotificationsEnabled {
-		return nil
-	}
-
 	spaceName, err := getSpaceNameForInviteEmail(ctx, i.EntityId)
 	if err != nil {
 		return err
 	}
 
-	req, err := buildSpaceInviteCreatedEmailRequest(config, i, token, spaceName, sender)
+	req, err := buildSpaceInviteCreatedEmailRequest(notification.LoadConfig(), i, token, spaceName, sender)
 	if err != nil {
 		return err
 	}
 
-	_, err = notification.NewService().EnqueueEmail(ctx, req)
-	return err
+	spaceId, err := uuid.Parse(i.EntityId)
+	if err != nil {
+		return err
+	}
+	senderId := i.SenderId
+	return inbox.Notify(ctx, inbox.Notification{
+		UserId:    i.UserId,
+		Category:  spaceInviteEmailCategory,
+		Type:      inbox.InviteReceived,
+		Title:     fmt.Sprintf("%s invited you to %s", req.TemplateData["sender_name"], spaceName),
+		Url:       spaceInviteActionPath + "?token=" + url.QueryEscape(token),
+		SpaceId:   &spaceId,
+		ActorId:   &senderId,
+		ActorName: sender.Name,
+		Data:      map[string]interface{}{"role": i.Role},
+		Email:     &req,
+	})
 }
 
 func buildSpaceInviteCreatedEmailRequest(config notification.Config, i Invite, token string, spaceName string, sender core.UserInfo) (notification.EnqueueEmailRequest, error) {
@@ -110,3 +128,339 @@ func getSpaceNameForInviteEmail(ctx context.Context, spaceID string) (string, er
 	}
 	return name, nil
 }
+
+// enqueuePendingInviteEmail sends a pending invite again, as the original
+// invite or, with reminder set, as a reminder. It is worded as coming from
+// whoever sent the invite.
+func enqueuePendingInviteEmail(ctx context.Context, pending PendingInvite, reminder bool) error {
+	if pending.Entity != "space" {
+		return nil
+	}
+
+	config := notification.LoadConfig()
+	if !config.NotificationsEnabled {
+		return nil
+	}
+
+	spaceName, err := getSpaceNameForInviteEmail(ctx, pending.EntityId)
+	if err != nil {
+		return err
+	}
+
+	build := buildSpaceInviteResentEmailRequest
+	if reminder {
+		build = buildSpaceInviteReminderEmailRequest
+	}
+	req, err := build(config, pending, spaceName, lookupSenderName(pending.SenderId))
+	if err != nil {
+		return err
+	}
+	if spaceId, err := uuid.Parse(pending.EntityId); err == nil {
+		req.SpaceID = &spaceId
+	}
+
+	_, err = notification.NewService().EnqueueEmail(ctx, req)
+	return err
+}
+
+func (p PendingInvite) asInvite() Invite {
+	i := Invite{Entity: p.Entity, EntityId: p.EntityId, Email: p.Email, Role: p.Role, SenderId: p.SenderId}
+	if p.UserId != nil {
+		i.UserId = *p.UserId
+	}
+	return i
+}
+
+// buildSpaceInviteResentEmailRequest is the invite email again, keyed by when
+// it was resent so it isn't taken for the first one.
+func buildSpaceInviteResentEmailRequest(config notification.Config, pending PendingInvite, spaceName string, senderName string) (notification.EnqueueEmailRequest, error) {
+	req, err := buildSpaceInviteCreatedEmailRequest(config, pending.asInvite(), pending.Token, spaceName, core.UserInfo{Name: senderName})
+	if err != nil {
+		return req, err
+	}
+	req.MessageKey = fmt.Sprintf("%s:%s:%d", notification.TemplateSpaceInviteCreated, pending.Token, pending.LastSentAt.UnixMilli())
+	return req, nil
+}
+
+func buildSpaceInviteReminderEmailRequest(config notification.Config, pending PendingInvite, spaceName string, senderName string) (notification.EnqueueEmailRequest, error) {
+	req, err := buildSpaceInviteCreatedEmailRequest(config, pending.asInvite(), pending.Token, spaceName, core.UserInfo{Name: senderName})
+	if err != nil {
+		return req, err
+	}
+	req.MessageKey = fmt.Sprintf("%s:%s:%d", notification.TemplateSpaceInviteReminder, pending.Token, pending.LastSentAt.UnixMilli())
+	req.TemplateKey = notification.TemplateSpaceInviteReminder
+	if pending.ExpiresAt != nil {
+		req.TemplateData["expires_at"] = pending.ExpiresAt.UTC().Format("2 Jan 2006 15:04 MST")
+	}
+	return req, nil
+}
+
+const (
+	inviteDecisionEmailCategory = notification.CategoryInviteDecision
+	spaceWelcomePageLimit       = 5
+)
+
+// inviteDecided records an answered space invite in the space's activity and
+// notifies the sender, and the space's admins when configured. An invitee
+// who accepted is emailed a welcome.
+func (invite InviteDBO) inviteDecided(ctx context.Context, token string, userId string, email string, status string) {
+	if invite.Entity != "space" {
+		return
+	}
+	spaceId, err := uuid.Parse(invite.EntityId)
+	if err != nil {
+		return
+	}
+	inviteeId := uuid.MustParse(userId)
+	profiles, err := core.GetUserProfiles([]string{userId, invite.SenderId.String()})
+	if err != nil {
+		logger().Error(err.Error())
+	}
+	inviteeName := strings.TrimSpace(profiles[userId].Name)
+	if inviteeName == "" {
+		inviteeName = email
+	}
+	eventType := activity.InviteDeclined
+	if status == STATUS_ACCEPTED {
+		eventType = activity.InviteAccepted
+	}
+	activity.Record(activity.Event{
+		SpaceId:   spaceId,
+		ActorId:   inviteeId,
+		ActorName: inviteeName,
+		Type:      eventType,
+		Data:      map[string]interface{}{"email": email, "role": invite.Role, "senderId": invite.SenderId.String()},
+	})
+	if err := notifyInviteDecision(ctx, invite, spaceId, token, inviteeId, inviteeName, email, status, profiles); err != nil {
+		logger().Error("failed to notify invite decision",
+			zap.String("entity_id", invite.EntityId),
+			zap.String("email", email),
+			zap.Error(err),
+		)
+	}
+}
+
+func notifyInviteDecision(ctx context.Context, invite InviteDBO, spaceId uuid.UUID, token string, inviteeId uuid.UUID, inviteeName string, email string, status string, profiles map[string]core.UserProfile) error {
+	config := notification.LoadConfig()
+	spaceName, err := getSpaceNameForInviteEmail(ctx, invite.EntityId)
+	if err != nil {
+		return err
+	}
+	service := notification.NewService()
+	decision := inviteDecision{
+		SpaceId:     spaceId,
+		SpaceName:   spaceName,
+		Token:       token,
+		InviteeName: inviteeName,
+		Accepted:    status == STATUS_ACCEPTED,
+		Role:        invite.Role,
+		SenderName:  strings.TrimSpace(profiles[invite.SenderId.String()].Name),
+	}
+
+	recipients := map[uuid.UUID]bool{invite.SenderId: false}
+	if LoadConfig().NotifyAdmins {
+		admins, err := spaceAdmins(ctx, spaceId)
+		if err != nil {
+			return err
+		}
+		missing := make([]string, 0, len(admins))
+		for _, admin := range admins {
+			if _, ok := recipients[admin]; !ok {
+				recipients[admin] = true
+				missing = append(missing, admin.String())
+			}
+		}
+		adminProfiles, err := core.GetUserProfiles(missing)
+		if err != nil {
+			return err
+		}
+		for id, profile := range adminProfiles {
+			profiles[id] = profile
+		}
+	}
+	for recipientId, forAdmin := range recipients {
+		if recipientId == inviteeId {
+			continue
+		}
+		n := inviteDecidedNotification(decision, inviteeId, recipientId, forAdmin)
+		if profile := profiles[recipientId.String()]; strings.TrimSpace(profile.Email) != "" {
+			req, err := buildInviteDecidedEmailRequest(config, decision, recipientId, profile, forAdmin)
+			if err != nil {
+				return err
+			}
+			n.Email = &req
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	if !decision.Accepted || !config.NotificationsEnabled {
+		return nil
+	}
+	pages, err := welcomePages(ctx, spaceId, inviteeId)
+	if err != nil {
+		return err
+	}
+	req, err := buildSpaceWelcomeEmailRequest(config, decision, inviteeId, email, pages)
+	if err != nil {
+		return err
+	}
+	_, err = service.EnqueueEmail(ctx, req)
+	return err
+}
+
+// inviteDecision is what the decision and welcome emails say about an
+// answered invite.
+type inviteDecision struct {
+	SpaceId     uuid.UUID
+	SpaceName   string
+	Token       string
+	InviteeName string
+	Accepted    bool
+	Role        string
+	SenderName  string
+}
+
+func inviteDecidedNotification(decision inviteDecision, inviteeId uuid.UUID, recipientId uuid.UUID, forAdmin bool) inbox.Notification {
+	eventType, verb := inbox.InviteDeclined, "declined"
+	if decision.Accepted {
+		eventType, verb = inbox.InviteAccepted, "accepted"
+	}
+	whose := "your"
+	if forAdmin {
+		whose = "an"
+	}
+	spaceId := decision.SpaceId
+	return inbox.Notification{
+		UserId:    recipientId,
+		Category:  inviteDecisionEmailCategory,
+		Type:      eventType,
+		Title:     fmt.Sprintf("%s %s %s invite to %s", decision.InviteeName, verb, whose, decision.SpaceName),
+		Url:       fmt.Sprintf("/space/%s/settings/users", decision.SpaceId),
+		SpaceId:   &spaceId,
+		ActorId:   &inviteeId,
+		ActorName: decision.InviteeName,
+		Data:      map[string]interface{}{"role": decision.Role},
+	}
+}
+
+func buildInviteDecidedEmailRequest(config notification.Config, decision inviteDecision, recipientId uuid.UUID, recipient core.UserProfile, forAdmin bool) (notification.EnqueueEmailRequest, error) {
+	if strings.TrimSpace(decision.Token) == "" {
+		return notification.EnqueueEmailRequest{}, fmt.Errorf("invite token is required")
+	}
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	data := map[string]any{
+		"space_name":   decision.SpaceName,
+		"invitee_name": decision.InviteeName,
+		"decision":     "declined",
+		"members_url":  fmt.Sprintf("%s/space/%s/settings/users", appURL, decision.SpaceId),
+		"app_url":      appURL + "/",
+		"for_admin":    forAdmin,
+	}
+	if decision.Accepted {
+		data["decision"] = "accepted"
+		data["role"] = decision.Role
+	}
+	if decision.SenderName != "" {
+		data["sender_name"] = decision.SenderName
+	}
+	return notification.EnqueueEmailRequest{
+		MessageKey:  fmt.Sprintf("%s:%s:%s", notification.TemplateSpaceInviteDecided, decision.Token, recipientId),
+		Category:    inviteDecisionEmailCategory,
+		TemplateKey: notification.TemplateSpaceInviteDecided,
+		Recipient: notification.EmailRecipient{
+			UserID: &recipientId,
+			Email:  recipient.Email,
+			Name:   recipient.Name,
+		},
+		TemplateData: data,
+		Priority:     notification.PriorityNormal,
+		SpaceID:      &decision.SpaceId,
+	}, nil
+}
+
+func buildSpaceWelcomeEmailRequest(config notification.Config, decision inviteDecision, inviteeId uuid.UUID, email string, pages []WelcomePage) (notification.EnqueueEmailRequest, error) {
+	if strings.TrimSpace(decision.Token) == "" {
+		return notification.EnqueueEmailRequest{}, fmt.Errorf("invite token is required")
+	}
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	links := make([]map[string]any, 0, len(pages))
+	for _, page := range pages {
+		links = append(links, map[string]any{
+			"title": page.Title,
+			"url":   fmt.Sprintf("%s/space/%s/view/%d", appURL, decision.SpaceId, page.PageId),
+		})
+	}
+	return notification.EnqueueEmailRequest{
+		MessageKey:  fmt.Sprintf("%s:%s", notification.TemplateSpaceWelcome, decision.Token),
+		Category:    inviteDecisionEmailCategory,
+		TemplateKey: notification.TemplateSpaceWelcome,
+		Recipient: notification.EmailRecipient{
+			UserID: &inviteeId,
+			Email:  email,
+			Name:   decision.InviteeName,
+		},
+		TemplateData: map[string]any{
+			"space_name": decision.SpaceName,
+			"role":       decision.Role,
+			"space_url":  fmt.Sprintf("%s/space/%s", appURL, decision.SpaceId),
+			"app_url":    appURL + "/",
+			"pages":      links,
+		},
+		Priority: notification.PriorityNormal,
+		SpaceID:  &decision.SpaceId,
+	}, nil
+}
+
+// spaceAdmins lists the users holding the owner or admin relation on the
+// space directly; admins through a group are not included.
+func spaceAdmins(ctx context.Context, spaceId uuid.UUID) ([]uuid.UUID, error) {
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return nil, err
+	}
+	tuples, err := core.GetSubjectsAssociatedWithEntity(tenantCtx, "space", spaceId.String())
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	admins := make([]uuid.UUID, 0)
+	for _, tuple := range tuples {
+		if tuple.Subject.Type != "user" || (tuple.Relation != "owner" && tuple.Relation != "admin") {
+			continue
+		}
+		if id, err := uuid.Parse(tuple.Subject.Id); err == nil {
+			admins = append(admins, id)
+		}
+	}
+	return admins, nil
+}
+
+// welcomePages picks the pages a new member is pointed to among those they
+// can view.
+func welcomePages(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) ([]WelcomePage, error) {
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return nil, err
+	}
+	pageIds, err := core.GetEntitiesWithPermission(tenantCtx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(pageIds) == 0 {
+		return nil, nil
+	}
+	rows, err := core.GetPool().Query(ctx, GET_WELCOME_PAGES, spaceId, pageIds, spaceWelcomePageLimit)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	pages, err := pgx.CollectRows(rows, pgx.RowToStructByName[WelcomePage])
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return pages, nil
+}
@@ -1,9 +1,12 @@
 package invite
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
 	"github.com/durgakiran/beskar/notification"
 	"github.com/google/uuid"
 )
@@ -92,3 +95,103 @@ func TestBuildInviteActionURLRejectsUnknownDecision(t *testing.T) {
 		t.Fatalf("expected empty url for unknown decision, got %q", got)
 	}
 }
+
+func TestBuildSpaceInviteReminderEmailRequest(t *testing.T) {
+	expiresAt := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	pending := PendingInvite{
+		Token:      "abc123",
+		Entity:     "space",
+		EntityId:   uuid.NewString(),
+		Email:      "invitee@example.com",
+		Role:       "viewer",
+		ExpiresAt:  &expiresAt,
+		LastSentAt: time.UnixMilli(1700000000000),
+	}
+	config := notification.Config{AppBaseURL: "https://app.example.com"}
+
+	reminder, err := buildSpaceInviteReminderEmailRequest(config, pending, "Roadmap", "Kiran")
+	if err != nil {
+		t.Fatalf("expected request build to succeed: %v", err)
+	}
+	if reminder.TemplateKey != notification.TemplateSpaceInviteReminder {
+		t.Fatalf("unexpected template key: %q", reminder.TemplateKey)
+	}
+	if reminder.MessageKey != "space_invite_reminder:abc123:1700000000000" {
+		t.Fatalf("unexpected message key: %q", reminder.MessageKey)
+	}
+	if reminder.TemplateData["expires_at"] != "2 Jan 2026 15:04 UTC" {
+		t.Fatalf("unexpected expiry: %q", reminder.TemplateData["expires_at"])
+	}
+
+	resent, err := buildSpaceInviteResentEmailRequest(config, pending, "Roadmap", "Kiran")
+	if err != nil {
+		t.Fatalf("expected request build to succeed: %v", err)
+	}
+	if resent.TemplateKey != notification.TemplateSpaceInviteCreated || resent.MessageKey == "space_invite_created:abc123" {
+		t.Fatalf("expected the invite email under a new message key, got %q", resent.MessageKey)
+	}
+}
+
+func TestBuildInviteDecidedEmailRequest(t *testing.T) {
+	spaceId := uuid.New()
+	recipientId := uuid.New()
+	decision := inviteDecision{SpaceId: spaceId, SpaceName: "Roadmap", Token: "abc123", InviteeName: "Ada", Role: "editor", SenderName: "Kiran"}
+
+	req, err := buildInviteDecidedEmailRequest(notification.Config{AppBaseURL: "https://app.example.com/"}, decision, recipientId, core.UserProfile{Name: "Grace", Email: "grace@example.com"}, true)
+	if err != nil {
+		t.Fatalf("expected request build to succeed: %v", err)
+	}
+	if req.MessageKey != "space_invite_decided:abc123:"+recipientId.String() {
+		t.Fatalf("unexpected message key: %q", req.MessageKey)
+	}
+	if req.TemplateData["decision"] != "declined" || req.TemplateData["role"] != nil {
+		t.Fatalf("expected a declined invite without a role, got %v", req.TemplateData)
+	}
+	if req.TemplateData["members_url"] != "https://app.example.com/space/"+spaceId.String()+"/settings/users" {
+		t.Fatalf("unexpected members url: %q", req.TemplateData["members_url"])
+	}
+	if _, err := notification.NewTemplateRegistry().Render(req.TemplateKey, req.TemplateData); err != nil {
+		t.Fatalf("expected the email to render: %v", err)
+	}
+}
+
+func TestInviteDecidedNotification(t *testing.T) {
+	inviteeId := uuid.New()
+	recipientId := uuid.New()
+	decision := inviteDecision{SpaceId: uuid.New(), SpaceName: "Roadmap", InviteeName: "Ada", Accepted: true, Role: "editor"}
+
+	n := inviteDecidedNotification(decision, inviteeId, recipientId, false)
+	if n.Type != inbox.InviteAccepted || n.Title != "Ada accepted your invite to Roadmap" {
+		t.Fatalf("unexpected notification for the sender: %+v", n)
+	}
+	if n.UserId != recipientId || n.ActorId == nil || *n.ActorId != inviteeId {
+		t.Fatalf("expected the notification to go to the recipient from the invitee: %+v", n)
+	}
+
+	decision.Accepted = false
+	n = inviteDecidedNotification(decision, inviteeId, recipientId, true)
+	if n.Type != inbox.InviteDeclined || n.Title != "Ada declined an invite to Roadmap" {
+		t.Fatalf("unexpected notification for an admin: %+v", n)
+	}
+}
+
+func TestBuildSpaceWelcomeEmailRequest(t *testing.T) {
+	spaceId := uuid.New()
+	inviteeId := uuid.New()
+	decision := inviteDecision{SpaceId: spaceId, SpaceName: "Roadmap", Token: "abc123", InviteeName: "Ada", Accepted: true, Role: "viewer"}
+
+	req, err := buildSpaceWelcomeEmailRequest(notification.Config{AppBaseURL: "https://app.example.com"}, decision, inviteeId, "ada@example.com", []WelcomePage{{PageId: 7, Title: "Start here"}})
+	if err != nil {
+		t.Fatalf("expected request build to succeed: %v", err)
+	}
+	if req.MessageKey != "space_welcome:abc123" || *req.Recipient.UserID != inviteeId {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	rendered, err := notification.NewTemplateRegistry().Render(req.TemplateKey, req.TemplateData)
+	if err != nil {
+		t.Fatalf("expected the email to render: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "Start here: https://app.example.com/space/"+spaceId.String()+"/view/7") {
+		t.Fatalf("expected the page link in the text body: %s", rendered.Text)
+	}
+}
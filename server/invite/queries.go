@@ -1,15 +1,18 @@
 package invite
 
 const (
-	CREATE_INVITE                             = "INSERT INTO notifications.invites( sender_id, token, user_id, entity, entity_id, email_id, role, created_at, updated_at ) VALUES ( $1, $2, $3, $4, $5, $6, $7, now(), now() )"
-	GET_TOKEN_STATUS                          = "SELECT status, sender_id, entity, entity_id, role  FROM notifications.invites WHERE lower(email_id) = lower($1) AND token = $2 ORDER BY created_at DESC LIMIT 1"
+	CREATE_INVITE                             = "INSERT INTO notifications.invites( sender_id, token, user_id, entity, entity_id, email_id, role, membership_expires_at, expires_at, created_at, updated_at, last_sent_at ) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now(), now() )"
+	GET_TOKEN_STATUS                          = "SELECT status, sender_id, entity, entity_id, role, membership_expires_at, expires_at FROM notifications.invites WHERE lower(email_id) = lower($1) AND token = $2 ORDER BY created_at DESC LIMIT 1"
 	GET_TOKEN_STATUS_BY_SENDER                = "SELECT status, entity, user_id, entity_id  FROM notifications.invites WHERE sender_id = $1 AND token = $2"
 	UPDATE_INVITE                             = "UPDATE notifications.invites SET status = $1, updated_at = now() WHERE token = $2 and lower(email_id) = lower($3) AND status IS NULL"
 	UPDATE_INVITE_BY_SENDER                   = "UPDATE notifications.invites SET status = $1, updated_at = now() WHERE token = $2 and sender_id = $3"
-	GET_INVITES_QUERY                         = "SELECT sender_id, entity, entity_id, email_id, role, status, created_at, updated_at FROM notifications.invites WHERE entity_id = $1 AND status IS NULL ORDER BY created_at DESC"
+	GET_INVITES_QUERY                         = "SELECT sender_id, entity, entity_id, email_id, role, status, membership_expires_at, expires_at, last_sent_at, created_at, updated_at FROM notifications.invites WHERE entity_id = $1 AND status IS NULL AND (expires_at IS NULL OR expires_at > now()) ORDER BY created_at DESC"
 	REMOVE_INVITATION                         = "DELETE FROM notifications.invites WHERE sender_id = $1 AND email_id = $2 AND entity_id = $3 AND role = $4"
-	CHECK_PENDING_INVITE_EXISTS_QUERY         = "SELECT 1 FROM notifications.invites WHERE entity = $1 AND entity_id = $2 AND email_id = $3 AND status IS NULL LIMIT 1"
-	CHECK_PENDING_INVITE_EXISTS_BY_USER_QUERY = "SELECT 1 FROM notifications.invites WHERE entity = $1 AND entity_id = $2 AND user_id = $3 AND status IS NULL LIMIT 1"
+	REMOVE_PENDING_SPACE_INVITES              = "DELETE FROM notifications.invites WHERE entity = 'space' AND entity_id = $1 AND lower(email_id) = lower($2) AND status IS NULL"
+	UPSERT_SPACE_MEMBER_EXPIRY                = "INSERT INTO core.space_member_expiries (space_id, user_id, expires_at, created_by) VALUES ($1, $2, $3, $4) ON CONFLICT (space_id, user_id) DO UPDATE SET expires_at = EXCLUDED.expires_at, created_by = EXCLUDED.created_by, created_at = now(), notified_at = NULL"
+	DELETE_SPACE_MEMBER_EXPIRY                = "DELETE FROM core.space_member_expiries WHERE space_id = $1 AND user_id = $2"
+	CHECK_PENDING_INVITE_EXISTS_QUERY         = "SELECT 1 FROM notifications.invites WHERE entity = $1 AND entity_id = $2 AND email_id = $3 AND status IS NULL AND (expires_at IS NULL OR expires_at > now()) LIMIT 1"
+	CHECK_PENDING_INVITE_EXISTS_BY_USER_QUERY = "SELECT 1 FROM notifications.invites WHERE entity = $1 AND entity_id = $2 AND user_id = $3 AND status IS NULL AND (expires_at IS NULL OR expires_at > now()) LIMIT 1"
 	GET_INVITE_DETAILS_BY_TOKEN_QUERY         = `SELECT
 										i.sender_id AS sender_id,
 										i.entity AS entity,
@@ -20,7 +23,8 @@ const (
 										i.token AS token,
 										COALESCE(s.name, '') AS name,
 										i.created_at AS created_at,
-										i.updated_at AS updated_at
+										i.updated_at AS updated_at,
+										i.expires_at AS expires_at
 									FROM
 										notifications.invites i LEFT JOIN core.space s ON (i.entity = 'space' AND i.entity_id = s.id::varchar)
 									WHERE
@@ -34,6 +38,8 @@ const (
 										i.email_id AS email_id, 
 										i.role AS role, 
 										i.status AS status,
+										i.membership_expires_at AS membership_expires_at,
+										i.expires_at AS expires_at,
 										i.token AS token,
 										COALESCE(s.name, '') AS name,
 										i.created_at AS created_at,
@@ -41,6 +47,47 @@ const (
 									FROM 
 										notifications.invites i LEFT JOIN core.space s ON (i.entity = 'space' AND i.entity_id = s.id::varchar)
 									WHERE 
-										lower(i.email_id) = lower($1) AND i.status IS NULL
+										lower(i.email_id) = lower($1) AND i.status IS NULL AND (i.expires_at IS NULL OR i.expires_at > now())
 									ORDER BY i.created_at DESC`
+
+	// the pages a new member is pointed to: the most read of the last month,
+	// then top-level pages
+	GET_WELCOME_PAGES = `SELECT p.id, t.title
+							FROM core.page p
+								INNER JOIN LATERAL (
+									SELECT d.title FROM core.page_doc_map d WHERE d.page_id = p.id ORDER BY d.version DESC LIMIT 1
+								) t ON (COALESCE(t.title, '') <> '')
+								LEFT JOIN (
+									SELECT page_id, COUNT(*) AS views FROM core.page_views
+									WHERE space_id = $1 AND viewed_at >= now() - interval '30 days' GROUP BY page_id
+								) v ON (v.page_id = p.id)
+							WHERE p.space_id = $1 AND p.id::text = ANY($2)
+							ORDER BY COALESCE(v.views, 0) DESC, p.parent_id IS NOT NULL, p.id
+							LIMIT $3`
+	PENDING_INVITE_COLUMNS = "sender_id, token, user_id, entity, entity_id, email_id, role, expires_at, last_sent_at"
+	// an expired invite still holds the pending slot until it is marked, so a
+	// new invite to the same address marks it first
+	EXPIRE_LAPSED_INVITE = "UPDATE notifications.invites SET status = 'EXPIRED', updated_at = now() WHERE entity = $1 AND entity_id = $2 AND lower(email_id) = lower($3) AND status IS NULL AND expires_at <= now()"
+	EXPIRE_DUE_INVITES   = `UPDATE notifications.invites SET status = 'EXPIRED', updated_at = now()
+							WHERE (entity, entity_id, email_id) IN (
+								SELECT entity, entity_id, email_id FROM notifications.invites
+								WHERE status IS NULL AND expires_at <= now()
+								ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED
+							) AND status IS NULL
+							RETURNING entity, entity_id, email_id`
+	CLAIM_INVITE_REMINDERS = `UPDATE notifications.invites SET reminded_at = now()
+							WHERE (entity, entity_id, email_id) IN (
+								SELECT entity, entity_id, email_id FROM notifications.invites
+								WHERE status IS NULL AND entity = 'space' AND reminded_at IS NULL AND last_sent_at <= $1
+									AND (expires_at IS NULL OR expires_at > now())
+								ORDER BY last_sent_at LIMIT $2 FOR UPDATE SKIP LOCKED
+							) AND status IS NULL
+							RETURNING ` + PENDING_INVITE_COLUMNS
+	// resending never shortens an invite, and leaves one without an expiry without
+	RESEND_SPACE_INVITE = `UPDATE notifications.invites
+							SET last_sent_at = now(), reminded_at = NULL, updated_at = now(),
+								expires_at = CASE WHEN expires_at IS NULL THEN NULL ELSE GREATEST(expires_at, $3) END
+							WHERE entity = 'space' AND entity_id = $1 AND lower(email_id) = lower($2) AND status IS NULL
+								AND (expires_at IS NULL OR expires_at > now())
+							RETURNING ` + PENDING_INVITE_COLUMNS
 )
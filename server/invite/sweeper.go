@@ -0,0 +1,103 @@
+package invite
+
+import (
+	"context"
+	"time"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Sweeper marks invites past their expiry as expired and reminds invitees
+// who have left an invite unanswered.
+type Sweeper struct {
+	config Config
+}
+
+func NewSweeper(config Config) *Sweeper {
+	return &Sweeper{config: config}
+}
+
+func (s *Sweeper) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		return
+	}
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			s.ExpireDue(ctx)
+			s.RemindDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireDue marks one batch of lapsed invites as expired and returns how many
+// it marked.
+func (s *Sweeper) ExpireDue(ctx context.Context) int {
+	rows, err := core.GetPool().Query(ctx, EXPIRE_DUE_INVITES, s.config.BatchSize)
+	if err != nil {
+		logger().Error("invite sweep: expiring invites failed", zap.Error(err))
+		return 0
+	}
+	expired, err := pgx.CollectRows(rows, pgx.RowToStructByName[ExpiredInvite])
+	if err != nil {
+		logger().Error("invite sweep: reading expired invites failed", zap.Error(err))
+		return 0
+	}
+	for _, invite := range expired {
+		entry := audit.Entry{
+			Action:     audit.ActionInviteExpired,
+			TargetType: "invite",
+			TargetId:   invite.Email,
+			After:      map[string]string{"status": STATUS_EXPIRED},
+		}
+		if spaceId, err := uuid.Parse(invite.EntityId); err == nil && invite.Entity == "space" {
+			entry.SpaceId = &spaceId
+		}
+		audit.RecordSystem(entry)
+	}
+	return len(expired)
+}
+
+// RemindDue emails one batch of invites left unanswered for the reminder
+// period and returns how many were claimed. Each invite is claimed before its
+// email is queued, so it is reminded at most once per sending.
+func (s *Sweeper) RemindDue(ctx context.Context) int {
+	if s.config.RemindAfter <= 0 {
+		return 0
+	}
+	rows, err := core.GetPool().Query(ctx, CLAIM_INVITE_REMINDERS, time.Now().Add(-s.config.RemindAfter), s.config.BatchSize)
+	if err != nil {
+		logger().Error("invite sweep: claiming reminders failed", zap.Error(err))
+		return 0
+	}
+	due, err := pgx.CollectRows(rows, pgx.RowToStructByName[PendingInvite])
+	if err != nil {
+		logger().Error("invite sweep: reading reminders failed", zap.Error(err))
+		return 0
+	}
+	for _, pending := range due {
+		if err := enqueuePendingInviteEmail(ctx, pending, true); err != nil {
+			logger().Error("invite sweep: queueing reminder failed",
+				zap.String("entity_id", pending.EntityId),
+				zap.String("email", pending.Email),
+				zap.Error(err),
+			)
+		}
+	}
+	return len(due)
+}
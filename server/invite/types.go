@@ -16,6 +16,12 @@ type Invite struct {
 	Status   string    `json:"status"`
 	Email    string    `json:"email"`
 	Role     string    `json:"role"`
+
+	// ExpiresAt ends the space membership the invite grants; nil keeps it.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// InviteExpiresAt ends the invite itself; nil takes the configured default.
+	InviteExpiresAt *time.Time `json:"inviteExpiresAt,omitempty"`
 }
 
 type InviteDBO struct {
@@ -24,6 +30,12 @@ type InviteDBO struct {
 	SenderId uuid.UUID      `json:"senderId" db:"sender_id"`
 	Status   sql.NullString `json:"status" db:"status"`
 	Role     string         `json:"role" db:"role"`
+
+	// MembershipExpiresAt is when the membership granted on accepting ends.
+	MembershipExpiresAt *time.Time `json:"membershipExpiresAt,omitempty" db:"membership_expires_at"`
+
+	// ExpiresAt is when the invite can no longer be accepted.
+	ExpiresAt *time.Time `json:"inviteExpiresAt,omitempty" db:"expires_at"`
 }
 
 type InviteDBOV2 struct {
@@ -57,6 +69,8 @@ type InviteDetailsDBO struct {
 	Name      string         `db:"name"`
 	CreatedAt *time.Time     `db:"created_at"`
 	UpdatedAt *time.Time     `db:"updated_at"`
+
+	ExpiresAt *time.Time `db:"expires_at"`
 }
 
 type InviteDetailsResponse struct {
@@ -70,6 +84,8 @@ type InviteDetailsResponse struct {
 	Status     *string    `json:"status"`
 	CreatedAt  *time.Time `json:"createdAt,omitempty"`
 	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+
+	InviteExpiresAt *time.Time `json:"inviteExpiresAt,omitempty"`
 }
 
 type InviteDecisionRequest struct {
@@ -108,4 +124,64 @@ type InviteDBOV3 struct {
 	Role      string         `json:"role" db:"role"`
 	CreatedAt *time.Time     `json:"createdAt,omitempty" db:"created_at"`
 	UpdatedAt *time.Time     `json:"updatedAt,omitempty" db:"updated_at"`
+
+	// MembershipExpiresAt is when the membership granted on accepting ends.
+	MembershipExpiresAt *time.Time `json:"membershipExpiresAt,omitempty" db:"membership_expires_at"`
+
+	InviteExpiresAt *time.Time `json:"inviteExpiresAt,omitempty" db:"expires_at"`
+	LastSentAt      *time.Time `json:"lastSentAt,omitempty" db:"last_sent_at"`
+}
+
+// PendingInvite is an unanswered invite picked up to be resent or reminded.
+type PendingInvite struct {
+	SenderId   uuid.UUID  `db:"sender_id"`
+	Token      string     `db:"token"`
+	UserId     *uuid.UUID `db:"user_id"`
+	Entity     string     `db:"entity"`
+	EntityId   string     `db:"entity_id"`
+	Email      string     `db:"email_id"`
+	Role       string     `db:"role"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastSentAt time.Time  `db:"last_sent_at"`
+}
+
+// ExpiredInvite is an invite the sweeper marked as expired.
+type ExpiredInvite struct {
+	Entity   string `db:"entity"`
+	EntityId string `db:"entity_id"`
+	Email    string `db:"email_id"`
+}
+
+type SpaceInviteRequest struct {
+	Email string `json:"email"`
+}
+
+// BulkInviteRow is one line of a bulk invite CSV. Error is set when the line
+// can't be invited as written.
+type BulkInviteRow struct {
+	Row       int
+	Email     string
+	Role      string
+	ExpiresAt *time.Time
+	Error     string
+}
+
+type BulkInviteResult struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkInviteReport struct {
+	Invited int                `json:"invited"`
+	Failed  int                `json:"failed"`
+	Results []BulkInviteResult `json:"results"`
+}
+
+// WelcomePage is a page a new member's welcome email points to.
+type WelcomePage struct {
+	PageId int64  `db:"id"`
+	Title  string `db:"title"`
 }
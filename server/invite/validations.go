@@ -1,8 +1,14 @@
 package invite
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
 
 	"github.com/durgakiran/beskar/core"
 )
@@ -26,5 +32,100 @@ func validateInput(data []byte) (Invite, error) {
 	if invite.Role == "" {
 		return invite, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
 	}
+	if invite.ExpiresAt != nil {
+		// only space memberships can be time-limited
+		if invite.Entity != "space" || !invite.ExpiresAt.After(time.Now()) {
+			return invite, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+	}
+	if invite.InviteExpiresAt != nil && !invite.InviteExpiresAt.After(time.Now()) {
+		return invite, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
 	return invite, nil
 }
+
+func validateSpaceInviteRequest(data []byte) (SpaceInviteRequest, error) {
+	var req SpaceInviteRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	return req, nil
+}
+
+const (
+	bulkInviteRowLimit = 500
+	bulkInviteMaxBytes = 1 << 20
+)
+
+// parseBulkInvites reads a CSV of email, role and an optional membership
+// expiry, with or without a header. Rows are numbered by their line in the
+// file, and rows that can't be invited are kept with Error set, so the report
+// lines up with the file; only a file that can't be read at all is an error.
+func parseBulkInvites(data []byte) ([]BulkInviteRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows := make([]BulkInviteRow, 0)
+	seen := make(map[string]bool)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		line, _ := reader.FieldPos(0)
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(rows) == bulkInviteRowLimit {
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		row := BulkInviteRow{Row: line, Email: strings.TrimSpace(record[0]), Role: "viewer"}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			row.Role = strings.ToLower(strings.TrimSpace(record[1]))
+		}
+		if row.Role == "commentor" {
+			row.Role = "commenter"
+		}
+		address, err := mail.ParseAddress(row.Email)
+		switch {
+		case err != nil || address.Address != row.Email:
+			row.Error = "invalid email"
+		case seen[strings.ToLower(row.Email)]:
+			row.Error = "duplicate email"
+		case row.Role != "admin" && row.Role != "editor" && row.Role != "commenter" && row.Role != "viewer":
+			row.Error = "invalid role"
+		case len(record) > 2 && strings.TrimSpace(record[2]) != "":
+			expiresAt, err := parseBulkExpiry(strings.TrimSpace(record[2]))
+			if err != nil || !expiresAt.After(time.Now()) {
+				row.Error = "invalid expiry"
+			} else {
+				row.ExpiresAt = &expiresAt
+			}
+		}
+		seen[strings.ToLower(row.Email)] = true
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return rows, nil
+}
+
+// parseBulkExpiry takes a timestamp or a plain date, which ends at the start
+// of that day in UTC.
+func parseBulkExpiry(value string) (time.Time, error) {
+	if expiresAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return expiresAt, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
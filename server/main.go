@@ -8,17 +8,28 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/analytics"
 	attachment "github.com/durgakiran/beskar/attachment/controller"
+	"github.com/durgakiran/beskar/audit"
 	auth "github.com/durgakiran/beskar/auth"
 	"github.com/durgakiran/beskar/comment"
 	"github.com/durgakiran/beskar/core"
 	editor "github.com/durgakiran/beskar/editor"
+	"github.com/durgakiran/beskar/gitsync"
+	"github.com/durgakiran/beskar/group"
+	"github.com/durgakiran/beskar/inbox"
 	"github.com/durgakiran/beskar/invite"
 	media "github.com/durgakiran/beskar/media/controller"
 	"github.com/durgakiran/beskar/notification"
+	"github.com/durgakiran/beskar/org"
 	page "github.com/durgakiran/beskar/page"
 	profile "github.com/durgakiran/beskar/profile/controller"
+	"github.com/durgakiran/beskar/relations"
+	"github.com/durgakiran/beskar/slug"
 	space "github.com/durgakiran/beskar/space"
+	"github.com/durgakiran/beskar/star"
+	"github.com/durgakiran/beskar/token"
 	"github.com/durgakiran/beskar/user"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -37,7 +48,7 @@ func addCorsMiddleWare(r *chi.Mux) {
 			AllowedOrigins: core.AllowedOriginsFromEnv(),
 			// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", org.HEADER_ORGANIZATION},
 			ExposedHeaders:   []string{"Link"},
 			AllowCredentials: false,
 			MaxAge:           300, // Maximum value not ignored by any of major browsers
@@ -95,14 +106,49 @@ func main() {
 	}
 	connection.Release()
 
+	if err := core.InitAuthorizer(); err != nil {
+		logger().Error(fmt.Sprintf("Could not set up the authorizer %s", err.Error()))
+		os.Exit(1)
+	}
+
 	notificationConfig := notification.LoadConfig()
 	if notificationConfig.WorkerEnabled {
 		go notification.NewWorker(notificationConfig).Start(context.Background())
 	}
+	purgeConfig := space.LoadPurgeConfig()
+	if purgeConfig.Enabled {
+		go space.NewPurger(purgeConfig).Start(context.Background())
+	}
+	expiryConfig := space.LoadExpiryConfig()
+	if expiryConfig.Enabled {
+		go space.NewExpirer(expiryConfig).Start(context.Background())
+	}
+	inviteConfig := invite.LoadConfig()
+	if inviteConfig.Enabled {
+		go invite.NewSweeper(inviteConfig).Start(context.Background())
+	}
+	reviewConfig := space.LoadReviewConfig()
+	if reviewConfig.Enabled {
+		go space.NewReviewer(reviewConfig).Start(context.Background())
+	}
+	gitSyncConfig := gitsync.LoadConfig()
+	if gitSyncConfig.Enabled {
+		go gitsync.NewWorker(gitSyncConfig).Start(context.Background())
+	}
+	relationsConfig := relations.LoadConfig()
+	if relationsConfig.RelayEnabled {
+		go relations.NewRelay(relationsConfig).Start(context.Background())
+	}
+	if relationsConfig.ReconcileEnabled {
+		go relations.NewReconciler(relationsConfig).Start(context.Background())
+	}
+	go analytics.Views.Start(context.Background())
 
 	r := chi.NewRouter()
 	addCorsMiddleWare(r)
 	mw := core.ZitadelMiddleware()
+	// personal access tokens are accepted wherever a session is
+	authenticate := token.Authenticate(mw.CheckAuthentication())
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Heartbeat("/"))
@@ -111,18 +157,33 @@ func main() {
 	// r.Use(QueryParamLogger)
 	r.Mount("/auth/", core.ZitadelAuthRouter())
 	r.Mount("/api/v1", auth.Router())
-	r.Mount("/api/v1/media", mw.CheckAuthentication()(media.Router()))
-	r.Mount("/api/v1/attachments", mw.CheckAuthentication()(attachment.Router()))
-	r.Mount("/api/v1/profile", mw.CheckAuthentication()(profile.Router()))
-	r.Mount("/api/v1/editor", mw.CheckAuthentication()(editor.Router()))
-	r.Mount("/api/v1/space", mw.CheckAuthentication()(space.Router()))
-	r.Mount("/api/v1/invite", mw.CheckAuthentication()(invite.Router()))
-	r.Mount("/api/v1/page", mw.CheckAuthentication()(page.Router()))
-	r.Mount("/api/v1/comment", mw.CheckAuthentication()(comment.Router()))
-	r.Mount("/api/v1/user", user.Router())
+	r.Mount("/api/v1/media", authenticate(media.Router()))
+	r.Mount("/api/v1/attachments", authenticate(org.Scoped(attachment.Router())))
+	r.Mount("/api/v1/profile", authenticate(profile.Router()))
+	r.Mount("/api/v1/editor", authenticate(org.Scoped(editor.Router())))
+	r.Mount("/api/v1/space", authenticate(org.Scoped(space.Router())))
+	r.Mount("/api/v1/invite", authenticate(org.Scoped(invite.Router())))
+	r.Mount("/api/v1/page", authenticate(org.Scoped(page.Router())))
+	r.Mount("/api/v1/comment", authenticate(org.Scoped(comment.Router())))
+	r.Mount("/api/v1/analytics", authenticate(org.Scoped(analytics.Router())))
+	r.Mount("/api/v1/star", authenticate(org.Scoped(star.Router())))
+	r.Mount("/api/v1/activity", authenticate(org.Scoped(activity.Router())))
+	r.Mount("/api/v1/audit", authenticate(org.Scoped(audit.Router())))
+	r.Mount("/api/v1/group", authenticate(org.Scoped(group.Router())))
+	r.Mount("/api/v1/slug", authenticate(org.Scoped(slug.Router())))
+	r.Mount("/api/v1/git", authenticate(org.Scoped(gitsync.Router(gitSyncConfig))))
+	r.Mount("/api/v1/org", authenticate(org.Router()))
+	// the inbox spans every organization the user belongs to
+	r.Mount("/api/v1/inbox", authenticate(inbox.Router()))
+	r.Mount("/api/v1/token", mw.CheckAuthentication()(org.Scoped(token.Router())))
+	r.Mount("/api/v1/user", org.Scoped(user.Router()))
 	if notificationConfig.AdminEnabled && notificationConfig.AdminToken != "" {
 		r.Mount("/api/v1/admin/email", mw.CheckAuthentication()(notification.NewAdminController(notificationConfig).Router()))
 	}
+	auditConfig := audit.LoadConfig()
+	if auditConfig.AdminEnabled && auditConfig.AdminToken != "" {
+		r.Mount("/api/v1/admin/audit", mw.CheckAuthentication()(audit.NewAdminController(auditConfig).Router()))
+	}
 
 	logger().Info(fmt.Sprintf("Serving on port: %s", port))
 	err = http.ListenAndServe(port, r)
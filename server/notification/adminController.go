@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/durgakiran/beskar/audit"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -125,6 +126,11 @@ func (a *AdminController) requeueMessage(w http.ResponseWriter, r *http.Request)
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "unable to requeue email message")
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionEmailRequeued,
+		TargetType: "email_message",
+		TargetId:   updated.String(),
+	})
 	summary, err := a.loadMessage(r.Context(), updated)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "unable to get requeued email message")
@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const digestMessageLimit = 100
+
+type digestRecipient struct {
+	UserID uuid.UUID
+	Email  string
+	Name   string
+}
+
+type heldMessage struct {
+	ID           uuid.UUID
+	TemplateKey  string
+	TemplateData []byte
+	CreatedAt    time.Time
+}
+
+// digestLinkFields are the template fields a digest entry links to, most
+// specific first. Invite action links are left out so an entry never
+// answers an invite.
+var digestLinkFields = []string{"page_url", "review_url", "request_url", "members_url", "space_url"}
+
+func digestLink(data map[string]any, appURL string) string {
+	for _, field := range digestLinkFields {
+		if link := optionalTemplateString(data, field); link != "" {
+			return link
+		}
+	}
+	return appURL
+}
+
+// SendDigests gathers each recipient's held emails into one digest once
+// their digest time has passed, and returns how many digests it queued.
+func (w *Worker) SendDigests(ctx context.Context) int {
+	batchSize := w.config.WorkerBatchSize
+	if batchSize <= 0 {
+		batchSize = 25
+	}
+	rows, err := w.pool.Query(ctx, listDigestRecipients, batchSize)
+	if err != nil {
+		w.logger.Error("email digest: listing recipients failed", zap.Error(err))
+		return 0
+	}
+	recipients := make([]digestRecipient, 0)
+	for rows.Next() {
+		var recipient digestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Email, &recipient.Name); err != nil {
+			rows.Close()
+			w.logger.Error("email digest: reading recipients failed", zap.Error(err))
+			return 0
+		}
+		recipients = append(recipients, recipient)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		w.logger.Error("email digest: reading recipients failed", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	now := time.Now()
+	for _, recipient := range recipients {
+		settings, err := loadDeliverySettings(ctx, w.pool, recipient.UserID)
+		if err != nil {
+			w.logger.Error("email digest failed", zap.String("user_id", recipient.UserID.String()), zap.Error(err))
+			continue
+		}
+		queued, err := w.sendDigest(ctx, recipient, settings.digestCutoff(now))
+		if err != nil {
+			w.logger.Error("email digest failed", zap.String("user_id", recipient.UserID.String()), zap.Error(err))
+			continue
+		}
+		if queued {
+			sent++
+		}
+	}
+	return sent
+}
+
+// sendDigest queues one digest of the recipient's emails held before the
+// cutoff, and reports whether there were any.
+func (w *Worker) sendDigest(ctx context.Context, recipient digestRecipient, cutoff time.Time) (bool, error) {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, claimHeldEmailMessages, recipient.UserID, cutoff, digestMessageLimit)
+	if err != nil {
+		return false, err
+	}
+	held := make([]heldMessage, 0)
+	for rows.Next() {
+		var message heldMessage
+		if err := rows.Scan(&message.ID, &message.TemplateKey, &message.TemplateData, &message.CreatedAt); err != nil {
+			rows.Close()
+			return false, err
+		}
+		held = append(held, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(held) == 0 {
+		return false, nil
+	}
+	slices.SortFunc(held, func(a, b heldMessage) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	payload, err := json.Marshal(w.digestData(held))
+	if err != nil {
+		return false, err
+	}
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, insertEmailMessage,
+		fmt.Sprintf("%s:%s:%d:%s", TemplateNotificationDigest, recipient.UserID, cutoff.Unix(), held[0].ID),
+		CategoryDigest,
+		TemplateNotificationDigest,
+		recipient.UserID,
+		recipient.Email,
+		recipient.Name,
+		string(payload),
+		PriorityNormal,
+		StatusPending,
+		time.Now(),
+		nil,
+	).Scan(&id)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// digestData lists each held email by its subject, linking to what it is
+// about.
+func (w *Worker) digestData(held []heldMessage) map[string]any {
+	appURL := strings.TrimRight(strings.TrimSpace(w.config.AppBaseURL), "/") + "/"
+	items := make([]map[string]any, 0, len(held))
+	for _, message := range held {
+		data := map[string]any{}
+		if len(message.TemplateData) > 0 {
+			if err := json.Unmarshal(message.TemplateData, &data); err != nil {
+				w.logger.Warn("email digest: unreadable template data", zap.String("message_id", message.ID.String()), zap.Error(err))
+			}
+		}
+		rendered, err := w.templates.Render(message.TemplateKey, data)
+		if err != nil {
+			w.logger.Warn("email digest: leaving out an email that doesn't render", zap.String("message_id", message.ID.String()), zap.Error(err))
+			continue
+		}
+		items = append(items, map[string]any{"title": rendered.Subject, "url": digestLink(data, appURL)})
+	}
+	return map[string]any{"items": items, "app_url": appURL}
+}
@@ -0,0 +1,160 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnknownSpace is returned for a space preference on a space that does
+// not exist.
+var ErrUnknownSpace = errors.New("space not found")
+
+// deliveryFor turns a stored preference into the channels it allows. A
+// disabled category goes nowhere.
+func deliveryFor(enabled bool, channel string) Delivery {
+	if !enabled {
+		return Delivery{}
+	}
+	return Delivery{
+		InApp: channel != ChannelEmail,
+		Email: channel != ChannelInApp,
+	}
+}
+
+// resolvePreference applies a space override on top of the category's
+// preference. The override decides whether the category is on, and its
+// channel when it has one.
+func resolvePreference(category CategoryPreference, override *SpacePreference) preference {
+	enabled, channel := category.Enabled, category.Channel
+	if override != nil {
+		enabled = override.Enabled
+		if override.Channel != nil {
+			channel = *override.Channel
+		}
+	}
+	return preference{Delivery: deliveryFor(enabled, channel), Frequency: category.Frequency}
+}
+
+// loadPreference reads what applies to a notification of the category about
+// the space, which may be nil. Without stored preferences it goes both
+// in-app and by email, right away.
+func loadPreference(ctx context.Context, pool queryPool, userID uuid.UUID, category string, spaceID *uuid.UUID) (preference, error) {
+	var stored CategoryPreference
+	var overrideEnabled *bool
+	var overrideChannel *string
+	err := pool.QueryRow(ctx, getDeliveryPreference, userID, category, spaceID).Scan(
+		&stored.Category, &stored.Enabled, &stored.Channel, &stored.Frequency, &overrideEnabled, &overrideChannel,
+	)
+	if err != nil {
+		return preference{}, err
+	}
+	var override *SpacePreference
+	if overrideEnabled != nil {
+		override = &SpacePreference{Category: category, Enabled: *overrideEnabled, Channel: overrideChannel}
+	}
+	return resolvePreference(stored, override), nil
+}
+
+// DeliveryFor reports where the user wants a notification of the category
+// about the space, which may be nil.
+func (s *Service) DeliveryFor(ctx context.Context, userID uuid.UUID, category string, spaceID *uuid.UUID) (Delivery, error) {
+	resolved, err := loadPreference(ctx, s.pool, userID, category, spaceID)
+	return resolved.Delivery, err
+}
+
+// Preferences lists the user's preference for every category, including the
+// ones left at the default, their space overrides and their schedule.
+func (s *Service) Preferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	rows, err := s.pool.Query(ctx, listCategoryPreferences, userID)
+	if err != nil {
+		return Preferences{}, err
+	}
+	stored, err := pgx.CollectRows(rows, pgx.RowToStructByPos[CategoryPreference])
+	if err != nil {
+		return Preferences{}, err
+	}
+	preferences := Preferences{Categories: make([]CategoryPreference, 0, len(Categories))}
+	for _, category := range Categories {
+		preference := CategoryPreference{Category: category, Enabled: true, Channel: ChannelBoth, Frequency: FrequencyInstant}
+		if i := slices.IndexFunc(stored, func(p CategoryPreference) bool { return p.Category == category }); i >= 0 {
+			preference = stored[i]
+		}
+		preferences.Categories = append(preferences.Categories, preference)
+	}
+
+	rows, err = s.pool.Query(ctx, listSpacePreferences, userID)
+	if err != nil {
+		return Preferences{}, err
+	}
+	if preferences.Spaces, err = pgx.CollectRows(rows, pgx.RowToStructByPos[SpacePreference]); err != nil {
+		return Preferences{}, err
+	}
+
+	settings, err := loadDeliverySettings(ctx, s.pool, userID)
+	if err != nil {
+		return Preferences{}, err
+	}
+	preferences.Schedule = settings.schedule()
+	return preferences, nil
+}
+
+func (s *Service) UpdateCategory(ctx context.Context, userID uuid.UUID, category string, update CategoryUpdate) (CategoryPreference, error) {
+	var updated CategoryPreference
+	err := s.pool.QueryRow(ctx, upsertCategoryPreference, userID, category, update.Enabled, update.Channel, update.Frequency).
+		Scan(&updated.Category, &updated.Enabled, &updated.Channel, &updated.Frequency)
+	return updated, err
+}
+
+func (s *Service) SetSpacePreference(ctx context.Context, userID uuid.UUID, preference SpacePreference) error {
+	tag, err := s.pool.Exec(ctx, upsertSpacePreference, userID, preference.SpaceId, preference.Category, preference.Enabled, preference.Channel)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUnknownSpace
+	}
+	return nil
+}
+
+// ClearSpacePreferences drops the user's override of the category in the
+// space, or all of the space's overrides when category is empty.
+func (s *Service) ClearSpacePreferences(ctx context.Context, userID uuid.UUID, spaceID uuid.UUID, category string) error {
+	_, err := s.pool.Exec(ctx, deleteSpacePreferences, userID, spaceID, category)
+	return err
+}
+
+// SetSchedule stores the user's timezone, quiet hours and digest time,
+// failing with ErrInvalidSchedule on one it can't use.
+func (s *Service) SetSchedule(ctx context.Context, userID uuid.UUID, schedule Schedule) error {
+	settings, err := parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, upsertDeliverySettings, userID, settings.Timezone, settings.QuietHoursStart, settings.QuietHoursEnd, settings.DigestMinute)
+	return err
+}
+
+func loadDeliverySettings(ctx context.Context, pool queryPool, userID uuid.UUID) (deliverySettings, error) {
+	settings := defaultDeliverySettings()
+	err := pool.QueryRow(ctx, getDeliverySettings, userID).Scan(&settings.Timezone, &settings.QuietHoursStart, &settings.QuietHoursEnd, &settings.DigestMinute)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultDeliverySettings(), nil
+	}
+	return settings, err
+}
+
+func IsCategory(category string) bool {
+	return slices.Contains(Categories, category)
+}
+
+func IsChannel(channel string) bool {
+	return channel == ChannelInApp || channel == ChannelEmail || channel == ChannelBoth
+}
+
+func IsFrequency(frequency string) bool {
+	return frequency == FrequencyInstant || frequency == FrequencyDaily
+}
@@ -0,0 +1,48 @@
+package notification
+
+import "testing"
+
+func TestDeliveryForChannels(t *testing.T) {
+	cases := []struct {
+		enabled bool
+		channel string
+		want    Delivery
+	}{
+		{true, ChannelBoth, Delivery{InApp: true, Email: true}},
+		{true, ChannelInApp, Delivery{InApp: true}},
+		{true, ChannelEmail, Delivery{Email: true}},
+		{false, ChannelBoth, Delivery{}},
+	}
+	for _, c := range cases {
+		if got := deliveryFor(c.enabled, c.channel); got != c.want {
+			t.Fatalf("deliveryFor(%v, %q) = %+v, want %+v", c.enabled, c.channel, got, c.want)
+		}
+	}
+}
+
+func TestCategoriesAreKnown(t *testing.T) {
+	if !IsCategory(CategoryCommentReply) || IsCategory("newsletter") {
+		t.Fatalf("expected only listed categories to be known")
+	}
+	if !IsChannel(ChannelInApp) || IsChannel("sms") {
+		t.Fatalf("expected only in_app, email and both to be channels")
+	}
+}
+
+func TestResolvePreferenceAppliesSpaceOverride(t *testing.T) {
+	category := CategoryPreference{Category: CategoryCommentReply, Enabled: true, Channel: ChannelBoth, Frequency: FrequencyDaily}
+	if got := resolvePreference(category, nil); got.Delivery != (Delivery{InApp: true, Email: true}) || got.Frequency != FrequencyDaily {
+		t.Fatalf("expected the category preference without an override, got %+v", got)
+	}
+	if got := resolvePreference(category, &SpacePreference{Enabled: false}); got.Delivery != (Delivery{}) {
+		t.Fatalf("expected a disabled override to mute the space, got %+v", got)
+	}
+	inApp := ChannelInApp
+	if got := resolvePreference(category, &SpacePreference{Enabled: true, Channel: &inApp}); got.Delivery != (Delivery{InApp: true}) {
+		t.Fatalf("expected the override's channel to win, got %+v", got)
+	}
+	category.Enabled = false
+	if got := resolvePreference(category, &SpacePreference{Enabled: true}); got.Delivery != (Delivery{InApp: true, Email: true}) {
+		t.Fatalf("expected an override to turn the category back on in its space, got %+v", got)
+	}
+}
@@ -13,9 +13,10 @@ const (
 		status,
 		scheduled_at,
 		next_attempt_at,
+		space_id,
 		created_at,
 		updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8, $9, $10, $10, now(), now())
+	) VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8, $9, $10, $10, $11, now(), now())
 	ON CONFLICT (message_key) DO UPDATE SET message_key = EXCLUDED.message_key
 	RETURNING id`
 
@@ -39,7 +40,7 @@ const (
 		m.priority, m.status, m.attempt_count, m.scheduled_at, m.next_attempt_at,
 		m.last_attempt_at, m.sent_at, m.failed_at, m.dead_lettered_at,
 		m.provider, m.provider_message_id, m.last_error_code, m.last_error_message,
-		m.created_at, m.updated_at`
+		m.created_at, m.updated_at, m.space_id`
 
 	updateRenderedEmailMessage = `UPDATE notifications.email_messages
 	SET subject = $2, text_body = $3, html_body = $4, updated_at = now()
@@ -49,9 +50,93 @@ const (
 		SELECT 1 FROM notifications.email_suppressions WHERE email = $1
 	)`
 
-	checkEmailPreference = `SELECT enabled
+	// getDeliveryPreference reads the user's preference for a category along
+	// with the most specific override for the space, if any.
+	getDeliveryPreference = `SELECT $2::text,
+		COALESCE(p.enabled, true), COALESCE(p.channel, 'both'), COALESCE(p.frequency, 'instant'),
+		s.enabled, s.channel
+	FROM (SELECT 1) one
+	LEFT JOIN notifications.email_preferences p
+		ON p.recipient_user_id = $1 AND p.category = $2
+	LEFT JOIN LATERAL (
+		SELECT enabled, channel
+		FROM notifications.space_preferences
+		WHERE recipient_user_id = $1 AND space_id = $3::uuid AND category IN ($2, 'all')
+		ORDER BY category = 'all'
+		LIMIT 1
+	) s ON true`
+
+	listCategoryPreferences = `SELECT category, enabled, channel, frequency
 		FROM notifications.email_preferences
-		WHERE recipient_user_id = $1 AND category = $2`
+		WHERE recipient_user_id = $1`
+
+	upsertCategoryPreference = `INSERT INTO notifications.email_preferences (recipient_user_id, category, enabled, channel, frequency)
+	VALUES ($1, $2, COALESCE($3::boolean, true), COALESCE($4::text, 'both'), COALESCE($5::text, 'instant'))
+	ON CONFLICT (recipient_user_id, category)
+	DO UPDATE SET
+		enabled = COALESCE($3::boolean, email_preferences.enabled),
+		channel = COALESCE($4::text, email_preferences.channel),
+		frequency = COALESCE($5::text, email_preferences.frequency),
+		updated_at = now()
+	RETURNING category, enabled, channel, frequency`
+
+	listSpacePreferences = `SELECT space_id, category, enabled, channel
+		FROM notifications.space_preferences
+		WHERE recipient_user_id = $1
+		ORDER BY space_id, category`
+
+	upsertSpacePreference = `INSERT INTO notifications.space_preferences (recipient_user_id, space_id, category, enabled, channel)
+	SELECT $1, s.id, $3, $4, $5
+	FROM core.space s
+	WHERE s.id = $2 AND s.deleted_at IS NULL
+	ON CONFLICT (recipient_user_id, space_id, category)
+	DO UPDATE SET enabled = EXCLUDED.enabled, channel = EXCLUDED.channel, updated_at = now()`
+
+	deleteSpacePreferences = `DELETE FROM notifications.space_preferences
+		WHERE recipient_user_id = $1 AND space_id = $2 AND ($3 = '' OR category = $3)`
+
+	getDeliverySettings = `SELECT timezone, quiet_hours_start, quiet_hours_end, digest_minute
+		FROM notifications.delivery_settings
+		WHERE recipient_user_id = $1`
+
+	upsertDeliverySettings = `INSERT INTO notifications.delivery_settings (recipient_user_id, timezone, quiet_hours_start, quiet_hours_end, digest_minute)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (recipient_user_id)
+	DO UPDATE SET
+		timezone = EXCLUDED.timezone,
+		quiet_hours_start = EXCLUDED.quiet_hours_start,
+		quiet_hours_end = EXCLUDED.quiet_hours_end,
+		digest_minute = EXCLUDED.digest_minute,
+		updated_at = now()`
+
+	deferEmailMessage = `UPDATE notifications.email_messages
+	SET status = 'pending', next_attempt_at = $2, updated_at = now()
+	WHERE id = $1`
+
+	holdEmailMessage = `UPDATE notifications.email_messages
+	SET status = 'held', updated_at = now()
+	WHERE id = $1`
+
+	listDigestRecipients = `SELECT recipient_user_id,
+		(array_agg(recipient_email ORDER BY created_at DESC))[1],
+		(array_agg(COALESCE(recipient_name, '') ORDER BY created_at DESC))[1]
+	FROM notifications.email_messages
+	WHERE status = 'held'
+	GROUP BY recipient_user_id
+	LIMIT $1`
+
+	// claimHeldEmailMessages marks what a digest covers: the recipient's held
+	// emails from before the digest time.
+	claimHeldEmailMessages = `UPDATE notifications.email_messages m
+	SET status = 'digested', updated_at = now()
+	WHERE m.id IN (
+		SELECT id FROM notifications.email_messages
+		WHERE recipient_user_id = $1 AND status = 'held' AND created_at <= $2
+		ORDER BY created_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING m.id, m.template_key, m.template_data, m.created_at`
 
 	insertDeliveryAttempt = `INSERT INTO notifications.email_delivery_attempts (
 		email_message_id, attempt_number, provider, status, started_at
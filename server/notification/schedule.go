@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// users pick any IANA timezone, whether or not the host has tzdata
+	_ "time/tzdata"
+)
+
+const defaultDigestMinute = 9 * 60
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+func defaultDeliverySettings() deliverySettings {
+	return deliverySettings{Timezone: "UTC", DigestMinute: defaultDigestMinute}
+}
+
+// parseClock reads an HH:MM time of day as minutes after midnight.
+func parseClock(value string) (int16, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, ErrInvalidSchedule
+	}
+	return int16(parsed.Hour()*60 + parsed.Minute()), nil
+}
+
+func formatClock(minute int16) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// parseSchedule checks a schedule and turns it into its stored form. Quiet
+// hours are given both ends or neither; an empty timezone is UTC and an
+// empty digest time is 09:00.
+func parseSchedule(schedule Schedule) (deliverySettings, error) {
+	settings := defaultDeliverySettings()
+	if timezone := strings.TrimSpace(schedule.Timezone); timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return settings, ErrInvalidSchedule
+		}
+		settings.Timezone = timezone
+	}
+	if (schedule.QuietHoursStart == nil) != (schedule.QuietHoursEnd == nil) {
+		return settings, ErrInvalidSchedule
+	}
+	if schedule.QuietHoursStart != nil {
+		start, err := parseClock(*schedule.QuietHoursStart)
+		if err != nil {
+			return settings, err
+		}
+		end, err := parseClock(*schedule.QuietHoursEnd)
+		if err != nil {
+			return settings, err
+		}
+		if start == end {
+			return settings, ErrInvalidSchedule
+		}
+		settings.QuietHoursStart, settings.QuietHoursEnd = &start, &end
+	}
+	if strings.TrimSpace(schedule.DigestTime) != "" {
+		minute, err := parseClock(schedule.DigestTime)
+		if err != nil {
+			return settings, err
+		}
+		settings.DigestMinute = minute
+	}
+	return settings, nil
+}
+
+func (s deliverySettings) schedule() Schedule {
+	schedule := Schedule{Timezone: s.Timezone, DigestTime: formatClock(s.DigestMinute)}
+	if s.QuietHoursStart != nil && s.QuietHoursEnd != nil {
+		start, end := formatClock(*s.QuietHoursStart), formatClock(*s.QuietHoursEnd)
+		schedule.QuietHoursStart, schedule.QuietHoursEnd = &start, &end
+	}
+	return schedule
+}
+
+func (s deliverySettings) location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// at is the given minute of the day of local, in the user's timezone.
+func at(local time.Time, minute int16) time.Time {
+	year, month, day := local.Date()
+	return time.Date(year, month, day, int(minute/60), int(minute%60), 0, 0, local.Location())
+}
+
+// quietUntil reports whether now falls in the user's quiet hours and, if so,
+// when they end. Quiet hours may run past midnight.
+func (s deliverySettings) quietUntil(now time.Time) (time.Time, bool) {
+	if s.QuietHoursStart == nil || s.QuietHoursEnd == nil {
+		return time.Time{}, false
+	}
+	start, end := *s.QuietHoursStart, *s.QuietHoursEnd
+	local := now.In(s.location())
+	minute := int16(local.Hour()*60 + local.Minute())
+	switch {
+	case start < end && minute >= start && minute < end:
+		return at(local, end), true
+	case start > end && minute >= start:
+		return at(local.AddDate(0, 0, 1), end), true
+	case start > end && minute < end:
+		return at(local, end), true
+	}
+	return time.Time{}, false
+}
+
+// digestCutoff is the latest digest time that has passed. A digest covers
+// what was held before it.
+func (s deliverySettings) digestCutoff(now time.Time) time.Time {
+	local := now.In(s.location())
+	cutoff := at(local, s.DigestMinute)
+	if cutoff.After(now) {
+		cutoff = at(local.AddDate(0, 0, -1), s.DigestMinute)
+	}
+	return cutoff
+}
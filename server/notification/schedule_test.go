@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func clock(value string) *string {
+	return &value
+}
+
+func TestParseScheduleDefaults(t *testing.T) {
+	settings, err := parseSchedule(Schedule{})
+	if err != nil {
+		t.Fatalf("expected an empty schedule to be accepted: %v", err)
+	}
+	if settings.Timezone != "UTC" || settings.DigestMinute != defaultDigestMinute || settings.QuietHoursStart != nil {
+		t.Fatalf("expected the default schedule, got %+v", settings)
+	}
+	if got := settings.schedule(); got.DigestTime != "09:00" || got.QuietHoursStart != nil {
+		t.Fatalf("unexpected schedule %+v", got)
+	}
+}
+
+func TestParseScheduleRejectsBadInput(t *testing.T) {
+	cases := []Schedule{
+		{Timezone: "Mars/Olympus"},
+		{QuietHoursStart: clock("22:00")},
+		{QuietHoursStart: clock("22:00"), QuietHoursEnd: clock("22:00")},
+		{QuietHoursStart: clock("25:00"), QuietHoursEnd: clock("07:00")},
+		{DigestTime: "9am"},
+	}
+	for _, schedule := range cases {
+		if _, err := parseSchedule(schedule); !errors.Is(err, ErrInvalidSchedule) {
+			t.Fatalf("expected %+v to be rejected, got %v", schedule, err)
+		}
+	}
+}
+
+func TestQuietUntilAcrossMidnight(t *testing.T) {
+	settings, err := parseSchedule(Schedule{Timezone: "Asia/Kolkata", QuietHoursStart: clock("22:00"), QuietHoursEnd: clock("07:00")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kolkata := settings.location()
+
+	until, quiet := settings.quietUntil(time.Date(2026, 3, 1, 23, 30, 0, 0, kolkata))
+	if !quiet || !until.Equal(time.Date(2026, 3, 2, 7, 0, 0, 0, kolkata)) {
+		t.Fatalf("expected late evening to wait for the next morning, got %v %v", until, quiet)
+	}
+	until, quiet = settings.quietUntil(time.Date(2026, 3, 2, 6, 59, 0, 0, kolkata))
+	if !quiet || !until.Equal(time.Date(2026, 3, 2, 7, 0, 0, 0, kolkata)) {
+		t.Fatalf("expected early morning to wait until 07:00, got %v %v", until, quiet)
+	}
+	if _, quiet := settings.quietUntil(time.Date(2026, 3, 2, 7, 0, 0, 0, kolkata)); quiet {
+		t.Fatalf("expected quiet hours to end at 07:00")
+	}
+	// the same instant in UTC is read in the user's timezone
+	if _, quiet := settings.quietUntil(time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)); !quiet {
+		t.Fatalf("expected 23:30 in Kolkata to be quiet")
+	}
+}
+
+func TestQuietUntilWithinDay(t *testing.T) {
+	settings, err := parseSchedule(Schedule{QuietHoursStart: clock("12:00"), QuietHoursEnd: clock("13:30")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	until, quiet := settings.quietUntil(time.Date(2026, 3, 2, 12, 15, 0, 0, time.UTC))
+	if !quiet || !until.Equal(time.Date(2026, 3, 2, 13, 30, 0, 0, time.UTC)) {
+		t.Fatalf("expected lunch to be quiet until 13:30, got %v %v", until, quiet)
+	}
+	if _, quiet := settings.quietUntil(time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)); quiet {
+		t.Fatalf("expected the evening not to be quiet")
+	}
+}
+
+func TestDigestCutoffIsLatestPassedDigestTime(t *testing.T) {
+	settings := defaultDeliverySettings()
+	if got := settings.digestCutoff(time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)); !got.Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected today's digest time, got %v", got)
+	}
+	if got := settings.digestCutoff(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)); !got.Equal(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected yesterday's digest time, got %v", got)
+	}
+}
@@ -43,6 +43,10 @@ func (s *Service) EnqueueEmail(ctx context.Context, req EnqueueEmailRequest) (uu
 	if req.Recipient.UserID != nil {
 		recipientUserID = *req.Recipient.UserID
 	}
+	var spaceID any
+	if req.SpaceID != nil {
+		spaceID = *req.SpaceID
+	}
 
 	var id uuid.UUID
 	err = s.pool.QueryRow(ctx, insertEmailMessage,
@@ -56,6 +60,7 @@ func (s *Service) EnqueueEmail(ctx context.Context, req EnqueueEmailRequest) (uu
 		req.Priority,
 		StatusPending,
 		scheduledAt,
+		spaceID,
 	).Scan(&id)
 	if err != nil {
 		return uuid.Nil, err
@@ -0,0 +1,68 @@
+package notification
+
+import "fmt"
+
+const TemplateCommentMention = "comment_mention"
+
+// CommentMentionTemplate tells someone they were mentioned in a comment.
+type CommentMentionTemplate struct{}
+
+func (CommentMentionTemplate) Key() string {
+	return TemplateCommentMention
+}
+
+func (CommentMentionTemplate) RequiredFields() []string {
+	return []string{
+		"author_name",
+		"page_title",
+		"comment",
+		"page_url",
+		"app_url",
+	}
+}
+
+func (t CommentMentionTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	authorName := templateString(data, "author_name")
+	pageTitle := templateString(data, "page_title")
+	comment := templateString(data, "comment")
+	pageURL := templateString(data, "page_url")
+	appURL := templateString(data, "app_url")
+
+	subject := fmt.Sprintf("%s mentioned you on %s", authorName, pageTitle)
+	text := fmt.Sprintf(`%s mentioned you in a comment on %q:
+
+%s
+
+View the comment:
+%s
+
+Open Beskar:
+%s
+`, authorName, pageTitle, comment, pageURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s mentioned you in a comment on <strong>%s</strong>:</p>
+    <blockquote>%s</blockquote>
+    <p><a href="%s">View the comment</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(authorName),
+		htmlEscape(pageTitle),
+		htmlEscape(comment),
+		htmlEscape(pageURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,69 @@
+package notification
+
+import "fmt"
+
+const TemplateCommentReply = "comment_reply"
+
+// CommentReplyTemplate tells someone taking part in a comment thread that it
+// got a new reply.
+type CommentReplyTemplate struct{}
+
+func (CommentReplyTemplate) Key() string {
+	return TemplateCommentReply
+}
+
+func (CommentReplyTemplate) RequiredFields() []string {
+	return []string{
+		"author_name",
+		"page_title",
+		"reply",
+		"page_url",
+		"app_url",
+	}
+}
+
+func (t CommentReplyTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	authorName := templateString(data, "author_name")
+	pageTitle := templateString(data, "page_title")
+	reply := templateString(data, "reply")
+	pageURL := templateString(data, "page_url")
+	appURL := templateString(data, "app_url")
+
+	subject := fmt.Sprintf("%s replied on %s", authorName, pageTitle)
+	text := fmt.Sprintf(`%s replied to a comment thread on %q:
+
+%s
+
+View the thread:
+%s
+
+Open Beskar:
+%s
+`, authorName, pageTitle, reply, pageURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s replied to a comment thread on <strong>%s</strong>:</p>
+    <blockquote>%s</blockquote>
+    <p><a href="%s">View the thread</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(authorName),
+		htmlEscape(pageTitle),
+		htmlEscape(reply),
+		htmlEscape(pageURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+)
+
+const TemplateNotificationDigest = "notification_digest"
+
+// NotificationDigestTemplate gathers a day of notifications into one email.
+// items is a list of objects with a title and a url.
+type NotificationDigestTemplate struct{}
+
+func (NotificationDigestTemplate) Key() string {
+	return TemplateNotificationDigest
+}
+
+func (NotificationDigestTemplate) RequiredFields() []string {
+	return []string{
+		"app_url",
+	}
+}
+
+func (t NotificationDigestTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	appURL := templateString(data, "app_url")
+	items := templateLinks(data, "items")
+	if len(items) == 0 {
+		return RenderedEmail{}, fmt.Errorf("missing template field: items")
+	}
+
+	var textItems, htmlItems strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&textItems, "- %s: %s\n", item.Title, item.URL)
+		fmt.Fprintf(&htmlItems, "      <li><a href=\"%s\">%s</a></li>\n", htmlEscape(item.URL), htmlEscape(item.Title))
+	}
+
+	subject := "Your Beskar digest: 1 notification"
+	if len(items) > 1 {
+		subject = fmt.Sprintf("Your Beskar digest: %d notifications", len(items))
+	}
+	text := fmt.Sprintf(`Here is what happened since your last digest:
+
+%s
+Open Beskar:
+%s
+`, textItems.String(), appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>Here is what happened since your last digest:</p>
+    <ul>
+%s    </ul>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlItems.String(),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,63 @@
+package notification
+
+import "fmt"
+
+const TemplatePageUpdated = "page_updated"
+
+// PageUpdatedTemplate tells someone who starred a page that a new version of
+// it was published.
+type PageUpdatedTemplate struct{}
+
+func (PageUpdatedTemplate) Key() string {
+	return TemplatePageUpdated
+}
+
+func (PageUpdatedTemplate) RequiredFields() []string {
+	return []string{
+		"editor_name",
+		"page_title",
+		"page_url",
+		"app_url",
+	}
+}
+
+func (t PageUpdatedTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	editorName := templateString(data, "editor_name")
+	pageTitle := templateString(data, "page_title")
+	pageURL := templateString(data, "page_url")
+	appURL := templateString(data, "app_url")
+
+	subject := fmt.Sprintf("%s updated %s", editorName, pageTitle)
+	text := fmt.Sprintf(`%s published a new version of %q, a page you starred.
+
+View the page:
+%s
+
+Open Beskar:
+%s
+`, editorName, pageTitle, pageURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s published a new version of <strong>%s</strong>, a page you starred.</p>
+    <p><a href="%s">View the page</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(editorName),
+		htmlEscape(pageTitle),
+		htmlEscape(pageURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
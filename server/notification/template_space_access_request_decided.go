@@ -0,0 +1,78 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceAccessRequestDecided = "space_access_request_decided"
+
+// SpaceAccessRequestDecidedTemplate tells the requester whether they got
+// access. role is only used when approved; response is optional.
+type SpaceAccessRequestDecidedTemplate struct{}
+
+func (SpaceAccessRequestDecidedTemplate) Key() string {
+	return TemplateSpaceAccessRequestDecided
+}
+
+func (SpaceAccessRequestDecidedTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"decision",
+		"space_url",
+		"app_url",
+	}
+}
+
+func (t SpaceAccessRequestDecidedTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	decision := templateString(data, "decision")
+	role := optionalTemplateString(data, "role")
+	response := optionalTemplateString(data, "response")
+	spaceURL := templateString(data, "space_url")
+	appURL := templateString(data, "app_url")
+
+	var subject, summary, htmlSummary, link string
+	switch decision {
+	case "approved":
+		subject = fmt.Sprintf("You now have access to %s", spaceName)
+		summary = fmt.Sprintf("Your request to access %s was approved. You joined as %s.", spaceName, role)
+		htmlSummary = fmt.Sprintf("Your request to access <strong>%s</strong> was approved. You joined as %s.", htmlEscape(spaceName), htmlEscape(role))
+		link = spaceURL
+	case "declined":
+		subject = fmt.Sprintf("Your request to access %s was declined", spaceName)
+		summary = fmt.Sprintf("Your request to access %s was declined.", spaceName)
+		htmlSummary = fmt.Sprintf("Your request to access <strong>%s</strong> was declined.", htmlEscape(spaceName))
+		link = appURL
+	default:
+		return RenderedEmail{}, fmt.Errorf("unknown access request decision: %s", decision)
+	}
+	if response != "" {
+		summary += fmt.Sprintf("\n\nNote from the space admin:\n%s", response)
+		htmlSummary += fmt.Sprintf("</p>\n    <p>Note from the space admin:</p>\n    <blockquote>%s</blockquote>\n    <p>", htmlEscape(response))
+	}
+
+	text := fmt.Sprintf(`%s
+
+Open Beskar:
+%s
+`, summary, link)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s</p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlSummary,
+		htmlEscape(link),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
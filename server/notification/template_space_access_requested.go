@@ -0,0 +1,82 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceAccessRequested = "space_access_requested"
+
+// SpaceAccessRequestedTemplate tells a space's owner and admins that someone
+// asked for access. page_title and message are optional.
+type SpaceAccessRequestedTemplate struct{}
+
+func (SpaceAccessRequestedTemplate) Key() string {
+	return TemplateSpaceAccessRequested
+}
+
+func (SpaceAccessRequestedTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"requester_name",
+		"requester_email",
+		"request_url",
+		"app_url",
+	}
+}
+
+func (t SpaceAccessRequestedTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	requesterName := templateString(data, "requester_name")
+	requesterEmail := templateString(data, "requester_email")
+	pageTitle := optionalTemplateString(data, "page_title")
+	message := optionalTemplateString(data, "message")
+	requestURL := templateString(data, "request_url")
+	appURL := templateString(data, "app_url")
+
+	target := spaceName
+	htmlTarget := fmt.Sprintf("<strong>%s</strong>", htmlEscape(spaceName))
+	if pageTitle != "" {
+		target = fmt.Sprintf("%q in %s", pageTitle, spaceName)
+		htmlTarget = fmt.Sprintf("&quot;%s&quot; in <strong>%s</strong>", htmlEscape(pageTitle), htmlEscape(spaceName))
+	}
+	quoted := ""
+	htmlQuoted := ""
+	if message != "" {
+		quoted = fmt.Sprintf("\nThey wrote:\n%s\n", message)
+		htmlQuoted = fmt.Sprintf("\n    <p>They wrote:</p>\n    <blockquote>%s</blockquote>", htmlEscape(message))
+	}
+
+	subject := fmt.Sprintf("%s requested access to %s", requesterName, spaceName)
+	text := fmt.Sprintf(`%s (%s) requested access to %s.
+%s
+Approve or decline the request:
+%s
+
+Open Beskar:
+%s
+`, requesterName, requesterEmail, target, quoted, requestURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s (%s) requested access to %s.</p>%s
+    <p><a href="%s">Approve or decline the request</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(requesterName),
+		htmlEscape(requesterEmail),
+		htmlTarget,
+		htmlQuoted,
+		htmlEscape(requestURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
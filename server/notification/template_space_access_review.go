@@ -0,0 +1,84 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceAccessReview = "space_access_review"
+
+type SpaceAccessReviewTemplate struct{}
+
+func (SpaceAccessReviewTemplate) Key() string {
+	return TemplateSpaceAccessReview
+}
+
+func (SpaceAccessReviewTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"member_count",
+		"invite_count",
+		"inactive_count",
+		"inactive_days",
+		"external_count",
+		"review_url",
+		"app_url",
+	}
+}
+
+func (t SpaceAccessReviewTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	memberCount := templateString(data, "member_count")
+	inviteCount := templateString(data, "invite_count")
+	inactiveCount := templateString(data, "inactive_count")
+	inactiveDays := templateString(data, "inactive_days")
+	externalCount := templateString(data, "external_count")
+	reviewURL := templateString(data, "review_url")
+	appURL := templateString(data, "app_url")
+
+	subject := fmt.Sprintf("Access review for %s", spaceName)
+	text := fmt.Sprintf(`It is time to review who has access to %s.
+
+Members: %s
+Pending invites: %s
+Inactive for %s days: %s
+Outside your organization's domains: %s
+
+Confirm or revoke access:
+%s
+
+Open Beskar:
+%s
+`, spaceName, memberCount, inviteCount, inactiveDays, inactiveCount, externalCount, reviewURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>It is time to review who has access to <strong>%s</strong>.</p>
+    <ul>
+      <li>Members: %s</li>
+      <li>Pending invites: %s</li>
+      <li>Inactive for %s days: %s</li>
+      <li>Outside your organization's domains: %s</li>
+    </ul>
+    <p><a href="%s">Confirm or revoke access</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(spaceName),
+		htmlEscape(memberCount),
+		htmlEscape(inviteCount),
+		htmlEscape(inactiveDays),
+		htmlEscape(inactiveCount),
+		htmlEscape(externalCount),
+		htmlEscape(reviewURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,85 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceInviteDecided = "space_invite_decided"
+
+// SpaceInviteDecidedTemplate tells whoever sent an invite that it was
+// accepted or declined. With for_admin set it is worded for the space's
+// admins, naming the sender when sender_name is given.
+type SpaceInviteDecidedTemplate struct{}
+
+func (SpaceInviteDecidedTemplate) Key() string {
+	return TemplateSpaceInviteDecided
+}
+
+func (SpaceInviteDecidedTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"invitee_name",
+		"decision",
+		"members_url",
+		"app_url",
+	}
+}
+
+func (t SpaceInviteDecidedTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	inviteeName := templateString(data, "invitee_name")
+	decision := templateString(data, "decision")
+	membersURL := templateString(data, "members_url")
+	appURL := templateString(data, "app_url")
+	role := optionalTemplateString(data, "role")
+	senderName := optionalTemplateString(data, "sender_name")
+	forAdmin, _ := data["for_admin"].(bool)
+	if decision != "accepted" && decision != "declined" {
+		return RenderedEmail{}, fmt.Errorf("unknown invite decision %q", decision)
+	}
+
+	invite := "your invite"
+	if forAdmin {
+		invite = "an invite"
+		if senderName != "" {
+			invite = senderName + "'s invite"
+		}
+	}
+	subject := fmt.Sprintf("%s %s %s to %s", inviteeName, decision, invite, spaceName)
+	summary := fmt.Sprintf("%s %s %s to %s.", inviteeName, decision, invite, spaceName)
+	htmlSummary := fmt.Sprintf("%s %s %s to <strong>%s</strong>.", htmlEscape(inviteeName), decision, htmlEscape(invite), htmlEscape(spaceName))
+	if decision == "accepted" && role != "" {
+		summary += fmt.Sprintf(" They joined as %s.", role)
+		htmlSummary += fmt.Sprintf(" They joined as <strong>%s</strong>.", htmlEscape(role))
+	}
+
+	text := fmt.Sprintf(`%s
+
+See the space's members:
+%s
+
+Open Beskar:
+%s
+`, summary, membersURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s</p>
+    <p><a href="%s">See the space's members</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlSummary,
+		htmlEscape(membersURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,80 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceInviteReminder = "space_invite_reminder"
+
+// SpaceInviteReminderTemplate nudges an invitee who hasn't answered a space
+// invite. expires_at is optional; invites without an expiry leave it out.
+type SpaceInviteReminderTemplate struct{}
+
+func (SpaceInviteReminderTemplate) Key() string {
+	return TemplateSpaceInviteReminder
+}
+
+func (SpaceInviteReminderTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"sender_name",
+		"role",
+		"accept_url",
+		"reject_url",
+		"app_url",
+	}
+}
+
+func (t SpaceInviteReminderTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	senderName := templateString(data, "sender_name")
+	role := templateString(data, "role")
+	acceptURL := templateString(data, "accept_url")
+	rejectURL := templateString(data, "reject_url")
+	appURL := templateString(data, "app_url")
+	expiresAt := optionalTemplateString(data, "expires_at")
+
+	subject := fmt.Sprintf("Reminder: %s invited you to %s", senderName, spaceName)
+	summary := fmt.Sprintf("%s invited you to join %s as %s, and the invitation is still waiting for you.", senderName, spaceName, role)
+	htmlSummary := fmt.Sprintf("%s invited you to join <strong>%s</strong> as <strong>%s</strong>, and the invitation is still waiting for you.",
+		htmlEscape(senderName), htmlEscape(spaceName), htmlEscape(role))
+	if expiresAt != "" {
+		summary += fmt.Sprintf(" It expires on %s.", expiresAt)
+		htmlSummary += fmt.Sprintf(" It expires on %s.", htmlEscape(expiresAt))
+	}
+
+	text := fmt.Sprintf(`%s
+
+Accept the invitation:
+%s
+
+Reject the invitation:
+%s
+
+Open Beskar:
+%s
+`, summary, acceptURL, rejectURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s</p>
+    <p><a href="%s">Accept invitation</a></p>
+    <p><a href="%s">Reject invitation</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlSummary,
+		htmlEscape(acceptURL),
+		htmlEscape(rejectURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
@@ -0,0 +1,74 @@
+package notification
+
+import "fmt"
+
+const TemplateSpaceMembershipExpiring = "space_membership_expiring"
+
+// SpaceMembershipExpiringTemplate warns that a time-limited membership is
+// about to end. With for_owner set it is worded for the space owner rather
+// than the member.
+type SpaceMembershipExpiringTemplate struct{}
+
+func (SpaceMembershipExpiringTemplate) Key() string {
+	return TemplateSpaceMembershipExpiring
+}
+
+func (SpaceMembershipExpiringTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"member_name",
+		"expires_at",
+		"space_url",
+		"app_url",
+	}
+}
+
+func (t SpaceMembershipExpiringTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	memberName := templateString(data, "member_name")
+	expiresAt := templateString(data, "expires_at")
+	spaceURL := templateString(data, "space_url")
+	appURL := templateString(data, "app_url")
+	forOwner, _ := data["for_owner"].(bool)
+
+	subject := fmt.Sprintf("Your access to %s ends soon", spaceName)
+	summary := fmt.Sprintf("Your access to %s ends on %s. Ask an admin of the space if you still need it.", spaceName, expiresAt)
+	htmlSummary := fmt.Sprintf("Your access to <strong>%s</strong> ends on %s. Ask an admin of the space if you still need it.", htmlEscape(spaceName), htmlEscape(expiresAt))
+	if forOwner {
+		subject = fmt.Sprintf("%s's access to %s ends soon", memberName, spaceName)
+		summary = fmt.Sprintf("%s's access to %s ends on %s. Extend it from the space's member settings if they still need it.", memberName, spaceName, expiresAt)
+		htmlSummary = fmt.Sprintf("%s's access to <strong>%s</strong> ends on %s. Extend it from the space's member settings if they still need it.", htmlEscape(memberName), htmlEscape(spaceName), htmlEscape(expiresAt))
+	}
+
+	text := fmt.Sprintf(`%s
+
+Open the space:
+%s
+
+Open Beskar:
+%s
+`, summary, spaceURL, appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>%s</p>
+    <p><a href="%s">Open the space</a></p>
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlSummary,
+		htmlEscape(spaceURL),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
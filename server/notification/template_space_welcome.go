@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+)
+
+const TemplateSpaceWelcome = "space_welcome"
+
+// SpaceWelcomeTemplate greets someone who just joined a space, with links to
+// its home page and, when given, its most read pages. pages is a list of
+// objects with a title and a url.
+type SpaceWelcomeTemplate struct{}
+
+func (SpaceWelcomeTemplate) Key() string {
+	return TemplateSpaceWelcome
+}
+
+func (SpaceWelcomeTemplate) RequiredFields() []string {
+	return []string{
+		"space_name",
+		"role",
+		"space_url",
+		"app_url",
+	}
+}
+
+func (t SpaceWelcomeTemplate) Render(data map[string]any) (RenderedEmail, error) {
+	if err := requireTemplateFields(data, t.RequiredFields()); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	spaceName := templateString(data, "space_name")
+	role := templateString(data, "role")
+	spaceURL := templateString(data, "space_url")
+	appURL := templateString(data, "app_url")
+	pages := templateLinks(data, "pages")
+
+	var textPages, htmlPages strings.Builder
+	if len(pages) > 0 {
+		textPages.WriteString("\nGood places to start:\n")
+		htmlPages.WriteString("\n    <p>Good places to start:</p>\n    <ul>\n")
+		for _, page := range pages {
+			fmt.Fprintf(&textPages, "- %s: %s\n", page.Title, page.URL)
+			fmt.Fprintf(&htmlPages, "      <li><a href=\"%s\">%s</a></li>\n", htmlEscape(page.URL), htmlEscape(page.Title))
+		}
+		htmlPages.WriteString("    </ul>")
+	}
+
+	subject := fmt.Sprintf("Welcome to %s", spaceName)
+	text := fmt.Sprintf(`You joined %s as %s.
+
+Open the space:
+%s
+%s
+Open Beskar:
+%s
+`, spaceName, role, spaceURL, textPages.String(), appURL)
+
+	htmlBody := fmt.Sprintf(`<!doctype html>
+<html>
+  <body>
+    <p>You joined <strong>%s</strong> as <strong>%s</strong>.</p>
+    <p><a href="%s">Open the space</a></p>%s
+    <p><a href="%s">Open Beskar</a></p>
+  </body>
+</html>`,
+		htmlEscape(spaceName),
+		htmlEscape(role),
+		htmlEscape(spaceURL),
+		htmlPages.String(),
+		htmlEscape(appURL),
+	)
+
+	rendered := RenderedEmail{Subject: subject, Text: text, HTML: htmlBody}
+	if err := validateRenderedEmail(rendered); err != nil {
+		return RenderedEmail{}, err
+	}
+	return rendered, nil
+}
+
+type templateLink struct {
+	Title string
+	URL   string
+}
+
+// templateLinks reads a list of {title, url} objects. Template data comes
+// back from the queue as decoded JSON, so both typed and decoded lists are
+// accepted; entries missing either field are skipped.
+func templateLinks(data map[string]any, field string) []templateLink {
+	var items []map[string]any
+	switch value := data[field].(type) {
+	case []map[string]any:
+		items = value
+	case []any:
+		for _, item := range value {
+			if entry, ok := item.(map[string]any); ok {
+				items = append(items, entry)
+			}
+		}
+	}
+	links := make([]templateLink, 0, len(items))
+	for _, item := range items {
+		title, _ := item["title"].(string)
+		url, _ := item["url"].(string)
+		if strings.TrimSpace(title) == "" || strings.TrimSpace(url) == "" {
+			continue
+		}
+		links = append(links, templateLink{Title: title, URL: url})
+	}
+	return links
+}
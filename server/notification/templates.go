@@ -26,6 +26,17 @@ type TemplateRegistry struct {
 func NewTemplateRegistry() *TemplateRegistry {
 	registry := &TemplateRegistry{templates: map[string]EmailTemplate{}}
 	registry.Register(SpaceInviteCreatedTemplate{})
+	registry.Register(SpaceAccessReviewTemplate{})
+	registry.Register(SpaceMembershipExpiringTemplate{})
+	registry.Register(SpaceAccessRequestedTemplate{})
+	registry.Register(SpaceAccessRequestDecidedTemplate{})
+	registry.Register(SpaceInviteReminderTemplate{})
+	registry.Register(SpaceInviteDecidedTemplate{})
+	registry.Register(SpaceWelcomeTemplate{})
+	registry.Register(CommentReplyTemplate{})
+	registry.Register(CommentMentionTemplate{})
+	registry.Register(PageUpdatedTemplate{})
+	registry.Register(NotificationDigestTemplate{})
 	return registry
 }
 
@@ -58,6 +69,15 @@ func templateString(data map[string]any, field string) string {
 	return strings.TrimSpace(fmt.Sprint(data[field]))
 }
 
+// optionalTemplateString is templateString for fields a template can do
+// without; a missing field is empty.
+func optionalTemplateString(data map[string]any, field string) string {
+	if data[field] == nil {
+		return ""
+	}
+	return templateString(data, field)
+}
+
 func htmlEscape(value string) string {
 	return html.EscapeString(value)
 }
@@ -1,6 +1,7 @@
 package notification
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -59,3 +60,268 @@ func TestTemplateRegistryUnknownTemplate(t *testing.T) {
 		t.Fatal("expected unknown template error")
 	}
 }
+
+func TestSpaceAccessReviewTemplateRenders(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceAccessReview, map[string]any{
+		"space_name":     "Roadmap",
+		"member_count":   12,
+		"invite_count":   2,
+		"inactive_count": 0,
+		"inactive_days":  90,
+		"external_count": 1,
+		"review_url":     "https://app.example.com/space/s/settings?review=r",
+		"app_url":        "https://app.example.com",
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "Members: 12") || !strings.Contains(rendered.Text, "Inactive for 90 days: 0") {
+		t.Fatalf("expected counts in the text body: %s", rendered.Text)
+	}
+}
+
+func TestSpaceMembershipExpiringTemplateAddressesOwner(t *testing.T) {
+	data := map[string]any{
+		"space_name":  "Roadmap",
+		"member_name": "Ada",
+		"expires_at":  "2 Jan 2026 15:04 UTC",
+		"space_url":   "https://app.example.com/space/s",
+		"app_url":     "https://app.example.com",
+	}
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceMembershipExpiring, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if rendered.Subject != "Your access to Roadmap ends soon" {
+		t.Fatalf("unexpected member subject: %s", rendered.Subject)
+	}
+	data["for_owner"] = true
+	rendered, err = NewTemplateRegistry().Render(TemplateSpaceMembershipExpiring, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "Ada's access to Roadmap ends on 2 Jan 2026 15:04 UTC") {
+		t.Fatalf("expected the owner wording in the text body: %s", rendered.Text)
+	}
+}
+
+func TestSpaceAccessRequestedTemplateQuotesMessage(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceAccessRequested, map[string]any{
+		"space_name":      "Roadmap",
+		"requester_name":  "Ada",
+		"requester_email": "ada@example.com",
+		"page_title":      "Q3 <plan>",
+		"message":         "Need it for the audit",
+		"request_url":     "https://app.example.com/space/s/settings/users?request=r",
+		"app_url":         "https://app.example.com",
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "Need it for the audit") {
+		t.Fatalf("expected the message in the text body: %s", rendered.Text)
+	}
+	if strings.Contains(rendered.HTML, "<plan>") {
+		t.Fatalf("expected the page title escaped in the html body: %s", rendered.HTML)
+	}
+}
+
+func TestSpaceAccessRequestDecidedTemplateRejectsUnknownDecision(t *testing.T) {
+	data := map[string]any{
+		"space_name": "Roadmap",
+		"decision":   "approved",
+		"role":       "editor",
+		"space_url":  "https://app.example.com/space/s",
+		"app_url":    "https://app.example.com",
+	}
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceAccessRequestDecided, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "You joined as editor.") {
+		t.Fatalf("expected the role in the text body: %s", rendered.Text)
+	}
+	data["decision"] = "maybe"
+	if _, err := NewTemplateRegistry().Render(TemplateSpaceAccessRequestDecided, data); err == nil {
+		t.Fatalf("expected an unknown decision to be rejected")
+	}
+}
+
+func TestSpaceInviteReminderTemplateMentionsExpiry(t *testing.T) {
+	data := map[string]any{
+		"space_name":  "Roadmap",
+		"sender_name": "Kiran",
+		"role":        "viewer",
+		"accept_url":  "https://app.example.com/invite/action?token=abc&decision=accept",
+		"reject_url":  "https://app.example.com/invite/action?token=abc&decision=reject",
+		"app_url":     "https://app.example.com",
+	}
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceInviteReminder, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if strings.Contains(rendered.Text, "expires") {
+		t.Fatalf("expected no expiry without expires_at: %s", rendered.Text)
+	}
+	data["expires_at"] = "2 Jan 2026 15:04 UTC"
+	rendered, err = NewTemplateRegistry().Render(TemplateSpaceInviteReminder, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "It expires on 2 Jan 2026 15:04 UTC.") {
+		t.Fatalf("expected the expiry in the text body: %s", rendered.Text)
+	}
+}
+
+func TestSpaceInviteDecidedTemplateAddressesAdmins(t *testing.T) {
+	data := map[string]any{
+		"space_name":   "Roadmap",
+		"invitee_name": "Ada",
+		"decision":     "accepted",
+		"role":         "editor",
+		"sender_name":  "Kiran",
+		"for_admin":    true,
+		"members_url":  "https://app.example.com/space/s/settings/users",
+		"app_url":      "https://app.example.com",
+	}
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceInviteDecided, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "Ada accepted Kiran's invite to Roadmap. They joined as editor.") {
+		t.Fatalf("expected the admin wording in the text body: %s", rendered.Text)
+	}
+	data["decision"] = "ignored"
+	if _, err := NewTemplateRegistry().Render(TemplateSpaceInviteDecided, data); err == nil {
+		t.Fatalf("expected an unknown decision to be rejected")
+	}
+}
+
+func TestSpaceWelcomeTemplateListsQueuedPages(t *testing.T) {
+	// pages come back from the queue as decoded JSON
+	var pages []any
+	if err := json.Unmarshal([]byte(`[{"title": "Start <here>", "url": "https://app.example.com/space/s/view/1"}, {"title": ""}]`), &pages); err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceWelcome, map[string]any{
+		"space_name": "Roadmap",
+		"role":       "viewer",
+		"space_url":  "https://app.example.com/space/s",
+		"app_url":    "https://app.example.com",
+		"pages":      pages,
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if !strings.Contains(rendered.Text, "- Start <here>: https://app.example.com/space/s/view/1") {
+		t.Fatalf("expected the page in the text body: %s", rendered.Text)
+	}
+	if strings.Contains(rendered.HTML, "<here>") || strings.Count(rendered.HTML, "<li>") != 1 {
+		t.Fatalf("expected one escaped page in the html body: %s", rendered.HTML)
+	}
+}
+
+func TestOptionalTemplateStringSkipsMissingFields(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateSpaceAccessRequested, map[string]any{
+		"space_name":      "Roadmap",
+		"requester_name":  "Ada",
+		"requester_email": "ada@example.com",
+		"request_url":     "https://app.example.com/space/s/settings/users?request=r",
+		"app_url":         "https://app.example.com",
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if strings.Contains(rendered.Text, "<nil>") || strings.Contains(rendered.Text, "They wrote") {
+		t.Fatalf("expected missing optional fields to be left out: %s", rendered.Text)
+	}
+}
+
+func TestCommentReplyTemplateEscapesReply(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateCommentReply, map[string]any{
+		"author_name": "Ada",
+		"page_title":  "Roadmap",
+		"reply":       "Looks good <b>to me</b>",
+		"page_url":    "https://app.example.com/space/s/view/1",
+		"app_url":     "https://app.example.com",
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if rendered.Subject != "Ada replied on Roadmap" {
+		t.Fatalf("unexpected subject %q", rendered.Subject)
+	}
+	if strings.Contains(rendered.HTML, "<b>") {
+		t.Fatalf("expected the reply to be escaped: %s", rendered.HTML)
+	}
+}
+
+func TestCommentMentionTemplateEscapesComment(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateCommentMention, map[string]any{
+		"author_name": "Ada",
+		"page_title":  "Roadmap",
+		"comment":     "@Kiran can you check <b>this</b>?",
+		"page_url":    "https://app.example.com/space/s/view/1",
+		"app_url":     "https://app.example.com",
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if rendered.Subject != "Ada mentioned you on Roadmap" {
+		t.Fatalf("unexpected subject %q", rendered.Subject)
+	}
+	if strings.Contains(rendered.HTML, "<b>") {
+		t.Fatalf("expected the comment to be escaped: %s", rendered.HTML)
+	}
+}
+
+func TestPageUpdatedTemplateRequiresEditor(t *testing.T) {
+	data := map[string]any{
+		"page_title": "Roadmap",
+		"page_url":   "https://app.example.com/space/s/view/1",
+		"app_url":    "https://app.example.com",
+	}
+	if _, err := NewTemplateRegistry().Render(TemplatePageUpdated, data); err == nil {
+		t.Fatalf("expected a missing editor name to be rejected")
+	}
+	data["editor_name"] = "Ada"
+	rendered, err := NewTemplateRegistry().Render(TemplatePageUpdated, data)
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if rendered.Subject != "Ada updated Roadmap" {
+		t.Fatalf("unexpected subject %q", rendered.Subject)
+	}
+}
+
+func TestNotificationDigestTemplateListsItems(t *testing.T) {
+	rendered, err := NewTemplateRegistry().Render(TemplateNotificationDigest, map[string]any{
+		"app_url": "https://app.example.com/",
+		"items": []any{
+			map[string]any{"title": "Ada replied on <Roadmap>", "url": "https://app.example.com/space/s/view/1"},
+			map[string]any{"title": "Kiran invited you to Docs", "url": "https://app.example.com/space/d"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected render to succeed: %v", err)
+	}
+	if rendered.Subject != "Your Beskar digest: 2 notifications" {
+		t.Fatalf("unexpected subject %q", rendered.Subject)
+	}
+	if strings.Contains(rendered.HTML, "<Roadmap>") || strings.Count(rendered.HTML, "<li>") != 2 {
+		t.Fatalf("expected two escaped items in the html body: %s", rendered.HTML)
+	}
+	if _, err := NewTemplateRegistry().Render(TemplateNotificationDigest, map[string]any{"app_url": "https://app.example.com/"}); err == nil {
+		t.Fatalf("expected a digest without items to be rejected")
+	}
+}
+
+func TestDigestLinkPrefersMostSpecificURL(t *testing.T) {
+	data := map[string]any{"space_url": "https://app.example.com/space/s", "page_url": "https://app.example.com/space/s/view/1"}
+	if got := digestLink(data, "https://app.example.com/"); got != "https://app.example.com/space/s/view/1" {
+		t.Fatalf("expected the page link, got %q", got)
+	}
+	if got := digestLink(map[string]any{"accept_url": "https://app.example.com/accept"}, "https://app.example.com/"); got != "https://app.example.com/" {
+		t.Fatalf("expected the app link for an invite, got %q", got)
+	}
+}
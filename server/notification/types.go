@@ -16,10 +16,119 @@ const (
 	StatusDeadLettered = "dead_lettered"
 	StatusSuppressed   = "suppressed"
 	StatusSkipped      = "skipped"
+	// StatusHeld waits for the recipient's daily digest; StatusDigested was
+	// sent as part of one.
+	StatusHeld     = "held"
+	StatusDigested = "digested"
 
 	PriorityNormal = "normal"
 )
 
+// Channels a notification category is delivered on.
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+	ChannelBoth  = "both"
+)
+
+// How often emails of a category are sent: one by one, or gathered into a
+// daily digest.
+const (
+	FrequencyInstant = "instant"
+	FrequencyDaily   = "daily"
+)
+
+// AllCategories stands for every category in a space preference.
+const AllCategories = "all"
+
+// CategoryDigest is the daily digest itself. It is not a category users
+// choose delivery for, and is never held for a digest.
+const CategoryDigest = "digest"
+
+// Categories users choose delivery for. Producers pass one of these as the
+// category of every notification they send.
+const (
+	CategorySpaceInvite     = "space_invite"
+	CategoryInviteDecision  = "space_invite_decision"
+	CategoryAccessRequest   = "access_request"
+	CategoryAccessReview    = "access_review"
+	CategorySpaceMembership = "space_membership"
+	CategoryCommentReply    = "comment_reply"
+	CategoryMention         = "mention"
+	CategoryPageUpdate      = "page_update"
+)
+
+var Categories = []string{
+	CategorySpaceInvite,
+	CategoryInviteDecision,
+	CategoryAccessRequest,
+	CategoryAccessReview,
+	CategorySpaceMembership,
+	CategoryCommentReply,
+	CategoryMention,
+	CategoryPageUpdate,
+}
+
+// Delivery is where a user wants notifications of one category delivered.
+type Delivery struct {
+	InApp bool
+	Email bool
+}
+
+type CategoryPreference struct {
+	Category  string `json:"category"`
+	Enabled   bool   `json:"enabled"`
+	Channel   string `json:"channel"`
+	Frequency string `json:"frequency"`
+}
+
+// CategoryUpdate changes the fields that are set and keeps the others.
+type CategoryUpdate struct {
+	Enabled   *bool   `json:"enabled"`
+	Channel   *string `json:"channel"`
+	Frequency *string `json:"frequency"`
+}
+
+// SpacePreference overrides a category, or every category, within one
+// space. A nil Channel keeps the category's channel.
+type SpacePreference struct {
+	SpaceId  uuid.UUID `json:"spaceId"`
+	Category string    `json:"category"`
+	Enabled  bool      `json:"enabled"`
+	Channel  *string   `json:"channel"`
+}
+
+// Schedule is when the user receives emails, in their timezone. Times are
+// HH:MM; emails due in quiet hours are sent when they end.
+type Schedule struct {
+	Timezone        string  `json:"timezone"`
+	QuietHoursStart *string `json:"quietHoursStart"`
+	QuietHoursEnd   *string `json:"quietHoursEnd"`
+	DigestTime      string  `json:"digestTime"`
+}
+
+type Preferences struct {
+	Categories []CategoryPreference `json:"categories"`
+	Spaces     []SpacePreference    `json:"spaces"`
+	Schedule   Schedule             `json:"schedule"`
+}
+
+// preference is what applies to one notification once space overrides are
+// taken into account.
+type preference struct {
+	Delivery
+	Frequency string
+}
+
+// deliverySettings is the stored form of a Schedule, in minutes after
+// midnight.
+type deliverySettings struct {
+	Timezone        string
+	QuietHoursStart *int16
+	QuietHoursEnd   *int16
+	DigestMinute    int16
+}
+
 type EmailRecipient struct {
 	UserID *uuid.UUID
 	Email  string
@@ -34,6 +143,9 @@ type EnqueueEmailRequest struct {
 	TemplateData map[string]any
 	Priority     string
 	ScheduledAt  *time.Time
+	// SpaceID is the space the email is about, if any, for the recipient's
+	// per-space preferences.
+	SpaceID *uuid.UUID
 }
 
 type EmailEngine interface {
@@ -68,6 +180,8 @@ type EmailMessage struct {
 	LastErrorMessage  *string
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+
+	SpaceID *uuid.UUID
 }
 
 type EmailDeliveryAttempt struct {
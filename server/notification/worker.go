@@ -3,7 +3,6 @@ package notification
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"time"
 
 	"github.com/durgakiran/beskar/core"
@@ -59,6 +58,7 @@ func (w *Worker) Start(ctx context.Context) {
 			return
 		default:
 			w.ProcessBatch(ctx)
+			w.SendDigests(ctx)
 		}
 
 		select {
@@ -129,13 +129,25 @@ func (w *Worker) processMessage(ctx context.Context, message EmailMessage) error
 		return w.markSuppressed(ctx, message.ID, "email_suppressed", "recipient email is suppressed")
 	}
 
-	enabled, err := w.preferenceEnabled(ctx, message)
+	preference, err := w.preference(ctx, message)
 	if err != nil {
 		return err
 	}
-	if !enabled {
+	if !preference.Email {
 		return w.markSuppressed(ctx, message.ID, "preference_disabled", "recipient email preference is disabled")
 	}
+	if preference.Frequency == FrequencyDaily && message.Category != CategoryDigest {
+		return w.hold(ctx, message.ID)
+	}
+	if message.RecipientUserID != nil {
+		settings, err := loadDeliverySettings(ctx, w.pool, *message.RecipientUserID)
+		if err != nil {
+			return err
+		}
+		if until, quiet := settings.quietUntil(time.Now()); quiet {
+			return w.deferUntil(ctx, message.ID, until)
+		}
+	}
 
 	rendered, err := w.templates.Render(message.TemplateKey, message.TemplateData)
 	if err != nil {
@@ -218,6 +230,7 @@ func scanEmailMessage(row pgx.Row) (EmailMessage, error) {
 		&message.LastErrorMessage,
 		&message.CreatedAt,
 		&message.UpdatedAt,
+		&message.SpaceID,
 	)
 	if err != nil {
 		return message, err
@@ -237,16 +250,26 @@ func (w *Worker) isSuppressed(ctx context.Context, email string) (bool, error) {
 	return suppressed, err
 }
 
-func (w *Worker) preferenceEnabled(ctx context.Context, message EmailMessage) (bool, error) {
+// preference is what the recipient wants for the message's category and
+// space. Emails to addresses without an account are always sent right away.
+func (w *Worker) preference(ctx context.Context, message EmailMessage) (preference, error) {
 	if message.RecipientUserID == nil {
-		return true, nil
-	}
-	var enabled bool
-	err := w.pool.QueryRow(ctx, checkEmailPreference, *message.RecipientUserID, message.Category).Scan(&enabled)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return true, nil
+		return preference{Delivery: Delivery{InApp: true, Email: true}, Frequency: FrequencyInstant}, nil
 	}
-	return enabled, err
+	return loadPreference(ctx, w.pool, *message.RecipientUserID, message.Category, message.SpaceID)
+}
+
+// deferUntil puts a message back in the queue for later, without counting
+// an attempt.
+func (w *Worker) deferUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	_, err := w.pool.Exec(ctx, deferEmailMessage, id, until)
+	return err
+}
+
+// hold keeps a message for the recipient's next digest.
+func (w *Worker) hold(ctx context.Context, id uuid.UUID) error {
+	_, err := w.pool.Exec(ctx, holdEmailMessage, id)
+	return err
 }
 
 func (w *Worker) updateRendered(ctx context.Context, id uuid.UUID, rendered RenderedEmail) error {
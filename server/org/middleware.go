@@ -0,0 +1,61 @@
+package org
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+// Scoped resolves the organization a request acts in and scopes its context,
+// and with it every Permify call, to that organization's tenant. An explicit
+// HEADER_ORGANIZATION must name an organization the caller belongs to.
+// Callers with no membership at all act in the default organization, where
+// they only see what they have been granted directly. Requests made with a
+// personal access token are pinned to the organization it was issued in.
+// Unauthenticated requests pass through untouched for the router to reject.
+func Scoped(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := core.GetUserInfo(r.Context())
+		if err != nil || user.AId == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := uuid.Parse(user.AId)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		requested := uuid.Nil
+		if header := strings.TrimSpace(r.Header.Get(HEADER_ORGANIZATION)); header != "" {
+			requested, err = uuid.Parse(header)
+			if err != nil {
+				core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+				return
+			}
+		}
+		token, pinned := core.AccessTokenFromContext(r.Context())
+		if pinned {
+			if requested != uuid.Nil && requested != token.OrgId {
+				core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return
+			}
+			requested = token.OrgId
+		}
+		member, err := membershipFor(r.Context(), userID, requested)
+		if err != nil {
+			if err.Error() != core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+				core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if requested != uuid.Nil && !(pinned && requested == core.DefaultOrganization) {
+				core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return
+			}
+			member = membership{OrgId: core.DefaultOrganization, TenantId: core.DefaultTenant}
+		}
+		ctx := core.WithOrganization(r.Context(), member.OrgId, member.TenantId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,300 @@
+package org
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// organizationFromRequest parses the organization id and loads it with the
+// caller's role, writing the failure response itself. With manage set the
+// caller must be an owner or admin.
+func organizationFromRequest(w http.ResponseWriter, r *http.Request, userID uuid.UUID, manage bool) (Organization, bool) {
+	orgID, err := uuid.Parse(chi.URLParam(r, "orgId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return Organization{}, false
+	}
+	// tokens are issued within one organization and cannot reach others
+	if token, ok := core.AccessTokenFromContext(r.Context()); ok && token.OrgId != orgID {
+		core.SendFailedReponse(w, r, http.StatusNotFound, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+		return Organization{}, false
+	}
+	organization, err := getOrganization(r.Context(), orgID, userID)
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+			return Organization{}, false
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return Organization{}, false
+	}
+	if manage && !canManage(organization.Role) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return Organization{}, false
+	}
+	return organization, true
+}
+
+func sendMemberError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err.Error() {
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT]:
+		core.SendFailedReponse(w, r, http.StatusConflict, err.Error())
+	default:
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func listOrganizationsController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organizations, err := listOrganizations(r.Context(), userID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, organizations)
+}
+
+func createOrganizationController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateCreateOrganization(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	organization, err := createOrganization(r.Context(), userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionOrgCreated,
+		TargetType: "organization",
+		TargetId:   organization.Id.String(),
+		After:      map[string]string{"name": organization.Name},
+	})
+	core.SendSuccessResponse(w, r, http.StatusCreated, organization)
+}
+
+func getOrganizationController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, organization)
+}
+
+func updateOrganizationController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateUpdateOrganization(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	before, after, err := updateOrganization(r.Context(), organization.Id, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionOrgUpdated,
+		TargetType: "organization",
+		TargetId:   organization.Id.String(),
+		Before:     map[string]string{"name": before.Name},
+		After:      map[string]string{"name": after.Name},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, after)
+}
+
+func listMembersController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+	members, err := listMembers(r.Context(), organization.Id)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, members)
+}
+
+func addMembersController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateAddMembers(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	added, err := addMembers(r.Context(), organization.Id, organization.Role, req.Members)
+	for _, item := range added {
+		audit.Record(r, audit.Entry{
+			Action:     audit.ActionOrgMemberAdded,
+			TargetType: "organization",
+			TargetId:   organization.Id.String(),
+			After:      map[string]string{"userId": item.UserId, "role": item.Role},
+		})
+	}
+	if err != nil {
+		sendMemberError(w, r, err)
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]any{"added": added})
+}
+
+func changeMemberRoleController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateChangeMemberRole(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	previous, err := changeMemberRole(r.Context(), organization.Id, organization.Role, req)
+	if err != nil {
+		sendMemberError(w, r, err)
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionOrgMemberRoleChanged,
+		TargetType: "organization",
+		TargetId:   organization.Id.String(),
+		Before:     map[string]string{"userId": req.UserId, "role": previous},
+		After:      map[string]string{"userId": req.UserId, "role": req.Role},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, req)
+}
+
+func removeMemberController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	organization, ok := organizationFromRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	previous, err := removeMember(r.Context(), organization.Id, organization.Role, memberID)
+	if err != nil {
+		sendMemberError(w, r, err)
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionOrgMemberRemoved,
+		TargetType: "organization",
+		TargetId:   organization.Id.String(),
+		Before:     map[string]string{"userId": memberID.String(), "role": previous},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]string{"userId": memberID.String()})
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+	r.Use(core.RequireTokenScope(core.TOKEN_SCOPE_ADMIN))
+	r.Get("/list", listOrganizationsController)
+	r.Post("/create", createOrganizationController)
+	r.Get("/{orgId}", getOrganizationController)
+	r.Put("/{orgId}", updateOrganizationController)
+	r.Get("/{orgId}/members", listMembersController)
+	r.Post("/{orgId}/members/add", addMembersController)
+	r.Put("/{orgId}/members/role", changeMemberRoleController)
+	r.Delete("/{orgId}/members/{userId}", removeMemberController)
+	return r
+}
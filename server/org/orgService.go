@@ -0,0 +1,290 @@
+package org
+
+import (
+	"context"
+	"errors"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func canManage(role string) bool {
+	return role == ROLE_OWNER || role == ROLE_ADMIN
+}
+
+// createOrganization stores the organization with the actor as its owner and
+// provisions a Permify tenant for it. The tenant id is the organization id.
+func createOrganization(ctx context.Context, actorId uuid.UUID, req CreateOrganizationRequest) (Organization, error) {
+	orgId := uuid.New()
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, insertOrganization, orgId, req.Name, orgId.String(), actorId)
+	if err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	organization, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Organization])
+	if err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if _, err := tx.Exec(ctx, insertMember, orgId, actorId, ROLE_OWNER); err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if err := core.CreateTenant(ctx, organization.TenantId, organization.Name); err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return organization, nil
+}
+
+func listOrganizations(ctx context.Context, userId uuid.UUID) ([]Organization, error) {
+	organizations := make([]Organization, 0)
+	rows, err := core.GetPool().Query(ctx, listUserOrganizations, userId)
+	if err != nil {
+		logger().Error(err.Error())
+		return organizations, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	organizations, err = pgx.CollectRows(rows, pgx.RowToStructByName[Organization])
+	if err != nil {
+		logger().Error(err.Error())
+		return organizations, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return organizations, nil
+}
+
+// getOrganization returns the organization with the caller's role in it, or
+// NO_DATA when it does not exist or the caller is not a member.
+func getOrganization(ctx context.Context, orgId uuid.UUID, userId uuid.UUID) (Organization, error) {
+	rows, err := core.GetPool().Query(ctx, selectOrganization, orgId, userId)
+	if err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	organization, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Organization])
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && organization.Role == "") {
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return organization, nil
+}
+
+// updateOrganization returns the organization before and after the change.
+func updateOrganization(ctx context.Context, orgId uuid.UUID, userId uuid.UUID, req UpdateOrganizationRequest) (Organization, Organization, error) {
+	before, err := getOrganization(ctx, orgId, userId)
+	if err != nil {
+		return Organization{}, Organization{}, err
+	}
+	rows, err := core.GetPool().Query(ctx, updateOrganizationName, orgId, req.Name)
+	if err != nil {
+		logger().Error(err.Error())
+		return before, Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	after, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Organization])
+	if err != nil {
+		logger().Error(err.Error())
+		return before, Organization{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	after.Role = before.Role
+	return before, after, nil
+}
+
+func listMembers(ctx context.Context, orgId uuid.UUID) ([]Member, error) {
+	members := make([]Member, 0)
+	rows, err := core.GetPool().Query(ctx, getMembers, orgId)
+	if err != nil {
+		logger().Error(err.Error())
+		return members, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	members, err = pgx.CollectRows(rows, pgx.RowToStructByName[Member])
+	if err != nil {
+		logger().Error(err.Error())
+		return members, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	userIds := make([]string, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId.String())
+	}
+	profiles, err := core.GetUserProfiles(userIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return members, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	for i := range members {
+		profile := profiles[members[i].UserId.String()]
+		members[i].Name = profile.Name
+		members[i].Email = profile.Email
+		if members[i].Name == "" {
+			members[i].Name = profile.Email
+		}
+	}
+	return members, nil
+}
+
+// addMembers returns the members that were not already in the organization.
+// Only owners may add other owners.
+func addMembers(ctx context.Context, orgId uuid.UUID, actorRole string, items []AddMemberItem) ([]AddMemberItem, error) {
+	added := make([]AddMemberItem, 0, len(items))
+	for _, item := range items {
+		if item.Role == ROLE_OWNER && actorRole != ROLE_OWNER {
+			return added, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		}
+		tag, err := core.GetPool().Exec(ctx, insertMember, orgId, uuid.MustParse(item.UserId), item.Role)
+		if err != nil {
+			logger().Error(err.Error())
+			return added, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		if tag.RowsAffected() > 0 {
+			added = append(added, item)
+		}
+	}
+	return added, nil
+}
+
+// changeMemberRole returns the member's previous role. The last owner cannot
+// be demoted, and only owners may promote or demote owners.
+func changeMemberRole(ctx context.Context, orgId uuid.UUID, actorRole string, req ChangeMemberRoleRequest) (string, error) {
+	userId := uuid.MustParse(req.UserId)
+	current, err := membershipFor(ctx, userId, orgId)
+	if err != nil {
+		return "", err
+	}
+	if (current.Role == ROLE_OWNER || req.Role == ROLE_OWNER) && actorRole != ROLE_OWNER {
+		return current.Role, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	if current.Role == ROLE_OWNER && req.Role != ROLE_OWNER {
+		if err := ensureAnotherOwner(ctx, orgId); err != nil {
+			return current.Role, err
+		}
+	}
+	if _, err := core.GetPool().Exec(ctx, updateMemberRole, orgId, userId, req.Role); err != nil {
+		logger().Error(err.Error())
+		return current.Role, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	return current.Role, nil
+}
+
+// removeMember returns the removed member's role. Space grants inside the
+// organization's tenant are left alone; losing membership is enough to lose
+// access because every request is scoped through Scoped.
+func removeMember(ctx context.Context, orgId uuid.UUID, actorRole string, userId uuid.UUID) (string, error) {
+	current, err := membershipFor(ctx, userId, orgId)
+	if err != nil {
+		return "", err
+	}
+	if current.Role == ROLE_OWNER {
+		if actorRole != ROLE_OWNER {
+			return current.Role, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		}
+		if err := ensureAnotherOwner(ctx, orgId); err != nil {
+			return current.Role, err
+		}
+	}
+	if _, err := core.GetPool().Exec(ctx, deleteMember, orgId, userId); err != nil {
+		logger().Error(err.Error())
+		return current.Role, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	return current.Role, nil
+}
+
+func ensureAnotherOwner(ctx context.Context, orgId uuid.UUID) error {
+	var owners int
+	if err := core.GetPool().QueryRow(ctx, countOwners, orgId).Scan(&owners); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	if owners < 2 {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return nil
+}
+
+// membershipFor returns the user's membership in orgId, or their earliest
+// membership when orgId is uuid.Nil.
+func membershipFor(ctx context.Context, userId uuid.UUID, orgId uuid.UUID) (membership, error) {
+	rows, err := core.GetPool().Query(ctx, getMembership, userId, orgId)
+	if err != nil {
+		logger().Error(err.Error())
+		return membership{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	member, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[membership])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return membership{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return membership{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return member, nil
+}
+
+// MemberIds returns which of userIds belong to the organization.
+func MemberIds(ctx context.Context, orgId uuid.UUID, userIds []string) (map[string]bool, error) {
+	members := make(map[string]bool, len(userIds))
+	if len(userIds) == 0 {
+		return members, nil
+	}
+	rows, err := core.GetPool().Query(ctx, getMemberIds, orgId, userIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return members, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		logger().Error(err.Error())
+		return members, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for _, id := range ids {
+		members[id.String()] = true
+	}
+	return members, nil
+}
+
+// Join adds the user to the organization as a member if they are not in it yet.
+func Join(ctx context.Context, orgId uuid.UUID, userId uuid.UUID) error {
+	if _, err := core.GetPool().Exec(ctx, insertMember, orgId, userId, ROLE_MEMBER); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return nil
+}
+
+// JoinTx is Join as part of tx, for callers that give the user something in
+// the organization in the same transaction.
+func JoinTx(ctx context.Context, tx pgx.Tx, orgId uuid.UUID, userId uuid.UUID) error {
+	if _, err := tx.Exec(ctx, insertMember, orgId, userId, ROLE_MEMBER); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return nil
+}
+
+// WithSpaceTenant scopes ctx to the organization that owns the space, for
+// flows such as accepting an invite where the caller may not be acting in
+// that organization yet.
+func WithSpaceTenant(ctx context.Context, spaceId uuid.UUID) (context.Context, uuid.UUID, error) {
+	var orgId uuid.UUID
+	var tenantId string
+	err := core.GetPool().QueryRow(ctx, getSpaceOrganization, spaceId).Scan(&orgId, &tenantId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ctx, uuid.Nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return ctx, uuid.Nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	return core.WithOrganization(ctx, orgId, tenantId), orgId, nil
+}
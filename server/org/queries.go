@@ -0,0 +1,55 @@
+package org
+
+const (
+	insertOrganization = `INSERT INTO core.organizations (id, name, tenant_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, tenant_id, created_by, created_at, updated_at, 1 AS member_count, 'owner' AS role`
+
+	updateOrganizationName = `UPDATE core.organizations o
+		SET name = $2, updated_at = now()
+		WHERE o.id = $1
+		RETURNING o.id, o.name, o.tenant_id, o.created_by, o.created_at, o.updated_at,
+			(SELECT count(*) FROM core.organization_members m WHERE m.org_id = o.id)::int AS member_count,
+			'' AS role`
+
+	selectOrganization = `SELECT o.id, o.name, o.tenant_id, o.created_by, o.created_at, o.updated_at,
+			(SELECT count(*) FROM core.organization_members c WHERE c.org_id = o.id)::int AS member_count,
+			COALESCE(m.role, '') AS role
+		FROM core.organizations o LEFT JOIN core.organization_members m ON (m.org_id = o.id AND m.user_id = $2)
+		WHERE o.id = $1`
+
+	listUserOrganizations = `SELECT o.id, o.name, o.tenant_id, o.created_by, o.created_at, o.updated_at,
+			(SELECT count(*) FROM core.organization_members c WHERE c.org_id = o.id)::int AS member_count,
+			m.role
+		FROM core.organizations o INNER JOIN core.organization_members m ON (m.org_id = o.id)
+		WHERE m.user_id = $1
+		ORDER BY m.joined_at, o.id`
+
+	// $2 = uuid.Nil picks the earliest membership
+	getMembership = `SELECT m.org_id, o.tenant_id, m.role
+		FROM core.organization_members m INNER JOIN core.organizations o ON (o.id = m.org_id)
+		WHERE m.user_id = $1 AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR m.org_id = $2)
+		ORDER BY m.joined_at, m.org_id
+		LIMIT 1`
+
+	getMembers = `SELECT user_id, role, joined_at
+		FROM core.organization_members
+		WHERE org_id = $1
+		ORDER BY joined_at, user_id`
+
+	getMemberIds = `SELECT user_id FROM core.organization_members WHERE org_id = $1 AND user_id = ANY($2)`
+
+	insertMember = `INSERT INTO core.organization_members (org_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	updateMemberRole = `UPDATE core.organization_members SET role = $3 WHERE org_id = $1 AND user_id = $2`
+
+	deleteMember = `DELETE FROM core.organization_members WHERE org_id = $1 AND user_id = $2`
+
+	countOwners = `SELECT count(*) FROM core.organization_members WHERE org_id = $1 AND role = 'owner'`
+
+	getSpaceOrganization = `SELECT o.id, o.tenant_id
+		FROM core.space s INNER JOIN core.organizations o ON (o.id = s.org_id)
+		WHERE s.id = $1`
+)
@@ -0,0 +1,64 @@
+package org
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ROLE_OWNER  = "owner"
+	ROLE_ADMIN  = "admin"
+	ROLE_MEMBER = "member"
+)
+
+// HEADER_ORGANIZATION selects the organization a request acts in. Without it
+// the caller's earliest membership is used.
+const HEADER_ORGANIZATION = "X-Beskar-Org"
+
+type Organization struct {
+	Id          uuid.UUID  `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	TenantId    string     `json:"-" db:"tenant_id"`
+	CreatedBy   *uuid.UUID `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updatedAt" db:"updated_at"`
+	MemberCount int        `json:"memberCount" db:"member_count"`
+	Role        string     `json:"role" db:"role"`
+}
+
+type Member struct {
+	UserId   uuid.UUID `json:"userId" db:"user_id"`
+	Role     string    `json:"role" db:"role"`
+	JoinedAt time.Time `json:"joinedAt" db:"joined_at"`
+	Name     string    `json:"name" db:"-"`
+	Email    string    `json:"email" db:"-"`
+}
+
+type membership struct {
+	OrgId    uuid.UUID `db:"org_id"`
+	TenantId string    `db:"tenant_id"`
+	Role     string    `db:"role"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type AddMemberItem struct {
+	UserId string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+type AddMembersRequest struct {
+	Members []AddMemberItem `json:"members"`
+}
+
+type ChangeMemberRoleRequest struct {
+	UserId string `json:"userId"`
+	Role   string `json:"role"`
+}
@@ -0,0 +1,118 @@
+package org
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+const (
+	maxOrganizationNameLength = 120
+	maxMembersPerAdd          = 200
+)
+
+func validateOrganizationName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(name) > maxOrganizationNameLength {
+		return name, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return name, nil
+}
+
+func normalizeOrgRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		return ROLE_MEMBER, nil
+	}
+	switch role {
+	case ROLE_OWNER, ROLE_ADMIN, ROLE_MEMBER:
+		return role, nil
+	}
+	return role, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+}
+
+func validateUserId(userId string) (string, error) {
+	id, err := uuid.Parse(strings.TrimSpace(userId))
+	if err != nil {
+		return userId, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return id.String(), nil
+}
+
+func validateCreateOrganization(data []byte) (CreateOrganizationRequest, error) {
+	var req CreateOrganizationRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	name, err := validateOrganizationName(req.Name)
+	if err != nil {
+		return req, err
+	}
+	req.Name = name
+	return req, nil
+}
+
+func validateUpdateOrganization(data []byte) (UpdateOrganizationRequest, error) {
+	var req UpdateOrganizationRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	name, err := validateOrganizationName(req.Name)
+	if err != nil {
+		return req, err
+	}
+	req.Name = name
+	return req, nil
+}
+
+func validateAddMembers(data []byte) (AddMembersRequest, error) {
+	var req AddMembersRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	if len(req.Members) == 0 {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(req.Members) > maxMembersPerAdd {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	for i := range req.Members {
+		userId, err := validateUserId(req.Members[i].UserId)
+		if err != nil {
+			return req, err
+		}
+		role, err := normalizeOrgRole(req.Members[i].Role)
+		if err != nil {
+			return req, err
+		}
+		req.Members[i] = AddMemberItem{UserId: userId, Role: role}
+	}
+	return req, nil
+}
+
+func validateChangeMemberRole(data []byte) (ChangeMemberRoleRequest, error) {
+	var req ChangeMemberRoleRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	userId, err := validateUserId(req.UserId)
+	if err != nil {
+		return req, err
+	}
+	if strings.TrimSpace(req.Role) == "" {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	role, err := normalizeOrgRole(req.Role)
+	if err != nil {
+		return req, err
+	}
+	req.UserId = userId
+	req.Role = role
+	return req, nil
+}
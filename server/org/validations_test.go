@@ -0,0 +1,50 @@
+package org
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateCreateOrganizationTrimsName(t *testing.T) {
+	req, err := validateCreateOrganization([]byte(`{"name":"  Research  "}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Name != "Research" {
+		t.Fatalf("expected trimmed name, got %q", req.Name)
+	}
+}
+
+func TestValidateCreateOrganizationRejectsLongName(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", maxOrganizationNameLength+1) + `"}`
+	if _, err := validateCreateOrganization([]byte(body)); err == nil {
+		t.Fatalf("expected overly long name to be rejected")
+	}
+}
+
+func TestValidateAddMembersDefaultsToMember(t *testing.T) {
+	id := uuid.New()
+	req, err := validateAddMembers([]byte(`{"members":[{"userId":"` + id.String() + `"},{"userId":"` + id.String() + `","role":" Admin "}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Members[0].Role != ROLE_MEMBER || req.Members[1].Role != ROLE_ADMIN {
+		t.Fatalf("unexpected roles: %+v", req.Members)
+	}
+}
+
+func TestValidateAddMembersRejectsUnknownRole(t *testing.T) {
+	body := `{"members":[{"userId":"` + uuid.NewString() + `","role":"guest"}]}`
+	if _, err := validateAddMembers([]byte(body)); err == nil {
+		t.Fatalf("expected unknown role to be rejected")
+	}
+}
+
+func TestValidateChangeMemberRoleRequiresRole(t *testing.T) {
+	body := `{"userId":"` + uuid.NewString() + `"}`
+	if _, err := validateChangeMemberRole([]byte(body)); err == nil {
+		t.Fatalf("expected missing role to be rejected")
+	}
+}
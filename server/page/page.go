@@ -1,13 +1,28 @@
 package page
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
+	"github.com/durgakiran/beskar/audit"
 	"github.com/durgakiran/beskar/core"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userId, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userId, true
+}
+
 func getBreadCrumbs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	user, err := core.GetUserInfo(ctx)
@@ -25,7 +40,12 @@ func getBreadCrumbs(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, "Unable to get document")
 		return
 	}
-	breadCrumbs, err := getPageBreadCrumbs(page)
+	userId, err := uuid.Parse(user.AId)
+	if err != nil || !core.ValidateUserPagePermission(r.Context(), pageId, userId, core.PAGE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	breadCrumbs, err := GetVisibleBreadCrumbs(r.Context(), page, userId)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 		return
@@ -33,8 +53,89 @@ func getBreadCrumbs(w http.ResponseWriter, r *http.Request) {
 	core.SendSuccessResponse(w, r, http.StatusOK, breadCrumbs)
 }
 
+func getRestrictions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	pageId, err := strconv.ParseInt(chi.URLParam(r, "pageId"), 10, 64)
+	if err != nil || pageId <= 0 {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserPagePermission(r.Context(), strconv.FormatInt(pageId, 10), userId, core.PAGE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	restrictions, err := GetPageRestrictions(r.Context(), pageId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, restrictions)
+}
+
+func updateRestrictions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	pageId, err := strconv.ParseInt(chi.URLParam(r, "pageId"), 10, 64)
+	if err != nil || pageId <= 0 {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserPagePermission(r.Context(), strconv.FormatInt(pageId, 10), userId, core.PAGE_EDIT) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceId, err := GetPageSpace(r.Context(), pageId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := core.ValidateSpaceMutable(spaceId); err != nil {
+		core.SendFailedReponse(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	next, err := validateUpdateRestrictions(data, userId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	previous, err := updatePageRestrictions(r.Context(), pageId, userId, next)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionPageRestricted,
+		TargetType: "page",
+		TargetId:   strconv.FormatInt(pageId, 10),
+		SpaceId:    &spaceId,
+		Before:     previous,
+		After:      next,
+	})
+	restrictions, err := GetPageRestrictions(r.Context(), pageId)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, restrictions)
+}
+
 func Router() *chi.Mux {
 	r := chi.NewRouter()
 	r.Get("/{pageId}/breadCrumbs", getBreadCrumbs)
+	r.Get("/{pageId}/restrictions", getRestrictions)
+	r.Put("/{pageId}/restrictions", updateRestrictions)
 	return r
 }
@@ -23,4 +23,52 @@ const (
 								core.page_doc_map d ON (p.id = d.page_id)
 							WHERE d.draft = 0
 							ORDER BY p.id, d.version desc`
+
+	GET_PAGE_SPACE = `SELECT space_id FROM core.page WHERE id = $1`
+
+	GET_PAGE_RESTRICTION_SOURCES = `WITH RECURSIVE ancestors AS (
+								SELECT p.id, p.parent_id, 0 AS depth
+								FROM core.page p
+								WHERE p.id = $1
+
+								UNION ALL
+
+								SELECT p.id, p.parent_id, a.depth + 1
+								FROM core.page p INNER JOIN ancestors a ON (p.id = a.parent_id)
+								WHERE a.depth < 100
+							)
+							SELECT
+								a.id AS page_id,
+								a.depth,
+								COALESCE((
+									SELECT d.title FROM core.page_doc_map d
+									WHERE d.page_id = a.id
+									ORDER BY d.version DESC
+									LIMIT 1
+								), '') AS title,
+								r.kind,
+								r.subject_type,
+								r.subject_id
+							FROM
+								ancestors a INNER JOIN core.page_restrictions r ON (r.page_id = a.id)
+							ORDER BY a.depth, r.kind, r.subject_type, r.subject_id`
+
+	GET_PAGE_SUBTREE_PARENTS = `WITH RECURSIVE subtree AS (
+								SELECT p.id, p.parent_id
+								FROM core.page p
+								WHERE p.id = $1
+
+								UNION
+
+								SELECT p.id, p.parent_id
+								FROM core.page p INNER JOIN subtree s ON (p.parent_id = s.id)
+							)
+							SELECT id, parent_id FROM subtree WHERE parent_id > 0`
+
+	GET_PAGE_OWN_RESTRICTIONS = `SELECT kind, subject_type, subject_id FROM core.page_restrictions WHERE page_id = $1`
+
+	DELETE_PAGE_RESTRICTIONS = `DELETE FROM core.page_restrictions WHERE page_id = $1`
+
+	INSERT_PAGE_RESTRICTION = `INSERT INTO core.page_restrictions (page_id, kind, subject_type, subject_id, created_by)
+								VALUES ($1, $2, $3, $4, $5)`
 )
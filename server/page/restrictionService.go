@@ -0,0 +1,298 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var restrictionRelations = map[string]string{
+	RESTRICTION_VIEW: "restricted_viewer",
+	RESTRICTION_EDIT: "restricted_editor",
+}
+
+var restrictionMarkers = map[string]string{
+	RESTRICTION_VIEW: "view_restricted",
+	RESTRICTION_EDIT: "edit_restricted",
+}
+
+// GetPageSpace returns the space a page belongs to.
+func GetPageSpace(ctx context.Context, pageId int64) (uuid.UUID, error) {
+	var spaceId uuid.UUID
+	err := core.GetPool().QueryRow(ctx, GET_PAGE_SPACE, pageId).Scan(&spaceId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return spaceId, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return spaceId, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	return spaceId, nil
+}
+
+func getRestrictionRows(ctx context.Context, pageId int64) ([]restrictionRow, error) {
+	rows, err := core.GetPool().Query(ctx, GET_PAGE_RESTRICTION_SOURCES, pageId)
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	restrictions, err := pgx.CollectRows(rows, pgx.RowToStructByName[restrictionRow])
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return restrictions, nil
+}
+
+// groupRestrictionSources folds restriction rows, ordered from the page up
+// through its ancestors, into one source per restricting page and kind.
+func groupRestrictionSources(pageId int64, rows []restrictionRow) PageRestrictions {
+	result := PageRestrictions{
+		PageId: pageId,
+		View:   make([]RestrictionSource, 0),
+		Edit:   make([]RestrictionSource, 0),
+	}
+	for _, row := range rows {
+		sources := &result.View
+		if row.Kind == RESTRICTION_EDIT {
+			sources = &result.Edit
+		}
+		last := len(*sources) - 1
+		if last < 0 || (*sources)[last].PageId != row.PageId {
+			*sources = append(*sources, RestrictionSource{
+				PageId:    row.PageId,
+				Title:     row.Title,
+				Inherited: row.Depth > 0,
+				Subjects:  make([]RestrictionSubject, 0),
+			})
+			last++
+		}
+		(*sources)[last].Subjects = append((*sources)[last].Subjects, RestrictionSubject{
+			Type: row.SubjectType,
+			Id:   row.SubjectId,
+		})
+	}
+	return result
+}
+
+// GetPageRestrictions lists the restrictions affecting a page, its own and
+// those inherited from ancestors, with the subjects each one lets through.
+func GetPageRestrictions(ctx context.Context, pageId int64) (PageRestrictions, error) {
+	rows, err := getRestrictionRows(ctx, pageId)
+	if err != nil {
+		return PageRestrictions{}, err
+	}
+	return groupRestrictionSources(pageId, rows), nil
+}
+
+// GetRestrictionSummary reports whether a view or edit restriction applies to
+// the page, either directly or inherited from an ancestor.
+func GetRestrictionSummary(ctx context.Context, pageId int64) (RestrictionSummary, error) {
+	var summary RestrictionSummary
+	rows, err := getRestrictionRows(ctx, pageId)
+	if err != nil {
+		return summary, err
+	}
+	for _, row := range rows {
+		switch row.Kind {
+		case RESTRICTION_VIEW:
+			summary.ViewRestricted = true
+		case RESTRICTION_EDIT:
+			summary.EditRestricted = true
+		}
+	}
+	return summary, nil
+}
+
+// linkSubtree makes sure every page below pageId, and pageId itself, carries a
+// page#parent tuple so restrictions set on pageId reach the whole subtree.
+// Pages created before restrictions existed were never linked. The tuples are
+// queued in tx.
+func linkSubtree(ctx context.Context, tx pgx.Tx, pageId int64) error {
+	rows, err := tx.Query(ctx, GET_PAGE_SUBTREE_PARENTS, pageId)
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	parents := make([]core.RelationChange, 0)
+	for rows.Next() {
+		var id, parentId int64
+		if err := rows.Scan(&id, &parentId); err != nil {
+			rows.Close()
+			core.Logger.Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		parents = append(parents, core.RelationWrite("page", strconv.FormatInt(id, 10), "parent", "page", strconv.FormatInt(parentId, 10)).AsStructural())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		core.Logger.Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if err := core.QueueRelations(ctx, tx, parents...); err != nil {
+		core.Logger.Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return nil
+}
+
+func restrictionKey(subject RestrictionSubject) string {
+	return subject.Type + ":" + subject.Id
+}
+
+// syncRestrictionRelations queues the Permify side of a change of one
+// restriction kind. Grants are written before the marker and the marker is
+// removed before grants, so nobody on the new list is locked out in between;
+// the outbox applies the changes in the order they were queued.
+func syncRestrictionRelations(ctx context.Context, tx pgx.Tx, pageId string, spaceId string, kind string, before []RestrictionSubject, after []RestrictionSubject) error {
+	relation := restrictionRelations[kind]
+	marker := restrictionMarkers[kind]
+	previous := make(map[string]RestrictionSubject, len(before))
+	for _, subject := range before {
+		previous[restrictionKey(subject)] = subject
+	}
+	next := make(map[string]RestrictionSubject, len(after))
+	for _, subject := range after {
+		next[restrictionKey(subject)] = subject
+	}
+
+	changes := make([]core.RelationChange, 0)
+	if len(after) == 0 && len(before) > 0 {
+		changes = append(changes, core.RelationDelete("page", pageId, marker, "space", spaceId))
+	}
+	for key, subject := range next {
+		if _, ok := previous[key]; ok {
+			continue
+		}
+		changes = append(changes, restrictionSubjectRelation(core.RELATION_WRITE, pageId, subject, relation))
+	}
+	if len(after) > 0 && len(before) == 0 {
+		changes = append(changes, core.RelationWrite("page", pageId, marker, "space", spaceId))
+	}
+	for key, subject := range previous {
+		if _, ok := next[key]; ok {
+			continue
+		}
+		changes = append(changes, restrictionSubjectRelation(core.RELATION_DELETE, pageId, subject, relation))
+	}
+	return core.QueueRelations(ctx, tx, changes...)
+}
+
+// groups are granted through their members, i.e. page#restricted_viewer@group#member
+func restrictionSubjectRelation(operation string, pageId string, subject RestrictionSubject, relation string) core.RelationChange {
+	change := core.RelationChange{Operation: operation, Entity: "page", EntityId: pageId, Relation: relation, Subject: subject.Type, SubjectId: subject.Id}
+	if subject.Type == "group" {
+		return change.OfSubjectSet("member")
+	}
+	return change
+}
+
+func getOwnRestrictions(ctx context.Context, tx pgx.Tx, pageId int64) (map[string][]RestrictionSubject, error) {
+	rows, err := tx.Query(ctx, GET_PAGE_OWN_RESTRICTIONS, pageId)
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	own := map[string][]RestrictionSubject{
+		RESTRICTION_VIEW: {},
+		RESTRICTION_EDIT: {},
+	}
+	for rows.Next() {
+		var kind string
+		var subject RestrictionSubject
+		if err := rows.Scan(&kind, &subject.Type, &subject.Id); err != nil {
+			core.Logger.Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		own[kind] = append(own[kind], subject)
+	}
+	if err := rows.Err(); err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return own, nil
+}
+
+// updatePageRestrictions replaces the page's own view and edit restrictions and
+// returns the previous and new lists keyed by kind.
+func updatePageRestrictions(ctx context.Context, pageId int64, actorId uuid.UUID, next map[string][]RestrictionSubject) (map[string][]RestrictionSubject, error) {
+	spaceId, err := GetPageSpace(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+
+	previous, err := getOwnRestrictions(ctx, tx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, DELETE_PAGE_RESTRICTIONS, pageId); err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	for _, kind := range []string{RESTRICTION_VIEW, RESTRICTION_EDIT} {
+		for _, subject := range next[kind] {
+			if _, err := tx.Exec(ctx, INSERT_PAGE_RESTRICTION, pageId, kind, subject.Type, subject.Id, actorId); err != nil {
+				core.Logger.Error(err.Error())
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+			}
+		}
+	}
+
+	if err := linkSubtree(ctx, tx, pageId); err != nil {
+		return nil, err
+	}
+	pageID := strconv.FormatInt(pageId, 10)
+	for _, kind := range []string{RESTRICTION_VIEW, RESTRICTION_EDIT} {
+		if err := syncRestrictionRelations(ctx, tx, pageID, spaceId.String(), kind, previous[kind], next[kind]); err != nil {
+			core.Logger.Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
+	return previous, nil
+}
+
+// GetVisibleBreadCrumbs returns the crumbs for a page with any ancestor the
+// user cannot view reduced to its id, so restricted titles never leak.
+func GetVisibleBreadCrumbs(ctx context.Context, pageId int64, userId uuid.UUID) ([]Crumb, error) {
+	crumbs, err := getPageBreadCrumbs(pageId)
+	if err != nil {
+		return nil, err
+	}
+	viewable, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		core.Logger.Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	return maskCrumbs(crumbs, viewable), nil
+}
+
+func maskCrumbs(crumbs []Crumb, viewable []string) []Crumb {
+	allowed := make(map[string]bool, len(viewable))
+	for _, id := range viewable {
+		allowed[id] = true
+	}
+	for i := range crumbs {
+		if !allowed[strconv.FormatInt(crumbs[i].Id, 10)] {
+			crumbs[i].Name = ""
+			crumbs[i].Restricted = true
+		}
+	}
+	return crumbs
+}
@@ -4,4 +4,52 @@ type Crumb struct {
 	Name     string `json:"name" db:"title"`
 	Id       int64  `json:"id" db:"id"`
 	ParentId int64  `json:"parentId" db:"parent_id"`
+	// Restricted marks an ancestor the caller cannot view; its name is blanked.
+	Restricted bool `json:"restricted" db:"-"`
+}
+
+const (
+	RESTRICTION_VIEW = "view"
+	RESTRICTION_EDIT = "edit"
+)
+
+type restrictionRow struct {
+	PageId      int64  `db:"page_id"`
+	Depth       int    `db:"depth"`
+	Title       string `db:"title"`
+	Kind        string `db:"kind"`
+	SubjectType string `db:"subject_type"`
+	SubjectId   string `db:"subject_id"`
+}
+
+type RestrictionSubject struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+// RestrictionSource is a page that carries a restriction affecting the
+// requested page, either the page itself or one of its ancestors.
+type RestrictionSource struct {
+	PageId    int64                `json:"pageId"`
+	Title     string               `json:"title"`
+	Inherited bool                 `json:"inherited"`
+	Subjects  []RestrictionSubject `json:"subjects"`
+}
+
+type PageRestrictions struct {
+	PageId int64               `json:"pageId"`
+	View   []RestrictionSource `json:"view"`
+	Edit   []RestrictionSource `json:"edit"`
+}
+
+type RestrictionSummary struct {
+	ViewRestricted bool `json:"viewRestricted"`
+	EditRestricted bool `json:"editRestricted"`
+}
+
+type UpdateRestrictionsRequest struct {
+	View       []string `json:"view"`
+	Edit       []string `json:"edit"`
+	ViewGroups []string `json:"viewGroups"`
+	EditGroups []string `json:"editGroups"`
 }
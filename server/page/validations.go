@@ -0,0 +1,70 @@
+package page
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+const maxRestrictionSubjects = 100
+
+// validateUpdateRestrictions turns the request into subject lists keyed by
+// kind. A non-empty list always includes the actor so nobody can restrict a
+// page away from themselves by accident.
+func validateUpdateRestrictions(data []byte, actorId uuid.UUID) (map[string][]RestrictionSubject, error) {
+	var req UpdateRestrictionsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	view, err := parseRestrictionSubjects(req.View, req.ViewGroups, actorId)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := parseRestrictionSubjects(req.Edit, req.EditGroups, actorId)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]RestrictionSubject{
+		RESTRICTION_VIEW: view,
+		RESTRICTION_EDIT: edit,
+	}, nil
+}
+
+func parseRestrictionSubjects(userIds []string, groupIds []string, actorId uuid.UUID) ([]RestrictionSubject, error) {
+	subjects := make([]RestrictionSubject, 0, len(userIds)+len(groupIds)+1)
+	if len(userIds) == 0 && len(groupIds) == 0 {
+		return subjects, nil
+	}
+	if len(userIds)+len(groupIds) > maxRestrictionSubjects {
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	users, err := parseRestrictionIds("user", append(userIds, actorId.String()))
+	if err != nil {
+		return nil, err
+	}
+	groups, err := parseRestrictionIds("group", groupIds)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(subjects, users...), groups...), nil
+}
+
+func parseRestrictionIds(subjectType string, ids []string) ([]RestrictionSubject, error) {
+	subjects := make([]RestrictionSubject, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, raw := range ids {
+		id, err := uuid.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		subjects = append(subjects, RestrictionSubject{Type: subjectType, Id: id.String()})
+	}
+	return subjects, nil
+}
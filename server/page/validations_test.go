@@ -0,0 +1,80 @@
+package page
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateUpdateRestrictionsAddsActor(t *testing.T) {
+	actor := uuid.New()
+	other := uuid.New()
+	body := []byte(`{"view":["` + other.String() + `","` + other.String() + `"],"edit":[]}`)
+
+	subjects, err := validateUpdateRestrictions(body, actor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	view := subjects[RESTRICTION_VIEW]
+	if len(view) != 2 {
+		t.Fatalf("expected de-duplicated user plus actor, got %d subjects", len(view))
+	}
+	if view[1].Id != actor.String() {
+		t.Fatalf("expected actor to be added, got %s", view[1].Id)
+	}
+	if len(subjects[RESTRICTION_EDIT]) != 0 {
+		t.Fatalf("expected empty edit list to stay unrestricted")
+	}
+}
+
+func TestValidateUpdateRestrictionsAcceptsGroups(t *testing.T) {
+	actor := uuid.New()
+	groupId := uuid.New()
+	body := []byte(`{"editGroups":["` + groupId.String() + `"]}`)
+
+	subjects, err := validateUpdateRestrictions(body, actor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	edit := subjects[RESTRICTION_EDIT]
+	if len(edit) != 2 || edit[0].Id != actor.String() || edit[1].Type != "group" || edit[1].Id != groupId.String() {
+		t.Fatalf("expected actor plus group, got %+v", edit)
+	}
+}
+
+func TestValidateUpdateRestrictionsRejectsInvalidIds(t *testing.T) {
+	if _, err := validateUpdateRestrictions([]byte(`{"view":["nope"]}`), uuid.New()); err == nil {
+		t.Fatalf("expected invalid user id to be rejected")
+	}
+}
+
+func TestGroupRestrictionSources(t *testing.T) {
+	rows := []restrictionRow{
+		{PageId: 5, Depth: 0, Title: "Salaries", Kind: RESTRICTION_EDIT, SubjectType: "user", SubjectId: "a"},
+		{PageId: 3, Depth: 1, Title: "HR", Kind: RESTRICTION_VIEW, SubjectType: "user", SubjectId: "a"},
+		{PageId: 3, Depth: 1, Title: "HR", Kind: RESTRICTION_VIEW, SubjectType: "user", SubjectId: "b"},
+	}
+
+	result := groupRestrictionSources(5, rows)
+	if len(result.Edit) != 1 || result.Edit[0].Inherited {
+		t.Fatalf("expected one direct edit source, got %+v", result.Edit)
+	}
+	if len(result.View) != 1 || !result.View[0].Inherited || result.View[0].PageId != 3 {
+		t.Fatalf("expected one inherited view source from page 3, got %+v", result.View)
+	}
+	if len(result.View[0].Subjects) != 2 {
+		t.Fatalf("expected both subjects on the inherited source, got %d", len(result.View[0].Subjects))
+	}
+}
+
+func TestMaskCrumbs(t *testing.T) {
+	crumbs := []Crumb{{Id: 1, Name: "HR"}, {Id: 2, Name: "Handbook"}}
+
+	masked := maskCrumbs(crumbs, []string{"2"})
+	if !masked[0].Restricted || masked[0].Name != "" {
+		t.Fatalf("expected first crumb to be masked, got %+v", masked[0])
+	}
+	if masked[1].Restricted || masked[1].Name != "Handbook" {
+		t.Fatalf("expected second crumb to stay visible, got %+v", masked[1])
+	}
+}
@@ -0,0 +1,45 @@
+package relations
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	RelayEnabled      bool
+	RelayInterval     time.Duration
+	Retention         time.Duration
+	ReconcileEnabled  bool
+	ReconcileInterval time.Duration
+}
+
+// LoadConfig reads the permission outbox settings. Requests apply their own
+// changes right away; the relay only retries what they could not apply, and
+// reconciliation repairs drift the outbox cannot see.
+func LoadConfig() Config {
+	return Config{
+		RelayEnabled:      envBool("PERMISSION_RELAY_ENABLED", true),
+		RelayInterval:     time.Duration(envInt("PERMISSION_RELAY_INTERVAL_SECONDS", 5)) * time.Second,
+		Retention:         time.Duration(envInt("PERMISSION_OUTBOX_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		ReconcileEnabled:  envBool("PERMISSION_RECONCILE_ENABLED", true),
+		ReconcileInterval: time.Duration(envInt("PERMISSION_RECONCILE_INTERVAL_MINUTES", 60)) * time.Minute,
+	}
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
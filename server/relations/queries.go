@@ -0,0 +1,26 @@
+package relations
+
+const (
+	listDueTenants = `SELECT DISTINCT tenant_id FROM core.permission_outbox
+WHERE status = 'pending' AND next_attempt_at <= now()`
+	deleteAppliedChanges = `DELETE FROM core.permission_outbox WHERE status = 'applied' AND applied_at < $1`
+
+	listTenants = `SELECT tenant_id FROM core.organizations ORDER BY created_at`
+
+	// held for the whole pass so two instances never repair the same tenant
+	lockTenantReconcile = `SELECT pg_try_advisory_xact_lock(hashtext('permission_reconcile'), hashtext($1))`
+	hasPendingChanges   = `SELECT EXISTS (SELECT 1 FROM core.permission_outbox WHERE tenant_id = $1 AND status = 'pending')`
+
+	listTenantSpaces = `SELECT s.id::text FROM core.space s
+JOIN core.organizations o ON o.id = s.org_id
+WHERE o.tenant_id = $1`
+	listTenantPages = `SELECT p.id::text, p.space_id::text, p.parent_id FROM core.page p
+JOIN core.space s ON s.id = p.space_id
+JOIN core.organizations o ON o.id = s.org_id
+WHERE o.tenant_id = $1`
+	listTenantRestrictions = `SELECT r.page_id::text, r.kind, r.subject_type, r.subject_id FROM core.page_restrictions r
+JOIN core.page p ON p.id = r.page_id
+JOIN core.space s ON s.id = p.space_id
+JOIN core.organizations o ON o.id = s.org_id
+WHERE o.tenant_id = $1`
+)
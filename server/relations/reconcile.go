@@ -0,0 +1,320 @@
+package relations
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/page"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Reconciliation compares what Permify holds for a tenant's spaces and pages
+// with what the database says they should hold and queues the difference
+// through the outbox. Only relations the database decides are touched: a
+// page's space, parent and restrictions, and the tuples of spaces and pages
+// that no longer exist. Space roles live only in Permify, so a space left
+// without an owner is reported rather than fixed. Groups have no tenant of
+// their own and are not reconciled.
+
+var restrictionRelations = map[string]string{
+	page.RESTRICTION_VIEW: "restricted_viewer",
+	page.RESTRICTION_EDIT: "restricted_editor",
+}
+
+var restrictionMarkers = map[string]string{
+	page.RESTRICTION_VIEW: "view_restricted",
+	page.RESTRICTION_EDIT: "edit_restricted",
+}
+
+// pageRelations are the page relations the database decides.
+var pageRelations = map[string]bool{
+	"space":             true,
+	"parent":            true,
+	"view_restricted":   true,
+	"edit_restricted":   true,
+	"restricted_viewer": true,
+	"restricted_editor": true,
+}
+
+type tuple struct {
+	entity          string
+	entityId        string
+	relation        string
+	subject         string
+	subjectId       string
+	subjectRelation string
+}
+
+func (t tuple) change(operation string) core.RelationChange {
+	c := core.RelationChange{
+		Operation:       operation,
+		Entity:          t.entity,
+		EntityId:        t.entityId,
+		Relation:        t.relation,
+		Subject:         t.subject,
+		SubjectId:       t.subjectId,
+		SubjectRelation: t.subjectRelation,
+	}
+	if t.relation == "space" || t.relation == "parent" {
+		c.Structural = true
+	}
+	return c
+}
+
+type pageRow struct {
+	id       string
+	spaceId  string
+	parentId int64
+}
+
+type restrictionRow struct {
+	pageId      string
+	kind        string
+	subjectType string
+	subjectId   string
+}
+
+// Report is what one reconciliation pass found for a tenant.
+type Report struct {
+	Tenant          string
+	Skipped         bool
+	Writes          int
+	Deletes         int
+	OwnerlessSpaces []string
+}
+
+// expectedPageTuples lists the page tuples the database implies.
+func expectedPageTuples(pages []pageRow, restrictions []restrictionRow) []tuple {
+	spaceOf := make(map[string]string, len(pages))
+	expected := make([]tuple, 0, len(pages)*2)
+	for _, p := range pages {
+		spaceOf[p.id] = p.spaceId
+		expected = append(expected, tuple{entity: "page", entityId: p.id, relation: "space", subject: "space", subjectId: p.spaceId})
+		if p.parentId > 0 {
+			expected = append(expected, tuple{entity: "page", entityId: p.id, relation: "parent", subject: "page", subjectId: strconv.FormatInt(p.parentId, 10)})
+		}
+	}
+	marked := make(map[string]bool)
+	for _, r := range restrictions {
+		relation, ok := restrictionRelations[r.kind]
+		if !ok {
+			continue
+		}
+		grant := tuple{entity: "page", entityId: r.pageId, relation: relation, subject: r.subjectType, subjectId: r.subjectId}
+		if r.subjectType == "group" {
+			grant.subjectRelation = "member"
+		}
+		expected = append(expected, grant)
+		if key := r.pageId + "|" + r.kind; !marked[key] {
+			marked[key] = true
+			expected = append(expected, tuple{entity: "page", entityId: r.pageId, relation: restrictionMarkers[r.kind], subject: "space", subjectId: spaceOf[r.pageId]})
+		}
+	}
+	return expected
+}
+
+func isMarker(relation string) bool {
+	return relation == "view_restricted" || relation == "edit_restricted"
+}
+
+// diffPages returns the changes that turn actual into expected. Pages that
+// are gone lose every tuple. The order follows the restriction rule: markers
+// go before grants are revoked and come after grants are written.
+func diffPages(pages map[string]bool, expected []tuple, actual []tuple) []core.RelationChange {
+	have := make(map[tuple]bool, len(actual))
+	for _, t := range actual {
+		have[t] = true
+	}
+	want := make(map[tuple]bool, len(expected))
+	for _, t := range expected {
+		want[t] = true
+	}
+
+	var markerDeletes, writes, markerWrites, deletes, gone []core.RelationChange
+	for _, t := range expected {
+		if have[t] {
+			continue
+		}
+		have[t] = true
+		if isMarker(t.relation) {
+			markerWrites = append(markerWrites, t.change(core.RELATION_WRITE))
+		} else {
+			writes = append(writes, t.change(core.RELATION_WRITE))
+		}
+	}
+	removed := make(map[string]bool)
+	for _, t := range actual {
+		if !pages[t.entityId] {
+			if !removed[t.entityId] {
+				removed[t.entityId] = true
+				gone = append(gone, core.RelationDelete("page", t.entityId, "", "", ""))
+			}
+			continue
+		}
+		if want[t] || !pageRelations[t.relation] {
+			continue
+		}
+		want[t] = true
+		if isMarker(t.relation) {
+			markerDeletes = append(markerDeletes, t.change(core.RELATION_DELETE))
+		} else {
+			deletes = append(deletes, t.change(core.RELATION_DELETE))
+		}
+	}
+
+	changes := make([]core.RelationChange, 0, len(markerDeletes)+len(writes)+len(markerWrites)+len(deletes)+len(gone))
+	changes = append(changes, markerDeletes...)
+	changes = append(changes, writes...)
+	changes = append(changes, markerWrites...)
+	changes = append(changes, deletes...)
+	return append(changes, gone...)
+}
+
+// ReconcileTenant repairs one tenant. It is skipped while another instance is
+// at it or while changes are still queued, since those would show up as drift.
+func ReconcileTenant(ctx context.Context, tenant string) (Report, error) {
+	report := Report{Tenant: tenant, OwnerlessSpaces: make([]string, 0)}
+	ctx = core.WithTenant(ctx, tenant)
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked, pending bool
+	if err := tx.QueryRow(ctx, lockTenantReconcile, tenant).Scan(&locked); err != nil {
+		return report, err
+	}
+	if locked {
+		if err := tx.QueryRow(ctx, hasPendingChanges, tenant).Scan(&pending); err != nil {
+			return report, err
+		}
+	}
+	if !locked || pending {
+		report.Skipped = true
+		return report, nil
+	}
+
+	// Permify first: anything written after this read is already in the
+	// database by the time it is read below, so it is never taken for drift
+	spaceTuples, err := readTuples(ctx, "space")
+	if err != nil {
+		return report, err
+	}
+	pageTuples, err := readTuples(ctx, "page")
+	if err != nil {
+		return report, err
+	}
+
+	spaces, err := collectSet(ctx, tx, listTenantSpaces, tenant)
+	if err != nil {
+		return report, err
+	}
+	rows, err := tx.Query(ctx, listTenantPages, tenant)
+	if err != nil {
+		return report, err
+	}
+	pages, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pageRow, error) {
+		var p pageRow
+		err := row.Scan(&p.id, &p.spaceId, &p.parentId)
+		return p, err
+	})
+	if err != nil {
+		return report, err
+	}
+	rows, err = tx.Query(ctx, listTenantRestrictions, tenant)
+	if err != nil {
+		return report, err
+	}
+	restrictions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (restrictionRow, error) {
+		var r restrictionRow
+		err := row.Scan(&r.pageId, &r.kind, &r.subjectType, &r.subjectId)
+		return r, err
+	})
+	if err != nil {
+		return report, err
+	}
+
+	pageIds := make(map[string]bool, len(pages))
+	for _, p := range pages {
+		pageIds[p.id] = true
+	}
+	changes := diffPages(pageIds, expectedPageTuples(pages, restrictions), pageTuples)
+
+	owned := make(map[string]bool)
+	removed := make(map[string]bool)
+	for _, t := range spaceTuples {
+		if !spaces[t.entityId] {
+			if !removed[t.entityId] {
+				removed[t.entityId] = true
+				changes = append(changes, core.RelationDelete("space", t.entityId, "", "", ""))
+			}
+			continue
+		}
+		if t.relation == "owner" {
+			owned[t.entityId] = true
+		}
+	}
+	for spaceId := range spaces {
+		if !owned[spaceId] {
+			report.OwnerlessSpaces = append(report.OwnerlessSpaces, spaceId)
+		}
+	}
+
+	for _, c := range changes {
+		if c.Operation == core.RELATION_WRITE {
+			report.Writes++
+		} else {
+			report.Deletes++
+		}
+	}
+	if err := core.QueueRelations(ctx, tx, changes...); err != nil {
+		return report, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return report, err
+	}
+	if len(changes) > 0 {
+		if err := core.ApplyTenantRelations(ctx, tenant); err != nil {
+			logger().Warn("permission reconcile: fixes are queued for retry", zap.String("tenant", tenant), zap.Error(err))
+		}
+	}
+	return report, nil
+}
+
+func readTuples(ctx context.Context, entity string) ([]tuple, error) {
+	raw, err := core.ReadEntityRelationships(ctx, entity)
+	if err != nil {
+		return nil, err
+	}
+	tuples := make([]tuple, 0, len(raw))
+	for _, t := range raw {
+		tuples = append(tuples, tuple{
+			entity:          t.GetEntity().GetType(),
+			entityId:        t.GetEntity().GetId(),
+			relation:        t.GetRelation(),
+			subject:         t.GetSubject().GetType(),
+			subjectId:       t.GetSubject().GetId(),
+			subjectRelation: t.GetSubject().GetRelation(),
+		})
+	}
+	return tuples, nil
+}
+
+func collectSet(ctx context.Context, tx pgx.Tx, query string, args ...any) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
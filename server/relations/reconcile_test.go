@@ -0,0 +1,64 @@
+package relations
+
+import "testing"
+
+func TestDiffPagesRepairsDrift(t *testing.T) {
+	pages := []pageRow{
+		{id: "1", spaceId: "s"},
+		{id: "2", spaceId: "s", parentId: 1},
+	}
+	restrictions := []restrictionRow{
+		{pageId: "1", kind: "view", subjectType: "user", subjectId: "u"},
+		{pageId: "1", kind: "view", subjectType: "group", subjectId: "g"},
+	}
+	actual := []tuple{
+		{entity: "page", entityId: "1", relation: "space", subject: "space", subjectId: "s"},
+		// written by hand, not in the database
+		{entity: "page", entityId: "1", relation: "restricted_editor", subject: "user", subjectId: "x"},
+		{entity: "page", entityId: "1", relation: "edit_restricted", subject: "space", subjectId: "s"},
+		// left behind by a deleted page
+		{entity: "page", entityId: "9", relation: "space", subject: "space", subjectId: "s"},
+		{entity: "page", entityId: "9", relation: "parent", subject: "page", subjectId: "1"},
+		// not decided by the database
+		{entity: "page", entityId: "2", relation: "owner", subject: "space", subjectId: "s", subjectRelation: "owner"},
+	}
+	ids := map[string]bool{"1": true, "2": true}
+	changes := diffPages(ids, expectedPageTuples(pages, restrictions), actual)
+
+	got := make([]string, 0, len(changes))
+	for _, c := range changes {
+		got = append(got, c.Operation+" "+c.EntityId+"#"+c.Relation+"@"+c.SubjectId)
+	}
+	want := []string{
+		"delete 1#edit_restricted@s",
+		"write 2#space@s",
+		"write 2#parent@1",
+		"write 1#restricted_viewer@u",
+		"write 1#restricted_viewer@g",
+		"write 1#view_restricted@s",
+		"delete 1#restricted_editor@x",
+		"delete 9#@",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if !changes[1].Structural || changes[3].Structural {
+		t.Fatalf("expected only space and parent writes to be structural")
+	}
+	if changes[4].SubjectRelation != "member" {
+		t.Fatalf("expected groups to be granted through their members")
+	}
+}
+
+func TestDiffPagesLeavesMatchingTuples(t *testing.T) {
+	pages := []pageRow{{id: "1", spaceId: "s"}}
+	actual := []tuple{{entity: "page", entityId: "1", relation: "space", subject: "space", subjectId: "s"}}
+	if changes := diffPages(map[string]bool{"1": true}, expectedPageTuples(pages, nil), actual); len(changes) != 0 {
+		t.Fatalf("expected nothing to do, got %+v", changes)
+	}
+}
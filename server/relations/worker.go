@@ -0,0 +1,139 @@
+package relations
+
+import (
+	"context"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+// Relay applies the outbox changes that requests could not, once their retry
+// is due, and drops applied changes after the retention period.
+type Relay struct {
+	config Config
+}
+
+func NewRelay(config Config) *Relay {
+	return &Relay{config: config}
+}
+
+func (r *Relay) Start(ctx context.Context) {
+	if !r.config.RelayEnabled {
+		return
+	}
+	ticker := time.NewTicker(r.config.RelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			r.RelayDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayDue works through every tenant with a change that is due. A tenant
+// that fails is left for its next retry.
+func (r *Relay) RelayDue(ctx context.Context) {
+	rows, err := core.GetPool().Query(ctx, listDueTenants)
+	if err != nil {
+		logger().Error("permission relay: listing tenants failed", zap.Error(err))
+		return
+	}
+	tenants, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		logger().Error("permission relay: reading tenants failed", zap.Error(err))
+		return
+	}
+	for _, tenant := range tenants {
+		if err := core.ApplyTenantRelations(ctx, tenant); err != nil {
+			logger().Warn("permission relay: applying changes failed", zap.String("tenant", tenant), zap.Error(err))
+		}
+	}
+	if _, err := core.GetPool().Exec(ctx, deleteAppliedChanges, time.Now().Add(-r.config.Retention)); err != nil {
+		logger().Error("permission relay: removing applied changes failed", zap.Error(err))
+	}
+}
+
+// Reconciler runs a reconciliation pass over every tenant on an interval.
+type Reconciler struct {
+	config Config
+}
+
+func NewReconciler(config Config) *Reconciler {
+	return &Reconciler{config: config}
+}
+
+func (r *Reconciler) Start(ctx context.Context) {
+	if !r.config.ReconcileEnabled {
+		return
+	}
+	ticker := time.NewTicker(r.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			r.ReconcileAll(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileAll reconciles every organization's tenant and returns the reports.
+func (r *Reconciler) ReconcileAll(ctx context.Context) []Report {
+	reports := make([]Report, 0)
+	rows, err := core.GetPool().Query(ctx, listTenants)
+	if err != nil {
+		logger().Error("permission reconcile: listing tenants failed", zap.Error(err))
+		return reports
+	}
+	tenants, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		logger().Error("permission reconcile: reading tenants failed", zap.Error(err))
+		return reports
+	}
+	for _, tenant := range tenants {
+		report, err := ReconcileTenant(ctx, tenant)
+		if err != nil {
+			logger().Error("permission reconcile failed", zap.String("tenant", tenant), zap.Error(err))
+			continue
+		}
+		if len(report.OwnerlessSpaces) > 0 {
+			logger().Warn("permission reconcile: spaces without an owner",
+				zap.String("tenant", tenant),
+				zap.Strings("space_ids", report.OwnerlessSpaces),
+			)
+		}
+		if report.Writes+report.Deletes > 0 {
+			logger().Info("permission reconcile: drift repaired",
+				zap.String("tenant", tenant),
+				zap.Int("writes", report.Writes),
+				zap.Int("deletes", report.Deletes),
+			)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
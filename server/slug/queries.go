@@ -0,0 +1,39 @@
+package slug
+
+const (
+	getSpaceSlug   = `SELECT slug FROM core.space_url WHERE space_id = $1 AND is_current`
+	getSpaceFamily = `SELECT u.slug, u.space_id FROM core.space_url u
+JOIN core.space s ON s.org_id = u.org_id
+WHERE s.id = $1 AND (u.slug = $2 OR u.slug LIKE $2 || '-%')`
+	insertSpaceSlug  = `INSERT INTO core.space_url (space_id, org_id, slug) SELECT id, org_id, $2 FROM core.space WHERE id = $1`
+	demoteSpaceSlug  = `UPDATE core.space_url SET is_current = false WHERE space_id = $1 AND is_current`
+	promoteSpaceSlug = `UPDATE core.space_url SET is_current = true, date_updated = now() WHERE space_id = $1 AND slug = $2`
+	findSpaceBySlug  = `SELECT u.space_id, c.slug
+FROM core.space_url u
+JOIN core.space s ON s.id = u.space_id AND s.deleted_at IS NULL
+JOIN core.space_url c ON c.space_id = u.space_id AND c.is_current
+WHERE u.org_id = $1 AND u.slug = $2`
+
+	getPageSlug     = `SELECT p.space_id, COALESCE(u.slug, '') FROM core.page p LEFT JOIN core.page_url u ON (u.page_id = p.id AND u.is_current) WHERE p.id = $1`
+	getPageSlugs    = `SELECT page_id, slug FROM core.page_url WHERE page_id = ANY($1) AND is_current`
+	getPageFamily   = `SELECT slug, page_id FROM core.page_url WHERE space_id = $1 AND (slug = $2 OR slug LIKE $2 || '-%')`
+	insertPageSlug  = `INSERT INTO core.page_url (page_id, space_id, slug) VALUES ($1, $2, $3)`
+	demotePageSlug  = `UPDATE core.page_url SET is_current = false WHERE page_id = $1 AND is_current`
+	promotePageSlug = `UPDATE core.page_url SET is_current = true, date_updated = now() WHERE page_id = $1 AND slug = $2`
+	// pages are deleted outright, taking their slugs with them, so only the
+	// space can be in the trash
+	findPageBySlug = `SELECT u.page_id, COALESCE(p.type, 'document')
+FROM core.page_url u
+JOIN core.page p ON p.id = u.page_id AND p.space_id = u.space_id
+JOIN core.space s ON s.id = p.space_id AND s.deleted_at IS NULL
+WHERE u.space_id = $1 AND u.slug = $2`
+	getPageAncestors = `WITH RECURSIVE chain AS (
+	SELECT p.id, p.parent_id, 0 AS depth FROM core.page p WHERE p.id = $1
+	UNION
+	SELECT p.id, p.parent_id, c.depth + 1 FROM core.page p JOIN chain c ON p.id = c.parent_id WHERE c.depth < 64
+)
+SELECT c.id, COALESCE(u.slug, '') AS slug
+FROM chain c
+LEFT JOIN core.page_url u ON u.page_id = c.id AND u.is_current
+ORDER BY c.depth DESC`
+)
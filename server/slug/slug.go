@@ -0,0 +1,55 @@
+package slug
+
+import (
+	"net/http"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func resolveController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	res, err := Resolve(r.Context(), userID, r.URL.Query().Get("path"))
+	if err != nil {
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT]:
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+		default:
+			core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, res)
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/resolve", resolveController)
+	return r
+}
@@ -0,0 +1,279 @@
+package slug
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// pickSlug walks base, base-2, base-3, ... and returns the first candidate
+// that is free or already belongs to the owner. owned reports which of the
+// two it was.
+func pickSlug(base string, holders map[string]string, owner string) (string, bool) {
+	taken := make(map[string]bool, len(holders))
+	for slug, holder := range holders {
+		if holder != owner {
+			taken[slug] = true
+		}
+	}
+	slug := nextFree(base, taken)
+	return slug, holders[slug] == owner
+}
+
+func spaceFamily(ctx context.Context, tx pgx.Tx, spaceId uuid.UUID, base string) (map[string]string, error) {
+	holders := make(map[string]string)
+	rows, err := tx.Query(ctx, getSpaceFamily, spaceId, base)
+	if err != nil {
+		logger().Error(err.Error())
+		return holders, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slug string
+		var holder uuid.UUID
+		if err := rows.Scan(&slug, &holder); err != nil {
+			logger().Error(err.Error())
+			return holders, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		holders[slug] = holder.String()
+	}
+	return holders, rows.Err()
+}
+
+func pageFamily(ctx context.Context, tx pgx.Tx, spaceId uuid.UUID, base string) (map[string]string, error) {
+	holders := make(map[string]string)
+	rows, err := tx.Query(ctx, getPageFamily, spaceId, base)
+	if err != nil {
+		logger().Error(err.Error())
+		return holders, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slug string
+		var holder int64
+		if err := rows.Scan(&slug, &holder); err != nil {
+			logger().Error(err.Error())
+			return holders, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		holders[slug] = strconv.FormatInt(holder, 10)
+	}
+	return holders, rows.Err()
+}
+
+// CreateSpaceSlug derives the first slug of a new space from its name. It runs
+// in the transaction that inserts the space.
+func CreateSpaceSlug(ctx context.Context, tx pgx.Tx, spaceId uuid.UUID, name string) (string, error) {
+	base := spaceSlugFor(name)
+	holders, err := spaceFamily(ctx, tx, spaceId, base)
+	if err != nil {
+		return "", err
+	}
+	slug, _ := pickSlug(base, holders, spaceId.String())
+	if _, err := tx.Exec(ctx, insertSpaceSlug, spaceId, slug); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return slug, nil
+}
+
+// SetSpaceSlug makes slug the current slug of the space and returns the one it
+// replaces. The old slug stays behind as a redirect, so it cannot be claimed
+// by another space of the organization.
+func SetSpaceSlug(ctx context.Context, spaceId uuid.UUID, slug string) (string, error) {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, getSpaceSlug, spaceId).Scan(&previous)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if previous == slug {
+		return previous, nil
+	}
+	holders, err := spaceFamily(ctx, tx, spaceId, slug)
+	if err != nil {
+		return "", err
+	}
+	holder, exists := holders[slug]
+	if exists && holder != spaceId.String() {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS])
+	}
+	if _, err := tx.Exec(ctx, demoteSpaceSlug, spaceId); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	query := insertSpaceSlug
+	if exists {
+		query = promoteSpaceSlug
+	}
+	if _, err := tx.Exec(ctx, query, spaceId, slug); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	return previous, nil
+}
+
+// SyncPageSlug keeps the page slug in step with its title. A slug that still
+// matches the title is left alone; otherwise the old one is kept as a redirect
+// and a new one becomes current. It runs in the transaction that stores the
+// title.
+func SyncPageSlug(ctx context.Context, tx pgx.Tx, pageId int64, title string) error {
+	base := pageSlugFor(title)
+	var spaceId uuid.UUID
+	var current string
+	err := tx.QueryRow(ctx, getPageSlug, pageId).Scan(&spaceId, &current)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if current != "" && inFamily(current, base) {
+		return nil
+	}
+	holders, err := pageFamily(ctx, tx, spaceId, base)
+	if err != nil {
+		return err
+	}
+	slug, owned := pickSlug(base, holders, strconv.FormatInt(pageId, 10))
+	if _, err := tx.Exec(ctx, demotePageSlug, pageId); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	if owned {
+		_, err = tx.Exec(ctx, promotePageSlug, pageId, slug)
+	} else {
+		_, err = tx.Exec(ctx, insertPageSlug, pageId, spaceId, slug)
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	return nil
+}
+
+// SpaceSlug returns the current slug of the space, or an empty string when it
+// has none.
+func SpaceSlug(ctx context.Context, spaceId uuid.UUID) (string, error) {
+	var slug string
+	err := core.GetPool().QueryRow(ctx, getSpaceSlug, spaceId).Scan(&slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return slug, nil
+}
+
+// PageSlugs returns the current slugs of the given pages. Pages without one
+// are left out.
+func PageSlugs(ctx context.Context, pageIds []int64) (map[int64]string, error) {
+	slugs := make(map[int64]string, len(pageIds))
+	if len(pageIds) == 0 {
+		return slugs, nil
+	}
+	rows, err := core.GetPool().Query(ctx, getPageSlugs, pageIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return slugs, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pageId int64
+		var slug string
+		if err := rows.Scan(&pageId, &slug); err != nil {
+			logger().Error(err.Error())
+			return slugs, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		slugs[pageId] = slug
+	}
+	return slugs, rows.Err()
+}
+
+// Resolve maps a slug path to a space and optionally a page. The first segment
+// is the space slug and the last one the page slug; segments in between only
+// shape the canonical path. Old slugs resolve too and are flagged for a
+// redirect. Targets the user cannot view are reported as missing.
+func Resolve(ctx context.Context, userId uuid.UUID, path string) (Resolution, error) {
+	var res Resolution
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return res, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	requested := Path(segments[0], segments[1:]...)
+
+	err := core.GetPool().QueryRow(ctx, findSpaceBySlug, core.OrganizationFromContext(ctx), segments[0]).Scan(&res.SpaceId, &res.SpaceSlug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return res, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return res, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if !core.ValidateUserSpacePermissions(ctx, res.SpaceId, userId, core.SPACE_VIEW) {
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if len(segments) == 1 {
+		res.Path = Path(res.SpaceSlug)
+		res.Redirect = res.Path != requested
+		return res, nil
+	}
+
+	var pageId int64
+	err = core.GetPool().QueryRow(ctx, findPageBySlug, res.SpaceId, segments[len(segments)-1]).Scan(&pageId, &res.PageType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	viewable, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	allowed := make(map[string]bool, len(viewable))
+	for _, id := range viewable {
+		allowed[id] = true
+	}
+	if !allowed[strconv.FormatInt(pageId, 10)] {
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+
+	rows, err := core.GetPool().Query(ctx, getPageAncestors, pageId)
+	if err != nil {
+		logger().Error(err.Error())
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	chain, err := pgx.CollectRows(rows, pgx.RowToStructByName[ancestor])
+	if err != nil {
+		logger().Error(err.Error())
+		return Resolution{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	// ancestors the user cannot view are left out of the path
+	slugs := make([]string, 0, len(chain))
+	for _, item := range chain {
+		if item.Slug != "" && allowed[strconv.FormatInt(item.Id, 10)] {
+			slugs = append(slugs, item.Slug)
+		}
+	}
+	res.PageId = &pageId
+	res.Path = Path(res.SpaceSlug, slugs...)
+	res.Redirect = res.Path != requested
+	return res, nil
+}
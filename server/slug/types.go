@@ -0,0 +1,24 @@
+package slug
+
+import "github.com/google/uuid"
+
+// Resolution is the answer to a slug path lookup. Path is the canonical path
+// for the resolved target; Redirect is set when the caller asked for a
+// different one, for example through a renamed slug.
+type Resolution struct {
+	SpaceId   uuid.UUID `json:"spaceId"`
+	SpaceSlug string    `json:"spaceSlug"`
+	PageId    *int64    `json:"pageId,omitempty"`
+	PageType  string    `json:"pageType,omitempty"`
+	Path      string    `json:"path"`
+	Redirect  bool      `json:"redirect"`
+}
+
+type UpdateSpaceSlugRequest struct {
+	Slug string `json:"slug"`
+}
+
+type ancestor struct {
+	Id   int64  `db:"id"`
+	Slug string `db:"slug"`
+}
@@ -0,0 +1,154 @@
+package slug
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSlugLength = 80
+	pathPrefix    = "/space/"
+)
+
+// reservedPageSlugs collide with the fixed routes under a space.
+var reservedPageSlugs = map[string]bool{
+	"view":       true,
+	"edit":       true,
+	"settings":   true,
+	"whiteboard": true,
+}
+
+// Slugify lowercases letters and digits and collapses everything else into
+// single dashes. The database backfill in slugs.xml follows the same rules.
+func Slugify(value string) string {
+	var b strings.Builder
+	dash := false
+	count := 0
+	for _, r := range value {
+		if count >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+			count++
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+			count++
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func spaceSlugFor(name string) string {
+	slug := Slugify(name)
+	if slug == "" {
+		return "space"
+	}
+	if _, err := uuid.Parse(slug); err == nil {
+		return "space-" + slug[:8]
+	}
+	return slug
+}
+
+func pageSlugFor(title string) string {
+	slug := Slugify(title)
+	if slug == "" {
+		return "page"
+	}
+	if reservedPageSlugs[slug] {
+		return slug + "-page"
+	}
+	return slug
+}
+
+// nextFree returns base, or base with the smallest numeric suffix that is not
+// in taken.
+func nextFree(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", base, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// inFamily reports whether slug is base or base with a numeric suffix, which
+// is what nextFree hands out for the same title.
+func inFamily(slug string, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok || suffix == "" {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func validateSpaceSlug(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return value, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	slug := Slugify(value)
+	if slug == "" || utf8.RuneCountInString(value) > maxSlugLength {
+		return slug, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	// space routes take ids as well, so an id-shaped slug would be ambiguous
+	if _, err := uuid.Parse(slug); err == nil {
+		return slug, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return slug, nil
+}
+
+func ValidateUpdateSpaceSlug(data []byte) (UpdateSpaceSlugRequest, error) {
+	var req UpdateSpaceSlugRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	slug, err := validateSpaceSlug(req.Slug)
+	if err != nil {
+		return req, err
+	}
+	req.Slug = slug
+	return req, nil
+}
+
+// splitPath accepts both "/space/a/b" and "a/b" and returns the lowercased
+// segments.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), pathPrefix)
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		segments = append(segments, strings.ToLower(segment))
+	}
+	return segments
+}
+
+// Path joins a space slug and page slugs into a browsable path.
+func Path(spaceSlug string, pageSlugs ...string) string {
+	parts := append([]string{spaceSlug}, pageSlugs...)
+	return pathPrefix + strings.Join(parts, "/")
+}
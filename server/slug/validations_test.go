@@ -0,0 +1,95 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSlugifyCollapsesSeparators(t *testing.T) {
+	cases := map[string]string{
+		"Platform Team":         "platform-team",
+		"  Deploy -- Runbook! ": "deploy-runbook",
+		"Über Café":             "über-café",
+		"***":                   "",
+	}
+	for input, want := range cases {
+		if got := Slugify(input); got != want {
+			t.Fatalf("Slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSlugifyTruncatesWithoutTrailingDash(t *testing.T) {
+	got := Slugify(strings.Repeat("a", maxSlugLength-1) + " tail")
+	if len(got) != maxSlugLength-1 || strings.HasSuffix(got, "-") {
+		t.Fatalf("unexpected truncation: %q", got)
+	}
+}
+
+func TestPageSlugForAvoidsReservedRoutes(t *testing.T) {
+	if got := pageSlugFor("Settings"); got != "settings-page" {
+		t.Fatalf("expected reserved slug to be suffixed, got %q", got)
+	}
+	if got := pageSlugFor("  "); got != "page" {
+		t.Fatalf("expected fallback slug, got %q", got)
+	}
+}
+
+func TestNextFreeSkipsTakenSlugs(t *testing.T) {
+	taken := map[string]bool{"deploy": true, "deploy-2": true}
+	if got := nextFree("deploy", taken); got != "deploy-3" {
+		t.Fatalf("expected deploy-3, got %q", got)
+	}
+	if got := nextFree("runbooks", taken); got != "runbooks" {
+		t.Fatalf("expected untouched base, got %q", got)
+	}
+}
+
+func TestPickSlugReusesOwnHistory(t *testing.T) {
+	holders := map[string]string{"deploy": "7", "deploy-2": "9"}
+	slug, owned := pickSlug("deploy", holders, "9")
+	if slug != "deploy-2" || !owned {
+		t.Fatalf("expected own history slug, got %q owned=%v", slug, owned)
+	}
+	slug, owned = pickSlug("deploy", holders, "11")
+	if slug != "deploy-3" || owned {
+		t.Fatalf("expected a fresh slug, got %q owned=%v", slug, owned)
+	}
+}
+
+func TestInFamily(t *testing.T) {
+	if !inFamily("deploy-4", "deploy") || !inFamily("deploy", "deploy") {
+		t.Fatalf("expected suffixed slug to belong to its base")
+	}
+	if inFamily("deploy-guide", "deploy") || inFamily("deploy-", "deploy") {
+		t.Fatalf("expected unrelated slugs to be rejected")
+	}
+}
+
+func TestValidateUpdateSpaceSlug(t *testing.T) {
+	req, err := ValidateUpdateSpaceSlug([]byte(`{"slug":" Platform Team "}`))
+	if err != nil || req.Slug != "platform-team" {
+		t.Fatalf("unexpected result %q, %v", req.Slug, err)
+	}
+	if _, err := ValidateUpdateSpaceSlug([]byte(`{"slug":"` + uuid.NewString() + `"}`)); err == nil {
+		t.Fatalf("expected id-shaped slug to be rejected")
+	}
+	if _, err := ValidateUpdateSpaceSlug([]byte(`{"slug":"!!"}`)); err == nil {
+		t.Fatalf("expected empty slug to be rejected")
+	}
+}
+
+func TestSplitPathAndPath(t *testing.T) {
+	segments := splitPath("/space/Platform/runbooks//deploy/")
+	if strings.Join(segments, ",") != "platform,runbooks,deploy" {
+		t.Fatalf("unexpected segments: %v", segments)
+	}
+	if got := Path(segments[0], segments[1:]...); got != "/space/platform/runbooks/deploy" {
+		t.Fatalf("unexpected path %q", got)
+	}
+	if got := splitPath("space/docs"); strings.Join(got, ",") != "space,docs" {
+		t.Fatalf("expected bare paths to keep their first segment, got %v", got)
+	}
+}
@@ -0,0 +1,182 @@
+package space
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/group"
+	"github.com/durgakiran/beskar/page"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// explainAccess answers why a user has, or lacks, a permission on the space
+// or one of its pages, for admins sorting out access problems.
+func explainAccess(ctx context.Context, spaceId uuid.UUID, req AccessQuery) (AccessExplanation, error) {
+	explanation := AccessExplanation{
+		UserId:       req.UserId,
+		Entity:       req.Entity,
+		EntityId:     req.EntityId,
+		Permission:   req.Permission,
+		Groups:       make([]GroupGrant, 0),
+		Restrictions: make([]RestrictionAccess, 0),
+	}
+	var pageId int64
+	if req.Entity == "page" {
+		pageId, _ = strconv.ParseInt(req.EntityId, 10, 64)
+		pageSpace, err := page.GetPageSpace(ctx, pageId)
+		if err != nil {
+			return explanation, err
+		}
+		if pageSpace != spaceId {
+			return explanation, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+		}
+	}
+
+	allowed, err := core.CheckPermission(ctx, req.Entity, req.EntityId, "user", req.UserId.String(), req.Permission)
+	if err != nil {
+		return explanation, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	explanation.Allowed = allowed
+	trace, err := core.TracePermission(ctx, req.Entity, req.EntityId, req.Permission, "user", req.UserId.String())
+	if err != nil {
+		return explanation, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	explanation.Grants = trace.Grants
+	explanation.Blocks = trace.Blocks
+
+	if err := describeMembership(ctx, spaceId, req.UserId, &explanation.Role, &explanation.Direct, &explanation.Groups); err != nil {
+		return explanation, err
+	}
+	if req.Entity == "page" {
+		if explanation.Restrictions, err = restrictionAccess(ctx, pageId, req.UserId); err != nil {
+			return explanation, err
+		}
+	}
+	return explanation, nil
+}
+
+// describeMembership fills in the user's highest space role and where it
+// comes from.
+func describeMembership(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, role *string, direct *bool, groups *[]GroupGrant) error {
+	members, err := resolveSpaceMembers(ctx, spaceId)
+	if err != nil {
+		return err
+	}
+	member, ok := members[userId.String()]
+	if !ok {
+		return nil
+	}
+	*role = getHighestRole(member.roles)
+	*direct = member.direct
+	if member.groups != nil {
+		*groups = member.groups
+	}
+	return nil
+}
+
+// restrictionAccess lists the restrictions on a page and its ancestors and
+// whether each one lets the user through.
+func restrictionAccess(ctx context.Context, pageId int64, userId uuid.UUID) ([]RestrictionAccess, error) {
+	restrictions, err := page.GetPageRestrictions(ctx, pageId)
+	if err != nil {
+		return nil, err
+	}
+	groupIds := make([]uuid.UUID, 0)
+	for _, sources := range [][]page.RestrictionSource{restrictions.View, restrictions.Edit} {
+		for _, source := range sources {
+			for _, subject := range source.Subjects {
+				if groupId, err := uuid.Parse(subject.Id); err == nil && subject.Type == "group" {
+					groupIds = append(groupIds, groupId)
+				}
+			}
+		}
+	}
+	members, err := group.ExpandUsers(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	inGroup := func(groupId uuid.UUID) bool {
+		for _, member := range members[groupId] {
+			if member == userId {
+				return true
+			}
+		}
+		return false
+	}
+
+	// sources come nearest page first, view before edit, the order they matter in
+	result := make([]RestrictionAccess, 0)
+	for _, kind := range []string{page.RESTRICTION_VIEW, page.RESTRICTION_EDIT} {
+		sources := restrictions.View
+		if kind == page.RESTRICTION_EDIT {
+			sources = restrictions.Edit
+		}
+		for _, source := range sources {
+			access := RestrictionAccess{Kind: kind, PageId: source.PageId, Title: source.Title, Inherited: source.Inherited}
+			for _, subject := range source.Subjects {
+				switch subject.Type {
+				case "user":
+					access.Listed = access.Listed || subject.Id == userId.String()
+				case "group":
+					groupId, err := uuid.Parse(subject.Id)
+					access.Listed = access.Listed || (err == nil && inGroup(groupId))
+				}
+			}
+			result = append(result, access)
+		}
+	}
+	return result, nil
+}
+
+// getAccessMatrix answers every space and page permission for a user, so
+// admins can see at a glance what the user can reach.
+func getAccessMatrix(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) (AccessMatrix, error) {
+	matrix := AccessMatrix{
+		UserId: userId,
+		Groups: make([]GroupGrant, 0),
+		Pages:  make([]PageAccess, 0),
+	}
+	if err := describeMembership(ctx, spaceId, userId, &matrix.Role, &matrix.Direct, &matrix.Groups); err != nil {
+		return matrix, err
+	}
+	space, err := core.CheckPermissions(ctx, "space", spaceId.String(), "user", userId.String(), spacePermissions...)
+	if err != nil {
+		return matrix, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	matrix.Space = space
+
+	pageIds, err := core.GetEntitiesWithPermission(ctx, "page", "space", spaceId.String(), "space")
+	if err != nil {
+		return matrix, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(pageIds) == 0 {
+		return matrix, nil
+	}
+	rows, err := core.GetPool().Query(ctx, GET_PAGE_LIST_QUERY, spaceId, pageIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return matrix, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	pages, err := pgx.CollectRows[PageList](rows, pgx.RowToStructByNameLax[PageList])
+	if err != nil {
+		logger().Error(err.Error())
+		return matrix, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for _, p := range pages {
+		permissions, err := core.CheckPermissions(ctx, "page", strconv.FormatInt(p.PageId, 10), "user", userId.String(), pagePermissions...)
+		if err != nil {
+			return matrix, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+		}
+		matrix.Pages = append(matrix.Pages, PageAccess{
+			PageId:      p.PageId,
+			Title:       p.Title,
+			ParentId:    p.ParentId,
+			Type:        p.Type,
+			Permissions: permissions,
+		})
+	}
+	return matrix, nil
+}
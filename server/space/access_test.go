@@ -0,0 +1,34 @@
+package space
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateAccessQueryDefaultsToSpaceView(t *testing.T) {
+	spaceId := uuid.New()
+	req, err := validateAccessQuery(url.Values{"userId": {uuid.NewString()}}, spaceId)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Entity != "space" || req.EntityId != spaceId.String() || req.Permission != "view" {
+		t.Fatalf("expected space view on %s, got %+v", spaceId, req)
+	}
+}
+
+func TestValidateAccessQueryRejectsForeignEntities(t *testing.T) {
+	spaceId := uuid.New()
+	for _, query := range []url.Values{
+		{},
+		{"userId": {uuid.NewString()}, "entity": {"space"}, "entityId": {uuid.NewString()}},
+		{"userId": {uuid.NewString()}, "entity": {"page"}, "entityId": {"abc"}},
+		{"userId": {uuid.NewString()}, "entity": {"page"}, "entityId": {"4"}, "permission": {"transfer_owner"}},
+		{"userId": {uuid.NewString()}, "entity": {"group"}, "entityId": {uuid.NewString()}},
+	} {
+		if _, err := validateAccessQuery(query, spaceId); err == nil {
+			t.Errorf("expected %v to be rejected", query)
+		}
+	}
+}
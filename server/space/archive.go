@@ -0,0 +1,176 @@
+package space
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/editor"
+)
+
+const (
+	ARCHIVE_FORMAT   = "beskar.space"
+	ARCHIVE_VERSION  = 1
+	ARCHIVE_MANIFEST = "manifest.json"
+)
+
+var (
+	// internal links carry the page id as the last path segment
+	archivePageLink = regexp.MustCompile(`/(view|edit|page|pages|doc|docs|document|documents|whiteboard)/(\d+)`)
+	// image uploads are only referenced through their URL
+	archiveImageLink = regexp.MustCompile(`/media/image/([^"?#/\\\s]+)`)
+)
+
+// archiveIds maps ids of the exporting instance to the ones created on
+// import.
+type archiveIds struct {
+	fromSpace   string
+	toSpace     string
+	pages       map[int64]int64
+	attachments map[string]string
+}
+
+// rewriteString only touches page ids in links that also name the exported
+// space, so numbers in external URLs are left alone.
+func (ids archiveIds) rewriteString(value string) string {
+	for from, to := range ids.attachments {
+		value = strings.ReplaceAll(value, from, to)
+	}
+	if ids.fromSpace == "" || !strings.Contains(value, ids.fromSpace) {
+		return value
+	}
+	value = strings.ReplaceAll(value, ids.fromSpace, ids.toSpace)
+	return archivePageLink.ReplaceAllStringFunc(value, func(link string) string {
+		match := archivePageLink.FindStringSubmatch(link)
+		pageId, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return link
+		}
+		if mapped, ok := ids.pages[pageId]; ok {
+			return fmt.Sprintf("/%s/%d", match[1], mapped)
+		}
+		return link
+	})
+}
+
+func (ids archiveIds) rewriteValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if key == "resourceId" {
+			if pageId, err := strconv.ParseInt(v, 10, 64); err == nil {
+				if mapped, ok := ids.pages[pageId]; ok {
+					return strconv.FormatInt(mapped, 10)
+				}
+			}
+			return v
+		}
+		return ids.rewriteString(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = ids.rewriteValue(k, item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = ids.rewriteValue(key, item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// rewriteNodes points attachment, image and internal page links in published
+// content at the imported ids.
+func (ids archiveIds) rewriteNodes(nodes editor.NodeData) editor.NodeData {
+	for i := range nodes.Content {
+		if nodes.Content[i].Attributes != nil {
+			ids.rewriteValue("", nodes.Content[i].Attributes)
+		}
+		for _, mark := range nodes.Content[i].Marks {
+			ids.rewriteValue("", mark)
+		}
+	}
+	for i := range nodes.Text {
+		for _, mark := range nodes.Text[i].Marks {
+			ids.rewriteValue("", mark)
+		}
+	}
+	return nodes
+}
+
+// rewriteBinary updates ids inside Yjs state. Strings there are length
+// prefixed, so only same-length replacements (uuids) are safe; internal
+// links to numeric page ids in drafts and whiteboards keep the source ids.
+func (ids archiveIds) rewriteBinary(data []byte) []byte {
+	if ids.fromSpace != "" {
+		data = bytes.ReplaceAll(data, []byte(ids.fromSpace), []byte(ids.toSpace))
+	}
+	for from, to := range ids.attachments {
+		data = bytes.ReplaceAll(data, []byte(from), []byte(to))
+	}
+	return data
+}
+
+// referencedImages returns the uploaded image names linked from data.
+func referencedImages(data []byte) []string {
+	names := make([]string, 0)
+	for _, match := range archiveImageLink.FindAllSubmatch(data, -1) {
+		names = append(names, string(match[1]))
+	}
+	return names
+}
+
+// imageFileName turns the name from an image URL into a file name inside the
+// image directory, rejecting anything that could escape it.
+func imageFileName(name string) (string, error) {
+	name, err := url.PathUnescape(name)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", errors.New("invalid image name")
+	}
+	return name, nil
+}
+
+// readArchiveFile reads a zip entry, refusing entries larger than limit so a
+// crafted archive cannot exhaust memory.
+func readArchiveFile(files map[string]*zip.File, name string, limit int64) ([]byte, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing from the archive", name)
+	}
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, data []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
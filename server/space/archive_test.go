@@ -0,0 +1,159 @@
+package space
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/durgakiran/beskar/editor"
+)
+
+func testArchiveIds() archiveIds {
+	return archiveIds{
+		fromSpace:   "11111111-1111-1111-1111-111111111111",
+		toSpace:     "22222222-2222-2222-2222-222222222222",
+		pages:       map[int64]int64{7: 107, 8: 108},
+		attachments: map[string]string{"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa": "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"},
+	}
+}
+
+func TestRewriteStringRemapsLinks(t *testing.T) {
+	ids := testArchiveIds()
+	got := ids.rewriteString("https://app/space/11111111-1111-1111-1111-111111111111/view/7?x=1")
+	if got != "https://app/space/22222222-2222-2222-2222-222222222222/view/107?x=1" {
+		t.Fatalf("unexpected page link %q", got)
+	}
+	got = ids.rewriteString("/api/v1/attachments/aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	if got != "/api/v1/attachments/bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb" {
+		t.Fatalf("unexpected attachment link %q", got)
+	}
+	if got := ids.rewriteString("/space/11111111-1111-1111-1111-111111111111/view/9"); got != "/space/22222222-2222-2222-2222-222222222222/view/9" {
+		t.Fatalf("expected unknown page to be kept, got %q", got)
+	}
+	if got := ids.rewriteString("https://github.com/org/repo/pages/7"); got != "https://github.com/org/repo/pages/7" {
+		t.Fatalf("expected external link to be kept, got %q", got)
+	}
+}
+
+func TestRewriteNodesRemapsAttributes(t *testing.T) {
+	ids := testArchiveIds()
+	nodes := editor.NodeData{Content: []editor.ContentNode{{
+		Type: "internalLink",
+		Attributes: map[string]interface{}{
+			"resourceId":   "8",
+			"attachmentId": "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+			"items":        []interface{}{map[string]interface{}{"resourceId": "7"}},
+		},
+	}}}
+	nodes = ids.rewriteNodes(nodes)
+	attrs := nodes.Content[0].Attributes
+	if attrs["resourceId"] != "108" {
+		t.Fatalf("expected page resource to be remapped, got %v", attrs["resourceId"])
+	}
+	if attrs["attachmentId"] != "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb" {
+		t.Fatalf("expected attachment to be remapped, got %v", attrs["attachmentId"])
+	}
+	nested := attrs["items"].([]interface{})[0].(map[string]interface{})
+	if nested["resourceId"] != "107" {
+		t.Fatalf("expected nested resource to be remapped, got %v", nested["resourceId"])
+	}
+}
+
+func TestRewriteBinaryKeepsLength(t *testing.T) {
+	ids := testArchiveIds()
+	data := []byte("\x24/space/11111111-1111-1111-1111-111111111111/view/7")
+	got := ids.rewriteBinary(data)
+	if len(got) != len(data) {
+		t.Fatalf("expected binary length to be preserved")
+	}
+	if !bytes.Contains(got, []byte(ids.toSpace)) {
+		t.Fatalf("expected space id to be replaced")
+	}
+}
+
+func TestReferencedImages(t *testing.T) {
+	names := referencedImages([]byte(`{"src":"https://img/media/image/cat-abc.png"} /media/image/dog%20x.jpg?v=1`))
+	if len(names) != 2 || names[0] != "cat-abc.png" || names[1] != "dog%20x.jpg" {
+		t.Fatalf("unexpected image names %v", names)
+	}
+}
+
+func TestValidateArchiveManifestOrdersPages(t *testing.T) {
+	manifest := ArchiveManifest{
+		Format:  ARCHIVE_FORMAT,
+		Version: ARCHIVE_VERSION,
+		Space:   ArchiveSpace{Name: " Docs "},
+		Pages: []ArchivePage{
+			{Id: 3, ParentId: 2},
+			{Id: 2, ParentId: 1},
+			{Id: 1, ParentId: 0},
+			{Id: 4, ParentId: -1, Type: "whiteboard"},
+		},
+		Attachments: []ArchiveAttachment{{PageId: 3, File: "attachments/a"}, {PageId: 99, File: "attachments/b"}},
+		Comments:    []ArchiveThread{{PageId: 99}},
+	}
+	data, _ := json.Marshal(manifest)
+	got, err := validateArchiveManifest(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Space.Name != "Docs" {
+		t.Fatalf("expected trimmed name, got %q", got.Space.Name)
+	}
+	position := map[int64]int{}
+	for i, page := range got.Pages {
+		position[page.Id] = i
+	}
+	if position[1] > position[2] || position[2] > position[3] {
+		t.Fatalf("expected parents before children, got %v", got.Pages)
+	}
+	if got.Pages[position[1]].Type != "document" {
+		t.Fatalf("expected missing type to default to document")
+	}
+	if len(got.Attachments) != 1 || len(got.Comments) != 0 {
+		t.Fatalf("expected entries for unknown pages to be dropped")
+	}
+}
+
+func TestValidateArchiveManifestRejects(t *testing.T) {
+	cases := map[string]ArchiveManifest{
+		"format":    {Format: "other", Version: 1, Space: ArchiveSpace{Name: "x"}},
+		"version":   {Format: ARCHIVE_FORMAT, Version: ARCHIVE_VERSION + 1, Space: ArchiveSpace{Name: "x"}},
+		"name":      {Format: ARCHIVE_FORMAT, Version: 1},
+		"duplicate": {Format: ARCHIVE_FORMAT, Version: 1, Space: ArchiveSpace{Name: "x"}, Pages: []ArchivePage{{Id: 1}, {Id: 1}}},
+		"cycle":     {Format: ARCHIVE_FORMAT, Version: 1, Space: ArchiveSpace{Name: "x"}, Pages: []ArchivePage{{Id: 1, ParentId: 2}, {Id: 2, ParentId: 1}}},
+	}
+	for name, manifest := range cases {
+		data, _ := json.Marshal(manifest)
+		if _, err := validateArchiveManifest(data); err == nil {
+			t.Fatalf("%s: expected manifest to be rejected", name)
+		}
+	}
+}
+
+func TestReadArchiveFileEnforcesLimit(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if err := writeArchiveFile(archive, "big.bin", bytes.Repeat([]byte("x"), 100)); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+	if _, err := readArchiveFile(files, "big.bin", 50); err == nil {
+		t.Fatalf("expected oversized entry to be rejected")
+	}
+	if data, err := readArchiveFile(files, "big.bin", 100); err != nil || len(data) != 100 {
+		t.Fatalf("expected entry within limit to be read, got %d (%v)", len(data), err)
+	}
+	if _, err := readArchiveFile(files, "missing", 100); err == nil {
+		t.Fatalf("expected missing entry to be reported")
+	}
+}
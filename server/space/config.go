@@ -0,0 +1,103 @@
+package space
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PurgeConfig struct {
+	Enabled     bool
+	GracePeriod time.Duration
+	Interval    time.Duration
+	BatchSize   int
+}
+
+// LoadPurgeConfig reads the soft-delete settings. The grace period also bounds
+// restores, so it applies even when the purge worker is disabled.
+func LoadPurgeConfig() PurgeConfig {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("SPACE_PURGE_ENABLED")))
+	if err != nil {
+		enabled = false
+	}
+	return PurgeConfig{
+		Enabled:     enabled,
+		GracePeriod: time.Duration(envInt("SPACE_PURGE_GRACE_DAYS", 30)) * 24 * time.Hour,
+		Interval:    time.Duration(envInt("SPACE_PURGE_INTERVAL_MINUTES", 60)) * time.Minute,
+		BatchSize:   envInt("SPACE_PURGE_BATCH_SIZE", 10),
+	}
+}
+
+type ExpiryConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+	// Notice is how long before a membership ends the member and the space
+	// owner are emailed about it.
+	Notice time.Duration
+}
+
+// LoadExpiryConfig reads the time-limited membership settings. The worker is
+// on unless turned off, since memberships set to expire are expected to.
+func LoadExpiryConfig() ExpiryConfig {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("MEMBERSHIP_EXPIRY_ENABLED")))
+	if err != nil {
+		enabled = true
+	}
+	return ExpiryConfig{
+		Enabled:   enabled,
+		Interval:  time.Duration(envInt("MEMBERSHIP_EXPIRY_INTERVAL_MINUTES", 15)) * time.Minute,
+		BatchSize: envInt("MEMBERSHIP_EXPIRY_BATCH_SIZE", 50),
+		Notice:    time.Duration(envInt("MEMBERSHIP_EXPIRY_NOTICE_DAYS", 3)) * 24 * time.Hour,
+	}
+}
+
+type ReviewConfig struct {
+	Enabled       bool
+	Interval      time.Duration
+	CheckInterval time.Duration
+	BatchSize     int
+	// InactiveDays is how long a member may go without any activity or page
+	// view in the space before the review flags them.
+	InactiveDays int
+	// InternalDomains are the email domains not flagged as external. When
+	// empty, the domain of the space owner's email is used.
+	InternalDomains []string
+}
+
+// LoadReviewConfig reads the access review settings. Reviews started by hand
+// use the same inactivity and domain settings as scheduled ones.
+func LoadReviewConfig() ReviewConfig {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("ACCESS_REVIEW_ENABLED")))
+	if err != nil {
+		enabled = false
+	}
+	domains := make([]string, 0)
+	for _, domain := range strings.Split(os.Getenv("ACCESS_REVIEW_INTERNAL_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, strings.TrimPrefix(domain, "@"))
+		}
+	}
+	return ReviewConfig{
+		Enabled:         enabled,
+		Interval:        time.Duration(envInt("ACCESS_REVIEW_INTERVAL_DAYS", 90)) * 24 * time.Hour,
+		CheckInterval:   time.Duration(envInt("ACCESS_REVIEW_CHECK_MINUTES", 60)) * time.Minute,
+		BatchSize:       envInt("ACCESS_REVIEW_BATCH_SIZE", 20),
+		InactiveDays:    envInt("ACCESS_REVIEW_INACTIVE_DAYS", 90),
+		InternalDomains: domains,
+	}
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// importMaxBytes caps the size of an uploaded space archive.
+func importMaxBytes() int64 {
+	return int64(envInt("SPACE_IMPORT_MAX_MB", 512)) << 20
+}
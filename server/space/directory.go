@@ -0,0 +1,150 @@
+package space
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/org"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// verifiedEmailDomain is the domain of the user's email, or empty when the
+// identity provider has not verified it.
+func verifiedEmailDomain(user core.UserInfo) string {
+	if !user.IsVerified {
+		return ""
+	}
+	return emailDomain(user.Email)
+}
+
+// canJoinSpace reports whether a user with the verified email domain may join
+// a space with the settings by themselves.
+func canJoinSpace(settings SpaceJoinSettings, domain string) bool {
+	switch settings.Policy {
+	case JOIN_OPEN:
+		return true
+	case JOIN_DOMAIN:
+		return domain != "" && slices.Contains(settings.Domains, domain)
+	}
+	return false
+}
+
+func getSpaceJoinSettings(ctx context.Context, spaceId uuid.UUID) (SpaceJoinSettings, error) {
+	var settings SpaceJoinSettings
+	err := core.GetPool().QueryRow(ctx, GET_SPACE_JOIN_SETTINGS, spaceId).Scan(&settings.Policy, &settings.Domains, &settings.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return settings, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	return settings, nil
+}
+
+// updateSpaceJoinSettings lets the owner open the space up, and returns the
+// settings it had before.
+func updateSpaceJoinSettings(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, settings SpaceJoinSettings) (SpaceJoinSettings, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return SpaceJoinSettings{}, err
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_TRANSFER_OWNER) {
+		return SpaceJoinSettings{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	previous, err := getSpaceJoinSettings(ctx, spaceId)
+	if err != nil {
+		return SpaceJoinSettings{}, err
+	}
+	if _, err := core.GetPool().Exec(ctx, UPDATE_SPACE_JOIN_SETTINGS, spaceId, settings.Policy, settings.Domains, settings.Role); err != nil {
+		logger().Error(err.Error())
+		return SpaceJoinSettings{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	return previous, nil
+}
+
+// listSpaceDirectory lists the spaces on the instance the user can join by
+// themselves, in every organization, marking the ones they are already in.
+func listSpaceDirectory(ctx context.Context, user core.UserInfo, userId uuid.UUID, query DirectoryQuery) ([]DirectorySpace, error) {
+	rows, err := core.GetPool().Query(ctx, GET_SPACE_DIRECTORY, verifiedEmailDomain(user), query.Query, query.Limit, query.Offset)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	spaces, err := pgx.CollectRows(rows, pgx.RowToStructByName[DirectorySpace])
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	// membership is answered by each organization's tenant, once per organization
+	member := make(map[uuid.UUID]map[string]bool)
+	for i := range spaces {
+		visible, ok := member[spaces[i].OrgId]
+		if !ok {
+			tenantCtx := core.WithOrganization(ctx, spaces[i].OrgId, spaces[i].TenantId)
+			ids, err := core.GetEntitiesWithPermission(tenantCtx, "space", "user", userId.String(), core.SPACE_VIEW)
+			if err != nil {
+				logger().Error(err.Error())
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+			}
+			visible = make(map[string]bool, len(ids))
+			for _, id := range ids {
+				visible[id] = true
+			}
+			member[spaces[i].OrgId] = visible
+		}
+		spaces[i].IsMember = visible[spaces[i].Id.String()]
+	}
+	return spaces, nil
+}
+
+// joinSpace adds the user to a space they may join by themselves, with the
+// space's join role, and to the space's organization. It returns the role.
+func joinSpace(ctx context.Context, user core.UserInfo, userId uuid.UUID, spaceId uuid.UUID) (string, error) {
+	tenantCtx, orgId, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return "", err
+	}
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	var settings SpaceJoinSettings
+	err = tx.QueryRow(ctx, LOCK_JOINABLE_SPACE, spaceId).Scan(&settings.Policy, &settings.Domains, &settings.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// deleted or archived spaces can't be joined
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	if !canJoinSpace(settings, verifiedEmailDomain(user)) {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	canView, err := core.CheckPermission(tenantCtx, "space", spaceId.String(), "user", userId.String(), core.SPACE_VIEW)
+	if err != nil {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if canView {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS])
+	}
+	// the membership and the space role commit together
+	if err := org.JoinTx(ctx, tx, orgId, userId); err != nil {
+		return "", err
+	}
+	if err := core.QueueRelations(tenantCtx, tx, core.RelationWrite("space", spaceId.String(), storageRole(settings.Role), "user", userId.String())); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(tenantCtx)
+	return settings.Role, nil
+}
@@ -0,0 +1,68 @@
+package space
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+func TestCanJoinSpace(t *testing.T) {
+	domain := SpaceJoinSettings{Policy: JOIN_DOMAIN, Domains: []string{"example.com"}, Role: "viewer"}
+	if !canJoinSpace(domain, "example.com") {
+		t.Fatalf("expected a matching domain to be allowed")
+	}
+	if canJoinSpace(domain, "example.org") || canJoinSpace(domain, "") {
+		t.Fatalf("expected other and unverified domains to be refused")
+	}
+	if !canJoinSpace(SpaceJoinSettings{Policy: JOIN_OPEN}, "") {
+		t.Fatalf("expected an open space to be joinable by anyone")
+	}
+	if canJoinSpace(SpaceJoinSettings{Policy: JOIN_PRIVATE, Domains: []string{"example.com"}}, "example.com") {
+		t.Fatalf("expected a private space to be refused")
+	}
+}
+
+func TestVerifiedEmailDomain(t *testing.T) {
+	if got := verifiedEmailDomain(core.UserInfo{Email: "Ana@Example.com", IsVerified: true}); got != "example.com" {
+		t.Fatalf("expected example.com, got %q", got)
+	}
+	if got := verifiedEmailDomain(core.UserInfo{Email: "ana@example.com"}); got != "" {
+		t.Fatalf("expected no domain for an unverified email, got %q", got)
+	}
+}
+
+func TestValidateSpaceJoinSettings(t *testing.T) {
+	settings, err := validateSpaceJoinSettings([]byte(`{"policy": "Domain", "domains": [" @Example.com ", "example.com", "corp.example.org"]}`))
+	if err != nil {
+		t.Fatalf("expected valid settings, got %v", err)
+	}
+	if settings.Policy != JOIN_DOMAIN || len(settings.Domains) != 2 || settings.Domains[0] != "example.com" || settings.Role != "viewer" {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+	for _, body := range []string{
+		`{"policy": "domain", "domains": []}`,
+		`{"policy": "open", "role": "admin"}`,
+		`{"policy": "public"}`,
+		`{"policy": "domain", "domains": ["localhost"]}`,
+	} {
+		if _, err := validateSpaceJoinSettings([]byte(body)); err == nil {
+			t.Fatalf("expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestValidateDirectoryQuery(t *testing.T) {
+	query, err := validateDirectoryQuery(url.Values{"q": {" 100%_off "}})
+	if err != nil {
+		t.Fatalf("expected a valid query, got %v", err)
+	}
+	if query.Query != `100\%\_off` || query.Limit != 50 || query.Offset != 0 {
+		t.Fatalf("unexpected query %+v", query)
+	}
+	for _, values := range []url.Values{{"limit": {"0"}}, {"limit": {"101"}}, {"offset": {"-1"}}} {
+		if _, err := validateDirectoryQuery(values); err == nil {
+			t.Fatalf("expected %v to be rejected", values)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package space
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/durgakiran/beskar/org"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const membershipExpiryEmailCategory = notification.CategorySpaceMembership
+
+// Expirer removes time-limited memberships once they end, after emailing the
+// member and the space owner when the end is near.
+type Expirer struct {
+	config ExpiryConfig
+}
+
+func NewExpirer(config ExpiryConfig) *Expirer {
+	return &Expirer{config: config}
+}
+
+func (e *Expirer) Start(ctx context.Context) {
+	if !e.config.Enabled {
+		return
+	}
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			e.NotifyDue(ctx)
+			e.ExpireDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NotifyDue notifies one batch of memberships that end within the notice
+// period and returns how many were claimed. Each membership is claimed before
+// its notices are sent, so a notice is sent at most once.
+func (e *Expirer) NotifyDue(ctx context.Context) int {
+	rows, err := core.GetPool().Query(ctx, CLAIM_EXPIRY_NOTICES, time.Now().Add(e.config.Notice), e.config.BatchSize)
+	if err != nil {
+		logger().Error("membership expiry: claiming notices failed", zap.Error(err))
+		return 0
+	}
+	due, err := pgx.CollectRows(rows, pgx.RowToStructByPos[MembershipExpiry])
+	if err != nil {
+		logger().Error("membership expiry: reading notices failed", zap.Error(err))
+		return 0
+	}
+	for _, expiry := range due {
+		if err := sendExpiryNotice(ctx, expiry); err != nil {
+			logger().Error("membership expiry: queueing notice failed",
+				zap.String("space_id", expiry.SpaceId.String()),
+				zap.String("user_id", expiry.UserId.String()),
+				zap.Error(err),
+			)
+		}
+	}
+	return len(due)
+}
+
+// ExpireDue removes one batch of ended memberships and returns how many were
+// removed. A membership that fails is retried on the next run.
+func (e *Expirer) ExpireDue(ctx context.Context) int {
+	rows, err := core.GetPool().Query(ctx, GET_EXPIRED_MEMBERSHIPS, e.config.BatchSize)
+	if err != nil {
+		logger().Error("membership expiry: listing ended memberships failed", zap.Error(err))
+		return 0
+	}
+	due, err := pgx.CollectRows(rows, pgx.RowToStructByPos[MembershipExpiry])
+	if err != nil {
+		logger().Error("membership expiry: reading ended memberships failed", zap.Error(err))
+		return 0
+	}
+	expired := 0
+	members := make(map[uuid.UUID]map[uuid.UUID]User)
+	for _, expiry := range due {
+		tenantCtx, _, err := org.WithSpaceTenant(ctx, expiry.SpaceId)
+		if err != nil {
+			logger().Error("membership expiry failed", zap.String("space_id", expiry.SpaceId.String()), zap.Error(err))
+			continue
+		}
+		if _, ok := members[expiry.SpaceId]; !ok {
+			users, err := getSpaceUsers(tenantCtx, expiry.SpaceId)
+			if err != nil {
+				logger().Error("membership expiry failed", zap.String("space_id", expiry.SpaceId.String()), zap.Error(err))
+				continue
+			}
+			members[expiry.SpaceId] = make(map[uuid.UUID]User, len(users))
+			for _, user := range users {
+				members[expiry.SpaceId][user.Id] = user
+			}
+		}
+		member := members[expiry.SpaceId][expiry.UserId]
+		removed, err := expireMembership(tenantCtx, expiry, member.IsOwner)
+		if err != nil {
+			logger().Error("membership expiry failed",
+				zap.String("space_id", expiry.SpaceId.String()),
+				zap.String("user_id", expiry.UserId.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if !removed {
+			continue
+		}
+		expired++
+		spaceId := expiry.SpaceId
+		audit.RecordSystem(audit.Entry{
+			Action:     audit.ActionMemberRemoved,
+			TargetType: "user",
+			TargetId:   expiry.UserId.String(),
+			SpaceId:    &spaceId,
+			Before:     map[string]interface{}{"role": member.Role, "expiresAt": expiry.ExpiresAt},
+			After:      map[string]string{"reason": "expired"},
+		})
+	}
+	return expired
+}
+
+// expireMembership removes the user's direct membership if its expiry is
+// still due, and reports whether it did. The owner keeps their access; only
+// the expiry is dropped, as ownership can't lapse.
+func expireMembership(ctx context.Context, expiry MembershipExpiry, isOwner bool) (bool, error) {
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return false, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	var locked uuid.UUID
+	err = tx.QueryRow(ctx, LOCK_EXPIRED_MEMBERSHIP, expiry.SpaceId, expiry.UserId).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// extended meanwhile, or another instance is on it
+		return false, nil
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return false, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	if isOwner {
+		logger().Warn("membership expiry: keeping the space owner",
+			zap.String("space_id", expiry.SpaceId.String()),
+			zap.String("user_id", expiry.UserId.String()),
+		)
+		if _, err := tx.Exec(ctx, DELETE_SPACE_MEMBER_EXPIRY, expiry.SpaceId, expiry.UserId); err != nil {
+			logger().Error(err.Error())
+			return false, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		}
+	} else if err := removeDirectMembership(ctx, tx, expiry.SpaceId, expiry.UserId); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return false, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
+	return !isOwner, nil
+}
+
+// sendExpiryNotice tells the member and the space owner that the membership
+// ends soon.
+func sendExpiryNotice(ctx context.Context, expiry MembershipExpiry) error {
+	config := notification.LoadConfig()
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, expiry.SpaceId)
+	if err != nil {
+		return err
+	}
+	var spaceName string
+	if err := core.GetPool().QueryRow(ctx, GET_SPACE_NAME, expiry.SpaceId).Scan(&spaceName); err != nil {
+		return err
+	}
+	profiles, err := core.GetUserProfiles([]string{expiry.UserId.String()})
+	if err != nil {
+		return err
+	}
+	member := profiles[expiry.UserId.String()]
+	memberName := member.Name
+	if memberName == "" {
+		memberName = member.Email
+	}
+	owner, err := getCurrentOwner(tenantCtx, expiry.SpaceId)
+	if err != nil {
+		return err
+	}
+
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	data := map[string]any{
+		"space_name":  spaceName,
+		"member_name": memberName,
+		"expires_at":  expiry.ExpiresAt.UTC().Format("2 Jan 2006 15:04 MST"),
+		"space_url":   fmt.Sprintf("%s/space/%s", appURL, expiry.SpaceId),
+		"app_url":     appURL + "/",
+	}
+	recipients := []User{{Id: expiry.UserId, Name: member.Name, Email: member.Email}}
+	if owner.Id != expiry.UserId {
+		recipients = append(recipients, owner)
+	}
+	spaceId := expiry.SpaceId
+	for i, recipient := range recipients {
+		title := fmt.Sprintf("Your access to %s ends on %s", spaceName, data["expires_at"])
+		url := fmt.Sprintf("/space/%s", expiry.SpaceId)
+		if i > 0 {
+			title = fmt.Sprintf("%s's access to %s ends on %s", memberName, spaceName, data["expires_at"])
+			url = fmt.Sprintf("/space/%s/settings/users", expiry.SpaceId)
+		}
+		n := inbox.Notification{
+			UserId:   recipient.Id,
+			Category: membershipExpiryEmailCategory,
+			Type:     inbox.MembershipExpiring,
+			Title:    title,
+			Url:      url,
+			SpaceId:  &spaceId,
+			Data:     map[string]interface{}{"userId": expiry.UserId.String(), "expiresAt": expiry.ExpiresAt},
+		}
+		if strings.TrimSpace(recipient.Email) != "" {
+			templateData := make(map[string]any, len(data)+1)
+			for key, value := range data {
+				templateData[key] = value
+			}
+			templateData["for_owner"] = i > 0
+			userId := recipient.Id
+			n.Email = &notification.EnqueueEmailRequest{
+				MessageKey:  fmt.Sprintf("%s:%s:%s:%d:%s", notification.TemplateSpaceMembershipExpiring, expiry.SpaceId, expiry.UserId, expiry.ExpiresAt.Unix(), recipient.Id),
+				Category:    membershipExpiryEmailCategory,
+				TemplateKey: notification.TemplateSpaceMembershipExpiring,
+				Recipient: notification.EmailRecipient{
+					UserID: &userId,
+					Email:  recipient.Email,
+					Name:   recipient.Name,
+				},
+				TemplateData: templateData,
+				Priority:     notification.PriorityNormal,
+			}
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package space
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadExpiryConfigDefaults(t *testing.T) {
+	t.Setenv("MEMBERSHIP_EXPIRY_ENABLED", "")
+	t.Setenv("MEMBERSHIP_EXPIRY_INTERVAL_MINUTES", "0")
+	t.Setenv("MEMBERSHIP_EXPIRY_BATCH_SIZE", "")
+	t.Setenv("MEMBERSHIP_EXPIRY_NOTICE_DAYS", "soon")
+
+	config := LoadExpiryConfig()
+	if !config.Enabled {
+		t.Fatalf("expected expiry worker enabled by default")
+	}
+	if config.Interval != 15*time.Minute {
+		t.Fatalf("expected 15 minute interval, got %s", config.Interval)
+	}
+	if config.BatchSize != 50 {
+		t.Fatalf("expected batch size 50, got %d", config.BatchSize)
+	}
+	if config.Notice != 3*24*time.Hour {
+		t.Fatalf("expected 3 day notice, got %s", config.Notice)
+	}
+}
+
+func TestValidateMemberExpiryRejectsPastTimes(t *testing.T) {
+	userId := "6f1c2a9e-3b44-4d52-9a61-0c1d2e3f4a5b"
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	if _, err := validateAddSpaceMembers([]byte(`{"members":[{"userId":"` + userId + `","role":"viewer","expiresAt":"` + past + `"}]}`)); err == nil {
+		t.Fatalf("expected an expiry in the past to be rejected when adding members")
+	}
+	req, err := validateAddSpaceMembers([]byte(`{"members":[{"userId":"` + userId + `","role":"viewer","expiresAt":"` + future + `"}]}`))
+	if err != nil || req.Members[0].ExpiresAt == nil {
+		t.Fatalf("expected a future expiry to be kept, got %+v, %v", req, err)
+	}
+	if _, err := validateSpaceMemberExpiry([]byte(`{"userId":"` + userId + `","expiresAt":"` + past + `"}`)); err == nil {
+		t.Fatalf("expected an expiry in the past to be rejected")
+	}
+	cleared, err := validateSpaceMemberExpiry([]byte(`{"userId":"` + userId + `","expiresAt":null}`))
+	if err != nil || cleared.ExpiresAt != nil {
+		t.Fatalf("expected a null expiry to clear it, got %+v, %v", cleared, err)
+	}
+}
@@ -0,0 +1,452 @@
+package space
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	attachmentservices "github.com/durgakiran/beskar/attachment/services"
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/editor"
+	mediaservices "github.com/durgakiran/beskar/media/services"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// exportSpace writes the space as a zip archive with a manifest.json
+// describing it. Everything is read in one repeatable-read transaction so
+// the archive is a consistent snapshot even while people keep editing.
+// People are stored as user ids while collecting and swapped for their
+// email addresses once all of them are known.
+func exportSpace(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, w io.Writer) (ArchiveManifest, error) {
+	manifest := ArchiveManifest{
+		Format:      ARCHIVE_FORMAT,
+		Version:     ARCHIVE_VERSION,
+		ExportedAt:  time.Now().UTC(),
+		ExportedBy:  actorId.String(),
+		Members:     make([]ArchiveMember, 0),
+		Pages:       make([]ArchivePage, 0),
+		Attachments: make([]ArchiveAttachment, 0),
+		Images:      make([]ArchiveImage, 0),
+		Comments:    make([]ArchiveThread, 0),
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_EDIT) {
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	tx, err := core.GetPool().BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		logger().Error(err.Error())
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, EXPORT_SPACE, spaceId).Scan(&manifest.Space.Id, &manifest.Space.Name, &manifest.Space.Description, &manifest.Space.DateCreated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+
+	archive := zip.NewWriter(w)
+	images := make(map[string]bool)
+
+	pageIds, err := exportPages(ctx, tx, archive, spaceId, &manifest, images)
+	if err != nil {
+		return manifest, err
+	}
+	attachmentIds, err := exportAttachments(ctx, tx, archive, pageIds, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+	if err := exportComments(ctx, tx, pageIds, attachmentIds, &manifest); err != nil {
+		return manifest, err
+	}
+	for name := range images {
+		fileName, err := imageFileName(name)
+		if err != nil {
+			continue
+		}
+		data, err := mediaservices.GetImage(fileName)
+		if err != nil {
+			logger().Warn("space export: image unavailable", zap.String("image", fileName), zap.Error(err))
+			continue
+		}
+		image := ArchiveImage{Name: fileName, File: "images/" + fileName}
+		if err := writeArchiveFile(archive, image.File, data); err != nil {
+			return manifest, err
+		}
+		manifest.Images = append(manifest.Images, image)
+	}
+	if err := exportMembers(ctx, spaceId, &manifest); err != nil {
+		return manifest, err
+	}
+	if err := replaceUserIdsWithEmails(&manifest); err != nil {
+		return manifest, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := writeArchiveFile(archive, ARCHIVE_MANIFEST, data); err != nil {
+		return manifest, err
+	}
+	return manifest, archive.Close()
+}
+
+// exportPages adds every page with all of its published versions, drafts and
+// whiteboard state, and returns the ids of the exported pages.
+func exportPages(ctx context.Context, tx pgx.Tx, archive *zip.Writer, spaceId uuid.UUID, manifest *ArchiveManifest, images map[string]bool) ([]int64, error) {
+	rows, err := tx.Query(ctx, EXPORT_PAGES, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	pageIndex := make(map[int64]int)
+	pageIds := make([]int64, 0)
+	for rows.Next() {
+		var page ArchivePage
+		var ownerId *uuid.UUID
+		if err := rows.Scan(&page.Id, &page.ParentId, &page.Type, &ownerId, &page.DateCreated, &page.Status); err != nil {
+			rows.Close()
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		if ownerId != nil {
+			page.Owner = ownerId.String()
+		}
+		page.Docs = make([]ArchiveDoc, 0)
+		pageIndex[page.Id] = len(manifest.Pages)
+		pageIds = append(pageIds, page.Id)
+		manifest.Pages = append(manifest.Pages, page)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+
+	type docRow struct {
+		doc    ArchiveDoc
+		pageId int64
+	}
+	rows, err = tx.Query(ctx, EXPORT_DOCS, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	docs := make([]docRow, 0)
+	for rows.Next() {
+		var row docRow
+		var ownerId *uuid.UUID
+		var draft int
+		if err := rows.Scan(&row.doc.Id, &row.pageId, &row.doc.Title, &row.doc.Version, &ownerId, &draft); err != nil {
+			rows.Close()
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		if ownerId != nil {
+			row.doc.Owner = ownerId.String()
+		}
+		row.doc.Draft = draft != 0
+		docs = append(docs, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+
+	for _, row := range docs {
+		doc := row.doc
+		prefix := fmt.Sprintf("pages/%d/docs/%d/", row.pageId, doc.Id)
+		if !doc.Draft {
+			nodes, err := editor.FetchContent(tx, ctx, doc.Id)
+			if err != nil {
+				return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+			}
+			if len(nodes.Content) > 0 || len(nodes.Text) > 0 {
+				data, err := json.Marshal(nodes)
+				if err != nil {
+					return nil, err
+				}
+				doc.Nodes = prefix + "nodes.json"
+				if err := writeArchiveFile(archive, doc.Nodes, data); err != nil {
+					return nil, err
+				}
+				for _, name := range referencedImages(data) {
+					images[name] = true
+				}
+			}
+		}
+		var draftData []byte
+		err := tx.QueryRow(ctx, EXPORT_DOC_DRAFT, doc.Id).Scan(&draftData)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+		}
+		if len(draftData) > 0 {
+			doc.DraftData = prefix + "draft.bin"
+			if err := writeArchiveFile(archive, doc.DraftData, draftData); err != nil {
+				return nil, err
+			}
+			for _, name := range referencedImages(draftData) {
+				images[name] = true
+			}
+		}
+		var whiteboard []byte
+		err = tx.QueryRow(ctx, EXPORT_DOC_WHITEBOARD, doc.Id).Scan(&whiteboard)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+		}
+		if len(whiteboard) > 0 {
+			doc.Whiteboard = prefix + "whiteboard.bin"
+			if err := writeArchiveFile(archive, doc.Whiteboard, whiteboard); err != nil {
+				return nil, err
+			}
+			for _, name := range referencedImages(whiteboard) {
+				images[name] = true
+			}
+		}
+		index := pageIndex[row.pageId]
+		manifest.Pages[index].Docs = append(manifest.Pages[index].Docs, doc)
+	}
+	return pageIds, nil
+}
+
+// exportAttachments adds the files attached to the exported pages. Files
+// missing from storage are left out and logged rather than failing the
+// export. It returns the ids of the attachments that made it in.
+func exportAttachments(ctx context.Context, tx pgx.Tx, archive *zip.Writer, pageIds []int64, manifest *ArchiveManifest) (map[uuid.UUID]bool, error) {
+	exported := make(map[uuid.UUID]bool)
+	rows, err := tx.Query(ctx, EXPORT_ATTACHMENTS, pageIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	type attachmentRow struct {
+		attachment  ArchiveAttachment
+		storagePath string
+	}
+	attachments := make([]attachmentRow, 0)
+	for rows.Next() {
+		var row attachmentRow
+		a := &row.attachment
+		if err := rows.Scan(&a.Id, &a.PageId, &row.storagePath, &a.FileName, &a.FileSize, &a.MimeType, &a.CreatedBy, &a.CreatedAt); err != nil {
+			rows.Close()
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		attachments = append(attachments, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for _, row := range attachments {
+		data, err := attachmentservices.ReadAttachmentBytes(row.storagePath)
+		if err != nil {
+			logger().Warn("space export: attachment unavailable", zap.String("attachment_id", row.attachment.Id.String()), zap.Error(err))
+			continue
+		}
+		row.attachment.File = "attachments/" + row.attachment.Id.String()
+		if err := writeArchiveFile(archive, row.attachment.File, data); err != nil {
+			return nil, err
+		}
+		exported[row.attachment.Id] = true
+		manifest.Attachments = append(manifest.Attachments, row.attachment)
+	}
+	return exported, nil
+}
+
+func exportComments(ctx context.Context, tx pgx.Tx, pageIds []int64, attachmentIds map[uuid.UUID]bool, manifest *ArchiveManifest) error {
+	rows, err := tx.Query(ctx, EXPORT_COMMENT_THREADS, pageIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	threadIndex := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var threadId uuid.UUID
+		var thread ArchiveThread
+		var createdBy, resolvedBy *string
+		if err := rows.Scan(&threadId, &thread.PageId, &thread.CommentId, &thread.QuotedText, &thread.Anchor, &thread.PublishedVisible, &thread.Orphaned, &createdBy, &resolvedBy, &thread.ResolvedAt, &thread.CreatedAt); err != nil {
+			rows.Close()
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		if createdBy != nil {
+			thread.CreatedBy = *createdBy
+		}
+		if resolvedBy != nil {
+			thread.ResolvedBy = *resolvedBy
+		}
+		thread.Replies = make([]ArchiveReply, 0)
+		threadIndex[threadId] = len(manifest.Comments)
+		manifest.Comments = append(manifest.Comments, thread)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+
+	rows, err = tx.Query(ctx, EXPORT_COMMENT_REPLIES, pageIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	type replyPosition struct{ thread, reply int }
+	replyIndex := make(map[uuid.UUID]replyPosition)
+	replyIds := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var replyId, threadId uuid.UUID
+		var reply ArchiveReply
+		var author *string
+		if err := rows.Scan(&replyId, &threadId, &author, &reply.Body, &reply.EditedAt, &reply.CreatedAt); err != nil {
+			rows.Close()
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		index, ok := threadIndex[threadId]
+		if !ok {
+			continue
+		}
+		if author != nil {
+			reply.Author = *author
+		}
+		reply.Attachments = make([]uuid.UUID, 0)
+		replyIndex[replyId] = replyPosition{thread: index, reply: len(manifest.Comments[index].Replies)}
+		replyIds = append(replyIds, replyId)
+		manifest.Comments[index].Replies = append(manifest.Comments[index].Replies, reply)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+
+	rows, err = tx.Query(ctx, EXPORT_REPLY_ATTACHMENTS, replyIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var replyId, attachmentId uuid.UUID
+		if err := rows.Scan(&replyId, &attachmentId); err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		position, ok := replyIndex[replyId]
+		if !ok || !attachmentIds[attachmentId] {
+			continue
+		}
+		reply := &manifest.Comments[position.thread].Replies[position.reply]
+		reply.Attachments = append(reply.Attachments, attachmentId)
+	}
+	return rows.Err()
+}
+
+// exportMembers records the direct members of the space. Group grants are
+// left out since groups belong to the organization, not the space.
+func exportMembers(ctx context.Context, spaceId uuid.UUID, manifest *ArchiveManifest) error {
+	tuples, err := core.GetSubjectsAssociatedWithEntity(ctx, "space", spaceId.String())
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	roles := make(map[string][]string)
+	order := make([]string, 0)
+	for _, tuple := range tuples {
+		if tuple.Subject.Type != "user" {
+			continue
+		}
+		if _, ok := roles[tuple.Subject.Id]; !ok {
+			order = append(order, tuple.Subject.Id)
+		}
+		roles[tuple.Subject.Id] = append(roles[tuple.Subject.Id], tuple.Relation)
+	}
+	for _, userId := range order {
+		manifest.Members = append(manifest.Members, ArchiveMember{Email: userId, Role: normalizeRole(getHighestRole(roles[userId]))})
+	}
+	return nil
+}
+
+// replaceUserIdsWithEmails swaps every user id collected in the manifest for
+// the user's email. Users that no longer resolve are dropped from members and
+// left blank elsewhere.
+func replaceUserIdsWithEmails(manifest *ArchiveManifest) error {
+	ids := make(map[string]bool)
+	ids[manifest.ExportedBy] = true
+	for _, member := range manifest.Members {
+		ids[member.Email] = true
+	}
+	for _, page := range manifest.Pages {
+		ids[page.Owner] = true
+		for _, doc := range page.Docs {
+			ids[doc.Owner] = true
+		}
+	}
+	for _, attachment := range manifest.Attachments {
+		ids[attachment.CreatedBy] = true
+	}
+	for _, thread := range manifest.Comments {
+		ids[thread.CreatedBy] = true
+		ids[thread.ResolvedBy] = true
+		for _, reply := range thread.Replies {
+			ids[reply.Author] = true
+		}
+	}
+	userIds := make([]string, 0, len(ids))
+	for id := range ids {
+		if _, err := uuid.Parse(id); err == nil {
+			userIds = append(userIds, id)
+		}
+	}
+	profiles, err := core.GetUserProfiles(userIds)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	email := func(id string) string {
+		return profiles[id].Email
+	}
+
+	manifest.ExportedBy = email(manifest.ExportedBy)
+	members := make([]ArchiveMember, 0, len(manifest.Members))
+	for _, member := range manifest.Members {
+		if member.Email = email(member.Email); member.Email != "" {
+			members = append(members, member)
+		}
+	}
+	manifest.Members = members
+	for i := range manifest.Pages {
+		page := &manifest.Pages[i]
+		page.Owner = email(page.Owner)
+		for j := range page.Docs {
+			page.Docs[j].Owner = email(page.Docs[j].Owner)
+		}
+	}
+	for i := range manifest.Attachments {
+		manifest.Attachments[i].CreatedBy = email(manifest.Attachments[i].CreatedBy)
+	}
+	for i := range manifest.Comments {
+		thread := &manifest.Comments[i]
+		thread.CreatedBy = email(thread.CreatedBy)
+		thread.ResolvedBy = email(thread.ResolvedBy)
+		for j := range thread.Replies {
+			thread.Replies[j].Author = email(thread.Replies[j].Author)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package space
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	attachmentservices "github.com/durgakiran/beskar/attachment/services"
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/editor"
+	"github.com/durgakiran/beskar/org"
+	"github.com/durgakiran/beskar/slug"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	archiveManifestLimit = 64 << 20
+	archiveDocLimit      = 256 << 20
+)
+
+// importSpace recreates an exported space in the current organization with
+// the caller as owner. Every id gets remapped: pages, docs, attachments and
+// comment threads are new rows, and links between them in published content
+// are rewritten. People are matched by email; anyone unknown here is
+// reported and their content attributed to the caller.
+func importSpace(ctx context.Context, actorId uuid.UUID, archivePath string) (ImportResult, error) {
+	result := ImportResult{
+		Pages:            make(map[string]int64),
+		UnresolvedEmails: make([]string, 0),
+		Skipped:          make([]string, 0),
+	}
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	defer reader.Close()
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+	data, err := readArchiveFile(files, ARCHIVE_MANIFEST, archiveManifestLimit)
+	if err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	manifest, err := validateArchiveManifest(data)
+	if err != nil {
+		return result, err
+	}
+	users := resolveArchiveEmails(manifest, &result)
+	userOrActor := func(email string) uuid.UUID {
+		if userId, ok := users[strings.ToLower(email)]; ok {
+			return userId
+		}
+		return actorId
+	}
+	userOrNil := func(email string) *string {
+		if userId, ok := users[strings.ToLower(email)]; ok {
+			id := userId.String()
+			return &id
+		}
+		return nil
+	}
+
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	// files written to storage are removed again unless the import commits
+	written := make([]string, 0)
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		for _, path := range written {
+			_ = os.Remove(path)
+		}
+	}()
+
+	now := time.Now()
+	space := Space{Name: manifest.Space.Name, Description: manifest.Space.Description, DateCreated: now, DateUpdated: now, CreatedBy: actorId}
+	spaceId, err := space.Create(tx, ctx)
+	if err != nil {
+		return result, err
+	}
+	result.SpaceId = spaceId
+	ids := archiveIds{
+		toSpace:     spaceId.String(),
+		pages:       make(map[int64]int64, len(manifest.Pages)),
+		attachments: make(map[string]string, len(manifest.Attachments)),
+	}
+	if manifest.Space.Id != uuid.Nil {
+		ids.fromSpace = manifest.Space.Id.String()
+	}
+
+	// pages are ordered parents first by validation
+	parents := make(map[string]string)
+	pageSpaces := make(map[string]string, len(manifest.Pages))
+	for _, page := range manifest.Pages {
+		parentId := page.ParentId
+		if mapped, ok := ids.pages[page.ParentId]; ok {
+			parentId = mapped
+		} else if parentId > 0 {
+			parentId = 0
+		}
+		var pageId int64
+		err := tx.QueryRow(ctx, IMPORT_PAGE, spaceId, userOrActor(page.Owner), parentId, page.DateCreated, page.Status, page.Type).Scan(&pageId)
+		if err != nil {
+			logger().Error(err.Error())
+			return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		ids.pages[page.Id] = pageId
+		result.Pages[strconv.FormatInt(page.Id, 10)] = pageId
+		pageSpaces[strconv.FormatInt(pageId, 10)] = spaceId.String()
+		if parentId > 0 {
+			parents[strconv.FormatInt(pageId, 10)] = strconv.FormatInt(parentId, 10)
+		}
+	}
+
+	// attachments come before content so their new ids can be linked
+	for _, attachment := range manifest.Attachments {
+		if !attachmentservices.MimeAllowed(attachment.MimeType) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("attachment %s: type %s is not allowed", attachment.FileName, attachment.MimeType))
+			continue
+		}
+		data, err := readArchiveFile(files, attachment.File, int64(attachmentservices.MaxAttachmentBytes))
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("attachment %s: %v", attachment.FileName, err))
+			continue
+		}
+		relPath, fullPath, err := attachmentservices.WriteAttachmentFile(attachment.FileName, data)
+		if err != nil {
+			return result, err
+		}
+		written = append(written, fullPath)
+		var attachmentId uuid.UUID
+		err = tx.QueryRow(ctx, IMPORT_ATTACHMENT, ids.pages[attachment.PageId], relPath, attachmentservices.SanitizeDisplayName(attachment.FileName), int64(len(data)), attachment.MimeType, userOrActor(attachment.CreatedBy).String(), attachment.CreatedAt).Scan(&attachmentId)
+		if err != nil {
+			logger().Error(err.Error())
+			return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		ids.attachments[attachment.Id.String()] = attachmentId.String()
+		result.Attachments++
+	}
+
+	for _, page := range manifest.Pages {
+		for _, doc := range page.Docs {
+			if err := importDoc(ctx, tx, files, ids, ids.pages[page.Id], doc, userOrActor(doc.Owner)); err != nil {
+				return result, err
+			}
+		}
+		if err := slug.SyncPageSlug(ctx, tx, ids.pages[page.Id], publishedTitle(page.Docs)); err != nil {
+			return result, err
+		}
+	}
+
+	if len(manifest.Images) > 0 {
+		if err := os.MkdirAll(core.ImageStorageDir(), 0o755); err != nil {
+			return result, err
+		}
+	}
+	for _, image := range manifest.Images {
+		name, err := imageFileName(image.Name)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("image %s: %v", image.Name, err))
+			continue
+		}
+		// image names carry a random suffix; one that exists is the same upload
+		fullPath := filepath.Join(core.ImageStorageDir(), name)
+		if _, err := os.Stat(fullPath); err == nil {
+			continue
+		}
+		data, err := readArchiveFile(files, image.File, archiveDocLimit)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("image %s: %v", name, err))
+			continue
+		}
+		if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+			return result, err
+		}
+		written = append(written, fullPath)
+		result.Images++
+	}
+
+	for _, thread := range manifest.Comments {
+		anchor := string(thread.Anchor)
+		if anchor == "" || anchor == "null" {
+			anchor = "{}"
+		}
+		var threadId uuid.UUID
+		err := tx.QueryRow(ctx, IMPORT_COMMENT_THREAD, ids.pages[thread.PageId], thread.CommentId, thread.QuotedText, anchor, thread.PublishedVisible, thread.Orphaned, userOrNil(thread.CreatedBy), userOrNil(thread.ResolvedBy), thread.ResolvedAt, thread.CreatedAt).Scan(&threadId)
+		if err != nil {
+			logger().Error(err.Error())
+			return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+		for _, reply := range thread.Replies {
+			var replyId uuid.UUID
+			err := tx.QueryRow(ctx, IMPORT_COMMENT_REPLY, threadId, userOrNil(reply.Author), reply.Body, reply.EditedAt, reply.CreatedAt).Scan(&replyId)
+			if err != nil {
+				logger().Error(err.Error())
+				return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+			}
+			for _, attachmentId := range reply.Attachments {
+				mapped, ok := ids.attachments[attachmentId.String()]
+				if !ok {
+					continue
+				}
+				if _, err := tx.Exec(ctx, IMPORT_REPLY_ATTACHMENT, replyId, mapped); err != nil {
+					logger().Error(err.Error())
+					return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+				}
+			}
+		}
+		result.Comments++
+	}
+
+	relations := []core.RelationChange{core.RelationWrite("space", spaceId.String(), "owner", "user", actorId.String())}
+	for pageId, pageSpace := range pageSpaces {
+		relations = append(relations, core.RelationWrite("page", pageId, "space", "space", pageSpace).AsStructural())
+	}
+	for pageId, parentId := range parents {
+		relations = append(relations, core.RelationWrite("page", pageId, "parent", "page", parentId).AsStructural())
+	}
+	members, err := importMembers(ctx, spaceId, actorId, manifest.Members, users, &result)
+	if err != nil {
+		return result, err
+	}
+	if err := core.QueueRelations(ctx, tx, append(relations, members...)...); err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return result, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	committed = true
+	core.ApplyQueuedRelations(ctx)
+	return result, nil
+}
+
+// publishedTitle is the title of the newest published doc, which is what page
+// slugs follow. Pages that were never published use their newest draft.
+func publishedTitle(docs []ArchiveDoc) string {
+	var best *ArchiveDoc
+	for i := range docs {
+		doc := &docs[i]
+		if best == nil || (best.Draft && !doc.Draft) || (best.Draft == doc.Draft && doc.Version.After(best.Version)) {
+			best = doc
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Title
+}
+
+// importDoc recreates one page_doc_map row with its published content,
+// draft and whiteboard state.
+func importDoc(ctx context.Context, tx pgx.Tx, files map[string]*zip.File, ids archiveIds, pageId int64, doc ArchiveDoc, ownerId uuid.UUID) error {
+	draft := 0
+	if doc.Draft {
+		draft = 1
+	}
+	var docId int64
+	if err := tx.QueryRow(ctx, IMPORT_DOC, pageId, doc.Title, doc.Version, ownerId, draft).Scan(&docId); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if doc.Nodes != "" {
+		data, err := readArchiveFile(files, doc.Nodes, archiveDocLimit)
+		if err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		var nodes editor.NodeData
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		nodes = ids.rewriteNodes(nodes)
+		for _, node := range nodes.Content {
+			node.DocId = docId
+			if _, err := node.Create(tx, ctx); err != nil {
+				return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+			}
+		}
+		for _, node := range nodes.Text {
+			node.DocId = docId
+			if _, err := node.Create(tx, ctx); err != nil {
+				return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+			}
+		}
+	}
+	if doc.DraftData != "" {
+		data, err := readArchiveFile(files, doc.DraftData, archiveDocLimit)
+		if err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if _, err := tx.Exec(ctx, IMPORT_DOC_DRAFT, docId, ids.rewriteBinary(data)); err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+	}
+	if doc.Whiteboard != "" {
+		data, err := readArchiveFile(files, doc.Whiteboard, archiveDocLimit)
+		if err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if _, err := tx.Exec(ctx, IMPORT_DOC_WHITEBOARD, docId, ids.rewriteBinary(data)); err != nil {
+			logger().Error(err.Error())
+			return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
+	}
+	return nil
+}
+
+// resolveArchiveEmails looks up every email mentioned in the manifest and
+// returns the local user id for those that exist, keyed by lower-cased email.
+func resolveArchiveEmails(manifest ArchiveManifest, result *ImportResult) map[string]uuid.UUID {
+	emails := make(map[string]bool)
+	add := func(email string) {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails[email] = true
+		}
+	}
+	for _, member := range manifest.Members {
+		add(member.Email)
+	}
+	for _, page := range manifest.Pages {
+		add(page.Owner)
+		for _, doc := range page.Docs {
+			add(doc.Owner)
+		}
+	}
+	for _, attachment := range manifest.Attachments {
+		add(attachment.CreatedBy)
+	}
+	for _, thread := range manifest.Comments {
+		add(thread.CreatedBy)
+		add(thread.ResolvedBy)
+		for _, reply := range thread.Replies {
+			add(reply.Author)
+		}
+	}
+
+	users := make(map[string]uuid.UUID, len(emails))
+	for email := range emails {
+		found, err := core.SearchUserByEmail(email, 1, 0)
+		if err != nil {
+			logger().Error(err.Error())
+		}
+		for _, user := range found.Result {
+			if !strings.EqualFold(user.Human.Email.Email, email) {
+				continue
+			}
+			id, err := core.GetBeskarUser(user.UserId)
+			if err != nil {
+				logger().Error(err.Error())
+				continue
+			}
+			if userId, err := uuid.Parse(id); err == nil {
+				users[email] = userId
+			}
+		}
+		if _, ok := users[email]; !ok {
+			result.UnresolvedEmails = append(result.UnresolvedEmails, email)
+		}
+	}
+	return users
+}
+
+// importMembers grants the archived roles to members that exist here and
+// belong to the organization. The importer is the owner, so an archived
+// owner comes back as admin. The grants are returned for the caller to queue.
+func importMembers(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, members []ArchiveMember, users map[string]uuid.UUID, result *ImportResult) ([]core.RelationChange, error) {
+	candidates := make([]string, 0, len(members))
+	for _, member := range members {
+		if userId, ok := users[strings.ToLower(member.Email)]; ok {
+			candidates = append(candidates, userId.String())
+		}
+	}
+	inOrganization, err := org.MemberIds(ctx, core.OrganizationFromContext(ctx), candidates)
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]core.RelationChange, 0, len(candidates))
+	for _, member := range members {
+		userId, ok := users[strings.ToLower(member.Email)]
+		if !ok || userId == actorId {
+			continue
+		}
+		if !inOrganization[userId.String()] {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("member %s: not in this organization", member.Email))
+			continue
+		}
+		role := normalizeIncomingRole(member.Role)
+		if role == "owner" {
+			role = "admin"
+		}
+		if !isValidMemberRole(role) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("member %s: unknown role %s", member.Email, member.Role))
+			continue
+		}
+		grants = append(grants, core.RelationWrite("space", spaceId.String(), storageRole(role), "user", userId.String()))
+		result.Members++
+	}
+	return grants, nil
+}
@@ -0,0 +1,295 @@
+package space
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/org"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const purgeRelationBatch = 100
+
+// Purger permanently removes spaces whose soft delete is older than the grace
+// period: Permify relations, attachment and image files, pending invites and
+// every row hanging off core.space.
+type Purger struct {
+	config PurgeConfig
+}
+
+func NewPurger(config PurgeConfig) *Purger {
+	return &Purger{config: config}
+}
+
+func (p *Purger) Start(ctx context.Context) {
+	if !p.config.Enabled {
+		return
+	}
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			p.PurgeDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeDue purges one batch of expired spaces and returns their reports.
+// A space that fails is left in place and retried on the next run.
+func (p *Purger) PurgeDue(ctx context.Context) []PurgeReport {
+	reports := make([]PurgeReport, 0)
+	cutoff := time.Now().Add(-p.config.GracePeriod)
+	rows, err := core.GetPool().Query(ctx, GET_PURGE_DUE_SPACES, cutoff, p.config.BatchSize)
+	if err != nil {
+		logger().Error("space purge: listing due spaces failed", zap.Error(err))
+		return reports
+	}
+	spaceIds, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		logger().Error("space purge: reading due spaces failed", zap.Error(err))
+		return reports
+	}
+	for _, spaceId := range spaceIds {
+		report, purged, err := purgeSpace(ctx, spaceId, cutoff)
+		if err != nil {
+			logger().Error("space purge failed", zap.String("space_id", spaceId.String()), zap.Error(err))
+			continue
+		}
+		if !purged {
+			continue
+		}
+		audit.RecordSystem(audit.Entry{
+			Action:     audit.ActionSpacePurged,
+			TargetType: "space",
+			TargetId:   spaceId.String(),
+			SpaceId:    &spaceId,
+			Before:     map[string]string{"name": report.SpaceName},
+			After:      report,
+		})
+		logger().Info("space purged",
+			zap.String("space_id", spaceId.String()),
+			zap.Int("pages", report.Pages),
+			zap.Int("attachments", report.Attachments),
+			zap.Int("images", report.Images),
+			zap.Int64("bytes_freed", report.BytesFreed),
+			zap.Int("errors", len(report.Errors)),
+		)
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// purgeSpace holds the space row locked for the whole purge so a concurrent
+// restore either wins before it starts or finds nothing to restore. Permify is
+// cleaned first: if that fails nothing else has been touched and the next run
+// retries. Files go before the rows are deleted so the report can include
+// them; a file that cannot be removed is recorded rather than blocking.
+func purgeSpace(ctx context.Context, spaceId uuid.UUID, cutoff time.Time) (PurgeReport, bool, error) {
+	var report PurgeReport
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		return report, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, LOCK_PURGE_SPACE, spaceId, cutoff).Scan(&report.SpaceId, &report.SpaceName, &report.OrgId, &report.DeletedAt, &report.DeletedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// restored meanwhile, or another instance is purging it
+		return report, false, nil
+	}
+	if err != nil {
+		return report, false, err
+	}
+
+	rows, err := tx.Query(ctx, GET_SPACE_PAGE_IDS, spaceId)
+	if err != nil {
+		return report, false, err
+	}
+	pageIds, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return report, false, err
+	}
+	rows, err = tx.Query(ctx, GET_SPACE_DOC_IDS, spaceId)
+	if err != nil {
+		return report, false, err
+	}
+	docIds, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return report, false, err
+	}
+	rows, err = tx.Query(ctx, GET_PAGE_ATTACHMENTS, pageIds)
+	if err != nil {
+		return report, false, err
+	}
+	attachmentPaths, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return report, false, err
+	}
+	rows, err = tx.Query(ctx, GET_DOC_IMAGE_NAMES, docIds)
+	if err != nil {
+		return report, false, err
+	}
+	imageNames, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return report, false, err
+	}
+	pageKeys := make([]string, 0, len(pageIds))
+	for _, pageId := range pageIds {
+		pageKeys = append(pageKeys, fmt.Sprintf("%v", pageId))
+	}
+	report.Pages = len(pageIds)
+
+	relations, err := purgeSpaceRelations(ctx, spaceId, pageKeys)
+	if err != nil {
+		return report, false, err
+	}
+	report.Relations = relations
+
+	tag, err := tx.Exec(ctx, DELETE_SPACE_INVITES, spaceId.String(), pageKeys)
+	if err != nil {
+		return report, false, err
+	}
+	report.Invites = int(tag.RowsAffected())
+
+	for _, storagePath := range attachmentPaths {
+		freed, err := removeAttachmentFile(storagePath)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("attachment %s: %v", storagePath, err))
+			continue
+		}
+		if freed >= 0 {
+			report.Attachments++
+			report.BytesFreed += freed
+		}
+	}
+	for _, name := range imageNames {
+		var elsewhere bool
+		if err := tx.QueryRow(ctx, IMAGE_REFERENCED_ELSEWHERE, name, docIds).Scan(&elsewhere); err != nil {
+			return report, false, err
+		}
+		if elsewhere {
+			continue
+		}
+		freed, err := removeImageFile(name)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("image %s: %v", name, err))
+			continue
+		}
+		if freed >= 0 {
+			report.Images++
+			report.BytesFreed += freed
+		}
+	}
+
+	// pages, docs, content, comments, attachments rows, stars, activity and
+	// views all cascade from core.space
+	if _, err := tx.Exec(ctx, PURGE_SPACE, spaceId); err != nil {
+		return report, false, err
+	}
+	report.PurgedAt = time.Now()
+	data, err := json.Marshal(report)
+	if err != nil {
+		return report, false, err
+	}
+	if _, err := tx.Exec(ctx, INSERT_PURGE_REPORT, report.SpaceId, report.SpaceName, report.OrgId, report.DeletedAt, report.DeletedBy, report.PurgedAt, data); err != nil {
+		return report, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return report, false, err
+	}
+	return report, true, nil
+}
+
+// purgeSpaceRelations removes every space grant and every relation held by the
+// space's pages, in the tenant of the organization owning the space. It
+// returns the number of space grants removed.
+func purgeSpaceRelations(ctx context.Context, spaceId uuid.UUID, pageKeys []string) (int, error) {
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return 0, err
+	}
+	tuples, err := core.GetSubjectsAssociatedWithEntity(tenantCtx, "space", spaceId.String())
+	if err != nil {
+		return 0, err
+	}
+	seen := map[string]bool{}
+	for _, tuple := range tuples {
+		subject := tuple.GetSubject()
+		key := subject.GetType() + ":" + subject.GetId()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := core.DeleteSubjectRelations(tenantCtx, spaceId.String(), "space", subject.GetId(), subject.GetType()); err != nil {
+			return 0, err
+		}
+	}
+	for start := 0; start < len(pageKeys); start += purgeRelationBatch {
+		end := min(start+purgeRelationBatch, len(pageKeys))
+		if err := core.DeleteEntityRelations(tenantCtx, "page", pageKeys[start:end]); err != nil {
+			return 0, err
+		}
+	}
+	return len(tuples), nil
+}
+
+// removeAttachmentFile deletes an attachment from upload storage and returns
+// its size, or -1 when the file was already gone.
+func removeAttachmentFile(storagePath string) (int64, error) {
+	relPath, err := core.NormalizeAttachmentStoragePath(storagePath)
+	if err != nil {
+		return -1, err
+	}
+	fullPath, err := core.ResolveUploadPath(relPath)
+	if err != nil {
+		return -1, err
+	}
+	return removeUpload(fullPath)
+}
+
+// removeImageFile deletes an uploaded image, looking in the legacy public
+// directory the same way media.GetImage does.
+func removeImageFile(name string) (int64, error) {
+	name, err := imageFileName(name)
+	if err != nil {
+		return -1, err
+	}
+	freed, err := removeUpload(filepath.Join(core.ImageStorageDir(), name))
+	if err != nil || freed >= 0 || core.UploadStorageDir() == "public" {
+		return freed, err
+	}
+	return removeUpload(filepath.Join("public", "images", name))
+}
+
+func removeUpload(fullPath string) (int64, error) {
+	info, err := os.Stat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	if err := os.Remove(fullPath); err != nil {
+		return -1, err
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,52 @@
+package space
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPurgeConfigDefaults(t *testing.T) {
+	t.Setenv("SPACE_PURGE_ENABLED", "")
+	t.Setenv("SPACE_PURGE_GRACE_DAYS", "not-a-number")
+	t.Setenv("SPACE_PURGE_INTERVAL_MINUTES", "0")
+	t.Setenv("SPACE_PURGE_BATCH_SIZE", "")
+
+	config := LoadPurgeConfig()
+	if config.Enabled {
+		t.Fatalf("expected purge worker disabled by default")
+	}
+	if config.GracePeriod != 30*24*time.Hour {
+		t.Fatalf("expected 30 day grace period, got %s", config.GracePeriod)
+	}
+	if config.Interval != time.Hour {
+		t.Fatalf("expected hourly interval, got %s", config.Interval)
+	}
+	if config.BatchSize != 10 {
+		t.Fatalf("expected batch size 10, got %d", config.BatchSize)
+	}
+}
+
+func TestRemoveUploadReportsSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, []byte("12345"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	freed, err := removeUpload(path)
+	if err != nil || freed != 5 {
+		t.Fatalf("expected 5 bytes freed, got %d (%v)", freed, err)
+	}
+	freed, err = removeUpload(path)
+	if err != nil || freed != -1 {
+		t.Fatalf("expected missing file to be skipped, got %d (%v)", freed, err)
+	}
+}
+
+func TestRemoveImageFileRejectsPaths(t *testing.T) {
+	for _, name := range []string{"", "..%2Fsecret", "../secret", ".hidden"} {
+		if _, err := removeImageFile(name); err == nil {
+			t.Fatalf("expected %q to be rejected", name)
+		}
+	}
+}
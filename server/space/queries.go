@@ -1,15 +1,17 @@
 package space
 
 const (
-	GET_SPACE             = `SELECT id, name, description, date_created, date_updated, user_id, archived_at, archived_by, deleted_at, deleted_by FROM core.space WHERE id = $1 AND deleted_at IS NULL`
+	GET_SPACE             = `SELECT s.id, s.name, s.description, s.date_created, s.date_updated, s.user_id, s.archived_at, s.archived_by, s.deleted_at, s.deleted_by, COALESCE(u.slug, '') AS slug FROM core.space s LEFT JOIN core.space_url u ON (u.space_id = s.id AND u.is_current) WHERE s.id = $1 AND s.deleted_at IS NULL`
 	GET_SPACE_SETTINGS    = `SELECT id, name, description, date_created, date_updated, user_id, archived_at, archived_by, deleted_at, deleted_by FROM core.space WHERE id = $1 AND deleted_at IS NULL`
-	GET_SPACES            = `SELECT id, name, description, date_updated, user_id, archived_at FROM core.space WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY date_updated DESC;`
-	INSERT_SPACE          = `INSERT INTO core.space (name, description, date_created, date_updated, user_id) VALUES ( $1, $2, $3, $4, $5) RETURNING id`
+	GET_SPACES            = `SELECT s.id, s.name, s.description, s.date_updated, s.user_id, s.archived_at, COALESCE(u.slug, '') AS slug FROM core.space s LEFT JOIN core.space_url u ON (u.space_id = s.id AND u.is_current) WHERE s.id = ANY($1) AND s.org_id = $2 AND s.deleted_at IS NULL ORDER BY s.date_updated DESC;`
+	INSERT_SPACE          = `INSERT INTO core.space (name, description, date_created, date_updated, user_id, org_id) VALUES ( $1, $2, $3, $4, $5, $6) RETURNING id`
 	UPDATE_SPACE          = `UPDATE core.space SET name = $1, description = $2, date_updated = $3 WHERE id = $4`
 	ARCHIVE_SPACE         = `UPDATE core.space SET archived_at = now(), archived_by = $2, date_updated = now() WHERE id = $1 AND deleted_at IS NULL RETURNING id, name, description, date_created, date_updated, user_id, archived_at, archived_by, deleted_at, deleted_by`
 	UNARCHIVE_SPACE       = `UPDATE core.space SET archived_at = NULL, archived_by = NULL, date_updated = now() WHERE id = $1 AND deleted_at IS NULL RETURNING id, name, description, date_created, date_updated, user_id, archived_at, archived_by, deleted_at, deleted_by`
 	SOFT_DELETE_SPACE     = `UPDATE core.space SET deleted_at = now(), deleted_by = $2, date_updated = now() WHERE id = $1 AND deleted_at IS NULL RETURNING id`
 	GET_SPACE_STATE       = `SELECT archived_at, deleted_at FROM core.space WHERE id = $1`
+	RESTORE_SPACE         = `UPDATE core.space SET deleted_at = NULL, deleted_by = NULL, date_updated = now() WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at > $2 RETURNING id, name, description, date_created, date_updated, user_id, archived_at, archived_by, deleted_at, deleted_by`
+	GET_DELETED_SPACES    = `SELECT id, name, description, deleted_at, deleted_by FROM core.space WHERE id = ANY($1) AND org_id = $2 AND deleted_at IS NOT NULL AND deleted_at > $3 ORDER BY deleted_at DESC`
 	GET_SPACE_PAGE_COUNTS = `SELECT 
 								p.space_id,
 								COUNT(*) FILTER (WHERE COALESCE(p.type, 'document') = 'document') AS doc_count,
@@ -32,4 +34,111 @@ const (
 							WHERE 
 								p.space_id = $1 and p.id = ANY($2)
 							ORDER BY p.id, d.version DESC`
+
+	GET_SPACE_NAME           = `SELECT name FROM core.space WHERE id = $1 AND deleted_at IS NULL`
+	GET_MEMBER_LAST_ACTIVITY = `SELECT user_id, MAX(at) FROM (
+								SELECT actor_id AS user_id, MAX(created_at) AS at FROM core.activity WHERE space_id = $1 AND actor_id = ANY($2) GROUP BY actor_id
+								UNION ALL
+								SELECT user_id, MAX(viewed_at) FROM core.page_views WHERE space_id = $1 AND user_id = ANY($2) GROUP BY user_id
+							) a GROUP BY user_id`
+	GET_REVIEW_DUE_SPACES = `SELECT s.id FROM core.space s
+							WHERE s.deleted_at IS NULL
+								AND NOT EXISTS (SELECT 1 FROM core.access_reviews r WHERE r.space_id = s.id AND r.created_at > $1)
+							ORDER BY s.date_created LIMIT $2`
+	LOCK_REVIEW_SPACE = `SELECT id FROM core.space s WHERE id = $1 AND deleted_at IS NULL
+							AND NOT EXISTS (SELECT 1 FROM core.access_reviews r WHERE r.space_id = s.id AND r.created_at > $2)
+							FOR UPDATE SKIP LOCKED`
+	INSERT_ACCESS_REVIEW = `INSERT INTO core.access_reviews (space_id, created_by, report) VALUES ($1, $2, $3) RETURNING id, created_at`
+	GET_ACCESS_REVIEW    = `SELECT created_at, created_by, completed_at, report FROM core.access_reviews WHERE id = $1 AND space_id = $2`
+	LIST_ACCESS_REVIEWS  = `SELECT r.id, r.created_at, r.created_by, r.completed_at,
+								jsonb_array_length(r.report->'members') AS members,
+								jsonb_array_length(r.report->'invites') AS invites,
+								(SELECT COUNT(*) FROM core.access_review_decisions d WHERE d.review_id = r.id) AS decided
+							FROM core.access_reviews r WHERE r.space_id = $1 ORDER BY r.created_at DESC LIMIT $2`
+	GET_REVIEW_DECISIONS   = `SELECT subject_type, subject_id, decision, decided_by, decided_at FROM core.access_review_decisions WHERE review_id = $1`
+	UPSERT_REVIEW_DECISION = `INSERT INTO core.access_review_decisions (review_id, subject_type, subject_id, decision, decided_by, decided_at)
+							VALUES ($1, $2, $3, $4, $5, now())
+							ON CONFLICT (review_id, subject_type, subject_id) DO UPDATE SET decision = EXCLUDED.decision, decided_by = EXCLUDED.decided_by, decided_at = EXCLUDED.decided_at`
+	COMPLETE_ACCESS_REVIEW     = `UPDATE core.access_reviews SET completed_at = now() WHERE id = $1 AND completed_at IS NULL`
+	GET_SPACE_MEMBER_EXPIRIES  = `SELECT user_id, expires_at FROM core.space_member_expiries WHERE space_id = $1`
+	UPSERT_SPACE_MEMBER_EXPIRY = `INSERT INTO core.space_member_expiries (space_id, user_id, expires_at, created_by) VALUES ($1, $2, $3, $4)
+							ON CONFLICT (space_id, user_id) DO UPDATE SET expires_at = EXCLUDED.expires_at, created_by = EXCLUDED.created_by, created_at = now(), notified_at = NULL`
+	DELETE_SPACE_MEMBER_EXPIRY = `DELETE FROM core.space_member_expiries WHERE space_id = $1 AND user_id = $2`
+	CLAIM_EXPIRY_NOTICES       = `UPDATE core.space_member_expiries SET notified_at = now()
+							WHERE (space_id, user_id) IN (
+								SELECT e.space_id, e.user_id FROM core.space_member_expiries e INNER JOIN core.space s ON (s.id = e.space_id)
+								WHERE e.notified_at IS NULL AND e.expires_at > now() AND e.expires_at <= $1 AND s.deleted_at IS NULL
+								ORDER BY e.expires_at LIMIT $2 FOR UPDATE OF e SKIP LOCKED)
+							RETURNING space_id, user_id, expires_at`
+	GET_EXPIRED_MEMBERSHIPS = `SELECT space_id, user_id, expires_at FROM core.space_member_expiries WHERE expires_at <= now() ORDER BY expires_at LIMIT $1`
+	INSERT_ACCESS_REQUEST   = `INSERT INTO core.access_requests (space_id, page_id, requester_id, message) VALUES ($1, $2, $3, $4)
+							ON CONFLICT (space_id, requester_id) WHERE status = 'pending' DO NOTHING RETURNING id, status, created_at`
+	ACCESS_REQUEST_COLUMNS = `r.id, r.space_id, r.page_id,
+							(SELECT d.title FROM core.page_doc_map d WHERE d.page_id = r.page_id ORDER BY d.version DESC LIMIT 1) AS page_title,
+							r.requester_id, r.message, r.status, r.role, r.response, r.decided_by, r.decided_at, r.created_at`
+	GET_PAGE_TITLE               = `SELECT title FROM core.page_doc_map WHERE page_id = $1 ORDER BY version DESC LIMIT 1`
+	LIST_PENDING_ACCESS_REQUESTS = `SELECT ` + ACCESS_REQUEST_COLUMNS + ` FROM core.access_requests r WHERE r.space_id = $1 AND r.status = 'pending' ORDER BY r.created_at`
+	LOCK_PENDING_ACCESS_REQUEST  = `SELECT ` + ACCESS_REQUEST_COLUMNS + ` FROM core.access_requests r WHERE r.id = $1 AND r.space_id = $2 AND r.status = 'pending' FOR UPDATE OF r`
+	DECIDE_ACCESS_REQUEST        = `UPDATE core.access_requests SET status = $2, role = $3, response = $4, decided_by = $5, decided_at = now() WHERE id = $1 RETURNING decided_at`
+	LOCK_EXPIRED_MEMBERSHIP      = `SELECT user_id FROM core.space_member_expiries WHERE space_id = $1 AND user_id = $2 AND expires_at <= now() FOR UPDATE SKIP LOCKED`
+
+	GET_SPACE_JOIN_SETTINGS    = `SELECT join_policy, join_domains, join_role FROM core.space WHERE id = $1 AND deleted_at IS NULL`
+	UPDATE_SPACE_JOIN_SETTINGS = `UPDATE core.space SET join_policy = $2, join_domains = $3, join_role = $4, date_updated = now() WHERE id = $1 AND deleted_at IS NULL`
+	LOCK_JOINABLE_SPACE        = `SELECT join_policy, join_domains, join_role FROM core.space WHERE id = $1 AND deleted_at IS NULL AND archived_at IS NULL FOR SHARE`
+	GET_SPACE_DIRECTORY        = `SELECT s.id, s.name, s.description, COALESCE(u.slug, '') AS slug, s.join_policy, s.join_role, s.org_id, o.tenant_id
+							FROM core.space s
+								INNER JOIN core.organizations o ON (o.id = s.org_id)
+								LEFT JOIN core.space_url u ON (u.space_id = s.id AND u.is_current)
+							WHERE s.deleted_at IS NULL AND s.archived_at IS NULL
+								AND (s.join_policy = 'open' OR (s.join_policy = 'domain' AND $1 <> '' AND $1 = ANY(s.join_domains)))
+								AND ($2 = '' OR s.name ILIKE '%' || $2 || '%')
+							ORDER BY lower(s.name), s.id LIMIT $3 OFFSET $4`
+
+	GET_PURGE_DUE_SPACES = `SELECT id FROM core.space WHERE deleted_at IS NOT NULL AND deleted_at <= $1 ORDER BY deleted_at LIMIT $2`
+	LOCK_PURGE_SPACE     = `SELECT id, name, org_id, deleted_at, deleted_by FROM core.space WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at <= $2 FOR UPDATE SKIP LOCKED`
+	GET_SPACE_PAGE_IDS   = `SELECT id FROM core.page WHERE space_id = $1`
+	GET_SPACE_DOC_IDS    = `SELECT d.doc_id FROM core.page_doc_map d INNER JOIN core.page p ON (p.id = d.page_id) WHERE p.space_id = $1`
+	GET_PAGE_ATTACHMENTS = `SELECT storage_path FROM core.attachment WHERE page_id = ANY($1)`
+	// image uploads are only linked to documents through their URL in the content
+	GET_DOC_IMAGE_NAMES = `SELECT DISTINCT m[1]
+							FROM (
+								SELECT attrs::text AS body FROM core.content WHERE doc_id = ANY($1)
+								UNION ALL
+								SELECT data::text AS body FROM core.content_draft WHERE doc_id = ANY($1)
+							) docs, regexp_matches(docs.body, '/media/image/([^"?#/\\\s]+)', 'g') AS m`
+	IMAGE_REFERENCED_ELSEWHERE = `SELECT EXISTS (
+								SELECT 1 FROM core.content WHERE doc_id <> ALL($2) AND attrs::text LIKE '%/media/image/' || $1 || '%'
+								UNION ALL
+								SELECT 1 FROM core.content_draft WHERE doc_id <> ALL($2) AND data::text LIKE '%/media/image/' || $1 || '%'
+							)`
+	DELETE_SPACE_INVITES = `DELETE FROM notifications.invites
+							WHERE status IS NULL AND ((entity = 'space' AND entity_id = $1) OR (entity = 'page' AND entity_id = ANY($2)))`
+	PURGE_SPACE         = `DELETE FROM core.space WHERE id = $1 AND deleted_at IS NOT NULL`
+	INSERT_PURGE_REPORT = `INSERT INTO core.space_purge_reports (space_id, space_name, org_id, deleted_at, deleted_by, purged_at, report) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	EXPORT_SPACE        = `SELECT id, name, description, date_created FROM core.space WHERE id = $1 AND deleted_at IS NULL`
+	EXPORT_PAGES        = `SELECT id, COALESCE(parent_id, 0), COALESCE(type, 'document'), owner_id, date_created, COALESCE(status, 0)
+							FROM core.page WHERE space_id = $1 ORDER BY id`
+	EXPORT_DOCS = `SELECT d.doc_id, d.page_id, COALESCE(d.title, ''), d.version, d.owner_id, COALESCE(d.draft, 0)
+							FROM core.page_doc_map d INNER JOIN core.page p ON (p.id = d.page_id)
+							WHERE p.space_id = $1 ORDER BY d.page_id, d.version`
+	EXPORT_DOC_DRAFT       = `SELECT data_binary FROM core.content_draft WHERE doc_id = $1 AND data_binary IS NOT NULL ORDER BY id DESC LIMIT 1`
+	EXPORT_DOC_WHITEBOARD  = `SELECT data FROM core.whiteboard_data WHERE doc_id = $1 AND data IS NOT NULL`
+	EXPORT_ATTACHMENTS     = `SELECT id, page_id, storage_path, file_name, file_size, mime_type, created_by, created_at FROM core.attachment WHERE page_id = ANY($1) AND deleted_at IS NULL`
+	EXPORT_COMMENT_THREADS = `SELECT id, document_id, comment_id, quoted_text, anchor, published_visible, orphaned, created_by, resolved_by, resolved_at, created_at
+							FROM core.comment_threads WHERE document_id = ANY($1) ORDER BY created_at`
+	EXPORT_COMMENT_REPLIES = `SELECT r.id, r.thread_id, r.author_id, r.body, r.edited_at, r.created_at
+							FROM core.comment_replies r INNER JOIN core.comment_threads t ON (t.id = r.thread_id)
+							WHERE t.document_id = ANY($1) ORDER BY r.created_at`
+	EXPORT_REPLY_ATTACHMENTS = `SELECT cra.reply_id, cra.attachment_id
+							FROM core.comment_reply_attachments cra INNER JOIN core.attachment a ON (a.id = cra.attachment_id)
+							WHERE cra.reply_id = ANY($1) AND a.deleted_at IS NULL`
+	IMPORT_PAGE           = `INSERT INTO core.page (space_id, owner_id, parent_id, date_created, status, type) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	IMPORT_DOC            = `INSERT INTO core.page_doc_map (page_id, title, version, owner_id, draft) VALUES ($1, $2, $3, $4, $5) RETURNING doc_id`
+	IMPORT_DOC_DRAFT      = `INSERT INTO core.content_draft (doc_id, data_binary) VALUES ($1, $2)`
+	IMPORT_DOC_WHITEBOARD = `INSERT INTO core.whiteboard_data (doc_id, data, updated_at) VALUES ($1, $2, NOW())`
+	IMPORT_ATTACHMENT     = `INSERT INTO core.attachment (page_id, storage_path, file_name, file_size, mime_type, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	IMPORT_COMMENT_THREAD = `INSERT INTO core.comment_threads (document_id, comment_id, quoted_text, anchor, published_visible, orphaned, created_by, resolved_by, resolved_at, created_at)
+							VALUES ($1, $2, $3, $4::jsonb, $5, $6, $7, $8, $9, $10) RETURNING id`
+	IMPORT_COMMENT_REPLY    = `INSERT INTO core.comment_replies (thread_id, author_id, body, edited_at, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	IMPORT_REPLY_ATTACHMENT = `INSERT INTO core.comment_reply_attachments (reply_id, attachment_id) VALUES ($1, $2)`
 )
@@ -0,0 +1,341 @@
+package space
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/inbox"
+	"github.com/durgakiran/beskar/notification"
+	"github.com/durgakiran/beskar/org"
+	"github.com/durgakiran/beskar/page"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const accessRequestEmailCategory = notification.CategoryAccessRequest
+
+// createAccessRequest records the user's request to join the space and
+// notifies its owner and admins. Users who can already view the space get
+// ALREADY_HAS_ACCESS, page restrictions being changed on the page rather than
+// through a request, and users with a request still pending ALREADY_EXISTS.
+// The requester gets nothing back about the page they named.
+func createAccessRequest(ctx context.Context, spaceId uuid.UUID, requesterId uuid.UUID, req CreateAccessRequest) (AccessRequest, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return AccessRequest{}, err
+	}
+	// the requester is usually not acting in the space's organization
+	tenantCtx, _, err := org.WithSpaceTenant(ctx, spaceId)
+	if err != nil {
+		return AccessRequest{}, err
+	}
+	if req.PageId != nil {
+		pageSpace, err := page.GetPageSpace(ctx, *req.PageId)
+		if err != nil && err.Error() != core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			return AccessRequest{}, err
+		}
+		// a missing page and a page of another space look the same
+		if err != nil || pageSpace != spaceId {
+			return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+		}
+	}
+	canView, err := core.CheckPermission(tenantCtx, "space", spaceId.String(), "user", requesterId.String(), core.SPACE_VIEW)
+	if err != nil {
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if canView {
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_HAS_ACCESS])
+	}
+
+	request := AccessRequest{
+		SpaceId:     spaceId,
+		PageId:      req.PageId,
+		RequesterId: requesterId,
+		Message:     req.Message,
+	}
+	err = core.GetPool().QueryRow(ctx, INSERT_ACCESS_REQUEST, spaceId, req.PageId, requesterId, req.Message).Scan(&request.Id, &request.Status, &request.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// a request is still pending
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if err := fillRequesters(ctx, []*AccessRequest{&request}); err != nil {
+		return AccessRequest{}, err
+	}
+	// the title is for the admins; the requester may not be allowed to see it
+	notice := request
+	if req.PageId != nil {
+		if title, err := pageTitle(ctx, *req.PageId); err == nil {
+			notice.PageTitle = &title
+		}
+	}
+	if err := notifyAccessRequest(tenantCtx, notice); err != nil {
+		logger().Error("access request: notifying admins failed", zap.String("request_id", request.Id.String()), zap.Error(err))
+	}
+	return request, nil
+}
+
+// listAccessRequests returns the space's pending requests, oldest first.
+func listAccessRequests(ctx context.Context, spaceId uuid.UUID) ([]AccessRequest, error) {
+	rows, err := core.GetPool().Query(ctx, LIST_PENDING_ACCESS_REQUESTS, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	requests, err := pgx.CollectRows(rows, pgx.RowToStructByName[AccessRequest])
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	refs := make([]*AccessRequest, 0, len(requests))
+	for i := range requests {
+		refs = append(refs, &requests[i])
+	}
+	if err := fillRequesters(ctx, refs); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// decideAccessRequest approves or declines a pending request and tells the
+// requester. Approving adds the requester to the space's organization and
+// writes their role the way addSpaceMembers does; a requester who became a
+// direct member meanwhile keeps the role they have.
+func decideAccessRequest(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, requestId uuid.UUID, status string, decision AccessRequestDecision) (AccessRequest, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return AccessRequest{}, err
+	}
+	existing := make(map[uuid.UUID]bool)
+	if status == ACCESS_REQUEST_APPROVED {
+		users, err := getSpaceUsers(ctx, spaceId)
+		if err != nil {
+			return AccessRequest{}, err
+		}
+		for _, user := range users {
+			existing[user.Id] = user.Direct
+		}
+	}
+
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, LOCK_PENDING_ACCESS_REQUEST, requestId, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	request, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[AccessRequest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		// unknown, or decided by someone else meanwhile
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+
+	var role, response *string
+	if decision.Response != "" {
+		response = &decision.Response
+	}
+	if status == ACCESS_REQUEST_APPROVED {
+		stored := storageRole(decision.Role)
+		role = &stored
+		if err := org.Join(ctx, core.OrganizationFromContext(ctx), request.RequesterId); err != nil {
+			return AccessRequest{}, err
+		}
+		if !existing[request.RequesterId] {
+			if err := core.QueueRelations(ctx, tx, core.RelationWrite("space", spaceId.String(), stored, "user", request.RequesterId.String())); err != nil {
+				logger().Error(err.Error())
+				return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+			}
+			if decision.ExpiresAt != nil {
+				if _, err := tx.Exec(ctx, UPSERT_SPACE_MEMBER_EXPIRY, spaceId, request.RequesterId, decision.ExpiresAt, actorId); err != nil {
+					logger().Error(err.Error())
+					return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+				}
+			}
+		}
+	}
+	if err := tx.QueryRow(ctx, DECIDE_ACCESS_REQUEST, requestId, status, role, response, actorId).Scan(&request.DecidedAt); err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return AccessRequest{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
+
+	request.Status = status
+	request.Role = role
+	request.Response = response
+	request.DecidedBy = &actorId
+	if err := fillRequesters(ctx, []*AccessRequest{&request}); err != nil {
+		logger().Error("access request: loading the requester failed", zap.String("request_id", request.Id.String()), zap.Error(err))
+	}
+	if err := notifyAccessRequestDecision(ctx, request); err != nil {
+		logger().Error("access request: notifying the requester failed", zap.String("request_id", request.Id.String()), zap.Error(err))
+	}
+	return request, nil
+}
+
+// fillRequesters sets the requesters' names and emails from their profiles.
+func fillRequesters(ctx context.Context, requests []*AccessRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(requests))
+	for _, request := range requests {
+		ids = append(ids, request.RequesterId.String())
+	}
+	profiles, err := core.GetUserProfiles(ids)
+	if err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	for _, request := range requests {
+		profile := profiles[request.RequesterId.String()]
+		request.RequesterName = profile.Name
+		request.RequesterEmail = profile.Email
+		if request.RequesterName == "" {
+			request.RequesterName = request.RequesterEmail
+		}
+	}
+	return nil
+}
+
+func pageTitle(ctx context.Context, pageId int64) (string, error) {
+	var title *string
+	err := core.GetPool().QueryRow(ctx, GET_PAGE_TITLE, pageId).Scan(&title)
+	if err != nil || title == nil {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return *title, nil
+}
+
+// notifyAccessRequest tells the space's owner and admins about a new
+// request.
+func notifyAccessRequest(ctx context.Context, request AccessRequest) error {
+	config := notification.LoadConfig()
+	var spaceName string
+	if err := core.GetPool().QueryRow(ctx, GET_SPACE_NAME, request.SpaceId).Scan(&spaceName); err != nil {
+		return err
+	}
+	users, err := getSpaceUsers(ctx, request.SpaceId)
+	if err != nil {
+		return err
+	}
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	data := map[string]any{
+		"space_name":      spaceName,
+		"requester_name":  request.RequesterName,
+		"requester_email": request.RequesterEmail,
+		"message":         request.Message,
+		"request_url":     fmt.Sprintf("%s/space/%s/settings/users?request=%s", appURL, request.SpaceId, request.Id),
+		"app_url":         appURL + "/",
+	}
+	if request.PageTitle != nil {
+		data["page_title"] = *request.PageTitle
+	}
+	spaceId, requesterId := request.SpaceId, request.RequesterId
+	for _, user := range users {
+		if !user.IsOwner && storageRole(user.Role) != "admin" {
+			continue
+		}
+		n := inbox.Notification{
+			UserId:    user.Id,
+			Category:  accessRequestEmailCategory,
+			Type:      inbox.AccessRequested,
+			Title:     fmt.Sprintf("%s requested access to %s", request.RequesterName, spaceName),
+			Body:      request.Message,
+			Url:       fmt.Sprintf("/space/%s/settings/users?request=%s", request.SpaceId, request.Id),
+			SpaceId:   &spaceId,
+			PageId:    request.PageId,
+			ActorId:   &requesterId,
+			ActorName: request.RequesterName,
+			Data:      map[string]interface{}{"requestId": request.Id.String()},
+		}
+		if strings.TrimSpace(user.Email) != "" {
+			userId := user.Id
+			n.Email = &notification.EnqueueEmailRequest{
+				MessageKey:  fmt.Sprintf("%s:%s:%s", notification.TemplateSpaceAccessRequested, request.Id, user.Id),
+				Category:    accessRequestEmailCategory,
+				TemplateKey: notification.TemplateSpaceAccessRequested,
+				Recipient: notification.EmailRecipient{
+					UserID: &userId,
+					Email:  user.Email,
+					Name:   user.Name,
+				},
+				TemplateData: data,
+				Priority:     notification.PriorityNormal,
+			}
+		}
+		if err := inbox.Notify(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyAccessRequestDecision tells the requester how their request was
+// decided.
+func notifyAccessRequestDecision(ctx context.Context, request AccessRequest) error {
+	config := notification.LoadConfig()
+	var spaceName string
+	if err := core.GetPool().QueryRow(ctx, GET_SPACE_NAME, request.SpaceId).Scan(&spaceName); err != nil {
+		return err
+	}
+	appURL := strings.TrimRight(strings.TrimSpace(config.AppBaseURL), "/")
+	data := map[string]any{
+		"space_name": spaceName,
+		"decision":   request.Status,
+		"space_url":  fmt.Sprintf("%s/space/%s", appURL, request.SpaceId),
+		"app_url":    appURL + "/",
+	}
+	if request.Role != nil {
+		data["role"] = normalizeRole(*request.Role)
+	}
+	if request.Response != nil {
+		data["response"] = *request.Response
+	}
+	spaceId := request.SpaceId
+	n := inbox.Notification{
+		UserId:   request.RequesterId,
+		Category: accessRequestEmailCategory,
+		Type:     inbox.AccessRequestDecided,
+		Title:    fmt.Sprintf("Your request to join %s was %s", spaceName, request.Status),
+		Url:      fmt.Sprintf("/space/%s", request.SpaceId),
+		SpaceId:  &spaceId,
+		ActorId:  request.DecidedBy,
+		Data:     map[string]interface{}{"requestId": request.Id.String(), "status": request.Status},
+	}
+	if request.Response != nil {
+		n.Body = *request.Response
+	}
+	if strings.TrimSpace(request.RequesterEmail) != "" {
+		userId := request.RequesterId
+		n.Email = &notification.EnqueueEmailRequest{
+			MessageKey:  fmt.Sprintf("%s:%s", notification.TemplateSpaceAccessRequestDecided, request.Id),
+			Category:    accessRequestEmailCategory,
+			TemplateKey: notification.TemplateSpaceAccessRequestDecided,
+			Recipient: notification.EmailRecipient{
+				UserID: &userId,
+				Email:  request.RequesterEmail,
+				Name:   request.RequesterName,
+			},
+			TemplateData: data,
+			Priority:     notification.PriorityNormal,
+		}
+	}
+	return inbox.Notify(ctx, n)
+}
@@ -0,0 +1,43 @@
+package space
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCreateAccessRequest(t *testing.T) {
+	req, err := validateCreateAccessRequest([]byte(`{"pageId": 12, "message": "  need it for the audit  "}`))
+	if err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+	if req.Message != "need it for the audit" || req.PageId == nil || *req.PageId != 12 {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	if _, err := validateCreateAccessRequest(nil); err != nil {
+		t.Fatalf("expected an empty body to be allowed, got %v", err)
+	}
+	long := `{"message": "` + strings.Repeat("a", accessRequestTextLimit+1) + `"}`
+	if _, err := validateCreateAccessRequest([]byte(long)); err == nil {
+		t.Fatalf("expected an over-long message to be rejected")
+	}
+	if _, err := validateCreateAccessRequest([]byte(`{"pageId": 0}`)); err == nil {
+		t.Fatalf("expected a zero page id to be rejected")
+	}
+}
+
+func TestValidateAccessRequestDecision(t *testing.T) {
+	if _, err := validateAccessRequestDecision([]byte(`{"role": "owner"}`), true); err == nil {
+		t.Fatalf("expected approving as owner to be rejected")
+	}
+	if _, err := validateAccessRequestDecision([]byte(`{}`), true); err == nil {
+		t.Fatalf("expected approving without a role to be rejected")
+	}
+	req, err := validateAccessRequestDecision([]byte(`{"role": "editor", "response": " welcome "}`), true)
+	if err != nil || req.Response != "welcome" {
+		t.Fatalf("expected a valid approval, got %+v, %v", req, err)
+	}
+	req, err = validateAccessRequestDecision([]byte(`{"role": "owner", "response": "not now"}`), false)
+	if err != nil || req.Role != "" {
+		t.Fatalf("expected a decline to ignore the role, got %+v, %v", req, err)
+	}
+}
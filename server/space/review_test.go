@@ -0,0 +1,107 @@
+package space
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIsExternalEmail(t *testing.T) {
+	domains := []string{"acme.com"}
+	cases := []struct {
+		email string
+		want  bool
+	}{
+		{"kiran@acme.com", false},
+		{"Kiran@ACME.com", false},
+		{"ops@eu.acme.com", false},
+		{"someone@notacme.com", true},
+		{"contractor@gmail.com", true},
+	}
+	for _, c := range cases {
+		if got := isExternalEmail(c.email, domains); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.email, c.want, got)
+		}
+	}
+	if isExternalEmail("contractor@gmail.com", []string{""}) {
+		t.Errorf("expected nobody to be external without a domain")
+	}
+}
+
+func TestWriteAccessReviewCSV(t *testing.T) {
+	decidedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	review := AccessReview{
+		Members: []ReviewMember{
+			{UserId: uuid.New(), Name: "Kiran", Email: "kiran@acme.com", Role: "owner", Direct: true},
+			{UserId: uuid.New(), Name: "Sam", Email: "sam@gmail.com", Role: "viewer", External: true, Inactive: true,
+				Groups:   []GroupGrant{{Name: "Contractors", Role: "viewer"}},
+				Decision: &ReviewDecision{Decision: REVIEW_REVOKE, DecidedBy: uuid.New(), DecidedAt: decidedAt}},
+		},
+		Invites: []ReviewInvite{{Email: "new@acme.com", Role: "editor", SenderId: uuid.New()}},
+	}
+	var buf bytes.Buffer
+	if err := writeAccessReviewCSV(&buf, review); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading csv: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected a header and three rows, got %d", len(records))
+	}
+	sam := records[2]
+	if sam[5] != "group Contractors" || sam[7] != "true" || sam[8] != "true" || sam[9] != REVIEW_REVOKE || sam[11] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected member row %v", sam)
+	}
+	if records[3][0] != REVIEW_SUBJECT_INVITE || records[3][1] != "new@acme.com" || records[3][9] != "" {
+		t.Fatalf("unexpected invite row %v", records[3])
+	}
+}
+
+func TestWriteAccessReviewCSVEscapesFormulas(t *testing.T) {
+	review := AccessReview{
+		Members: []ReviewMember{{UserId: uuid.New(), Name: "=HYPERLINK(\"http://evil\")", Email: "@sam@acme.com", Role: "viewer",
+			Groups: []GroupGrant{{Name: "+Contractors", Role: "viewer"}}}},
+		Invites: []ReviewInvite{{Email: "-1+1@acme.com", Role: "editor", SenderId: uuid.New()}},
+	}
+	var buf bytes.Buffer
+	if err := writeAccessReviewCSV(&buf, review); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading csv: %v", err)
+	}
+	member := records[1]
+	if member[2] != "'=HYPERLINK(\"http://evil\")" || member[3] != "'@sam@acme.com" || member[5] != "group +Contractors" {
+		t.Fatalf("unexpected member row %v", member)
+	}
+	if records[2][1] != "'-1+1@acme.com" {
+		t.Fatalf("unexpected invite row %v", records[2])
+	}
+}
+
+func TestValidateReviewDecisions(t *testing.T) {
+	userId := uuid.NewString()
+	req, err := validateReviewDecisions([]byte(`{"decisions": [{"type": "User", "id": " ` + userId + ` ", "decision": "Revoke"}, {"type": "invite", "id": "a@b.com", "decision": "confirm"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Decisions[0].Type != REVIEW_SUBJECT_USER || req.Decisions[0].Id != userId || req.Decisions[0].Decision != REVIEW_REVOKE {
+		t.Fatalf("expected the decision to be normalized, got %+v", req.Decisions[0])
+	}
+	for _, body := range []string{
+		`{"decisions": []}`,
+		`{"decisions": [{"type": "user", "id": "nope", "decision": "confirm"}]}`,
+		`{"decisions": [{"type": "invite", "id": "a@b.com", "decision": "ignore"}]}`,
+		`{"decisions": [{"type": "group", "id": "` + userId + `", "decision": "confirm"}]}`,
+	} {
+		if _, err := validateReviewDecisions([]byte(body)); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+}
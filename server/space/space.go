@@ -1,11 +1,19 @@
 package space
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/durgakiran/beskar/activity"
+	"github.com/durgakiran/beskar/audit"
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/slug"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -48,7 +56,7 @@ func createSpace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	space.CreatedBy = ownerID
-	spaceID, err := createSpaceEntry(space)
+	spaceID, err := createSpaceEntry(r.Context(), space)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 		return
@@ -62,7 +70,7 @@ func getSpaces(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	spaces, err := ListSpaces(ownerID)
+	spaces, err := ListSpaces(r.Context(), ownerID)
 	if err != nil {
 		logger().Error(err.Error())
 		core.SendFailedReponse(w, r, 0, err.Error())
@@ -78,11 +86,11 @@ func getPageList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_VIEW) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	data, err := getDocumentList(spaceID, userID)
+	data, err := getDocumentList(r.Context(), spaceID, userID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 		return
@@ -102,7 +110,7 @@ func getPageDescendantsController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
 		return
 	}
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_VIEW) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
@@ -113,7 +121,7 @@ func getPageDescendantsController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := getPageDescendants(spaceID, userID, pageID)
+	data, err := getPageDescendants(r.Context(), spaceID, userID, pageID)
 	if err != nil {
 		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
 			core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
@@ -132,11 +140,11 @@ func listUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_VIEW) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	data, err := getSpaceUsers(spaceID)
+	data, err := getSpaceUsers(r.Context(), spaceID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -151,7 +159,7 @@ func updateSpace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_EDIT) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_EDIT) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
@@ -187,11 +195,11 @@ func getSpaceDetailsController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_VIEW) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	space, err := getSpaceDetails(spaceID, userID)
+	space, err := getSpaceDetails(r.Context(), spaceID, userID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
 		return
@@ -206,11 +214,11 @@ func getSpaceSettingsController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	if !core.ValidateUserSpacePermissions(spaceID, userID, core.SPACE_VIEW) {
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
 	}
-	state, err := getSpaceSettingsState(spaceID, userID)
+	state, err := getSpaceSettingsState(r.Context(), spaceID, userID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
 		return
@@ -236,7 +244,7 @@ func searchMemberCandidatesController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	result, err := searchMemberCandidates(spaceID, userID, req)
+	result, err := searchMemberCandidates(r.Context(), spaceID, userID, req)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
@@ -245,7 +253,7 @@ func searchMemberCandidatesController(w http.ResponseWriter, r *http.Request) {
 }
 
 func addMembersController(w http.ResponseWriter, r *http.Request) {
-	_, userID, ok := currentUser(r)
+	user, userID, ok := currentUser(r)
 	if !ok {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
@@ -262,16 +270,34 @@ func addMembersController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	result, err := addSpaceMembers(spaceID, userID, req)
+	result, err := addSpaceMembers(r.Context(), spaceID, userID, req)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	if added, ok := result["added"].([]AddSpaceMemberItem); ok {
+		for _, member := range added {
+			audit.Record(r, audit.Entry{
+				Action:     audit.ActionMemberAdded,
+				TargetType: "user",
+				TargetId:   member.UserId,
+				SpaceId:    &spaceID,
+				After:      map[string]interface{}{"role": normalizeRole(member.Role), "expiresAt": member.ExpiresAt},
+			})
+			activity.Record(activity.Event{
+				SpaceId:   spaceID,
+				ActorId:   userID,
+				ActorName: user.Name,
+				Type:      activity.MemberAdded,
+				Data:      map[string]interface{}{"userId": member.UserId, "role": normalizeRole(member.Role)},
+			})
+		}
+	}
 	core.SendSuccessResponse(w, r, http.StatusOK, result)
 }
 
 func changeMemberRoleController(w http.ResponseWriter, r *http.Request) {
-	_, userID, ok := currentUser(r)
+	user, userID, ok := currentUser(r)
 	if !ok {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
@@ -288,16 +314,31 @@ func changeMemberRoleController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	member, err := changeSpaceMemberRole(spaceID, userID, req)
+	member, previousRole, err := changeSpaceMemberRole(r.Context(), spaceID, userID, req)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberRoleChanged,
+		TargetType: "user",
+		TargetId:   member.Id.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"role": previousRole},
+		After:      map[string]string{"role": member.Role},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberRoleChanged,
+		Data:      map[string]interface{}{"userId": member.Id.String(), "name": member.Name, "role": member.Role},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, member)
 }
 
 func removeMemberController(w http.ResponseWriter, r *http.Request) {
-	_, userID, ok := currentUser(r)
+	user, userID, ok := currentUser(r)
 	if !ok {
 		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 		return
@@ -314,10 +355,193 @@ func removeMemberController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := removeSpaceMember(spaceID, userID, req); err != nil {
+	removed, err := removeSpaceMember(r.Context(), spaceID, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberRemoved,
+		TargetType: "user",
+		TargetId:   req.UserId,
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"role": removed.Role},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberRemoved,
+		Data:      map[string]interface{}{"userId": req.UserId},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]bool{"removed": true})
+}
+
+func memberExpiryController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateSpaceMemberExpiry(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	member, previous, err := setSpaceMemberExpiry(r.Context(), spaceID, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberExpiryChanged,
+		TargetType: "user",
+		TargetId:   member.Id.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]*time.Time{"expiresAt": previous},
+		After:      map[string]*time.Time{"expiresAt": member.ExpiresAt},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, member)
+}
+
+func listGroupsController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	groups, err := listSpaceGroups(r.Context(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, groups)
+}
+
+func addGroupController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateSpaceGroupRequest(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	added, err := addSpaceGroup(r.Context(), spaceID, userID, req)
+	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberAdded,
+		TargetType: "group",
+		TargetId:   added.GroupId.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]string{"role": added.Role},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberAdded,
+		Data:      map[string]interface{}{"groupId": added.GroupId.String(), "name": added.Name, "role": added.Role},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, added)
+}
+
+func changeGroupRoleController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateSpaceGroupRequest(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	changed, previousRole, err := changeSpaceGroupRole(r.Context(), spaceID, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberRoleChanged,
+		TargetType: "group",
+		TargetId:   changed.GroupId.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"role": previousRole},
+		After:      map[string]string{"role": changed.Role},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberRoleChanged,
+		Data:      map[string]interface{}{"groupId": changed.GroupId.String(), "name": changed.Name, "role": changed.Role},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, changed)
+}
+
+func removeGroupController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	groupID, err := uuid.Parse(chi.URLParam(r, "groupId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	removed, err := removeSpaceGroup(r.Context(), spaceID, userID, groupID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberRemoved,
+		TargetType: "group",
+		TargetId:   groupID.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"role": removed.Role},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberRemoved,
+		Data:      map[string]interface{}{"groupId": groupID.String()},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, map[string]bool{"removed": true})
 }
 
@@ -339,11 +563,19 @@ func transferOwnershipController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	owner, err := transferOwnership(spaceID, userID, req)
+	owner, err := transferOwnership(r.Context(), spaceID, userID, req)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionOwnershipTransferred,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"ownerId": userID.String()},
+		After:      map[string]string{"ownerId": owner.Id.String()},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, owner)
 }
 
@@ -354,14 +586,68 @@ func archiveSpaceController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	space, err := archiveSpace(spaceID, userID)
+	space, err := archiveSpace(r.Context(), spaceID, userID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceArchived,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"archivedAt": space.ArchivedAt},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, space)
 }
 
+func changeSpaceSlugController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := slug.ValidateUpdateSpaceSlug(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	previous, err := changeSpaceSlug(r.Context(), spaceID, userID, req)
+	if err != nil {
+		status := http.StatusForbidden
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS]:
+			status = http.StatusConflict
+		case core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS],
+			core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS],
+			core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS],
+			core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE]:
+			status = http.StatusInternalServerError
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	if previous != req.Slug {
+		audit.Record(r, audit.Entry{
+			Action:     audit.ActionSpaceSlugChanged,
+			TargetType: "space",
+			TargetId:   spaceID.String(),
+			SpaceId:    &spaceID,
+			Before:     map[string]interface{}{"slug": previous},
+			After:      map[string]interface{}{"slug": req.Slug},
+		})
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]string{"slug": req.Slug, "path": slug.Path(req.Slug)})
+}
+
 func unarchiveSpaceController(w http.ResponseWriter, r *http.Request) {
 	_, userID, ok := currentUser(r)
 	if !ok {
@@ -369,14 +655,176 @@ func unarchiveSpaceController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
-	space, err := unarchiveSpace(spaceID, userID)
+	space, err := unarchiveSpace(r.Context(), spaceID, userID)
 	if err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceUnarchived,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"archivedAt": space.ArchivedAt},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, space)
 }
 
+func restoreSpaceController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	space, err := restoreSpace(r.Context(), spaceID, userID)
+	if err != nil {
+		status := http.StatusForbidden
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			status = http.StatusNotFound
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceRestored,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"name": space.Name},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, space)
+}
+
+func listDeletedSpacesController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaces, err := listDeletedSpaces(r.Context(), userID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, spaces)
+}
+
+func archiveErrorStatus(err error) int {
+	switch err.Error() {
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+		return http.StatusForbidden
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+		return http.StatusNotFound
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT], core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT]:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// exportSpaceController builds the archive in a temporary file first so a
+// failure can still be reported as an error instead of a truncated zip.
+func exportSpaceController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	file, err := os.CreateTemp("", "beskar-export-*.zip")
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	manifest, err := exportSpace(r.Context(), spaceID, userID, file)
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, archiveErrorStatus(err), err.Error())
+		return
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceExported,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After: map[string]interface{}{
+			"pages":       len(manifest.Pages),
+			"attachments": len(manifest.Attachments),
+			"images":      len(manifest.Images),
+			"comments":    len(manifest.Comments),
+			"members":     len(manifest.Members),
+		},
+	})
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 || r == '"' || r == '\\' || r == '/' {
+			return '_'
+		}
+		return r
+	}, manifest.Space.Name)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		logger().Error(err.Error())
+	}
+}
+
+// importSpaceController takes the archive as the raw request body.
+func importSpaceController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	file, err := os.CreateTemp("", "beskar-import-*.zip")
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	body := http.MaxBytesReader(w, r.Body, importMaxBytes())
+	defer body.Close()
+	if _, err := io.Copy(file, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			core.SendFailedReponse(w, r, http.StatusRequestEntityTooLarge, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	result, err := importSpace(r.Context(), userID, file.Name())
+	if err != nil {
+		logger().Error(err.Error())
+		core.SendFailedReponse(w, r, archiveErrorStatus(err), err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceImported,
+		TargetType: "space",
+		TargetId:   result.SpaceId.String(),
+		SpaceId:    &result.SpaceId,
+		After:      result,
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, result)
+}
+
 func deleteSpaceController(w http.ResponseWriter, r *http.Request) {
 	_, userID, ok := currentUser(r)
 	if !ok {
@@ -395,31 +843,560 @@ func deleteSpaceController(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := softDeleteSpace(spaceID, userID, req); err != nil {
+	if err := softDeleteSpace(r.Context(), spaceID, userID, req); err != nil {
 		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
 		return
 	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceDeleted,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		Before:     map[string]string{"name": req.ConfirmName},
+	})
 	core.SendSuccessResponse(w, r, http.StatusOK, map[string]bool{"deleted": true})
 }
 
+func getSpaceActivityController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	query := r.URL.Query()
+	feed, err := activity.ListSpaceActivity(r.Context(), spaceID, userID, query.Get("cursor"), query.Get("limit"))
+	if err != nil {
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT] {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, feed)
+}
+
+func spaceActivityEventsController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_VIEW) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	activity.Hub.SSEHandler(w, r, spaceID, userID)
+}
+
+func explainAccessController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_MANAGE_MEMBERS) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	req, err := validateAccessQuery(r.URL.Query(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	explanation, err := explainAccess(r.Context(), spaceID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			status = http.StatusNotFound
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, explanation)
+}
+
+func accessMatrixController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_MANAGE_MEMBERS) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	subjectID, err := uuid.Parse(r.URL.Query().Get("userId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	matrix, err := getAccessMatrix(r.Context(), spaceID, subjectID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, matrix)
+}
+
+// manageMembersRequest checks that the caller may manage the space's members
+// and returns the caller and the space.
+func manageMembersRequest(w http.ResponseWriter, r *http.Request) (core.UserInfo, uuid.UUID, uuid.UUID, bool) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return user, userID, uuid.Nil, false
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return user, userID, spaceID, false
+	}
+	if !core.ValidateUserSpacePermissions(r.Context(), spaceID, userID, core.SPACE_MANAGE_MEMBERS) {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return user, userID, spaceID, false
+	}
+	return user, userID, spaceID, true
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+		return http.StatusNotFound
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT]:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func startAccessReviewController(w http.ResponseWriter, r *http.Request) {
+	_, userID, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	review, _, err := startAccessReview(r.Context(), spaceID, &userID, LoadReviewConfig(), time.Now())
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	if review.Id == uuid.Nil {
+		// another review of the space is being stored right now
+		core.SendFailedReponse(w, r, http.StatusConflict, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionAccessReviewCreated,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"reviewId": review.Id, "members": len(review.Members), "invites": len(review.Invites)},
+	})
+	core.SendSuccessResponse(w, r, http.StatusCreated, review)
+}
+
+func listAccessReviewsController(w http.ResponseWriter, r *http.Request) {
+	_, _, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	reviews, err := listAccessReviews(r.Context(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, reviews)
+}
+
+func getAccessReviewController(w http.ResponseWriter, r *http.Request) {
+	_, _, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	review, err := getAccessReview(r.Context(), spaceID, reviewID)
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, review)
+}
+
+func downloadAccessReviewController(w http.ResponseWriter, r *http.Request) {
+	_, _, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	review, err := getAccessReview(r.Context(), spaceID, reviewID)
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="access-review-%s.csv"`, review.CreatedAt.UTC().Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
+	if err := writeAccessReviewCSV(w, review); err != nil {
+		logger().Error(err.Error())
+	}
+}
+
+func decideAccessReviewController(w http.ResponseWriter, r *http.Request) {
+	user, userID, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateReviewDecisions(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	results, review, err := decideAccessReview(r.Context(), spaceID, reviewID, userID, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	counts := map[string]int{}
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		counts[result.Decision]++
+		if result.Decision != REVIEW_REVOKE {
+			continue
+		}
+		if result.Type == REVIEW_SUBJECT_INVITE {
+			audit.Record(r, audit.Entry{
+				Action:     audit.ActionInviteRemoved,
+				TargetType: "invite",
+				TargetId:   result.Id,
+				SpaceId:    &spaceID,
+				After:      map[string]string{"reviewId": reviewID.String()},
+			})
+			continue
+		}
+		audit.Record(r, audit.Entry{
+			Action:     audit.ActionMemberRemoved,
+			TargetType: "user",
+			TargetId:   result.Id,
+			SpaceId:    &spaceID,
+			After:      map[string]string{"reviewId": reviewID.String()},
+		})
+		activity.Record(activity.Event{
+			SpaceId:   spaceID,
+			ActorId:   userID,
+			ActorName: user.Name,
+			Type:      activity.MemberRemoved,
+			Data:      map[string]interface{}{"userId": result.Id},
+		})
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionAccessReviewDecided,
+		TargetType: "access_review",
+		TargetId:   reviewID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"confirmed": counts[REVIEW_CONFIRM], "revoked": counts[REVIEW_REVOKE], "completed": review.CompletedAt != nil},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]interface{}{"results": results, "review": review})
+}
+
+func spaceDirectoryController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	query, err := validateDirectoryQuery(r.URL.Query())
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	spaces, err := listSpaceDirectory(r.Context(), user, userID, query)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, spaces)
+}
+
+func joinSpaceController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	role, err := joinSpace(r.Context(), user, userID, spaceID)
+	if err != nil {
+		status := errorStatus(err)
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+			status = http.StatusForbidden
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS]:
+			status = http.StatusConflict
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionMemberAdded,
+		TargetType: "user",
+		TargetId:   userID.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]string{"role": normalizeRole(role), "via": "self_join"},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberAdded,
+		Data:      map[string]interface{}{"userId": userID.String(), "role": normalizeRole(role)},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, map[string]string{"role": normalizeRole(role)})
+}
+
+func updateJoinSettingsController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID := uuid.MustParse(chi.URLParam(r, "spaceId"))
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	settings, err := validateSpaceJoinSettings(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	previous, err := updateSpaceJoinSettings(r.Context(), spaceID, userID, settings)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionSpaceJoinPolicyChanged,
+		TargetType: "space",
+		TargetId:   spaceID.String(),
+		SpaceId:    &spaceID,
+		Before:     previous,
+		After:      settings,
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, settings)
+}
+
+func createAccessRequestController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateCreateAccessRequest(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	request, err := createAccessRequest(r.Context(), spaceID, userID, req)
+	if err != nil {
+		status := errorStatus(err)
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_EXISTS], core.ErrorCode_name[core.ErrorCode_ERROR_CODE_ALREADY_HAS_ACCESS]:
+			status = http.StatusConflict
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionAccessRequested,
+		TargetType: "access_request",
+		TargetId:   request.Id.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"pageId": request.PageId},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, request)
+}
+
+func listAccessRequestsController(w http.ResponseWriter, r *http.Request) {
+	_, _, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	requests, err := listAccessRequests(r.Context(), spaceID)
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, requests)
+}
+
+func approveAccessRequestController(w http.ResponseWriter, r *http.Request) {
+	decideAccessRequestController(w, r, ACCESS_REQUEST_APPROVED)
+}
+
+func declineAccessRequestController(w http.ResponseWriter, r *http.Request) {
+	decideAccessRequestController(w, r, ACCESS_REQUEST_DECLINED)
+}
+
+func decideAccessRequestController(w http.ResponseWriter, r *http.Request, status string) {
+	user, userID, spaceID, ok := manageMembersRequest(w, r)
+	if !ok {
+		return
+	}
+	requestID, err := uuid.Parse(chi.URLParam(r, "requestId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateAccessRequestDecision(data, status == ACCESS_REQUEST_APPROVED)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	request, err := decideAccessRequest(r.Context(), spaceID, userID, requestID, status, req)
+	if err != nil {
+		core.SendFailedReponse(w, r, errorStatus(err), err.Error())
+		return
+	}
+	if status == ACCESS_REQUEST_DECLINED {
+		audit.Record(r, audit.Entry{
+			Action:     audit.ActionAccessRequestDeclined,
+			TargetType: "access_request",
+			TargetId:   request.Id.String(),
+			SpaceId:    &spaceID,
+			After:      map[string]string{"userId": request.RequesterId.String()},
+		})
+		core.SendSuccessResponse(w, r, http.StatusOK, request)
+		return
+	}
+	role := normalizeRole(*request.Role)
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionAccessRequestApproved,
+		TargetType: "access_request",
+		TargetId:   request.Id.String(),
+		SpaceId:    &spaceID,
+		After:      map[string]interface{}{"userId": request.RequesterId.String(), "role": role, "expiresAt": req.ExpiresAt},
+	})
+	activity.Record(activity.Event{
+		SpaceId:   spaceID,
+		ActorId:   userID,
+		ActorName: user.Name,
+		Type:      activity.MemberAdded,
+		Data:      map[string]interface{}{"userId": request.RequesterId.String(), "role": role},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, request)
+}
+
 func Router() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(core.Authenticated)
 	r.Get("/list", getSpaces)
+	r.Get("/deleted", listDeletedSpacesController)
 	r.Post("/create", createSpace)
+	r.Post("/import", importSpaceController)
+	r.Get("/directory", spaceDirectoryController)
 	r.Get("/{spaceId}/page/list", getPageList)
 	r.Get("/{spaceId}/page/{pageId}/descendants", getPageDescendantsController)
 	r.Get("/{spaceId}/users", listUsers)
 	r.Get("/{spaceId}/details", getSpaceDetailsController)
 	r.Get("/{spaceId}/settings", getSpaceSettingsController)
+	r.Get("/{spaceId}/activity", getSpaceActivityController)
+	r.Get("/{spaceId}/activity/events", spaceActivityEventsController)
 	r.Post("/{spaceId}/members/candidates/search", searchMemberCandidatesController)
 	r.Post("/{spaceId}/members/add", addMembersController)
 	r.Put("/{spaceId}/members/role", changeMemberRoleController)
 	r.Delete("/{spaceId}/members/remove", removeMemberController)
+	r.Put("/{spaceId}/members/expiry", memberExpiryController)
+	r.Get("/{spaceId}/groups", listGroupsController)
+	r.Post("/{spaceId}/groups/add", addGroupController)
+	r.Put("/{spaceId}/groups/role", changeGroupRoleController)
+	r.Delete("/{spaceId}/groups/{groupId}", removeGroupController)
 	r.Post("/{spaceId}/ownership/transfer", transferOwnershipController)
+	r.Get("/{spaceId}/access/explain", explainAccessController)
+	r.Get("/{spaceId}/access/matrix", accessMatrixController)
+	r.Post("/{spaceId}/join", joinSpaceController)
+	r.Put("/{spaceId}/joining", updateJoinSettingsController)
+	r.Post("/{spaceId}/access/requests", createAccessRequestController)
+	r.Get("/{spaceId}/access/requests", listAccessRequestsController)
+	r.Post("/{spaceId}/access/requests/{requestId}/approve", approveAccessRequestController)
+	r.Post("/{spaceId}/access/requests/{requestId}/decline", declineAccessRequestController)
+	r.Post("/{spaceId}/reviews", startAccessReviewController)
+	r.Get("/{spaceId}/reviews", listAccessReviewsController)
+	r.Get("/{spaceId}/reviews/{reviewId}", getAccessReviewController)
+	r.Get("/{spaceId}/reviews/{reviewId}/csv", downloadAccessReviewController)
+	r.Post("/{spaceId}/reviews/{reviewId}/decisions", decideAccessReviewController)
 	r.Post("/{spaceId}/archive", archiveSpaceController)
 	r.Post("/{spaceId}/unarchive", unarchiveSpaceController)
 	r.Post("/{spaceId}/delete", deleteSpaceController)
+	r.Post("/{spaceId}/restore", restoreSpaceController)
+	r.Get("/{spaceId}/export", exportSpaceController)
+	r.Put("/{spaceId}/slug", changeSpaceSlugController)
 	r.Put("/{spaceId}", updateSpace)
 	return r
 }
@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/group"
+	"github.com/durgakiran/beskar/org"
+	"github.com/durgakiran/beskar/slug"
+	"github.com/durgakiran/beskar/star"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -25,11 +30,14 @@ type spaceStateRow struct {
 
 func (s Space) Create(conn pgx.Tx, ctx context.Context) (uuid.UUID, error) {
 	var spaceId uuid.UUID
-	err := conn.QueryRow(ctx, INSERT_SPACE, s.Name, s.Description, s.DateCreated, s.DateUpdated, s.CreatedBy).Scan(&spaceId)
+	err := conn.QueryRow(ctx, INSERT_SPACE, s.Name, s.Description, s.DateCreated, s.DateUpdated, s.CreatedBy, core.OrganizationFromContext(ctx)).Scan(&spaceId)
 	if err != nil {
 		logger().Error(err.Error())
 		return uuid.Nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
 	}
+	if _, err := slug.CreateSpaceSlug(ctx, conn, spaceId, s.Name); err != nil {
+		return uuid.Nil, err
+	}
 	return spaceId, nil
 }
 
@@ -53,10 +61,9 @@ func (s Space) Update() error {
 
 func (s Space) Delete() {}
 
-func createSpaceEntry(s Space) (uuid.UUID, error) {
+func createSpaceEntry(ctx context.Context, s Space) (uuid.UUID, error) {
 	var spaceId uuid.UUID
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to acquire a connection: " + err.Error())
@@ -75,15 +82,16 @@ func createSpaceEntry(s Space) (uuid.UUID, error) {
 	if err != nil {
 		return uuid.Nil, err
 	}
-	err = core.WriteRelations(spaceId.String(), "space", s.CreatedBy.String(), "user", "owner")
+	err = core.QueueRelations(ctx, tx, core.RelationWrite("space", spaceId.String(), "owner", "user", s.CreatedBy.String()))
 	if err != nil {
 		logger().Error(err.Error())
-		return spaceId, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+		return spaceId, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
 	}
 	if err = tx.Commit(ctx); err != nil {
 		logger().Error(err.Error())
 		return uuid.Nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
 	}
+	core.ApplyQueuedRelations(ctx)
 	return spaceId, nil
 }
 
@@ -132,29 +140,87 @@ func getHighestRole(roles []string) string {
 	return normalizeRole(highest)
 }
 
-func getSpaceMembershipSummary(spaceId uuid.UUID, userId uuid.UUID) (int, string, error) {
-	tuples, err := core.GetSubjectsAssociatedWithEntity("space", spaceId.String())
+func getSpaceMembershipSummary(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) (int, string, error) {
+	members, err := resolveSpaceMembers(ctx, spaceId)
+	if err != nil {
+		return 0, "", err
+	}
+
+	userRole := "viewer"
+	if member, ok := members[userId.String()]; ok && len(member.roles) > 0 {
+		userRole = getHighestRole(member.roles)
+	}
+
+	return len(members), userRole, nil
+}
+
+type memberAccess struct {
+	roles  []string
+	direct bool
+	groups []GroupGrant
+}
+
+// resolveSpaceMembers collects everyone with a role on the space, keyed by
+// user id. Roles granted to a group are expanded to the group's members,
+// including members of nested groups.
+func resolveSpaceMembers(ctx context.Context, spaceId uuid.UUID) (map[string]*memberAccess, error) {
+	tuples, err := core.GetSubjectsAssociatedWithEntity(ctx, "space", spaceId.String())
 	if err != nil {
 		logger().Error(err.Error())
-		return 0, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
 	}
 
-	usersMap := make(map[string][]string)
+	members := make(map[string]*memberAccess)
+	groupRoles := make(map[uuid.UUID][]string)
 	for _, tuple := range tuples {
-		usersMap[tuple.Subject.Id] = append(usersMap[tuple.Subject.Id], tuple.Relation)
+		if tuple.Subject.Type == "group" {
+			if groupId, err := uuid.Parse(tuple.Subject.Id); err == nil {
+				groupRoles[groupId] = append(groupRoles[groupId], tuple.Relation)
+			}
+			continue
+		}
+		member, ok := members[tuple.Subject.Id]
+		if !ok {
+			member = &memberAccess{}
+			members[tuple.Subject.Id] = member
+		}
+		member.direct = true
+		member.roles = append(member.roles, tuple.Relation)
 	}
-
-	userRole := "viewer"
-	if roles, ok := usersMap[userId.String()]; ok && len(roles) > 0 {
-		userRole = getHighestRole(roles)
+	if len(groupRoles) == 0 {
+		return members, nil
 	}
 
-	return len(usersMap), userRole, nil
+	groupIds := make([]uuid.UUID, 0, len(groupRoles))
+	for groupId := range groupRoles {
+		groupIds = append(groupIds, groupId)
+	}
+	expanded, err := group.ExpandUsers(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	names, err := group.GroupNames(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	for groupId, roles := range groupRoles {
+		grant := GroupGrant{GroupId: groupId, Name: names[groupId], Role: getHighestRole(roles)}
+		for _, userId := range expanded[groupId] {
+			member, ok := members[userId.String()]
+			if !ok {
+				member = &memberAccess{}
+				members[userId.String()] = member
+			}
+			member.roles = append(member.roles, roles...)
+			member.groups = append(member.groups, grant)
+		}
+	}
+	return members, nil
 }
 
-func ListSpaces(userId uuid.UUID) ([]SpaceListItem, error) {
+func ListSpaces(ctx context.Context, userId uuid.UUID) ([]SpaceListItem, error) {
 	var spaces []SpaceListItem
-	spaceIds, err := core.GetEntitiesWithPermission("space", "user", userId.String(), "view")
+	spaceIds, err := core.GetEntitiesWithPermission(ctx, "space", "user", userId.String(), "view")
 	if err != nil {
 		logger().Error(err.Error())
 		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
@@ -174,7 +240,6 @@ func ListSpaces(userId uuid.UUID) ([]SpaceListItem, error) {
 	}
 
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error("Unable to acquire a connection: " + err.Error())
@@ -182,7 +247,7 @@ func ListSpaces(userId uuid.UUID) ([]SpaceListItem, error) {
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx, GET_SPACES, parsedSpaceIds)
+	rows, err := conn.Query(ctx, GET_SPACES, parsedSpaceIds, core.OrganizationFromContext(ctx))
 	if err != nil {
 		logger().Error(err.Error())
 		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
@@ -192,7 +257,7 @@ func ListSpaces(userId uuid.UUID) ([]SpaceListItem, error) {
 	spaceIndexByID := make(map[uuid.UUID]int)
 	for rows.Next() {
 		var item SpaceListItem
-		if err := rows.Scan(&item.Id, &item.Name, &item.Description, &item.DateUpdated, &item.CreatedBy, &item.ArchivedAt); err != nil {
+		if err := rows.Scan(&item.Id, &item.Name, &item.Description, &item.DateUpdated, &item.CreatedBy, &item.ArchivedAt, &item.Slug); err != nil {
 			logger().Error(err.Error())
 			return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
 		}
@@ -211,12 +276,23 @@ func ListSpaces(userId uuid.UUID) ([]SpaceListItem, error) {
 		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
 	}
 	defer countRows.Close()
-	return hydrateSpaceList(spaces, spaceIndexByID, countRows, userId)
+	spaces, err = hydrateSpaceList(ctx, spaces, spaceIndexByID, countRows, userId)
+	if err != nil {
+		return spaces, err
+	}
+	starred, err := star.StarredSpaceIds(userId)
+	if err != nil {
+		return spaces, err
+	}
+	for i := range spaces {
+		spaces[i].IsStarred = starred[spaces[i].Id]
+	}
+	return spaces, nil
 }
 
 type countRowsType = pageCountRow
 
-func hydrateSpaceList(spaces []SpaceListItem, spaceIndexByID map[uuid.UUID]int, countRows pgx.Rows, userId uuid.UUID) ([]SpaceListItem, error) {
+func hydrateSpaceList(ctx context.Context, spaces []SpaceListItem, spaceIndexByID map[uuid.UUID]int, countRows pgx.Rows, userId uuid.UUID) ([]SpaceListItem, error) {
 	pageCounts, err := pgx.CollectRows[countRowsType](countRows, pgx.RowToStructByNameLax[countRowsType])
 	if err != nil {
 		logger().Error(err.Error())
@@ -229,7 +305,7 @@ func hydrateSpaceList(spaces []SpaceListItem, spaceIndexByID map[uuid.UUID]int,
 		}
 	}
 	for i := range spaces {
-		memberCount, userRole, membershipErr := getSpaceMembershipSummary(spaces[i].Id, userId)
+		memberCount, userRole, membershipErr := getSpaceMembershipSummary(ctx, spaces[i].Id, userId)
 		if membershipErr != nil {
 			return spaces, membershipErr
 		}
@@ -239,18 +315,27 @@ func hydrateSpaceList(spaces []SpaceListItem, spaceIndexByID map[uuid.UUID]int,
 	return spaces, nil
 }
 
-func getDocumentList(spaceId uuid.UUID, userId uuid.UUID) ([]PageList, error) {
+func getDocumentList(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) ([]PageList, error) {
 	var pageList []PageList
-	pageIds, err := core.GetEntitiesWithPermission("page", "space", spaceId.String(), "space")
+	pageIds, err := core.GetEntitiesWithPermission(ctx, "page", "space", spaceId.String(), "space")
+	if err != nil {
+		logger().Error(err.Error())
+		return pageList, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(pageIds) == 0 {
+		return pageList, nil
+	}
+	// drop pages hidden from this user by a page restriction
+	viewable, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
 	if err != nil {
 		logger().Error(err.Error())
 		return pageList, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
 	}
+	pageIds = intersectIds(pageIds, viewable)
 	if len(pageIds) == 0 {
 		return pageList, nil
 	}
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error(err.Error())
@@ -268,11 +353,32 @@ func getDocumentList(spaceId uuid.UUID, userId uuid.UUID) ([]PageList, error) {
 		logger().Error(err.Error())
 		return pageList, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
 	}
+	starred, err := star.StarredPageIds(userId, spaceId)
+	if err != nil {
+		return pageList, err
+	}
+	for i := range pageList {
+		pageList[i].IsStarred = starred[pageList[i].PageId]
+	}
 	return pageList, nil
 }
 
-func getPageDescendants(spaceId uuid.UUID, userId uuid.UUID, pageId int64) ([]PageDescendant, error) {
-	pages, err := getDocumentList(spaceId, userId)
+func intersectIds(ids []string, allowed []string) []string {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, id := range allowed {
+		allowedSet[id] = true
+	}
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if allowedSet[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func getPageDescendants(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID, pageId int64) ([]PageDescendant, error) {
+	pages, err := getDocumentList(ctx, spaceId, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -321,30 +427,26 @@ func getPageDescendants(spaceId uuid.UUID, userId uuid.UUID, pageId int64) ([]Pa
 	return buildTree(pageId), nil
 }
 
-func getSpaceUsers(spaceId uuid.UUID) ([]User, error) {
-	tuples, err := core.GetSubjectsAssociatedWithEntity("space", spaceId.String())
+func getSpaceUsers(ctx context.Context, spaceId uuid.UUID) ([]User, error) {
+	members, err := resolveSpaceMembers(ctx, spaceId)
 	if err != nil {
-		logger().Error(err.Error())
-		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
-	}
-
-	usersMap := make(map[string][]string)
-	for _, tuple := range tuples {
-		usersMap[tuple.Subject.Id] = append(usersMap[tuple.Subject.Id], tuple.Relation)
+		return nil, err
 	}
 
-	users := make([]User, 0, len(usersMap))
-	for userID, roles := range usersMap {
+	users := make([]User, 0, len(members))
+	for userID, member := range members {
 		parsed, err := uuid.Parse(userID)
 		if err != nil {
 			continue
 		}
-		highestRole := getHighestRole(roles)
+		highestRole := getHighestRole(member.roles)
 		users = append(users, User{
 			Id:      parsed,
 			Name:    userID,
 			Role:    highestRole,
 			IsOwner: storageRole(highestRole) == "owner",
+			Direct:  member.direct,
+			Groups:  member.groups,
 		})
 	}
 
@@ -356,45 +458,56 @@ func getSpaceUsers(spaceId uuid.UUID) ([]User, error) {
 	for _, user := range users {
 		ids = append(ids, user.Id.String())
 	}
-	zitaMapRows, err := core.GetZitaIds(ids)
+	profiles, err := core.GetUserProfiles(ids)
 	if err != nil {
 		logger().Error(err.Error())
 		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 	}
-	zitaIDByBeskarID := make(map[string]string, len(zitaMapRows))
-	zitaIDs := make([]string, 0, len(zitaMapRows))
-	for _, row := range zitaMapRows {
-		zitaIDByBeskarID[row.UserId] = row.Id
-		zitaIDs = append(zitaIDs, row.Id)
-	}
-	if len(zitaIDs) == 0 {
-		return users, nil
-	}
-	usersDetails, err := core.SearchUsersByIds(zitaIDs)
+	expiries, err := getSpaceMemberExpiries(ctx, spaceId)
 	if err != nil {
-		logger().Error(err.Error())
-		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
-	}
-	userByZitaID := make(map[string]core.User, len(usersDetails.Result))
-	for _, result := range usersDetails.Result {
-		userByZitaID[result.UserId] = result
+		return nil, err
 	}
 	for i := range users {
-		if zitaID, ok := zitaIDByBeskarID[users[i].Id.String()]; ok {
-			if result, found := userByZitaID[zitaID]; found {
-				users[i].Name = result.Human.Profile.DisplayName
-				users[i].Email = result.Human.Email.Email
-			}
+		if profile, ok := profiles[users[i].Id.String()]; ok {
+			users[i].Name = profile.Name
+			users[i].Email = profile.Email
 		}
 		if users[i].Name == "" {
 			users[i].Name = users[i].Email
 		}
+		if expiresAt, ok := expiries[users[i].Id]; ok && users[i].Direct {
+			users[i].ExpiresAt = &expiresAt
+		}
 	}
 	return users, nil
 }
 
-func getCurrentOwner(spaceId uuid.UUID) (User, error) {
-	users, err := getSpaceUsers(spaceId)
+func getSpaceMemberExpiries(ctx context.Context, spaceId uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	rows, err := core.GetPool().Query(ctx, GET_SPACE_MEMBER_EXPIRIES, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	defer rows.Close()
+	expiries := make(map[uuid.UUID]time.Time)
+	for rows.Next() {
+		var userId uuid.UUID
+		var expiresAt time.Time
+		if err := rows.Scan(&userId, &expiresAt); err != nil {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+		expiries[userId] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return expiries, nil
+}
+
+func getCurrentOwner(ctx context.Context, spaceId uuid.UUID) (User, error) {
+	users, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
 		return User{}, err
 	}
@@ -406,10 +519,9 @@ func getCurrentOwner(spaceId uuid.UUID) (User, error) {
 	return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
 }
 
-func getSpaceDetails(spaceId uuid.UUID, userId uuid.UUID) (Space, error) {
+func getSpaceDetails(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) (Space, error) {
 	var space Space
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		logger().Error(err.Error())
@@ -441,13 +553,13 @@ func getSpaceDetails(spaceId uuid.UUID, userId uuid.UUID) (Space, error) {
 		}
 	}
 
-	memberCount, userRole, membershipErr := getSpaceMembershipSummary(spaceId, userId)
+	memberCount, userRole, membershipErr := getSpaceMembershipSummary(ctx, spaceId, userId)
 	if membershipErr == nil {
 		space.MemberCount = memberCount
 		space.UserRole = userRole
 	}
 
-	owner, ownerErr := getCurrentOwner(spaceId)
+	owner, ownerErr := getCurrentOwner(ctx, spaceId)
 	if ownerErr == nil {
 		space.CurrentOwnerId = owner.Id
 	}
@@ -455,15 +567,21 @@ func getSpaceDetails(spaceId uuid.UUID, userId uuid.UUID) (Space, error) {
 	return space, nil
 }
 
-func getSpaceSettingsState(spaceId uuid.UUID, userId uuid.UUID) (SpaceSettingsState, error) {
-	space, err := getSpaceDetails(spaceId, userId)
+func getSpaceSettingsState(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) (SpaceSettingsState, error) {
+	space, err := getSpaceDetails(ctx, spaceId, userId)
 	if err != nil {
 		return SpaceSettingsState{}, err
 	}
-	owner, err := getCurrentOwner(spaceId)
+	owner, err := getCurrentOwner(ctx, spaceId)
 	if err != nil {
 		return SpaceSettingsState{}, err
 	}
+	joining, err := getSpaceJoinSettings(ctx, spaceId)
+	if err != nil {
+		return SpaceSettingsState{}, err
+	}
+	allowed, _ := core.CheckPermissions(ctx, "space", spaceId.String(), "user", userId.String(),
+		core.SPACE_MANAGE_MEMBERS, core.SPACE_TRANSFER_OWNER, core.SPACE_ARCHIVE, core.SPACE_DELETE)
 	return SpaceSettingsState{
 		Id:                   space.Id,
 		Name:                 space.Name,
@@ -479,10 +597,11 @@ func getSpaceSettingsState(spaceId uuid.UUID, userId uuid.UUID) (SpaceSettingsSt
 		DocCount:             space.DocCount,
 		WhiteboardCount:      space.WhiteboardCount,
 		UserRole:             space.UserRole,
-		CanManageMembers:     core.ValidateUserSpacePermissions(spaceId, userId, core.SPACE_MANAGE_MEMBERS),
-		CanTransferOwnership: core.ValidateUserSpacePermissions(spaceId, userId, core.SPACE_TRANSFER_OWNER),
-		CanArchive:           core.ValidateUserSpacePermissions(spaceId, userId, core.SPACE_ARCHIVE),
-		CanDelete:            core.ValidateUserSpacePermissions(spaceId, userId, core.SPACE_DELETE),
+		CanManageMembers:     allowed[core.SPACE_MANAGE_MEMBERS],
+		CanTransferOwnership: allowed[core.SPACE_TRANSFER_OWNER],
+		CanArchive:           allowed[core.SPACE_ARCHIVE],
+		CanDelete:            allowed[core.SPACE_DELETE],
+		Joining:              joining,
 	}, nil
 }
 
@@ -532,99 +651,356 @@ func ensureSpaceMutable(spaceId uuid.UUID) error {
 	return nil
 }
 
-func addSpaceMembers(spaceId uuid.UUID, actorId uuid.UUID, req AddSpaceMembersRequest) (map[string]any, error) {
+func addSpaceMembers(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req AddSpaceMembersRequest) (map[string]any, error) {
 	if err := ensureSpaceMutable(spaceId); err != nil {
 		return nil, err
 	}
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
 		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	existingUsers, err := getSpaceUsers(spaceId)
+	existingUsers, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
 		return nil, err
 	}
 	existing := make(map[string]bool, len(existingUsers))
 	for _, user := range existingUsers {
-		existing[user.Id.String()] = true
+		// users who only have group access can still be added directly
+		existing[user.Id.String()] = user.Direct
+	}
+	requested := make([]string, 0, len(req.Members))
+	for _, member := range req.Members {
+		requested = append(requested, member.UserId)
+	}
+	// only members of the organization can be added directly; others are invited
+	inOrganization, err := org.MemberIds(ctx, core.OrganizationFromContext(ctx), requested)
+	if err != nil {
+		return nil, err
 	}
 	addedCount := 0
 	skippedExisting := 0
+	added := make([]AddSpaceMemberItem, 0, len(req.Members))
+	relations := make([]core.RelationChange, 0, len(req.Members))
 	for _, member := range req.Members {
 		if existing[member.UserId] {
 			skippedExisting++
 			continue
 		}
-		if err := core.WriteRelations(spaceId.String(), "space", member.UserId, "user", storageRole(member.Role)); err != nil {
-			return nil, err
+		if !inOrganization[member.UserId] {
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
 		}
+		relations = append(relations, core.RelationWrite("space", spaceId.String(), storageRole(member.Role), "user", member.UserId))
 		addedCount++
+		added = append(added, member)
+	}
+	tx, err := core.GetPool().Begin(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer tx.Rollback(ctx)
+	if err := core.QueueRelations(ctx, tx, relations...); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	for _, member := range added {
+		if member.ExpiresAt == nil {
+			continue
+		}
+		if _, err := tx.Exec(ctx, UPSERT_SPACE_MEMBER_EXPIRY, spaceId, member.UserId, member.ExpiresAt, actorId); err != nil {
+			logger().Error(err.Error())
+			return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+		}
 	}
+	if err := tx.Commit(ctx); err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	core.ApplyQueuedRelations(ctx)
 	return map[string]any{
 		"addedCount":      addedCount,
 		"skippedExisting": skippedExisting,
+		"added":           added,
 	}, nil
 }
 
-func changeSpaceMemberRole(spaceId uuid.UUID, actorId uuid.UUID, req ChangeSpaceMemberRoleRequest) (User, error) {
+// changeSpaceMemberRole returns the member with the new role and the role
+// they had before the change.
+func changeSpaceMemberRole(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req ChangeSpaceMemberRoleRequest) (User, string, error) {
 	if err := ensureSpaceMutable(spaceId); err != nil {
-		return User{}, err
+		return User{}, "", err
 	}
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
-		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return User{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
 	targetID := uuid.MustParse(req.UserId)
-	users, err := getSpaceUsers(spaceId)
+	users, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
-		return User{}, err
+		return User{}, "", err
 	}
 	for _, user := range users {
 		if user.Id == targetID {
 			if user.IsOwner {
-				return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return User{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 			}
-			if err := core.DeleteSubjectRelations(spaceId.String(), "space", req.UserId, "user"); err != nil {
-				return User{}, err
-			}
-			if err := core.WriteRelations(spaceId.String(), "space", req.UserId, "user", storageRole(req.Role)); err != nil {
-				return User{}, err
+			previousRole := user.Role
+			err := core.ApplyRelations(ctx,
+				core.RelationDelete("space", spaceId.String(), "", "user", req.UserId),
+				core.RelationWrite("space", spaceId.String(), storageRole(req.Role), "user", req.UserId),
+			)
+			if err != nil {
+				return User{}, "", err
 			}
 			user.Role = normalizeRole(req.Role)
-			return user, nil
+			return user, previousRole, nil
 		}
 	}
-	return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	return User{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
 }
 
-func removeSpaceMember(spaceId uuid.UUID, actorId uuid.UUID, req RemoveSpaceMemberRequest) error {
+// removeSpaceMember returns the member as they were before removal.
+func removeSpaceMember(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req RemoveSpaceMemberRequest) (User, error) {
 	if err := ensureSpaceMutable(spaceId); err != nil {
-		return err
+		return User{}, err
 	}
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
-		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
 	if actorId.String() == req.UserId {
-		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	users, err := getSpaceUsers(spaceId)
+	users, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
-		return err
+		return User{}, err
 	}
 	for _, user := range users {
 		if user.Id.String() == req.UserId {
 			if user.IsOwner {
-				return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+			}
+			if !user.Direct {
+				// access comes from a group; remove the group or the user from it instead
+				return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+			tx, err := core.GetPool().Begin(ctx)
+			if err != nil {
+				logger().Error(err.Error())
+				return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+			}
+			defer tx.Rollback(ctx)
+			if err := removeDirectMembership(ctx, tx, spaceId, user.Id); err != nil {
+				return User{}, err
+			}
+			if err := tx.Commit(ctx); err != nil {
+				logger().Error(err.Error())
+				return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 			}
-			return core.DeleteSubjectRelations(spaceId.String(), "space", req.UserId, "user")
+			core.ApplyQueuedRelations(ctx)
+			return user, nil
 		}
 	}
-	return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+}
+
+// removeDirectMembership queues the removal of the user's direct roles on the
+// space as part of tx, along with any expiry set for them. Call
+// core.ApplyQueuedRelations once tx has committed.
+func removeDirectMembership(ctx context.Context, tx pgx.Tx, spaceId uuid.UUID, userId uuid.UUID) error {
+	if err := core.QueueRelations(ctx, tx, core.RelationDelete("space", spaceId.String(), "", "user", userId.String())); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if _, err := tx.Exec(ctx, DELETE_SPACE_MEMBER_EXPIRY, spaceId, userId); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	return nil
 }
 
-func searchMemberCandidates(spaceId uuid.UUID, actorId uuid.UUID, req MemberCandidateSearchRequest) (MemberCandidateSearchResponse, error) {
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+// setSpaceMemberExpiry sets or clears when a direct member's access ends and
+// returns the member with the new expiry and the one they had before.
+func setSpaceMemberExpiry(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req SpaceMemberExpiryRequest) (User, *time.Time, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return User{}, nil, err
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return User{}, nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	users, err := getSpaceUsers(ctx, spaceId)
+	if err != nil {
+		return User{}, nil, err
+	}
+	for _, user := range users {
+		if user.Id.String() != req.UserId {
+			continue
+		}
+		if user.IsOwner {
+			return User{}, nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		}
+		if !user.Direct {
+			// group access ends with the group, not here
+			return User{}, nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if req.ExpiresAt != nil {
+			_, err = core.GetPool().Exec(ctx, UPSERT_SPACE_MEMBER_EXPIRY, spaceId, user.Id, req.ExpiresAt, actorId)
+		} else {
+			_, err = core.GetPool().Exec(ctx, DELETE_SPACE_MEMBER_EXPIRY, spaceId, user.Id)
+		}
+		if err != nil {
+			logger().Error(err.Error())
+			return User{}, nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		}
+		previous := user.ExpiresAt
+		user.ExpiresAt = req.ExpiresAt
+		return user, previous, nil
+	}
+	return User{}, nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+}
+
+func getSpaceGroupRoles(ctx context.Context, spaceId uuid.UUID) (map[uuid.UUID][]string, error) {
+	tuples, err := core.GetSubjectsAssociatedWithEntity(ctx, "space", spaceId.String())
+	if err != nil {
+		logger().Error(err.Error())
+		return nil, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	groupRoles := make(map[uuid.UUID][]string)
+	for _, tuple := range tuples {
+		if tuple.Subject.Type != "group" {
+			continue
+		}
+		if groupId, err := uuid.Parse(tuple.Subject.Id); err == nil {
+			groupRoles[groupId] = append(groupRoles[groupId], tuple.Relation)
+		}
+	}
+	return groupRoles, nil
+}
+
+func listSpaceGroups(ctx context.Context, spaceId uuid.UUID) ([]SpaceGroup, error) {
+	groupRoles, err := getSpaceGroupRoles(ctx, spaceId)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]SpaceGroup, 0, len(groupRoles))
+	if len(groupRoles) == 0 {
+		return groups, nil
+	}
+	groupIds := make([]uuid.UUID, 0, len(groupRoles))
+	for groupId := range groupRoles {
+		groupIds = append(groupIds, groupId)
+	}
+	names, err := group.GroupNames(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := group.ExpandUsers(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupId := range groupIds {
+		groups = append(groups, SpaceGroup{
+			GroupId:     groupId,
+			Name:        names[groupId],
+			Role:        getHighestRole(groupRoles[groupId]),
+			MemberCount: len(expanded[groupId]),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+	return groups, nil
+}
+
+// addSpaceGroup grants a role on the space to every member of the group.
+func addSpaceGroup(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req SpaceGroupRequest) (SpaceGroup, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return SpaceGroup{}, err
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	groupId := uuid.MustParse(req.GroupId)
+	if !core.ValidateUserEntityPermission(ctx, "group", req.GroupId, actorId, "view") {
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	target, err := group.GetGroup(ctx, groupId)
+	if err != nil {
+		return SpaceGroup{}, err
+	}
+	groupRoles, err := getSpaceGroupRoles(ctx, spaceId)
+	if err != nil {
+		return SpaceGroup{}, err
+	}
+	if _, exists := groupRoles[groupId]; exists {
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	grant := core.RelationWrite("space", spaceId.String(), storageRole(req.Role), "group", req.GroupId).OfSubjectSet("member")
+	if err := core.ApplyRelations(ctx, grant); err != nil {
+		logger().Error(err.Error())
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	return SpaceGroup{GroupId: groupId, Name: target.Name, Role: normalizeRole(req.Role)}, nil
+}
+
+// changeSpaceGroupRole returns the group with its new role and the role it
+// had before the change.
+func changeSpaceGroupRole(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req SpaceGroupRequest) (SpaceGroup, string, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return SpaceGroup{}, "", err
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return SpaceGroup{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	groupId := uuid.MustParse(req.GroupId)
+	groupRoles, err := getSpaceGroupRoles(ctx, spaceId)
+	if err != nil {
+		return SpaceGroup{}, "", err
+	}
+	roles, exists := groupRoles[groupId]
+	if !exists {
+		return SpaceGroup{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	err = core.ApplyRelations(ctx,
+		core.RelationDelete("space", spaceId.String(), "", "group", req.GroupId).OfSubjectSet("member"),
+		core.RelationWrite("space", spaceId.String(), storageRole(req.Role), "group", req.GroupId).OfSubjectSet("member"),
+	)
+	if err != nil {
+		logger().Error(err.Error())
+		return SpaceGroup{}, "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	names, err := group.GroupNames(ctx, []uuid.UUID{groupId})
+	if err != nil {
+		return SpaceGroup{}, "", err
+	}
+	return SpaceGroup{GroupId: groupId, Name: names[groupId], Role: normalizeRole(req.Role)}, getHighestRole(roles), nil
+}
+
+// removeSpaceGroup returns the group as it was before removal.
+func removeSpaceGroup(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, groupId uuid.UUID) (SpaceGroup, error) {
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return SpaceGroup{}, err
+	}
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	groupRoles, err := getSpaceGroupRoles(ctx, spaceId)
+	if err != nil {
+		return SpaceGroup{}, err
+	}
+	roles, exists := groupRoles[groupId]
+	if !exists {
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err := core.ApplyRelations(ctx, core.RelationDelete("space", spaceId.String(), "", "group", groupId.String()).OfSubjectSet("member")); err != nil {
+		logger().Error(err.Error())
+		return SpaceGroup{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	return SpaceGroup{GroupId: groupId, Role: getHighestRole(roles)}, nil
+}
+
+func searchMemberCandidates(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req MemberCandidateSearchRequest) (MemberCandidateSearchResponse, error) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_MANAGE_MEMBERS) {
 		return MemberCandidateSearchResponse{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	users, err := getSpaceUsers(spaceId)
+	users, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
 		return MemberCandidateSearchResponse{}, err
 	}
@@ -699,6 +1075,10 @@ func searchMemberCandidates(spaceId uuid.UUID, actorId uuid.UUID, req MemberCand
 				})
 			}
 		}
+		resp.Matches, err = organizationCandidates(ctx, resp.Matches)
+		if err != nil {
+			return MemberCandidateSearchResponse{}, err
+		}
 	}
 
 	inputEmails := append([]string{}, req.Emails...)
@@ -730,6 +1110,7 @@ func searchMemberCandidates(spaceId uuid.UUID, actorId uuid.UUID, req MemberCand
 		}
 		searchResult, searchErr := core.SearchUserByEmail(email, req.Limit, req.Offset)
 		if searchErr == nil && len(searchResult.Result) > 0 {
+			matches := make([]MemberCandidate, 0, len(searchResult.Result))
 			for _, result := range searchResult.Result {
 				beskarID, idErr := core.GetBeskarUser(result.UserId)
 				if idErr != nil {
@@ -742,13 +1123,18 @@ func searchMemberCandidates(spaceId uuid.UUID, actorId uuid.UUID, req MemberCand
 				if displayName == "" {
 					displayName = result.Human.Email.Email
 				}
-				resp.Matches = append(resp.Matches, MemberCandidate{
+				matches = append(matches, MemberCandidate{
 					UserId: beskarID,
 					Name:   displayName,
 					Email:  result.Human.Email.Email,
 				})
 			}
-			if len(searchResult.Result) > 0 {
+			matches, err = organizationCandidates(ctx, matches)
+			if err != nil {
+				return MemberCandidateSearchResponse{}, err
+			}
+			if len(matches) > 0 {
+				resp.Matches = append(resp.Matches, matches...)
 				continue
 			}
 		}
@@ -761,21 +1147,21 @@ func searchMemberCandidates(spaceId uuid.UUID, actorId uuid.UUID, req MemberCand
 	return resp, nil
 }
 
-func transferOwnership(spaceId uuid.UUID, actorId uuid.UUID, req TransferOwnershipRequest) (User, error) {
+func transferOwnership(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req TransferOwnershipRequest) (User, error) {
 	if err := ensureSpaceMutable(spaceId); err != nil {
 		return User{}, err
 	}
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_TRANSFER_OWNER) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_TRANSFER_OWNER) {
 		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	owner, err := getCurrentOwner(spaceId)
+	owner, err := getCurrentOwner(ctx, spaceId)
 	if err != nil {
 		return User{}, err
 	}
 	if owner.Id != actorId {
 		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	users, err := getSpaceUsers(spaceId)
+	users, err := getSpaceUsers(ctx, spaceId)
 	if err != nil {
 		return User{}, err
 	}
@@ -791,16 +1177,13 @@ func transferOwnership(spaceId uuid.UUID, actorId uuid.UUID, req TransferOwnersh
 	if !found || nextOwner.IsOwner {
 		return User{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
 	}
-	if err := core.DeleteRelation(spaceId.String(), "space", actorId.String(), "user", "owner"); err != nil {
-		return User{}, err
-	}
-	if err := core.DeleteSubjectRelations(spaceId.String(), "space", req.NewOwnerUserId, "user"); err != nil {
-		return User{}, err
-	}
-	if err := core.WriteRelations(spaceId.String(), "space", actorId.String(), "user", "admin"); err != nil {
-		return User{}, err
-	}
-	if err := core.WriteRelations(spaceId.String(), "space", req.NewOwnerUserId, "user", "owner"); err != nil {
+	err = core.ApplyRelations(ctx,
+		core.RelationDelete("space", spaceId.String(), "owner", "user", actorId.String()),
+		core.RelationDelete("space", spaceId.String(), "", "user", req.NewOwnerUserId),
+		core.RelationWrite("space", spaceId.String(), "admin", "user", actorId.String()),
+		core.RelationWrite("space", spaceId.String(), "owner", "user", req.NewOwnerUserId),
+	)
+	if err != nil {
 		return User{}, err
 	}
 	nextOwner.Role = "owner"
@@ -808,12 +1191,11 @@ func transferOwnership(spaceId uuid.UUID, actorId uuid.UUID, req TransferOwnersh
 	return nextOwner, nil
 }
 
-func archiveSpace(spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_ARCHIVE) {
+func archiveSpace(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_ARCHIVE) {
 		return Space{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		return Space{}, err
@@ -830,12 +1212,11 @@ func archiveSpace(spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
 	return space, nil
 }
 
-func unarchiveSpace(spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_ARCHIVE) {
+func unarchiveSpace(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_ARCHIVE) {
 		return Space{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		return Space{}, err
@@ -848,11 +1229,22 @@ func unarchiveSpace(spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
 	return pgx.CollectExactlyOneRow(rows, pgx.RowToStructByNameLax[Space])
 }
 
-func softDeleteSpace(spaceId uuid.UUID, actorId uuid.UUID, req DeleteSpaceRequest) error {
-	if !core.ValidateUserSpacePermissions(spaceId, actorId, core.SPACE_DELETE) {
+// changeSpaceSlug returns the slug that was replaced along with the new one.
+func changeSpaceSlug(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req slug.UpdateSpaceSlugRequest) (string, error) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_EDIT) {
+		return "", errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	if err := ensureSpaceMutable(spaceId); err != nil {
+		return "", err
+	}
+	return slug.SetSpaceSlug(ctx, spaceId, req.Slug)
+}
+
+func softDeleteSpace(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID, req DeleteSpaceRequest) error {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_DELETE) {
 		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
 	}
-	details, err := getSpaceDetails(spaceId, actorId)
+	details, err := getSpaceDetails(ctx, spaceId, actorId)
 	if err != nil {
 		return err
 	}
@@ -860,7 +1252,6 @@ func softDeleteSpace(spaceId uuid.UUID, actorId uuid.UUID, req DeleteSpaceReques
 		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
 	}
 	connPool := core.GetPool()
-	ctx := context.Background()
 	conn, err := connPool.Acquire(ctx)
 	if err != nil {
 		return err
@@ -870,6 +1261,67 @@ func softDeleteSpace(spaceId uuid.UUID, actorId uuid.UUID, req DeleteSpaceReques
 	return err
 }
 
+// restoreSpace undoes a soft delete while the space is still inside the purge
+// grace period. Once the window has passed the space is treated as gone.
+func restoreSpace(ctx context.Context, spaceId uuid.UUID, actorId uuid.UUID) (Space, error) {
+	if !core.ValidateUserSpacePermissions(ctx, spaceId, actorId, core.SPACE_DELETE) {
+		return Space{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	connPool := core.GetPool()
+	conn, err := connPool.Acquire(ctx)
+	if err != nil {
+		return Space{}, err
+	}
+	defer conn.Release()
+	cutoff := time.Now().Add(-LoadPurgeConfig().GracePeriod)
+	rows, err := conn.Query(ctx, RESTORE_SPACE, spaceId, cutoff)
+	if err != nil {
+		return Space{}, err
+	}
+	space, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByNameLax[Space])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Space{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return space, err
+}
+
+// listDeletedSpaces returns the soft-deleted spaces in the current
+// organization that the user could still restore.
+func listDeletedSpaces(ctx context.Context, userId uuid.UUID) ([]DeletedSpace, error) {
+	spaces := make([]DeletedSpace, 0)
+	spaceIds, err := core.GetEntitiesWithPermission(ctx, "space", "user", userId.String(), core.SPACE_DELETE)
+	if err != nil {
+		logger().Error(err.Error())
+		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	if len(spaceIds) == 0 {
+		return spaces, nil
+	}
+	parsedSpaceIds := make([]uuid.UUID, 0, len(spaceIds))
+	for _, spaceId := range spaceIds {
+		parsedId, parseErr := uuid.Parse(spaceId)
+		if parseErr != nil {
+			continue
+		}
+		parsedSpaceIds = append(parsedSpaceIds, parsedId)
+	}
+	grace := LoadPurgeConfig().GracePeriod
+	rows, err := core.GetPool().Query(ctx, GET_DELETED_SPACES, parsedSpaceIds, core.OrganizationFromContext(ctx), time.Now().Add(-grace))
+	if err != nil {
+		logger().Error(err.Error())
+		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	spaces, err = pgx.CollectRows(rows, pgx.RowToStructByNameLax[DeletedSpace])
+	if err != nil {
+		logger().Error(err.Error())
+		return spaces, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for i := range spaces {
+		spaces[i].PurgeAfter = spaces[i].DeletedAt.Add(grace)
+	}
+	return spaces, nil
+}
+
 func getPendingInviteEmails(spaceId uuid.UUID) (map[string]string, error) {
 	connPool := core.GetPool()
 	ctx := context.Background()
@@ -895,6 +1347,28 @@ func getPendingInviteEmails(spaceId uuid.UUID) (map[string]string, error) {
 	return result, rows.Err()
 }
 
+// organizationCandidates keeps only users in the caller's organization. Users
+// elsewhere on the instance are never revealed; their email falls through to
+// the unknown list and can still be invited, which adds them to the
+// organization on acceptance.
+func organizationCandidates(ctx context.Context, candidates []MemberCandidate) ([]MemberCandidate, error) {
+	userIds := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		userIds = append(userIds, candidate.UserId)
+	}
+	members, err := org.MemberIds(ctx, core.OrganizationFromContext(ctx), userIds)
+	if err != nil {
+		return candidates, err
+	}
+	filtered := make([]MemberCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if members[candidate.UserId] {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered, nil
+}
+
 func ensureSpaceMutableByID(spaceId uuid.UUID) error {
 	return ensureSpaceMutable(spaceId)
 }
@@ -1,8 +1,10 @@
 package space
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/durgakiran/beskar/core"
 	"github.com/google/uuid"
 )
 
@@ -22,6 +24,7 @@ type Space struct {
 	DocCount        int        `json:"docCount"`
 	WhiteboardCount int        `json:"whiteboardCount"`
 	UserRole        string     `json:"userRole"`
+	Slug            string     `json:"slug" db:"slug"`
 }
 
 type SpaceListItem struct {
@@ -35,15 +38,18 @@ type SpaceListItem struct {
 	DocCount        int        `json:"docCount"`
 	WhiteboardCount int        `json:"whiteboardCount"`
 	UserRole        string     `json:"userRole"`
+	IsStarred       bool       `json:"isStarred"`
+	Slug            string     `json:"slug" db:"slug"`
 }
 
 type PageList struct {
-	PageId   int64     `json:"pageId" db:"id"`
-	OwnerId  uuid.UUID `json:"ownerId" db:"owner_id"`
-	Title    string    `json:"title" db:"title"`
-	ParentId int64     `json:"parentId" db:"parent_id"`
-	Draft    int8      `json:"draft" db:"draft"`
-	Type     string    `json:"type" db:"type"`
+	PageId    int64     `json:"pageId" db:"id"`
+	OwnerId   uuid.UUID `json:"ownerId" db:"owner_id"`
+	Title     string    `json:"title" db:"title"`
+	ParentId  int64     `json:"parentId" db:"parent_id"`
+	Draft     int8      `json:"draft" db:"draft"`
+	Type      string    `json:"type" db:"type"`
+	IsStarred bool      `json:"isStarred" db:"-"`
 }
 
 type PageDescendant struct {
@@ -60,6 +66,29 @@ type User struct {
 	Email    string     `json:"email" db:"email"`
 	IsOwner  bool       `json:"isOwner"`
 	JoinedAt *time.Time `json:"joinedAt,omitempty" db:"joined_at"`
+	// Direct is false when the user only has access through Groups.
+	Direct bool         `json:"direct"`
+	Groups []GroupGrant `json:"groups,omitempty"`
+	// ExpiresAt is when a time-limited direct membership ends.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type GroupGrant struct {
+	GroupId uuid.UUID `json:"groupId"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+}
+
+type SpaceGroup struct {
+	GroupId     uuid.UUID `json:"groupId"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role"`
+	MemberCount int       `json:"memberCount"`
+}
+
+type SpaceGroupRequest struct {
+	GroupId string `json:"groupId"`
+	Role    string `json:"role"`
 }
 
 type SpaceSettingsState struct {
@@ -81,11 +110,15 @@ type SpaceSettingsState struct {
 	CanTransferOwnership bool       `json:"canTransferOwnership"`
 	CanArchive           bool       `json:"canArchive"`
 	CanDelete            bool       `json:"canDelete"`
+
+	Joining SpaceJoinSettings `json:"joining"`
 }
 
 type AddSpaceMemberItem struct {
 	UserId string `json:"userId"`
 	Role   string `json:"role"`
+	// ExpiresAt makes the membership time-limited; nil keeps it.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type AddSpaceMembersRequest struct {
@@ -101,6 +134,20 @@ type RemoveSpaceMemberRequest struct {
 	UserId string `json:"userId"`
 }
 
+// SpaceMemberExpiryRequest sets when a direct membership ends, or clears
+// the end when ExpiresAt is nil.
+type SpaceMemberExpiryRequest struct {
+	UserId    string     `json:"userId"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// MembershipExpiry is a time-limited membership due for a notice or removal.
+type MembershipExpiry struct {
+	SpaceId   uuid.UUID
+	UserId    uuid.UUID
+	ExpiresAt time.Time
+}
+
 type MemberCandidateSearchRequest struct {
 	Query  string   `json:"query"`
 	Emails []string `json:"emails"`
@@ -131,3 +178,339 @@ type TransferOwnershipRequest struct {
 type DeleteSpaceRequest struct {
 	ConfirmName string `json:"confirmName"`
 }
+
+type DeletedSpace struct {
+	Id          uuid.UUID  `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	DeletedAt   time.Time  `json:"deletedAt" db:"deleted_at"`
+	DeletedBy   *uuid.UUID `json:"deletedBy,omitempty" db:"deleted_by"`
+	PurgeAfter  time.Time  `json:"purgeAfter" db:"-"`
+}
+
+// PurgeReport describes what a purge removed. It is stored in
+// core.space_purge_reports and recorded in the audit log.
+type PurgeReport struct {
+	SpaceId     uuid.UUID  `json:"spaceId"`
+	SpaceName   string     `json:"spaceName"`
+	OrgId       *uuid.UUID `json:"orgId,omitempty"`
+	DeletedAt   time.Time  `json:"deletedAt"`
+	DeletedBy   *uuid.UUID `json:"deletedBy,omitempty"`
+	PurgedAt    time.Time  `json:"purgedAt"`
+	Pages       int        `json:"pages"`
+	Attachments int        `json:"attachments"`
+	Images      int        `json:"images"`
+	BytesFreed  int64      `json:"bytesFreed"`
+	Invites     int        `json:"invites"`
+	Relations   int        `json:"relations"`
+	Errors      []string   `json:"errors,omitempty"`
+}
+
+// ArchiveManifest is manifest.json inside a space export. File fields are
+// paths of entries in the same zip. Ids are the ones of the exporting
+// instance and are remapped on import; people are referenced by email.
+type ArchiveManifest struct {
+	Format      string              `json:"format"`
+	Version     int                 `json:"version"`
+	ExportedAt  time.Time           `json:"exportedAt"`
+	ExportedBy  string              `json:"exportedBy"`
+	Space       ArchiveSpace        `json:"space"`
+	Members     []ArchiveMember     `json:"members"`
+	Pages       []ArchivePage       `json:"pages"`
+	Attachments []ArchiveAttachment `json:"attachments"`
+	Images      []ArchiveImage      `json:"images"`
+	Comments    []ArchiveThread     `json:"comments"`
+}
+
+type ArchiveSpace struct {
+	Id          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	DateCreated time.Time `json:"dateCreated"`
+}
+
+type ArchiveMember struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type ArchivePage struct {
+	Id          int64        `json:"id"`
+	ParentId    int64        `json:"parentId"`
+	Type        string       `json:"type"`
+	Owner       string       `json:"owner"`
+	DateCreated time.Time    `json:"dateCreated"`
+	Status      int          `json:"status"`
+	Docs        []ArchiveDoc `json:"docs"`
+}
+
+// ArchiveDoc is one row of core.page_doc_map: a published version when
+// Draft is false, the working draft otherwise.
+type ArchiveDoc struct {
+	Id         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Version    time.Time `json:"version"`
+	Owner      string    `json:"owner"`
+	Draft      bool      `json:"draft"`
+	Nodes      string    `json:"nodes,omitempty"`
+	DraftData  string    `json:"draftData,omitempty"`
+	Whiteboard string    `json:"whiteboard,omitempty"`
+}
+
+type ArchiveAttachment struct {
+	Id        uuid.UUID `json:"id"`
+	PageId    int64     `json:"pageId"`
+	FileName  string    `json:"fileName"`
+	MimeType  string    `json:"mimeType"`
+	FileSize  int64     `json:"fileSize"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	File      string    `json:"file"`
+}
+
+type ArchiveImage struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+type ArchiveThread struct {
+	PageId           int64           `json:"pageId"`
+	CommentId        uuid.UUID       `json:"commentId"`
+	QuotedText       string          `json:"quotedText"`
+	Anchor           json.RawMessage `json:"anchor"`
+	PublishedVisible bool            `json:"publishedVisible"`
+	Orphaned         bool            `json:"orphaned"`
+	CreatedBy        string          `json:"createdBy,omitempty"`
+	ResolvedBy       string          `json:"resolvedBy,omitempty"`
+	ResolvedAt       *time.Time      `json:"resolvedAt,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	Replies          []ArchiveReply  `json:"replies"`
+}
+
+type ArchiveReply struct {
+	Author      string      `json:"author,omitempty"`
+	Body        string      `json:"body"`
+	EditedAt    *time.Time  `json:"editedAt,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	Attachments []uuid.UUID `json:"attachments"`
+}
+
+// ImportResult tells the caller where the space landed and what could not be
+// carried over.
+type ImportResult struct {
+	SpaceId          uuid.UUID        `json:"spaceId"`
+	Pages            map[string]int64 `json:"pages"`
+	Attachments      int              `json:"attachments"`
+	Images           int              `json:"images"`
+	Comments         int              `json:"comments"`
+	Members          int              `json:"members"`
+	UnresolvedEmails []string         `json:"unresolvedEmails"`
+	Skipped          []string         `json:"skipped"`
+}
+
+// AccessQuery names the permission an access explanation is asked for.
+type AccessQuery struct {
+	UserId     uuid.UUID
+	Entity     string
+	EntityId   string
+	Permission string
+}
+
+// AccessExplanation tells why a user has, or lacks, a permission on the space
+// or one of its pages. Grants and Blocks are the relationship paths found by
+// expanding the permission; the other fields describe the same access in the
+// terms the settings pages use.
+type AccessExplanation struct {
+	UserId       uuid.UUID           `json:"userId"`
+	Entity       string              `json:"entity"`
+	EntityId     string              `json:"entityId"`
+	Permission   string              `json:"permission"`
+	Allowed      bool                `json:"allowed"`
+	Role         string              `json:"role"`
+	Direct       bool                `json:"direct"`
+	Groups       []GroupGrant        `json:"groups"`
+	Restrictions []RestrictionAccess `json:"restrictions"`
+	Grants       []core.AccessPath   `json:"grants"`
+	Blocks       []core.AccessPath   `json:"blocks"`
+}
+
+// RestrictionAccess is a page restriction affecting the explained page.
+type RestrictionAccess struct {
+	Kind      string `json:"kind"`
+	PageId    int64  `json:"pageId"`
+	Title     string `json:"title"`
+	Inherited bool   `json:"inherited"`
+	// Listed is true when the restriction lets the user through, directly or
+	// through a group.
+	Listed bool `json:"listed"`
+}
+
+// AccessMatrix lists what a user may do on a space and on each of its pages.
+type AccessMatrix struct {
+	UserId uuid.UUID       `json:"userId"`
+	Role   string          `json:"role"`
+	Direct bool            `json:"direct"`
+	Groups []GroupGrant    `json:"groups"`
+	Space  map[string]bool `json:"space"`
+	Pages  []PageAccess    `json:"pages"`
+}
+
+type PageAccess struct {
+	PageId      int64           `json:"pageId"`
+	Title       string          `json:"title"`
+	ParentId    int64           `json:"parentId"`
+	Type        string          `json:"type"`
+	Permissions map[string]bool `json:"permissions"`
+}
+
+const (
+	REVIEW_SUBJECT_USER   = "user"
+	REVIEW_SUBJECT_INVITE = "invite"
+
+	REVIEW_CONFIRM = "confirm"
+	REVIEW_REVOKE  = "revoke"
+)
+
+// AccessReview is a snapshot of who had access to a space when the review
+// was started, with the decisions owners and admins made on it since.
+type AccessReview struct {
+	Id           uuid.UUID      `json:"id"`
+	SpaceId      uuid.UUID      `json:"spaceId"`
+	SpaceName    string         `json:"spaceName"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	CreatedBy    *uuid.UUID     `json:"createdBy,omitempty"`
+	CompletedAt  *time.Time     `json:"completedAt,omitempty"`
+	InactiveDays int            `json:"inactiveDays"`
+	Members      []ReviewMember `json:"members"`
+	Invites      []ReviewInvite `json:"invites"`
+}
+
+type ReviewMember struct {
+	UserId       uuid.UUID       `json:"userId"`
+	Name         string          `json:"name"`
+	Email        string          `json:"email"`
+	Role         string          `json:"role"`
+	Direct       bool            `json:"direct"`
+	Groups       []GroupGrant    `json:"groups,omitempty"`
+	LastActiveAt *time.Time      `json:"lastActiveAt,omitempty"`
+	Inactive     bool            `json:"inactive"`
+	External     bool            `json:"external"`
+	Decision     *ReviewDecision `json:"decision,omitempty"`
+}
+
+type ReviewInvite struct {
+	Email     string          `json:"email"`
+	Role      string          `json:"role"`
+	SenderId  uuid.UUID       `json:"senderId"`
+	CreatedAt *time.Time      `json:"createdAt,omitempty"`
+	External  bool            `json:"external"`
+	Decision  *ReviewDecision `json:"decision,omitempty"`
+}
+
+type ReviewDecision struct {
+	Decision  string    `json:"decision"`
+	DecidedBy uuid.UUID `json:"decidedBy"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+type AccessReviewSummary struct {
+	Id          uuid.UUID  `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy   *uuid.UUID `json:"createdBy,omitempty" db:"created_by"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+	Members     int        `json:"members" db:"members"`
+	Invites     int        `json:"invites" db:"invites"`
+	Decided     int        `json:"decided" db:"decided"`
+}
+
+// ReviewDecisionsRequest confirms or revokes access in bulk. Type is "user",
+// with the user id, or "invite", with the invited email address.
+type ReviewDecisionsRequest struct {
+	Decisions []ReviewDecisionRequest `json:"decisions"`
+}
+
+type ReviewDecisionRequest struct {
+	Type     string `json:"type"`
+	Id       string `json:"id"`
+	Decision string `json:"decision"`
+}
+
+type ReviewDecisionResult struct {
+	Type     string `json:"type"`
+	Id       string `json:"id"`
+	Decision string `json:"decision"`
+	Error    string `json:"error,omitempty"`
+}
+
+const (
+	ACCESS_REQUEST_PENDING  = "pending"
+	ACCESS_REQUEST_APPROVED = "approved"
+	ACCESS_REQUEST_DECLINED = "declined"
+)
+
+// AccessRequest is a user asking to join a space, usually after being
+// turned away from one of its pages.
+type AccessRequest struct {
+	Id             uuid.UUID  `json:"id" db:"id"`
+	SpaceId        uuid.UUID  `json:"spaceId" db:"space_id"`
+	PageId         *int64     `json:"pageId,omitempty" db:"page_id"`
+	PageTitle      *string    `json:"pageTitle,omitempty" db:"page_title"`
+	RequesterId    uuid.UUID  `json:"requesterId" db:"requester_id"`
+	RequesterName  string     `json:"requesterName" db:"-"`
+	RequesterEmail string     `json:"requesterEmail" db:"-"`
+	Message        string     `json:"message" db:"message"`
+	Status         string     `json:"status" db:"status"`
+	Role           *string    `json:"role,omitempty" db:"role"`
+	Response       *string    `json:"response,omitempty" db:"response"`
+	DecidedBy      *uuid.UUID `json:"decidedBy,omitempty" db:"decided_by"`
+	DecidedAt      *time.Time `json:"decidedAt,omitempty" db:"decided_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type CreateAccessRequest struct {
+	PageId  *int64 `json:"pageId"`
+	Message string `json:"message"`
+}
+
+// AccessRequestDecision approves a request with Role, and optionally an
+// ExpiresAt for the membership, or declines it. Response is passed on to the
+// requester either way.
+type AccessRequestDecision struct {
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Response  string     `json:"response"`
+}
+
+const (
+	JOIN_PRIVATE = "private"
+	JOIN_OPEN    = "open"
+	JOIN_DOMAIN  = "domain"
+)
+
+// SpaceJoinSettings says who can find and join a space without an invite.
+// Open spaces are listed to everyone on the instance; domain spaces only to
+// users whose verified email domain is in Domains. Joining grants Role.
+type SpaceJoinSettings struct {
+	Policy  string   `json:"policy" db:"join_policy"`
+	Domains []string `json:"domains" db:"join_domains"`
+	Role    string   `json:"role" db:"join_role"`
+}
+
+// DirectorySpace is a space the user can join by themselves.
+type DirectorySpace struct {
+	Id          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Slug        string    `json:"slug" db:"slug"`
+	Policy      string    `json:"policy" db:"join_policy"`
+	Role        string    `json:"role" db:"join_role"`
+	OrgId       uuid.UUID `json:"-" db:"org_id"`
+	TenantId    string    `json:"-" db:"tenant_id"`
+	IsMember    bool      `json:"isMember" db:"-"`
+}
+
+type DirectoryQuery struct {
+	Query  string
+	Limit  int
+	Offset int
+}
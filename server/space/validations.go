@@ -3,7 +3,11 @@ package space
 import (
 	"encoding/json"
 	"errors"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/durgakiran/beskar/core"
 	"github.com/google/uuid"
@@ -60,6 +64,9 @@ func validateAddSpaceMembers(data []byte) (AddSpaceMembersRequest, error) {
 		if !isValidMemberRole(req.Members[i].Role) {
 			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
 		}
+		if req.Members[i].ExpiresAt != nil && !req.Members[i].ExpiresAt.After(time.Now()) {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
 	}
 	return req, nil
 }
@@ -92,6 +99,37 @@ func validateRemoveSpaceMember(data []byte) (RemoveSpaceMemberRequest, error) {
 	return req, nil
 }
 
+func validateSpaceMemberExpiry(data []byte) (SpaceMemberExpiryRequest, error) {
+	var req SpaceMemberExpiryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	req.UserId = strings.TrimSpace(req.UserId)
+	if _, err := uuid.Parse(req.UserId); err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
+func validateSpaceGroupRequest(data []byte) (SpaceGroupRequest, error) {
+	var req SpaceGroupRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	req.GroupId = strings.TrimSpace(req.GroupId)
+	req.Role = normalizeIncomingRole(req.Role)
+	if _, err := uuid.Parse(req.GroupId); err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if !isValidMemberRole(req.Role) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
 func validateMemberCandidateSearch(data []byte) (MemberCandidateSearchRequest, error) {
 	var req MemberCandidateSearchRequest
 	if err := json.Unmarshal(data, &req); err != nil {
@@ -136,3 +174,277 @@ func validateDeleteSpaceRequest(data []byte) (DeleteSpaceRequest, error) {
 	}
 	return req, nil
 }
+
+// validateArchiveManifest checks that an uploaded manifest is one this
+// version understands and that its references hold together. Pages are
+// returned parents first so they can be inserted in order; entries pointing
+// at pages that are not in the archive are dropped.
+func validateArchiveManifest(data []byte) (ArchiveManifest, error) {
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if manifest.Format != ARCHIVE_FORMAT || manifest.Version < 1 || manifest.Version > ARCHIVE_VERSION {
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	manifest.Space.Name = strings.TrimSpace(manifest.Space.Name)
+	if manifest.Space.Name == "" {
+		return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+
+	pages := make(map[int64]ArchivePage, len(manifest.Pages))
+	docs := make(map[int64]bool)
+	for i := range manifest.Pages {
+		page := &manifest.Pages[i]
+		if _, ok := pages[page.Id]; ok || page.Id <= 0 {
+			return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if page.Type == "" {
+			page.Type = "document"
+		}
+		if page.Type != "document" && page.Type != "whiteboard" {
+			return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		for _, doc := range page.Docs {
+			if docs[doc.Id] {
+				return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+			docs[doc.Id] = true
+		}
+		pages[page.Id] = *page
+	}
+
+	// parents first; a page whose parent never resolves is part of a cycle
+	ordered := make([]ArchivePage, 0, len(manifest.Pages))
+	placed := make(map[int64]bool, len(manifest.Pages))
+	remaining := manifest.Pages
+	for len(remaining) > 0 {
+		next := make([]ArchivePage, 0)
+		for _, page := range remaining {
+			_, hasParent := pages[page.ParentId]
+			if hasParent && !placed[page.ParentId] {
+				next = append(next, page)
+				continue
+			}
+			placed[page.Id] = true
+			ordered = append(ordered, page)
+		}
+		if len(next) == len(remaining) {
+			return manifest, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		remaining = next
+	}
+	manifest.Pages = ordered
+
+	attachments := make([]ArchiveAttachment, 0, len(manifest.Attachments))
+	for _, attachment := range manifest.Attachments {
+		if _, ok := pages[attachment.PageId]; ok && attachment.File != "" {
+			attachments = append(attachments, attachment)
+		}
+	}
+	manifest.Attachments = attachments
+	comments := make([]ArchiveThread, 0, len(manifest.Comments))
+	for _, thread := range manifest.Comments {
+		if _, ok := pages[thread.PageId]; ok {
+			comments = append(comments, thread)
+		}
+	}
+	manifest.Comments = comments
+	return manifest, nil
+}
+
+var spacePermissions = []string{
+	core.SPACE_VIEW, core.SPACE_EDIT_PAGE, core.SPACE_EDIT, core.SPACE_DELETE,
+	core.SPACE_INVITE_ADMIN, core.SPACE_INVITE_MEMBER, core.SPACE_MANAGE_MEMBERS,
+	core.SPACE_TRANSFER_OWNER, core.SPACE_ARCHIVE, core.SPACE_VIEW_AUDIT,
+}
+
+var pagePermissions = []string{core.PAGE_VIEW, core.PAGE_EDIT, core.PAGE_DELETE, core.PAGE_ADD_COMMENT}
+
+// validateAccessQuery reads userId, entity, entityId and permission. The
+// entity defaults to the space itself and the permission to view.
+func validateAccessQuery(query url.Values, spaceId uuid.UUID) (AccessQuery, error) {
+	var req AccessQuery
+	userId, err := uuid.Parse(strings.TrimSpace(query.Get("userId")))
+	if err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.UserId = userId
+	req.Entity = strings.ToLower(strings.TrimSpace(query.Get("entity")))
+	req.EntityId = strings.TrimSpace(query.Get("entityId"))
+	req.Permission = strings.ToLower(strings.TrimSpace(query.Get("permission")))
+	if req.Permission == "" {
+		req.Permission = core.SPACE_VIEW
+	}
+	var allowed []string
+	switch req.Entity {
+	case "", "space":
+		req.Entity = "space"
+		if req.EntityId != "" && req.EntityId != spaceId.String() {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		req.EntityId = spaceId.String()
+		allowed = spacePermissions
+	case "page":
+		if pageId, err := strconv.ParseInt(req.EntityId, 10, 64); err != nil || pageId <= 0 {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		allowed = pagePermissions
+	default:
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if !slices.Contains(allowed, req.Permission) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
+// reviewDecisionLimit caps one bulk decision request.
+const reviewDecisionLimit = 500
+
+func validateReviewDecisions(data []byte) (ReviewDecisionsRequest, error) {
+	var req ReviewDecisionsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if len(req.Decisions) == 0 {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(req.Decisions) > reviewDecisionLimit {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	for i := range req.Decisions {
+		d := &req.Decisions[i]
+		d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+		d.Id = strings.TrimSpace(d.Id)
+		d.Decision = strings.ToLower(strings.TrimSpace(d.Decision))
+		if d.Decision != REVIEW_CONFIRM && d.Decision != REVIEW_REVOKE {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		switch d.Type {
+		case REVIEW_SUBJECT_USER:
+			if _, err := uuid.Parse(d.Id); err != nil {
+				return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+		case REVIEW_SUBJECT_INVITE:
+			if !strings.Contains(d.Id, "@") {
+				return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			}
+		default:
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+	}
+	return req, nil
+}
+
+// accessRequestTextLimit caps the message of a request and the response to it.
+const accessRequestTextLimit = 1000
+
+func validateCreateAccessRequest(data []byte) (CreateAccessRequest, error) {
+	var req CreateAccessRequest
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req); err != nil {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if len([]rune(req.Message)) > accessRequestTextLimit {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if req.PageId != nil && *req.PageId <= 0 {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
+// validateAccessRequestDecision checks the body of an approval, when approve
+// is set, or of a decline, which only takes a response.
+func validateAccessRequestDecision(data []byte, approve bool) (AccessRequestDecision, error) {
+	var req AccessRequestDecision
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req); err != nil {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+	}
+	req.Response = strings.TrimSpace(req.Response)
+	if len([]rune(req.Response)) > accessRequestTextLimit {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if !approve {
+		req.Role = ""
+		req.ExpiresAt = nil
+		return req, nil
+	}
+	req.Role = normalizeIncomingRole(req.Role)
+	if !isValidMemberRole(req.Role) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
+// spaceJoinDomainLimit caps the email domains a space can be joined from.
+const spaceJoinDomainLimit = 20
+
+func validateSpaceJoinSettings(data []byte) (SpaceJoinSettings, error) {
+	var req SpaceJoinSettings
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.Policy = strings.ToLower(strings.TrimSpace(req.Policy))
+	if req.Policy != JOIN_PRIVATE && req.Policy != JOIN_OPEN && req.Policy != JOIN_DOMAIN {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	domains := make([]string, 0, len(req.Domains))
+	for _, domain := range req.Domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+		if domain == "" || slices.Contains(domains, domain) {
+			continue
+		}
+		if len(domain) > 253 || !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ /\t") {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) > spaceJoinDomainLimit || (req.Policy == JOIN_DOMAIN && len(domains) == 0) {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.Domains = domains
+	req.Role = normalizeIncomingRole(req.Role)
+	if req.Role == "" {
+		req.Role = "viewer"
+	}
+	// admins are chosen, not self-appointed
+	if req.Role != "viewer" && req.Role != "commenter" && req.Role != "editor" {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return req, nil
+}
+
+// validateDirectoryQuery reads q, limit and offset. q is matched against
+// space names as given, so LIKE wildcards in it are escaped.
+func validateDirectoryQuery(query url.Values) (DirectoryQuery, error) {
+	req := DirectoryQuery{Limit: 50}
+	req.Query = strings.TrimSpace(query.Get("q"))
+	if len([]rune(req.Query)) > 100 {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.Query = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(req.Query)
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 100 {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		req.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		req.Offset = offset
+	}
+	return req, nil
+}
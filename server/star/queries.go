@@ -0,0 +1,49 @@
+package star
+
+const (
+	// starSpace and starPage star the target if it exists, starring it again
+	// being a no-op, and report whether it exists.
+	starSpace = `WITH target AS (
+	SELECT id FROM core.space WHERE id = $2 AND deleted_at IS NULL
+), starred AS (
+	INSERT INTO core.starred_spaces (user_id, space_id) SELECT $1, id FROM target ON CONFLICT DO NOTHING
+)
+SELECT EXISTS (SELECT 1 FROM target)`
+	unstarSpace = `DELETE FROM core.starred_spaces WHERE user_id = $1 AND space_id = $2`
+	starPage    = `WITH target AS (
+	SELECT p.id FROM core.page p JOIN core.space s ON s.id = p.space_id WHERE p.id = $2 AND s.deleted_at IS NULL
+), starred AS (
+	INSERT INTO core.starred_pages (user_id, page_id) SELECT $1, id FROM target ON CONFLICT DO NOTHING
+)
+SELECT EXISTS (SELECT 1 FROM target)`
+	unstarPage = `DELETE FROM core.starred_pages WHERE user_id = $1 AND page_id = $2`
+
+	getStarredSpaceIds = `SELECT space_id FROM core.starred_spaces WHERE user_id = $1`
+	getStarredPageIds  = `SELECT sp.page_id FROM core.starred_pages sp JOIN core.page p ON p.id = sp.page_id WHERE sp.user_id = $1 AND p.space_id = $2`
+
+	getStarredSpaces = `SELECT ss.space_id, s.name, s.archived_at, ss.created_at
+FROM core.starred_spaces ss
+JOIN core.space s ON s.id = ss.space_id
+WHERE ss.user_id = $1 AND ss.space_id = ANY($2) AND s.deleted_at IS NULL
+ORDER BY ss.created_at DESC`
+
+	getStarredPages = `SELECT * FROM (
+	SELECT
+		DISTINCT ON (p.id)
+		p.id,
+		p.space_id,
+		s.name AS space_name,
+		d.title,
+		COALESCE(p.type, 'document') AS type,
+		sp.created_at
+	FROM
+		core.starred_pages sp
+		JOIN core.page p ON p.id = sp.page_id
+		JOIN core.space s ON s.id = p.space_id
+		LEFT JOIN core.page_doc_map d ON ( p.id = d.page_id )
+	WHERE
+		sp.user_id = $1 AND p.id = ANY($2) AND s.deleted_at IS NULL
+	ORDER BY p.id, d.version DESC
+) starred
+ORDER BY starred.created_at DESC`
+)
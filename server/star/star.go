@@ -0,0 +1,103 @@
+package star
+
+import (
+	"net/http"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func sendStarError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err.Error() {
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+		core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+	case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA]:
+		core.SendFailedReponse(w, r, http.StatusNotFound, err.Error())
+	default:
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func listStarredController(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	items, err := listStarred(r.Context(), userID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, items)
+}
+
+func spaceStarController(starred bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(r)
+		if !ok {
+			core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+			return
+		}
+		spaceID, err := uuid.Parse(chi.URLParam(r, "spaceId"))
+		if err != nil {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+			return
+		}
+		if err := newStarService().setSpaceStar(r.Context(), userID, spaceID, starred); err != nil {
+			sendStarError(w, r, err)
+			return
+		}
+		core.SendSuccessResponse(w, r, http.StatusOK, starred)
+	}
+}
+
+func pageStarController(starred bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(r)
+		if !ok {
+			core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+			return
+		}
+		pageID, err := parsePageId(chi.URLParam(r, "pageId"))
+		if err != nil {
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := newStarService().setPageStar(r.Context(), userID, pageID, starred); err != nil {
+			sendStarError(w, r, err)
+			return
+		}
+		core.SendSuccessResponse(w, r, http.StatusOK, starred)
+	}
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/list", listStarredController)
+	r.Put("/space/{spaceId}", spaceStarController(true))
+	r.Delete("/space/{spaceId}", spaceStarController(false))
+	r.Put("/page/{pageId}", pageStarController(true))
+	r.Delete("/page/{pageId}", pageStarController(false))
+	return r
+}
@@ -0,0 +1,169 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// starStore is the part of the pool starring needs.
+type starStore interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// starService stars and unstars spaces and pages for a user. Only what the
+// user can view can be starred; unstarring is always allowed, so stars on
+// things they lost access to can be cleared.
+type starService struct {
+	store        starStore
+	canViewSpace func(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) bool
+	canViewPage  func(ctx context.Context, pageId int64, userId uuid.UUID) bool
+}
+
+func newStarService() *starService {
+	return &starService{
+		store: core.GetPool(),
+		canViewSpace: func(ctx context.Context, spaceId uuid.UUID, userId uuid.UUID) bool {
+			return core.ValidateUserSpacePermissions(ctx, spaceId, userId, core.SPACE_VIEW)
+		},
+		canViewPage: func(ctx context.Context, pageId int64, userId uuid.UUID) bool {
+			return core.ValidateUserPagePermission(ctx, strconv.FormatInt(pageId, 10), userId, core.PAGE_VIEW)
+		},
+	}
+}
+
+func (s *starService) setSpaceStar(ctx context.Context, userId uuid.UUID, spaceId uuid.UUID, starred bool) error {
+	if !starred {
+		return s.unstar(ctx, unstarSpace, userId, spaceId)
+	}
+	if !s.canViewSpace(ctx, spaceId, userId) {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	return s.star(ctx, starSpace, userId, spaceId)
+}
+
+func (s *starService) setPageStar(ctx context.Context, userId uuid.UUID, pageId int64, starred bool) error {
+	if !starred {
+		return s.unstar(ctx, unstarPage, userId, pageId)
+	}
+	if !s.canViewPage(ctx, pageId, userId) {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+	}
+	return s.star(ctx, starPage, userId, pageId)
+}
+
+func (s *starService) star(ctx context.Context, query string, userId uuid.UUID, target any) error {
+	var found bool
+	if err := s.store.QueryRow(ctx, query, userId, target).Scan(&found); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	if !found {
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	return nil
+}
+
+func (s *starService) unstar(ctx context.Context, query string, userId uuid.UUID, target any) error {
+	if _, err := s.store.Exec(ctx, query, userId, target); err != nil {
+		logger().Error(err.Error())
+		return errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	return nil
+}
+
+// StarredSpaceIds returns the set of spaces the user has starred.
+func StarredSpaceIds(userId uuid.UUID) (map[uuid.UUID]bool, error) {
+	starred := make(map[uuid.UUID]bool)
+	rows, err := core.GetPool().Query(context.Background(), getStarredSpaceIds, userId)
+	if err != nil {
+		logger().Error(err.Error())
+		return starred, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		logger().Error(err.Error())
+		return starred, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for _, id := range ids {
+		starred[id] = true
+	}
+	return starred, nil
+}
+
+// StarredPageIds returns the set of pages in a space the user has starred.
+func StarredPageIds(userId uuid.UUID, spaceId uuid.UUID) (map[int64]bool, error) {
+	starred := make(map[int64]bool)
+	rows, err := core.GetPool().Query(context.Background(), getStarredPageIds, userId, spaceId)
+	if err != nil {
+		logger().Error(err.Error())
+		return starred, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		logger().Error(err.Error())
+		return starred, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	for _, id := range ids {
+		starred[id] = true
+	}
+	return starred, nil
+}
+
+// listStarred returns everything the user starred. Spaces and pages the user
+// can no longer view are filtered out but their stars are kept, so access
+// being restored brings them back.
+func listStarred(ctx context.Context, userId uuid.UUID) (StarredItems, error) {
+	items := StarredItems{Spaces: []StarredSpace{}, Pages: []StarredPage{}}
+
+	spaceIds, err := core.GetEntitiesWithPermission(ctx, "space", "user", userId.String(), core.SPACE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+	pageIds, err := core.GetEntitiesWithPermission(ctx, "page", "user", userId.String(), core.PAGE_VIEW)
+	if err != nil {
+		logger().Error(err.Error())
+		return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_PERMISSION_SERVER_ISSUE])
+	}
+
+	conn, err := core.GetPool().Acquire(ctx)
+	if err != nil {
+		logger().Error(err.Error())
+		return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_CONNECTION_ISSUE])
+	}
+	defer conn.Release()
+
+	if len(spaceIds) > 0 {
+		rows, err := conn.Query(ctx, getStarredSpaces, userId, spaceIds)
+		if err != nil {
+			logger().Error(err.Error())
+			return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+		}
+		items.Spaces, err = pgx.CollectRows(rows, pgx.RowToStructByName[StarredSpace])
+		if err != nil {
+			logger().Error(err.Error())
+			return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+	}
+
+	if len(pageIds) > 0 {
+		rows, err := conn.Query(ctx, getStarredPages, userId, pageIds)
+		if err != nil {
+			logger().Error(err.Error())
+			return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+		}
+		items.Pages, err = pgx.CollectRows(rows, pgx.RowToStructByName[StarredPage])
+		if err != nil {
+			logger().Error(err.Error())
+			return items, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+		}
+	}
+	return items, nil
+}
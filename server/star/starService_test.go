@@ -0,0 +1,140 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type starKey struct {
+	user   uuid.UUID
+	target any
+}
+
+// memoryStore answers the star queries from memory.
+type memoryStore struct {
+	spaces map[uuid.UUID]bool
+	pages  map[int64]bool
+	stars  map[starKey]bool
+	fail   error
+}
+
+type boolRow struct {
+	value bool
+	err   error
+}
+
+func (r boolRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*bool) = r.value
+	return nil
+}
+
+func (m *memoryStore) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if m.fail != nil {
+		return boolRow{err: m.fail}
+	}
+	key := starKey{user: args[0].(uuid.UUID), target: args[1]}
+	var found bool
+	switch query {
+	case starSpace:
+		found = m.spaces[args[1].(uuid.UUID)]
+	case starPage:
+		found = m.pages[args[1].(int64)]
+	}
+	if found {
+		m.stars[key] = true
+	}
+	return boolRow{value: found}
+}
+
+func (m *memoryStore) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	if m.fail != nil {
+		return pgconn.CommandTag{}, m.fail
+	}
+	delete(m.stars, starKey{user: args[0].(uuid.UUID), target: args[1]})
+	return pgconn.CommandTag{}, nil
+}
+
+func newTestService(store *memoryStore, canView bool) *starService {
+	return &starService{
+		store:        store,
+		canViewSpace: func(context.Context, uuid.UUID, uuid.UUID) bool { return canView },
+		canViewPage:  func(context.Context, int64, uuid.UUID) bool { return canView },
+	}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{spaces: map[uuid.UUID]bool{}, pages: map[int64]bool{}, stars: map[starKey]bool{}}
+}
+
+func errorIs(err error, code core.ErrorCode) bool {
+	return err != nil && err.Error() == core.ErrorCode_name[code]
+}
+
+func TestStarPageTwiceKeepsOneStar(t *testing.T) {
+	store := newMemoryStore()
+	store.pages[7] = true
+	service := newTestService(store, true)
+	user := uuid.New()
+	for range 2 {
+		if err := service.setPageStar(context.Background(), user, 7, true); err != nil {
+			t.Fatalf("expected starring to succeed, got %v", err)
+		}
+	}
+	if len(store.stars) != 1 || !store.stars[starKey{user, int64(7)}] {
+		t.Fatalf("expected a single star, got %+v", store.stars)
+	}
+	if err := service.setPageStar(context.Background(), user, 7, false); err != nil || len(store.stars) != 0 {
+		t.Fatalf("expected unstarring to remove the star, got %v, %+v", err, store.stars)
+	}
+}
+
+func TestStarUnknownTargets(t *testing.T) {
+	service := newTestService(newMemoryStore(), true)
+	if err := service.setPageStar(context.Background(), uuid.New(), 99, true); !errorIs(err, core.ErrorCode_ERROR_CODE_NO_DATA) {
+		t.Fatalf("expected an unknown page to be reported as missing, got %v", err)
+	}
+	if err := service.setSpaceStar(context.Background(), uuid.New(), uuid.New(), true); !errorIs(err, core.ErrorCode_ERROR_CODE_NO_DATA) {
+		t.Fatalf("expected an unknown space to be reported as missing, got %v", err)
+	}
+}
+
+func TestStarNeedsViewPermission(t *testing.T) {
+	store := newMemoryStore()
+	space := uuid.New()
+	store.spaces[space] = true
+	store.pages[7] = true
+	service := newTestService(store, false)
+	user := uuid.New()
+	if err := service.setSpaceStar(context.Background(), user, space, true); !errorIs(err, core.ErrorCode_ERROR_CODE_UNAUTHORIZED) {
+		t.Fatalf("expected starring a space the user can't view to be denied, got %v", err)
+	}
+	if err := service.setPageStar(context.Background(), user, 7, true); !errorIs(err, core.ErrorCode_ERROR_CODE_UNAUTHORIZED) {
+		t.Fatalf("expected starring a page the user can't view to be denied, got %v", err)
+	}
+	if len(store.stars) != 0 {
+		t.Fatalf("expected nothing starred, got %+v", store.stars)
+	}
+	// a star left from before access was lost can still be cleared
+	store.stars[starKey{user, space}] = true
+	if err := service.setSpaceStar(context.Background(), user, space, false); err != nil || len(store.stars) != 0 {
+		t.Fatalf("expected unstarring to be allowed, got %v, %+v", err, store.stars)
+	}
+}
+
+func TestStarStoreFailure(t *testing.T) {
+	store := newMemoryStore()
+	store.fail = errors.New("connection reset")
+	service := newTestService(store, true)
+	if err := service.setPageStar(context.Background(), uuid.New(), 7, true); !errorIs(err, core.ErrorCode_ERROR_WHILE_INSERTING_ROWS) {
+		t.Fatalf("expected a store failure to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package star
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StarredSpace struct {
+	SpaceId    uuid.UUID  `json:"spaceId" db:"space_id"`
+	Name       string     `json:"name" db:"name"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty" db:"archived_at"`
+	StarredAt  time.Time  `json:"starredAt" db:"created_at"`
+}
+
+type StarredPage struct {
+	PageId    int64     `json:"pageId" db:"id"`
+	SpaceId   uuid.UUID `json:"spaceId" db:"space_id"`
+	SpaceName string    `json:"spaceName" db:"space_name"`
+	Title     *string   `json:"title" db:"title"`
+	Type      string    `json:"type" db:"type"`
+	StarredAt time.Time `json:"starredAt" db:"created_at"`
+}
+
+type StarredItems struct {
+	Spaces []StarredSpace `json:"spaces"`
+	Pages  []StarredPage  `json:"pages"`
+}
@@ -0,0 +1,17 @@
+package star
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+func parsePageId(value string) (int64, error) {
+	pageId, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || pageId <= 0 {
+		return 0, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	return pageId, nil
+}
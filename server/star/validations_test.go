@@ -0,0 +1,14 @@
+package star
+
+import "testing"
+
+func TestParsePageId(t *testing.T) {
+	if id, err := parsePageId(" 42 "); err != nil || id != 42 {
+		t.Fatalf("expected page 42, got %d, %v", id, err)
+	}
+	for _, value := range []string{"", "0", "-3", "abc", "1.5"} {
+		if _, err := parsePageId(value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
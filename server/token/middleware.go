@@ -0,0 +1,38 @@
+package token
+
+import (
+	"net/http"
+
+	"github.com/durgakiran/beskar/core"
+)
+
+// Authenticate accepts Beskar personal access tokens as bearer tokens and
+// hands every other request to session. Token requests carry the same
+// core.UserInfo a session would, so handlers need no changes; read scoped
+// tokens are limited to safe methods.
+func Authenticate(session func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withSession := session(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := bearerSecret(r.Header.Get("Authorization"))
+			if !ok {
+				withSession.ServeHTTP(w, r)
+				return
+			}
+			user, token, err := authenticateSecret(r.Context(), secret)
+			if err != nil {
+				status := http.StatusUnauthorized
+				if err.Error() != core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+					status = http.StatusInternalServerError
+				}
+				core.SendFailedReponse(w, r, status, err.Error())
+				return
+			}
+			if token.Scope == core.TOKEN_SCOPE_READ && !core.IsSafeMethod(r.Method) {
+				core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(core.WithAccessToken(r.Context(), user, token)))
+		})
+	}
+}
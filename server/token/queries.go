@@ -0,0 +1,21 @@
+package token
+
+const (
+	insertToken = `INSERT INTO core.access_tokens (user_id, org_id, name, token_prefix, token_hash, scope, space_ids, user_name, user_email, user_email_verified, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, name, token_prefix, scope, space_ids, created_at, expires_at, last_used_at, revoked_at`
+	listTokens = `SELECT id, name, token_prefix, scope, space_ids, created_at, expires_at, last_used_at, revoked_at
+FROM core.access_tokens
+WHERE user_id = $1 AND org_id = $2
+ORDER BY created_at DESC`
+	countActiveTokens = `SELECT COUNT(*) FROM core.access_tokens WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()`
+	revokeToken       = `UPDATE core.access_tokens SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING id, name, token_prefix, scope, space_ids, created_at, expires_at, last_used_at, revoked_at`
+	getTokenOwner = `SELECT t.id, t.user_id, t.org_id, t.scope, t.space_ids, t.user_name, t.user_email, t.user_email_verified, m.zita_id
+FROM core.access_tokens t
+JOIN core.user_id_map m ON m.user_id = t.user_id
+WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > now()`
+	touchToken = `UPDATE core.access_tokens SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+)
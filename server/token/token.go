@@ -0,0 +1,124 @@
+package token
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/durgakiran/beskar/audit"
+	"github.com/durgakiran/beskar/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func logger() *zap.Logger {
+	return core.Logger
+}
+
+// currentUser only accepts session requests; a token cannot be used to mint,
+// list or revoke tokens.
+func currentUser(r *http.Request) (core.UserInfo, uuid.UUID, bool) {
+	if _, ok := core.AccessTokenFromContext(r.Context()); ok {
+		return core.UserInfo{}, uuid.Nil, false
+	}
+	user, err := core.GetUserInfo(r.Context())
+	if err != nil || user.Id == "" {
+		return user, uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.AId)
+	if err != nil {
+		return user, uuid.Nil, false
+	}
+	return user, userID, true
+}
+
+func listTokensController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	tokens, err := listUserTokens(r.Context(), userID)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	core.SendSuccessResponse(w, r, http.StatusOK, tokens)
+}
+
+func createTokenController(w http.ResponseWriter, r *http.Request) {
+	user, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	req, err := validateCreateToken(data)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	created, err := createToken(r.Context(), user, userID, req)
+	if err != nil {
+		switch err.Error() {
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED]:
+			core.SendFailedReponse(w, r, http.StatusForbidden, err.Error())
+		case core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT]:
+			core.SendFailedReponse(w, r, http.StatusBadRequest, err.Error())
+		default:
+			core.SendFailedReponse(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionTokenCreated,
+		TargetType: "access_token",
+		TargetId:   created.Id.String(),
+		After:      created.AccessToken,
+	})
+	core.SendSuccessResponse(w, r, http.StatusCreated, created)
+}
+
+func revokeTokenController(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := currentUser(r)
+	if !ok {
+		core.SendFailedReponse(w, r, http.StatusForbidden, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		return
+	}
+	tokenID, err := uuid.Parse(chi.URLParam(r, "tokenId"))
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusBadRequest, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		return
+	}
+	token, err := revokeUserToken(r.Context(), userID, tokenID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA] {
+			status = http.StatusNotFound
+		}
+		core.SendFailedReponse(w, r, status, err.Error())
+		return
+	}
+	audit.Record(r, audit.Entry{
+		Action:     audit.ActionTokenRevoked,
+		TargetType: "access_token",
+		TargetId:   token.Id.String(),
+		Before:     map[string]interface{}{"name": token.Name, "scope": token.Scope},
+	})
+	core.SendSuccessResponse(w, r, http.StatusOK, token)
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(core.Authenticated)
+
+	r.Get("/list", listTokensController)
+	r.Post("/create", createTokenController)
+	r.Delete("/{tokenId}", revokeTokenController)
+	return r
+}
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// createToken issues a token for the user in the current organization. Space
+// limits may only name spaces the user can already view.
+func createToken(ctx context.Context, user core.UserInfo, userId uuid.UUID, req CreateTokenRequest) (CreatedToken, error) {
+	var created CreatedToken
+	for _, spaceId := range req.SpaceIds {
+		if !core.ValidateUserSpacePermissions(ctx, spaceId, userId, core.SPACE_VIEW) {
+			return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNAUTHORIZED])
+		}
+	}
+	var active int
+	if err := core.GetPool().QueryRow(ctx, countActiveTokens, userId).Scan(&active); err != nil {
+		logger().Error(err.Error())
+		return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if active >= maxActiveTokens {
+		return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	secret, hash, err := newSecret()
+	if err != nil {
+		logger().Error(err.Error())
+		return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+	}
+	var spaceIds []uuid.UUID
+	if len(req.SpaceIds) > 0 {
+		spaceIds = req.SpaceIds
+	}
+	expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+	rows, err := core.GetPool().Query(ctx, insertToken, userId, core.OrganizationFromContext(ctx), req.Name, secret[:displayPrefixLength], hash, req.Scope, spaceIds, user.Name, user.Email, user.IsVerified, expiresAt)
+	if err != nil {
+		logger().Error(err.Error())
+		return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[AccessToken])
+	if err != nil {
+		logger().Error(err.Error())
+		return created, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_INSERTING_ROWS])
+	}
+	return CreatedToken{AccessToken: token, Token: secret}, nil
+}
+
+func listUserTokens(ctx context.Context, userId uuid.UUID) ([]AccessToken, error) {
+	tokens := make([]AccessToken, 0)
+	rows, err := core.GetPool().Query(ctx, listTokens, userId, core.OrganizationFromContext(ctx))
+	if err != nil {
+		logger().Error(err.Error())
+		return tokens, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	tokens, err = pgx.CollectRows(rows, pgx.RowToStructByName[AccessToken])
+	if err != nil {
+		logger().Error(err.Error())
+		return tokens, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	return tokens, nil
+}
+
+func revokeUserToken(ctx context.Context, userId uuid.UUID, tokenId uuid.UUID) (AccessToken, error) {
+	rows, err := core.GetPool().Query(ctx, revokeToken, tokenId, userId)
+	if err != nil {
+		logger().Error(err.Error())
+		return AccessToken{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[AccessToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return token, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return token, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_UPDATING_ROWS])
+	}
+	return token, nil
+}
+
+// authenticateSecret resolves a presented secret to the user it acts as.
+// Unknown, revoked and expired tokens all report NO_DATA.
+func authenticateSecret(ctx context.Context, secret string) (core.UserInfo, core.AccessToken, error) {
+	rows, err := core.GetPool().Query(ctx, getTokenOwner, hashSecret(secret))
+	if err != nil {
+		logger().Error(err.Error())
+		return core.UserInfo{}, core.AccessToken{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_FETCHING_ROWS])
+	}
+	owner, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[tokenOwner])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return core.UserInfo{}, core.AccessToken{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_NO_DATA])
+	}
+	if err != nil {
+		logger().Error(err.Error())
+		return core.UserInfo{}, core.AccessToken{}, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_WHILE_READING_ROWS])
+	}
+	if _, err := core.GetPool().Exec(ctx, touchToken, owner.Id); err != nil {
+		logger().Error(err.Error())
+	}
+	token := core.AccessToken{
+		Id:       owner.Id,
+		UserId:   owner.UserId,
+		OrgId:    owner.OrgId,
+		Scope:    owner.Scope,
+		SpaceIds: owner.SpaceIds,
+	}
+	return ownerIdentity(owner), token, nil
+}
+
+// ownerIdentity is the user a token acts as, carrying the name, email and
+// email verification recorded when the token was created.
+func ownerIdentity(owner tokenOwner) core.UserInfo {
+	user := core.UserInfo{
+		Id:         owner.ZitaId,
+		AId:        owner.UserId.String(),
+		IsVerified: owner.Verified,
+	}
+	if owner.UserName != nil {
+		user.Name = *owner.UserName
+	}
+	if owner.UserEmail != nil {
+		user.Email = *owner.UserEmail
+	}
+	return user
+}
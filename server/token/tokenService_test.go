@@ -0,0 +1,24 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestOwnerIdentityKeepsRecordedVerification(t *testing.T) {
+	email := "someone@example.com"
+	owner := tokenOwner{UserId: uuid.New(), ZitaId: "zita", UserEmail: &email}
+
+	if user := ownerIdentity(owner); user.IsVerified {
+		t.Fatal("token created with an unverified email must not act as verified")
+	}
+	owner.Verified = true
+	user := ownerIdentity(owner)
+	if !user.IsVerified {
+		t.Fatal("token created with a verified email should act as verified")
+	}
+	if user.Email != email || user.Id != "zita" || user.AId != owner.UserId.String() {
+		t.Fatalf("unexpected identity %+v", user)
+	}
+}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessToken is a personal access token as listed to its owner. The secret
+// itself is only ever returned once, in CreatedToken.
+type AccessToken struct {
+	Id         uuid.UUID   `json:"id" db:"id"`
+	Name       string      `json:"name" db:"name"`
+	Prefix     string      `json:"prefix" db:"token_prefix"`
+	Scope      string      `json:"scope" db:"scope"`
+	SpaceIds   []uuid.UUID `json:"spaceIds" db:"space_ids"`
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+	ExpiresAt  time.Time   `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time  `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revokedAt" db:"revoked_at"`
+}
+
+type CreatedToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+type CreateTokenRequest struct {
+	Name          string      `json:"name"`
+	Scope         string      `json:"scope"`
+	SpaceIds      []uuid.UUID `json:"spaceIds"`
+	ExpiresInDays int         `json:"expiresInDays"`
+}
+
+// tokenOwner is what a presented token resolves to.
+type tokenOwner struct {
+	Id        uuid.UUID   `db:"id"`
+	UserId    uuid.UUID   `db:"user_id"`
+	OrgId     uuid.UUID   `db:"org_id"`
+	Scope     string      `db:"scope"`
+	SpaceIds  []uuid.UUID `db:"space_ids"`
+	UserName  *string     `db:"user_name"`
+	UserEmail *string     `db:"user_email"`
+	Verified  bool        `db:"user_email_verified"`
+	ZitaId    string      `db:"zita_id"`
+}
@@ -0,0 +1,108 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+const (
+	// TOKEN_PREFIX marks Beskar tokens so they are easy to spot in logs and
+	// secret scanners, and tells them apart from other bearer tokens.
+	TOKEN_PREFIX = "bsk_"
+
+	maxTokenNameLength   = 100
+	maxTokenSpaces       = 50
+	maxActiveTokens      = 50
+	defaultTokenLifetime = 30
+	maxTokenLifetime     = 365
+	displayPrefixLength  = len(TOKEN_PREFIX) + 8
+)
+
+// newSecret returns a fresh token and the hash it is stored under.
+func newSecret() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret := TOKEN_PREFIX + base64.RawURLEncoding.EncodeToString(buf)
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerSecret returns the Beskar token carried in the Authorization header.
+// Other bearer tokens are left for the session middleware.
+func bearerSecret(header string) (string, bool) {
+	scheme, value, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, TOKEN_PREFIX) {
+		return "", false
+	}
+	return value, true
+}
+
+func normalizeScope(scope string) (string, error) {
+	scope = strings.ToLower(strings.TrimSpace(scope))
+	switch scope {
+	case "":
+		return scope, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	case core.TOKEN_SCOPE_READ, core.TOKEN_SCOPE_EDIT, core.TOKEN_SCOPE_ADMIN:
+		return scope, nil
+	}
+	return scope, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+}
+
+func validateCreateToken(data []byte) (CreateTokenRequest, error) {
+	var req CreateTokenRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_MISSING_INPUT])
+	}
+	if len(req.Name) > maxTokenNameLength {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	scope, err := normalizeScope(req.Scope)
+	if err != nil {
+		return req, err
+	}
+	req.Scope = scope
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultTokenLifetime
+	}
+	if req.ExpiresInDays < 1 || req.ExpiresInDays > maxTokenLifetime {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	seen := make(map[uuid.UUID]bool, len(req.SpaceIds))
+	spaceIds := make([]uuid.UUID, 0, len(req.SpaceIds))
+	for _, spaceId := range req.SpaceIds {
+		if spaceId == uuid.Nil {
+			return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+		}
+		if !seen[spaceId] {
+			seen[spaceId] = true
+			spaceIds = append(spaceIds, spaceId)
+		}
+	}
+	if len(spaceIds) > maxTokenSpaces {
+		return req, errors.New(core.ErrorCode_name[core.ErrorCode_ERROR_CODE_INVALID_INPUT])
+	}
+	req.SpaceIds = spaceIds
+	return req, nil
+}
@@ -0,0 +1,70 @@
+package token
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/durgakiran/beskar/core"
+	"github.com/google/uuid"
+)
+
+func TestNewSecretIsPrefixedAndHashed(t *testing.T) {
+	secret, hash, err := newSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(secret, TOKEN_PREFIX) || len(secret) < displayPrefixLength {
+		t.Fatalf("unexpected secret %q", secret)
+	}
+	if hash != hashSecret(secret) || strings.Contains(hash, secret) {
+		t.Fatalf("expected the stored hash to be derived from the secret")
+	}
+	other, _, _ := newSecret()
+	if other == secret {
+		t.Fatalf("expected fresh secrets to differ")
+	}
+}
+
+func TestBearerSecretOnlyAcceptsBeskarTokens(t *testing.T) {
+	if secret, ok := bearerSecret("Bearer bsk_abc"); !ok || secret != "bsk_abc" {
+		t.Fatalf("expected token to be accepted, got %q %v", secret, ok)
+	}
+	if _, ok := bearerSecret("bearer  bsk_abc "); !ok {
+		t.Fatalf("expected scheme to be case insensitive")
+	}
+	for _, header := range []string{"", "Bearer eyJhbGciOi", "Basic bsk_abc", "bsk_abc"} {
+		if _, ok := bearerSecret(header); ok {
+			t.Fatalf("expected %q to be left for the session", header)
+		}
+	}
+}
+
+func TestValidateCreateTokenDefaults(t *testing.T) {
+	spaceId := uuid.New()
+	body := `{"name":" ci docs ","scope":" Edit ","spaceIds":["` + spaceId.String() + `","` + spaceId.String() + `"]}`
+	req, err := validateCreateToken([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Name != "ci docs" || req.Scope != core.TOKEN_SCOPE_EDIT || req.ExpiresInDays != defaultTokenLifetime {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if len(req.SpaceIds) != 1 || req.SpaceIds[0] != spaceId {
+		t.Fatalf("expected duplicate spaces to be dropped: %v", req.SpaceIds)
+	}
+}
+
+func TestValidateCreateTokenRejectsBadInput(t *testing.T) {
+	bodies := []string{
+		`{"name":"","scope":"read"}`,
+		`{"name":"ci","scope":""}`,
+		`{"name":"ci","scope":"owner"}`,
+		`{"name":"ci","scope":"read","expiresInDays":400}`,
+		`{"name":"ci","scope":"read","expiresInDays":-1}`,
+	}
+	for _, body := range bodies {
+		if _, err := validateCreateToken([]byte(body)); err == nil {
+			t.Fatalf("expected %s to be rejected", body)
+		}
+	}
+}
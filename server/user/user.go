@@ -1,10 +1,12 @@
 package user
 
 import (
+	"context"
 	"io"
 	"net/http"
 
 	"github.com/durgakiran/beskar/core"
+	"github.com/durgakiran/beskar/org"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -39,10 +41,41 @@ func searchUser(w http.ResponseWriter, r *http.Request) {
 		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
 		return
 	}
+	users, err = organizationUsers(ctx, users)
+	if err != nil {
+		core.SendFailedReponse(w, r, http.StatusInternalServerError, core.ErrorCode_name[core.ErrorCode_ERROR_CODE_UNSPECIFIED])
+		return
+	}
 
 	core.SendSuccessResponse(w, r, http.StatusOK, users)
 }
 
+// organizationUsers drops search results outside the caller's organization.
+func organizationUsers(ctx context.Context, users core.UserSearchResponse) (core.UserSearchResponse, error) {
+	beskarIDs := make(map[string]string, len(users.Result))
+	userIds := make([]string, 0, len(users.Result))
+	for _, result := range users.Result {
+		beskarID, err := core.GetBeskarUser(result.UserId)
+		if err != nil {
+			continue
+		}
+		beskarIDs[result.UserId] = beskarID
+		userIds = append(userIds, beskarID)
+	}
+	members, err := org.MemberIds(ctx, core.OrganizationFromContext(ctx), userIds)
+	if err != nil {
+		return users, err
+	}
+	filtered := make([]core.User, 0, len(users.Result))
+	for _, result := range users.Result {
+		if members[beskarIDs[result.UserId]] {
+			filtered = append(filtered, result)
+		}
+	}
+	users.Result = filtered
+	return users, nil
+}
+
 func Router() *chi.Mux {
 	r := chi.NewRouter()
 	r.Post("/search", searchUser)
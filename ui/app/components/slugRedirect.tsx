@@ -0,0 +1,48 @@
+"use client";
+
+import { useEffect } from "react";
+import { useRouter } from "next/navigation";
+import { Response, useGet } from "@http/hooks";
+
+interface SlugResolution {
+    spaceId: string;
+    pageId?: number;
+}
+
+const UUID_PATTERN = /^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/i;
+
+export function isSpaceSlug(spaceId: string) {
+    return !UUID_PATTERN.test(spaceId);
+}
+
+/**
+ * Resolves a slug path such as /space/platform/runbooks/deploy and replaces
+ * it with the id based route the space pages are built on.
+ */
+export default function SlugRedirect({ path }: { path: string }) {
+    const router = useRouter();
+    const [{ data, response }, resolve] = useGet<Response<SlugResolution>>("slug/resolve");
+
+    useEffect(() => {
+        resolve({ path });
+    }, [resolve, path]);
+
+    useEffect(() => {
+        const target = data?.data;
+        if (!target?.spaceId) return;
+        router.replace(target.pageId ? `/space/${target.spaceId}/view/${target.pageId}` : `/space/${target.spaceId}`);
+    }, [data, router]);
+
+    if (response && response !== 200) {
+        return (
+            <div className="flex h-full w-full items-center justify-center">
+                <p className="text-sm text-neutral-700">This page does not exist or you do not have access to it.</p>
+            </div>
+        );
+    }
+    return (
+        <div className="flex h-full w-full items-center justify-center">
+            <div className="h-4 w-4 animate-spin rounded-full border-2 border-primary-600 border-t-transparent" />
+        </div>
+    );
+}
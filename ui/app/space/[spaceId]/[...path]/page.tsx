@@ -0,0 +1,4 @@
+// Slug paths below a space are resolved and redirected by the space layout.
+export default function Page() {
+    return null;
+}
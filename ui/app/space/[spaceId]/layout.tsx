@@ -8,6 +8,7 @@ import { cn } from "@/lib/utils";
 import { PageTree, PageTreeNode } from "@components/primitives";
 import AddPage from "@components/addPage";
 import { Response, useGet } from "@http/hooks";
+import SlugRedirect, { isSpaceSlug } from "@components/slugRedirect";
 
 interface IPageList {
     pageId: number;
@@ -25,6 +26,7 @@ interface SpaceState {
 export default function Layout({ children, params }: { children: React.ReactNode, params: Promise<{ spaceId: string }> }) {
     const { spaceId } = use(params);
     const pathname = usePathname();
+    const slugPath = isSpaceSlug(spaceId);
     const router = useRouter();
     
     // Sidebar Resize State
@@ -42,9 +44,10 @@ export default function Layout({ children, params }: { children: React.ReactNode
     const [{ data: spaceDetails }, fetchSpaceDetails] = useGet<Response<SpaceState>>(`space/${spaceId}/details`);
 
     useEffect(() => {
+        if (slugPath) return;
         fetchPages();
         fetchSpaceDetails();
-    }, [fetchPages, fetchSpaceDetails]);
+    }, [fetchPages, fetchSpaceDetails, slugPath]);
 
     useEffect(() => {
         if (data?.data) {
@@ -143,6 +146,10 @@ export default function Layout({ children, params }: { children: React.ReactNode
         return pathname.startsWith(path);
     };
 
+    if (slugPath) {
+        return <SlugRedirect path={pathname} />;
+    }
+
     return (
         <div className="flex h-full w-full overflow-hidden bg-white">
             {/* Sidebar */}